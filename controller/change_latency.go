@@ -0,0 +1,72 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var changeApplyLatency = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Namespace: "external_dns",
+		Subsystem: "controller",
+		Name:      "change_apply_latency_seconds",
+		Help:      "Time from the last change of a resource to the application of its DNS records.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 14),
+	},
+	[]string{"pipeline", "kind"},
+)
+
+func init() {
+	prometheus.MustRegister(changeApplyLatency)
+}
+
+// ResourceChangeTimer returns when the resources of the endpoints were last changed, so the latency
+// from a change of a resource to the application of its records can be tracked as an SLO.
+type ResourceChangeTimer interface {
+	// LastChanged returns the time of the last change of a resource, e.g. service/default/foo, or false
+	// if it isn't known.
+	LastChanged(resource string) (time.Time, bool)
+}
+
+// observeChangeLatency observes the latency from the last change of each of the resources to the given
+// time their records were applied. A change is observed once, so records applied again for the same
+// change, e.g. after a restart, don't count twice.
+func (c *Controller) observeChangeLatency(resources []string, appliedAt time.Time) {
+	c.observedChangesLock.Lock()
+	defer c.observedChangesLock.Unlock()
+	if c.observedChanges == nil {
+		c.observedChanges = map[string]time.Time{}
+	}
+
+	for _, resource := range resources {
+		changedAt, ok := c.ResourceChangeTimer.LastChanged(resource)
+		if !ok || changedAt.Equal(c.observedChanges[resource]) {
+			continue
+		}
+		c.observedChanges[resource] = changedAt
+
+		latency := appliedAt.Sub(changedAt)
+		if latency < 0 {
+			latency = 0
+		}
+		changeApplyLatency.WithLabelValues(c.pipeline, strings.SplitN(resource, "/", 2)[0]).Observe(latency.Seconds())
+	}
+}
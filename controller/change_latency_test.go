@@ -0,0 +1,79 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// staticChangeTimer returns the change times of the resources in the map.
+type staticChangeTimer map[string]time.Time
+
+func (t staticChangeTimer) LastChanged(resource string) (time.Time, bool) {
+	changedAt, ok := t[resource]
+	return changedAt, ok
+}
+
+// changeLatencyMetric returns the text format of the latency histogram of the service kind with the values.
+func changeLatencyMetric(values ...float64) string {
+	const name = "external_dns_controller_change_apply_latency_seconds"
+	var b strings.Builder
+	fmt.Fprintf(&b, "# HELP %s Time from the last change of a resource to the application of its DNS records.\n", name)
+	fmt.Fprintf(&b, "# TYPE %s histogram\n", name)
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	for bound := 1.0; bound <= 8192; bound *= 2 {
+		count := 0
+		for _, v := range values {
+			if v <= bound {
+				count++
+			}
+		}
+		fmt.Fprintf(&b, "%s_bucket{kind=\"service\",pipeline=\"\",le=\"%g\"} %d\n", name, bound, count)
+	}
+	fmt.Fprintf(&b, "%s_bucket{kind=\"service\",pipeline=\"\",le=\"+Inf\"} %d\n", name, len(values))
+	fmt.Fprintf(&b, "%s_sum{kind=\"service\",pipeline=\"\"} %g\n", name, sum)
+	fmt.Fprintf(&b, "%s_count{kind=\"service\",pipeline=\"\"} %d\n", name, len(values))
+	return b.String()
+}
+
+func TestObserveChangeLatency(t *testing.T) {
+	appliedAt := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	timer := staticChangeTimer{"service/default/a": appliedAt.Add(-30 * time.Second)}
+	ctrl := &Controller{ResourceChangeTimer: timer}
+
+	// resources whose changes aren't known are skipped
+	ctrl.observeChangeLatency([]string{"service/default/a", "service/default/unknown"}, appliedAt)
+	assert.NoError(t, testutil.CollectAndCompare(changeApplyLatency, strings.NewReader(changeLatencyMetric(30))))
+
+	// the same change is observed once
+	ctrl.observeChangeLatency([]string{"service/default/a"}, appliedAt.Add(time.Minute))
+	assert.NoError(t, testutil.CollectAndCompare(changeApplyLatency, strings.NewReader(changeLatencyMetric(30))))
+
+	// a new change is observed again, changes from the future count as no latency
+	timer["service/default/a"] = appliedAt.Add(time.Hour + 5*time.Second)
+	ctrl.observeChangeLatency([]string{"service/default/a"}, appliedAt.Add(time.Hour))
+	assert.NoError(t, testutil.CollectAndCompare(changeApplyLatency, strings.NewReader(changeLatencyMetric(30, 0))))
+}
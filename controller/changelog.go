@@ -0,0 +1,75 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// logChanges logs the changes which are about to be applied, one line per record with its targets and
+// TTL before and after the change. A synchronization without changes logs a single summary line, so the
+// logs of a steady state don't grow with the number of records.
+func logChanges(changes *plan.Changes, records int) {
+	if len(changes.Create)+len(changes.UpdateNew)+len(changes.Delete) == 0 {
+		log.Infof("All %d records are already up to date", records)
+		return
+	}
+	log.Infof("Applying %d creations, %d updates and %d deletions", len(changes.Create), len(changes.UpdateNew), len(changes.Delete))
+
+	for _, ep := range changes.Create {
+		log.WithFields(changeFields(nil, ep)).Info("Creating record")
+	}
+	old := map[string]*endpoint.Endpoint{}
+	for _, ep := range changes.UpdateOld {
+		old[renamedDeletionKey(ep)] = ep
+	}
+	for _, ep := range changes.UpdateNew {
+		log.WithFields(changeFields(old[renamedDeletionKey(ep)], ep)).Info("Updating record")
+	}
+	for _, ep := range changes.Delete {
+		log.WithFields(changeFields(ep, nil)).Info("Deleting record")
+	}
+}
+
+// changeFields returns the log fields of a change from the record before to the record after it, either
+// of which is nil for creations and deletions.
+func changeFields(before, after *endpoint.Endpoint) log.Fields {
+	ep := after
+	if ep == nil {
+		ep = before
+	}
+	fields := log.Fields{"record": ep.DNSName, "type": ep.RecordType}
+	if ep.SetIdentifier != "" {
+		fields["set_identifier"] = ep.SetIdentifier
+	}
+	if before != nil {
+		fields["targets_before"] = before.Targets.String()
+		if before.RecordTTL.IsConfigured() {
+			fields["ttl_before"] = int64(before.RecordTTL)
+		}
+	}
+	if after != nil {
+		fields["targets_after"] = after.Targets.String()
+		if after.RecordTTL.IsConfigured() {
+			fields["ttl_after"] = int64(after.RecordTTL)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestLogChanges(t *testing.T) {
+	hook := test.NewGlobal()
+	defer hook.Reset()
+
+	logChanges(&plan.Changes{}, 42)
+	require.Len(t, hook.AllEntries(), 1)
+	assert.Equal(t, "All 42 records are already up to date", hook.LastEntry().Message)
+	assert.Equal(t, log.InfoLevel, hook.LastEntry().Level)
+
+	hook.Reset()
+	logChanges(&plan.Changes{
+		Create:    []*endpoint.Endpoint{endpoint.NewEndpointWithTTL("new.example.org", endpoint.RecordTypeA, 300, "1.2.3.4")},
+		UpdateOld: []*endpoint.Endpoint{endpoint.NewEndpoint("www.example.org", endpoint.RecordTypeCNAME, "old.example.com").WithSetIdentifier("eu")},
+		UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpointWithTTL("www.example.org", endpoint.RecordTypeCNAME, 60, "new.example.com").WithSetIdentifier("eu")},
+		Delete:    []*endpoint.Endpoint{endpoint.NewEndpoint("gone.example.org", endpoint.RecordTypeA, "1.2.3.4", "4.3.2.1")},
+	}, 42)
+
+	entries := hook.AllEntries()
+	require.Len(t, entries, 4)
+	assert.Equal(t, "Applying 1 creations, 1 updates and 1 deletions", entries[0].Message)
+	assert.Equal(t, "Creating record", entries[1].Message)
+	assert.Equal(t, log.Fields{
+		"record":        "new.example.org",
+		"type":          "A",
+		"targets_after": "1.2.3.4",
+		"ttl_after":     int64(300),
+	}, entries[1].Data)
+	assert.Equal(t, "Updating record", entries[2].Message)
+	assert.Equal(t, log.Fields{
+		"record":         "www.example.org",
+		"type":           "CNAME",
+		"set_identifier": "eu",
+		"targets_before": "old.example.com",
+		"targets_after":  "new.example.com",
+		"ttl_after":      int64(60),
+	}, entries[2].Data)
+	assert.Equal(t, "Deleting record", entries[3].Message)
+	assert.Equal(t, log.Fields{
+		"record":         "gone.example.org",
+		"type":           "A",
+		"targets_before": "1.2.3.4;4.3.2.1",
+	}, entries[3].Data)
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+var danglingCNAMEsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "external_dns",
+		Subsystem: "controller",
+		Name:      "dangling_cnames_total",
+		Help:      "Number of creations and updates of CNAME records whose target doesn't exist.",
+	},
+	[]string{"pipeline"},
+)
+
+func init() {
+	prometheus.MustRegister(danglingCNAMEsTotal)
+}
+
+const (
+	// The reason of the events reporting CNAME records whose target doesn't exist
+	danglingCNAMEReason = "DanglingCNAME"
+	// The time the lookup of a single CNAME target may take
+	cnameTargetLookupTimeout = 5 * time.Second
+)
+
+// TargetResolver looks up the addresses of the targets of CNAME records, e.g. net.DefaultResolver.
+type TargetResolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// CNAMETargetCheck resolves the targets of the CNAME records which are about to be created or updated.
+// A CNAME pointing at a name which doesn't exist anymore, typically the hostname of a decommissioned
+// load balancer, is dangling: whoever gets the name next, e.g. by creating a load balancer of the same
+// name, takes over the subdomain. Dangling CNAMEs are logged, counted and reported to their resource and,
+// when rejecting, held back.
+type CNAMETargetCheck struct {
+	resolver TargetResolver
+	reject   bool
+}
+
+// NewCNAMETargetCheck creates a CNAMETargetCheck resolving the targets with the given resolver. When
+// reject is true, the changes of dangling CNAMEs aren't applied.
+func NewCNAMETargetCheck(resolver TargetResolver, reject bool) *CNAMETargetCheck {
+	return &CNAMETargetCheck{
+		resolver: resolver,
+		reject:   reject,
+	}
+}
+
+// danglingTargets returns the targets of a CNAME record which don't exist. Targets which are desired
+// names themselves are created by the same synchronization and aren't looked up. Lookups which fail
+// for other reasons than a missing name, e.g. timeouts, don't make a target dangling.
+func (c *CNAMETargetCheck) danglingTargets(ctx context.Context, ep *endpoint.Endpoint, desired map[string]bool) []string {
+	var dangling []string
+	for _, target := range ep.Targets {
+		name := strings.ToLower(strings.TrimSuffix(target, "."))
+		if desired[name] {
+			continue
+		}
+		// the name is looked up as an absolute name, so the search domains of the resolver aren't tried
+		lookupCtx, cancel := context.WithTimeout(ctx, cnameTargetLookupTimeout)
+		_, err := c.resolver.LookupHost(lookupCtx, name+".")
+		cancel()
+		if dnsErr, ok := err.(*net.DNSError); ok && dnsErr.IsNotFound {
+			dangling = append(dangling, target)
+			continue
+		}
+		if err != nil {
+			log.Debugf("Unable to resolve the target %s of %s: %v", target, ep.DNSName, err)
+		}
+	}
+	return dangling
+}
+
+// checkCNAMETargets reports the creations and updates of CNAME records whose targets don't exist and
+// drops them if the check rejects them.
+func (c *Controller) checkCNAMETargets(ctx context.Context, changes *plan.Changes, endpoints []*endpoint.Endpoint) (*plan.Changes, error) {
+	desired := map[string]bool{}
+	for _, ep := range endpoints {
+		desired[strings.ToLower(strings.TrimSuffix(ep.DNSName, "."))] = true
+	}
+
+	dangling := func(ep *endpoint.Endpoint) bool {
+		if ep.RecordType != endpoint.RecordTypeCNAME {
+			return false
+		}
+		targets := c.CNAMETargetCheck.danglingTargets(ctx, ep, desired)
+		if len(targets) == 0 {
+			return false
+		}
+
+		danglingCNAMEsTotal.WithLabelValues(c.pipeline).Inc()
+		message := fmt.Sprintf("The CNAME record %s points at %s which doesn't exist", ep.DNSName, strings.Join(targets, ", "))
+		if c.CNAMETargetCheck.reject {
+			message += ", it isn't applied"
+		}
+		resource := ep.Labels[endpoint.ResourceLabelKey]
+		if resource != "" {
+			log.Warnf("%s (%s)", message, resource)
+		} else {
+			log.Warn(message)
+		}
+		if c.EventRecorder != nil && resource != "" {
+			c.EventRecorder.RecordWarning(resource, danglingCNAMEReason, message)
+		}
+		return c.CNAMETargetCheck.reject
+	}
+
+	return changes.Filter(false, func(_, ep *endpoint.Endpoint) bool {
+		return !dangling(ep)
+	})
+}
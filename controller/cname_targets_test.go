@@ -0,0 +1,135 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/internal/testutils"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// staticTargetResolver resolves the names it knows, the other names don't exist.
+type staticTargetResolver struct {
+	hosts   map[string][]string
+	errors  map[string]error
+	lookups []string
+}
+
+func (r *staticTargetResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	r.lookups = append(r.lookups, host)
+	if err, ok := r.errors[host]; ok {
+		return nil, err
+	}
+	if addrs, ok := r.hosts[host]; ok {
+		return addrs, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+func newCNAMETargetCheckTest(reject bool) (*Controller, *recordingRegistry, *recordingEventRecorder, *staticTargetResolver) {
+	dangling := endpoint.NewEndpoint("old.example.org", endpoint.RecordTypeCNAME, "lb-1.elb.amazonaws.com")
+	dangling.Labels[endpoint.ResourceLabelKey] = "ingress/default/old"
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{
+		dangling,
+		endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeCNAME, "lb-2.elb.amazonaws.com"),
+		endpoint.NewEndpoint("www.example.org", endpoint.RecordTypeCNAME, "web.example.org"),
+		endpoint.NewEndpoint("web.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+		endpoint.NewEndpoint("slow.example.org", endpoint.RecordTypeCNAME, "slow.example.com"),
+	}, nil)
+	r := &recordingRegistry{}
+	recorder := &recordingEventRecorder{}
+	resolver := &staticTargetResolver{
+		hosts:  map[string][]string{"lb-2.elb.amazonaws.com.": {"1.2.3.4"}},
+		errors: map[string]error{"slow.example.com.": &net.DNSError{Err: "i/o timeout", Name: "slow.example.com", IsTimeout: true}},
+	}
+	ctrl := &Controller{
+		Source:           source,
+		Registry:         r,
+		Policy:           &plan.SyncPolicy{},
+		EventRecorder:    recorder,
+		CNAMETargetCheck: NewCNAMETargetCheck(resolver, reject),
+	}
+	return ctrl, r, recorder, resolver
+}
+
+func createdNames(changes *plan.Changes) []string {
+	var names []string
+	for _, ep := range changes.Create {
+		names = append(names, ep.DNSName)
+	}
+	return names
+}
+
+// TestCNAMETargetCheckWarn tests that dangling CNAMEs are reported but still applied when warning.
+func TestCNAMETargetCheckWarn(t *testing.T) {
+	ctrl, r, recorder, resolver := newCNAMETargetCheckTest(false)
+	before := testutil.ToFloat64(danglingCNAMEsTotal.WithLabelValues(""))
+
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	require.Len(t, r.applied, 1)
+	assert.ElementsMatch(t, []string{"old.example.org", "app.example.org", "www.example.org", "web.example.org", "slow.example.org"}, createdNames(r.applied[0]))
+	assert.Equal(t, []string{"ingress/default/old DanglingCNAME: The CNAME record old.example.org points at lb-1.elb.amazonaws.com which doesn't exist"}, recorder.warnings)
+	assert.Equal(t, before+1, testutil.ToFloat64(danglingCNAMEsTotal.WithLabelValues("")))
+	// the target created by the same synchronization isn't looked up
+	assert.ElementsMatch(t, []string{"lb-1.elb.amazonaws.com.", "lb-2.elb.amazonaws.com.", "slow.example.com."}, resolver.lookups)
+}
+
+// TestCNAMETargetCheckReject tests that dangling CNAMEs are held back when rejecting.
+func TestCNAMETargetCheckReject(t *testing.T) {
+	ctrl, r, recorder, _ := newCNAMETargetCheckTest(true)
+
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	require.Len(t, r.applied, 1)
+	assert.ElementsMatch(t, []string{"app.example.org", "www.example.org", "web.example.org", "slow.example.org"}, createdNames(r.applied[0]))
+	assert.Equal(t, []string{"ingress/default/old DanglingCNAME: The CNAME record old.example.org points at lb-1.elb.amazonaws.com which doesn't exist, it isn't applied"}, recorder.warnings)
+}
+
+func TestCNAMETargetCheckUpdates(t *testing.T) {
+	resolver := &staticTargetResolver{errors: map[string]error{"other.example.com.": errors.New("unexpected")}}
+	ctrl := &Controller{CNAMETargetCheck: NewCNAMETargetCheck(resolver, true)}
+	changes := &plan.Changes{
+		UpdateOld: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeCNAME, "lb-1.elb.amazonaws.com"),
+			endpoint.NewEndpoint("api.example.org", endpoint.RecordTypeCNAME, "lb-1.elb.amazonaws.com"),
+		},
+		UpdateNew: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeCNAME, "lb-3.elb.amazonaws.com"),
+			endpoint.NewEndpoint("api.example.org", endpoint.RecordTypeCNAME, "other.example.com"),
+		},
+		Delete: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("old.example.org", endpoint.RecordTypeCNAME, "lb-1.elb.amazonaws.com"),
+		},
+	}
+
+	checked, err := ctrl.checkCNAMETargets(context.Background(), changes, nil)
+	require.NoError(t, err)
+	require.Len(t, checked.UpdateNew, 1)
+	require.Len(t, checked.UpdateOld, 1)
+	assert.Equal(t, "api.example.org", checked.UpdateNew[0].DNSName)
+	assert.Equal(t, "api.example.org", checked.UpdateOld[0].DNSName)
+	assert.Equal(t, changes.Delete, checked.Delete)
+}
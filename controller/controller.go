@@ -18,11 +18,15 @@ package controller
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
 
+	"sigs.k8s.io/external-dns/endpoint"
 	"sigs.k8s.io/external-dns/plan"
 	"sigs.k8s.io/external-dns/provider"
 	"sigs.k8s.io/external-dns/registry"
@@ -30,37 +34,59 @@ import (
 )
 
 var (
-	registryErrorsTotal = prometheus.NewCounter(
+	registryErrorsTotal = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Namespace: "external_dns",
 			Subsystem: "registry",
 			Name:      "errors_total",
 			Help:      "Number of Registry errors.",
 		},
+		[]string{"pipeline"},
 	)
-	sourceErrorsTotal = prometheus.NewCounter(
+	sourceErrorsTotal = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Namespace: "external_dns",
 			Subsystem: "source",
 			Name:      "errors_total",
 			Help:      "Number of Source errors.",
 		},
+		[]string{"pipeline"},
 	)
-	sourceEndpointsTotal = prometheus.NewGauge(
+	sourceEndpointsTotal = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace: "external_dns",
 			Subsystem: "source",
 			Name:      "endpoints_total",
 			Help:      "Number of Endpoints in all sources",
 		},
+		[]string{"pipeline"},
 	)
-	registryEndpointsTotal = prometheus.NewGauge(
+	registryEndpointsTotal = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace: "external_dns",
 			Subsystem: "registry",
 			Name:      "endpoints_total",
 			Help:      "Number of Endpoints in the registry",
 		},
+		[]string{"pipeline"},
+	)
+	invalidEndpointsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "controller",
+			Name:      "invalid_endpoints_total",
+			Help:      "Number of Endpoints rejected because of invalid targets.",
+		},
+		[]string{"pipeline"},
+	)
+	zoneRecordsErrorsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "registry",
+			Name:      "zone_errors_total",
+			Help:      "Number of times the records of a zone couldn't be listed, the changes of the zone are skipped.",
+		},
+		[]string{"pipeline", "zone"},
 	)
 	deprecatedRegistryErrors = prometheus.NewCounter(
 		prometheus.CounterOpts{
@@ -83,10 +109,25 @@ func init() {
 	prometheus.MustRegister(sourceErrorsTotal)
 	prometheus.MustRegister(sourceEndpointsTotal)
 	prometheus.MustRegister(registryEndpointsTotal)
+	prometheus.MustRegister(invalidEndpointsTotal)
+	prometheus.MustRegister(zoneRecordsErrorsTotal)
 	prometheus.MustRegister(deprecatedRegistryErrors)
 	prometheus.MustRegister(deprecatedSourceErrors)
 }
 
+// AppliedRecordsWriter writes the records applied for resources back onto them, e.g. as annotations.
+type AppliedRecordsWriter interface {
+	// WriteAppliedRecords is called with the resources whose records changed and the records once the
+	// changes are applied.
+	WriteAppliedRecords(resources []string, endpoints []*endpoint.Endpoint, appliedAt time.Time)
+}
+
+// OwnershipPublisher publishes the ownership of the records of the registry, e.g. as Kubernetes resources.
+type OwnershipPublisher interface {
+	// PublishOwnership is called with the records of the registry, unless the records of a zone couldn't be listed.
+	PublishOwnership(records []*endpoint.Endpoint)
+}
+
 // Controller is responsible for orchestrating the different components.
 // It works in the following way:
 // * Ask the DNS provider for current list of endpoints.
@@ -98,48 +139,684 @@ type Controller struct {
 	Registry registry.Registry
 	// The policy that defines which changes to DNS records are allowed
 	Policy plan.Policy
+	// The record types which are managed, defaults to plan.DefaultManagedRecordTypes
+	ManagedRecordTypes []string
+	// Whether the provider materializes the health checks of the endpoints, otherwise their changes aren't planned
+	HealthChecks bool
 	// The interval between individual synchronizations
 	Interval time.Duration
+	// The time the old records of a renamed resource are kept after the new ones were created
+	RenameDeletionGracePeriod time.Duration
+	// The time the records of deleted resources are kept, tracked in the labels of the registry
+	OrphanDeletionGracePeriod time.Duration
+	// The time the changes are accumulated for before they are applied as one batch, e.g. when many
+	// resources are created at once
+	ChangeDebounce time.Duration
+	// When the first of the accumulated changes was held back, zero if there are none
+	debounceSince time.Time
+	debounceLock  sync.Mutex
+	// The old records of renamed resources waiting for deletion and when they were first held back
+	renamedDeletions     map[string]time.Time
+	renamedDeletionsLock sync.Mutex
+	// The model used to log the estimated impact of each plan, e.g. in dry-run mode, nil to disable it
+	ImpactModel *plan.ImpactModel
+	// The guard holding back the deletions of zones whose records are suddenly missing, nil to disable it
+	DeletionGuard *DeletionGuard
+	// The file the records and endpoints of every synchronization are written to, empty to disable it
+	StateDumpFile string
+	// The writer the applied records of the changed resources are passed to, nil to disable it
+	AppliedRecordsWriter AppliedRecordsWriter
+	// The publisher the records of the registry are passed to in every synchronization, nil to disable it
+	OwnershipPublisher OwnershipPublisher
+	// The lister of the subzones which are created and delegated by ZoneDelegator, nil to disable it
+	SubzoneLister SubzoneLister
+	// The provider creating the subzones of SubzoneLister and delegating them from their parent zones
+	ZoneDelegator provider.ZoneDelegator
+	// The subzones which were delegated
+	delegatedZones     map[string]bool
+	delegatedZonesLock sync.Mutex
+	// The metrics tracking the synchronization of each zone, nil to disable them
+	ZoneSyncMetrics *ZoneSyncMetrics
+	// The minimum and maximum TTL of the endpoints, zero for no bound
+	MinTTL time.Duration
+	MaxTTL time.Duration
+	// The endpoints whose TTL was clamped by the last synchronization
+	clampedTTLs     map[string]bool
+	clampedTTLsLock sync.Mutex
+	// The recorder the warnings about the endpoints of resources are reported to, nil to disable it
+	EventRecorder EventRecorder
+	// The endpoints rejected as invalid by the last synchronization
+	rejectedEndpoints     map[string]bool
+	rejectedEndpointsLock sync.Mutex
+	// The report of the DNS names produced by more than one resource, nil to disable it
+	DuplicateReport *DuplicateReport
+	// The lister of the DNS freezes holding back the changes of their names, nil to disable it
+	FreezeLister FreezeLister
+	// The timer of the changes of the resources whose records are applied, nil to disable the latency metric
+	ResourceChangeTimer ResourceChangeTimer
+	// The filters applied to the endpoints of the source before planning, nil to disable them
+	EndpointFilters *EndpointFilterChain
+	// The provider adjusting the endpoints to what it supports before planning, nil to disable it
+	EndpointAdjuster provider.EndpointAdjuster
+	// The provider validating the provider-specific properties of the endpoints, nil to disable it
+	ProviderSpecificValidator provider.ProviderSpecificValidator
+	// The endpoints whose provider-specific properties were rejected by the last synchronization
+	rejectedProviderSpecific     map[string]bool
+	rejectedProviderSpecificLock sync.Mutex
+	// The check of the targets of the CNAME records which are created or updated, nil to disable it
+	CNAMETargetCheck *CNAMETargetCheck
+	// The scanner of the owned CNAME records for subdomain takeover risks, nil to disable it
+	TakeoverScanner *TakeoverScanner
+	// The last change of each resource whose latency was observed
+	observedChanges     map[string]time.Time
+	observedChangesLock sync.Mutex
+	// The pipeline labeling the metrics, empty unless set with SetPipeline
+	pipeline string
+}
+
+// pipelineLabeler is implemented by the registries labeling their metrics with the pipeline.
+type pipelineLabeler interface {
+	SetPipeline(name string)
+}
+
+// SetPipeline labels the metrics of the controller, its components and its registry with the name of its
+// pipeline, so the metrics of the controllers of several pipelines of one process don't overwrite each
+// other. The components are labeled as they are set when it's called.
+func (c *Controller) SetPipeline(name string) {
+	c.pipeline = name
+	if c.DeletionGuard != nil {
+		c.DeletionGuard.pipeline = name
+	}
+	if c.DuplicateReport != nil {
+		c.DuplicateReport.pipeline = name
+	}
+	if c.EndpointFilters != nil {
+		c.EndpointFilters.pipeline = name
+	}
+	if c.ZoneSyncMetrics != nil {
+		c.ZoneSyncMetrics.pipeline = name
+	}
+	if c.TakeoverScanner != nil {
+		c.TakeoverScanner.pipeline = name
+	}
+	if registry, ok := c.Registry.(pipelineLabeler); ok {
+		registry.SetPipeline(name)
+	}
+}
+
+// SyncPlan is the outcome of the planning phase of a synchronization, see PlanChanges.
+type SyncPlan struct {
+	// The normalized records of the registry and the zones whose records couldn't be listed
+	Records    []*endpoint.Endpoint
+	ZoneErrors provider.ZoneErrors
+	// The endpoints of the sources, the endpoints each endpoint filter dropped and the normalized
+	// endpoints the changes were calculated for
+	SourceEndpoints []*endpoint.Endpoint
+	FilterTraces    []EndpointFilterTrace
+	Endpoints       []*endpoint.Endpoint
+	// The valid changes calculated from the records and endpoints, before the checks held back any of them
+	Planned *plan.Changes
+	// The changes to apply and the deletions of renamed records, which follow once the changes are
+	// applied. Debounced changes wait for the change debounce, nothing is applied.
+	Changes   *plan.Changes
+	Renamed   []*endpoint.Endpoint
+	Debounced bool
+	// The number of changes the DNS freezes held back
+	Frozen int
 }
 
 // RunOnce runs a single iteration of a reconciliation loop.
 func (c *Controller) RunOnce(ctx context.Context) error {
-	records, err := c.Registry.Records(ctx)
+	// the subzones are delegated first, so their records can be created in the same synchronization
+	if c.SubzoneLister != nil && c.ZoneDelegator != nil {
+		c.delegateSubzones(ctx)
+	}
+
+	syncPlan, err := c.PlanChanges(ctx)
+	if err != nil {
+		return err
+	}
+	records, changes, renamed, zoneErrors := syncPlan.Records, syncPlan.Changes, syncPlan.Renamed, syncPlan.ZoneErrors
+	if syncPlan.Debounced {
+		c.observeZoneSync(ctx, changes, zoneErrors)
+		return nil
+	}
+	ctx = context.WithValue(ctx, provider.RecordsContextKey, records)
+	// the changes held back by the freezes are reported once per synchronization, including the
+	// held back renamed deletions
+	frozen := syncPlan.Frozen
+	if c.FreezeLister != nil {
+		defer func() { frozenChanges.WithLabelValues(c.pipeline).Set(float64(frozen)) }()
+	}
+
+	logChanges(changes, len(records))
+	applyErr := c.Registry.ApplyChanges(ctx, changes)
+	pending, err := pendingChanges(changes, applyErr)
 	if err != nil {
-		registryErrorsTotal.Inc()
+		return err
+	}
+	c.observeZoneSync(ctx, pending, zoneErrors)
+	if applyErr != nil {
+		registryErrorsTotal.WithLabelValues(c.pipeline).Inc()
+		deprecatedRegistryErrors.Inc()
+		return applyErr
+	}
+	if c.AppliedRecordsWriter != nil {
+		if resources := changedResources(changes); len(resources) > 0 {
+			c.AppliedRecordsWriter.WriteAppliedRecords(resources, appliedEndpoints(records, changes), time.Now())
+		}
+	}
+	if c.ResourceChangeTimer != nil {
+		c.observeChangeLatency(changedResources(changes), time.Now())
+	}
+
+	deletions := c.dueRenamedDeletions(renamed, time.Now())
+	if deletions == nil {
+		return nil
+	}
+	if c.FreezeLister != nil {
+		var frozenDeletions int
+		deletions, frozenDeletions, err = c.holdBackFrozenChanges(deletions)
+		if err != nil {
+			return err
+		}
+		frozen += frozenDeletions
+	}
+	if len(deletions.Delete) == 0 {
+		return nil
+	}
+	logChanges(deletions, len(records))
+	err = c.Registry.ApplyChanges(ctx, deletions)
+	if err != nil {
+		registryErrorsTotal.WithLabelValues(c.pipeline).Inc()
 		deprecatedRegistryErrors.Inc()
 		return err
 	}
-	registryEndpointsTotal.Set(float64(len(records)))
+	c.renamedDeletionsLock.Lock()
+	for _, ep := range deletions.Delete {
+		delete(c.renamedDeletions, renamedDeletionKey(ep))
+	}
+	c.renamedDeletionsLock.Unlock()
+	return nil
+}
 
-	ctx = context.WithValue(ctx, provider.RecordsContextKey, records)
+// PlanChanges runs the planning phase of a synchronization: it lists the records and endpoints,
+// calculates the changes and holds back the changes which mustn't be applied yet. Nothing is applied,
+// the events and metrics of the planning are still reported, apart from the changes held back by the
+// DNS freezes, which RunOnce reports once the renamed deletions are held back as well.
+func (c *Controller) PlanChanges(ctx context.Context) (*SyncPlan, error) {
+	records, err := c.Registry.Records(ctx)
+	zoneErrors, partial := err.(provider.ZoneErrors)
+	if err != nil && !partial {
+		registryErrorsTotal.WithLabelValues(c.pipeline).Inc()
+		deprecatedRegistryErrors.Inc()
+		return nil, err
+	}
+	for _, zone := range zoneErrors.Zones() {
+		log.Errorf("Skipping the changes of zone %s: %v", zone, zoneErrors[zone])
+		zoneRecordsErrorsTotal.WithLabelValues(c.pipeline, zone).Inc()
+	}
+	registryEndpointsTotal.WithLabelValues(c.pipeline).Set(float64(len(records)))
+	// the ownership of the zones which failed is unknown, so it's published once all zones are listed again
+	if c.OwnershipPublisher != nil && len(zoneErrors) == 0 {
+		c.OwnershipPublisher.PublishOwnership(records)
+	}
 
 	endpoints, err := c.Source.Endpoints()
 	if err != nil {
-		sourceErrorsTotal.Inc()
+		sourceErrorsTotal.WithLabelValues(c.pipeline).Inc()
 		deprecatedSourceErrors.Inc()
-		return err
+		return nil, err
+	}
+	sourceEndpointsTotal.WithLabelValues(c.pipeline).Set(float64(len(endpoints)))
+	syncPlan := &SyncPlan{ZoneErrors: zoneErrors, SourceEndpoints: endpoints}
+	if c.EndpointFilters != nil {
+		endpoints, syncPlan.FilterTraces, err = c.EndpointFilters.Trace(ctx, endpoints)
+		if err != nil {
+			return nil, err
+		}
+		c.EndpointFilters.observe(syncPlan.FilterTraces)
+	}
+	if c.EndpointAdjuster != nil {
+		endpoints, err = c.EndpointAdjuster.AdjustEndpoints(ctx, endpoints)
+		if err != nil {
+			return nil, fmt.Errorf("failed to adjust the endpoints: %v", err)
+		}
 	}
-	sourceEndpointsTotal.Set(float64(len(endpoints)))
 
-	plan := &plan.Plan{
-		Policies: []plan.Policy{c.Policy},
-		Current:  records,
-		Desired:  endpoints,
+	if c.StateDumpFile != "" {
+		if err := WriteStateFile(c.StateDumpFile, newState(records, endpoints, zoneErrors, c.HealthChecks)); err != nil {
+			log.Warnf("Unable to write the state dump file: %v", err)
+		}
 	}
 
-	plan = plan.Calculate()
+	records, _ = normalizeEndpoints(c.pipeline, records, false)
+	endpoints, invalid := normalizeEndpoints(c.pipeline, endpoints, true)
+	c.clampTTLs(endpoints)
+	syncPlan.Records, syncPlan.Endpoints = records, endpoints
 
-	err = c.Registry.ApplyChanges(ctx, plan.Changes)
+	planned, rejected, err := calculateChanges(c.pipeline, c.Policy, c.ManagedRecordTypes, c.HealthChecks, records, endpoints, zoneErrors)
 	if err != nil {
-		registryErrorsTotal.Inc()
-		deprecatedRegistryErrors.Inc()
-		return err
+		return nil, err
 	}
-	return nil
+	c.reportInvalidEndpoints(append(invalid, rejected...))
+	syncPlan.Planned = planned
+	if c.ProviderSpecificValidator != nil {
+		if planned, err = c.rejectInvalidProviderSpecific(planned); err != nil {
+			return nil, err
+		}
+	}
+	if c.CNAMETargetCheck != nil {
+		if planned, err = c.checkCNAMETargets(ctx, planned, endpoints); err != nil {
+			return nil, err
+		}
+	}
+	if c.TakeoverScanner != nil {
+		if planned, err = c.scanForTakeovers(ctx, records, planned); err != nil {
+			return nil, err
+		}
+	}
+	if c.DuplicateReport != nil {
+		var dropped []*endpoint.Endpoint
+		if reporter, ok := c.Source.(source.DuplicateReporter); ok {
+			dropped = reporter.DroppedDuplicates()
+		}
+		c.DuplicateReport.Update(endpoints, dropped, records, planned)
+	}
+	if c.DeletionGuard != nil {
+		planned = c.DeletionGuard.HoldBack(records, planned, zoneErrors)
+	}
+
+	if c.ImpactModel != nil {
+		log.Infof("Estimated impact of the plan: %s", c.ImpactModel.Estimate(records, planned))
+	}
+
+	// Records of resources which get new records at the same time, e.g. on a hostname change, are
+	// deleted in a second phase once the new records exist, so the resource stays resolvable.
+	changes, renamed := c.splitRenamedDeletions(planned)
+	changes = c.deferOrphanedDeletions(changes, renamed, records, endpoints, time.Now())
+	syncPlan.Changes, syncPlan.Renamed = changes, renamed
+	if c.debounceChanges(changes, time.Now()) {
+		syncPlan.Debounced = true
+		return syncPlan, nil
+	}
+	if c.FreezeLister != nil {
+		if syncPlan.Changes, syncPlan.Frozen, err = c.holdBackFrozenChanges(changes); err != nil {
+			return nil, err
+		}
+	}
+	return syncPlan, nil
 }
 
-// Run runs RunOnce in a loop with a delay until stopChan receives a value.
+// calculateChanges calculates the plan of the normalized records and endpoints and drops the changes
+// which can't or mustn't be applied. The endpoints of the changes with invalid targets are returned
+// as well.
+func calculateChanges(pipeline string, policy plan.Policy, managedRecordTypes []string, healthChecks bool, records, endpoints []*endpoint.Endpoint, zoneErrors provider.ZoneErrors) (*plan.Changes, []invalidEndpoint, error) {
+	p := &plan.Plan{
+		Policies:       []plan.Policy{policy},
+		Current:        records,
+		Desired:        endpoints,
+		ManagedRecords: managedRecordTypes,
+		HealthChecks:   healthChecks,
+	}
+
+	changes, rejected, err := rejectInvalidChanges(pipeline, p.Calculate().Changes)
+	if err != nil {
+		return nil, nil, err
+	}
+	changes, err = skipFailedZones(changes, zoneErrors)
+	if err != nil {
+		return nil, nil, err
+	}
+	return changes, rejected, nil
+}
+
+// changedResources returns the sorted resources whose records are changed.
+func changedResources(changes *plan.Changes) []string {
+	seen := map[string]bool{}
+	var resources []string
+	for _, eps := range [][]*endpoint.Endpoint{changes.Create, changes.UpdateNew, changes.Delete} {
+		for _, ep := range eps {
+			resource := ep.Labels[endpoint.ResourceLabelKey]
+			if resource == "" || seen[resource] {
+				continue
+			}
+			seen[resource] = true
+			resources = append(resources, resource)
+		}
+	}
+	sort.Strings(resources)
+	return resources
+}
+
+// appliedEndpoints returns the records once the changes are applied, i.e. the records without the replaced
+// and deleted ones and with the created and updated ones. The changes which were held back, e.g. by a freeze
+// or the debounce, aren't part of the applied changes, so their endpoints aren't either.
+func appliedEndpoints(records []*endpoint.Endpoint, changes *plan.Changes) []*endpoint.Endpoint {
+	key := func(ep *endpoint.Endpoint) string {
+		return fmt.Sprintf("%s::%s::%s", ep.DNSName, ep.RecordType, ep.SetIdentifier)
+	}
+	removed := map[string]bool{}
+	for _, eps := range [][]*endpoint.Endpoint{changes.UpdateOld, changes.Delete} {
+		for _, ep := range eps {
+			removed[key(ep)] = true
+		}
+	}
+
+	applied := make([]*endpoint.Endpoint, 0, len(records)+len(changes.Create))
+	for _, ep := range records {
+		if !removed[key(ep)] {
+			applied = append(applied, ep)
+		}
+	}
+	applied = append(applied, changes.Create...)
+	return append(applied, changes.UpdateNew...)
+}
+
+// normalizeEndpoints brings the names and targets of the endpoints into their canonical form, see
+// endpoint.Normalize, so the records of the providers, which may return absolute, mixed-case, escaped or
+// Unicode names, compare equal to the desired endpoints of the sources. The over-long character strings
+// of desired TXT endpoints are split, see endpoint.SplitTXTTargets. Desired endpoints with names that can't
+// be normalized are dropped and returned, existing records are kept as they are.
+func normalizeEndpoints(pipeline string, endpoints []*endpoint.Endpoint, desired bool) ([]*endpoint.Endpoint, []invalidEndpoint) {
+	result := make([]*endpoint.Endpoint, 0, len(endpoints))
+	var invalid []invalidEndpoint
+	for _, ep := range endpoints {
+		if err := ep.Normalize(); err != nil {
+			if desired {
+				rejectInvalidEndpoint(pipeline, ep, err)
+				invalid = append(invalid, invalidEndpoint{endpoint: ep, err: err})
+				continue
+			}
+			log.Debugf("Keeping record %s as it is: %v", ep.DNSName, err)
+		}
+		if desired {
+			ep.SplitTXTTargets()
+		}
+		result = append(result, ep)
+	}
+	return result, invalid
+}
+
+// rejectInvalidChanges drops the creations and updates of endpoints with targets that are invalid for
+// their record type, so a single bad value doesn't fail the whole batch in the provider. The rejected
+// endpoints are returned along with the valid changes.
+func rejectInvalidChanges(pipeline string, changes *plan.Changes) (*plan.Changes, []invalidEndpoint, error) {
+	var rejected []invalidEndpoint
+	valid, err := changes.Filter(false, func(_, ep *endpoint.Endpoint) bool {
+		if err := ep.ValidateTargets(); err != nil {
+			rejectInvalidEndpoint(pipeline, ep, err)
+			rejected = append(rejected, invalidEndpoint{endpoint: ep, err: err})
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return valid, rejected, nil
+}
+
+// skipFailedZones drops the changes of the zones whose records couldn't be listed. Their records are
+// missing from the plan, which would otherwise delete the records owned by the controller.
+func skipFailedZones(changes *plan.Changes, zoneErrors provider.ZoneErrors) (*plan.Changes, error) {
+	if len(zoneErrors) == 0 {
+		return changes, nil
+	}
+
+	return changes.Filter(true, func(_, ep *endpoint.Endpoint) bool {
+		if zoneErrors.Contains(ep.DNSName) {
+			log.Debugf("Skipping change of %s (%s) because its zone couldn't be listed", ep.DNSName, ep.RecordType)
+			return false
+		}
+		return true
+	})
+}
+
+// The reason of the events reporting endpoints which were rejected as invalid
+const invalidEndpointReason = "InvalidEndpoint"
+
+// invalidEndpoint is a desired endpoint which was rejected and why.
+type invalidEndpoint struct {
+	endpoint *endpoint.Endpoint
+	err      error
+}
+
+// reportInvalidEndpoints reports the rejected endpoints to their resources the first time they are
+// rejected, so e.g. kubectl describe shows why the records of a resource are missing.
+func (c *Controller) reportInvalidEndpoints(invalid []invalidEndpoint) {
+	c.rejectedEndpointsLock.Lock()
+	defer c.rejectedEndpointsLock.Unlock()
+
+	rejected := map[string]bool{}
+	for _, i := range invalid {
+		resource := i.endpoint.Labels[endpoint.ResourceLabelKey]
+		key := fmt.Sprintf("%s::%v", resource, i.err)
+		rejected[key] = true
+		if c.rejectedEndpoints[key] || c.EventRecorder == nil || resource == "" {
+			continue
+		}
+		c.EventRecorder.RecordWarning(resource, invalidEndpointReason, i.err.Error())
+	}
+	// endpoints which are fixed are reported again if they break once more
+	c.rejectedEndpoints = rejected
+}
+
+func rejectInvalidEndpoint(pipeline string, ep *endpoint.Endpoint, err error) {
+	invalidEndpointsTotal.WithLabelValues(pipeline).Inc()
+	if resource := ep.Labels[endpoint.ResourceLabelKey]; resource != "" {
+		log.Warnf("Skipping endpoint of %s: %v", resource, err)
+		return
+	}
+	log.Warnf("Skipping endpoint: %v", err)
+}
+
+// splitRenamedDeletions separates the deletions of records whose resource gets new records in the
+// same plan, or which are already waiting for the rename grace period, from the other changes.
+func (c *Controller) splitRenamedDeletions(changes *plan.Changes) (*plan.Changes, []*endpoint.Endpoint) {
+	created := map[string]bool{}
+	for _, ep := range changes.Create {
+		if resource := ep.Labels[endpoint.ResourceLabelKey]; resource != "" {
+			created[resource] = true
+		}
+	}
+
+	c.renamedDeletionsLock.Lock()
+	defer c.renamedDeletionsLock.Unlock()
+
+	var deletions, renamed []*endpoint.Endpoint
+	for _, ep := range changes.Delete {
+		_, pending := c.renamedDeletions[renamedDeletionKey(ep)]
+		if pending || created[ep.Labels[endpoint.ResourceLabelKey]] {
+			renamed = append(renamed, ep)
+			continue
+		}
+		deletions = append(deletions, ep)
+	}
+	if len(renamed) == 0 {
+		return changes, nil
+	}
+
+	return &plan.Changes{
+		Create:    changes.Create,
+		UpdateNew: changes.UpdateNew,
+		UpdateOld: changes.UpdateOld,
+		Delete:    deletions,
+	}, renamed
+}
+
+// dueRenamedDeletions returns the deletions of renamed records whose grace period has passed, if any, and
+// keeps track of the others. Records which aren't waiting for deletion anymore are forgotten.
+func (c *Controller) dueRenamedDeletions(renamed []*endpoint.Endpoint, now time.Time) *plan.Changes {
+	c.renamedDeletionsLock.Lock()
+	defer c.renamedDeletionsLock.Unlock()
+
+	pending := make(map[string]time.Time, len(renamed))
+	var due []*endpoint.Endpoint
+	for _, ep := range renamed {
+		key := renamedDeletionKey(ep)
+		since, ok := c.renamedDeletions[key]
+		if !ok {
+			since = now
+		}
+		pending[key] = since
+		switch {
+		case now.Sub(since) >= c.RenameDeletionGracePeriod:
+			due = append(due, ep)
+		case !ok:
+			log.Infof("Keeping %s (%s) of renamed resource %s until %s", ep.DNSName, ep.RecordType, ep.Labels[endpoint.ResourceLabelKey], since.Add(c.RenameDeletionGracePeriod).Format(time.RFC3339))
+		default:
+			log.Debugf("Keeping %s (%s) of renamed resource %s until %s", ep.DNSName, ep.RecordType, ep.Labels[endpoint.ResourceLabelKey], since.Add(c.RenameDeletionGracePeriod).Format(time.RFC3339))
+		}
+	}
+	c.renamedDeletions = pending
+	if len(due) == 0 {
+		return nil
+	}
+	return &plan.Changes{Delete: due}
+}
+
+func renamedDeletionKey(ep *endpoint.Endpoint) string {
+	return fmt.Sprintf("%s::%s::%s", ep.DNSName, ep.RecordType, ep.SetIdentifier)
+}
+
+// deferOrphanedDeletions keeps the records of deleted resources for the orphan deletion grace period.
+// The time a record is first kept is stored in its labels with an update, so it survives restarts, and
+// it's deleted once the grace period has passed. If the resource is back by then, i.e. the record is
+// desired again, the label is removed. The label of records which are neither desired nor deleted, e.g.
+// because the deletion is held back, is kept.
+func (c *Controller) deferOrphanedDeletions(changes *plan.Changes, renamed, records, desired []*endpoint.Endpoint, now time.Time) *plan.Changes {
+	if c.OrphanDeletionGracePeriod <= 0 {
+		return changes
+	}
+
+	deferred := &plan.Changes{
+		Create:    changes.Create,
+		UpdateNew: append([]*endpoint.Endpoint{}, changes.UpdateNew...),
+		UpdateOld: append([]*endpoint.Endpoint{}, changes.UpdateOld...),
+	}
+	handled := map[string]bool{}
+	for _, ep := range append(append([]*endpoint.Endpoint{}, renamed...), changes.UpdateOld...) {
+		handled[renamedDeletionKey(ep)] = true
+	}
+
+	for _, ep := range changes.Delete {
+		handled[renamedDeletionKey(ep)] = true
+		since, pending := deletionPendingSince(ep)
+		switch {
+		case !pending:
+			marked := ep.DeepCopy()
+			if marked.Labels == nil {
+				marked.Labels = endpoint.NewLabels()
+			}
+			marked.Labels[endpoint.DeletionPendingLabelKey] = now.UTC().Format(time.RFC3339)
+			deferred.UpdateOld = append(deferred.UpdateOld, ep)
+			deferred.UpdateNew = append(deferred.UpdateNew, marked)
+			log.Infof("Keeping %s (%s) of deleted resource %s until %s", ep.DNSName, ep.RecordType, ep.Labels[endpoint.ResourceLabelKey], now.Add(c.OrphanDeletionGracePeriod).Format(time.RFC3339))
+		case now.Sub(since) >= c.OrphanDeletionGracePeriod:
+			deferred.Delete = append(deferred.Delete, ep)
+		default:
+			log.Debugf("Keeping %s (%s) of deleted resource %s until %s", ep.DNSName, ep.RecordType, ep.Labels[endpoint.ResourceLabelKey], since.Add(c.OrphanDeletionGracePeriod).Format(time.RFC3339))
+		}
+	}
+
+	wanted := map[string]bool{}
+	for _, ep := range desired {
+		wanted[renamedDeletionKey(ep)] = true
+	}
+	for _, ep := range records {
+		if _, ok := ep.Labels[endpoint.DeletionPendingLabelKey]; !ok || handled[renamedDeletionKey(ep)] || !wanted[renamedDeletionKey(ep)] {
+			continue
+		}
+		unmarked := ep.DeepCopy()
+		delete(unmarked.Labels, endpoint.DeletionPendingLabelKey)
+		deferred.UpdateOld = append(deferred.UpdateOld, ep)
+		deferred.UpdateNew = append(deferred.UpdateNew, unmarked)
+		log.Infof("Cancelling the deletion of %s (%s), its resource is back", ep.DNSName, ep.RecordType)
+	}
+	return deferred
+}
+
+// deletionPendingSince returns since when the resource of a record is gone, if it's known.
+func deletionPendingSince(ep *endpoint.Endpoint) (time.Time, bool) {
+	value, ok := ep.Labels[endpoint.DeletionPendingLabelKey]
+	if !ok {
+		return time.Time{}, false
+	}
+	since, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		log.Warnf("Ignoring invalid label %s=%s of %s (%s): %v", endpoint.DeletionPendingLabelKey, value, ep.DNSName, ep.RecordType, err)
+		return time.Time{}, false
+	}
+	return since, true
+}
+
+func (c *Controller) observeZoneSync(ctx context.Context, pending *plan.Changes, zoneErrors provider.ZoneErrors) {
+	if c.ZoneSyncMetrics != nil {
+		c.ZoneSyncMetrics.Observe(ctx, pending, zoneErrors, time.Now())
+	}
+}
+
+// pendingChanges returns the changes which weren't applied because ApplyChanges failed. If the provider
+// names the zones whose changes failed, the changes of the other zones were applied.
+func pendingChanges(changes *plan.Changes, err error) (*plan.Changes, error) {
+	if err == nil {
+		return &plan.Changes{}, nil
+	}
+	zoneErrors, partial := err.(provider.ZoneErrors)
+	if !partial {
+		return changes, nil
+	}
+
+	return changes.Filter(true, func(_, ep *endpoint.Endpoint) bool {
+		return zoneErrors.Contains(ep.DNSName)
+	})
+}
+
+// debounceChanges returns true if the changes are held back until the change debounce has passed since
+// the first of them was planned. They are recalculated by every synchronization in the meantime, so the
+// changes of all of them are applied together and the provider can batch them per zone.
+func (c *Controller) debounceChanges(changes *plan.Changes, now time.Time) bool {
+	if c.ChangeDebounce <= 0 {
+		return false
+	}
+
+	c.debounceLock.Lock()
+	defer c.debounceLock.Unlock()
+
+	count := len(changes.Create) + len(changes.UpdateNew) + len(changes.Delete)
+	if count == 0 {
+		c.debounceSince = time.Time{}
+		return false
+	}
+	if c.debounceSince.IsZero() {
+		c.debounceSince = now
+	}
+	if now.Sub(c.debounceSince) < c.ChangeDebounce {
+		log.Infof("Holding back %d changes until %s to apply them together", count, c.debounceSince.Add(c.ChangeDebounce).Format(time.RFC3339))
+		return true
+	}
+	c.debounceSince = time.Time{}
+	return false
+}
+
+// debounceRemaining returns the time until the held back changes are due, zero if there are none.
+func (c *Controller) debounceRemaining(now time.Time) time.Duration {
+	c.debounceLock.Lock()
+	defer c.debounceLock.Unlock()
+
+	if c.debounceSince.IsZero() {
+		return 0
+	}
+	if remaining := c.debounceSince.Add(c.ChangeDebounce).Sub(now); remaining > 0 {
+		return remaining
+	}
+	// already due, e.g. after a slow synchronization
+	return time.Nanosecond
+}
+
+// Run runs RunOnce in a loop with a delay until stopChan receives a value. Held back changes are
+// applied once their debounce has passed, even if that's before the next interval.
 func (c *Controller) Run(ctx context.Context, stopChan <-chan struct{}) {
 	ticker := time.NewTicker(c.Interval)
 	defer ticker.Stop()
@@ -148,8 +825,13 @@ func (c *Controller) Run(ctx context.Context, stopChan <-chan struct{}) {
 		if err != nil {
 			log.Error(err)
 		}
+		var debounced <-chan time.Time
+		if remaining := c.debounceRemaining(time.Now()); remaining > 0 {
+			debounced = time.After(remaining)
+		}
 		select {
 		case <-ticker.C:
+		case <-debounced:
 		case <-stopChan:
 			log.Info("Terminating main controller loop")
 			return
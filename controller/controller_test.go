@@ -20,9 +20,12 @@ import (
 	"context"
 	"errors"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
 	"sigs.k8s.io/external-dns/endpoint"
 	"sigs.k8s.io/external-dns/internal/testutils"
 	"sigs.k8s.io/external-dns/plan"
@@ -153,6 +156,634 @@ func TestRunOnce(t *testing.T) {
 	source.AssertExpectations(t)
 }
 
+// recordingRegistry returns the given records and records the changes it is asked to apply.
+type recordingRegistry struct {
+	records []*endpoint.Endpoint
+	err     error
+	applied []*plan.Changes
+}
+
+func (r *recordingRegistry) Records(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	return r.records, r.err
+}
+
+func (r *recordingRegistry) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	r.applied = append(r.applied, changes)
+	return nil
+}
+
+func newRenameTest() (*testutils.MockSource, *recordingRegistry) {
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{
+		{
+			DNSName:    "new-record",
+			RecordType: endpoint.RecordTypeA,
+			Targets:    endpoint.Targets{"1.2.3.4"},
+			Labels:     endpoint.Labels{endpoint.ResourceLabelKey: "ingress/default/app"},
+		},
+	}, nil)
+
+	r := &recordingRegistry{
+		records: []*endpoint.Endpoint{
+			{
+				DNSName:    "old-record",
+				RecordType: endpoint.RecordTypeA,
+				Targets:    endpoint.Targets{"1.2.3.4"},
+				Labels:     endpoint.Labels{endpoint.ResourceLabelKey: "ingress/default/app"},
+			},
+			{
+				DNSName:    "unrelated-record",
+				RecordType: endpoint.RecordTypeA,
+				Targets:    endpoint.Targets{"4.3.2.1"},
+				Labels:     endpoint.Labels{endpoint.ResourceLabelKey: "ingress/default/other"},
+			},
+		},
+	}
+	return source, r
+}
+
+// TestRunOnceRename tests that the old records of a renamed resource are deleted after the new ones are created.
+func TestRunOnceRename(t *testing.T) {
+	source, r := newRenameTest()
+	ctrl := &Controller{
+		Source:   source,
+		Registry: r,
+		Policy:   &plan.SyncPolicy{},
+	}
+
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+
+	require.Len(t, r.applied, 2)
+	require.Len(t, r.applied[0].Create, 1)
+	assert.Equal(t, "new-record", r.applied[0].Create[0].DNSName)
+	require.Len(t, r.applied[0].Delete, 1)
+	assert.Equal(t, "unrelated-record", r.applied[0].Delete[0].DNSName)
+	assert.Empty(t, r.applied[1].Create)
+	require.Len(t, r.applied[1].Delete, 1)
+	assert.Equal(t, "old-record", r.applied[1].Delete[0].DNSName)
+	assert.Empty(t, ctrl.renamedDeletions)
+}
+
+// TestRunOnceRenameGracePeriod tests that the old records of a renamed resource are kept for the grace period.
+func TestRunOnceRenameGracePeriod(t *testing.T) {
+	source, r := newRenameTest()
+	ctrl := &Controller{
+		Source:                    source,
+		Registry:                  r,
+		Policy:                    &plan.SyncPolicy{},
+		RenameDeletionGracePeriod: time.Hour,
+	}
+
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+
+	require.Len(t, r.applied, 1)
+	require.Len(t, r.applied[0].Create, 1)
+	require.Len(t, r.applied[0].Delete, 1)
+	assert.Equal(t, "unrelated-record", r.applied[0].Delete[0].DNSName)
+	require.Contains(t, ctrl.renamedDeletions, "old-record::A::")
+
+	// once the new record exists, the old one is still held back as it is already waiting
+	oldRecord := r.records[0]
+	changes, renamed := ctrl.splitRenamedDeletions(&plan.Changes{Delete: []*endpoint.Endpoint{oldRecord}})
+	assert.Empty(t, changes.Delete)
+	require.Len(t, renamed, 1)
+
+	since := ctrl.renamedDeletions["old-record::A::"]
+	assert.Nil(t, ctrl.dueRenamedDeletions(renamed, since.Add(time.Minute)))
+	due := ctrl.dueRenamedDeletions(renamed, since.Add(time.Hour))
+	require.NotNil(t, due)
+	assert.Equal(t, []*endpoint.Endpoint{oldRecord}, due.Delete)
+}
+
+// TestRunOnceOrphanDeletionGracePeriod tests that the records of deleted resources are marked instead of deleted.
+func TestRunOnceOrphanDeletionGracePeriod(t *testing.T) {
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{}, nil)
+	r := &recordingRegistry{
+		records: []*endpoint.Endpoint{
+			{
+				DNSName:    "orphan-record",
+				RecordType: endpoint.RecordTypeA,
+				Targets:    endpoint.Targets{"1.2.3.4"},
+				Labels:     endpoint.Labels{endpoint.ResourceLabelKey: "ingress/deleted/app"},
+			},
+		},
+	}
+	ctrl := &Controller{
+		Source:                    source,
+		Registry:                  r,
+		Policy:                    &plan.SyncPolicy{},
+		OrphanDeletionGracePeriod: time.Hour,
+	}
+
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+
+	require.Len(t, r.applied, 1)
+	assert.Empty(t, r.applied[0].Delete)
+	require.Len(t, r.applied[0].UpdateOld, 1)
+	assert.Equal(t, r.records[0], r.applied[0].UpdateOld[0])
+	require.Len(t, r.applied[0].UpdateNew, 1)
+	since, pending := deletionPendingSince(r.applied[0].UpdateNew[0])
+	require.True(t, pending)
+	assert.WithinDuration(t, time.Now(), since, time.Minute)
+	assert.NotContains(t, r.records[0].Labels, endpoint.DeletionPendingLabelKey)
+}
+
+func TestDeferOrphanedDeletions(t *testing.T) {
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	pending := func(name, since string) *endpoint.Endpoint {
+		return &endpoint.Endpoint{
+			DNSName:    name,
+			RecordType: endpoint.RecordTypeA,
+			Targets:    endpoint.Targets{"1.2.3.4"},
+			Labels:     endpoint.Labels{endpoint.DeletionPendingLabelKey: since},
+		}
+	}
+	waiting := pending("waiting-record", "2020-06-01T11:30:00Z")
+	due := pending("due-record", "2020-06-01T11:00:00Z")
+	invalid := pending("invalid-record", "yesterday")
+	back := pending("back-record", "2020-06-01T11:30:00Z")
+	renamed := pending("renamed-record", "2020-06-01T11:30:00Z")
+	// the deletion of a record neither desired nor deleted, e.g. because it's held back, stays pending
+	held := pending("held-record", "2020-06-01T11:30:00Z")
+	ctrl := &Controller{OrphanDeletionGracePeriod: time.Hour}
+
+	changes := ctrl.deferOrphanedDeletions(
+		&plan.Changes{Delete: []*endpoint.Endpoint{waiting, due, invalid}},
+		[]*endpoint.Endpoint{renamed},
+		[]*endpoint.Endpoint{waiting, due, invalid, back, renamed, held},
+		[]*endpoint.Endpoint{endpoint.NewEndpoint("back-record", endpoint.RecordTypeA, "1.2.3.4")},
+		now,
+	)
+
+	assert.Equal(t, []*endpoint.Endpoint{due}, changes.Delete)
+	assert.Equal(t, []*endpoint.Endpoint{invalid, back}, changes.UpdateOld)
+	require.Len(t, changes.UpdateNew, 2)
+	assert.Equal(t, "2020-06-01T12:00:00Z", changes.UpdateNew[0].Labels[endpoint.DeletionPendingLabelKey])
+	assert.Equal(t, "back-record", changes.UpdateNew[1].DNSName)
+	assert.NotContains(t, changes.UpdateNew[1].Labels, endpoint.DeletionPendingLabelKey)
+	assert.Contains(t, back.Labels, endpoint.DeletionPendingLabelKey)
+
+	// without a grace period the changes are left alone
+	ctrl = &Controller{}
+	deletions := &plan.Changes{Delete: []*endpoint.Endpoint{waiting}}
+	assert.Equal(t, deletions, ctrl.deferOrphanedDeletions(deletions, nil, []*endpoint.Endpoint{back}, nil, now))
+}
+
+// TestRunOnceChangeDebounce tests that the changes are held back for the change debounce.
+func TestRunOnceChangeDebounce(t *testing.T) {
+	source, r := newRenameTest()
+	ctrl := &Controller{
+		Source:         source,
+		Registry:       r,
+		Policy:         &plan.SyncPolicy{},
+		ChangeDebounce: time.Hour,
+	}
+
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+
+	assert.Empty(t, r.applied)
+	assert.Empty(t, ctrl.renamedDeletions)
+	remaining := ctrl.debounceRemaining(time.Now())
+	assert.True(t, remaining > 59*time.Minute && remaining <= time.Hour)
+}
+
+func TestRunOnceZoneSyncMetrics(t *testing.T) {
+	source, r := newRenameTest()
+	ctrl := &Controller{
+		Source:          source,
+		Registry:        r,
+		Policy:          &plan.SyncPolicy{},
+		ChangeDebounce:  time.Hour,
+		ZoneSyncMetrics: NewZoneSyncMetrics(nil, nil),
+	}
+
+	// the creation and the unrelated deletion are held back, the renamed deletion waits for its grace period
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	_, pending := zoneSyncMetrics("")
+	assert.Equal(t, float64(2), pending)
+
+	ctrl.ChangeDebounce = 0
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	synced, pending := zoneSyncMetrics("")
+	assert.Equal(t, float64(0), pending)
+	assert.InDelta(t, float64(time.Now().Unix()), synced, 60)
+}
+
+// TestPendingChanges tests that only the changes of the zones named by the error of ApplyChanges are pending.
+func TestPendingChanges(t *testing.T) {
+	changes := &plan.Changes{
+		Create:    []*endpoint.Endpoint{endpoint.NewEndpoint("a.example.org", endpoint.RecordTypeA, "1.2.3.4"), endpoint.NewEndpoint("a.other.org", endpoint.RecordTypeA, "1.2.3.4")},
+		UpdateOld: []*endpoint.Endpoint{endpoint.NewEndpoint("b.other.org", endpoint.RecordTypeA, "1.2.3.4"), endpoint.NewEndpoint("b.example.org", endpoint.RecordTypeA, "1.2.3.4")},
+		UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpoint("b.other.org", endpoint.RecordTypeA, "4.3.2.1"), endpoint.NewEndpoint("b.example.org", endpoint.RecordTypeA, "4.3.2.1")},
+		Delete:    []*endpoint.Endpoint{endpoint.NewEndpoint("c.other.org", endpoint.RecordTypeA, "1.2.3.4")},
+	}
+
+	pending, err := pendingChanges(changes, nil)
+	require.NoError(t, err)
+	assert.Equal(t, &plan.Changes{}, pending)
+	pending, err = pendingChanges(changes, errors.New("failed"))
+	require.NoError(t, err)
+	assert.Equal(t, changes, pending)
+	pending, err = pendingChanges(changes, provider.ZoneErrors{"example.org.": errors.New("failed")})
+	require.NoError(t, err)
+	assert.Equal(t, &plan.Changes{
+		Create:    changes.Create[:1],
+		UpdateOld: changes.UpdateOld[1:],
+		UpdateNew: changes.UpdateNew[1:],
+	}, pending)
+
+	// the updates can't be paired if an old or new version is missing
+	changes.UpdateOld = changes.UpdateOld[1:]
+	_, err = pendingChanges(changes, provider.ZoneErrors{"example.org.": errors.New("failed")})
+	assert.EqualError(t, err, "unable to pair 1 old with 2 new versions of the updates")
+}
+
+// TestRunOnceSetPipeline tests that the metrics of the controllers of several pipelines don't overwrite each other.
+func TestRunOnceSetPipeline(t *testing.T) {
+	source, r := newRenameTest()
+	public := &Controller{Source: source, Registry: r, Policy: &plan.SyncPolicy{}}
+	public.SetPipeline("public")
+	require.NoError(t, public.RunOnce(context.Background()))
+
+	empty := new(testutils.MockSource)
+	empty.On("Endpoints").Return([]*endpoint.Endpoint{}, nil)
+	internal := &Controller{Source: empty, Registry: &recordingRegistry{}, Policy: &plan.SyncPolicy{}}
+	internal.SetPipeline("internal")
+	require.NoError(t, internal.RunOnce(context.Background()))
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(sourceEndpointsTotal.WithLabelValues("public")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(registryEndpointsTotal.WithLabelValues("public")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(sourceEndpointsTotal.WithLabelValues("internal")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(registryEndpointsTotal.WithLabelValues("internal")))
+}
+
+func TestDebounceChanges(t *testing.T) {
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	changes := &plan.Changes{Create: []*endpoint.Endpoint{{DNSName: "new-record", RecordType: endpoint.RecordTypeA}}}
+	ctrl := &Controller{ChangeDebounce: 30 * time.Second}
+
+	assert.True(t, ctrl.debounceChanges(changes, now))
+	assert.Equal(t, 30*time.Second, ctrl.debounceRemaining(now))
+	assert.True(t, ctrl.debounceChanges(changes, now.Add(20*time.Second)))
+	assert.Equal(t, 10*time.Second, ctrl.debounceRemaining(now.Add(20*time.Second)))
+	assert.False(t, ctrl.debounceChanges(changes, now.Add(30*time.Second)))
+	assert.Equal(t, time.Duration(0), ctrl.debounceRemaining(now.Add(30*time.Second)))
+
+	// changes which vanish before they are due aren't waited for anymore
+	assert.True(t, ctrl.debounceChanges(changes, now.Add(time.Minute)))
+	assert.False(t, ctrl.debounceChanges(&plan.Changes{}, now.Add(70*time.Second)))
+	assert.Equal(t, time.Duration(0), ctrl.debounceRemaining(now.Add(70*time.Second)))
+
+	// without a debounce the changes are applied at once
+	ctrl = &Controller{}
+	assert.False(t, ctrl.debounceChanges(changes, now))
+}
+
+type recordingAppliedRecordsWriter struct {
+	resources []string
+	endpoints []*endpoint.Endpoint
+}
+
+func (w *recordingAppliedRecordsWriter) WriteAppliedRecords(resources []string, endpoints []*endpoint.Endpoint, appliedAt time.Time) {
+	w.resources = append(w.resources, resources...)
+	w.endpoints = endpoints
+}
+
+func endpointNames(endpoints []*endpoint.Endpoint) []string {
+	var names []string
+	for _, ep := range endpoints {
+		names = append(names, ep.DNSName)
+	}
+	return names
+}
+
+// TestRunOnceAppliedRecordsWriter tests that the resources whose records changed are passed to the writer.
+func TestRunOnceAppliedRecordsWriter(t *testing.T) {
+	source, r := newRenameTest()
+	w := &recordingAppliedRecordsWriter{}
+	ctrl := &Controller{
+		Source:               source,
+		Registry:             r,
+		Policy:               &plan.SyncPolicy{},
+		AppliedRecordsWriter: w,
+	}
+
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+
+	assert.Equal(t, []string{"ingress/default/app", "ingress/default/other"}, w.resources)
+	// the old record of the renamed resource is only deleted once the new one exists
+	assert.ElementsMatch(t, []string{"old-record", "new-record"}, endpointNames(w.endpoints))
+
+	// nothing is written if nothing changed
+	w.resources = nil
+	r.records = []*endpoint.Endpoint{w.endpoints[1]}
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	assert.Empty(t, w.resources)
+}
+
+// TestRunOnceAppliedRecordsWriterFrozen tests that the records of held back changes aren't written.
+func TestRunOnceAppliedRecordsWriterFrozen(t *testing.T) {
+	source, r := newRenameTest()
+	w := &recordingAppliedRecordsWriter{}
+	ctrl := &Controller{
+		Source:               source,
+		Registry:             r,
+		Policy:               &plan.SyncPolicy{},
+		AppliedRecordsWriter: w,
+		FreezeLister:         &staticFreezeLister{freezes: map[string][]string{"incident-42": {"new-record"}}},
+	}
+
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+
+	assert.Equal(t, []string{"ingress/default/other"}, w.resources)
+	assert.Equal(t, []string{"old-record"}, endpointNames(w.endpoints))
+}
+
+type recordingOwnershipPublisher struct {
+	records [][]*endpoint.Endpoint
+}
+
+func (p *recordingOwnershipPublisher) PublishOwnership(records []*endpoint.Endpoint) {
+	p.records = append(p.records, records)
+}
+
+// TestRunOnceOwnershipPublisher tests that the records are published unless the records of a zone are missing.
+func TestRunOnceOwnershipPublisher(t *testing.T) {
+	source, r := newRenameTest()
+	p := &recordingOwnershipPublisher{}
+	ctrl := &Controller{
+		Source:             source,
+		Registry:           r,
+		Policy:             &plan.SyncPolicy{},
+		OwnershipPublisher: p,
+	}
+
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	require.Len(t, p.records, 1)
+	assert.Equal(t, r.records, p.records[0])
+
+	r.err = provider.ZoneErrors{"broken.org.": errors.New("500 Internal Server Error")}
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	assert.Len(t, p.records, 1)
+}
+
+type endpointAdjusterFunc func(ctx context.Context, endpoints []*endpoint.Endpoint) ([]*endpoint.Endpoint, error)
+
+func (f endpointAdjusterFunc) AdjustEndpoints(ctx context.Context, endpoints []*endpoint.Endpoint) ([]*endpoint.Endpoint, error) {
+	return f(ctx, endpoints)
+}
+
+// TestRunOnceEndpointAdjuster tests that the changes are planned for the endpoints adjusted by the provider.
+func TestRunOnceEndpointAdjuster(t *testing.T) {
+	source, r := newRenameTest()
+	var adjustErr error
+	ctrl := &Controller{
+		Source:   source,
+		Registry: r,
+		Policy:   &plan.UpsertOnlyPolicy{},
+		EndpointAdjuster: endpointAdjusterFunc(func(ctx context.Context, endpoints []*endpoint.Endpoint) ([]*endpoint.Endpoint, error) {
+			for _, ep := range endpoints {
+				ep.RecordTTL = 300
+			}
+			return endpoints, adjustErr
+		}),
+	}
+
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	require.Len(t, r.applied, 1)
+	require.Len(t, r.applied[0].Create, 1)
+	assert.Equal(t, "new-record", r.applied[0].Create[0].DNSName)
+	assert.Equal(t, endpoint.TTL(300), r.applied[0].Create[0].RecordTTL)
+
+	adjustErr = errors.New("connection refused")
+	assert.EqualError(t, ctrl.RunOnce(context.Background()), "failed to adjust the endpoints: connection refused")
+	assert.Len(t, r.applied, 1)
+}
+
+// TestRunOnceInvalidTargets tests that endpoints with invalid targets are not passed to the registry
+// and reported to their resource once, and that long TXT content is split instead.
+func TestRunOnceInvalidTargets(t *testing.T) {
+	invalid := endpoint.NewEndpoint("invalid-record", endpoint.RecordTypeA, "not-an-ip")
+	invalid.Labels[endpoint.ResourceLabelKey] = "ingress/default/app"
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{
+		endpoint.NewEndpoint("valid-record", endpoint.RecordTypeA, "1.2.3.4"),
+		invalid,
+		endpoint.NewEndpoint("update-record", endpoint.RecordTypeCNAME, "1.2.3.4"),
+		endpoint.NewEndpoint("dkim-record", endpoint.RecordTypeTXT, strings.Repeat("k", 300)),
+	}, nil)
+	r := &recordingRegistry{
+		records: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("update-record", endpoint.RecordTypeCNAME, "lb.example.org"),
+		},
+	}
+	recorder := &recordingEventRecorder{}
+	ctrl := &Controller{
+		Source:             source,
+		Registry:           r,
+		Policy:             &plan.SyncPolicy{},
+		ManagedRecordTypes: []string{endpoint.RecordTypeA, endpoint.RecordTypeCNAME, endpoint.RecordTypeTXT},
+		EventRecorder:      recorder,
+	}
+
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+
+	require.Len(t, r.applied, 1)
+	assert.ElementsMatch(t, []string{"valid-record", "dkim-record"}, createdNames(r.applied[0]))
+	for _, ep := range r.applied[0].Create {
+		if ep.DNSName == "dkim-record" {
+			assert.Equal(t, endpoint.Targets{`"` + strings.Repeat("k", 255) + `" "` + strings.Repeat("k", 45) + `"`}, ep.Targets)
+		}
+	}
+	assert.Empty(t, r.applied[0].UpdateNew)
+	assert.Empty(t, r.applied[0].UpdateOld)
+	assert.Empty(t, r.applied[0].Delete)
+
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	assert.Equal(t, []string{`ingress/default/app InvalidEndpoint: A record invalid-record has invalid target "not-an-ip": not an IPv4 address`}, recorder.warnings)
+}
+
+type providerSpecificValidatorFunc func(ep *endpoint.Endpoint) error
+
+func (f providerSpecificValidatorFunc) ValidateProviderSpecific(ep *endpoint.Endpoint) error {
+	return f(ep)
+}
+
+// TestRunOnceInvalidProviderSpecific tests that the creations and updates of endpoints with invalid
+// provider-specific properties are dropped and reported to their resource once.
+func TestRunOnceInvalidProviderSpecific(t *testing.T) {
+	invalid := endpoint.NewEndpoint("invalid-record", endpoint.RecordTypeA, "1.2.3.4").WithProviderSpecific("aws/wieght", "10")
+	invalid.Labels[endpoint.ResourceLabelKey] = "crd/default/invalid"
+	update := endpoint.NewEndpoint("update-record", endpoint.RecordTypeA, "1.2.3.4").WithProviderSpecific("aws/weight", "256")
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{
+		endpoint.NewEndpoint("valid-record", endpoint.RecordTypeA, "1.2.3.4"),
+		invalid,
+		update,
+	}, nil)
+	r := &recordingRegistry{
+		records: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("update-record", endpoint.RecordTypeA, "1.2.3.4"),
+		},
+	}
+	recorder := &recordingEventRecorder{}
+	ctrl := &Controller{
+		Source:        source,
+		Registry:      r,
+		Policy:        &plan.SyncPolicy{},
+		EventRecorder: recorder,
+		ProviderSpecificValidator: providerSpecificValidatorFunc(func(ep *endpoint.Endpoint) error {
+			if len(ep.ProviderSpecific) > 0 {
+				return errors.New("invalid " + ep.ProviderSpecific[0].Name)
+			}
+			return nil
+		}),
+	}
+
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	require.Len(t, r.applied, 1)
+	require.Len(t, r.applied[0].Create, 1)
+	assert.Equal(t, "valid-record", r.applied[0].Create[0].DNSName)
+	assert.Empty(t, r.applied[0].UpdateNew)
+	assert.Empty(t, r.applied[0].UpdateOld)
+	assert.Empty(t, r.applied[0].Delete)
+	assert.Equal(t, []string{"crd/default/invalid InvalidProviderSpecific: invalid aws/wieght"}, recorder.warnings)
+
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	assert.Len(t, recorder.warnings, 1)
+}
+
+// TestRunOnceInternationalizedNames tests that Unicode hostnames match the punycode records of the provider.
+func TestRunOnceInternationalizedNames(t *testing.T) {
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{
+		endpoint.NewEndpoint("bücher.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+		endpoint.NewEndpoint("www.bücher.example.org", endpoint.RecordTypeCNAME, "bücher.example.org"),
+		endpoint.NewEndpoint("neu.bücher.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+	}, nil)
+	r := &recordingRegistry{
+		records: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("xn--bcher-kva.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+			endpoint.NewEndpoint("www.xn--bcher-kva.example.org", endpoint.RecordTypeCNAME, "xn--bcher-kva.example.org"),
+		},
+	}
+	ctrl := &Controller{
+		Source:   source,
+		Registry: r,
+		Policy:   &plan.SyncPolicy{},
+	}
+
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+
+	require.Len(t, r.applied, 1)
+	require.Len(t, r.applied[0].Create, 1)
+	assert.Equal(t, "neu.xn--bcher-kva.example.org", r.applied[0].Create[0].DNSName)
+	assert.Empty(t, r.applied[0].UpdateNew)
+	assert.Empty(t, r.applied[0].Delete)
+}
+
+// TestRunOnceNormalizedNames tests that absolute and mixed-case names of the provider don't cause updates.
+func TestRunOnceNormalizedNames(t *testing.T) {
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{
+		endpoint.NewEndpoint("foo.example.org", endpoint.RecordTypeCNAME, "lb.example.org"),
+		endpoint.NewEndpoint("*.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+	}, nil)
+	r := &recordingRegistry{
+		records: []*endpoint.Endpoint{
+			{DNSName: "Foo.Example.org.", RecordType: endpoint.RecordTypeCNAME, Targets: endpoint.Targets{"LB.example.org."}},
+			{DNSName: `\052.example.org.`, RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}},
+		},
+	}
+	ctrl := &Controller{
+		Source:   source,
+		Registry: r,
+		Policy:   &plan.SyncPolicy{},
+	}
+
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+
+	require.Len(t, r.applied, 1)
+	assert.Empty(t, r.applied[0].Create)
+	assert.Empty(t, r.applied[0].UpdateNew)
+	assert.Empty(t, r.applied[0].Delete)
+}
+
+// TestRunOnceZoneErrors tests that the zones whose records couldn't be listed are left alone while the others are synchronized.
+func TestRunOnceZoneErrors(t *testing.T) {
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{
+		endpoint.NewEndpoint("new.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+		endpoint.NewEndpoint("new.broken.org", endpoint.RecordTypeA, "1.2.3.4"),
+		endpoint.NewEndpoint("update.broken.org", endpoint.RecordTypeA, "1.2.3.4"),
+	}, nil)
+	r := &recordingRegistry{
+		records: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("old.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+			endpoint.NewEndpoint("update.broken.org", endpoint.RecordTypeA, "4.3.2.1"),
+		},
+		err: provider.ZoneErrors{"broken.org.": errors.New("500 Internal Server Error")},
+	}
+	ctrl := &Controller{
+		Source:   source,
+		Registry: r,
+		Policy:   &plan.SyncPolicy{},
+	}
+
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+
+	require.Len(t, r.applied, 1)
+	require.Len(t, r.applied[0].Create, 1)
+	assert.Equal(t, "new.example.org", r.applied[0].Create[0].DNSName)
+	assert.Empty(t, r.applied[0].UpdateNew)
+	assert.Empty(t, r.applied[0].UpdateOld)
+	require.Len(t, r.applied[0].Delete, 1)
+	assert.Equal(t, "old.example.org", r.applied[0].Delete[0].DNSName)
+}
+
+// TestRunOnceDeletionGuard tests that the deletions are held back when the records suddenly disappear.
+func TestRunOnceDeletionGuard(t *testing.T) {
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{}, nil)
+	r := &recordingRegistry{}
+	ctrl := &Controller{
+		Source:        source,
+		Registry:      r,
+		Policy:        &plan.SyncPolicy{},
+		DeletionGuard: NewDeletionGuard([]string{"example.org"}, 0.5, 1, 1),
+	}
+
+	r.records = []*endpoint.Endpoint{
+		endpoint.NewEndpoint("a.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+		endpoint.NewEndpoint("b.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+		endpoint.NewEndpoint("c.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+	}
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	r.records = r.records[:1]
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+
+	require.Len(t, r.applied, 3)
+	assert.Len(t, r.applied[0].Delete, 3)
+	assert.Empty(t, r.applied[1].Delete)
+	assert.Len(t, r.applied[2].Delete, 1)
+}
+
+// TestRunOnceRegistryError tests that other errors of the registry still abort the synchronization.
+func TestRunOnceRegistryError(t *testing.T) {
+	source := new(testutils.MockSource)
+	r := &recordingRegistry{err: errors.New("registry error")}
+	ctrl := &Controller{
+		Source:   source,
+		Registry: r,
+		Policy:   &plan.SyncPolicy{},
+	}
+
+	assert.EqualError(t, ctrl.RunOnce(context.Background()), "registry error")
+	assert.Empty(t, r.applied)
+}
+
 // TestSourceEventHandler tests that the Controller can use a Source's registered handler as a callback.
 func TestSourceEventHandler(t *testing.T) {
 	source := new(testutils.MockSource)
@@ -193,3 +824,54 @@ func TestSourceEventHandler(t *testing.T) {
 	close(handlerCh)
 	close(timeoutCh)
 }
+
+type recordingEventRecorder struct {
+	warnings []string
+}
+
+func (r *recordingEventRecorder) RecordWarning(resource, reason, message string) {
+	r.warnings = append(r.warnings, resource+" "+reason+": "+message)
+}
+
+func TestClampTTLs(t *testing.T) {
+	newEndpoints := func() []*endpoint.Endpoint {
+		endpoints := []*endpoint.Endpoint{
+			endpoint.NewEndpointWithTTL("short.example.org", endpoint.RecordTypeA, 1, "1.2.3.4"),
+			endpoint.NewEndpointWithTTL("long.example.org", endpoint.RecordTypeA, 86400, "1.2.3.4"),
+			endpoint.NewEndpointWithTTL("ok.example.org", endpoint.RecordTypeA, 300, "1.2.3.4"),
+			endpoint.NewEndpoint("default.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+		}
+		for _, ep := range endpoints {
+			ep.Labels[endpoint.ResourceLabelKey] = "ingress/default/" + strings.Split(ep.DNSName, ".")[0]
+		}
+		return endpoints
+	}
+	recorder := &recordingEventRecorder{}
+	ctrl := &Controller{MinTTL: time.Minute, MaxTTL: time.Hour, EventRecorder: recorder}
+
+	endpoints := newEndpoints()
+	ctrl.clampTTLs(endpoints)
+	var ttls []endpoint.TTL
+	for _, ep := range endpoints {
+		ttls = append(ttls, ep.RecordTTL)
+	}
+	assert.Equal(t, []endpoint.TTL{60, 3600, 300, 0}, ttls)
+	assert.Equal(t, []string{
+		"ingress/default/short TTLClamped: The TTL 1 of short.example.org (A) was changed to 60 to stay within the range of 1m0s to 1h0m0s",
+		"ingress/default/long TTLClamped: The TTL 86400 of long.example.org (A) was changed to 3600 to stay within the range of 1m0s to 1h0m0s",
+	}, recorder.warnings)
+
+	// the clamped endpoints are only reported once
+	ctrl.clampTTLs(newEndpoints())
+	assert.Len(t, recorder.warnings, 2)
+
+	// unless they were fixed in the meantime
+	ctrl.clampTTLs(newEndpoints()[1:])
+	ctrl.clampTTLs(newEndpoints())
+	assert.Len(t, recorder.warnings, 3)
+
+	// without bounds the TTLs are kept
+	endpoints = newEndpoints()
+	(&Controller{}).clampTTLs(endpoints)
+	assert.Equal(t, endpoint.TTL(1), endpoints[0].RecordTTL)
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+var deletionsHeldBack = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Namespace: "external_dns",
+		Subsystem: "controller",
+		Name:      "deletions_held_back",
+		Help:      "Whether the deletions of a domain are held back because its number of records dropped suspiciously.",
+	},
+	[]string{"pipeline", "zone"},
+)
+
+func init() {
+	prometheus.MustRegister(deletionsHeldBack)
+}
+
+// DeletionGuard protects against providers which transiently list none or only a fraction of the
+// records of a zone, which would make the plan delete all the missing records owned by the controller.
+// It keeps the number of records of each domain of the domain filter; the records outside of them are
+// counted together. When the number drops by more than the threshold, the deletions of the domain are
+// held back for the configured number of synchronizations. If the records aren't listed again by then,
+// the new number is accepted and the deletions are applied.
+type DeletionGuard struct {
+	zones      []string
+	threshold  float64
+	minRecords int
+	cycles     int
+	// The pipeline labeling the metrics, see Controller.SetPipeline
+	pipeline string
+
+	lock sync.Mutex
+	// The accepted number of records of each domain
+	baselines map[string]int
+	// The number of synchronizations the deletions of each domain have been held back
+	heldBack map[string]int
+}
+
+// NewDeletionGuard creates a DeletionGuard for the given domains. The deletions of a domain are held
+// back when it had at least minRecords records and the number dropped by more than threshold, e.g. 0.5.
+func NewDeletionGuard(zones []string, threshold float64, minRecords, cycles int) *DeletionGuard {
+	normalized := []string{}
+	for _, zone := range zones {
+		zone = strings.ToLower(strings.Trim(zone, "."))
+		if zone != "" {
+			normalized = append(normalized, zone)
+		}
+	}
+	sort.Strings(normalized)
+
+	return &DeletionGuard{
+		zones:      normalized,
+		threshold:  threshold,
+		minRecords: minRecords,
+		cycles:     cycles,
+		baselines:  map[string]int{},
+		heldBack:   map[string]int{},
+	}
+}
+
+// HoldBack compares the number of current records of each domain with the previous synchronizations and
+// drops the deletions of the domains whose number dropped suspiciously from the changes. Domains whose
+// records couldn't be listed are left alone, their changes are skipped anyway.
+func (g *DeletionGuard) HoldBack(records []*endpoint.Endpoint, changes *plan.Changes, zoneErrors provider.ZoneErrors) *plan.Changes {
+	counts := map[string]int{}
+	for _, ep := range records {
+		counts[g.zoneOf(ep.DNSName)]++
+	}
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	blocked := map[string]bool{}
+	for _, zone := range append([]string{""}, g.zones...) {
+		if zone != "" && zoneErrors.Contains(zone) {
+			continue
+		}
+		count := counts[zone]
+		baseline, known := g.baselines[zone]
+		if known && baseline >= g.minRecords && float64(count) < float64(baseline)*(1-g.threshold) {
+			g.heldBack[zone]++
+			if g.heldBack[zone] <= g.cycles {
+				log.Errorf("The number of %s dropped from %d to %d, holding back their deletions (%d/%d)", g.describe(zone), baseline, count, g.heldBack[zone], g.cycles)
+				deletionsHeldBack.WithLabelValues(g.pipeline, zone).Set(1)
+				blocked[zone] = true
+				continue
+			}
+			log.Warnf("Accepting %d %s after holding back their deletions for %d synchronizations", count, g.describe(zone), g.cycles)
+		}
+		delete(g.heldBack, zone)
+		deletionsHeldBack.WithLabelValues(g.pipeline, zone).Set(0)
+		g.baselines[zone] = count
+	}
+	if len(blocked) == 0 {
+		return changes
+	}
+
+	var deletions []*endpoint.Endpoint
+	for _, ep := range changes.Delete {
+		if blocked[g.zoneOf(ep.DNSName)] {
+			log.Debugf("Holding back the deletion of %s (%s)", ep.DNSName, ep.RecordType)
+			continue
+		}
+		deletions = append(deletions, ep)
+	}
+	return &plan.Changes{
+		Create:    changes.Create,
+		UpdateNew: changes.UpdateNew,
+		UpdateOld: changes.UpdateOld,
+		Delete:    deletions,
+	}
+}
+
+// zoneOf returns the most specific domain a DNS name belongs to or "" if it isn't part of any.
+func (g *DeletionGuard) zoneOf(dnsName string) string {
+	name := strings.ToLower(strings.TrimSuffix(dnsName, "."))
+	match := ""
+	for _, zone := range g.zones {
+		if (name == zone || strings.HasSuffix(name, "."+zone)) && len(zone) > len(match) {
+			match = zone
+		}
+	}
+	return match
+}
+
+func (g *DeletionGuard) describe(zone string) string {
+	if zone == "" && len(g.zones) == 0 {
+		return "records"
+	}
+	if zone == "" {
+		return "records outside of the domain filter"
+	}
+	return "records of domain " + zone
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+func guardRecords(zone string, n int) []*endpoint.Endpoint {
+	records := make([]*endpoint.Endpoint, 0, n)
+	for i := 0; i < n; i++ {
+		records = append(records, endpoint.NewEndpoint(fmt.Sprintf("record-%d.%s", i, zone), endpoint.RecordTypeA, "1.2.3.4"))
+	}
+	return records
+}
+
+func guardDeletions(names ...string) *plan.Changes {
+	changes := &plan.Changes{}
+	for _, name := range names {
+		changes.Delete = append(changes.Delete, endpoint.NewEndpoint(name, endpoint.RecordTypeA, "1.2.3.4"))
+	}
+	return changes
+}
+
+func deletedNames(changes *plan.Changes) []string {
+	var names []string
+	for _, ep := range changes.Delete {
+		names = append(names, ep.DNSName)
+	}
+	return names
+}
+
+func TestDeletionGuardHoldsBackDeletions(t *testing.T) {
+	g := NewDeletionGuard([]string{"example.org", ".other.org."}, 0.5, 10, 2)
+	full := append(guardRecords("example.org", 20), guardRecords("other.org", 20)...)
+	changes := guardDeletions("record-1.example.org", "record-1.other.org")
+
+	assert.Equal(t, []string{"record-1.example.org", "record-1.other.org"}, deletedNames(g.HoldBack(full, changes, nil)))
+
+	// example.org suddenly lists only a few records
+	dropped := append(guardRecords("example.org", 2), guardRecords("other.org", 20)...)
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, []string{"record-1.other.org"}, deletedNames(g.HoldBack(dropped, changes, nil)))
+	}
+
+	// the new number of records is accepted after the configured number of synchronizations
+	assert.Equal(t, []string{"record-1.example.org", "record-1.other.org"}, deletedNames(g.HoldBack(dropped, changes, nil)))
+	assert.Equal(t, []string{"record-1.example.org", "record-1.other.org"}, deletedNames(g.HoldBack(dropped, changes, nil)))
+}
+
+func TestDeletionGuardRecovers(t *testing.T) {
+	g := NewDeletionGuard(nil, 0.5, 10, 3)
+	changes := guardDeletions("record-1.example.org")
+
+	g.HoldBack(guardRecords("example.org", 20), changes, nil)
+	assert.Empty(t, g.HoldBack(nil, changes, nil).Delete)
+	assert.Equal(t, []string{"record-1.example.org"}, deletedNames(g.HoldBack(guardRecords("example.org", 20), changes, nil)))
+
+	// a later drop is held back for the full number of synchronizations again
+	for i := 0; i < 3; i++ {
+		assert.Empty(t, g.HoldBack(nil, changes, nil).Delete)
+	}
+	assert.Equal(t, []string{"record-1.example.org"}, deletedNames(g.HoldBack(nil, changes, nil)))
+}
+
+func TestDeletionGuardIgnoresSmallZonesAndDrops(t *testing.T) {
+	g := NewDeletionGuard([]string{"example.org", "small.org"}, 0.5, 10, 3)
+	changes := guardDeletions("record-1.example.org", "record-1.small.org")
+
+	g.HoldBack(append(guardRecords("example.org", 20), guardRecords("small.org", 5)...), changes, nil)
+	assert.Equal(t, []string{"record-1.example.org", "record-1.small.org"}, deletedNames(g.HoldBack(guardRecords("example.org", 11), changes, nil)))
+}
+
+func TestDeletionGuardMostSpecificZone(t *testing.T) {
+	g := NewDeletionGuard([]string{"example.org", "sub.example.org"}, 0.5, 10, 3)
+	changes := guardDeletions("record-1.sub.example.org", "record-1.example.org", "record-1.unfiltered.com")
+
+	g.HoldBack(append(guardRecords("example.org", 20), guardRecords("sub.example.org", 20)...), changes, nil)
+	assert.Equal(t, []string{"record-1.example.org", "record-1.unfiltered.com"}, deletedNames(g.HoldBack(guardRecords("example.org", 20), changes, nil)))
+}
+
+func TestDeletionGuardSkipsFailedZones(t *testing.T) {
+	g := NewDeletionGuard([]string{"example.org"}, 0.5, 10, 1)
+	changes := guardDeletions("record-1.example.org")
+	zoneErrors := provider.ZoneErrors{"example.org": errors.New("500 Internal Server Error")}
+
+	g.HoldBack(guardRecords("example.org", 20), changes, nil)
+	for i := 0; i < 3; i++ {
+		g.HoldBack(nil, changes, zoneErrors)
+	}
+	// the failed synchronizations neither changed the baseline nor used up the held back synchronizations
+	assert.Empty(t, g.HoldBack(nil, changes, nil).Delete)
+}
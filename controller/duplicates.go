@@ -0,0 +1,183 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+const (
+	// duplicateKind is the kind of endpoints of several resources with the same targets
+	duplicateKind = "duplicate"
+	// conflictKind is the kind of endpoints of several resources with different targets
+	conflictKind = "conflict"
+)
+
+var duplicateEndpoints = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Namespace: "external_dns",
+		Subsystem: "controller",
+		Name:      "duplicate_endpoints",
+		Help:      "Number of DNS names produced by more than one resource in the last synchronization.",
+	},
+	[]string{"pipeline", "kind"},
+)
+
+func init() {
+	prometheus.MustRegister(duplicateEndpoints)
+}
+
+// DuplicateCandidate is one of the endpoints produced for a duplicated DNS name.
+type DuplicateCandidate struct {
+	// The resource which produced the endpoint, e.g. service/default/foo
+	Resource string           `json:"resource"`
+	Targets  endpoint.Targets `json:"targets"`
+	// Whether the endpoint was dropped as an exact duplicate before planning
+	Dropped bool `json:"dropped,omitempty"`
+}
+
+// DuplicateEntry is a DNS name produced by more than one resource.
+type DuplicateEntry struct {
+	DNSName       string `json:"dnsName"`
+	RecordType    string `json:"recordType"`
+	SetIdentifier string `json:"setIdentifier,omitempty"`
+	// Either "duplicate" if all the candidates have the same targets or "conflict" otherwise
+	Kind string `json:"kind"`
+	// The resource whose targets are published, empty if it isn't known yet
+	Winner     string               `json:"winner"`
+	Candidates []DuplicateCandidate `json:"candidates"`
+}
+
+// DuplicateReport tracks the DNS names produced by more than one resource, either as exact duplicates which
+// are merged by the sources or as conflicts which are resolved by the plan, and which resource won. The
+// number of each kind is exposed as a metric and the detailed report is served as JSON.
+type DuplicateReport struct {
+	sync.Mutex
+	entries []DuplicateEntry
+	// The pipeline labeling the metrics, see Controller.SetPipeline
+	pipeline string
+}
+
+// NewDuplicateReport creates an empty DuplicateReport.
+func NewDuplicateReport() *DuplicateReport {
+	return &DuplicateReport{entries: []DuplicateEntry{}}
+}
+
+// Update replaces the report with the duplicates of a synchronization. The dropped endpoints are the ones
+// removed by the sources before planning. The winner of a name is the first resource whose targets match
+// the ones planned for it, or the ones of the current records if there's no change.
+func (r *DuplicateReport) Update(endpoints, dropped, records []*endpoint.Endpoint, changes *plan.Changes) {
+	type key struct{ dnsName, recordType, setIdentifier string }
+	keyOf := func(ep *endpoint.Endpoint) key {
+		return key{strings.ToLower(strings.TrimSuffix(ep.DNSName, ".")), ep.RecordType, ep.SetIdentifier}
+	}
+
+	groups := map[key][]DuplicateCandidate{}
+	var keys []key
+	add := func(ep *endpoint.Endpoint, isDropped bool) {
+		k := keyOf(ep)
+		if _, ok := groups[k]; !ok {
+			keys = append(keys, k)
+		}
+		groups[k] = append(groups[k], DuplicateCandidate{Resource: ep.Labels[endpoint.ResourceLabelKey], Targets: ep.Targets, Dropped: isDropped})
+	}
+	for _, ep := range endpoints {
+		add(ep, false)
+	}
+	for _, ep := range dropped {
+		add(ep, true)
+	}
+
+	published := map[key]endpoint.Targets{}
+	for _, ep := range records {
+		published[keyOf(ep)] = ep.Targets
+	}
+	if changes != nil {
+		for _, ep := range append(append([]*endpoint.Endpoint{}, changes.Create...), changes.UpdateNew...) {
+			published[keyOf(ep)] = ep.Targets
+		}
+	}
+
+	entries := []DuplicateEntry{}
+	counts := map[string]int{duplicateKind: 0, conflictKind: 0}
+	for _, k := range keys {
+		candidates := groups[k]
+		if len(candidates) < 2 {
+			continue
+		}
+
+		entry := DuplicateEntry{DNSName: k.dnsName, RecordType: k.recordType, SetIdentifier: k.setIdentifier, Kind: duplicateKind, Candidates: candidates}
+		for _, candidate := range candidates[1:] {
+			if !candidate.Targets.Same(candidates[0].Targets) {
+				entry.Kind = conflictKind
+				break
+			}
+		}
+		if targets, ok := published[k]; ok {
+			for _, candidate := range candidates {
+				if candidate.Targets.Same(targets) {
+					entry.Winner = candidate.Resource
+					break
+				}
+			}
+		}
+
+		counts[entry.Kind]++
+		entries = append(entries, entry)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].DNSName != entries[j].DNSName {
+			return entries[i].DNSName < entries[j].DNSName
+		}
+		return entries[i].RecordType < entries[j].RecordType
+	})
+
+	for kind, count := range counts {
+		duplicateEndpoints.WithLabelValues(r.pipeline, kind).Set(float64(count))
+	}
+
+	r.Lock()
+	r.entries = entries
+	r.Unlock()
+}
+
+// Entries returns the duplicates of the last synchronization sorted by DNS name.
+func (r *DuplicateReport) Entries() []DuplicateEntry {
+	r.Lock()
+	defer r.Unlock()
+	return r.entries
+}
+
+// ServeHTTP serves the duplicates of the last synchronization as JSON.
+func (r *DuplicateReport) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	data, err := json.MarshalIndent(r.Entries(), "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
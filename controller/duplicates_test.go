@@ -0,0 +1,124 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func duplicateEndpoint(dnsName, resource string, targets ...string) *endpoint.Endpoint {
+	ep := endpoint.NewEndpoint(dnsName, endpoint.RecordTypeA, targets...)
+	ep.Labels[endpoint.ResourceLabelKey] = resource
+	return ep
+}
+
+func TestDuplicateReport(t *testing.T) {
+	endpoints := []*endpoint.Endpoint{
+		duplicateEndpoint("same.example.org", "service/default/a", "1.2.3.4"),
+		duplicateEndpoint("conflict.example.org", "service/default/a", "1.2.3.4"),
+		duplicateEndpoint("conflict.example.org.", "ingress/default/b", "5.6.7.8"),
+		duplicateEndpoint("unique.example.org", "service/default/a", "1.2.3.4"),
+		duplicateEndpoint("current.example.org", "service/default/a", "1.2.3.4"),
+		duplicateEndpoint("current.example.org", "ingress/default/b", "5.6.7.8"),
+	}
+	dropped := []*endpoint.Endpoint{
+		duplicateEndpoint("same.example.org", "ingress/default/b", "1.2.3.4"),
+	}
+	records := []*endpoint.Endpoint{
+		endpoint.NewEndpoint("current.example.org", endpoint.RecordTypeA, "5.6.7.8"),
+	}
+	changes := &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("same.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+			endpoint.NewEndpoint("conflict.example.org", endpoint.RecordTypeA, "5.6.7.8"),
+		},
+	}
+
+	r := NewDuplicateReport()
+	r.Update(endpoints, dropped, records, changes)
+
+	assert.Equal(t, []DuplicateEntry{
+		{
+			DNSName:    "conflict.example.org",
+			RecordType: endpoint.RecordTypeA,
+			Kind:       conflictKind,
+			Winner:     "ingress/default/b",
+			Candidates: []DuplicateCandidate{
+				{Resource: "service/default/a", Targets: endpoint.Targets{"1.2.3.4"}},
+				{Resource: "ingress/default/b", Targets: endpoint.Targets{"5.6.7.8"}},
+			},
+		},
+		{
+			DNSName:    "current.example.org",
+			RecordType: endpoint.RecordTypeA,
+			Kind:       conflictKind,
+			Winner:     "ingress/default/b",
+			Candidates: []DuplicateCandidate{
+				{Resource: "service/default/a", Targets: endpoint.Targets{"1.2.3.4"}},
+				{Resource: "ingress/default/b", Targets: endpoint.Targets{"5.6.7.8"}},
+			},
+		},
+		{
+			DNSName:    "same.example.org",
+			RecordType: endpoint.RecordTypeA,
+			Kind:       duplicateKind,
+			Winner:     "service/default/a",
+			Candidates: []DuplicateCandidate{
+				{Resource: "service/default/a", Targets: endpoint.Targets{"1.2.3.4"}},
+				{Resource: "ingress/default/b", Targets: endpoint.Targets{"1.2.3.4"}, Dropped: true},
+			},
+		},
+	}, r.Entries())
+	assert.Equal(t, 1.0, testutil.ToFloat64(duplicateEndpoints.WithLabelValues("", duplicateKind)))
+	assert.Equal(t, 2.0, testutil.ToFloat64(duplicateEndpoints.WithLabelValues("", conflictKind)))
+
+	// the report is replaced by the next synchronization
+	r.Update(endpoints[3:4], nil, records, &plan.Changes{})
+	assert.Empty(t, r.Entries())
+	assert.Equal(t, 0.0, testutil.ToFloat64(duplicateEndpoints.WithLabelValues("", duplicateKind)))
+	assert.Equal(t, 0.0, testutil.ToFloat64(duplicateEndpoints.WithLabelValues("", conflictKind)))
+}
+
+func TestDuplicateReportServeHTTP(t *testing.T) {
+	r := NewDuplicateReport()
+	r.Update([]*endpoint.Endpoint{
+		duplicateEndpoint("foo.example.org", "service/default/a", "1.2.3.4"),
+		duplicateEndpoint("foo.example.org", "ingress/default/b", "5.6.7.8"),
+	}, nil, nil, &plan.Changes{})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/duplicates", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	var entries []DuplicateEntry
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &entries))
+	require.Len(t, entries, 1)
+	assert.Equal(t, "foo.example.org", entries[0].DNSName)
+	assert.Equal(t, "", entries[0].Winner)
+	assert.Len(t, entries[0].Candidates, 2)
+}
@@ -0,0 +1,276 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+// The time a filter webhook has to answer
+const endpointFilterWebhookTimeout = 10 * time.Second
+
+var filteredEndpoints = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Namespace: "external_dns",
+		Subsystem: "controller",
+		Name:      "filtered_endpoints",
+		Help:      "Number of endpoints dropped by each endpoint filter in the last synchronization.",
+	},
+	[]string{"pipeline", "filter"},
+)
+
+func init() {
+	prometheus.MustRegister(filteredEndpoints)
+}
+
+// EndpointFilter filters the endpoints of the sources before the changes are planned.
+type EndpointFilter interface {
+	// Filter returns the endpoints to keep.
+	Filter(ctx context.Context, endpoints []*endpoint.Endpoint) ([]*endpoint.Endpoint, error)
+}
+
+// EndpointFilterChain applies its filters to the endpoints of the sources in order, each one to the
+// endpoints kept by the previous filters. The records of dropped endpoints are treated like the records
+// of deleted resources, so their owned records are deleted unless the policy prevents it.
+type EndpointFilterChain struct {
+	names   []string
+	filters []EndpointFilter
+	// The pipeline labeling the metrics, see Controller.SetPipeline
+	pipeline string
+}
+
+// NewEndpointFilterChain creates a chain of the filters of the given specs in order. A spec is the kind
+// of the filter and its value:
+//
+//	domain=example.org,example.com      keeps the endpoints of the domains and their subdomains
+//	exclude-domain=internal.example.org drops the endpoints of the domains and their subdomains
+//	regex=^api\.                        keeps the endpoints whose DNS name matches the expression
+//	exclude-regex=^test-                drops the endpoints whose DNS name matches the expression
+//	label=team=payments                 keeps the endpoints whose resources' labels match the selector
+//	webhook=http://filter:8080/filter   posts the endpoints as JSON and keeps the endpoints of the response
+//
+// The label filter matches the labels of the resource of an endpoint, e.g. of the Service or DNSEndpoint it
+// was generated from, listed with the given lister. The endpoints of resources the lister doesn't know,
+// or all endpoints if it's nil, are matched by their own labels.
+func NewEndpointFilterChain(specs []string, resources ResourceLabelLister) (*EndpointFilterChain, error) {
+	chain := &EndpointFilterChain{}
+	for _, spec := range specs {
+		filter, err := newEndpointFilter(spec, resources)
+		if err != nil {
+			return nil, err
+		}
+		chain.Append(spec, filter)
+	}
+	return chain, nil
+}
+
+// Append adds a filter to the end of the chain. Its name labels the metrics and logs of the filter.
+func (c *EndpointFilterChain) Append(name string, filter EndpointFilter) {
+	c.names = append(c.names, name)
+	c.filters = append(c.filters, filter)
+}
+
+// Filter returns the endpoints kept by all filters of the chain. The number of endpoints dropped by
+// each filter is exposed as a metric.
+func (c *EndpointFilterChain) Filter(ctx context.Context, endpoints []*endpoint.Endpoint) ([]*endpoint.Endpoint, error) {
+	filtered, traces, err := c.Trace(ctx, endpoints)
+	if err != nil {
+		return nil, err
+	}
+	c.observe(traces)
+	return filtered, nil
+}
+
+// observe exposes the number of endpoints dropped by each filter of the traces.
+func (c *EndpointFilterChain) observe(traces []EndpointFilterTrace) {
+	for _, trace := range traces {
+		if len(trace.Dropped) > 0 {
+			log.Debugf("Endpoint filter %s dropped %d endpoints", trace.Filter, len(trace.Dropped))
+		}
+		filteredEndpoints.WithLabelValues(c.pipeline, trace.Filter).Set(float64(len(trace.Dropped)))
+	}
+}
+
+// EndpointFilterTrace is the decision of a filter of the chain.
+type EndpointFilterTrace struct {
+	Filter  string
+	Dropped []*endpoint.Endpoint
+}
+
+// Trace returns the endpoints kept by all filters of the chain like Filter and the endpoints dropped by
+// each filter, e.g. to explain why the records of a hostname are missing.
+func (c *EndpointFilterChain) Trace(ctx context.Context, endpoints []*endpoint.Endpoint) ([]*endpoint.Endpoint, []EndpointFilterTrace, error) {
+	var traces []EndpointFilterTrace
+	for i, filter := range c.filters {
+		filtered, err := filter.Filter(ctx, endpoints)
+		if err != nil {
+			return nil, nil, fmt.Errorf("endpoint filter %s failed: %v", c.names[i], err)
+		}
+
+		// webhooks answer with copies, so the kept endpoints are compared by their names and targets
+		kept := map[string]bool{}
+		for _, ep := range filtered {
+			kept[endpointFilterKey(ep)] = true
+		}
+		trace := EndpointFilterTrace{Filter: c.names[i]}
+		for _, ep := range endpoints {
+			if !kept[endpointFilterKey(ep)] {
+				trace.Dropped = append(trace.Dropped, ep)
+			}
+		}
+		traces = append(traces, trace)
+		endpoints = filtered
+	}
+	return endpoints, traces, nil
+}
+
+func endpointFilterKey(ep *endpoint.Endpoint) string {
+	return ep.DNSName + " / " + ep.RecordType + " / " + ep.SetIdentifier + " / " + ep.Targets.String()
+}
+
+// ResourceLabelLister lists the labels of the resources of the endpoints by their resource labels, e.g.
+// service/default/api.
+type ResourceLabelLister interface {
+	// ResourceLabels returns the labels of the resource and whether its kind is known. The labels of a
+	// resource which is gone are empty.
+	ResourceLabels(resource string) (map[string]string, bool, error)
+}
+
+func newEndpointFilter(spec string, resources ResourceLabelLister) (EndpointFilter, error) {
+	parts := strings.SplitN(spec, "=", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, fmt.Errorf("invalid endpoint filter (kind=value) found '%v'", spec)
+	}
+	kind, value := parts[0], parts[1]
+
+	switch kind {
+	case "domain", "exclude-domain":
+		domains := provider.NewDomainFilter(strings.Split(strings.Replace(value, " ", "", -1), ","))
+		return newMatchEndpointFilter(domains.Match, kind == "exclude-domain"), nil
+	case "regex", "exclude-regex":
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid endpoint filter expression %q: %v", value, err)
+		}
+		return newMatchEndpointFilter(re.MatchString, kind == "exclude-regex"), nil
+	case "label":
+		selector, err := labels.Parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid endpoint filter selector %q: %v", value, err)
+		}
+		return EndpointFilterFunc(func(_ context.Context, endpoints []*endpoint.Endpoint) ([]*endpoint.Endpoint, error) {
+			var filtered []*endpoint.Endpoint
+			for _, ep := range endpoints {
+				matched := ep.Labels
+				if resources != nil {
+					resourceLabels, known, err := resources.ResourceLabels(ep.Labels[endpoint.ResourceLabelKey])
+					if err != nil {
+						return nil, err
+					}
+					if known {
+						matched = resourceLabels
+					}
+				}
+				if selector.Matches(labels.Set(matched)) {
+					filtered = append(filtered, ep)
+				}
+			}
+			return filtered, nil
+		}), nil
+	case "webhook":
+		if u, err := url.Parse(value); err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid endpoint filter webhook URL %q", value)
+		}
+		return &webhookEndpointFilter{url: value, client: &http.Client{Timeout: endpointFilterWebhookTimeout}}, nil
+	}
+	return nil, fmt.Errorf("unknown endpoint filter kind %q, options: domain, exclude-domain, regex, exclude-regex, label, webhook", kind)
+}
+
+// EndpointFilterFunc is an EndpointFilter implemented by a function.
+type EndpointFilterFunc func(ctx context.Context, endpoints []*endpoint.Endpoint) ([]*endpoint.Endpoint, error)
+
+// Filter calls the function.
+func (f EndpointFilterFunc) Filter(ctx context.Context, endpoints []*endpoint.Endpoint) ([]*endpoint.Endpoint, error) {
+	return f(ctx, endpoints)
+}
+
+// newMatchEndpointFilter returns a filter keeping the endpoints whose DNS name matches, or doesn't match
+// when excluding.
+func newMatchEndpointFilter(match func(string) bool, exclude bool) EndpointFilter {
+	return EndpointFilterFunc(func(_ context.Context, endpoints []*endpoint.Endpoint) ([]*endpoint.Endpoint, error) {
+		var filtered []*endpoint.Endpoint
+		for _, ep := range endpoints {
+			if match(strings.TrimSuffix(ep.DNSName, ".")) != exclude {
+				filtered = append(filtered, ep)
+			}
+		}
+		return filtered, nil
+	})
+}
+
+// webhookEndpointFilter posts the endpoints to an HTTP endpoint, which answers with the endpoints to
+// keep, e.g. a policy engine deciding which names a cluster may publish.
+type webhookEndpointFilter struct {
+	url    string
+	client *http.Client
+}
+
+func (f *webhookEndpointFilter) Filter(ctx context.Context, endpoints []*endpoint.Endpoint) ([]*endpoint.Endpoint, error) {
+	body, err := json.Marshal(endpoints)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var filtered []*endpoint.Endpoint
+	if err := json.NewDecoder(resp.Body).Decode(&filtered); err != nil {
+		return nil, fmt.Errorf("failed to decode the response: %v", err)
+	}
+	// dropping every endpoint deletes all the records, which is more likely a broken webhook than its decision
+	if len(filtered) == 0 && len(endpoints) > 0 {
+		return nil, fmt.Errorf("the webhook dropped all %d endpoints", len(endpoints))
+	}
+	return filtered, nil
+}
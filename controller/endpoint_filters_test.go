@@ -0,0 +1,228 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func dnsNames(endpoints []*endpoint.Endpoint) []string {
+	var names []string
+	for _, ep := range endpoints {
+		names = append(names, ep.DNSName)
+	}
+	return names
+}
+
+func TestEndpointFilterChain(t *testing.T) {
+	payments := endpoint.NewEndpoint("pay.example.org", endpoint.RecordTypeA, "1.2.3.4")
+	payments.Labels["team"] = "payments"
+	endpoints := []*endpoint.Endpoint{
+		payments,
+		endpoint.NewEndpoint("api.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+		endpoint.NewEndpoint("db.internal.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+		endpoint.NewEndpoint("test-api.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+		endpoint.NewEndpoint("api.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+	}
+
+	for _, tc := range []struct {
+		title    string
+		specs    []string
+		expected []string
+	}{
+		{
+			title:    "no filters",
+			expected: []string{"pay.example.org", "api.example.org", "db.internal.example.org", "test-api.example.org", "api.example.com"},
+		},
+		{
+			title:    "domain",
+			specs:    []string{"domain=example.org"},
+			expected: []string{"pay.example.org", "api.example.org", "db.internal.example.org", "test-api.example.org"},
+		},
+		{
+			title:    "domains and exclusions",
+			specs:    []string{"domain=example.org, example.com", "exclude-domain=internal.example.org", "exclude-regex=^test-"},
+			expected: []string{"pay.example.org", "api.example.org", "api.example.com"},
+		},
+		{
+			title:    "regex",
+			specs:    []string{`regex=^api\.`},
+			expected: []string{"api.example.org", "api.example.com"},
+		},
+		{
+			title:    "label",
+			specs:    []string{"label=team=payments"},
+			expected: []string{"pay.example.org"},
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			chain, err := NewEndpointFilterChain(tc.specs, nil)
+			require.NoError(t, err)
+
+			filtered, err := chain.Filter(context.Background(), endpoints)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, dnsNames(filtered))
+		})
+	}
+}
+
+func TestEndpointFilterChainMetrics(t *testing.T) {
+	chain, err := NewEndpointFilterChain([]string{"domain=example.org", "exclude-domain=internal.example.org"}, nil)
+	require.NoError(t, err)
+
+	_, err = chain.Filter(context.Background(), []*endpoint.Endpoint{
+		endpoint.NewEndpoint("api.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+		endpoint.NewEndpoint("db.internal.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+		endpoint.NewEndpoint("api.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(filteredEndpoints.WithLabelValues("", "domain=example.org")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(filteredEndpoints.WithLabelValues("", "exclude-domain=internal.example.org")))
+}
+
+type fakeResourceLabelLister map[string]map[string]string
+
+func (l fakeResourceLabelLister) ResourceLabels(resource string) (map[string]string, bool, error) {
+	if strings.HasPrefix(resource, "pod/") {
+		return nil, false, nil
+	}
+	return l[resource], true, nil
+}
+
+func TestEndpointFilterChainResourceLabels(t *testing.T) {
+	service := endpoint.NewEndpoint("api.example.org", endpoint.RecordTypeA, "1.2.3.4")
+	service.Labels[endpoint.ResourceLabelKey] = "service/default/api"
+	gone := endpoint.NewEndpoint("old.example.org", endpoint.RecordTypeA, "1.2.3.4")
+	gone.Labels[endpoint.ResourceLabelKey] = "service/default/old"
+	crd := endpoint.NewEndpoint("pay.example.org", endpoint.RecordTypeA, "1.2.3.4")
+	crd.Labels[endpoint.ResourceLabelKey] = "crd/default/pay"
+	crd.Labels["team"] = "payments"
+	pod := endpoint.NewEndpoint("pod.example.org", endpoint.RecordTypeA, "1.2.3.4")
+	pod.Labels[endpoint.ResourceLabelKey] = "pod/default/pod"
+	pod.Labels["team"] = "payments"
+
+	chain, err := NewEndpointFilterChain([]string{"label=team=payments"}, fakeResourceLabelLister{
+		"service/default/api": {"team": "payments"},
+		"crd/default/pay":     {"team": "search"},
+	})
+	require.NoError(t, err)
+
+	filtered, err := chain.Filter(context.Background(), []*endpoint.Endpoint{service, gone, crd, pod})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"api.example.org", "pod.example.org"}, dnsNames(filtered))
+}
+
+func TestEndpointFilterChainInvalidSpecs(t *testing.T) {
+	for _, spec := range []string{
+		"example.org",
+		"domain=",
+		"unknown=value",
+		"regex=[",
+		"label=team in (",
+		"webhook=filter",
+	} {
+		_, err := NewEndpointFilterChain([]string{spec}, nil)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestEndpointFilterChainWebhook(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var endpoints []*endpoint.Endpoint
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&endpoints))
+		require.Len(t, endpoints, 2)
+		require.NoError(t, json.NewEncoder(w).Encode(endpoints[1:]))
+	}))
+	defer server.Close()
+
+	chain, err := NewEndpointFilterChain([]string{"webhook=" + server.URL}, nil)
+	require.NoError(t, err)
+
+	filtered, err := chain.Filter(context.Background(), []*endpoint.Endpoint{
+		endpoint.NewEndpoint("api.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+		endpoint.NewEndpoint("www.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"www.example.org"}, dnsNames(filtered))
+}
+
+func TestEndpointFilterChainWebhookError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	chain, err := NewEndpointFilterChain([]string{"webhook=" + server.URL}, nil)
+	require.NoError(t, err)
+
+	_, err = chain.Filter(context.Background(), []*endpoint.Endpoint{
+		endpoint.NewEndpoint("api.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+	})
+	assert.Error(t, err)
+}
+
+func TestEndpointFilterChainWebhookEmpty(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+
+	chain, err := NewEndpointFilterChain([]string{"webhook=" + server.URL}, nil)
+	require.NoError(t, err)
+
+	_, err = chain.Filter(context.Background(), []*endpoint.Endpoint{
+		endpoint.NewEndpoint("api.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "the webhook dropped all 1 endpoints")
+
+	// without endpoints there is nothing to drop
+	filtered, err := chain.Filter(context.Background(), []*endpoint.Endpoint{})
+	require.NoError(t, err)
+	assert.Empty(t, filtered)
+}
+
+func TestEndpointFilterChainTrace(t *testing.T) {
+	chain, err := NewEndpointFilterChain([]string{"domain=example.org", "exclude-regex=^test-"}, nil)
+	require.NoError(t, err)
+
+	filtered, traces, err := chain.Trace(context.Background(), []*endpoint.Endpoint{
+		endpoint.NewEndpoint("api.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+		endpoint.NewEndpoint("test-api.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+		endpoint.NewEndpoint("api.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"api.example.org"}, dnsNames(filtered))
+	require.Len(t, traces, 2)
+	assert.Equal(t, "domain=example.org", traces[0].Filter)
+	assert.Equal(t, []string{"api.example.com"}, dnsNames(traces[0].Dropped))
+	assert.Equal(t, "exclude-regex=^test-", traces[1].Filter)
+	assert.Equal(t, []string{"test-api.example.org"}, dnsNames(traces[1].Dropped))
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+var frozenChanges = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Namespace: "external_dns",
+		Subsystem: "controller",
+		Name:      "frozen_changes",
+		Help:      "Number of changes held back by DNS freezes in the last synchronization.",
+	},
+	[]string{"pipeline"},
+)
+
+func init() {
+	prometheus.MustRegister(frozenChanges)
+}
+
+// FreezeLister lists the active DNS freezes, e.g. the DNSFreeze resources created by on-call during an
+// incident. The changes of frozen DNS names are planned but not applied until the freeze is lifted.
+type FreezeLister interface {
+	// Freezes returns the DNS name patterns of each active freeze by its name, e.g. *.example.org. A
+	// freeze without patterns holds back all changes.
+	Freezes() (map[string][]string, error)
+}
+
+// frozenSubzones returns the subzones whose delegation is held back by an active freeze, as delegating
+// them changes the NS records of the subzone in its parent zone. When the freezes can't be listed, all
+// delegations are held back.
+func (c *Controller) frozenSubzones(subzones []string) map[string]bool {
+	held := map[string]bool{}
+	freezes, err := c.FreezeLister.Freezes()
+	if err != nil {
+		log.Errorf("Holding back the delegation of all %d subzones because the DNS freezes couldn't be listed: %v", len(subzones), err)
+		for _, zone := range subzones {
+			held[zone] = true
+		}
+		return held
+	}
+
+	names := make([]string, 0, len(freezes))
+	for name := range freezes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, zone := range subzones {
+		if name := frozenBy(names, freezes, zone); name != "" {
+			log.Warnf("DNS freeze %s is active, holding back the delegation of the subzone %s", name, zone)
+			held[zone] = true
+		}
+	}
+	return held
+}
+
+// holdBackFrozenChanges drops the changes of the DNS names frozen by an active freeze and returns the
+// number of changes held back. When the freezes can't be listed, all changes are held back, as applying
+// them could interfere with an incident.
+func (c *Controller) holdBackFrozenChanges(changes *plan.Changes) (*plan.Changes, int, error) {
+	total := len(changes.Create) + len(changes.UpdateNew) + len(changes.Delete)
+
+	freezes, err := c.FreezeLister.Freezes()
+	if err != nil {
+		log.Errorf("Holding back all %d changes because the DNS freezes couldn't be listed: %v", total, err)
+		return &plan.Changes{}, total, nil
+	}
+	if len(freezes) == 0 {
+		return changes, 0, nil
+	}
+
+	names := make([]string, 0, len(freezes))
+	for name := range freezes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	heldBack := map[string]int{}
+	filtered, err := changes.Filter(true, func(_, ep *endpoint.Endpoint) bool {
+		if name := frozenBy(names, freezes, ep.DNSName); name != "" {
+			log.Debugf("Holding back change of %s (%s) because of DNS freeze %s", ep.DNSName, ep.RecordType, name)
+			heldBack[name]++
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	count := 0
+	for _, name := range names {
+		if heldBack[name] > 0 {
+			log.Warnf("DNS freeze %s is active, holding back %d changes", name, heldBack[name])
+			count += heldBack[name]
+		}
+	}
+	return filtered, count, nil
+}
+
+// frozenBy returns the first of the sorted freezes matching the DNS name, "" if it isn't frozen.
+func frozenBy(names []string, freezes map[string][]string, dnsName string) string {
+	dnsName = strings.ToLower(strings.TrimSuffix(dnsName, "."))
+	for _, name := range names {
+		patterns := freezes[name]
+		if len(patterns) == 0 {
+			return name
+		}
+		for _, pattern := range patterns {
+			if matchesFreezePattern(strings.ToLower(strings.TrimSuffix(pattern, ".")), dnsName) {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// matchesFreezePattern returns true if the DNS name matches the pattern. A leading *. matches the
+// subdomains at any depth, e.g. *.example.org matches a.b.example.org. Other patterns are matched label
+// by label with path.Match, whose * and ? don't match the separator the dots are replaced with.
+func matchesFreezePattern(pattern, dnsName string) bool {
+	if strings.HasPrefix(pattern, "*.") {
+		return strings.HasSuffix(dnsName, pattern[1:])
+	}
+	ok, _ := path.Match(strings.Replace(pattern, ".", "/", -1), strings.Replace(dnsName, ".", "/", -1))
+	return ok
+}
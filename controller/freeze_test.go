@@ -0,0 +1,189 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/internal/testutils"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// staticFreezeLister returns the same freezes in every synchronization.
+type staticFreezeLister struct {
+	freezes map[string][]string
+	err     error
+}
+
+func (l *staticFreezeLister) Freezes() (map[string][]string, error) {
+	return l.freezes, l.err
+}
+
+func TestHoldBackFrozenChanges(t *testing.T) {
+	changes := &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("a.prod.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+			endpoint.NewEndpoint("a.dev.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+		},
+		UpdateOld: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("api.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+			endpoint.NewEndpoint("b.dev.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+		},
+		UpdateNew: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("api.example.org", endpoint.RecordTypeA, "5.6.7.8"),
+			endpoint.NewEndpoint("b.dev.example.org", endpoint.RecordTypeA, "5.6.7.8"),
+		},
+		Delete: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("PROD.example.org.", endpoint.RecordTypeA, "1.2.3.4"),
+		},
+	}
+
+	for _, tc := range []struct {
+		title    string
+		lister   *staticFreezeLister
+		expected []string
+		frozen   int
+	}{
+		{
+			title:    "without freezes all changes are applied",
+			lister:   &staticFreezeLister{},
+			expected: []string{"a.prod.example.org", "a.dev.example.org", "api.example.org", "b.dev.example.org", "PROD.example.org"},
+		},
+		{
+			title: "changes of the frozen names are held back",
+			lister: &staticFreezeLister{freezes: map[string][]string{
+				"prod": {"prod.example.org", "*.prod.example.org"},
+				"api":  {"API.example.org."},
+			}},
+			expected: []string{"a.dev.example.org", "b.dev.example.org"},
+			frozen:   3,
+		},
+		{
+			title:    "a freeze without patterns holds back all changes",
+			lister:   &staticFreezeLister{freezes: map[string][]string{"incident-42": nil}},
+			expected: []string{},
+			frozen:   5,
+		},
+		{
+			title:    "all changes are held back when the freezes can't be listed",
+			lister:   &staticFreezeLister{err: errors.New("forbidden")},
+			expected: []string{},
+			frozen:   5,
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			ctrl := &Controller{FreezeLister: tc.lister}
+
+			filtered, frozen, err := ctrl.holdBackFrozenChanges(changes)
+			require.NoError(t, err)
+
+			names := []string{}
+			for _, eps := range [][]*endpoint.Endpoint{filtered.Create, filtered.UpdateNew, filtered.Delete} {
+				for _, ep := range eps {
+					names = append(names, ep.DNSName)
+				}
+			}
+			assert.Equal(t, tc.expected, names)
+			assert.Equal(t, tc.frozen, frozen)
+			require.Len(t, filtered.UpdateOld, len(filtered.UpdateNew))
+			for i := range filtered.UpdateNew {
+				assert.Equal(t, filtered.UpdateOld[i].DNSName, filtered.UpdateNew[i].DNSName)
+			}
+		})
+	}
+}
+
+func TestRunOnceFreezeLister(t *testing.T) {
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{
+		endpoint.NewEndpoint("a.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+		endpoint.NewEndpoint("b.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+	}, nil)
+	lister := &staticFreezeLister{freezes: map[string][]string{"incident": {"a.example.org"}}}
+	r := &recordingRegistry{}
+	ctrl := &Controller{
+		Source:       source,
+		Registry:     r,
+		Policy:       &plan.SyncPolicy{},
+		FreezeLister: lister,
+	}
+
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	require.Len(t, r.applied, 1)
+	require.Len(t, r.applied[0].Create, 1)
+	assert.Equal(t, "b.example.org", r.applied[0].Create[0].DNSName)
+	assert.Equal(t, 1.0, testutil.ToFloat64(frozenChanges.WithLabelValues("")))
+
+	// the held back changes are applied once the freeze is lifted
+	lister.freezes = nil
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	require.Len(t, r.applied, 2)
+	require.Len(t, r.applied[1].Create, 2)
+	assert.Equal(t, 0.0, testutil.ToFloat64(frozenChanges.WithLabelValues("")))
+}
+
+func TestRunOnceFreezeListerRenamedDeletions(t *testing.T) {
+	hook := test.NewGlobal()
+	defer hook.Reset()
+
+	source, r := newRenameTest()
+	ctrl := &Controller{
+		Source:       source,
+		Registry:     r,
+		Policy:       &plan.SyncPolicy{},
+		FreezeLister: &staticFreezeLister{freezes: map[string][]string{"incident": {"*-record"}}},
+	}
+
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	// the frozen changes of both phases are counted, the frozen renamed deletion isn't applied and
+	// the synchronization isn't logged a second time
+	require.Len(t, r.applied, 1)
+	assert.Equal(t, 3.0, testutil.ToFloat64(frozenChanges.WithLabelValues("")))
+	upToDate := 0
+	for _, entry := range hook.AllEntries() {
+		if entry.Message == "All 2 records are already up to date" {
+			upToDate++
+		}
+	}
+	assert.LessOrEqual(t, upToDate, 1)
+}
+
+func TestMatchesFreezePattern(t *testing.T) {
+	for _, tc := range []struct {
+		pattern  string
+		dnsName  string
+		expected bool
+	}{
+		{pattern: "prod.example.org", dnsName: "prod.example.org", expected: true},
+		{pattern: "*.prod.example.org", dnsName: "a.prod.example.org", expected: true},
+		{pattern: "*.prod.example.org", dnsName: "a.b.prod.example.org", expected: true},
+		{pattern: "*.prod.example.org", dnsName: "prod.example.org", expected: false},
+		{pattern: "*.prod.example.org", dnsName: "aprod.example.org", expected: false},
+		{pattern: "api-?.example.org", dnsName: "api-1.example.org", expected: true},
+		{pattern: "api-*.example.org", dnsName: "api-1.eu.example.org", expected: false},
+	} {
+		assert.Equal(t, tc.expected, matchesFreezePattern(tc.pattern, tc.dnsName), "%s %s", tc.pattern, tc.dnsName)
+	}
+}
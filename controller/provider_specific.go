@@ -0,0 +1,70 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// The reason of the events reporting endpoints with invalid provider-specific properties
+const invalidProviderSpecificReason = "InvalidProviderSpecific"
+
+// rejectInvalidProviderSpecific drops the creations and updates of endpoints whose provider-specific
+// properties are rejected by the ProviderSpecificValidator, so the existing records stay as they are
+// instead of losing e.g. their routing policy. Every rejected endpoint is logged and, the first time,
+// reported to its resource.
+func (c *Controller) rejectInvalidProviderSpecific(changes *plan.Changes) (*plan.Changes, error) {
+	c.rejectedProviderSpecificLock.Lock()
+	defer c.rejectedProviderSpecificLock.Unlock()
+
+	rejected := map[string]bool{}
+	reject := func(ep *endpoint.Endpoint) bool {
+		err := c.ProviderSpecificValidator.ValidateProviderSpecific(ep)
+		if err == nil {
+			return false
+		}
+		resource := ep.Labels[endpoint.ResourceLabelKey]
+		key := fmt.Sprintf("%s::%v", resource, err)
+		rejected[key] = true
+		if c.rejectedProviderSpecific[key] {
+			invalidEndpointsTotal.WithLabelValues(c.pipeline).Inc()
+			log.Debugf("Skipping endpoint of %s: %v", resource, err)
+			return true
+		}
+		rejectInvalidEndpoint(c.pipeline, ep, err)
+		if c.EventRecorder != nil && resource != "" {
+			c.EventRecorder.RecordWarning(resource, invalidProviderSpecificReason, err.Error())
+		}
+		return true
+	}
+
+	valid, err := changes.Filter(false, func(_, ep *endpoint.Endpoint) bool {
+		return !reject(ep)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// endpoints which are fixed or rejected for another reason are reported again
+	c.rejectedProviderSpecific = rejected
+	return valid, nil
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+// State is the input of a synchronization: the records of the registry and the endpoints of the
+// source as they were returned, before they are normalized. It's written to the state dump file, so
+// the plan can be reproduced offline with Simulate.
+type State struct {
+	Records   []*endpoint.Endpoint `json:"records"`
+	Endpoints []*endpoint.Endpoint `json:"endpoints"`
+	// The zones whose records couldn't be listed, their changes are skipped
+	FailedZones []string `json:"failedZones,omitempty"`
+	// Whether the provider materialized the health checks of the endpoints
+	HealthChecks bool `json:"healthChecks,omitempty"`
+}
+
+// WriteStateFile writes the state as JSON to the given file.
+func WriteStateFile(path string, state *State) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// ReadStateFile reads a state written by WriteStateFile.
+func ReadStateFile(path string) (*State, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	state := &State{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+func newState(records, endpoints []*endpoint.Endpoint, zoneErrors provider.ZoneErrors, healthChecks bool) *State {
+	return &State{
+		Records:      records,
+		Endpoints:    endpoints,
+		FailedZones:  zoneErrors.Zones(),
+		HealthChecks: healthChecks,
+	}
+}
+
+// Simulate calculates the changes of a synchronization of the state with the given policy and managed
+// record types the same way RunOnce does, without the registry, the source or the provider. The changes
+// which depend on earlier synchronizations, i.e. the held back deletions of renamed resources and of
+// the deletion guard, aren't simulated.
+func Simulate(state *State, policy plan.Policy, managedRecordTypes []string) (*plan.Changes, error) {
+	var zoneErrors provider.ZoneErrors
+	for _, zone := range state.FailedZones {
+		if zoneErrors == nil {
+			zoneErrors = provider.ZoneErrors{}
+		}
+		zoneErrors[zone] = errors.New("the records couldn't be listed in the recorded synchronization")
+	}
+
+	records, _ := normalizeEndpoints("", state.Records, false)
+	endpoints, _ := normalizeEndpoints("", state.Endpoints, true)
+	changes, _, err := calculateChanges("", policy, managedRecordTypes, state.HealthChecks, records, endpoints, zoneErrors)
+	return changes, err
+}
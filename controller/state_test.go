@@ -0,0 +1,138 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/internal/testutils"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestStateFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.json")
+
+	state := &State{
+		Records: []*endpoint.Endpoint{{
+			DNSName:    "www.example.org",
+			Targets:    endpoint.Targets{"1.2.3.4"},
+			RecordType: endpoint.RecordTypeA,
+			RecordTTL:  300,
+			Labels:     endpoint.Labels{endpoint.OwnerLabelKey: "default"},
+		}},
+		Endpoints: []*endpoint.Endpoint{{
+			DNSName:          "www.example.org",
+			Targets:          endpoint.Targets{"1.2.3.4", "5.6.7.8"},
+			RecordType:       endpoint.RecordTypeA,
+			SetIdentifier:    "blue",
+			Labels:           endpoint.Labels{endpoint.ResourceLabelKey: "service/default/www"},
+			ProviderSpecific: endpoint.ProviderSpecific{{Name: "alias", Value: "true"}},
+		}},
+		FailedZones: []string{"broken.org"},
+	}
+	require.NoError(t, WriteStateFile(path, state))
+
+	read, err := ReadStateFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, state, read)
+
+	_, err = ReadStateFile(filepath.Join(dir, "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestSimulate(t *testing.T) {
+	state := &State{
+		Records: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("Old.Example.org.", endpoint.RecordTypeA, "1.2.3.4"),
+			endpoint.NewEndpoint("update.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+			endpoint.NewEndpoint("kept.broken.org", endpoint.RecordTypeA, "1.2.3.4"),
+		},
+		Endpoints: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("new.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+			endpoint.NewEndpoint("update.example.org", endpoint.RecordTypeA, "4.3.2.1"),
+			endpoint.NewEndpoint("invalid.example.org", endpoint.RecordTypeA, "not-an-ip"),
+			endpoint.NewEndpoint("new.broken.org", endpoint.RecordTypeA, "1.2.3.4"),
+		},
+		FailedZones: []string{"broken.org"},
+	}
+
+	changes, err := Simulate(state, &plan.SyncPolicy{}, nil)
+	require.NoError(t, err)
+
+	require.Len(t, changes.Create, 1)
+	assert.Equal(t, "new.example.org", changes.Create[0].DNSName)
+	require.Len(t, changes.UpdateNew, 1)
+	assert.Equal(t, endpoint.Targets{"4.3.2.1"}, changes.UpdateNew[0].Targets)
+	require.Len(t, changes.Delete, 1)
+	assert.Equal(t, "old.example.org", changes.Delete[0].DNSName)
+
+	changes, err = Simulate(state, &plan.UpsertOnlyPolicy{}, nil)
+	require.NoError(t, err)
+	assert.Empty(t, changes.Delete)
+}
+
+// TestRunOnceStateDump tests that the unnormalized input of a synchronization is written to the state dump file.
+func TestRunOnceStateDump(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.json")
+
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{
+		endpoint.NewEndpoint("New.Example.org", endpoint.RecordTypeA, "1.2.3.4"),
+	}, nil)
+	r := &recordingRegistry{
+		records: []*endpoint.Endpoint{
+			{DNSName: "old.example.org.", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}, Labels: endpoint.Labels{}},
+		},
+	}
+	ctrl := &Controller{
+		Source:        source,
+		Registry:      r,
+		Policy:        &plan.SyncPolicy{},
+		StateDumpFile: path,
+	}
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+
+	state, err := ReadStateFile(path)
+	require.NoError(t, err)
+	require.Len(t, state.Records, 1)
+	assert.Equal(t, "old.example.org.", state.Records[0].DNSName)
+	require.Len(t, state.Endpoints, 1)
+	assert.Equal(t, "New.Example.org", state.Endpoints[0].DNSName)
+
+	// the simulation reproduces the applied changes
+	changes, err := Simulate(state, &plan.SyncPolicy{}, nil)
+	require.NoError(t, err)
+	require.Len(t, r.applied, 1)
+	require.Len(t, changes.Create, 1)
+	assert.Equal(t, r.applied[0].Create[0].DNSName, changes.Create[0].DNSName)
+	require.Len(t, changes.Delete, 1)
+	assert.Equal(t, r.applied[0].Delete[0].DNSName, changes.Delete[0].DNSName)
+}
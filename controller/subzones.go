@@ -0,0 +1,78 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+var subzoneDelegationErrorsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "external_dns",
+		Subsystem: "controller",
+		Name:      "subzone_delegation_errors_total",
+		Help:      "Number of subzones which couldn't be created or delegated.",
+	},
+	[]string{"pipeline"},
+)
+
+func init() {
+	prometheus.MustRegister(subzoneDelegationErrorsTotal)
+}
+
+// SubzoneLister lists the zones which are created and delegated from their parent zones.
+type SubzoneLister interface {
+	Subzones() ([]string, error)
+}
+
+// delegateSubzones creates and delegates the listed subzones which weren't delegated yet. A failed
+// delegation is logged and retried in the next synchronization; it doesn't stop the synchronization.
+// The delegations of frozen subzones wait until the freeze is lifted.
+func (c *Controller) delegateSubzones(ctx context.Context) {
+	subzones, err := c.SubzoneLister.Subzones()
+	if err != nil {
+		log.Errorf("Unable to list the subzones: %v", err)
+		subzoneDelegationErrorsTotal.WithLabelValues(c.pipeline).Inc()
+		return
+	}
+	frozen := map[string]bool{}
+	if c.FreezeLister != nil {
+		frozen = c.frozenSubzones(subzones)
+	}
+
+	c.delegatedZonesLock.Lock()
+	defer c.delegatedZonesLock.Unlock()
+	if c.delegatedZones == nil {
+		c.delegatedZones = map[string]bool{}
+	}
+
+	for _, zone := range subzones {
+		if c.delegatedZones[zone] || frozen[zone] {
+			continue
+		}
+		if err := c.ZoneDelegator.DelegateZone(ctx, zone); err != nil {
+			log.Errorf("Unable to delegate the subzone %s: %v", zone, err)
+			subzoneDelegationErrorsTotal.WithLabelValues(c.pipeline).Inc()
+			continue
+		}
+		log.Infof("Delegated the subzone %s", zone)
+		c.delegatedZones[zone] = true
+	}
+}
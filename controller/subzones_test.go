@@ -0,0 +1,108 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/plan"
+)
+
+type fakeSubzoneLister struct {
+	subzones []string
+	err      error
+}
+
+func (l *fakeSubzoneLister) Subzones() ([]string, error) {
+	return l.subzones, l.err
+}
+
+// recordingZoneDelegator records the delegated zones and fails the zones of failing once.
+type recordingZoneDelegator struct {
+	delegated []string
+	failing   map[string]bool
+}
+
+func (d *recordingZoneDelegator) DelegateZone(ctx context.Context, zone string) error {
+	if d.failing[zone] {
+		delete(d.failing, zone)
+		return errors.New("throttled")
+	}
+	d.delegated = append(d.delegated, zone)
+	return nil
+}
+
+// TestRunOnceDelegatesSubzones tests that every subzone is delegated once and failed delegations are retried.
+func TestRunOnceDelegatesSubzones(t *testing.T) {
+	source, r := newRenameTest()
+	lister := &fakeSubzoneLister{subzones: []string{"team-a.example.org", "team-b.example.org"}}
+	delegator := &recordingZoneDelegator{failing: map[string]bool{"team-b.example.org": true}}
+	ctrl := &Controller{
+		Source:        source,
+		Registry:      r,
+		Policy:        &plan.SyncPolicy{},
+		SubzoneLister: lister,
+		ZoneDelegator: delegator,
+	}
+
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	assert.Equal(t, []string{"team-a.example.org"}, delegator.delegated)
+
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	assert.Equal(t, []string{"team-a.example.org", "team-b.example.org"}, delegator.delegated)
+
+	lister.subzones = append(lister.subzones, "team-c.example.org")
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	assert.Equal(t, []string{"team-a.example.org", "team-b.example.org", "team-c.example.org"}, delegator.delegated)
+
+	// the synchronization continues if the subzones can't be listed
+	lister.err = errors.New("forbidden")
+	applied := len(r.applied)
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	assert.NotEqual(t, applied, len(r.applied))
+}
+
+// TestRunOnceHoldsBackFrozenSubzones tests that the subzones are only delegated once their freeze is lifted.
+func TestRunOnceHoldsBackFrozenSubzones(t *testing.T) {
+	source, r := newRenameTest()
+	freezes := &staticFreezeLister{freezes: map[string][]string{"incident-42": {"*.example.org"}}}
+	delegator := &recordingZoneDelegator{}
+	ctrl := &Controller{
+		Source:        source,
+		Registry:      r,
+		Policy:        &plan.SyncPolicy{},
+		SubzoneLister: &fakeSubzoneLister{subzones: []string{"team-a.example.org", "team-b.dev.example.net"}},
+		ZoneDelegator: delegator,
+		FreezeLister:  freezes,
+	}
+
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	assert.Equal(t, []string{"team-b.dev.example.net"}, delegator.delegated)
+
+	freezes.err = errors.New("forbidden")
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	assert.Equal(t, []string{"team-b.dev.example.net"}, delegator.delegated)
+
+	freezes.freezes, freezes.err = nil, nil
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	assert.Equal(t, []string{"team-b.dev.example.net", "team-a.example.org"}, delegator.delegated)
+}
@@ -0,0 +1,393 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+var takeoverRiskRecords = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Namespace: "external_dns",
+		Subsystem: "controller",
+		Name:      "takeover_risk_records",
+		Help:      "Number of owned CNAME records pointing at an unclaimed target found by the last takeover scan.",
+	},
+	[]string{"pipeline"},
+)
+
+func init() {
+	prometheus.MustRegister(takeoverRiskRecords)
+}
+
+const (
+	// The reason of the events reporting owned CNAME records whose target is unclaimed
+	takeoverRiskReason = "SubdomainTakeoverRisk"
+	// The time probing a single target may take
+	takeoverProbeTimeout = 10 * time.Second
+	// The part of the response of a storage bucket endpoint which is searched for the missing bucket error
+	takeoverProbeBodyLimit = 16 * 1024
+)
+
+// storageBucketTargets match the endpoints of storage buckets, which resolve whether or not the bucket
+// behind the hostname exists.
+var storageBucketTargets = []*regexp.Regexp{
+	// bucket.s3.amazonaws.com, bucket.s3-website-us-east-1.amazonaws.com, bucket.s3.eu-west-1.amazonaws.com
+	regexp.MustCompile(`(^|\.)s3([.-][a-z0-9-]+)*\.amazonaws\.com$`),
+	// c.storage.googleapis.com, bucket.storage.googleapis.com
+	regexp.MustCompile(`(^|\.)storage\.googleapis\.com$`),
+}
+
+// TakeoverFinding is an owned CNAME record whose target is unclaimed.
+type TakeoverFinding struct {
+	DNSName       string `json:"dnsName"`
+	SetIdentifier string `json:"setIdentifier,omitempty"`
+	Target        string `json:"target"`
+	Resource      string `json:"resource,omitempty"`
+	Reason        string `json:"reason"`
+}
+
+func (f TakeoverFinding) key() string {
+	return f.DNSName + "/" + f.SetIdentifier
+}
+
+// TakeoverScanner periodically inspects the CNAME records owned by the controller for targets pointing
+// at unclaimed cloud resources, e.g. the hostname of a deleted load balancer or a storage bucket which
+// doesn't exist anymore. Whoever claims the resource next takes over the subdomain. The findings are
+// logged, exposed as a metric, reported to their resources and posted to the webhook, if any. When
+// removing, the records are deleted and not created again as long as their target stays unclaimed.
+// A lookup may report a missing name for a target which exists, e.g. a private target invisible to the
+// resolver of the cluster or a name without addresses, so records are only deleted once a number of
+// consecutive scans confirmed the finding; a single finding is only reported.
+type TakeoverScanner struct {
+	ownerID       string
+	interval      time.Duration
+	remove        bool
+	confirmations int
+	webhookURL    string
+	resolver      TargetResolver
+	client        *http.Client
+	// The pipeline labeling the metrics, see Controller.SetPipeline
+	pipeline string
+
+	lock     sync.Mutex
+	lastScan time.Time
+	findings map[string]TakeoverFinding
+	// The number of consecutive scans which found each finding
+	scans map[string]int
+}
+
+// NewTakeoverScanner creates a TakeoverScanner inspecting the records of the owner every interval. When
+// remove is true, the records at risk are deleted once the given number of consecutive scans found them.
+// The findings are posted as JSON to the webhook URL, empty to disable it.
+func NewTakeoverScanner(ownerID string, interval time.Duration, remove bool, confirmations int, webhookURL string, resolver TargetResolver) *TakeoverScanner {
+	if confirmations < 1 {
+		confirmations = 1
+	}
+	return &TakeoverScanner{
+		ownerID:       ownerID,
+		interval:      interval,
+		remove:        remove,
+		confirmations: confirmations,
+		webhookURL:    webhookURL,
+		resolver:      resolver,
+		client: &http.Client{
+			Timeout: takeoverProbeTimeout,
+			// the response of the target itself is inspected
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		findings: map[string]TakeoverFinding{},
+		scans:    map[string]int{},
+	}
+}
+
+// due returns true if the last scan is at least an interval ago.
+func (s *TakeoverScanner) due(now time.Time) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.lastScan.IsZero() || now.Sub(s.lastScan) >= s.interval
+}
+
+// scan inspects the owned CNAME records and returns the findings which are new since the last scan.
+// The targets of the records found by earlier scans which were deleted are inspected again, so they
+// are held back until their target is claimed again.
+func (s *TakeoverScanner) scan(ctx context.Context, records []*endpoint.Endpoint, now time.Time) []TakeoverFinding {
+	s.lock.Lock()
+	previous, previousScans := s.findings, s.scans
+	s.lock.Unlock()
+
+	findings := map[string]TakeoverFinding{}
+	seen := map[string]bool{}
+	inspect := func(candidate TakeoverFinding) {
+		if seen[candidate.key()+"/"+candidate.Target] {
+			return
+		}
+		seen[candidate.key()+"/"+candidate.Target] = true
+		if reason := s.unclaimed(ctx, candidate.DNSName, candidate.Target); reason != "" {
+			candidate.Reason = reason
+			findings[candidate.key()] = candidate
+		}
+	}
+
+	present := map[string]bool{}
+	for _, ep := range records {
+		if ep.RecordType != endpoint.RecordTypeCNAME || ep.Labels[endpoint.OwnerLabelKey] != s.ownerID {
+			continue
+		}
+		for _, target := range ep.Targets {
+			candidate := TakeoverFinding{
+				DNSName:       ep.DNSName,
+				SetIdentifier: ep.SetIdentifier,
+				Target:        strings.ToLower(strings.TrimSuffix(target, ".")),
+				Resource:      ep.Labels[endpoint.ResourceLabelKey],
+			}
+			present[candidate.key()] = true
+			inspect(candidate)
+		}
+	}
+	if s.remove {
+		for key, finding := range previous {
+			if !present[key] {
+				inspect(finding)
+			}
+		}
+	}
+
+	var added []TakeoverFinding
+	scans := map[string]int{}
+	for key, finding := range findings {
+		if old, ok := previous[key]; ok && old.Target == finding.Target {
+			scans[key] = previousScans[key] + 1
+			continue
+		}
+		scans[key] = 1
+		added = append(added, finding)
+	}
+	sort.Slice(added, func(i, j int) bool {
+		return added[i].key() < added[j].key()
+	})
+
+	s.lock.Lock()
+	s.findings = findings
+	s.scans = scans
+	s.lastScan = now
+	s.lock.Unlock()
+	takeoverRiskRecords.WithLabelValues(s.pipeline).Set(float64(len(findings)))
+	return added
+}
+
+// unclaimed returns why the target of a record is unclaimed or an empty string if it's claimed or
+// couldn't be inspected. A target is unclaimed if its name doesn't exist or, for the endpoints of
+// storage buckets, if the bucket serving the record doesn't exist.
+func (s *TakeoverScanner) unclaimed(ctx context.Context, name, target string) string {
+	// the name is looked up as an absolute name, so the search domains of the resolver aren't tried
+	lookupCtx, cancel := context.WithTimeout(ctx, takeoverProbeTimeout)
+	_, err := s.resolver.LookupHost(lookupCtx, target+".")
+	cancel()
+	if dnsErr, ok := err.(*net.DNSError); ok && dnsErr.IsNotFound {
+		return "the target doesn't exist"
+	}
+	if err != nil {
+		log.Debugf("Unable to resolve the target %s of %s: %v", target, name, err)
+		return ""
+	}
+	if !isStorageBucketTarget(target) {
+		return ""
+	}
+
+	missing, err := s.bucketMissing(ctx, name, target)
+	if err != nil {
+		log.Debugf("Unable to probe the storage bucket %s of %s: %v", target, name, err)
+		return ""
+	}
+	if missing {
+		return "the storage bucket doesn't exist"
+	}
+	return ""
+}
+
+// isStorageBucketTarget returns true if the target is the endpoint of a storage bucket service.
+func isStorageBucketTarget(target string) bool {
+	for _, pattern := range storageBucketTargets {
+		if pattern.MatchString(target) {
+			return true
+		}
+	}
+	return false
+}
+
+// bucketMissing requests the record from the storage bucket endpoint, which serves the bucket named
+// after the host and answers with a NoSuchBucket error if there's none.
+func (s *TakeoverScanner) bucketMissing(ctx context.Context, name, target string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+target+"/", nil)
+	if err != nil {
+		return false, err
+	}
+	req.Host = strings.TrimSuffix(name, ".")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		return false, nil
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, takeoverProbeBodyLimit))
+	if err != nil {
+		return false, err
+	}
+	return bytes.Contains(body, []byte("NoSuchBucket")), nil
+}
+
+// notify posts the findings to the webhook.
+func (s *TakeoverScanner) notify(ctx context.Context, findings []TakeoverFinding) error {
+	body, err := json.Marshal(struct {
+		Findings []TakeoverFinding `json:"findings"`
+	}{findings})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// confirmed returns the findings which were found by enough consecutive scans to act on them.
+func (s *TakeoverScanner) confirmed() map[string]TakeoverFinding {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	confirmed := map[string]TakeoverFinding{}
+	for key, finding := range s.findings {
+		if s.scans[key] >= s.confirmations {
+			confirmed[key] = finding
+		}
+	}
+	return confirmed
+}
+
+// holdBack adds the deletions of the records at risk to the changes and drops the creations and
+// updates pointing at their unclaimed targets, so they aren't created again until the target is claimed.
+// Only the confirmed findings are acted on.
+func (s *TakeoverScanner) holdBack(records []*endpoint.Endpoint, changes *plan.Changes) (*plan.Changes, error) {
+	findings := s.confirmed()
+	if len(findings) == 0 {
+		return changes, nil
+	}
+
+	atRisk := func(ep *endpoint.Endpoint) bool {
+		if ep.RecordType != endpoint.RecordTypeCNAME {
+			return false
+		}
+		finding, ok := findings[TakeoverFinding{DNSName: ep.DNSName, SetIdentifier: ep.SetIdentifier}.key()]
+		if !ok {
+			return false
+		}
+		for _, target := range ep.Targets {
+			if strings.EqualFold(strings.TrimSuffix(target, "."), finding.Target) {
+				return true
+			}
+		}
+		return false
+	}
+
+	held, err := changes.Filter(false, func(old, ep *endpoint.Endpoint) bool {
+		return !atRisk(ep) && (old == nil || !atRisk(old))
+	})
+	if err != nil {
+		return nil, err
+	}
+	held.Delete = nil
+	deleted := map[string]bool{}
+	for _, ep := range changes.Delete {
+		held.Delete = append(held.Delete, ep)
+		if ep.RecordType == endpoint.RecordTypeCNAME {
+			deleted[TakeoverFinding{DNSName: ep.DNSName, SetIdentifier: ep.SetIdentifier}.key()] = true
+		}
+	}
+	for _, ep := range records {
+		key := TakeoverFinding{DNSName: ep.DNSName, SetIdentifier: ep.SetIdentifier}.key()
+		if atRisk(ep) && ep.Labels[endpoint.OwnerLabelKey] == s.ownerID && !deleted[key] {
+			log.Warnf("Deleting the CNAME record %s because its target %s is unclaimed", ep.DNSName, findings[key].Target)
+			held.Delete = append(held.Delete, ep)
+			deleted[key] = true
+		}
+	}
+	return held, nil
+}
+
+// scanForTakeovers scans the records for subdomain takeover risks when a scan is due, reports the new
+// findings and, if the scanner removes them, deletes the records at risk once they are confirmed.
+func (c *Controller) scanForTakeovers(ctx context.Context, records []*endpoint.Endpoint, changes *plan.Changes) (*plan.Changes, error) {
+	scanner := c.TakeoverScanner
+	if now := time.Now(); scanner.due(now) {
+		findings := scanner.scan(ctx, records, now)
+		for _, finding := range findings {
+			message := fmt.Sprintf("The CNAME record %s points at %s, %s and whoever claims it takes over the record", finding.DNSName, finding.Target, finding.Reason)
+			if finding.Resource != "" {
+				log.Warnf("%s (%s)", message, finding.Resource)
+			} else {
+				log.Warn(message)
+			}
+			if c.EventRecorder != nil && finding.Resource != "" {
+				c.EventRecorder.RecordWarning(finding.Resource, takeoverRiskReason, message)
+			}
+		}
+		if len(findings) > 0 && scanner.webhookURL != "" {
+			if err := scanner.notify(ctx, findings); err != nil {
+				log.Warnf("Unable to post the subdomain takeover findings to the webhook: %v", err)
+			}
+		}
+	}
+
+	if !scanner.remove {
+		return changes, nil
+	}
+	return scanner.holdBack(records, changes)
+}
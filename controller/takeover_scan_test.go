@@ -0,0 +1,213 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/internal/testutils"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func newTakeoverScanTest(interval time.Duration, remove bool, confirmations int, webhookURL string) (*Controller, *recordingRegistry, *recordingEventRecorder, *staticTargetResolver) {
+	owned := func(ep *endpoint.Endpoint, owner, resource string) *endpoint.Endpoint {
+		ep.Labels[endpoint.OwnerLabelKey] = owner
+		if resource != "" {
+			ep.Labels[endpoint.ResourceLabelKey] = resource
+		}
+		return ep
+	}
+	records := []*endpoint.Endpoint{
+		owned(endpoint.NewEndpoint("old.example.org", endpoint.RecordTypeCNAME, "lb-1.elb.amazonaws.com"), "default", "ingress/default/old"),
+		owned(endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeCNAME, "lb-2.elb.amazonaws.com"), "default", "ingress/default/app"),
+		owned(endpoint.NewEndpoint("foreign.example.org", endpoint.RecordTypeCNAME, "lb-3.elb.amazonaws.com"), "other", ""),
+		owned(endpoint.NewEndpoint("web.example.org", endpoint.RecordTypeA, "1.2.3.4"), "default", ""),
+	}
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{
+		endpoint.NewEndpoint("old.example.org", endpoint.RecordTypeCNAME, "lb-1.elb.amazonaws.com"),
+		endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeCNAME, "lb-2.elb.amazonaws.com"),
+		endpoint.NewEndpoint("foreign.example.org", endpoint.RecordTypeCNAME, "lb-3.elb.amazonaws.com"),
+		endpoint.NewEndpoint("web.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+	}, nil)
+	r := &recordingRegistry{records: records}
+	recorder := &recordingEventRecorder{}
+	resolver := &staticTargetResolver{hosts: map[string][]string{"lb-2.elb.amazonaws.com.": {"1.2.3.4"}}}
+	ctrl := &Controller{
+		Source:          source,
+		Registry:        r,
+		Policy:          &plan.SyncPolicy{},
+		EventRecorder:   recorder,
+		TakeoverScanner: NewTakeoverScanner("default", interval, remove, confirmations, webhookURL, resolver),
+	}
+	return ctrl, r, recorder, resolver
+}
+
+// TestTakeoverScannerAlert tests that the owned records at risk are reported once and kept.
+func TestTakeoverScannerAlert(t *testing.T) {
+	var posted []TakeoverFinding
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Findings []TakeoverFinding `json:"findings"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		posted = append(posted, body.Findings...)
+	}))
+	defer webhook.Close()
+
+	ctrl, r, recorder, resolver := newTakeoverScanTest(time.Hour, false, 1, webhook.URL)
+
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+
+	message := "The CNAME record old.example.org points at lb-1.elb.amazonaws.com, the target doesn't exist and whoever claims it takes over the record"
+	assert.Equal(t, []string{"ingress/default/old SubdomainTakeoverRisk: " + message}, recorder.warnings)
+	assert.Equal(t, []TakeoverFinding{{
+		DNSName:  "old.example.org",
+		Target:   "lb-1.elb.amazonaws.com",
+		Resource: "ingress/default/old",
+		Reason:   "the target doesn't exist",
+	}}, posted)
+	assert.Equal(t, 1.0, testutil.ToFloat64(takeoverRiskRecords.WithLabelValues("")))
+	// the records of other owners aren't inspected and the second synchronization isn't due for a scan
+	assert.ElementsMatch(t, []string{"lb-1.elb.amazonaws.com.", "lb-2.elb.amazonaws.com."}, resolver.lookups)
+	for _, changes := range r.applied {
+		assert.Empty(t, changes.Delete)
+	}
+}
+
+// TestTakeoverScannerDelete tests that the records at risk are deleted once confirmed and only created
+// again once their target is claimed.
+func TestTakeoverScannerDelete(t *testing.T) {
+	ctrl, r, recorder, resolver := newTakeoverScanTest(0, true, 2, "")
+
+	// a single finding is only reported
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	require.Len(t, r.applied, 1)
+	assert.Empty(t, r.applied[0].Delete)
+	assert.Len(t, recorder.warnings, 1)
+
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	require.Len(t, r.applied, 2)
+	require.Len(t, r.applied[1].Delete, 1)
+	assert.Equal(t, "old.example.org", r.applied[1].Delete[0].DNSName)
+	assert.Len(t, recorder.warnings, 1)
+
+	// the record is gone but still desired
+	r.records = r.records[1:]
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	require.Len(t, r.applied, 3)
+	assert.Empty(t, r.applied[2].Create)
+	assert.Len(t, recorder.warnings, 1)
+
+	resolver.hosts["lb-1.elb.amazonaws.com."] = []string{"5.6.7.8"}
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	require.Len(t, r.applied, 4)
+	assert.Equal(t, []string{"old.example.org"}, createdNames(r.applied[3]))
+}
+
+// TestTakeoverScannerTransientFinding tests that a finding which isn't confirmed by the next scan
+// doesn't delete the record.
+func TestTakeoverScannerTransientFinding(t *testing.T) {
+	ctrl, r, _, resolver := newTakeoverScanTest(0, true, 2, "")
+
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	resolver.hosts["lb-1.elb.amazonaws.com."] = []string{"5.6.7.8"}
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	delete(resolver.hosts, "lb-1.elb.amazonaws.com.")
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+
+	for _, changes := range r.applied {
+		assert.Empty(t, changes.Delete)
+	}
+}
+
+func TestTakeoverScannerHoldBackUpdates(t *testing.T) {
+	scanner := NewTakeoverScanner("default", time.Hour, true, 1, "", &staticTargetResolver{})
+	scanner.findings = map[string]TakeoverFinding{
+		"app.example.org/": {DNSName: "app.example.org", Target: "lb-1.elb.amazonaws.com"},
+		"api.example.org/": {DNSName: "api.example.org", Target: "lb-1.elb.amazonaws.com"},
+	}
+	scanner.scans = map[string]int{"app.example.org/": 1, "api.example.org/": 1}
+	changes := &plan.Changes{
+		UpdateOld: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeCNAME, "lb-2.elb.amazonaws.com"),
+			endpoint.NewEndpoint("api.example.org", endpoint.RecordTypeCNAME, "lb-2.elb.amazonaws.com"),
+		},
+		UpdateNew: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeCNAME, "lb-1.elb.amazonaws.com."),
+			endpoint.NewEndpoint("api.example.org", endpoint.RecordTypeCNAME, "lb-4.elb.amazonaws.com"),
+		},
+	}
+
+	held, err := scanner.holdBack(nil, changes)
+	require.NoError(t, err)
+	require.Len(t, held.UpdateNew, 1)
+	require.Len(t, held.UpdateOld, 1)
+	assert.Equal(t, "api.example.org", held.UpdateNew[0].DNSName)
+	assert.Equal(t, "api.example.org", held.UpdateOld[0].DNSName)
+	assert.Empty(t, held.Delete)
+}
+
+func TestTakeoverScannerStorageBucket(t *testing.T) {
+	buckets := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host == "assets.example.org" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("<Error><Code>NoSuchBucket</Code><BucketName>" + r.Host + "</BucketName></Error>"))
+	}))
+	defer buckets.Close()
+
+	resolver := &staticTargetResolver{hosts: map[string][]string{"s3-website-us-east-1.amazonaws.com.": {"1.2.3.4"}}}
+	scanner := NewTakeoverScanner("default", time.Hour, false, 1, "", resolver)
+	scanner.client = &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return net.Dial("tcp", buckets.Listener.Addr().String())
+		},
+	}}
+
+	assert.Equal(t, "", scanner.unclaimed(context.Background(), "assets.example.org", "s3-website-us-east-1.amazonaws.com"))
+	assert.Equal(t, "the storage bucket doesn't exist", scanner.unclaimed(context.Background(), "static.example.org", "s3-website-us-east-1.amazonaws.com"))
+}
+
+func TestIsStorageBucketTarget(t *testing.T) {
+	for target, expected := range map[string]bool{
+		"bucket.s3.amazonaws.com":                   true,
+		"bucket.s3-website-us-east-1.amazonaws.com": true,
+		"s3-website.eu-central-1.amazonaws.com":     true,
+		"bucket.s3.eu-west-1.amazonaws.com":         true,
+		"c.storage.googleapis.com":                  true,
+		"lb-1.elb.amazonaws.com":                    false,
+		"mys3.example.org":                          false,
+		"storage.googleapis.com.example.org":        false,
+	} {
+		assert.Equal(t, expected, isStorageBucketTarget(target), target)
+	}
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// The reason of the events reporting endpoints whose TTL was brought within the bounds
+const ttlClampedReason = "TTLClamped"
+
+// EventRecorder reports the warnings about the endpoints of a resource to it, e.g. as Kubernetes events.
+type EventRecorder interface {
+	// RecordWarning is called with the resource of the endpoint, e.g. ingress/default/app.
+	RecordWarning(resource, reason, message string)
+}
+
+// clampTTLs brings the configured TTLs of the endpoints within the minimum and maximum TTL, so resources
+// can't ask for TTLs violating the policy of the provider or the organization. Endpoints without a TTL
+// keep the default of the provider. Every clamped endpoint is logged and, the first time, reported to
+// its resource.
+func (c *Controller) clampTTLs(endpoints []*endpoint.Endpoint) {
+	if c.MinTTL <= 0 && c.MaxTTL <= 0 {
+		return
+	}
+	minTTL := endpoint.TTL(c.MinTTL / time.Second)
+	maxTTL := endpoint.TTL(c.MaxTTL / time.Second)
+
+	c.clampedTTLsLock.Lock()
+	defer c.clampedTTLsLock.Unlock()
+
+	clamped := map[string]bool{}
+	for _, ep := range endpoints {
+		if !ep.RecordTTL.IsConfigured() {
+			continue
+		}
+		ttl := ep.RecordTTL
+		if minTTL > 0 && ttl < minTTL {
+			ttl = minTTL
+		}
+		if maxTTL > 0 && ttl > maxTTL {
+			ttl = maxTTL
+		}
+		if ttl == ep.RecordTTL {
+			continue
+		}
+
+		message := fmt.Sprintf("The TTL %d of %s (%s) was changed to %d to stay within the range of %s to %s", ep.RecordTTL, ep.DNSName, ep.RecordType, ttl, c.MinTTL, c.MaxTTL)
+		resource := ep.Labels[endpoint.ResourceLabelKey]
+		key := fmt.Sprintf("%s::%s", resource, message)
+		clamped[key] = true
+		if c.clampedTTLs[key] {
+			log.Debug(message)
+		} else {
+			log.Warn(message)
+			if c.EventRecorder != nil && resource != "" {
+				c.EventRecorder.RecordWarning(resource, ttlClampedReason, message)
+			}
+		}
+		ep.RecordTTL = ttl
+	}
+	// endpoints which are fixed or clamped differently are reported again
+	c.clampedTTLs = clamped
+}
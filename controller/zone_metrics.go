@@ -0,0 +1,157 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+var (
+	zoneLastSuccessfulSync = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "external_dns",
+			Subsystem: "zone",
+			Name:      "last_successful_sync_timestamp",
+			Help:      "Timestamp of the last synchronization which applied all the changes of a zone.",
+		},
+		[]string{"pipeline", "zone"},
+	)
+	zonePendingChanges = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "external_dns",
+			Subsystem: "zone",
+			Name:      "pending_changes",
+			Help:      "Number of planned changes of a zone which weren't applied by the last synchronization.",
+		},
+		[]string{"pipeline", "zone"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(zoneLastSuccessfulSync)
+	prometheus.MustRegister(zonePendingChanges)
+}
+
+// ZoneSyncMetrics tracks the synchronization of each zone of the provider separately, so an alert can catch
+// a single zone which silently stops syncing while the others are fine. A zone is synchronized successfully
+// when its records were listed and all its changes were applied. Providers which can't list their zones
+// are tracked per domain of the domain filter instead. The records outside of the zones are tracked
+// together with an empty zone label.
+type ZoneSyncMetrics struct {
+	zones []string
+	// The provider listing the zones before every synchronization, nil to track the domains
+	lister provider.ZoneLister
+	// The pipeline labeling the metrics, see Controller.SetPipeline
+	pipeline string
+}
+
+// NewZoneSyncMetrics creates a ZoneSyncMetrics for the zones of the provider, or for the given domains if
+// the provider is nil.
+func NewZoneSyncMetrics(lister provider.ZoneLister, domains []string) *ZoneSyncMetrics {
+	return &ZoneSyncMetrics{zones: normalizeZones(domains), lister: lister}
+}
+
+// Observe records the outcome of a synchronization at the given time. The pending changes are the changes
+// which weren't applied. Zones whose records couldn't be listed, or which contain a provider zone which
+// couldn't be listed, are left alone, so their last successful synchronization gets older until they
+// recover.
+func (m *ZoneSyncMetrics) Observe(ctx context.Context, changes *plan.Changes, zoneErrors provider.ZoneErrors, now time.Time) {
+	m.refreshZones(ctx)
+
+	pending := map[string]int{}
+	for _, ep := range append(append(append([]*endpoint.Endpoint{}, changes.Create...), changes.UpdateNew...), changes.Delete...) {
+		pending[m.zoneOf(ep.DNSName)]++
+	}
+
+	failed := map[string]bool{}
+	for _, zone := range zoneErrors.Zones() {
+		failed[m.zoneOf(zone)] = true
+	}
+
+	for _, zone := range append([]string{""}, m.zones...) {
+		if failed[zone] || zone != "" && zoneErrors.Contains(zone) {
+			continue
+		}
+		zonePendingChanges.WithLabelValues(m.pipeline, zone).Set(float64(pending[zone]))
+		if pending[zone] == 0 {
+			zoneLastSuccessfulSync.WithLabelValues(m.pipeline, zone).Set(float64(now.Unix()))
+		}
+	}
+}
+
+// refreshZones lists the zones of the provider, the metrics of the zones which are gone are removed. The
+// previous zones are kept if they can't be listed.
+func (m *ZoneSyncMetrics) refreshZones(ctx context.Context) {
+	if m.lister == nil {
+		return
+	}
+	names, err := m.lister.ZoneNames(ctx)
+	if err != nil {
+		log.Warnf("Failed to list the zones for the zone metrics: %v", err)
+		return
+	}
+
+	zones := normalizeZones(names)
+	current := map[string]bool{}
+	for _, zone := range zones {
+		current[zone] = true
+	}
+	for _, zone := range m.zones {
+		if !current[zone] {
+			zoneLastSuccessfulSync.DeleteLabelValues(m.pipeline, zone)
+			zonePendingChanges.DeleteLabelValues(m.pipeline, zone)
+		}
+	}
+	m.zones = zones
+}
+
+// normalizeZones returns the sorted and unique zones without their dots and in lower case.
+func normalizeZones(zones []string) []string {
+	normalized := []string{}
+	seen := map[string]bool{}
+	for _, zone := range zones {
+		zone = strings.ToLower(strings.Trim(zone, "."))
+		if zone != "" && !seen[zone] {
+			seen[zone] = true
+			normalized = append(normalized, zone)
+		}
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+// zoneOf returns the most specific zone a DNS name belongs to or "" if it isn't part of any.
+func (m *ZoneSyncMetrics) zoneOf(dnsName string) string {
+	name := strings.ToLower(strings.TrimSuffix(dnsName, "."))
+	match := ""
+	for _, zone := range m.zones {
+		if (name == zone || strings.HasSuffix(name, "."+zone)) && len(zone) > len(match) {
+			match = zone
+		}
+	}
+	return match
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+func zoneSyncMetrics(zone string) (float64, float64) {
+	return testutil.ToFloat64(zoneLastSuccessfulSync.WithLabelValues("", zone)), testutil.ToFloat64(zonePendingChanges.WithLabelValues("", zone))
+}
+
+// staticZoneLister lists the given zones, or fails with the error if set.
+type staticZoneLister struct {
+	zones []string
+	err   error
+}
+
+func (l *staticZoneLister) ZoneNames(ctx context.Context) ([]string, error) {
+	return l.zones, l.err
+}
+
+func TestZoneSyncMetrics(t *testing.T) {
+	m := NewZoneSyncMetrics(nil, []string{"metrics.example.org", ".sub.metrics.example.org.", "metrics.other.org"})
+	changes := &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("a.metrics.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+			endpoint.NewEndpoint("a.sub.metrics.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+		},
+		Delete: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("b.sub.metrics.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+		},
+	}
+	first := time.Unix(1000, 0)
+	second := time.Unix(2000, 0)
+
+	m.Observe(context.Background(), &plan.Changes{}, nil, first)
+	for _, zone := range []string{"metrics.example.org", "sub.metrics.example.org", "metrics.other.org"} {
+		synced, pending := zoneSyncMetrics(zone)
+		assert.Equal(t, float64(1000), synced, zone)
+		assert.Equal(t, float64(0), pending, zone)
+	}
+
+	// the changes weren't applied and the records of one zone couldn't be listed
+	m.Observe(context.Background(), changes, provider.ZoneErrors{"metrics.other.org": errors.New("failed")}, second)
+	synced, pending := zoneSyncMetrics("metrics.example.org")
+	assert.Equal(t, float64(1000), synced)
+	assert.Equal(t, float64(1), pending)
+	synced, pending = zoneSyncMetrics("sub.metrics.example.org")
+	assert.Equal(t, float64(1000), synced)
+	assert.Equal(t, float64(2), pending)
+	synced, pending = zoneSyncMetrics("metrics.other.org")
+	assert.Equal(t, float64(1000), synced)
+	assert.Equal(t, float64(0), pending)
+
+	// a failed provider zone below a domain makes the domain stale
+	m.Observe(context.Background(), &plan.Changes{}, provider.ZoneErrors{"deep.sub.metrics.example.org": errors.New("failed")}, second)
+	synced, _ = zoneSyncMetrics("sub.metrics.example.org")
+	assert.Equal(t, float64(1000), synced)
+	synced, pending = zoneSyncMetrics("metrics.example.org")
+	assert.Equal(t, float64(2000), synced)
+	assert.Equal(t, float64(0), pending)
+}
+
+// TestZoneSyncMetricsZoneLister tests that the zones of the provider are tracked without a domain filter,
+// and that the metrics of a zone which is gone are removed.
+func TestZoneSyncMetricsZoneLister(t *testing.T) {
+	lister := &staticZoneLister{zones: []string{"lister.example.org.", "lister.other.org.", "Lister.example.org"}}
+	m := NewZoneSyncMetrics(lister, nil)
+	changes := &plan.Changes{Create: []*endpoint.Endpoint{endpoint.NewEndpoint("a.lister.example.org", endpoint.RecordTypeA, "1.2.3.4")}}
+
+	m.Observe(context.Background(), changes, nil, time.Unix(1000, 0))
+	assert.Equal(t, []string{"lister.example.org", "lister.other.org"}, m.zones)
+	synced, pending := zoneSyncMetrics("lister.example.org")
+	assert.Equal(t, float64(0), synced)
+	assert.Equal(t, float64(1), pending)
+	synced, pending = zoneSyncMetrics("lister.other.org")
+	assert.Equal(t, float64(1000), synced)
+	assert.Equal(t, float64(0), pending)
+
+	// the previous zones are kept if they can't be listed
+	lister.err = errors.New("failed")
+	m.Observe(context.Background(), &plan.Changes{}, nil, time.Unix(2000, 0))
+	assert.Equal(t, []string{"lister.example.org", "lister.other.org"}, m.zones)
+
+	lister.zones, lister.err = []string{"lister.example.org"}, nil
+	m.Observe(context.Background(), &plan.Changes{}, nil, time.Unix(3000, 0))
+	assert.Equal(t, []string{"lister.example.org"}, m.zones)
+	// the metrics of the zone are gone already
+	assert.False(t, zoneLastSuccessfulSync.DeleteLabelValues("", "lister.other.org"))
+	assert.False(t, zonePendingChanges.DeleteLabelValues("", "lister.other.org"))
+}
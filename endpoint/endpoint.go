@@ -27,12 +27,16 @@ import (
 const (
 	// RecordTypeA is a RecordType enum value
 	RecordTypeA = "A"
+	// RecordTypeAAAA is a RecordType enum value
+	RecordTypeAAAA = "AAAA"
 	// RecordTypeCNAME is a RecordType enum value
 	RecordTypeCNAME = "CNAME"
 	// RecordTypeTXT is a RecordType enum value
 	RecordTypeTXT = "TXT"
 	// RecordTypeSRV is a RecordType enum value
 	RecordTypeSRV = "SRV"
+	// RecordTypeCAA is a RecordType enum value
+	RecordTypeCAA = "CAA"
 )
 
 // TTL is a structure defining the TTL of a DNS record
@@ -70,12 +74,13 @@ func (t Targets) Swap(i, j int) {
 }
 
 // Same compares to Targets and returns true if they are completely identical
+// The order of the targets is ignored, the per-target attributes providers attach a meaning to, like
+// weights and priorities, are compared by Endpoint.SameTargetMetadata.
 func (t Targets) Same(o Targets) bool {
 	if len(t) != len(o) {
 		return false
 	}
-	sort.Stable(t)
-	sort.Stable(o)
+	t, o = t.sorted(), o.sorted()
 
 	for i, e := range t {
 		if e != o[i] {
@@ -98,8 +103,7 @@ func (t Targets) IsLess(o Targets) bool {
 		return false
 	}
 
-	sort.Sort(t)
-	sort.Sort(o)
+	t, o = t.sorted(), o.sorted()
 
 	for i, e := range t {
 		if e != o[i] {
@@ -109,6 +113,13 @@ func (t Targets) IsLess(o Targets) bool {
 	return false
 }
 
+// sorted returns a sorted copy of the targets.
+func (t Targets) sorted() Targets {
+	s := NewTargets(t...)
+	sort.Stable(s)
+	return s
+}
+
 // ProviderSpecificProperty holds the name and value of a configuration which is specific to individual DNS providers
 type ProviderSpecificProperty struct {
 	Name  string `json:"name,omitempty"`
@@ -118,6 +129,18 @@ type ProviderSpecificProperty struct {
 // ProviderSpecific holds configuration which is specific to individual DNS providers
 type ProviderSpecific []ProviderSpecificProperty
 
+// DescriptionProperty is the provider-specific property carrying a human readable description of a record.
+// Providers supporting record comments write it along with the record.
+const DescriptionProperty = "description"
+
+// TargetMetadata holds the structured attributes of a single target, like the weight of an A record target
+// or the priority, weight and port of an SRV record target. Unset attributes are zero.
+type TargetMetadata struct {
+	Priority int64 `json:"priority,omitempty"`
+	Weight   int64 `json:"weight,omitempty"`
+	Port     int64 `json:"port,omitempty"`
+}
+
 // Endpoint is a high-level way of a connection between a service and an IP
 type Endpoint struct {
 	// The hostname of the DNS record
@@ -136,6 +159,9 @@ type Endpoint struct {
 	// ProviderSpecific stores provider specific config
 	// +optional
 	ProviderSpecific ProviderSpecific `json:"providerSpecific,omitempty"`
+	// TargetMetadata stores the structured attributes of the targets by target
+	// +optional
+	TargetMetadata map[string]TargetMetadata `json:"targetMetadata,omitempty"`
 }
 
 // NewEndpoint initialization method to be used to create an endpoint
@@ -188,6 +214,36 @@ func (e *Endpoint) GetProviderSpecificProperty(key string) (ProviderSpecificProp
 	return ProviderSpecificProperty{}, false
 }
 
+// WithTargetMetadata attaches structured attributes to a target of the Endpoint and returns the Endpoint.
+func (e *Endpoint) WithTargetMetadata(target string, metadata TargetMetadata) *Endpoint {
+	if e.TargetMetadata == nil {
+		e.TargetMetadata = map[string]TargetMetadata{}
+	}
+	e.TargetMetadata[strings.TrimSuffix(target, ".")] = metadata
+	return e
+}
+
+// GetTargetMetadata returns the structured attributes of a target and whether the target has any.
+func (e *Endpoint) GetTargetMetadata(target string) (TargetMetadata, bool) {
+	metadata, ok := e.TargetMetadata[strings.TrimSuffix(target, ".")]
+	return metadata, ok
+}
+
+// SameTargetMetadata returns true if the targets of both endpoints have the same structured attributes.
+func (e *Endpoint) SameTargetMetadata(o *Endpoint) bool {
+	for target, metadata := range e.TargetMetadata {
+		if other, _ := o.GetTargetMetadata(target); other != metadata {
+			return false
+		}
+	}
+	for target, metadata := range o.TargetMetadata {
+		if other, _ := e.GetTargetMetadata(target); other != metadata {
+			return false
+		}
+	}
+	return true
+}
+
 func (e *Endpoint) String() string {
 	return fmt.Sprintf("%s %d IN %s %s %s %s", e.DNSName, e.RecordTTL, e.RecordType, e.SetIdentifier, e.Targets, e.ProviderSpecific)
 }
@@ -75,3 +75,61 @@ func TestSameFailures(t *testing.T) {
 		}
 	}
 }
+
+func TestSamePreservesOrder(t *testing.T) {
+	a := Targets{"8.8.8.8", "1.2.3.4"}
+	b := Targets{"1.2.3.4", "8.8.8.8"}
+
+	if !a.Same(b) {
+		t.Errorf("%#v should equal %#v", a, b)
+	}
+	if a[0] != "8.8.8.8" || b[0] != "1.2.3.4" {
+		t.Errorf("comparing targets must not reorder them, got %#v and %#v", a, b)
+	}
+}
+
+func TestSameTargetMetadata(t *testing.T) {
+	for _, tc := range []struct {
+		title    string
+		a        *Endpoint
+		b        *Endpoint
+		expected bool
+	}{
+		{
+			title:    "without metadata",
+			a:        NewEndpoint("example.org", RecordTypeA, "1.2.3.4"),
+			b:        NewEndpoint("example.org", RecordTypeA, "1.2.3.4"),
+			expected: true,
+		},
+		{
+			title:    "same metadata",
+			a:        NewEndpoint("example.org", RecordTypeA, "1.2.3.4").WithTargetMetadata("1.2.3.4", TargetMetadata{Weight: 10}),
+			b:        NewEndpoint("example.org", RecordTypeA, "1.2.3.4").WithTargetMetadata("1.2.3.4", TargetMetadata{Weight: 10}),
+			expected: true,
+		},
+		{
+			title:    "zero metadata equals missing metadata",
+			a:        NewEndpoint("example.org", RecordTypeA, "1.2.3.4").WithTargetMetadata("1.2.3.4", TargetMetadata{}),
+			b:        NewEndpoint("example.org", RecordTypeA, "1.2.3.4"),
+			expected: true,
+		},
+		{
+			title:    "different weight",
+			a:        NewEndpoint("example.org", RecordTypeA, "1.2.3.4").WithTargetMetadata("1.2.3.4", TargetMetadata{Weight: 10}),
+			b:        NewEndpoint("example.org", RecordTypeA, "1.2.3.4").WithTargetMetadata("1.2.3.4", TargetMetadata{Weight: 20}),
+			expected: false,
+		},
+		{
+			title:    "metadata removed",
+			a:        NewEndpoint("example.org", RecordTypeA, "1.2.3.4"),
+			b:        NewEndpoint("example.org", RecordTypeA, "1.2.3.4").WithTargetMetadata("1.2.3.4.", TargetMetadata{Priority: 1}),
+			expected: false,
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			if tc.a.SameTargetMetadata(tc.b) != tc.expected {
+				t.Errorf("expected SameTargetMetadata to be %t for %v and %v", tc.expected, tc.a.TargetMetadata, tc.b.TargetMetadata)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package endpoint
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// The provider-specific properties describing the health check of an endpoint. Providers which
+// support health checks materialize them, e.g. as Route53 health checks, the others ignore them.
+const (
+	HealthCheckKeyPrefix           = "health-check/"
+	HealthCheckProtocolKey         = "health-check/protocol"
+	HealthCheckPortKey             = "health-check/port"
+	HealthCheckPathKey             = "health-check/path"
+	HealthCheckIntervalKey         = "health-check/interval"
+	HealthCheckFailureThresholdKey = "health-check/failure-threshold"
+)
+
+const (
+	// HealthCheckProtocolHTTP is a health check protocol enum value
+	HealthCheckProtocolHTTP = "HTTP"
+	// HealthCheckProtocolHTTPS is a health check protocol enum value
+	HealthCheckProtocolHTTPS = "HTTPS"
+	// HealthCheckProtocolTCP is a health check protocol enum value
+	HealthCheckProtocolTCP = "TCP"
+)
+
+const (
+	defaultHealthCheckInterval         = 30
+	defaultHealthCheckFailureThreshold = 3
+)
+
+// HealthCheck is a provider-agnostic description of how the targets of an endpoint are monitored
+type HealthCheck struct {
+	// Protocol is one of HTTP, HTTPS or TCP
+	Protocol string
+	// Port defaults to 80 for HTTP, 443 for HTTPS and is required for TCP
+	Port int
+	// Path requested by HTTP and HTTPS health checks, defaults to /
+	Path string
+	// Interval between two checks in seconds, defaults to 30
+	Interval int
+	// FailureThreshold is the number of consecutive failed checks before a target is unhealthy, defaults to 3
+	FailureThreshold int
+}
+
+// WithDefaults returns a copy of the health check with the unset fields defaulted and validates it.
+func (hc HealthCheck) WithDefaults() (*HealthCheck, error) {
+	switch hc.Protocol {
+	case HealthCheckProtocolHTTP, HealthCheckProtocolHTTPS:
+		if hc.Path == "" {
+			hc.Path = "/"
+		}
+		if hc.Port == 0 && hc.Protocol == HealthCheckProtocolHTTP {
+			hc.Port = 80
+		}
+		if hc.Port == 0 && hc.Protocol == HealthCheckProtocolHTTPS {
+			hc.Port = 443
+		}
+	case HealthCheckProtocolTCP:
+		if hc.Path != "" {
+			return nil, fmt.Errorf("TCP health checks don't support a path")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported health check protocol %q", hc.Protocol)
+	}
+	if hc.Port <= 0 || hc.Port > 65535 {
+		return nil, fmt.Errorf("invalid health check port %d", hc.Port)
+	}
+	if hc.Interval == 0 {
+		hc.Interval = defaultHealthCheckInterval
+	}
+	// the providers validate the intervals they support
+	if hc.Interval < 0 {
+		return nil, fmt.Errorf("invalid health check interval %d", hc.Interval)
+	}
+	if hc.FailureThreshold == 0 {
+		hc.FailureThreshold = defaultHealthCheckFailureThreshold
+	}
+	if hc.FailureThreshold < 0 {
+		return nil, fmt.Errorf("invalid health check failure threshold %d", hc.FailureThreshold)
+	}
+	return &hc, nil
+}
+
+// ProviderSpecific returns the provider-specific properties describing the health check
+func (hc HealthCheck) ProviderSpecific() ProviderSpecific {
+	ps := ProviderSpecific{
+		{Name: HealthCheckProtocolKey, Value: hc.Protocol},
+		{Name: HealthCheckPortKey, Value: strconv.Itoa(hc.Port)},
+	}
+	if hc.Path != "" {
+		ps = append(ps, ProviderSpecificProperty{Name: HealthCheckPathKey, Value: hc.Path})
+	}
+	return append(ps,
+		ProviderSpecificProperty{Name: HealthCheckIntervalKey, Value: strconv.Itoa(hc.Interval)},
+		ProviderSpecificProperty{Name: HealthCheckFailureThresholdKey, Value: strconv.Itoa(hc.FailureThreshold)},
+	)
+}
+
+// WithHealthCheck attaches the properties of a health check to the Endpoint and returns the Endpoint.
+func (e *Endpoint) WithHealthCheck(hc *HealthCheck) *Endpoint {
+	for _, property := range hc.ProviderSpecific() {
+		e.WithProviderSpecific(property.Name, property.Value)
+	}
+	return e
+}
+
+// HealthCheck returns the health check of the Endpoint or nil if it doesn't have one.
+func (e *Endpoint) HealthCheck() (*HealthCheck, error) {
+	protocol, ok := e.GetProviderSpecificProperty(HealthCheckProtocolKey)
+	if !ok {
+		return nil, nil
+	}
+
+	hc := HealthCheck{Protocol: protocol.Value}
+	for key, value := range map[string]*int{
+		HealthCheckPortKey:             &hc.Port,
+		HealthCheckIntervalKey:         &hc.Interval,
+		HealthCheckFailureThresholdKey: &hc.FailureThreshold,
+	} {
+		property, ok := e.GetProviderSpecificProperty(key)
+		if !ok {
+			continue
+		}
+		i, err := strconv.Atoi(property.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q of %s", property.Value, key)
+		}
+		*value = i
+	}
+	if path, ok := e.GetProviderSpecificProperty(HealthCheckPathKey); ok {
+		hc.Path = path.Value
+	}
+	return hc.WithDefaults()
+}
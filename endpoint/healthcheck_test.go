@@ -0,0 +1,98 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package endpoint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthCheckWithDefaults(t *testing.T) {
+	for _, tc := range []struct {
+		title     string
+		hc        HealthCheck
+		expected  *HealthCheck
+		expectErr bool
+	}{
+		{
+			title:    "http health checks default to port 80 and path /",
+			hc:       HealthCheck{Protocol: HealthCheckProtocolHTTP},
+			expected: &HealthCheck{Protocol: HealthCheckProtocolHTTP, Port: 80, Path: "/", Interval: 30, FailureThreshold: 3},
+		},
+		{
+			title:    "https health checks default to port 443",
+			hc:       HealthCheck{Protocol: HealthCheckProtocolHTTPS, Path: "/healthz", Interval: 10},
+			expected: &HealthCheck{Protocol: HealthCheckProtocolHTTPS, Port: 443, Path: "/healthz", Interval: 10, FailureThreshold: 3},
+		},
+		{
+			title:    "tcp health checks",
+			hc:       HealthCheck{Protocol: HealthCheckProtocolTCP, Port: 5432, FailureThreshold: 1},
+			expected: &HealthCheck{Protocol: HealthCheckProtocolTCP, Port: 5432, Interval: 30, FailureThreshold: 1},
+		},
+		{
+			title:     "tcp health checks require a port",
+			hc:        HealthCheck{Protocol: HealthCheckProtocolTCP},
+			expectErr: true,
+		},
+		{
+			title:     "tcp health checks don't support a path",
+			hc:        HealthCheck{Protocol: HealthCheckProtocolTCP, Port: 22, Path: "/"},
+			expectErr: true,
+		},
+		{
+			title:     "negative intervals are rejected",
+			hc:        HealthCheck{Protocol: HealthCheckProtocolHTTP, Interval: -10},
+			expectErr: true,
+		},
+		{
+			title:     "unknown protocols are rejected",
+			hc:        HealthCheck{Protocol: "UDP", Port: 53},
+			expectErr: true,
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			hc, err := tc.hc.WithDefaults()
+			if tc.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, hc)
+		})
+	}
+}
+
+func TestEndpointHealthCheck(t *testing.T) {
+	ep := NewEndpoint("example.org", RecordTypeA, "1.2.3.4")
+	hc, err := ep.HealthCheck()
+	require.NoError(t, err)
+	assert.Nil(t, hc)
+
+	expected := &HealthCheck{Protocol: HealthCheckProtocolHTTPS, Port: 8443, Path: "/healthz", Interval: 10, FailureThreshold: 2}
+	ep.WithHealthCheck(expected)
+	hc, err = ep.HealthCheck()
+	require.NoError(t, err)
+	assert.Equal(t, expected, hc)
+
+	ep = NewEndpoint("example.org", RecordTypeA, "1.2.3.4").
+		WithProviderSpecific(HealthCheckProtocolKey, HealthCheckProtocolHTTP).
+		WithProviderSpecific(HealthCheckPortKey, "eighty")
+	_, err = ep.HealthCheck()
+	assert.Error(t, err)
+}
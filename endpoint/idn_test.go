@@ -0,0 +1,51 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package endpoint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToASCIIName(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		expected  string
+		expectErr bool
+	}{
+		{"example.org", "example.org", false},
+		{"_acme-challenge.example.org", "_acme-challenge.example.org", false},
+		{"*.example.org", "*.example.org", false},
+		{"bücher.example.org", "xn--bcher-kva.example.org", false},
+		{"*.Bücher.example.org", "*.xn--bcher-kva.example.org", false},
+		{"xn--bcher-kva.example.org", "xn--bcher-kva.example.org", false},
+		{"例え.テスト", "xn--r8jz45g.xn--zckzah", false},
+		{"bü cher.example.org", "", true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ascii, err := ToASCIIName(tc.name)
+			if tc.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, ascii)
+		})
+	}
+}
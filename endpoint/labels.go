@@ -39,8 +39,20 @@ const (
 	// supposed to be inserted by AWS SD Provider, and parsed into OwnerLabelKey and ResourceLabelKey key by AWS SD Registry
 	AWSSDDescriptionLabel = "aws-sd-description"
 
+	// OwnedRecordLabelKey is the name of the label that identifies the record an ownership TXT record belongs to.
+	// It is set by the TXT registry and not persisted, providers use it to keep both records in the same batch.
+	OwnedRecordLabelKey = "owned-record"
+
 	// DualstackLabelKey is the name of the label that identifies dualstack endpoints
 	DualstackLabelKey = "dualstack"
+
+	// ClusterLabelKey is the name of the label that identifies the cluster of the owner of an Endpoint, so
+	// instances of several clusters sharing an owner ID don't overwrite each other's records.
+	ClusterLabelKey = "cluster"
+
+	// DeletionPendingLabelKey is the name of the label that stores since when, in RFC 3339 format, the
+	// resource of a record is gone and the record waits for the orphan deletion grace period.
+	DeletionPendingLabelKey = "deletion-pending-since"
 )
 
 // Labels store metadata related to the endpoint
@@ -57,10 +69,10 @@ func NewLabels() Labels {
 // no heritage automatically assumes is not owned by external-dns and returns invalidHeritage error
 func NewLabelsFromString(labelText string) (Labels, error) {
 	endpointLabels := map[string]string{}
-	labelText = strings.Trim(labelText, "\"") // drop quotes
-	tokens := strings.Split(labelText, ",")
+	tokens := strings.Split(normalizeLabelText(labelText), ",")
 	foundExternalDNSHeritage := false
 	for _, token := range tokens {
+		token = strings.TrimSpace(token)
 		if len(strings.Split(token, "=")) != 2 {
 			continue
 		}
@@ -85,6 +97,36 @@ func NewLabelsFromString(labelText string) (Labels, error) {
 	return endpointLabels, nil
 }
 
+// normalizeLabelText undoes the rewrites providers apply to the content of TXT records, so labels
+// survive a round-trip through any of them: surrounding whitespace and quotes are dropped, escaped
+// quotes are unescaped and content split into multiple character strings, e.g. "heritage=" "external-dns",
+// is joined again.
+func normalizeLabelText(labelText string) string {
+	labelText = strings.TrimSpace(labelText)
+	labelText = strings.Replace(labelText, `\"`, `"`, -1)
+	labelText = joinCharacterStrings(labelText)
+	return strings.Trim(labelText, "\"") // drop quotes
+}
+
+// joinCharacterStrings removes the quotes and whitespace between the character strings of TXT content.
+func joinCharacterStrings(labelText string) string {
+	var b strings.Builder
+	for i := 0; i < len(labelText); i++ {
+		if labelText[i] == '"' {
+			j := i + 1
+			for j < len(labelText) && (labelText[j] == ' ' || labelText[j] == '\t') {
+				j++
+			}
+			if j < len(labelText) && labelText[j] == '"' {
+				i = j
+				continue
+			}
+		}
+		b.WriteByte(labelText[i])
+	}
+	return b.String()
+}
+
 // Serialize transforms endpoints labels into a external-dns recognizable format string
 // withQuotes adds additional quotes
 func (l Labels) Serialize(withQuotes bool) string {
@@ -18,6 +18,7 @@ package endpoint
 
 import (
 	"fmt"
+	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/suite"
@@ -86,6 +87,31 @@ func (suite *LabelsSuite) TestDeserialize() {
 	suite.Nil(multipleHeritage, "if error should return nil")
 }
 
+// TestRoundTrip is the conformance check for the ways providers rewrite the content of TXT records.
+// Labels written by the registry must be read back unchanged from any of them, otherwise ownership is lost.
+func (suite *LabelsSuite) TestRoundTrip() {
+	withQuotes := suite.foo.Serialize(true)
+	withoutQuotes := suite.foo.Serialize(false)
+
+	for _, tc := range []struct {
+		title string
+		text  string
+	}{
+		{"unquoted", withoutQuotes},
+		{"quoted", withQuotes},
+		{"quoted with surrounding whitespace", " " + withQuotes + "\n"},
+		{"escaped quotes", strconv.Quote(withQuotes)},
+		{"escaped quotes of unquoted content", strconv.Quote(withoutQuotes)},
+		{"split into character strings", `"heritage=external-dns,external-dns/own" "er=foo-owner,external-dns/resource=foo-resource"`},
+		{"split into character strings without whitespace", `"heritage=external-dns,external-dns/owner=foo-owner,""external-dns/resource=foo-resource"`},
+		{"whitespace between labels", "heritage=external-dns, external-dns/owner=foo-owner, external-dns/resource=foo-resource"},
+	} {
+		labels, err := NewLabelsFromString(tc.text)
+		suite.NoError(err, tc.title)
+		suite.Equal(suite.foo, labels, tc.title)
+	}
+}
+
 func TestLabels(t *testing.T) {
 	suite.Run(t, new(LabelsSuite))
 }
@@ -0,0 +1,121 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package endpoint
+
+import (
+	"net"
+	"strconv"
+	"strings"
+)
+
+// NormalizeDNSName returns the canonical form of a domain name, which desired and observed endpoints are
+// compared in: without surrounding whitespace and trailing dot, in lower case, with escaped characters like
+// \052 for a wildcard decoded and with internationalized labels converted to punycode.
+func NormalizeDNSName(name string) (string, error) {
+	name = strings.TrimSuffix(unescapeDNSName(strings.TrimSpace(name)), ".")
+	return ToASCIIName(strings.ToLower(name))
+}
+
+// Normalize brings the name and the targets of the endpoint into their canonical form, so the same record
+// doesn't look different depending on whether it was generated by a source or read from a provider. Names
+// and the host names of CNAME targets are normalized with NormalizeDNSName and IP addresses of A and AAAA
+// records are written in their shortest form. The content of other records is kept.
+func (e *Endpoint) Normalize() error {
+	name, err := NormalizeDNSName(e.DNSName)
+	if err != nil {
+		return err
+	}
+
+	targets := make(Targets, 0, len(e.Targets))
+	for _, target := range e.Targets {
+		if target, err = e.normalizeTarget(target); err != nil {
+			return err
+		}
+		targets = append(targets, target)
+	}
+
+	// the structured attributes are stored by target
+	if len(e.TargetMetadata) > 0 {
+		metadata := make(map[string]TargetMetadata, len(e.TargetMetadata))
+		for target, md := range e.TargetMetadata {
+			if normalized, err := e.normalizeTarget(target); err == nil {
+				target = normalized
+			}
+			metadata[target] = md
+		}
+		e.TargetMetadata = metadata
+	}
+
+	e.DNSName = name
+	e.Targets = targets
+	return nil
+}
+
+func (e *Endpoint) normalizeTarget(target string) (string, error) {
+	switch e.RecordType {
+	case RecordTypeCNAME:
+		return NormalizeDNSName(target)
+	case RecordTypeA, RecordTypeAAAA:
+		if ip := net.ParseIP(strings.TrimSpace(target)); ip != nil {
+			return ip.String(), nil
+		}
+	}
+	return target, nil
+}
+
+// unescapeDNSName decodes the escaped characters of a domain name, e.g. \* or the octal code \052, like
+// Route53 returns them. Escaped dots are kept, as they are part of a label.
+func unescapeDNSName(name string) string {
+	if !strings.Contains(name, `\`) {
+		return name
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] != '\\' || i == len(name)-1 {
+			b.WriteByte(name[i])
+			continue
+		}
+		if i+3 < len(name) && isOctalDigits(name[i+1:i+4]) {
+			if c, err := strconv.ParseUint(name[i+1:i+4], 8, 8); err == nil {
+				if c == '.' {
+					b.WriteString(`\.`)
+				} else {
+					b.WriteByte(byte(c))
+				}
+				i += 3
+				continue
+			}
+		}
+		if name[i+1] == '.' {
+			b.WriteString(`\.`)
+		} else {
+			b.WriteByte(name[i+1])
+		}
+		i++
+	}
+	return b.String()
+}
+
+func isOctalDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '7' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package endpoint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeDNSName(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		expected string
+	}{
+		{"foo.example.org", "foo.example.org"},
+		{"foo.example.org.", "foo.example.org"},
+		{" Foo.Example.ORG ", "foo.example.org"},
+		{`\052.example.org`, "*.example.org"},
+		{`\*.example.org`, "*.example.org"},
+		{`foo\056bar.example.org`, `foo\.bar.example.org`},
+		{`foo\.bar.example.org`, `foo\.bar.example.org`},
+		{"Bücher.example.org.", "xn--bcher-kva.example.org"},
+		{"XN--BCHER-KVA.example.org", "xn--bcher-kva.example.org"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			name, err := NormalizeDNSName(tc.name)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, name)
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	for _, tc := range []struct {
+		title    string
+		endpoint *Endpoint
+		expected *Endpoint
+	}{
+		{
+			"CNAME targets are normalized",
+			&Endpoint{DNSName: "Foo.example.org.", RecordType: RecordTypeCNAME, Targets: Targets{"LB.example.org."}},
+			&Endpoint{DNSName: "foo.example.org", RecordType: RecordTypeCNAME, Targets: Targets{"lb.example.org"}},
+		},
+		{
+			"IPv6 addresses are shortened",
+			&Endpoint{DNSName: "foo.example.org", RecordType: RecordTypeAAAA, Targets: Targets{"2001:DB8:0:0:0:0:0:1"}},
+			&Endpoint{DNSName: "foo.example.org", RecordType: RecordTypeAAAA, Targets: Targets{"2001:db8::1"}},
+		},
+		{
+			"target metadata follows the targets",
+			&Endpoint{DNSName: "foo.example.org", RecordType: RecordTypeAAAA, Targets: Targets{"2001:DB8::1"}, TargetMetadata: map[string]TargetMetadata{"2001:DB8::1": {Weight: 10}}},
+			&Endpoint{DNSName: "foo.example.org", RecordType: RecordTypeAAAA, Targets: Targets{"2001:db8::1"}, TargetMetadata: map[string]TargetMetadata{"2001:db8::1": {Weight: 10}}},
+		},
+		{
+			"TXT content is kept",
+			&Endpoint{DNSName: "Foo.example.org", RecordType: RecordTypeTXT, Targets: Targets{"\"Heritage=external-dns\""}},
+			&Endpoint{DNSName: "foo.example.org", RecordType: RecordTypeTXT, Targets: Targets{"\"Heritage=external-dns\""}},
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			require.NoError(t, tc.endpoint.Normalize())
+			assert.Equal(t, tc.expected, tc.endpoint)
+		})
+	}
+}
@@ -0,0 +1,217 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package endpoint
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// The maximum length of a character string in a TXT record, see RFC 1035 section 3.3
+	maxTXTCharacterStringLength = 255
+	// The maximum length of a domain name in its text form without the trailing dot
+	maxDomainNameLength = 253
+	// The maximum length of a label of a domain name
+	maxLabelLength = 63
+)
+
+var (
+	// underscores are allowed for service labels like _sip._tcp.example.org
+	labelRegexp = regexp.MustCompile(`^(\*|[A-Za-z0-9_]([A-Za-z0-9_-]*[A-Za-z0-9])?)$`)
+	// the quoted character strings of TXT content, e.g. "first" "second"
+	txtCharacterStringRegexp = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"`)
+	// the property of a CAA record, e.g. issue "letsencrypt.org", see RFC 8659 section 4.1
+	caaPropertyRegexp = regexp.MustCompile(`^[A-Za-z0-9]+ "[^"]*"$`)
+)
+
+// ValidateTargets checks the targets of the endpoint against the syntax of its record type, so invalid
+// values are rejected before they reach a provider: A and AAAA records need IPv4 and IPv6 addresses,
+// CNAME records a single domain name, the character strings of TXT records must not exceed 255 bytes,
+// SRV records need a priority, a weight, a port and a target and CAA records flags, a tag and a quoted
+// value. Other record types are not validated.
+func (e *Endpoint) ValidateTargets() error {
+	if len(e.Targets) == 0 {
+		return fmt.Errorf("%s record %s has no targets", e.RecordType, e.DNSName)
+	}
+
+	if e.RecordType == RecordTypeCNAME && len(e.Targets) > 1 {
+		return fmt.Errorf("CNAME record %s has more than one target: %s", e.DNSName, e.Targets)
+	}
+
+	for _, target := range e.Targets {
+		if err := validateTarget(e.RecordType, target); err != nil {
+			return fmt.Errorf("%s record %s has invalid target %q: %v", e.RecordType, e.DNSName, target, err)
+		}
+	}
+	return nil
+}
+
+func validateTarget(recordType, target string) error {
+	switch recordType {
+	case RecordTypeA:
+		if ip := net.ParseIP(target); ip == nil || ip.To4() == nil {
+			return fmt.Errorf("not an IPv4 address")
+		}
+	case RecordTypeAAAA:
+		if ip := net.ParseIP(target); ip == nil || ip.To4() != nil {
+			return fmt.Errorf("not an IPv6 address")
+		}
+	case RecordTypeCNAME:
+		if net.ParseIP(target) != nil {
+			return fmt.Errorf("an IP address instead of a domain name")
+		}
+		return validateDomainName(target)
+	case RecordTypeTXT:
+		return validateTXTContent(target)
+	case RecordTypeSRV:
+		fields := strings.Fields(target)
+		// NewEndpoint trims the trailing dot of the targets, which leaves nothing of the "." target
+		if len(fields) == 3 && strings.HasSuffix(target, " ") {
+			fields = append(fields, ".")
+		}
+		if len(fields) != 4 {
+			return fmt.Errorf("expected priority, weight, port and target, found %d fields", len(fields))
+		}
+		for i, name := range []string{"priority", "weight", "port"} {
+			if _, err := strconv.ParseUint(fields[i], 10, 16); err != nil {
+				return fmt.Errorf("invalid %s %q", name, fields[i])
+			}
+		}
+		// a target of "." means the service is not available
+		if fields[3] != "." {
+			return validateDomainName(fields[3])
+		}
+	case RecordTypeCAA:
+		fields := strings.SplitN(target, " ", 2)
+		if len(fields) != 2 {
+			return fmt.Errorf("expected flags, tag and value")
+		}
+		if _, err := strconv.ParseUint(fields[0], 10, 8); err != nil {
+			return fmt.Errorf("invalid flags %q", fields[0])
+		}
+		if !caaPropertyRegexp.MatchString(fields[1]) {
+			return fmt.Errorf("invalid property %q, expected a tag and a quoted value", fields[1])
+		}
+	}
+	return nil
+}
+
+// validateDomainName checks the lengths and characters of the labels of a domain name.
+func validateDomainName(name string) error {
+	name = strings.TrimSuffix(name, ".")
+	if name == "" {
+		return fmt.Errorf("empty domain name")
+	}
+	if len(name) > maxDomainNameLength {
+		return fmt.Errorf("domain name longer than %d characters", maxDomainNameLength)
+	}
+	for _, label := range strings.Split(name, ".") {
+		if len(label) > maxLabelLength {
+			return fmt.Errorf("label %q longer than %d characters", label, maxLabelLength)
+		}
+		if !labelRegexp.MatchString(label) {
+			return fmt.Errorf("invalid label %q", label)
+		}
+	}
+	return nil
+}
+
+// validateTXTContent checks that no character string of TXT content exceeds 255 bytes. Quoted content may
+// consist of several character strings, e.g. "first" "second", unquoted content is a single one.
+func validateTXTContent(content string) error {
+	for _, s := range txtCharacterStrings(content) {
+		if len(s) > maxTXTCharacterStringLength {
+			return fmt.Errorf("character string longer than %d bytes, split it into quoted strings", maxTXTCharacterStringLength)
+		}
+	}
+	return nil
+}
+
+// txtCharacterStrings returns the character strings of TXT content, as they are quoted, i.e. with their
+// escape sequences.
+func txtCharacterStrings(content string) []string {
+	if len(content) < 2 || content[0] != '"' || content[len(content)-1] != '"' {
+		return []string{content}
+	}
+	var characterStrings []string
+	for _, match := range txtCharacterStringRegexp.FindAllStringSubmatch(content, -1) {
+		characterStrings = append(characterStrings, match[1])
+	}
+	return characterStrings
+}
+
+// SplitTXTTargets splits the character strings of TXT targets which exceed 255 bytes, e.g. a long DKIM
+// key, into quoted strings of at most 255 bytes, so they are published instead of rejected. The targets
+// within the limit and of other record types are left alone.
+func (e *Endpoint) SplitTXTTargets() {
+	if e.RecordType != RecordTypeTXT {
+		return
+	}
+	for i, target := range e.Targets {
+		if validateTXTContent(target) != nil {
+			e.Targets[i] = splitTXTContent(target)
+		}
+	}
+}
+
+// splitTXTContent splits the character strings of TXT content into quoted strings of at most 255 bytes.
+// The quotes and backslashes of unquoted content are escaped. Escape sequences and multi-byte characters
+// aren't split.
+func splitTXTContent(content string) string {
+	characterStrings := txtCharacterStrings(content)
+	if len(characterStrings) == 1 && characterStrings[0] == content {
+		characterStrings[0] = strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(content)
+	}
+
+	var chunks []string
+	for _, s := range characterStrings {
+		start := 0
+		for i := 0; i < len(s); {
+			size := txtCharacterSize(s[i:])
+			if i+size-start > maxTXTCharacterStringLength {
+				chunks = append(chunks, s[start:i])
+				start = i
+			}
+			i += size
+		}
+		chunks = append(chunks, s[start:])
+	}
+	return `"` + strings.Join(chunks, `" "`) + `"`
+}
+
+// txtCharacterSize returns the length of the first character of a quoted character string, i.e. of its
+// escape sequence, e.g. \" or \065, or its UTF-8 encoding.
+func txtCharacterSize(s string) int {
+	if s[0] != '\\' || len(s) == 1 {
+		_, size := utf8.DecodeRuneInString(s)
+		return size
+	}
+	if len(s) >= 4 && isDigit(s[1]) && isDigit(s[2]) && isDigit(s[3]) {
+		return 4
+	}
+	_, size := utf8.DecodeRuneInString(s[1:])
+	return 1 + size
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
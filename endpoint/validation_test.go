@@ -0,0 +1,101 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package endpoint
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateTargets(t *testing.T) {
+	longString := strings.Repeat("a", 256)
+	chunk := strings.Repeat("a", 255)
+
+	for _, tc := range []struct {
+		title      string
+		recordType string
+		targets    Targets
+		valid      bool
+	}{
+		{"IPv4 address", RecordTypeA, Targets{"1.2.3.4", "5.6.7.8"}, true},
+		{"hostname in A record", RecordTypeA, Targets{"lb.example.org"}, false},
+		{"IPv6 address in A record", RecordTypeA, Targets{"2001:db8::1"}, false},
+		{"IPv6 address", RecordTypeAAAA, Targets{"2001:db8::1"}, true},
+		{"IPv4 address in AAAA record", RecordTypeAAAA, Targets{"1.2.3.4"}, false},
+		{"no targets", RecordTypeA, Targets{}, false},
+		{"domain name", RecordTypeCNAME, Targets{"lb-1.example.org"}, true},
+		{"domain name with trailing dot", RecordTypeCNAME, Targets{"lb.example.org."}, true},
+		{"multiple CNAME targets", RecordTypeCNAME, Targets{"a.example.org", "b.example.org"}, false},
+		{"IP address in CNAME record", RecordTypeCNAME, Targets{"1.2.3.4"}, false},
+		{"space in domain name", RecordTypeCNAME, Targets{"lb example.org"}, false},
+		{"label starting with a hyphen", RecordTypeCNAME, Targets{"-lb.example.org"}, false},
+		{"too long label", RecordTypeCNAME, Targets{strings.Repeat("a", 64) + ".example.org"}, false},
+		{"empty label", RecordTypeCNAME, Targets{"lb..example.org"}, false},
+		{"short TXT", RecordTypeTXT, Targets{"\"heritage=external-dns\""}, true},
+		{"too long unquoted TXT", RecordTypeTXT, Targets{longString}, false},
+		{"too long quoted TXT", RecordTypeTXT, Targets{"\"" + longString + "\""}, false},
+		{"TXT split into character strings", RecordTypeTXT, Targets{"\"" + chunk + "\" \"" + chunk + "\""}, true},
+		{"SRV", RecordTypeSRV, Targets{"10 5 5060 sip.example.org"}, true},
+		{"SRV of unavailable service", RecordTypeSRV, Targets{"0 0 0 ."}, true},
+		{"SRV without weight", RecordTypeSRV, Targets{"10 5060 sip.example.org"}, false},
+		{"SRV with invalid port", RecordTypeSRV, Targets{"10 5 70000 sip.example.org"}, false},
+		{"CAA", RecordTypeCAA, Targets{"0 issue \"letsencrypt.org\"", "128 iodef \"mailto:security@example.org\""}, true},
+		{"CAA forbidding all issuers", RecordTypeCAA, Targets{"0 issue \";\""}, true},
+		{"CAA with unquoted value", RecordTypeCAA, Targets{"0 issue letsencrypt.org"}, false},
+		{"CAA with invalid flags", RecordTypeCAA, Targets{"256 issue \"letsencrypt.org\""}, false},
+		{"unvalidated record type", "MX", Targets{"10 mail.example.org"}, true},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			err := NewEndpoint("foo.example.org", tc.recordType, tc.targets...).ValidateTargets()
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestSplitTXTTargets(t *testing.T) {
+	chunk := strings.Repeat("a", 255)
+
+	for _, tc := range []struct {
+		title    string
+		target   string
+		expected string
+	}{
+		{"short TXT", "v=spf1 -all", "v=spf1 -all"},
+		{"too long unquoted TXT", chunk + "bc", `"` + chunk + `" "bc"`},
+		{"too long quoted TXT", `"` + chunk + `b" "c"`, `"` + chunk + `" "b" "c"`},
+		{"escaped quotes of unquoted TXT", strings.Repeat("a", 254) + `"b`, `"` + strings.Repeat("a", 254) + `" "\"b"`},
+		{"escape sequence isn't split", `"` + strings.Repeat("a", 253) + `\065b"`, `"` + strings.Repeat("a", 253) + `" "\065b"`},
+		{"multi-byte character isn't split", strings.Repeat("a", 254) + "ü", `"` + strings.Repeat("a", 254) + `" "ü"`},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			ep := NewEndpoint("foo.example.org", RecordTypeTXT, tc.target)
+			ep.SplitTXTTargets()
+			assert.Equal(t, Targets{tc.expected}, ep.Targets)
+			assert.NoError(t, ep.ValidateTargets())
+		})
+	}
+
+	ep := NewEndpoint("foo.example.org", RecordTypeCNAME, chunk)
+	ep.SplitTXTTargets()
+	assert.Equal(t, Targets{chunk}, ep.Targets)
+}
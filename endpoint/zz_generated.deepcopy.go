@@ -151,6 +151,13 @@ func (in *Endpoint) DeepCopyInto(out *Endpoint) {
 			(*out)[key] = val
 		}
 	}
+	if in.TargetMetadata != nil {
+		in, out := &in.TargetMetadata, &out.TargetMetadata
+		*out = make(map[string]TargetMetadata, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	return
 }
 
@@ -18,9 +18,12 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -30,25 +33,18 @@ import (
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
 	"sigs.k8s.io/external-dns/controller"
-	"sigs.k8s.io/external-dns/pkg/apis/externaldns"
+	apis "sigs.k8s.io/external-dns/pkg/apis/externaldns"
 	"sigs.k8s.io/external-dns/pkg/apis/externaldns/validation"
-	"sigs.k8s.io/external-dns/plan"
-	"sigs.k8s.io/external-dns/provider"
-	"sigs.k8s.io/external-dns/registry"
-	"sigs.k8s.io/external-dns/source"
+	"sigs.k8s.io/external-dns/pkg/externaldns"
 )
 
 func main() {
-	cfg := externaldns.NewConfig()
+	cfg := apis.NewConfig()
 	if err := cfg.ParseFlags(os.Args[1:]); err != nil {
 		log.Fatalf("flag parsing error: %v", err)
 	}
 	log.Infof("config: %s", cfg)
 
-	if err := validation.ValidateConfig(cfg); err != nil {
-		log.Fatalf("config validation failed: %v", err)
-	}
-
 	if cfg.LogFormat == "json" {
 		log.SetFormatter(&log.JSONFormatter{})
 	}
@@ -62,232 +58,69 @@ func main() {
 	}
 	log.SetLevel(ll)
 
-	ctx := context.Background()
-
-	stopChan := make(chan struct{}, 1)
-
-	go serveMetrics(cfg.MetricsAddress)
-	go handleSigterm(stopChan)
-
-	// Create a source.Config from the flags passed by the user.
-	sourceCfg := &source.Config{
-		Namespace:                   cfg.Namespace,
-		AnnotationFilter:            cfg.AnnotationFilter,
-		FQDNTemplate:                cfg.FQDNTemplate,
-		CombineFQDNAndAnnotation:    cfg.CombineFQDNAndAnnotation,
-		IgnoreHostnameAnnotation:    cfg.IgnoreHostnameAnnotation,
-		Compatibility:               cfg.Compatibility,
-		PublishInternal:             cfg.PublishInternal,
-		PublishHostIP:               cfg.PublishHostIP,
-		ConnectorServer:             cfg.ConnectorSourceServer,
-		CRDSourceAPIVersion:         cfg.CRDSourceAPIVersion,
-		CRDSourceKind:               cfg.CRDSourceKind,
-		KubeConfig:                  cfg.KubeConfig,
-		KubeMaster:                  cfg.Master,
-		ServiceTypeFilter:           cfg.ServiceTypeFilter,
-		IstioIngressGatewayServices: cfg.IstioIngressGatewayServices,
-		CFAPIEndpoint:               cfg.CFAPIEndpoint,
-		CFUsername:                  cfg.CFUsername,
-		CFPassword:                  cfg.CFPassword,
-		ContourLoadBalancerService:  cfg.ContourLoadBalancerService,
+	// the readiness report includes the validation of the config and the simulation needs neither
+	// sources nor a provider
+	switch cfg.Command {
+	case "validate":
+		validate(cfg)
+	case "simulate":
+		if err := externaldns.Simulate(os.Stdout, cfg.SimulateInput, cfg.Policy, externaldns.ManagedRecordTypesFromConfig(cfg)); err != nil {
+			log.Fatalf("simulation failed: %v", err)
+		}
+		os.Exit(0)
 	}
 
-	// Lookup all the selected sources by names and pass them the desired configuration.
-	sources, err := source.ByNames(&source.SingletonClientGenerator{
-		KubeConfig: cfg.KubeConfig,
-		KubeMaster: cfg.Master,
-		// If update events are enabled, disable timeout.
-		RequestTimeout: func() time.Duration {
-			if cfg.UpdateEvents {
-				return 0
-			}
-			return cfg.RequestTimeout
-		}(),
-	}, cfg.Sources, sourceCfg)
-	if err != nil {
-		log.Fatal(err)
+	// the pipelines are validated separately, the other commands use the config of the process
+	if len(cfg.PipelineFiles) == 0 || cfg.Command != "run" {
+		if err := validation.ValidateConfig(cfg); err != nil {
+			log.Fatalf("config validation failed: %v", err)
+		}
 	}
 
-	// Combine multiple sources into a single, deduplicated source.
-	endpointsSource := source.NewDedupSource(source.NewMultiSource(sources))
-
-	domainFilter := provider.NewDomainFilterWithExclusions(cfg.DomainFilter, cfg.ExcludeDomains)
-	zoneIDFilter := provider.NewZoneIDFilter(cfg.ZoneIDFilter)
-	zoneTypeFilter := provider.NewZoneTypeFilter(cfg.AWSZoneType)
-	zoneTagFilter := provider.NewZoneTagFilter(cfg.AWSZoneTagFilter)
-
-	var p provider.Provider
-	switch cfg.Provider {
-	case "akamai":
-		p = provider.NewAkamaiProvider(
-			provider.AkamaiConfig{
-				DomainFilter:          domainFilter,
-				ZoneIDFilter:          zoneIDFilter,
-				ServiceConsumerDomain: cfg.AkamaiServiceConsumerDomain,
-				ClientToken:           cfg.AkamaiClientToken,
-				ClientSecret:          cfg.AkamaiClientSecret,
-				AccessToken:           cfg.AkamaiAccessToken,
-				DryRun:                cfg.DryRun,
-			},
-		)
-	case "alibabacloud":
-		p, err = provider.NewAlibabaCloudProvider(cfg.AlibabaCloudConfigFile, domainFilter, zoneIDFilter, cfg.AlibabaCloudZoneType, cfg.DryRun)
-	case "aws":
-		p, err = provider.NewAWSProvider(
-			provider.AWSConfig{
-				DomainFilter:         domainFilter,
-				ZoneIDFilter:         zoneIDFilter,
-				ZoneTypeFilter:       zoneTypeFilter,
-				ZoneTagFilter:        zoneTagFilter,
-				BatchChangeSize:      cfg.AWSBatchChangeSize,
-				BatchChangeInterval:  cfg.AWSBatchChangeInterval,
-				EvaluateTargetHealth: cfg.AWSEvaluateTargetHealth,
-				AssumeRole:           cfg.AWSAssumeRole,
-				APIRetries:           cfg.AWSAPIRetries,
-				PreferCNAME:          cfg.AWSPreferCNAME,
-				DryRun:               cfg.DryRun,
-			},
-		)
-	case "aws-sd":
-		// Check that only compatible Registry is used with AWS-SD
-		if cfg.Registry != "noop" && cfg.Registry != "aws-sd" {
-			log.Infof("Registry \"%s\" cannot be used with AWS Cloud Map. Switching to \"aws-sd\".", cfg.Registry)
-			cfg.Registry = "aws-sd"
+	switch cfg.Command {
+	case "adopt":
+		if err := externaldns.Adopt(context.Background(), os.Stdout, cfg); err != nil {
+			log.Fatalf("adoption failed: %v", err)
 		}
-		p, err = provider.NewAWSSDProvider(domainFilter, cfg.AWSZoneType, cfg.AWSAssumeRole, cfg.DryRun)
-	case "azure-dns", "azure":
-		p, err = provider.NewAzureProvider(cfg.AzureConfigFile, domainFilter, zoneIDFilter, cfg.AzureResourceGroup, cfg.AzureUserAssignedIdentityClientID, cfg.DryRun)
-	case "azure-private-dns":
-		p, err = provider.NewAzurePrivateDNSProvider(domainFilter, zoneIDFilter, cfg.AzureResourceGroup, cfg.AzureSubscriptionID, cfg.DryRun)
-	case "vinyldns":
-		p, err = provider.NewVinylDNSProvider(domainFilter, zoneIDFilter, cfg.DryRun)
-	case "cloudflare":
-		p, err = provider.NewCloudFlareProvider(domainFilter, zoneIDFilter, cfg.CloudflareZonesPerPage, cfg.CloudflareProxied, cfg.DryRun)
-	case "rcodezero":
-		p, err = provider.NewRcodeZeroProvider(domainFilter, cfg.DryRun, cfg.RcodezeroTXTEncrypt)
-	case "google":
-		p, err = provider.NewGoogleProvider(ctx, cfg.GoogleProject, domainFilter, zoneIDFilter, cfg.GoogleBatchChangeSize, cfg.GoogleBatchChangeInterval, cfg.DryRun)
-	case "digitalocean":
-		p, err = provider.NewDigitalOceanProvider(ctx, domainFilter, cfg.DryRun)
-	case "linode":
-		p, err = provider.NewLinodeProvider(domainFilter, cfg.DryRun, externaldns.Version)
-	case "dnsimple":
-		p, err = provider.NewDnsimpleProvider(domainFilter, zoneIDFilter, cfg.DryRun)
-	case "infoblox":
-		p, err = provider.NewInfobloxProvider(
-			provider.InfobloxConfig{
-				DomainFilter: domainFilter,
-				ZoneIDFilter: zoneIDFilter,
-				Host:         cfg.InfobloxGridHost,
-				Port:         cfg.InfobloxWapiPort,
-				Username:     cfg.InfobloxWapiUsername,
-				Password:     cfg.InfobloxWapiPassword,
-				Version:      cfg.InfobloxWapiVersion,
-				SSLVerify:    cfg.InfobloxSSLVerify,
-				View:         cfg.InfobloxView,
-				MaxResults:   cfg.InfobloxMaxResults,
-				DryRun:       cfg.DryRun,
-			},
-		)
-	case "dyn":
-		p, err = provider.NewDynProvider(
-			provider.DynConfig{
-				DomainFilter:  domainFilter,
-				ZoneIDFilter:  zoneIDFilter,
-				DryRun:        cfg.DryRun,
-				CustomerName:  cfg.DynCustomerName,
-				Username:      cfg.DynUsername,
-				Password:      cfg.DynPassword,
-				MinTTLSeconds: cfg.DynMinTTLSeconds,
-				AppVersion:    externaldns.Version,
-			},
-		)
-	case "coredns", "skydns":
-		p, err = provider.NewCoreDNSProvider(domainFilter, cfg.CoreDNSPrefix, cfg.DryRun)
-	case "rdns":
-		p, err = provider.NewRDNSProvider(
-			provider.RDNSConfig{
-				DomainFilter: domainFilter,
-				DryRun:       cfg.DryRun,
-			},
-		)
-	case "exoscale":
-		p, err = provider.NewExoscaleProvider(cfg.ExoscaleEndpoint, cfg.ExoscaleAPIKey, cfg.ExoscaleAPISecret, cfg.DryRun, provider.ExoscaleWithDomain(domainFilter), provider.ExoscaleWithLogging()), nil
-	case "inmemory":
-		p, err = provider.NewInMemoryProvider(provider.InMemoryInitZones(cfg.InMemoryZones), provider.InMemoryWithDomain(domainFilter), provider.InMemoryWithLogging()), nil
-	case "designate":
-		p, err = provider.NewDesignateProvider(domainFilter, cfg.DryRun)
-	case "pdns":
-		p, err = provider.NewPDNSProvider(
-			ctx,
-			provider.PDNSConfig{
-				DomainFilter: domainFilter,
-				DryRun:       cfg.DryRun,
-				Server:       cfg.PDNSServer,
-				APIKey:       cfg.PDNSAPIKey,
-				TLSConfig: provider.TLSConfig{
-					TLSEnabled:            cfg.PDNSTLSEnabled,
-					CAFilePath:            cfg.TLSCA,
-					ClientCertFilePath:    cfg.TLSClientCert,
-					ClientCertKeyFilePath: cfg.TLSClientCertKey,
-				},
-			},
-		)
-	case "oci":
-		var config *provider.OCIConfig
-		config, err = provider.LoadOCIConfig(cfg.OCIConfigFile)
-		if err == nil {
-			p, err = provider.NewOCIProvider(*config, domainFilter, zoneIDFilter, cfg.DryRun)
+		os.Exit(0)
+	case "migrate-registry":
+		if err := externaldns.MigrateRegistry(context.Background(), os.Stdout, cfg); err != nil {
+			log.Fatalf("migration failed: %v", err)
 		}
-	case "rfc2136":
-		p, err = provider.NewRfc2136Provider(cfg.RFC2136Host, cfg.RFC2136Port, cfg.RFC2136Zone, cfg.RFC2136Insecure, cfg.RFC2136TSIGKeyName, cfg.RFC2136TSIGSecret, cfg.RFC2136TSIGSecretAlg, cfg.RFC2136TAXFR, domainFilter, cfg.DryRun, nil)
-	case "ns1":
-		p, err = provider.NewNS1Provider(
-			provider.NS1Config{
-				DomainFilter: domainFilter,
-				ZoneIDFilter: zoneIDFilter,
-				NS1Endpoint:  cfg.NS1Endpoint,
-				NS1IgnoreSSL: cfg.NS1IgnoreSSL,
-				DryRun:       cfg.DryRun,
-			},
-		)
-	case "transip":
-		p, err = provider.NewTransIPProvider(cfg.TransIPAccountName, cfg.TransIPPrivateKeyFile, domainFilter, cfg.DryRun)
-	default:
-		log.Fatalf("unknown dns provider: %s", cfg.Provider)
-	}
-	if err != nil {
-		log.Fatal(err)
+		os.Exit(0)
+	case "trace":
+		if err := externaldns.Trace(context.Background(), os.Stdout, cfg); err != nil {
+			log.Fatalf("tracing failed: %v", err)
+		}
+		os.Exit(0)
 	}
 
-	var r registry.Registry
-	switch cfg.Registry {
-	case "noop":
-		r, err = registry.NewNoopRegistry(p)
-	case "txt":
-		r, err = registry.NewTXTRegistry(p, cfg.TXTPrefix, cfg.TXTOwnerID, cfg.TXTCacheInterval)
-	case "aws-sd":
-		r, err = registry.NewAWSSDRegistry(p.(*provider.AWSSDProvider), cfg.TXTOwnerID)
-	default:
-		log.Fatalf("unknown registry: %s", cfg.Registry)
+	ctx := context.Background()
+
+	stopChan := make(chan struct{}, 1)
+
+	go serveMetrics(cfg.MetricsAddress)
+	go handleSigterm(stopChan)
+
+	if len(cfg.PipelineFiles) > 0 {
+		runPipelines(ctx, cfg, stopChan)
+		return
 	}
 
+	ctrl, err := externaldns.NewControllerFromConfig(ctx, cfg)
 	if err != nil {
 		log.Fatal(err)
 	}
-
-	policy, exists := plan.Policies[cfg.Policy]
-	if !exists {
-		log.Fatalf("unknown policy: %s", cfg.Policy)
+	if cfg.DuplicateReport {
+		http.Handle("/debug/duplicates", ctrl.DuplicateReport)
 	}
-
-	ctrl := controller.Controller{
-		Source:   endpointsSource,
-		Registry: r,
-		Policy:   policy,
-		Interval: cfg.Interval,
+	if err := runController(ctx, cfg, ctrl, stopChan); err != nil {
+		log.Fatal(err)
 	}
+}
 
+// runController synchronizes once with --once or until the stop channel is closed otherwise.
+func runController(ctx context.Context, cfg *apis.Config, ctrl *controller.Controller, stopChan chan struct{}) error {
 	if cfg.UpdateEvents {
 		// Add RunOnce as the handler function that will be called when ingress/service sources have changed.
 		// Note that k8s Informers will perform an initial list operation, which results in the handler
@@ -296,14 +129,67 @@ func main() {
 	}
 
 	if cfg.Once {
-		err := ctrl.RunOnce(ctx)
+		return ctrl.RunOnce(ctx)
+	}
+	ctrl.Run(ctx, stopChan)
+	return nil
+}
+
+// runPipelines runs a controller for each pipeline of --pipeline in parallel. All controllers are created
+// before the first one starts, so a broken pipeline fails the process at once.
+func runPipelines(ctx context.Context, cfg *apis.Config, stopChan chan struct{}) {
+	pipelines, err := cfg.Pipelines(os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	controllers := make([]*controller.Controller, 0, len(pipelines))
+	for _, pipeline := range pipelines {
+		if err := validation.ValidateConfig(pipeline.Config); err != nil {
+			log.Fatalf("config validation of pipeline %s failed: %v", pipeline.Name, err)
+		}
+		ctrl, err := externaldns.NewControllerFromConfig(ctx, pipeline.Config)
 		if err != nil {
-			log.Fatal(err)
+			log.Fatalf("pipeline %s: %v", pipeline.Name, err)
 		}
+		ctrl.SetPipeline(pipeline.Name)
+		if pipeline.Config.DuplicateReport {
+			http.Handle("/debug/duplicates/"+pipeline.Name, ctrl.DuplicateReport)
+		}
+		controllers = append(controllers, ctrl)
+	}
 
-		os.Exit(0)
+	// a failing pipeline doesn't stop the others, the process fails once all of them are done
+	var (
+		wg     sync.WaitGroup
+		failed int32
+	)
+	for i, pipeline := range pipelines {
+		wg.Add(1)
+		go func(pipeline apis.Pipeline, ctrl *controller.Controller) {
+			defer wg.Done()
+			log.Infof("Starting pipeline %s with the %s provider and the %s policy", pipeline.Name, pipeline.Config.Provider, pipeline.Config.Policy)
+			if err := runController(ctx, pipeline.Config, ctrl, stopChan); err != nil {
+				log.Errorf("pipeline %s: %v", pipeline.Name, err)
+				atomic.AddInt32(&failed, 1)
+			}
+		}(pipeline, controllers[i])
 	}
-	ctrl.Run(ctx, stopChan)
+	wg.Wait()
+	if failed > 0 {
+		log.Fatalf("%d of %d pipelines failed", failed, len(pipelines))
+	}
+}
+
+// validate prints the readiness report of the configuration and exits with a non-zero code if the
+// controller isn't ready to be deployed.
+func validate(cfg *apis.Config) {
+	report := externaldns.Validate(context.Background(), cfg)
+	fmt.Print(report)
+	if !report.Ready() {
+		os.Exit(1)
+	}
+	os.Exit(0)
 }
 
 func handleSigterm(stopChan chan struct{}) {
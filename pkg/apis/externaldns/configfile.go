@@ -0,0 +1,157 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package externaldns
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/alecthomas/kingpin"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	configFileFlag  = "config"
+	configFileEnvar = "EXTERNAL_DNS_CONFIG"
+)
+
+// configFileArgs returns the flags set by the config files of the --config flags as arguments, so they're parsed
+// like flags from the command line. A config file is a YAML map of flag names to their values, e.g.
+//
+//	provider: aws
+//	source: [service, ingress]
+//	txt-cache-interval: 1h
+//	dry-run: true
+//
+// The config files are merged in the order they're specified: a flag replaces the value of the same flag in
+// the previous files, lists included, and a flag set to null removes it again. Flags specified on the command
+// line or as an environment variable take precedence over the merged config files.
+func configFileArgs(app *kingpin.Application, args []string) ([]string, error) {
+	files := configFilesFromArgs(args)
+	if len(files) == 0 {
+		if value := os.Getenv(configFileEnvar); value != "" {
+			files = strings.Split(value, "\n")
+		}
+	}
+
+	merged := map[string]interface{}{}
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file: %v", err)
+		}
+		var values map[string]interface{}
+		if err := yaml.Unmarshal(data, &values); err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s: %v", file, err)
+		}
+		for name, value := range values {
+			if name == configFileFlag || app.GetFlag(name) == nil {
+				return nil, fmt.Errorf("unknown flag %q in config file %s", name, file)
+			}
+			if value == nil {
+				delete(merged, name)
+			} else {
+				merged[name] = value
+			}
+		}
+	}
+
+	names := make([]string, 0, len(merged))
+	for name := range merged {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var configArgs []string
+	for _, name := range names {
+		if flagInArgs(name, args) {
+			continue
+		}
+		if os.Getenv(flagEnvar(name)) != "" {
+			continue
+		}
+		flagArgs, err := configValueArgs(name, merged[name])
+		if err != nil {
+			return nil, err
+		}
+		configArgs = append(configArgs, flagArgs...)
+	}
+	return configArgs, nil
+}
+
+// configFilesFromArgs returns the values of the --config flags on the command line.
+func configFilesFromArgs(args []string) []string {
+	var files []string
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--":
+			return files
+		case args[i] == "--"+configFileFlag && i+1 < len(args):
+			files = append(files, args[i+1])
+			i++
+		case strings.HasPrefix(args[i], "--"+configFileFlag+"="):
+			files = append(files, strings.TrimPrefix(args[i], "--"+configFileFlag+"="))
+		}
+	}
+	return files
+}
+
+// flagInArgs returns true if the flag is specified on the command line.
+func flagInArgs(name string, args []string) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			return false
+		}
+		if arg == "--"+name || arg == "--no-"+name || strings.HasPrefix(arg, "--"+name+"=") {
+			return true
+		}
+	}
+	return false
+}
+
+// flagEnvar returns the environment variable of a flag, e.g. EXTERNAL_DNS_DRY_RUN for --dry-run.
+func flagEnvar(name string) string {
+	return "EXTERNAL_DNS_" + strings.ToUpper(strings.Replace(name, "-", "_", -1))
+}
+
+// configValueArgs returns the arguments setting a flag to the value of a config file.
+func configValueArgs(name string, value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case bool:
+		if v {
+			return []string{"--" + name}, nil
+		}
+		return []string{"--no-" + name}, nil
+	case []interface{}:
+		var args []string
+		for _, item := range v {
+			switch item.(type) {
+			case []interface{}, map[interface{}]interface{}:
+				return nil, fmt.Errorf("invalid value of flag %q in config file: nested values aren't supported", name)
+			}
+			args = append(args, fmt.Sprintf("--%s=%v", name, item))
+		}
+		return args, nil
+	case map[interface{}]interface{}:
+		return nil, fmt.Errorf("invalid value of flag %q in config file: maps aren't supported", name)
+	default:
+		return []string{fmt.Sprintf("--%s=%v", name, v)}, nil
+	}
+}
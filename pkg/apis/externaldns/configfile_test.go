@@ -0,0 +1,144 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package externaldns
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfigFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestParseConfigFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "external-dns-config")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	base := writeConfigFile(t, dir, "base.yaml", `
+provider: aws
+source: [service, ingress]
+domain-filter: [example.org]
+interval: 5m
+txt-owner-id: base
+dry-run: true
+aws-batch-change-size: 100
+`)
+	overlay := writeConfigFile(t, dir, "prod.yaml", `
+source: [ingress]
+domain-filter: null
+txt-owner-id: prod
+dry-run: false
+`)
+
+	for _, tc := range []struct {
+		title    string
+		args     []string
+		envVars  map[string]string
+		validate func(t *testing.T, cfg *Config)
+	}{
+		{
+			title: "a single config file sets the flags",
+			args:  []string{"--config=" + base},
+			validate: func(t *testing.T, cfg *Config) {
+				assert.Equal(t, "aws", cfg.Provider)
+				assert.Equal(t, []string{"service", "ingress"}, cfg.Sources)
+				assert.Equal(t, []string{"example.org"}, cfg.DomainFilter)
+				assert.Equal(t, 5*time.Minute, cfg.Interval)
+				assert.Equal(t, "base", cfg.TXTOwnerID)
+				assert.True(t, cfg.DryRun)
+				assert.Equal(t, 100, cfg.AWSBatchChangeSize)
+				assert.Equal(t, []string{base}, cfg.ConfigFiles)
+			},
+		},
+		{
+			title: "an overlay replaces and removes the flags of the base",
+			args:  []string{"--config", base, "--config=" + overlay},
+			validate: func(t *testing.T, cfg *Config) {
+				assert.Equal(t, "aws", cfg.Provider)
+				assert.Equal(t, []string{"ingress"}, cfg.Sources)
+				assert.Equal(t, []string{""}, cfg.DomainFilter)
+				assert.Equal(t, 5*time.Minute, cfg.Interval)
+				assert.Equal(t, "prod", cfg.TXTOwnerID)
+				assert.False(t, cfg.DryRun)
+			},
+		},
+		{
+			title: "the command line and environment variables override the config files",
+			args:  []string{"--config=" + base, "--config=" + overlay, "--source=service", "--dry-run"},
+			envVars: map[string]string{
+				"EXTERNAL_DNS_TXT_OWNER_ID": "env",
+			},
+			validate: func(t *testing.T, cfg *Config) {
+				assert.Equal(t, []string{"service"}, cfg.Sources)
+				assert.Equal(t, "env", cfg.TXTOwnerID)
+				assert.True(t, cfg.DryRun)
+			},
+		},
+		{
+			title: "the config files can be specified as an environment variable",
+			envVars: map[string]string{
+				"EXTERNAL_DNS_CONFIG": base + "\n" + overlay,
+			},
+			validate: func(t *testing.T, cfg *Config) {
+				assert.Equal(t, "aws", cfg.Provider)
+				assert.Equal(t, "prod", cfg.TXTOwnerID)
+			},
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			originalEnv := setEnv(t, tc.envVars)
+			defer func() { restoreEnv(t, originalEnv) }()
+
+			cfg := NewConfig()
+			require.NoError(t, cfg.ParseFlags(tc.args))
+			tc.validate(t, cfg)
+		})
+	}
+}
+
+func TestParseConfigFilesErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "external-dns-config")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, tc := range []struct {
+		title   string
+		content string
+	}{
+		{"unknown flags are rejected", "provider: aws\nunknown-flag: true\n"},
+		{"config files can't include other files", "config: other.yaml\n"},
+		{"maps are rejected", "provider: aws\nsource:\n  service: true\n"},
+		{"invalid YAML is rejected", "provider: [aws\n"},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			path := writeConfigFile(t, dir, "config.yaml", tc.content)
+			assert.Error(t, NewConfig().ParseFlags([]string{"--config=" + path}))
+		})
+	}
+
+	assert.Error(t, NewConfig().ParseFlags([]string{"--config=" + filepath.Join(dir, "missing.yaml")}))
+}
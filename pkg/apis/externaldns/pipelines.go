@@ -0,0 +1,110 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package externaldns
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const pipelineFlag = "pipeline"
+
+// Pipeline is the configuration of one of several independent synchronizations run by one process,
+// e.g. the public records synchronized to one provider and the internal records upserted to another.
+type Pipeline struct {
+	// The name of the config file of the pipeline without its extension
+	Name   string
+	Config *Config
+}
+
+// Pipelines returns the configurations of the pipelines of the --pipeline flags. A pipeline is a config
+// file like the ones of --config, which is overlaid on the config files of the process, so the settings
+// shared by all pipelines only need to be specified once. The flags on the command line and the
+// environment variables apply to all pipelines and override the config files as usual. The flags
+// configuring the whole process, e.g. the metrics address and the provider HTTP client, can't be changed by
+// a pipeline. The args are the arguments the configuration was parsed from.
+func (cfg *Config) Pipelines(args []string) ([]Pipeline, error) {
+	var pipelineArgs []string
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--":
+			pipelineArgs = append(pipelineArgs, args[i:]...)
+			i = len(args)
+		case args[i] == "--"+configFileFlag || args[i] == "--"+pipelineFlag:
+			i++
+		case strings.HasPrefix(args[i], "--"+configFileFlag+"=") || strings.HasPrefix(args[i], "--"+pipelineFlag+"="):
+		default:
+			pipelineArgs = append(pipelineArgs, args[i])
+		}
+	}
+
+	seen := map[string]bool{}
+	pipelines := make([]Pipeline, 0, len(cfg.PipelineFiles))
+	for _, file := range cfg.PipelineFiles {
+		name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		if seen[name] {
+			return nil, fmt.Errorf("the name of the pipeline %s isn't unique", file)
+		}
+		seen[name] = true
+
+		var configArgs []string
+		for _, configFile := range append(append([]string{}, cfg.ConfigFiles...), file) {
+			configArgs = append(configArgs, "--"+configFileFlag+"="+configFile)
+		}
+		pipeline := NewConfig()
+		if err := pipeline.ParseFlags(append(configArgs, pipelineArgs...)); err != nil {
+			return nil, fmt.Errorf("failed to parse the pipeline %s: %v", file, err)
+		}
+		// the pipelines of the config files of the process don't nest
+		pipeline.PipelineFiles = nil
+		pipeline.PipelineName = name
+		processFlags := pipeline.processFlags()
+		for flag, value := range cfg.processFlags() {
+			if processFlags[flag] != value {
+				return nil, fmt.Errorf("the pipeline %s can't override the process-wide flag --%s", file, flag)
+			}
+		}
+		pipelines = append(pipelines, Pipeline{Name: name, Config: pipeline})
+	}
+
+	// the provider annotation of a resource selects the pipelines publishing it by their names
+	names := make([]string, 0, len(pipelines))
+	for _, pipeline := range pipelines {
+		names = append(names, pipeline.Name)
+	}
+	for _, pipeline := range pipelines {
+		pipeline.Config.PipelineNames = names
+	}
+	return pipelines, nil
+}
+
+// processFlags returns the values of the flags configuring the whole process rather than a synchronization,
+// e.g. the default HTTP client shared by the provider SDKs, by their names.
+func (cfg *Config) processFlags() map[string]string {
+	return map[string]string{
+		"provider-http-proxy":         cfg.ProviderHTTPProxy,
+		"provider-ca-bundle":          cfg.ProviderCABundle,
+		"provider-user-agent":         cfg.ProviderUserAgent,
+		"provider-retry-budget":       strconv.Itoa(cfg.ProviderRetryBudget),
+		"provider-retry-budget-ratio": strconv.FormatFloat(cfg.ProviderRetryBudgetRatio, 'g', -1, 64),
+		"metrics-address":             cfg.MetricsAddress,
+		"log-level":                   cfg.LogLevel,
+		"log-format":                  cfg.LogFormat,
+	}
+}
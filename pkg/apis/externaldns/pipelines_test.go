@@ -0,0 +1,108 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package externaldns
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPipelines(t *testing.T) {
+	dir, err := ioutil.TempDir("", "external-dns-pipelines")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	public := writeConfigFile(t, dir, "public.yaml", `
+provider: google
+policy: sync
+txt-owner-id: public
+`)
+	internal := writeConfigFile(t, dir, "internal.yaml", `
+provider: rfc2136
+policy: upsert-only
+source: [service]
+txt-owner-id: internal
+`)
+	base := writeConfigFile(t, dir, "base.yaml", `
+source: [service, ingress]
+interval: 5m
+pipeline: [`+public+`, `+internal+`]
+`)
+
+	args := []string{"--config=" + base, "--log-level=debug"}
+	cfg := NewConfig()
+	require.NoError(t, cfg.ParseFlags(args))
+	assert.Equal(t, []string{public, internal}, cfg.PipelineFiles)
+
+	pipelines, err := cfg.Pipelines(args)
+	require.NoError(t, err)
+	require.Len(t, pipelines, 2)
+
+	assert.Equal(t, "public", pipelines[0].Name)
+	assert.Equal(t, "google", pipelines[0].Config.Provider)
+	assert.Equal(t, "sync", pipelines[0].Config.Policy)
+	assert.Equal(t, "public", pipelines[0].Config.TXTOwnerID)
+	assert.Equal(t, []string{"service", "ingress"}, pipelines[0].Config.Sources)
+
+	assert.Equal(t, "internal", pipelines[1].Name)
+	assert.Equal(t, "rfc2136", pipelines[1].Config.Provider)
+	assert.Equal(t, "upsert-only", pipelines[1].Config.Policy)
+	assert.Equal(t, []string{"service"}, pipelines[1].Config.Sources)
+
+	for _, pipeline := range pipelines {
+		assert.Equal(t, "debug", pipeline.Config.LogLevel)
+		assert.Equal(t, cfg.Interval, pipeline.Config.Interval)
+		assert.Empty(t, pipeline.Config.PipelineFiles)
+		assert.Equal(t, pipeline.Name, pipeline.Config.PipelineName)
+		assert.Equal(t, []string{"public", "internal"}, pipeline.Config.PipelineNames)
+	}
+}
+
+func TestPipelinesFromArgs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "external-dns-pipelines")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	public := writeConfigFile(t, dir, "public.yaml", "provider: google\n")
+	args := []string{"--pipeline", public, "--source=service", "--provider=aws"}
+	cfg := NewConfig()
+	require.NoError(t, cfg.ParseFlags(args))
+
+	pipelines, err := cfg.Pipelines(args)
+	require.NoError(t, err)
+	require.Len(t, pipelines, 1)
+	// the command line overrides the config files
+	assert.Equal(t, "aws", pipelines[0].Config.Provider)
+	assert.Equal(t, []string{"service"}, pipelines[0].Config.Sources)
+
+	cfg.PipelineFiles = []string{public, public}
+	_, err = cfg.Pipelines(args)
+	assert.Error(t, err)
+
+	cfg.PipelineFiles = []string{writeConfigFile(t, dir, "broken.yaml", "unknown-flag: true\n")}
+	_, err = cfg.Pipelines(args)
+	assert.Error(t, err)
+
+	// the default HTTP client of the provider SDKs is shared by the pipelines
+	cfg.PipelineFiles = []string{writeConfigFile(t, dir, "proxied.yaml", "provider-http-proxy: http://proxy:3128\n")}
+	_, err = cfg.Pipelines(args)
+	assert.EqualError(t, err, "the pipeline "+cfg.PipelineFiles[0]+" can't override the process-wide flag --provider-http-proxy")
+}
@@ -42,6 +42,8 @@ type Config struct {
 	RequestTimeout                    time.Duration
 	IstioIngressGatewayServices       []string
 	ContourLoadBalancerService        string
+	EmissaryService                   string
+	APIServerAdditionalServices       []string
 	Sources                           []string
 	Namespace                         string
 	AnnotationFilter                  string
@@ -51,6 +53,10 @@ type Config struct {
 	Compatibility                     string
 	PublishInternal                   bool
 	PublishHostIP                     bool
+	MetalLBAnnouncedOnly              bool
+	InheritNamespaceDefaults          bool
+	InternalZone                      string
+	SyncExternalNames                 bool
 	ConnectorSourceServer             string
 	Provider                          string
 	GoogleProject                     string
@@ -58,6 +64,8 @@ type Config struct {
 	GoogleBatchChangeInterval         time.Duration
 	DomainFilter                      []string
 	ExcludeDomains                    []string
+	EndpointFilters                   []string
+	ManagedRecordTypes                []string
 	ZoneIDFilter                      []string
 	AlibabaCloudConfigFile            string
 	AlibabaCloudZoneType              string
@@ -69,6 +77,8 @@ type Config struct {
 	AWSEvaluateTargetHealth           bool
 	AWSAPIRetries                     int
 	AWSPreferCNAME                    bool
+	AWSPrivateZoneVPCs                []string
+	AWSDeleteOrphanedHealthChecks     bool
 	AzureConfigFile                   string
 	AzureResourceGroup                string
 	AzureSubscriptionID               string
@@ -76,6 +86,7 @@ type Config struct {
 	CloudflareProxied                 bool
 	CloudflareZonesPerPage            int
 	CoreDNSPrefix                     string
+	DesignateFloatingIPs              bool
 	RcodezeroTXTEncrypt               bool
 	AkamaiServiceConsumerDomain       string
 	AkamaiClientToken                 string
@@ -98,27 +109,80 @@ type Config struct {
 	PDNSServer                        string
 	PDNSAPIKey                        string `secure:"yes"`
 	PDNSTLSEnabled                    bool
+	WebhookProviderURL                string
 	TLSCA                             string
 	TLSClientCert                     string
 	TLSClientCertKey                  string
+	ProviderHTTPProxy                 string
+	ProviderCABundle                  string
+	ProviderUserAgent                 string
+	ProviderRetryBudget               int
+	ProviderRetryBudgetRatio          float64
+	ClusterName                       string
+	ProviderTimeout                   time.Duration
+	ProviderTimeoutOverrides          []string
+	MinTTL                            time.Duration
+	MaxTTL                            time.Duration
+	CNAMETargetCheck                  string
+	TakeoverScanInterval              time.Duration
+	TakeoverScanDelete                bool
+	TakeoverScanConfirmations         int
+	TakeoverScanWebhook               string
 	Policy                            string
 	Registry                          string
 	TXTOwnerID                        string
 	TXTPrefix                         string
+	TXTClusterID                      string
+	TXTTakeoverFrom                   []string
+	TXTTakeoverGracePeriod            time.Duration
+	DynamoDBTable                     string
+	DynamoDBRegion                    string
+	DynamoDBCacheInterval             time.Duration
 	Interval                          time.Duration
+	RenameDeletionGracePeriod         time.Duration
+	OrphanDeletionGracePeriod         time.Duration
+	ChangeDebounce                    time.Duration
+	ZonePriority                      []string
+	FailFast                          bool
+	DeletionSafetyThreshold           float64
+	DeletionSafetyMinRecords          int
+	DeletionSafetyCycles              int
 	Once                              bool
 	DryRun                            bool
 	UpdateEvents                      bool
 	LogFormat                         string
 	MetricsAddress                    string
 	LogLevel                          string
+	ConfigFiles                       []string
+	PipelineFiles                     []string
+	PipelineName                      string
+	PipelineNames                     []string
+	Command                           string
+	SimulateInput                     string
+	MigrateDeleteTXTRecords           bool
+	TraceHostname                     string
+	StateDumpFile                     string
+	WriteBackAppliedRecords           bool
+	PublishRecordClaims               bool
+	DelegateNamespaceSubzones         bool
+	DuplicateReport                   bool
+	DNSFreeze                         bool
+	TrackChangeLatency                bool
 	TXTCacheInterval                  time.Duration
 	ExoscaleEndpoint                  string
 	ExoscaleAPIKey                    string `secure:"yes"`
 	ExoscaleAPISecret                 string `secure:"yes"`
 	CRDSourceAPIVersion               string
 	CRDSourceKind                     string
+	JSONPathSourceAPIVersion          string
+	JSONPathSourceKind                string
+	JSONPathSourceHostname            string
+	JSONPathSourceTarget              string
 	ServiceTypeFilter                 []string
+	CutoverConfigMap                  string
+	TopologyRouting                   bool
+	TopologySetIdentifier             string
+	CAAPolicies                       []string
 	CFAPIEndpoint                     string
 	CFUsername                        string
 	CFPassword                        string
@@ -130,6 +194,7 @@ type Config struct {
 	RFC2136TSIGSecret                 string `secure:"yes"`
 	RFC2136TSIGSecretAlg              string
 	RFC2136TAXFR                      bool
+	RFC2136Notify                     []string
 	NS1Endpoint                       string
 	NS1IgnoreSSL                      bool
 	TransIPAccountName                string
@@ -142,6 +207,7 @@ var defaultConfig = &Config{
 	RequestTimeout:              time.Second * 30,
 	IstioIngressGatewayServices: []string{"istio-system/istio-ingressgateway"},
 	ContourLoadBalancerService:  "heptio-contour/contour",
+	EmissaryService:             "emissary/emissary-ingress",
 	Sources:                     nil,
 	Namespace:                   "",
 	AnnotationFilter:            "",
@@ -151,6 +217,10 @@ var defaultConfig = &Config{
 	Compatibility:               "",
 	PublishInternal:             false,
 	PublishHostIP:               false,
+	MetalLBAnnouncedOnly:        false,
+	InheritNamespaceDefaults:    false,
+	InternalZone:                "",
+	SyncExternalNames:           false,
 	ConnectorSourceServer:       "localhost:8080",
 	Provider:                    "",
 	GoogleProject:               "",
@@ -173,6 +243,7 @@ var defaultConfig = &Config{
 	CloudflareProxied:           false,
 	CloudflareZonesPerPage:      50,
 	CoreDNSPrefix:               "/skydns/",
+	DesignateFloatingIPs:        false,
 	RcodezeroTXTEncrypt:         false,
 	AkamaiServiceConsumerDomain: "",
 	AkamaiClientToken:           "",
@@ -191,27 +262,68 @@ var defaultConfig = &Config{
 	PDNSServer:                  "http://localhost:8081",
 	PDNSAPIKey:                  "",
 	PDNSTLSEnabled:              false,
+	WebhookProviderURL:          "http://localhost:8888",
 	TLSCA:                       "",
 	TLSClientCert:               "",
 	TLSClientCertKey:            "",
+	ProviderHTTPProxy:           "",
+	ProviderCABundle:            "",
+	ProviderUserAgent:           "ExternalDNS/{{.Version}} (owner {{.OwnerID}}{{if .ClusterName}}, cluster {{.ClusterName}}{{end}})",
+	ProviderRetryBudget:         0,
+	ProviderRetryBudgetRatio:    0.1,
+	ClusterName:                 "",
+	ProviderTimeout:             0,
+	MinTTL:                      0,
+	MaxTTL:                      0,
+	CNAMETargetCheck:            "",
+	TakeoverScanInterval:        0,
+	TakeoverScanDelete:          false,
+	TakeoverScanConfirmations:   3,
+	TakeoverScanWebhook:         "",
 	Policy:                      "sync",
 	Registry:                    "txt",
 	TXTOwnerID:                  "default",
 	TXTPrefix:                   "",
+	TXTClusterID:                "",
+	TXTTakeoverGracePeriod:      10 * time.Minute,
+	DynamoDBTable:               "external-dns",
+	DynamoDBRegion:              "",
+	DynamoDBCacheInterval:       time.Hour,
 	TXTCacheInterval:            0,
 	Interval:                    time.Minute,
+	RenameDeletionGracePeriod:   0,
+	OrphanDeletionGracePeriod:   0,
+	ChangeDebounce:              0,
+	FailFast:                    false,
+	DeletionSafetyThreshold:     0,
+	DeletionSafetyMinRecords:    10,
+	DeletionSafetyCycles:        3,
 	Once:                        false,
 	DryRun:                      false,
 	UpdateEvents:                false,
 	LogFormat:                   "text",
 	MetricsAddress:              ":7979",
 	LogLevel:                    logrus.InfoLevel.String(),
+	Command:                     "run",
+	SimulateInput:               "",
+	MigrateDeleteTXTRecords:     false,
+	TraceHostname:               "",
+	StateDumpFile:               "",
+	WriteBackAppliedRecords:     false,
+	PublishRecordClaims:         false,
+	DelegateNamespaceSubzones:   false,
+	DuplicateReport:             false,
+	DNSFreeze:                   false,
+	TrackChangeLatency:          false,
 	ExoscaleEndpoint:            "https://api.exoscale.ch/dns",
 	ExoscaleAPIKey:              "",
 	ExoscaleAPISecret:           "",
 	CRDSourceAPIVersion:         "externaldns.k8s.io/v1alpha1",
 	CRDSourceKind:               "DNSEndpoint",
 	ServiceTypeFilter:           []string{},
+	CutoverConfigMap:            "",
+	TopologyRouting:             false,
+	TopologySetIdentifier:       "",
 	CFAPIEndpoint:               "",
 	CFUsername:                  "",
 	CFPassword:                  "",
@@ -282,9 +394,13 @@ func (cfg *Config) ParseFlags(args []string) error {
 
 	// Flags related to Contour
 	app.Flag("contour-load-balancer", "The fully-qualified name of the Contour load balancer service. (default: heptio-contour/contour)").Default("heptio-contour/contour").StringVar(&cfg.ContourLoadBalancerService)
+	app.Flag("emissary-service", "The fully-qualified name of the Emissary-ingress load balancer service the Hosts of the emissary-host source point at, unless overridden by their emissary-service annotation (default: emissary/emissary-ingress)").Default(defaultConfig.EmissaryService).StringVar(&cfg.EmissaryService)
+
+	// Flags related to the API server source
+	app.Flag("api-server-additional-service", "Additional control plane services published by the api-server source, e.g. the konnectivity server; specify multiple times for multiple services (namespace/name, optional)").StringsVar(&cfg.APIServerAdditionalServices)
 
 	// Flags related to processing sources
-	app.Flag("source", "The resource types that are queried for endpoints; specify multiple times for multiple sources (required, options: service, ingress, node, fake, connector, istio-gateway, cloudfoundry, contour-ingressroute, crd, empty)").Required().PlaceHolder("source").EnumsVar(&cfg.Sources, "service", "ingress", "node", "istio-gateway", "cloudfoundry", "contour-ingressroute", "fake", "connector", "crd", "empty")
+	app.Flag("source", "The resource types that are queried for endpoints; specify multiple times for multiple sources (required, options: service, ingress, node, statefulset, pod, fake, connector, istio-gateway, istio-virtualservice, emissary-host, cloudfoundry, contour-ingressroute, multicluster-service, aws-target-group-binding, gke-ingress, argo-rollout, gateway-httproute, gateway-tlsroute, gateway-grpcroute, cert-manager-challenge-delegation, domain-verification, api-server, jsonpath, crd, empty)").PlaceHolder("source").EnumsVar(&cfg.Sources, "service", "ingress", "node", "statefulset", "pod", "istio-gateway", "istio-virtualservice", "emissary-host", "cloudfoundry", "contour-ingressroute", "multicluster-service", "aws-target-group-binding", "gke-ingress", "argo-rollout", "gateway-httproute", "gateway-tlsroute", "gateway-grpcroute", "cert-manager-challenge-delegation", "domain-verification", "api-server", "jsonpath", "fake", "connector", "crd", "empty")
 	app.Flag("namespace", "Limit sources of endpoints to a specific namespace (default: all namespaces)").Default(defaultConfig.Namespace).StringVar(&cfg.Namespace)
 	app.Flag("annotation-filter", "Filter sources managed by external-dns via annotation using label selector semantics (default: all sources)").Default(defaultConfig.AnnotationFilter).StringVar(&cfg.AnnotationFilter)
 	app.Flag("fqdn-template", "A templated string that's used to generate DNS names from sources that don't define a hostname themselves, or to add a hostname suffix when paired with the fake source (optional). Accepts comma separated list for multiple global FQDN.").Default(defaultConfig.FQDNTemplate).StringVar(&cfg.FQDNTemplate)
@@ -293,17 +409,31 @@ func (cfg *Config) ParseFlags(args []string) error {
 	app.Flag("compatibility", "Process annotation semantics from legacy implementations (optional, options: mate, molecule)").Default(defaultConfig.Compatibility).EnumVar(&cfg.Compatibility, "", "mate", "molecule")
 	app.Flag("publish-internal-services", "Allow external-dns to publish DNS records for ClusterIP services (optional)").BoolVar(&cfg.PublishInternal)
 	app.Flag("publish-host-ip", "Allow external-dns to publish host-ip for headless services (optional)").BoolVar(&cfg.PublishHostIP)
+	app.Flag("metallb-announced-only", "Only publish the addresses of LoadBalancer services while a ready MetalLB speaker is able to announce them (optional)").BoolVar(&cfg.MetalLBAnnouncedOnly)
+	app.Flag("inherit-namespace-defaults", "Inherit the TTL and provider-specific annotations of Services and Ingresses and the target annotation of Ingresses their namespace defines, unless they set them themselves; requires listing namespaces (default: disabled)").BoolVar(&cfg.InheritNamespaceDefaults)
+	app.Flag("internal-zone", "Publish the cluster IPs of ClusterIP services with the internal-hostname annotation into this private zone, e.g. for service meshes or VPN clients resolving cluster services (optional)").Default(defaultConfig.InternalZone).StringVar(&cfg.InternalZone)
+	app.Flag("sync-external-names", "Keep the externalName of ExternalName services with the external-name-lookup annotation up to date with the canonical name of the annotated DNS name; requires updating services (default: disabled)").BoolVar(&cfg.SyncExternalNames)
 	app.Flag("connector-source-server", "The server to connect for connector source, valid only when using connector source").Default(defaultConfig.ConnectorSourceServer).StringVar(&cfg.ConnectorSourceServer)
 	app.Flag("crd-source-apiversion", "API version of the CRD for crd source, e.g. `externaldns.k8s.io/v1alpha1`, valid only when using crd source").Default(defaultConfig.CRDSourceAPIVersion).StringVar(&cfg.CRDSourceAPIVersion)
 	app.Flag("crd-source-kind", "Kind of the CRD for the crd source in API group and version specified by crd-source-apiversion").Default(defaultConfig.CRDSourceKind).StringVar(&cfg.CRDSourceKind)
+	app.Flag("jsonpath-source-apiversion", "API version of the resources for the jsonpath source, e.g. `gateway.example.com/v1`, valid only when using jsonpath source").StringVar(&cfg.JSONPathSourceAPIVersion)
+	app.Flag("jsonpath-source-kind", "Kind of the resources for the jsonpath source in API group and version specified by jsonpath-source-apiversion").StringVar(&cfg.JSONPathSourceKind)
+	app.Flag("jsonpath-source-hostname", "JSONPath expression for the hostnames of a resource for the jsonpath source, e.g. `{.spec.hosts[*]}`").StringVar(&cfg.JSONPathSourceHostname)
+	app.Flag("jsonpath-source-target", "JSONPath expression for the targets of a resource for the jsonpath source, e.g. `{.status.addresses[*].value}`").StringVar(&cfg.JSONPathSourceTarget)
 	app.Flag("service-type-filter", "The service types to take care about (default: all, expected: ClusterIP, NodePort, LoadBalancer or ExternalName)").StringsVar(&cfg.ServiceTypeFilter)
+	app.Flag("cutover-configmap", "The ConfigMap promoting blue/green cutover groups, i.e. pointing the records of a group at their standby targets when its value is `standby` (namespace/name, optional)").Default(defaultConfig.CutoverConfigMap).StringVar(&cfg.CutoverConfigMap)
+	app.Flag("topology-routing", "Attach latency routing by the region of the cluster to the A and CNAME records without a routing policy, the region most nodes have in their topology.kubernetes.io/region label; supported by the aws provider (default: disabled)").BoolVar(&cfg.TopologyRouting)
+	app.Flag("topology-set-identifier", "When using topology routing, the set identifier telling the records of this cluster apart from the other clusters (default: the region)").Default(defaultConfig.TopologySetIdentifier).StringVar(&cfg.TopologySetIdentifier)
+	app.Flag("caa-policy", "Publish and maintain the CAA records allowing only these certificate authorities to issue certificates for a zone, e.g. `example.org=letsencrypt.org,digicert.com`, no issuers forbid issuance; specify multiple times for multiple zones (optional)").StringsVar(&cfg.CAAPolicies)
 
 	// Flags related to providers
-	app.Flag("provider", "The DNS provider where the DNS records will be created (required, options: aws, aws-sd, google, azure, azure-dns, azure-private-dns, cloudflare, rcodezero, digitalocean, dnsimple, akamai, infoblox, dyn, designate, coredns, skydns, inmemory, pdns, oci, exoscale, linode, rfc2136, ns1, transip, vinyldns, rdns)").Required().PlaceHolder("provider").EnumVar(&cfg.Provider, "aws", "aws-sd", "google", "azure", "azure-dns", "azure-private-dns", "alibabacloud", "cloudflare", "rcodezero", "digitalocean", "dnsimple", "akamai", "infoblox", "dyn", "designate", "coredns", "skydns", "inmemory", "pdns", "oci", "exoscale", "linode", "rfc2136", "ns1", "transip", "vinyldns", "rdns")
+	app.Flag("provider", "The DNS provider where the DNS records will be created (required, options: aws, aws-sd, google, azure, azure-dns, azure-private-dns, cloudflare, rcodezero, digitalocean, dnsimple, akamai, infoblox, dyn, designate, coredns, skydns, inmemory, pdns, oci, exoscale, linode, rfc2136, axfr, ns1, transip, vinyldns, rdns, webhook)").PlaceHolder("provider").StringVar(&cfg.Provider)
 	app.Flag("domain-filter", "Limit possible target zones by a domain suffix; specify multiple times for multiple domains (optional)").Default("").StringsVar(&cfg.DomainFilter)
 	app.Flag("exclude-domains", "Exclude subdomains (optional)").Default("").StringsVar(&cfg.ExcludeDomains)
+	app.Flag("endpoint-filter", "Filter the endpoints of the sources before planning, e.g. domain=example.org, exclude-domain=internal.example.org, regex=^api\\., exclude-regex=^test-, label=team=payments matching the labels of the resources of the endpoints or webhook=http://filter/endpoints; specify multiple times for multiple filters applied in order (optional)").StringsVar(&cfg.EndpointFilters)
+	app.Flag("managed-record-types", "The record types ExternalDNS manages, records of other types are neither planned nor listed or changed at the provider; specify multiple times for multiple types (default: A, CNAME; the TXT records of the txt registry are always managed)").StringsVar(&cfg.ManagedRecordTypes)
 	app.Flag("zone-id-filter", "Filter target zones by hosted zone id; specify multiple times for multiple zones (optional)").Default("").StringsVar(&cfg.ZoneIDFilter)
-	app.Flag("google-project", "When using the Google provider, current project is auto-detected, when running on GCP. Specify other project with this. Must be specified when running outside GCP.").Default(defaultConfig.GoogleProject).StringVar(&cfg.GoogleProject)
+	app.Flag("google-project", "When using the Google provider or the gke-ingress source, current project is auto-detected, when running on GCP. Specify other project with this. Must be specified when running outside GCP.").Default(defaultConfig.GoogleProject).StringVar(&cfg.GoogleProject)
 	app.Flag("google-batch-change-size", "When using the Google provider, set the maximum number of changes that will be applied in each batch.").Default(strconv.Itoa(defaultConfig.GoogleBatchChangeSize)).IntVar(&cfg.GoogleBatchChangeSize)
 	app.Flag("google-batch-change-interval", "When using the Google provider, set the interval between batch changes.").Default(defaultConfig.GoogleBatchChangeInterval.String()).DurationVar(&cfg.GoogleBatchChangeInterval)
 	app.Flag("alibaba-cloud-config-file", "When using the Alibaba Cloud provider, specify the Alibaba Cloud configuration file (required when --provider=alibabacloud").Default(defaultConfig.AlibabaCloudConfigFile).StringVar(&cfg.AlibabaCloudConfigFile)
@@ -316,6 +446,8 @@ func (cfg *Config) ParseFlags(args []string) error {
 	app.Flag("aws-evaluate-target-health", "When using the AWS provider, set whether to evaluate the health of a DNS target (default: enabled, disable with --no-aws-evaluate-target-health)").Default(strconv.FormatBool(defaultConfig.AWSEvaluateTargetHealth)).BoolVar(&cfg.AWSEvaluateTargetHealth)
 	app.Flag("aws-api-retries", "When using the AWS provider, set the maximum number of retries for API calls before giving up.").Default(strconv.Itoa(defaultConfig.AWSAPIRetries)).IntVar(&cfg.AWSAPIRetries)
 	app.Flag("aws-prefer-cname", "When using the AWS provider, prefer using CNAME instead of ALIAS (default: disabled)").BoolVar(&cfg.AWSPreferCNAME)
+	app.Flag("aws-delete-orphaned-health-checks", "When using the AWS provider, delete the health checks created for the TXT owner ID in the managed zones which no record references anymore (default: disabled)").BoolVar(&cfg.AWSDeleteOrphanedHealthChecks)
+	app.Flag("aws-private-zone-vpc", "When using the AWS provider, associate the private hosted zones records are changed in with this VPC if they aren't yet, specify the region and VPC ID, e.g. `us-east-1:vpc-0123456789abcdef0` (optional, specify multiple times for multiple VPCs)").StringsVar(&cfg.AWSPrivateZoneVPCs)
 	app.Flag("azure-config-file", "When using the Azure provider, specify the Azure configuration file (required when --provider=azure").Default(defaultConfig.AzureConfigFile).StringVar(&cfg.AzureConfigFile)
 	app.Flag("azure-resource-group", "When using the Azure provider, override the Azure resource group to use (required when --provider=azure-private-dns)").Default(defaultConfig.AzureResourceGroup).StringVar(&cfg.AzureResourceGroup)
 	app.Flag("azure-subscription-id", "When using the Azure provider, specify the Azure configuration file (required when --provider=azure-private-dns)").Default(defaultConfig.AzureSubscriptionID).StringVar(&cfg.AzureSubscriptionID)
@@ -323,6 +455,7 @@ func (cfg *Config) ParseFlags(args []string) error {
 	app.Flag("cloudflare-proxied", "When using the Cloudflare provider, specify if the proxy mode must be enabled (default: disabled)").BoolVar(&cfg.CloudflareProxied)
 	app.Flag("cloudflare-zones-per-page", "When using the Cloudflare provider, specify how many zones per page listed, max. possible 50 (default: 50)").Default(strconv.Itoa(defaultConfig.CloudflareZonesPerPage)).IntVar(&cfg.CloudflareZonesPerPage)
 	app.Flag("coredns-prefix", "When using the CoreDNS provider, specify the prefix name").Default(defaultConfig.CoreDNSPrefix).StringVar(&cfg.CoreDNSPrefix)
+	app.Flag("designate-floating-ips", "When using the Designate provider, publish the Neutron floating IPs bound to the targets of A records instead of the targets, e.g. of Octavia VIPs, and follow them when they are moved (default: disabled)").BoolVar(&cfg.DesignateFloatingIPs)
 	app.Flag("akamai-serviceconsumerdomain", "When using the Akamai provider, specify the base URL (required when --provider=akamai)").Default(defaultConfig.AkamaiServiceConsumerDomain).StringVar(&cfg.AkamaiServiceConsumerDomain)
 	app.Flag("akamai-client-token", "When using the Akamai provider, specify the client token (required when --provider=akamai)").Default(defaultConfig.AkamaiClientToken).StringVar(&cfg.AkamaiClientToken)
 	app.Flag("akamai-client-secret", "When using the Akamai provider, specify the client secret (required when --provider=akamai)").Default(defaultConfig.AkamaiClientSecret).StringVar(&cfg.AkamaiClientSecret)
@@ -345,6 +478,7 @@ func (cfg *Config) ParseFlags(args []string) error {
 	app.Flag("pdns-server", "When using the PowerDNS/PDNS provider, specify the URL to the pdns server (required when --provider=pdns)").Default(defaultConfig.PDNSServer).StringVar(&cfg.PDNSServer)
 	app.Flag("pdns-api-key", "When using the PowerDNS/PDNS provider, specify the API key to use to authorize requests (required when --provider=pdns)").Default(defaultConfig.PDNSAPIKey).StringVar(&cfg.PDNSAPIKey)
 	app.Flag("pdns-tls-enabled", "When using the PowerDNS/PDNS provider, specify whether to use TLS (default: false, requires --tls-ca, optionally specify --tls-client-cert and --tls-client-cert-key)").Default(strconv.FormatBool(defaultConfig.PDNSTLSEnabled)).BoolVar(&cfg.PDNSTLSEnabled)
+	app.Flag("webhook-provider-url", "When using the webhook provider, specify the URL of the server implementing the webhook provider protocol, usually a sidecar (default: http://localhost:8888)").Default(defaultConfig.WebhookProviderURL).StringVar(&cfg.WebhookProviderURL)
 	app.Flag("ns1-endpoint", "When using the NS1 provider, specify the URL of the API endpoint to target (default: https://api.nsone.net/v1/)").Default(defaultConfig.NS1Endpoint).StringVar(&cfg.NS1Endpoint)
 	app.Flag("ns1-ignoressl", "When using the NS1 provider, specify whether to verify the SSL certificate (default: false)").Default(strconv.FormatBool(defaultConfig.NS1IgnoreSSL)).BoolVar(&cfg.NS1IgnoreSSL)
 
@@ -352,20 +486,36 @@ func (cfg *Config) ParseFlags(args []string) error {
 	app.Flag("tls-ca", "When using TLS communication, the path to the certificate authority to verify server communications (optionally specify --tls-client-cert for two-way TLS)").Default(defaultConfig.TLSCA).StringVar(&cfg.TLSCA)
 	app.Flag("tls-client-cert", "When using TLS communication, the path to the certificate to present as a client (not required for TLS)").Default(defaultConfig.TLSClientCert).StringVar(&cfg.TLSClientCert)
 	app.Flag("tls-client-cert-key", "When using TLS communication, the path to the certificate key to use with the client certificate (not required for TLS)").Default(defaultConfig.TLSClientCertKey).StringVar(&cfg.TLSClientCertKey)
+	app.Flag("provider-http-proxy", "The URL of the proxy the requests to the provider API are sent through, e.g. an egress proxy (default: the HTTPS_PROXY and HTTP_PROXY environment variables)").Default(defaultConfig.ProviderHTTPProxy).StringVar(&cfg.ProviderHTTPProxy)
+	app.Flag("provider-ca-bundle", "The path to a PEM bundle of certificate authorities trusted by the provider API clients in addition to the system roots, e.g. of a proxy intercepting TLS (optional)").Default(defaultConfig.ProviderCABundle).StringVar(&cfg.ProviderCABundle)
+	app.Flag("provider-user-agent", "A template of the User-Agent appended to the requests of the provider API clients, so the audit logs of the provider attribute the changes to this instance; {{.Version}}, {{.OwnerID}} (--txt-owner-id) and {{.ClusterName}} are replaced").Default(defaultConfig.ProviderUserAgent).StringVar(&cfg.ProviderUserAgent)
+	app.Flag("provider-retry-budget", "Limit the retries of the requests to each provider API host with a token bucket of this size shared by all zones, like the retry throttling of gRPC: a failed request costs a token and retries are rejected while half of the tokens or less are left (default: 0, disabled)").Default(strconv.Itoa(defaultConfig.ProviderRetryBudget)).IntVar(&cfg.ProviderRetryBudget)
+	app.Flag("provider-retry-budget-ratio", "When the provider retry budget is enabled, the tokens a successful request returns to the budget of its host (default: 0.1)").Default(strconv.FormatFloat(defaultConfig.ProviderRetryBudgetRatio, 'f', -1, 64)).Float64Var(&cfg.ProviderRetryBudgetRatio)
+	app.Flag("cluster-name", "The name of the cluster of this instance, e.g. for the User-Agent of the provider API clients (optional)").Default(defaultConfig.ClusterName).StringVar(&cfg.ClusterName)
+	app.Flag("provider-timeout", "Give up on listing the records or applying the changes with the provider after this long, so a hung provider API doesn't stall the synchronization (default: 0s, no timeout)").Default(defaultConfig.ProviderTimeout.String()).DurationVar(&cfg.ProviderTimeout)
+	app.Flag("provider-timeout-override", "Override --provider-timeout for a provider, e.g. `pdns=10s`, so a shared configuration can set the timeout of each provider; specify multiple times for multiple providers (optional)").StringsVar(&cfg.ProviderTimeoutOverrides)
+	app.Flag("min-ttl", "Raise the TTLs of the endpoints below this duration to it before planning, reported as TTLClamped events of their resources (default: 0s, no minimum)").Default(defaultConfig.MinTTL.String()).DurationVar(&cfg.MinTTL)
+	app.Flag("max-ttl", "Lower the TTLs of the endpoints above this duration to it before planning, reported as TTLClamped events of their resources (default: 0s, no maximum)").Default(defaultConfig.MaxTTL.String()).DurationVar(&cfg.MaxTTL)
+	app.Flag("cname-target-check", "Resolve the targets of the CNAME records before creating or updating them and report the dangling ones whose target doesn't exist, e.g. a decommissioned load balancer, as DanglingCNAME events of their resources; warn still applies them, reject holds them back (optional, options: warn, reject)").Default(defaultConfig.CNAMETargetCheck).EnumVar(&cfg.CNAMETargetCheck, "", "warn", "reject")
+	app.Flag("takeover-scan-interval", "Scan the owned CNAME records this often for targets pointing at unclaimed cloud resources, e.g. a deleted load balancer or storage bucket, and report them as SubdomainTakeoverRisk events of their resources (default: 0s, disabled)").Default(defaultConfig.TakeoverScanInterval.String()).DurationVar(&cfg.TakeoverScanInterval)
+	app.Flag("takeover-scan-delete", "When scanning for subdomain takeover risks, delete the records at risk once --takeover-scan-confirmations consecutive scans found them and don't create them again until their target is claimed (default: disabled)").BoolVar(&cfg.TakeoverScanDelete)
+	app.Flag("takeover-scan-confirmations", "When deleting the records at risk of a subdomain takeover, the number of consecutive scans which must find a record before it's deleted; earlier findings are only reported").Default(strconv.Itoa(defaultConfig.TakeoverScanConfirmations)).IntVar(&cfg.TakeoverScanConfirmations)
+	app.Flag("takeover-scan-webhook", "When scanning for subdomain takeover risks, post the new findings as JSON to this URL, e.g. of an alerting system (optional)").Default(defaultConfig.TakeoverScanWebhook).StringVar(&cfg.TakeoverScanWebhook)
 
 	app.Flag("exoscale-endpoint", "Provide the endpoint for the Exoscale provider").Default(defaultConfig.ExoscaleEndpoint).StringVar(&cfg.ExoscaleEndpoint)
 	app.Flag("exoscale-apikey", "Provide your API Key for the Exoscale provider").Default(defaultConfig.ExoscaleAPIKey).StringVar(&cfg.ExoscaleAPIKey)
 	app.Flag("exoscale-apisecret", "Provide your API Secret for the Exoscale provider").Default(defaultConfig.ExoscaleAPISecret).StringVar(&cfg.ExoscaleAPISecret)
 
 	// Flags related to RFC2136 provider
-	app.Flag("rfc2136-host", "When using the RFC2136 provider, specify the host of the DNS server").Default(defaultConfig.RFC2136Host).StringVar(&cfg.RFC2136Host)
-	app.Flag("rfc2136-port", "When using the RFC2136 provider, specify the port of the DNS server").Default(strconv.Itoa(defaultConfig.RFC2136Port)).IntVar(&cfg.RFC2136Port)
-	app.Flag("rfc2136-zone", "When using the RFC2136 provider, specify the zone entry of the DNS server to use").Default(defaultConfig.RFC2136Zone).StringVar(&cfg.RFC2136Zone)
+	app.Flag("rfc2136-host", "When using the RFC2136 or AXFR provider, specify the host of the DNS server").Default(defaultConfig.RFC2136Host).StringVar(&cfg.RFC2136Host)
+	app.Flag("rfc2136-port", "When using the RFC2136 or AXFR provider, specify the port of the DNS server").Default(strconv.Itoa(defaultConfig.RFC2136Port)).IntVar(&cfg.RFC2136Port)
+	app.Flag("rfc2136-zone", "When using the RFC2136 or AXFR provider, specify the zone entry of the DNS server to use").Default(defaultConfig.RFC2136Zone).StringVar(&cfg.RFC2136Zone)
 	app.Flag("rfc2136-insecure", "When using the RFC2136 provider, specify whether to attach TSIG or not (default: false, requires --rfc2136-tsig-keyname and rfc2136-tsig-secret)").Default(strconv.FormatBool(defaultConfig.RFC2136Insecure)).BoolVar(&cfg.RFC2136Insecure)
 	app.Flag("rfc2136-tsig-keyname", "When using the RFC2136 provider, specify the TSIG key to attached to DNS messages (required when --rfc2136-insecure=false)").Default(defaultConfig.RFC2136TSIGKeyName).StringVar(&cfg.RFC2136TSIGKeyName)
 	app.Flag("rfc2136-tsig-secret", "When using the RFC2136 provider, specify the TSIG (base64) value to attached to DNS messages (required when --rfc2136-insecure=false)").Default(defaultConfig.RFC2136TSIGSecret).StringVar(&cfg.RFC2136TSIGSecret)
 	app.Flag("rfc2136-tsig-secret-alg", "When using the RFC2136 provider, specify the TSIG (base64) value to attached to DNS messages (required when --rfc2136-insecure=false)").Default(defaultConfig.RFC2136TSIGSecretAlg).StringVar(&cfg.RFC2136TSIGSecretAlg)
 	app.Flag("rfc2136-tsig-axfr", "When using the RFC2136 provider, specify the TSIG (base64) value to attached to DNS messages (required when --rfc2136-insecure=false)").BoolVar(&cfg.RFC2136TAXFR)
+	app.Flag("rfc2136-notify", "When using the RFC2136 provider, send a NOTIFY to this secondary server (host or host:port) after the zone was updated, so it transfers the changes right away; specify multiple times for multiple secondaries (optional)").StringsVar(&cfg.RFC2136Notify)
 
 	// Flags related to TransIP provider
 	app.Flag("transip-account", "When using the TransIP provider, specify the account name (required when --provider=transip)").Default(defaultConfig.TransIPAccountName).StringVar(&cfg.TransIPAccountName)
@@ -375,13 +525,34 @@ func (cfg *Config) ParseFlags(args []string) error {
 	app.Flag("policy", "Modify how DNS records are synchronized between sources and providers (default: sync, options: sync, upsert-only, create-only)").Default(defaultConfig.Policy).EnumVar(&cfg.Policy, "sync", "upsert-only", "create-only")
 
 	// Flags related to the registry
-	app.Flag("registry", "The registry implementation to use to keep track of DNS record ownership (default: txt, options: txt, noop, aws-sd)").Default(defaultConfig.Registry).EnumVar(&cfg.Registry, "txt", "noop", "aws-sd")
+	app.Flag("registry", "The registry implementation to use to keep track of DNS record ownership (default: txt, options: txt, noop, aws-sd, dynamodb)").Default(defaultConfig.Registry).EnumVar(&cfg.Registry, "txt", "noop", "aws-sd", "dynamodb")
 	app.Flag("txt-owner-id", "When using the TXT registry, a name that identifies this instance of ExternalDNS (default: default)").Default(defaultConfig.TXTOwnerID).StringVar(&cfg.TXTOwnerID)
 	app.Flag("txt-prefix", "When using the TXT registry, a custom string that's prefixed to each ownership DNS record (optional)").Default(defaultConfig.TXTPrefix).StringVar(&cfg.TXTPrefix)
+	app.Flag("txt-cluster-id", "When using the TXT registry, a name that identifies the cluster of this instance when several clusters share an owner id; the records of other clusters are neither updated nor deleted (optional)").Default(defaultConfig.TXTClusterID).StringVar(&cfg.TXTClusterID)
+	app.Flag("txt-takeover-from", "When using the TXT registry with a cluster id, take over the records of this cluster after the takeover grace period, e.g. when it's decommissioned; specify multiple times for multiple clusters (optional)").StringsVar(&cfg.TXTTakeoverFrom)
+	app.Flag("txt-takeover-grace-period", "When taking over the records of other clusters, wait for this long after a conflict is found in duration format (default: 10m)").Default(defaultConfig.TXTTakeoverGracePeriod.String()).DurationVar(&cfg.TXTTakeoverGracePeriod)
+	app.Flag("dynamodb-table", "When using the DynamoDB registry, the name of the table storing the ownership of the records; it needs a string partition key named id (default: external-dns)").Default(defaultConfig.DynamoDBTable).StringVar(&cfg.DynamoDBTable)
+	app.Flag("dynamodb-region", "When using the DynamoDB registry, the region of the table (default: the region of the AWS SDK configuration)").Default(defaultConfig.DynamoDBRegion).StringVar(&cfg.DynamoDBRegion)
+	app.Flag("dynamodb-cache-interval", "When using the DynamoDB registry, the interval after which the table is scanned again instead of using the cached items; 0 scans it in every synchronization (default: 1h)").Default(defaultConfig.DynamoDBCacheInterval.String()).DurationVar(&cfg.DynamoDBCacheInterval)
 
 	// Flags related to the main control loop
 	app.Flag("txt-cache-interval", "The interval between cache synchronizations in duration format (default: disabled)").Default(defaultConfig.TXTCacheInterval.String()).DurationVar(&cfg.TXTCacheInterval)
 	app.Flag("interval", "The interval between two consecutive synchronizations in duration format (default: 1m)").Default(defaultConfig.Interval.String()).DurationVar(&cfg.Interval)
+	app.Flag("rename-deletion-grace-period", "When a resource gets new records while its old ones are deleted, e.g. on a hostname change, create the new records first and delete the old ones after this period in duration format (default: 0, right after the creation)").Default(defaultConfig.RenameDeletionGracePeriod.String()).DurationVar(&cfg.RenameDeletionGracePeriod)
+	app.Flag("orphan-deletion-grace-period", "Keep the records of deleted resources, e.g. of an accidentally deleted namespace, for this long before deleting them; the time is tracked in the labels of the txt registry (default: 0s, delete at once)").Default(defaultConfig.OrphanDeletionGracePeriod.String()).DurationVar(&cfg.OrphanDeletionGracePeriod)
+	app.Flag("change-debounce", "Accumulate the changes for this long after the first of them is planned and apply them as one batch, e.g. when many resources are created at once (default: 0s, apply at once)").Default(defaultConfig.ChangeDebounce.String()).DurationVar(&cfg.ChangeDebounce)
+	app.Flag("zone-priority", "Apply the changes of this zone before those of the zones specified after it and of all other zones, e.g. to update critical zones first; specify multiple times in the order of their priority (optional)").StringsVar(&cfg.ZonePriority)
+	app.Flag("fail-fast", "When the changes of a zone of --zone-priority fail, skip the changes of the remaining zones to limit the damage (default: disabled)").BoolVar(&cfg.FailFast)
+	app.Flag("state-dump-file", "Write the provider records and source endpoints of every synchronization to this file, it can be replayed offline with the simulate command (optional)").Default(defaultConfig.StateDumpFile).StringVar(&cfg.StateDumpFile)
+	app.Flag("write-back-applied-records", "Write the records applied for Services, Ingresses and DNSEndpoints back onto them as the external-dns.alpha.kubernetes.io/applied-records annotation (default: disabled)").BoolVar(&cfg.WriteBackAppliedRecords)
+	app.Flag("publish-record-claims", "Publish the records owned by --txt-owner-id as cluster-scoped DNSRecordClaim resources, so their ownership can be queried with kubectl without provider credentials (default: disabled)").BoolVar(&cfg.PublishRecordClaims)
+	app.Flag("delegate-namespace-subzones", "Create the subzone of the external-dns.alpha.kubernetes.io/subzone annotation of each namespace and delegate it from its parent zone with NS records; requires a provider which can create zones, e.g. aws (default: disabled)").BoolVar(&cfg.DelegateNamespaceSubzones)
+	app.Flag("duplicate-report", "Serve the DNS names produced by more than one resource and which resource won as JSON on /debug/duplicates of the metrics address; their number is always exposed as the external_dns_controller_duplicate_endpoints metric (default: disabled)").BoolVar(&cfg.DuplicateReport)
+	app.Flag("dns-freeze", "Plan but don't apply the changes of the DNS names frozen by the cluster-scoped DNSFreeze resources, so on-call can halt the DNS automation during an incident; requires the DNSFreeze CRD (default: disabled)").BoolVar(&cfg.DNSFreeze)
+	app.Flag("track-change-latency", "Track the time from the last change of a Service, Ingress or DNSEndpoint to the application of its records as the external_dns_controller_change_apply_latency_seconds histogram, e.g. for a DNS propagation SLO (default: disabled)").BoolVar(&cfg.TrackChangeLatency)
+	app.Flag("deletion-safety-threshold", "When the number of records of a domain of the domain filter drops by more than this fraction between two synchronizations, e.g. 0.5, don't delete any records of the domain for a few synchronizations, as the provider probably failed to list them (default: 0, disabled)").Default(strconv.FormatFloat(defaultConfig.DeletionSafetyThreshold, 'f', -1, 64)).Float64Var(&cfg.DeletionSafetyThreshold)
+	app.Flag("deletion-safety-min-records", "When the deletion safety is enabled, the minimum number of records a domain must have had for a drop to hold back its deletions (default: 10)").Default(strconv.Itoa(defaultConfig.DeletionSafetyMinRecords)).IntVar(&cfg.DeletionSafetyMinRecords)
+	app.Flag("deletion-safety-cycles", "When the deletion safety is enabled, the number of synchronizations the deletions of a domain are held back before its new number of records is accepted (default: 3)").Default(strconv.Itoa(defaultConfig.DeletionSafetyCycles)).IntVar(&cfg.DeletionSafetyCycles)
 	app.Flag("once", "When enabled, exits the synchronization loop after the first iteration (default: disabled)").BoolVar(&cfg.Once)
 	app.Flag("dry-run", "When enabled, prints DNS record changes rather than actually performing them (default: disabled)").BoolVar(&cfg.DryRun)
 	app.Flag("events", "When enabled, in addition to running every interval, the reconciliation loop will get triggered when supported sources change (default: disabled)").BoolVar(&cfg.UpdateEvents)
@@ -389,12 +560,30 @@ func (cfg *Config) ParseFlags(args []string) error {
 	// Miscellaneous flags
 	app.Flag("log-format", "The format in which log messages are printed (default: text, options: text, json)").Default(defaultConfig.LogFormat).EnumVar(&cfg.LogFormat, "text", "json")
 	app.Flag("metrics-address", "Specify where to serve the metrics and health check endpoint (default: :7979)").Default(defaultConfig.MetricsAddress).StringVar(&cfg.MetricsAddress)
+	app.Flag("config", "Read the flags from this YAML file mapping flag names to values; specify multiple times to overlay files, later files override the flags of earlier ones and flags on the command line or as environment variables override the files (optional)").PlaceHolder("config.yaml").StringsVar(&cfg.ConfigFiles)
+	app.Flag("pipeline", "Run an independent synchronization with the flags of this YAML file overlaid on the config files, e.g. with another provider and policy; specify multiple times for multiple pipelines in one process (optional)").PlaceHolder("pipeline.yaml").StringsVar(&cfg.PipelineFiles)
 	app.Flag("log-level", "Set the level of logging. (default: info, options: panic, debug, info, warning, error, fatal").Default(defaultConfig.LogLevel).EnumVar(&cfg.LogLevel, allLogLevelsAsStrings()...)
 
-	_, err := app.Parse(args)
+	// Commands
+	app.Command("run", "Synchronize the DNS records with the sources (default)").Default()
+	app.Command("validate", "Check the configuration, the filters, the provider credentials and zones and the RBAC permissions of the sources, print a readiness report and exit")
+	app.Command("adopt", "Take over the ownership of the existing unowned records matching the endpoints of the sources, print them and exit (only prints them with --dry-run)")
+	simulate := app.Command("simulate", "Calculate the changes of a synchronization recorded with --state-dump-file offline, print them and exit")
+	simulate.Flag("input", "The file written by --state-dump-file (required)").Required().StringVar(&cfg.SimulateInput)
+	migrate := app.Command("migrate-registry", "Copy the ownership of the records of the txt registry with the owner id to the registry selected by --registry, print them and exit (only prints them with --dry-run)")
+	migrate.Flag("delete-txt-records", "Delete the ownership TXT records of the owner id once their ownership is copied (default: disabled)").BoolVar(&cfg.MigrateDeleteTXTRecords)
+	trace := app.Command("trace", "Explain the decisions of a synchronization for the records of a hostname, e.g. the endpoint and domain filters, the zone and the ownership, print them and exit")
+	trace.Flag("hostname", "The hostname whose records are explained (required)").Required().StringVar(&cfg.TraceHostname)
+
+	configArgs, err := configFileArgs(app, args)
+	if err != nil {
+		return err
+	}
+	command, err := app.Parse(append(configArgs, args...))
 	if err != nil {
 		return err
 	}
+	cfg.Command = command
 
 	return nil
 }
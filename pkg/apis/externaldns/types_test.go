@@ -33,6 +33,7 @@ var (
 		KubeConfig:                  "",
 		RequestTimeout:              time.Second * 30,
 		ContourLoadBalancerService:  "heptio-contour/contour",
+		EmissaryService:             "emissary/emissary-ingress",
 		Sources:                     []string{"service"},
 		Namespace:                   "",
 		FQDNTemplate:                "",
@@ -59,6 +60,7 @@ var (
 		CloudflareProxied:           false,
 		CloudflareZonesPerPage:      50,
 		CoreDNSPrefix:               "/skydns/",
+		DesignateFloatingIPs:        false,
 		AkamaiServiceConsumerDomain: "",
 		AkamaiClientToken:           "",
 		AkamaiClientSecret:          "",
@@ -74,19 +76,62 @@ var (
 		OCIConfigFile:               "/etc/kubernetes/oci.yaml",
 		InMemoryZones:               []string{""},
 		PDNSServer:                  "http://localhost:8081",
+		WebhookProviderURL:          "http://localhost:8888",
 		PDNSAPIKey:                  "",
+		ProviderHTTPProxy:           "",
+		ProviderCABundle:            "",
+		ProviderUserAgent:           "ExternalDNS/{{.Version}} (owner {{.OwnerID}}{{if .ClusterName}}, cluster {{.ClusterName}}{{end}})",
+		ProviderRetryBudget:         0,
+		ProviderRetryBudgetRatio:    0.1,
+		ClusterName:                 "",
+		ProviderTimeout:             0,
+		MinTTL:                      0,
+		MaxTTL:                      0,
+		CNAMETargetCheck:            "",
+		TakeoverScanInterval:        0,
+		TakeoverScanDelete:          false,
+		TakeoverScanConfirmations:   3,
+		TakeoverScanWebhook:         "",
 		Policy:                      "sync",
 		Registry:                    "txt",
 		TXTOwnerID:                  "default",
 		TXTPrefix:                   "",
+		TXTClusterID:                "",
+		TXTTakeoverGracePeriod:      10 * time.Minute,
+		DynamoDBTable:               "external-dns",
+		DynamoDBRegion:              "",
+		DynamoDBCacheInterval:       time.Hour,
 		TXTCacheInterval:            0,
 		Interval:                    time.Minute,
+		RenameDeletionGracePeriod:   0,
+		OrphanDeletionGracePeriod:   0,
+		ChangeDebounce:              0,
+		FailFast:                    false,
+		InheritNamespaceDefaults:    false,
+		InternalZone:                "",
+		SyncExternalNames:           false,
+		DeletionSafetyThreshold:     0,
+		DeletionSafetyMinRecords:    10,
+		DeletionSafetyCycles:        3,
 		Once:                        false,
 		DryRun:                      false,
 		UpdateEvents:                false,
 		LogFormat:                   "text",
 		MetricsAddress:              ":7979",
 		LogLevel:                    logrus.InfoLevel.String(),
+		Command:                     "run",
+		SimulateInput:               "",
+		MigrateDeleteTXTRecords:     false,
+		TraceHostname:               "",
+		StateDumpFile:               "",
+		WriteBackAppliedRecords:     false,
+		PublishRecordClaims:         false,
+		DelegateNamespaceSubzones:   false,
+		DuplicateReport:             false,
+		DNSFreeze:                   false,
+		TrackChangeLatency:          false,
+		TopologyRouting:             false,
+		TopologySetIdentifier:       "",
 		ConnectorSourceServer:       "localhost:8080",
 		ExoscaleEndpoint:            "https://api.exoscale.ch/dns",
 		ExoscaleAPIKey:              "",
@@ -103,6 +148,7 @@ var (
 		KubeConfig:                  "/some/path",
 		RequestTimeout:              time.Second * 77,
 		ContourLoadBalancerService:  "heptio-contour-other/contour-other",
+		EmissaryService:             "ambassador/ambassador",
 		Sources:                     []string{"service", "ingress", "connector"},
 		Namespace:                   "namespace",
 		IgnoreHostnameAnnotation:    true,
@@ -114,6 +160,9 @@ var (
 		GoogleBatchChangeInterval:   time.Second * 2,
 		DomainFilter:                []string{"example.org", "company.com"},
 		ExcludeDomains:              []string{"xapi.example.org", "xapi.company.com"},
+		EndpointFilters:             []string{"exclude-domain=internal.example.org", "label=team=payments"},
+		PipelineFiles:               []string{"public.yaml", "internal.yaml"},
+		ManagedRecordTypes:          []string{"A", "AAAA", "CNAME"},
 		ZoneIDFilter:                []string{"/hostedzone/ZTST1", "/hostedzone/ZTST2"},
 		AlibabaCloudConfigFile:      "/etc/kubernetes/alibaba-cloud.json",
 		AWSZoneType:                 "private",
@@ -124,12 +173,14 @@ var (
 		AWSEvaluateTargetHealth:     false,
 		AWSAPIRetries:               13,
 		AWSPreferCNAME:              true,
+		AWSPrivateZoneVPCs:          []string{"eu-central-1:vpc-1", "eu-west-1:vpc-2"},
 		AzureConfigFile:             "azure.json",
 		AzureResourceGroup:          "arg",
 		AzureSubscriptionID:         "arg",
 		CloudflareProxied:           true,
 		CloudflareZonesPerPage:      20,
 		CoreDNSPrefix:               "/coredns/",
+		DesignateFloatingIPs:        true,
 		AkamaiServiceConsumerDomain: "oooo-xxxxxxxxxxxxxxxx-xxxxxxxxxxxxxxxx.luna.akamaiapis.net",
 		AkamaiClientToken:           "o184671d5307a388180fbf7f11dbdf46",
 		AkamaiClientSecret:          "o184671d5307a388180fbf7f11dbdf46",
@@ -147,21 +198,68 @@ var (
 		PDNSServer:                  "http://ns.example.com:8081",
 		PDNSAPIKey:                  "some-secret-key",
 		PDNSTLSEnabled:              true,
+		WebhookProviderURL:          "http://dns-webhook:8888",
 		TLSCA:                       "/path/to/ca.crt",
 		TLSClientCert:               "/path/to/cert.pem",
 		TLSClientCertKey:            "/path/to/key.pem",
+		ProviderHTTPProxy:           "http://proxy.example.org:3128",
+		ProviderCABundle:            "/path/to/proxy-ca.crt",
+		ProviderUserAgent:           "ExternalDNS/{{.Version}} {{.ClusterName}}",
+		ProviderRetryBudget:         10,
+		ProviderRetryBudgetRatio:    0.2,
+		ClusterName:                 "cluster-a",
+		ProviderTimeout:             time.Minute,
+		ProviderTimeoutOverrides:    []string{"pdns=10s", "aws=2m"},
+		MinTTL:                      time.Minute,
+		MaxTTL:                      24 * time.Hour,
+		CNAMETargetCheck:            "reject",
+		TakeoverScanInterval:        6 * time.Hour,
+		TakeoverScanDelete:          true,
+		TakeoverScanConfirmations:   5,
+		TakeoverScanWebhook:         "https://alerts.example.org/takeover",
 		Policy:                      "upsert-only",
 		Registry:                    "noop",
 		TXTOwnerID:                  "owner-1",
 		TXTPrefix:                   "associated-txt-record",
+		TXTClusterID:                "cluster-a",
+		TXTTakeoverFrom:             []string{"cluster-b", "cluster-c"},
+		TXTTakeoverGracePeriod:      time.Hour,
+		DynamoDBTable:               "dns-ownership",
+		DynamoDBRegion:              "eu-west-1",
+		DynamoDBCacheInterval:       5 * time.Minute,
 		TXTCacheInterval:            12 * time.Hour,
 		Interval:                    10 * time.Minute,
+		RenameDeletionGracePeriod:   5 * time.Minute,
+		OrphanDeletionGracePeriod:   24 * time.Hour,
+		ChangeDebounce:              30 * time.Second,
+		ZonePriority:                []string{"critical.example.org", "example.org"},
+		FailFast:                    true,
+		InheritNamespaceDefaults:    true,
+		InternalZone:                "internal.example.org",
+		SyncExternalNames:           true,
+		DeletionSafetyThreshold:     0.5,
+		DeletionSafetyMinRecords:    20,
+		DeletionSafetyCycles:        5,
 		Once:                        true,
 		DryRun:                      true,
 		UpdateEvents:                true,
 		LogFormat:                   "json",
 		MetricsAddress:              "127.0.0.1:9099",
 		LogLevel:                    logrus.DebugLevel.String(),
+		Command:                     "run",
+		SimulateInput:               "",
+		MigrateDeleteTXTRecords:     false,
+		TraceHostname:               "",
+		StateDumpFile:               "/var/lib/external-dns/state.json",
+		WriteBackAppliedRecords:     true,
+		PublishRecordClaims:         true,
+		DelegateNamespaceSubzones:   true,
+		DuplicateReport:             true,
+		DNSFreeze:                   true,
+		TrackChangeLatency:          true,
+		TopologyRouting:             true,
+		TopologySetIdentifier:       "cluster-a",
+		CAAPolicies:                 []string{"example.org=letsencrypt.org,digicert.com", "internal.example.org="},
 		ConnectorSourceServer:       "localhost:8081",
 		ExoscaleEndpoint:            "https://api.foo.ch/dns",
 		ExoscaleAPIKey:              "1",
@@ -173,6 +271,8 @@ var (
 		NS1IgnoreSSL:                true,
 		TransIPAccountName:          "transip",
 		TransIPPrivateKeyFile:       "/path/to/transip.key",
+
+		AWSDeleteOrphanedHealthChecks: true,
 	}
 )
 
@@ -199,6 +299,7 @@ func TestParseFlags(t *testing.T) {
 				"--kubeconfig=/some/path",
 				"--request-timeout=77s",
 				"--contour-load-balancer=heptio-contour-other/contour-other",
+				"--emissary-service=ambassador/ambassador",
 				"--source=service",
 				"--source=ingress",
 				"--source=connector",
@@ -215,6 +316,7 @@ func TestParseFlags(t *testing.T) {
 				"--azure-subscription-id=arg",
 				"--cloudflare-proxied",
 				"--cloudflare-zones-per-page=20",
+				"--designate-floating-ips",
 				"--coredns-prefix=/coredns/",
 				"--akamai-serviceconsumerdomain=oooo-xxxxxxxxxxxxxxxx-xxxxxxxxxxxxxxxx.luna.akamaiapis.net",
 				"--akamai-client-token=o184671d5307a388180fbf7f11dbdf46",
@@ -232,15 +334,39 @@ func TestParseFlags(t *testing.T) {
 				"--pdns-server=http://ns.example.com:8081",
 				"--pdns-api-key=some-secret-key",
 				"--pdns-tls-enabled",
+				"--webhook-provider-url=http://dns-webhook:8888",
 				"--oci-config-file=oci.yaml",
 				"--tls-ca=/path/to/ca.crt",
 				"--tls-client-cert=/path/to/cert.pem",
 				"--tls-client-cert-key=/path/to/key.pem",
+				"--provider-http-proxy=http://proxy.example.org:3128",
+				"--provider-ca-bundle=/path/to/proxy-ca.crt",
+				"--provider-user-agent=ExternalDNS/{{.Version}} {{.ClusterName}}",
+				"--provider-retry-budget=10",
+				"--provider-retry-budget-ratio=0.2",
+				"--cluster-name=cluster-a",
+				"--provider-timeout=1m",
+				"--provider-timeout-override=pdns=10s",
+				"--provider-timeout-override=aws=2m",
+				"--min-ttl=1m",
+				"--max-ttl=24h",
+				"--cname-target-check=reject",
+				"--takeover-scan-interval=6h",
+				"--takeover-scan-delete",
+				"--takeover-scan-confirmations=5",
+				"--takeover-scan-webhook=https://alerts.example.org/takeover",
 				"--no-infoblox-ssl-verify",
 				"--domain-filter=example.org",
 				"--domain-filter=company.com",
+				"--managed-record-types=A",
+				"--managed-record-types=AAAA",
+				"--managed-record-types=CNAME",
 				"--exclude-domains=xapi.example.org",
 				"--exclude-domains=xapi.company.com",
+				"--endpoint-filter=exclude-domain=internal.example.org",
+				"--endpoint-filter=label=team=payments",
+				"--pipeline=public.yaml",
+				"--pipeline=internal.yaml",
 				"--zone-id-filter=/hostedzone/ZTST1",
 				"--zone-id-filter=/hostedzone/ZTST2",
 				"--aws-zone-type=private",
@@ -250,13 +376,45 @@ func TestParseFlags(t *testing.T) {
 				"--aws-batch-change-interval=2s",
 				"--aws-api-retries=13",
 				"--aws-prefer-cname",
+				"--aws-private-zone-vpc=eu-central-1:vpc-1",
+				"--aws-private-zone-vpc=eu-west-1:vpc-2",
 				"--no-aws-evaluate-target-health",
 				"--policy=upsert-only",
 				"--registry=noop",
 				"--txt-owner-id=owner-1",
 				"--txt-prefix=associated-txt-record",
+				"--txt-cluster-id=cluster-a",
+				"--txt-takeover-from=cluster-b",
+				"--txt-takeover-from=cluster-c",
+				"--txt-takeover-grace-period=1h",
+				"--dynamodb-table=dns-ownership",
+				"--dynamodb-region=eu-west-1",
+				"--dynamodb-cache-interval=5m",
 				"--txt-cache-interval=12h",
 				"--interval=10m",
+				"--rename-deletion-grace-period=5m",
+				"--orphan-deletion-grace-period=24h",
+				"--change-debounce=30s",
+				"--zone-priority=critical.example.org",
+				"--zone-priority=example.org",
+				"--fail-fast",
+				"--inherit-namespace-defaults",
+				"--internal-zone=internal.example.org",
+				"--sync-external-names",
+				"--deletion-safety-threshold=0.5",
+				"--deletion-safety-min-records=20",
+				"--deletion-safety-cycles=5",
+				"--state-dump-file=/var/lib/external-dns/state.json",
+				"--write-back-applied-records",
+				"--publish-record-claims",
+				"--delegate-namespace-subzones",
+				"--duplicate-report",
+				"--dns-freeze",
+				"--track-change-latency",
+				"--topology-routing",
+				"--topology-set-identifier=cluster-a",
+				"--caa-policy=example.org=letsencrypt.org,digicert.com",
+				"--caa-policy=internal.example.org=",
 				"--once",
 				"--dry-run",
 				"--events",
@@ -274,6 +432,7 @@ func TestParseFlags(t *testing.T) {
 				"--ns1-ignoressl",
 				"--transip-account=transip",
 				"--transip-keyfile=/path/to/transip.key",
+				"--aws-delete-orphaned-health-checks",
 			},
 			envVars:  map[string]string{},
 			expected: overriddenConfig,
@@ -286,6 +445,7 @@ func TestParseFlags(t *testing.T) {
 				"EXTERNAL_DNS_KUBECONFIG":                   "/some/path",
 				"EXTERNAL_DNS_REQUEST_TIMEOUT":              "77s",
 				"EXTERNAL_DNS_CONTOUR_LOAD_BALANCER":        "heptio-contour-other/contour-other",
+				"EXTERNAL_DNS_EMISSARY_SERVICE":             "ambassador/ambassador",
 				"EXTERNAL_DNS_SOURCE":                       "service\ningress\nconnector",
 				"EXTERNAL_DNS_NAMESPACE":                    "namespace",
 				"EXTERNAL_DNS_FQDN_TEMPLATE":                "{{.Name}}.service.example.com",
@@ -300,6 +460,7 @@ func TestParseFlags(t *testing.T) {
 				"EXTERNAL_DNS_AZURE_SUBSCRIPTION_ID":        "arg",
 				"EXTERNAL_DNS_CLOUDFLARE_PROXIED":           "1",
 				"EXTERNAL_DNS_CLOUDFLARE_ZONES_PER_PAGE":    "20",
+				"EXTERNAL_DNS_DESIGNATE_FLOATING_IPS":       "true",
 				"EXTERNAL_DNS_COREDNS_PREFIX":               "/coredns/",
 				"EXTERNAL_DNS_AKAMAI_SERVICECONSUMERDOMAIN": "oooo-xxxxxxxxxxxxxxxx-xxxxxxxxxxxxxxxx.luna.akamaiapis.net",
 				"EXTERNAL_DNS_AKAMAI_CLIENT_TOKEN":          "o184671d5307a388180fbf7f11dbdf46",
@@ -316,14 +477,33 @@ func TestParseFlags(t *testing.T) {
 				"EXTERNAL_DNS_OCI_CONFIG_FILE":              "oci.yaml",
 				"EXTERNAL_DNS_INMEMORY_ZONE":                "example.org\ncompany.com",
 				"EXTERNAL_DNS_DOMAIN_FILTER":                "example.org\ncompany.com",
+				"EXTERNAL_DNS_MANAGED_RECORD_TYPES":         "A\nAAAA\nCNAME",
 				"EXTERNAL_DNS_EXCLUDE_DOMAINS":              "xapi.example.org\nxapi.company.com",
+				"EXTERNAL_DNS_ENDPOINT_FILTER":              "exclude-domain=internal.example.org\nlabel=team=payments",
+				"EXTERNAL_DNS_PIPELINE":                     "public.yaml\ninternal.yaml",
 				"EXTERNAL_DNS_PDNS_SERVER":                  "http://ns.example.com:8081",
 				"EXTERNAL_DNS_PDNS_API_KEY":                 "some-secret-key",
 				"EXTERNAL_DNS_PDNS_TLS_ENABLED":             "1",
+				"EXTERNAL_DNS_WEBHOOK_PROVIDER_URL":         "http://dns-webhook:8888",
 				"EXTERNAL_DNS_RDNS_ROOT_DOMAIN":             "lb.rancher.cloud",
 				"EXTERNAL_DNS_TLS_CA":                       "/path/to/ca.crt",
 				"EXTERNAL_DNS_TLS_CLIENT_CERT":              "/path/to/cert.pem",
 				"EXTERNAL_DNS_TLS_CLIENT_CERT_KEY":          "/path/to/key.pem",
+				"EXTERNAL_DNS_PROVIDER_HTTP_PROXY":          "http://proxy.example.org:3128",
+				"EXTERNAL_DNS_PROVIDER_CA_BUNDLE":           "/path/to/proxy-ca.crt",
+				"EXTERNAL_DNS_PROVIDER_USER_AGENT":          "ExternalDNS/{{.Version}} {{.ClusterName}}",
+				"EXTERNAL_DNS_PROVIDER_RETRY_BUDGET":        "10",
+				"EXTERNAL_DNS_PROVIDER_RETRY_BUDGET_RATIO":  "0.2",
+				"EXTERNAL_DNS_CLUSTER_NAME":                 "cluster-a",
+				"EXTERNAL_DNS_PROVIDER_TIMEOUT":             "1m",
+				"EXTERNAL_DNS_PROVIDER_TIMEOUT_OVERRIDE":    "pdns=10s\naws=2m",
+				"EXTERNAL_DNS_MIN_TTL":                      "1m",
+				"EXTERNAL_DNS_MAX_TTL":                      "24h",
+				"EXTERNAL_DNS_CNAME_TARGET_CHECK":           "reject",
+				"EXTERNAL_DNS_TAKEOVER_SCAN_INTERVAL":       "6h",
+				"EXTERNAL_DNS_TAKEOVER_SCAN_DELETE":         "1",
+				"EXTERNAL_DNS_TAKEOVER_SCAN_CONFIRMATIONS":  "5",
+				"EXTERNAL_DNS_TAKEOVER_SCAN_WEBHOOK":        "https://alerts.example.org/takeover",
 				"EXTERNAL_DNS_ZONE_ID_FILTER":               "/hostedzone/ZTST1\n/hostedzone/ZTST2",
 				"EXTERNAL_DNS_AWS_ZONE_TYPE":                "private",
 				"EXTERNAL_DNS_AWS_ZONE_TAGS":                "tag=foo",
@@ -333,12 +513,40 @@ func TestParseFlags(t *testing.T) {
 				"EXTERNAL_DNS_AWS_EVALUATE_TARGET_HEALTH":   "0",
 				"EXTERNAL_DNS_AWS_API_RETRIES":              "13",
 				"EXTERNAL_DNS_AWS_PREFER_CNAME":             "true",
+				"EXTERNAL_DNS_AWS_PRIVATE_ZONE_VPC":         "eu-central-1:vpc-1\neu-west-1:vpc-2",
 				"EXTERNAL_DNS_POLICY":                       "upsert-only",
 				"EXTERNAL_DNS_REGISTRY":                     "noop",
 				"EXTERNAL_DNS_TXT_OWNER_ID":                 "owner-1",
 				"EXTERNAL_DNS_TXT_PREFIX":                   "associated-txt-record",
+				"EXTERNAL_DNS_TXT_CLUSTER_ID":               "cluster-a",
+				"EXTERNAL_DNS_TXT_TAKEOVER_FROM":            "cluster-b\ncluster-c",
+				"EXTERNAL_DNS_TXT_TAKEOVER_GRACE_PERIOD":    "1h",
+				"EXTERNAL_DNS_DYNAMODB_TABLE":               "dns-ownership",
+				"EXTERNAL_DNS_DYNAMODB_REGION":              "eu-west-1",
+				"EXTERNAL_DNS_DYNAMODB_CACHE_INTERVAL":      "5m",
 				"EXTERNAL_DNS_TXT_CACHE_INTERVAL":           "12h",
 				"EXTERNAL_DNS_INTERVAL":                     "10m",
+				"EXTERNAL_DNS_RENAME_DELETION_GRACE_PERIOD": "5m",
+				"EXTERNAL_DNS_ORPHAN_DELETION_GRACE_PERIOD": "24h",
+				"EXTERNAL_DNS_CHANGE_DEBOUNCE":              "30s",
+				"EXTERNAL_DNS_ZONE_PRIORITY":                "critical.example.org\nexample.org",
+				"EXTERNAL_DNS_FAIL_FAST":                    "1",
+				"EXTERNAL_DNS_INHERIT_NAMESPACE_DEFAULTS":   "1",
+				"EXTERNAL_DNS_INTERNAL_ZONE":                "internal.example.org",
+				"EXTERNAL_DNS_SYNC_EXTERNAL_NAMES":          "1",
+				"EXTERNAL_DNS_DELETION_SAFETY_THRESHOLD":    "0.5",
+				"EXTERNAL_DNS_DELETION_SAFETY_MIN_RECORDS":  "20",
+				"EXTERNAL_DNS_DELETION_SAFETY_CYCLES":       "5",
+				"EXTERNAL_DNS_STATE_DUMP_FILE":              "/var/lib/external-dns/state.json",
+				"EXTERNAL_DNS_WRITE_BACK_APPLIED_RECORDS":   "1",
+				"EXTERNAL_DNS_PUBLISH_RECORD_CLAIMS":        "1",
+				"EXTERNAL_DNS_DELEGATE_NAMESPACE_SUBZONES":  "1",
+				"EXTERNAL_DNS_DUPLICATE_REPORT":             "1",
+				"EXTERNAL_DNS_DNS_FREEZE":                   "1",
+				"EXTERNAL_DNS_TRACK_CHANGE_LATENCY":         "1",
+				"EXTERNAL_DNS_TOPOLOGY_ROUTING":             "1",
+				"EXTERNAL_DNS_TOPOLOGY_SET_IDENTIFIER":      "cluster-a",
+				"EXTERNAL_DNS_CAA_POLICY":                   "example.org=letsencrypt.org,digicert.com\ninternal.example.org=",
 				"EXTERNAL_DNS_ONCE":                         "1",
 				"EXTERNAL_DNS_DRY_RUN":                      "1",
 				"EXTERNAL_DNS_EVENTS":                       "1",
@@ -356,6 +564,8 @@ func TestParseFlags(t *testing.T) {
 				"EXTERNAL_DNS_NS1_IGNORESSL":                "1",
 				"EXTERNAL_DNS_TRANSIP_ACCOUNT":              "transip",
 				"EXTERNAL_DNS_TRANSIP_KEYFILE":              "/path/to/transip.key",
+
+				"EXTERNAL_DNS_AWS_DELETE_ORPHANED_HEALTH_CHECKS": "1",
 			},
 			expected: overriddenConfig,
 		},
@@ -371,6 +581,43 @@ func TestParseFlags(t *testing.T) {
 	}
 }
 
+func TestParseCommands(t *testing.T) {
+	for _, ti := range []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"--source=service", "--provider=google"}, "run"},
+		{[]string{"run", "--source=service", "--provider=google"}, "run"},
+		{[]string{"validate", "--source=service", "--provider=google"}, "validate"},
+		{[]string{"--source=service", "--provider=google", "validate"}, "validate"},
+		{[]string{"adopt", "--source=service", "--provider=google", "--dry-run"}, "adopt"},
+		{[]string{"simulate", "--input=state.json"}, "simulate"},
+		{[]string{"migrate-registry", "--registry=dynamodb", "--provider=aws"}, "migrate-registry"},
+		{[]string{"trace", "--hostname=shop.example.org", "--source=service", "--provider=google"}, "trace"},
+	} {
+		cfg := NewConfig()
+		require.NoError(t, cfg.ParseFlags(ti.args))
+		assert.Equal(t, ti.expected, cfg.Command, "%v", ti.args)
+	}
+
+	cfg := NewConfig()
+	require.NoError(t, cfg.ParseFlags([]string{"simulate", "--input=state.json", "--policy=upsert-only"}))
+	assert.Equal(t, "state.json", cfg.SimulateInput)
+	assert.Equal(t, "upsert-only", cfg.Policy)
+	assert.Error(t, NewConfig().ParseFlags([]string{"simulate"}))
+
+	cfg = NewConfig()
+	require.NoError(t, cfg.ParseFlags([]string{"migrate-registry", "--delete-txt-records", "--registry=dynamodb"}))
+	assert.True(t, cfg.MigrateDeleteTXTRecords)
+
+	cfg = NewConfig()
+	require.NoError(t, cfg.ParseFlags([]string{"trace", "--hostname=shop.example.org"}))
+	assert.Equal(t, "shop.example.org", cfg.TraceHostname)
+	assert.Error(t, NewConfig().ParseFlags([]string{"trace"}))
+
+	assert.Error(t, NewConfig().ParseFlags([]string{"unknown", "--source=service", "--provider=google"}))
+}
+
 // helper functions
 
 func setEnv(t *testing.T, env map[string]string) map[string]string {
@@ -85,5 +85,33 @@ func ValidateConfig(cfg *externaldns.Config) error {
 	if cfg.IgnoreHostnameAnnotation && cfg.FQDNTemplate == "" {
 		return errors.New("FQDN Template must be set if ignoring annotations")
 	}
+
+	if cfg.OrphanDeletionGracePeriod > 0 && cfg.Registry != "txt" && cfg.Registry != "dynamodb" {
+		return errors.New("the orphan deletion grace period requires the txt or dynamodb registry")
+	}
+
+	if cfg.MinTTL > 0 && cfg.MaxTTL > 0 && cfg.MinTTL > cfg.MaxTTL {
+		return errors.New("the minimum TTL must not be greater than the maximum TTL")
+	}
+
+	// the region of the topology routing is materialized as Route53 latency-based routing
+	if cfg.TopologyRouting && cfg.Provider != "aws" {
+		return errors.New("the topology routing requires the aws provider")
+	}
+
+	// the other providers don't list the CAA records, which would be created again in every synchronization
+	if len(cfg.CAAPolicies) > 0 && cfg.Provider != "aws" && cfg.Provider != "inmemory" {
+		return fmt.Errorf("the CAA policies require a provider listing CAA records, i.e. aws or inmemory, not %s", cfg.Provider)
+	}
+
+	// a single synchronization would hold back its changes and exit without applying them
+	if cfg.ChangeDebounce > 0 && cfg.Once {
+		return errors.New("the change debounce can't be combined with --once")
+	}
+
+	// without prioritized zones, the changes of all zones are applied together and there's nothing to skip
+	if cfg.FailFast && len(cfg.ZonePriority) == 0 {
+		return errors.New("--fail-fast requires --zone-priority")
+	}
 	return nil
 }
@@ -18,6 +18,7 @@ package validation
 
 import (
 	"testing"
+	"time"
 
 	"sigs.k8s.io/external-dns/pkg/apis/externaldns"
 
@@ -124,3 +125,66 @@ func TestValidateBadIgnoreHostnameAnnotationsConfig(t *testing.T) {
 
 	assert.Error(t, ValidateConfig(cfg))
 }
+
+func TestValidateOrphanDeletionGracePeriodConfig(t *testing.T) {
+	cfg := newValidConfig(t)
+	cfg.OrphanDeletionGracePeriod = time.Hour
+	cfg.Registry = "noop"
+	assert.Error(t, ValidateConfig(cfg))
+
+	cfg.Registry = "txt"
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg.Registry = "dynamodb"
+	assert.NoError(t, ValidateConfig(cfg))
+}
+
+func TestValidateTTLBoundsConfig(t *testing.T) {
+	cfg := newValidConfig(t)
+	cfg.MinTTL = time.Hour
+	cfg.MaxTTL = time.Minute
+	assert.EqualError(t, ValidateConfig(cfg), "the minimum TTL must not be greater than the maximum TTL")
+
+	cfg.MaxTTL = 0
+	assert.NoError(t, ValidateConfig(cfg))
+	cfg.MaxTTL = time.Hour
+	assert.NoError(t, ValidateConfig(cfg))
+}
+
+func TestValidateTopologyRoutingConfig(t *testing.T) {
+	cfg := newValidConfig(t)
+	cfg.TopologyRouting = true
+	assert.EqualError(t, ValidateConfig(cfg), "the topology routing requires the aws provider")
+
+	cfg.Provider = "aws"
+	assert.NoError(t, ValidateConfig(cfg))
+}
+
+func TestValidateCAAPoliciesConfig(t *testing.T) {
+	cfg := newValidConfig(t)
+	cfg.CAAPolicies = []string{"example.org=letsencrypt.org"}
+	assert.EqualError(t, ValidateConfig(cfg), "the CAA policies require a provider listing CAA records, i.e. aws or inmemory, not test-provider")
+
+	for _, provider := range []string{"aws", "inmemory"} {
+		cfg.Provider = provider
+		assert.NoError(t, ValidateConfig(cfg))
+	}
+}
+
+func TestValidateChangeDebounceConfig(t *testing.T) {
+	cfg := newValidConfig(t)
+	cfg.ChangeDebounce = time.Minute
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg.Once = true
+	assert.EqualError(t, ValidateConfig(cfg), "the change debounce can't be combined with --once")
+}
+
+func TestValidateFailFastConfig(t *testing.T) {
+	cfg := newValidConfig(t)
+	cfg.FailFast = true
+	assert.EqualError(t, ValidateConfig(cfg), "--fail-fast requires --zone-priority")
+
+	cfg.ZonePriority = []string{"example.org"}
+	assert.NoError(t, ValidateConfig(cfg))
+}
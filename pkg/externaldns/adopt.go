@@ -0,0 +1,125 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package externaldns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	apis "sigs.k8s.io/external-dns/pkg/apis/externaldns"
+	"sigs.k8s.io/external-dns/provider"
+	"sigs.k8s.io/external-dns/registry"
+	"sigs.k8s.io/external-dns/source"
+)
+
+// Adopt takes over the ownership of the existing records which aren't owned by anyone but match the
+// desired endpoints of the sources, so zones maintained by hand or by another tool can be brought under
+// management without deleting and recreating their records. Records with other targets than their
+// endpoints are reported and left alone. In dry-run mode the matches are only printed.
+func Adopt(ctx context.Context, w io.Writer, cfg *apis.Config) error {
+	endpointsSource, err := NewSourceFromConfig(cfg)
+	if err != nil {
+		return err
+	}
+	p, err := NewProviderFromConfig(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	r, err := NewRegistryFromConfig(cfg, p)
+	if err != nil {
+		return err
+	}
+	return adoptRecords(ctx, w, endpointsSource, r, cfg.DryRun)
+}
+
+func adoptRecords(ctx context.Context, w io.Writer, endpointsSource source.Source, r registry.Registry, dryRun bool) error {
+	adopter, ok := r.(registry.Adopter)
+	if !ok {
+		return errors.New("the registry can't adopt records, use the txt registry")
+	}
+
+	records, err := r.Records(ctx)
+	zoneErrors, partial := err.(provider.ZoneErrors)
+	if err != nil && !partial {
+		return err
+	}
+	for _, zone := range zoneErrors.Zones() {
+		fmt.Fprintf(w, "Skipping zone %s, its records couldn't be listed\n", zone)
+	}
+	endpoints, err := endpointsSource.Endpoints()
+	if err != nil {
+		return err
+	}
+
+	unowned := map[string]*endpoint.Endpoint{}
+	for _, record := range records {
+		if record.Labels[endpoint.OwnerLabelKey] != "" {
+			continue
+		}
+		record = record.DeepCopy()
+		if err := record.Normalize(); err != nil {
+			continue
+		}
+		unowned[adoptionKey(record)] = record
+	}
+
+	var adopted []*endpoint.Endpoint
+	mismatched := 0
+	for _, ep := range endpoints {
+		ep = ep.DeepCopy()
+		if err := ep.Normalize(); err != nil {
+			continue
+		}
+		record, ok := unowned[adoptionKey(ep)]
+		if !ok {
+			continue
+		}
+		// every record is adopted once, even if several endpoints match it
+		delete(unowned, adoptionKey(ep))
+		if !record.Targets.Same(ep.Targets) {
+			fmt.Fprintf(w, "MISMATCH %s -> %s\n", record, ep)
+			mismatched++
+			continue
+		}
+
+		record.Labels = endpoint.NewLabels()
+		if resource := ep.Labels[endpoint.ResourceLabelKey]; resource != "" {
+			record.Labels[endpoint.ResourceLabelKey] = resource
+		}
+		fmt.Fprintf(w, "ADOPT %s\n", record)
+		adopted = append(adopted, record)
+	}
+
+	if dryRun {
+		fmt.Fprintf(w, "%d records would be adopted, %d records differ from their endpoints\n", len(adopted), mismatched)
+		return nil
+	}
+	if len(adopted) > 0 {
+		if err := adopter.AdoptRecords(ctx, adopted); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(w, "%d records adopted, %d records differ from their endpoints\n", len(adopted), mismatched)
+	return nil
+}
+
+func adoptionKey(ep *endpoint.Endpoint) string {
+	return fmt.Sprintf("%s::%s::%s", ep.DNSName, ep.RecordType, ep.SetIdentifier)
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package externaldns
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/internal/testutils"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+	"sigs.k8s.io/external-dns/registry"
+)
+
+func TestAdoptRecords(t *testing.T) {
+	ctx := context.Background()
+	newProvider := func() provider.Provider {
+		p := provider.NewInMemoryProvider(provider.InMemoryInitZones([]string{"example.org"}))
+		require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{
+			Create: []*endpoint.Endpoint{
+				endpoint.NewEndpoint("match.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+				endpoint.NewEndpoint("mismatch.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+				endpoint.NewEndpoint("unused.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+			},
+		}))
+		return p
+	}
+	match := endpoint.NewEndpoint("match.example.org", endpoint.RecordTypeA, "1.2.3.4")
+	match.Labels[endpoint.ResourceLabelKey] = "ingress/default/match"
+	src := new(testutils.MockSource)
+	src.On("Endpoints").Return([]*endpoint.Endpoint{
+		match,
+		endpoint.NewEndpoint("mismatch.example.org", endpoint.RecordTypeA, "4.3.2.1"),
+		endpoint.NewEndpoint("new.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+	}, nil)
+
+	t.Run("dry run", func(t *testing.T) {
+		r, err := registry.NewTXTRegistry(newProvider(), "", "owner", time.Hour, "", nil, 0)
+		require.NoError(t, err)
+
+		var out bytes.Buffer
+		require.NoError(t, adoptRecords(ctx, &out, src, r, true))
+		assert.Equal(t, `ADOPT match.example.org 0 IN A  1.2.3.4 []
+MISMATCH mismatch.example.org 0 IN A  1.2.3.4 [] -> mismatch.example.org 0 IN A  4.3.2.1 []
+1 records would be adopted, 1 records differ from their endpoints
+`, out.String())
+
+		records, err := r.Records(ctx)
+		require.NoError(t, err)
+		for _, record := range records {
+			assert.Empty(t, record.Labels[endpoint.OwnerLabelKey], record.DNSName)
+		}
+	})
+
+	t.Run("adopt", func(t *testing.T) {
+		r, err := registry.NewTXTRegistry(newProvider(), "", "owner", time.Hour, "", nil, 0)
+		require.NoError(t, err)
+
+		var out bytes.Buffer
+		require.NoError(t, adoptRecords(ctx, &out, src, r, false))
+		assert.Contains(t, out.String(), "1 records adopted, 1 records differ from their endpoints\n")
+
+		records, err := r.Records(ctx)
+		require.NoError(t, err)
+		owners := map[string]string{}
+		for _, record := range records {
+			owners[record.DNSName] = record.Labels[endpoint.OwnerLabelKey]
+			if record.DNSName == "match.example.org" {
+				assert.Equal(t, "ingress/default/match", record.Labels[endpoint.ResourceLabelKey])
+			}
+		}
+		assert.Equal(t, map[string]string{
+			"match.example.org":    "owner",
+			"mismatch.example.org": "",
+			"unused.example.org":   "",
+		}, owners)
+	})
+
+	t.Run("unsupported registry", func(t *testing.T) {
+		r, err := registry.NewNoopRegistry(newProvider())
+		require.NoError(t, err)
+		assert.EqualError(t, adoptRecords(ctx, &bytes.Buffer{}, src, r, false), "the registry can't adopt records, use the txt registry")
+	})
+}
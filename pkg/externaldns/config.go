@@ -0,0 +1,447 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package externaldns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"golang.org/x/oauth2"
+	"k8s.io/client-go/kubernetes"
+
+	"sigs.k8s.io/external-dns/controller"
+	"sigs.k8s.io/external-dns/endpoint"
+	apis "sigs.k8s.io/external-dns/pkg/apis/externaldns"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+	"sigs.k8s.io/external-dns/registry"
+	"sigs.k8s.io/external-dns/source"
+)
+
+// NewSourceFromConfig creates the sources selected by the configuration, combined into a single,
+// deduplicated source.
+func NewSourceFromConfig(cfg *apis.Config) (source.Source, error) {
+	return newSourceFromConfig(cfg, newClientGeneratorFromConfig(cfg))
+}
+
+// newClientGeneratorFromConfig creates the generator of the Kubernetes clients and the informers shared by
+// the sources and the controller.
+func newClientGeneratorFromConfig(cfg *apis.Config) *source.SingletonClientGenerator {
+	return &source.SingletonClientGenerator{
+		KubeConfig: cfg.KubeConfig,
+		KubeMaster: cfg.Master,
+		// If update events are enabled, disable timeout.
+		RequestTimeout: func() time.Duration {
+			if cfg.UpdateEvents {
+				return 0
+			}
+			return cfg.RequestTimeout
+		}(),
+	}
+}
+
+func newSourceFromConfig(cfg *apis.Config, clientGenerator source.ClientGenerator) (source.Source, error) {
+	// Create a source.Config from the flags passed by the user.
+	sourceCfg := &source.Config{
+		Namespace:                   cfg.Namespace,
+		AnnotationFilter:            cfg.AnnotationFilter,
+		FQDNTemplate:                cfg.FQDNTemplate,
+		CombineFQDNAndAnnotation:    cfg.CombineFQDNAndAnnotation,
+		IgnoreHostnameAnnotation:    cfg.IgnoreHostnameAnnotation,
+		Compatibility:               cfg.Compatibility,
+		PublishInternal:             cfg.PublishInternal,
+		PublishHostIP:               cfg.PublishHostIP,
+		MetalLBAnnouncedOnly:        cfg.MetalLBAnnouncedOnly,
+		InheritNamespaceDefaults:    cfg.InheritNamespaceDefaults,
+		InternalZone:                cfg.InternalZone,
+		SyncExternalNames:           cfg.SyncExternalNames && !cfg.DryRun,
+		ConnectorServer:             cfg.ConnectorSourceServer,
+		CRDSourceAPIVersion:         cfg.CRDSourceAPIVersion,
+		CRDSourceKind:               cfg.CRDSourceKind,
+		JSONPathSourceAPIVersion:    cfg.JSONPathSourceAPIVersion,
+		JSONPathSourceKind:          cfg.JSONPathSourceKind,
+		JSONPathSourceHostname:      cfg.JSONPathSourceHostname,
+		JSONPathSourceTarget:        cfg.JSONPathSourceTarget,
+		KubeConfig:                  cfg.KubeConfig,
+		KubeMaster:                  cfg.Master,
+		ServiceTypeFilter:           cfg.ServiceTypeFilter,
+		IstioIngressGatewayServices: cfg.IstioIngressGatewayServices,
+		CFAPIEndpoint:               cfg.CFAPIEndpoint,
+		CFUsername:                  cfg.CFUsername,
+		CFPassword:                  cfg.CFPassword,
+		ContourLoadBalancerService:  cfg.ContourLoadBalancerService,
+		EmissaryService:             cfg.EmissaryService,
+		GoogleProject:               cfg.GoogleProject,
+		APIServerAdditionalServices: cfg.APIServerAdditionalServices,
+	}
+
+	// Lookup all the selected sources by names and pass them the desired configuration.
+	sources, err := source.ByNames(clientGenerator, cfg.Sources, sourceCfg)
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.CAAPolicies) > 0 {
+		caaSource, err := source.NewCAAPolicySource(cfg.CAAPolicies)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, caaSource)
+	}
+
+	var cutoverClient kubernetes.Interface
+	if cfg.CutoverConfigMap != "" {
+		cutoverClient, err = clientGenerator.KubeClient()
+		if err != nil {
+			return nil, err
+		}
+	}
+	combinedSource, err := source.NewCutoverSource(source.NewMultiSource(sources), cutoverClient, cfg.CutoverConfigMap)
+	if err != nil {
+		return nil, err
+	}
+	combinedSource = source.NewProviderSelectionSource(combinedSource, cfg.PipelineName, cfg.PipelineNames, domainFilterFromConfig(cfg).Match)
+	if cfg.TopologyRouting {
+		client, err := clientGenerator.KubeClient()
+		if err != nil {
+			return nil, err
+		}
+		combinedSource, err = source.NewTopologySource(combinedSource, client, cfg.TopologySetIdentifier)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Combine multiple sources into a single, deduplicated source with the cutover groups, the provider
+	// selection and the topology routing applied.
+	return source.NewDedupSource(combinedSource), nil
+}
+
+// NewProviderFromConfig creates the DNS provider selected by the configuration from the providers
+// registered with provider.Register. The providers of ExternalDNS are registered by this package
+// unless they are excluded with the no_<provider> build tag, e.g. no_aws_sd for the aws-sd provider.
+// The provider gets its own HTTP client with a copy of the default transport configured with the proxy and
+// CA bundle of the provider API clients, the User-Agent and the retry budget, passed to its factory with
+// provider.WithHTTPClient and as the oauth2 client. The transport is passed with provider.WithHTTPTransport
+// to the providers building a transport of their own. The default HTTP client of the process is left alone.
+func NewProviderFromConfig(ctx context.Context, cfg *apis.Config) (provider.Provider, error) {
+	transport, err := newProviderTransport(cfg.ProviderHTTPProxy, cfg.ProviderCABundle)
+	if err != nil {
+		return nil, err
+	}
+	userAgent, err := providerUserAgent(cfg)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{}
+	configureProviderClient(client, transport, userAgent, cfg)
+	ctx = provider.WithHTTPClient(ctx, client)
+	ctx = provider.WithHTTPTransport(ctx, transport)
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, client)
+	return provider.New(ctx, cfg.Provider, cfg)
+}
+
+func domainFilterFromConfig(cfg *apis.Config) provider.DomainFilter {
+	return provider.NewDomainFilterWithExclusions(cfg.DomainFilter, cfg.ExcludeDomains)
+}
+
+func zoneIDFilterFromConfig(cfg *apis.Config) provider.ZoneIDFilter {
+	return provider.NewZoneIDFilter(cfg.ZoneIDFilter)
+}
+
+// NewRegistryFromConfig creates the registry selected by the configuration on top of the provider.
+func NewRegistryFromConfig(cfg *apis.Config, p provider.Provider) (registry.Registry, error) {
+	var (
+		r   registry.Registry
+		err error
+	)
+	// the provider only lists and changes the managed record types, and the TXT records of the txt registry
+	managed := ManagedRecordTypesFromConfig(cfg)
+	timeout, err := ProviderTimeoutFromConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	// the changes of the prioritized zones are applied first, every call of the provider times out separately
+	prioritized := provider.NewZonePriorityProvider(provider.NewTimeoutProvider(p, timeout), cfg.ZonePriority, cfg.FailFast)
+	switch cfg.Registry {
+	case "noop":
+		r, err = registry.NewNoopRegistry(provider.NewRecordTypeFilter(prioritized, managed))
+	case "txt":
+		if len(managed) > 0 {
+			managed = append(managed, endpoint.RecordTypeTXT)
+		}
+		r, err = registry.NewTXTRegistry(provider.NewRecordTypeFilter(prioritized, managed), cfg.TXTPrefix, cfg.TXTOwnerID, cfg.TXTCacheInterval, cfg.TXTClusterID, cfg.TXTTakeoverFrom, cfg.TXTTakeoverGracePeriod)
+	case "aws-sd":
+		sdProvider, ok := p.(*provider.AWSSDProvider)
+		if !ok {
+			return nil, errors.New("the aws-sd registry requires the aws-sd provider")
+		}
+		r, err = registry.NewAWSSDRegistry(sdProvider, cfg.TXTOwnerID)
+	case "dynamodb":
+		var client dynamodbiface.DynamoDBAPI
+		if client, err = registry.NewDynamoDBClient(cfg.DynamoDBRegion, cfg.AWSAssumeRole); err == nil {
+			r, err = registry.NewDynamoDBRegistry(provider.NewRecordTypeFilter(prioritized, managed), client, cfg.DynamoDBTable, cfg.TXTOwnerID, cfg.DynamoDBCacheInterval)
+		}
+	default:
+		return nil, fmt.Errorf("unknown registry: %s", cfg.Registry)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// NewControllerFromConfig creates a controller with the sources, provider, registry and policy selected
+// by the configuration, the same way the external-dns binary does.
+func NewControllerFromConfig(ctx context.Context, cfg *apis.Config) (*controller.Controller, error) {
+	// the controller watches the same resources as the sources with the informers of their clients
+	clientGenerator := newClientGeneratorFromConfig(cfg)
+	endpointsSource, err := newSourceFromConfig(cfg, clientGenerator)
+	if err != nil {
+		return nil, err
+	}
+	p, err := NewProviderFromConfig(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	r, err := NewRegistryFromConfig(cfg, p)
+	if err != nil {
+		return nil, err
+	}
+	policy, exists := plan.Policies[cfg.Policy]
+	if !exists {
+		return nil, fmt.Errorf("unknown policy: %s", cfg.Policy)
+	}
+
+	opts := Options{
+		Source:                    endpointsSource,
+		Registry:                  r,
+		Policy:                    policy,
+		ManagedRecordTypes:        ManagedRecordTypesFromConfig(cfg),
+		Interval:                  cfg.Interval,
+		RenameDeletionGracePeriod: cfg.RenameDeletionGracePeriod,
+		OrphanDeletionGracePeriod: cfg.OrphanDeletionGracePeriod,
+		ChangeDebounce:            cfg.ChangeDebounce,
+		StateDumpFile:             cfg.StateDumpFile,
+		MinTTL:                    cfg.MinTTL,
+		MaxTTL:                    cfg.MaxTTL,
+	}
+	if cfg.DryRun {
+		opts.ImpactModel = NewImpactModelFromConfig(cfg)
+	}
+	// the records aren't applied in dry-run mode
+	if cfg.WriteBackAppliedRecords && !cfg.DryRun {
+		client, err := source.NewDynamicKubernetesClient(cfg.KubeConfig, cfg.Master, cfg.RequestTimeout)
+		if err != nil {
+			return nil, err
+		}
+		annotator, err := source.NewAppliedRecordsAnnotator(client, cfg.CRDSourceAPIVersion, cfg.CRDSourceKind, cfg.DomainFilter)
+		if err != nil {
+			return nil, err
+		}
+		opts.AppliedRecordsWriter = annotator
+	}
+	// the claims of the records are published unless in dry-run mode, like the applied records
+	if cfg.PublishRecordClaims && !cfg.DryRun {
+		client, err := source.NewDynamicKubernetesClient(cfg.KubeConfig, cfg.Master, cfg.RequestTimeout)
+		if err != nil {
+			return nil, err
+		}
+		publisher, err := source.NewDNSRecordClaimPublisher(client, cfg.TXTOwnerID)
+		if err != nil {
+			return nil, err
+		}
+		opts.OwnershipPublisher = publisher
+	}
+	// the clamped TTLs, invalid provider-specific properties, dangling CNAMEs, takeover risks and conflicts with other clusters are reported to the resources, unless in dry-run mode
+	_, validatesProviderSpecific := p.(provider.ProviderSpecificValidator)
+	txtRegistry, reportsConflicts := r.(*registry.TXTRegistry)
+	reportsConflicts = reportsConflicts && cfg.TXTClusterID != ""
+	if (cfg.MinTTL > 0 || cfg.MaxTTL > 0 || validatesProviderSpecific || cfg.CNAMETargetCheck != "" || cfg.TakeoverScanInterval > 0 || reportsConflicts) && !cfg.DryRun {
+		kubeClient, err := source.NewKubeClient(cfg.KubeConfig, cfg.Master, cfg.RequestTimeout)
+		if err != nil {
+			return nil, err
+		}
+		dynamicClient, err := source.NewDynamicKubernetesClient(cfg.KubeConfig, cfg.Master, cfg.RequestTimeout)
+		if err != nil {
+			return nil, err
+		}
+		recorder, err := source.NewResourceEventRecorder(kubeClient, dynamicClient, cfg.CRDSourceAPIVersion, cfg.CRDSourceKind)
+		if err != nil {
+			return nil, err
+		}
+		opts.EventRecorder = recorder
+		if reportsConflicts {
+			txtRegistry.SetEventRecorder(recorder)
+		}
+	}
+	if cfg.DNSFreeze {
+		client, err := clientGenerator.DynamicKubernetesClient()
+		if err != nil {
+			return nil, err
+		}
+		opts.FreezeLister = source.NewDNSFreezeLister(clientGenerator.InformerFactories(), client)
+	}
+	if cfg.TrackChangeLatency {
+		client, err := clientGenerator.DynamicKubernetesClient()
+		if err != nil {
+			return nil, err
+		}
+		timer, err := source.NewResourceChangeTimes(clientGenerator.InformerFactories(), client, cfg.CRDSourceAPIVersion, cfg.CRDSourceKind)
+		if err != nil {
+			return nil, err
+		}
+		opts.ResourceChangeTimer = timer
+	}
+	if len(cfg.EndpointFilters) > 0 {
+		var resources controller.ResourceLabelLister
+		for _, spec := range cfg.EndpointFilters {
+			if !strings.HasPrefix(spec, "label=") {
+				continue
+			}
+			// the label filters match the labels of the resources of the endpoints
+			kubeClient, err := clientGenerator.KubeClient()
+			if err != nil {
+				return nil, err
+			}
+			dynamicClient, err := clientGenerator.DynamicKubernetesClient()
+			if err != nil {
+				return nil, err
+			}
+			lister, err := source.NewResourceLabelLister(clientGenerator.InformerFactories(), kubeClient, dynamicClient, cfg.Namespace, cfg.CRDSourceAPIVersion, cfg.CRDSourceKind)
+			if err != nil {
+				return nil, err
+			}
+			resources = lister
+			break
+		}
+		filters, err := controller.NewEndpointFilterChain(cfg.EndpointFilters, resources)
+		if err != nil {
+			return nil, err
+		}
+		opts.EndpointFilters = filters
+	}
+	if adjuster, ok := p.(provider.EndpointAdjuster); ok {
+		// the endpoints are adjusted by the provider itself, which times out like the calls of the registry
+		timeout, err := ProviderTimeoutFromConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		opts.EndpointAdjuster = provider.NewTimeoutEndpointAdjuster(adjuster, timeout)
+	}
+	if validator, ok := p.(provider.ProviderSpecificValidator); ok {
+		opts.ProviderSpecificValidator = validator
+	}
+	if hc, ok := p.(provider.HealthCheckProvider); ok {
+		opts.HealthChecks = hc.SupportsHealthChecks()
+	}
+	if cfg.CNAMETargetCheck != "" {
+		opts.CNAMETargetCheck = controller.NewCNAMETargetCheck(net.DefaultResolver, cfg.CNAMETargetCheck == "reject")
+	}
+	if cfg.TakeoverScanInterval > 0 {
+		opts.TakeoverScanner = controller.NewTakeoverScanner(cfg.TXTOwnerID, cfg.TakeoverScanInterval, cfg.TakeoverScanDelete, cfg.TakeoverScanConfirmations, cfg.TakeoverScanWebhook, net.DefaultResolver)
+	}
+	if cfg.DelegateNamespaceSubzones {
+		delegator, ok := p.(provider.ZoneDelegator)
+		if !ok {
+			return nil, fmt.Errorf("the %s provider can't create subzones", cfg.Provider)
+		}
+		client, err := source.NewKubeClient(cfg.KubeConfig, cfg.Master, cfg.RequestTimeout)
+		if err != nil {
+			return nil, err
+		}
+		opts.SubzoneLister = source.NewNamespaceSubzoneLister(client)
+		opts.ZoneDelegator = delegator
+	}
+	if cfg.DeletionSafetyThreshold > 0 {
+		opts.DeletionGuard = controller.NewDeletionGuard(cfg.DomainFilter, cfg.DeletionSafetyThreshold, cfg.DeletionSafetyMinRecords, cfg.DeletionSafetyCycles)
+	}
+	lister, _ := p.(provider.ZoneLister)
+	opts.ZoneSyncMetrics = controller.NewZoneSyncMetrics(lister, cfg.DomainFilter)
+	opts.DuplicateReport = controller.NewDuplicateReport()
+	return NewController(opts)
+}
+
+// ManagedRecordTypesFromConfig returns the record types of --managed-record-types, which may also be
+// comma separated, or nil if they aren't restricted. The CAA records of --caa-policy are always managed.
+func ManagedRecordTypesFromConfig(cfg *apis.Config) []string {
+	var recordTypes []string
+	for _, value := range cfg.ManagedRecordTypes {
+		for _, recordType := range strings.Split(value, ",") {
+			if recordType = strings.ToUpper(strings.TrimSpace(recordType)); recordType != "" {
+				recordTypes = append(recordTypes, recordType)
+			}
+		}
+	}
+	if len(cfg.CAAPolicies) == 0 {
+		return recordTypes
+	}
+
+	if len(recordTypes) == 0 {
+		recordTypes = append(recordTypes, plan.DefaultManagedRecordTypes...)
+	}
+	for _, recordType := range recordTypes {
+		if recordType == endpoint.RecordTypeCAA {
+			return recordTypes
+		}
+	}
+	return append(recordTypes, endpoint.RecordTypeCAA)
+}
+
+// ProviderTimeoutFromConfig returns the timeout of the calls of the provider: the one of
+// --provider-timeout-override for the provider, if any, or --provider-timeout.
+func ProviderTimeoutFromConfig(cfg *apis.Config) (time.Duration, error) {
+	timeout := cfg.ProviderTimeout
+	for _, override := range cfg.ProviderTimeoutOverrides {
+		parts := strings.SplitN(override, "=", 2)
+		if len(parts) != 2 {
+			return 0, fmt.Errorf("invalid provider timeout override (provider=timeout) found '%v'", override)
+		}
+		value, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return 0, fmt.Errorf("invalid provider timeout override '%v': %v", override, err)
+		}
+		if strings.TrimSpace(parts[0]) == cfg.Provider {
+			timeout = value
+		}
+	}
+	return timeout, nil
+}
+
+// NewImpactModelFromConfig returns how the configured provider and registry apply changes, used to
+// estimate the impact of the plans in dry-run mode.
+func NewImpactModelFromConfig(cfg *apis.Config) *plan.ImpactModel {
+	model := &plan.ImpactModel{RecordsPerEndpoint: 1}
+	if cfg.Registry == "txt" {
+		model.RecordsPerEndpoint = 2
+	}
+
+	switch cfg.Provider {
+	case "aws":
+		model.BatchSize = cfg.AWSBatchChangeSize
+		model.CallInterval = cfg.AWSBatchChangeInterval
+	case "google":
+		model.BatchSize = cfg.GoogleBatchChangeSize
+		model.CallInterval = cfg.GoogleBatchChangeInterval
+	}
+	return model
+}
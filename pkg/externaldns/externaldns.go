@@ -0,0 +1,159 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package externaldns allows embedding the DNS reconciliation of ExternalDNS in other programs, e.g.
+// operators which publish the records of the resources they manage. The sources, the provider and the
+// registry are pluggable: they can be created from an ExternalDNS configuration with the
+// New...FromConfig functions or be implementations of the source.Source, provider.Provider and
+// registry.Registry interfaces of the embedding program.
+package externaldns
+
+import (
+	"errors"
+	"time"
+
+	"sigs.k8s.io/external-dns/controller"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+	"sigs.k8s.io/external-dns/registry"
+	"sigs.k8s.io/external-dns/source"
+)
+
+// defaultInterval is the interval between synchronizations if none is configured, the same as
+// the default of the --interval flag.
+const defaultInterval = time.Minute
+
+// Options configures a controller created with NewController.
+type Options struct {
+	// The source of the desired endpoints, required
+	Source source.Source
+	// The registry keeping track of the records owned by the controller. If it's nil, the records of
+	// Provider are managed without ownership information by the noop registry.
+	Registry registry.Registry
+	// The provider the noop registry is created for if Registry is nil
+	Provider provider.Provider
+	// The policy that defines which changes to DNS records are allowed, defaults to sync
+	Policy plan.Policy
+	// The record types which are managed, defaults to plan.DefaultManagedRecordTypes
+	ManagedRecordTypes []string
+	// Whether the provider materializes the health checks of the endpoints, see provider.HealthCheckProvider
+	HealthChecks bool
+	// The interval between individual synchronizations of Controller.Run, defaults to one minute
+	Interval time.Duration
+	// The time the old records of a renamed resource are kept after the new ones were created
+	RenameDeletionGracePeriod time.Duration
+	// The time the records of deleted resources are kept, requires a registry storing labels like the txt registry
+	OrphanDeletionGracePeriod time.Duration
+	// The time the changes are accumulated for before they are applied as one batch
+	ChangeDebounce time.Duration
+	// The model used to log the estimated impact of each plan, nil to disable it
+	ImpactModel *plan.ImpactModel
+	// The guard holding back the deletions of zones whose records are suddenly missing, nil to disable it
+	DeletionGuard *controller.DeletionGuard
+	// The file the records and endpoints of every synchronization are written to, empty to disable it
+	StateDumpFile string
+	// The writer the applied records of the changed resources are passed to, nil to disable it
+	AppliedRecordsWriter controller.AppliedRecordsWriter
+	// The publisher the records of the registry are passed to in every synchronization, nil to disable it
+	OwnershipPublisher controller.OwnershipPublisher
+	// The lister of the subzones which are created and delegated by ZoneDelegator, nil to disable it
+	SubzoneLister controller.SubzoneLister
+	// The provider creating the subzones of SubzoneLister and delegating them from their parent zones
+	ZoneDelegator provider.ZoneDelegator
+	// The metrics tracking the synchronization of each zone, nil to disable them
+	ZoneSyncMetrics *controller.ZoneSyncMetrics
+	// The minimum and maximum TTL of the endpoints, zero for no bound
+	MinTTL time.Duration
+	MaxTTL time.Duration
+	// The recorder the warnings about the endpoints of resources are reported to, nil to disable it
+	EventRecorder controller.EventRecorder
+	// The report of the DNS names produced by more than one resource, nil to disable it
+	DuplicateReport *controller.DuplicateReport
+	// The lister of the DNS freezes holding back the changes of their names, nil to disable it
+	FreezeLister controller.FreezeLister
+	// The timer of the changes of the resources whose records are applied, nil to disable the latency metric
+	ResourceChangeTimer controller.ResourceChangeTimer
+	// The filters applied to the endpoints of the source before planning, nil to disable them
+	EndpointFilters *controller.EndpointFilterChain
+	// The provider adjusting the endpoints to what it supports before planning, nil to disable it
+	EndpointAdjuster provider.EndpointAdjuster
+	// The provider validating the provider-specific properties of the endpoints, nil to disable it
+	ProviderSpecificValidator provider.ProviderSpecificValidator
+	// The check of the targets of the CNAME records which are created or updated, nil to disable it
+	CNAMETargetCheck *controller.CNAMETargetCheck
+	// The scanner of the owned CNAME records for subdomain takeover risks, nil to disable it
+	TakeoverScanner *controller.TakeoverScanner
+}
+
+// NewController creates a controller which synchronizes the records of the registry with the
+// endpoints of the source. It's started with Run or synchronizes once with RunOnce.
+func NewController(opts Options) (*controller.Controller, error) {
+	if opts.Source == nil {
+		return nil, errors.New("a source is required")
+	}
+
+	r := opts.Registry
+	if r == nil {
+		if opts.Provider == nil {
+			return nil, errors.New("a registry or provider is required")
+		}
+		var err error
+		r, err = registry.NewNoopRegistry(opts.Provider)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	policy := opts.Policy
+	if policy == nil {
+		policy = &plan.SyncPolicy{}
+	}
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+
+	return &controller.Controller{
+		Source:                    opts.Source,
+		Registry:                  r,
+		Policy:                    policy,
+		ManagedRecordTypes:        opts.ManagedRecordTypes,
+		HealthChecks:              opts.HealthChecks,
+		Interval:                  interval,
+		RenameDeletionGracePeriod: opts.RenameDeletionGracePeriod,
+		OrphanDeletionGracePeriod: opts.OrphanDeletionGracePeriod,
+		ChangeDebounce:            opts.ChangeDebounce,
+		ImpactModel:               opts.ImpactModel,
+		DeletionGuard:             opts.DeletionGuard,
+		StateDumpFile:             opts.StateDumpFile,
+		AppliedRecordsWriter:      opts.AppliedRecordsWriter,
+		OwnershipPublisher:        opts.OwnershipPublisher,
+		SubzoneLister:             opts.SubzoneLister,
+		ZoneDelegator:             opts.ZoneDelegator,
+		ZoneSyncMetrics:           opts.ZoneSyncMetrics,
+		MinTTL:                    opts.MinTTL,
+		MaxTTL:                    opts.MaxTTL,
+		EventRecorder:             opts.EventRecorder,
+		DuplicateReport:           opts.DuplicateReport,
+		FreezeLister:              opts.FreezeLister,
+		ResourceChangeTimer:       opts.ResourceChangeTimer,
+		EndpointFilters:           opts.EndpointFilters,
+		EndpointAdjuster:          opts.EndpointAdjuster,
+		ProviderSpecificValidator: opts.ProviderSpecificValidator,
+		CNAMETargetCheck:          opts.CNAMETargetCheck,
+		TakeoverScanner:           opts.TakeoverScanner,
+	}, nil
+}
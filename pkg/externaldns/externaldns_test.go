@@ -0,0 +1,198 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package externaldns
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	apis "sigs.k8s.io/external-dns/pkg/apis/externaldns"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+	"sigs.k8s.io/external-dns/registry"
+)
+
+type staticSource []*endpoint.Endpoint
+
+func (s staticSource) Endpoints() ([]*endpoint.Endpoint, error) {
+	return s, nil
+}
+
+func (s staticSource) AddEventHandler(handler func() error, stopChan <-chan struct{}, minInterval time.Duration) {
+}
+
+func TestNewController(t *testing.T) {
+	p := provider.NewInMemoryProvider(provider.InMemoryInitZones([]string{"example.org"}))
+
+	_, err := NewController(Options{Provider: p})
+	assert.EqualError(t, err, "a source is required")
+
+	_, err = NewController(Options{Source: staticSource{}})
+	assert.EqualError(t, err, "a registry or provider is required")
+
+	ctrl, err := NewController(Options{Source: staticSource{}, Provider: p})
+	require.NoError(t, err)
+	assert.IsType(t, &registry.NoopRegistry{}, ctrl.Registry)
+	assert.Equal(t, &plan.SyncPolicy{}, ctrl.Policy)
+	assert.Equal(t, time.Minute, ctrl.Interval)
+
+	r, err := registry.NewTXTRegistry(p, "", "owner", 0, "", nil, 0)
+	require.NoError(t, err)
+	ctrl, err = NewController(Options{
+		Source:   staticSource{},
+		Registry: r,
+		Policy:   &plan.UpsertOnlyPolicy{},
+		Interval: 5 * time.Minute,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, r, ctrl.Registry)
+	assert.Equal(t, &plan.UpsertOnlyPolicy{}, ctrl.Policy)
+	assert.Equal(t, 5*time.Minute, ctrl.Interval)
+}
+
+func TestNewControllerRunOnce(t *testing.T) {
+	p := provider.NewInMemoryProvider(provider.InMemoryInitZones([]string{"example.org"}))
+	ctrl, err := NewController(Options{
+		Source:   staticSource{endpoint.NewEndpoint("foo.example.org", endpoint.RecordTypeA, "1.2.3.4")},
+		Provider: p,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+
+	records, err := p.Records(context.Background())
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "foo.example.org", records[0].DNSName)
+	assert.Equal(t, endpoint.Targets{"1.2.3.4"}, records[0].Targets)
+}
+
+func TestNewProviderFromConfig(t *testing.T) {
+	cfg := apis.NewConfig()
+	cfg.Provider = "inmemory"
+	cfg.InMemoryZones = []string{"example.org"}
+	p, err := NewProviderFromConfig(context.Background(), cfg)
+	require.NoError(t, err)
+	assert.IsType(t, &provider.InMemoryProvider{}, p)
+
+	cfg.Provider = "unknown"
+	_, err = NewProviderFromConfig(context.Background(), cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown dns provider: unknown")
+}
+
+func TestProvidersRegistered(t *testing.T) {
+	for _, name := range []string{
+		"akamai", "alibabacloud", "aws", "aws-sd", "axfr", "azure", "azure-dns", "azure-private-dns", "cloudflare",
+		"coredns", "designate", "digitalocean", "dnsimple", "dyn", "exoscale", "google", "infoblox", "inmemory",
+		"linode", "ns1", "oci", "pdns", "rcodezero", "rdns", "rfc2136", "skydns", "transip", "vinyldns", "webhook",
+	} {
+		assert.Contains(t, provider.Registered(), name)
+	}
+}
+
+func TestNewRegistryFromConfig(t *testing.T) {
+	p := provider.NewInMemoryProvider()
+	cfg := apis.NewConfig()
+
+	cfg.Registry = "noop"
+	r, err := NewRegistryFromConfig(cfg, p)
+	require.NoError(t, err)
+	assert.IsType(t, &registry.NoopRegistry{}, r)
+
+	cfg.Registry = "txt"
+	cfg.TXTOwnerID = "owner"
+	r, err = NewRegistryFromConfig(cfg, p)
+	require.NoError(t, err)
+	assert.IsType(t, &registry.TXTRegistry{}, r)
+
+	cfg.Registry = "aws-sd"
+	_, err = NewRegistryFromConfig(cfg, p)
+	assert.EqualError(t, err, "the aws-sd registry requires the aws-sd provider")
+
+	cfg.Registry = "unknown"
+	_, err = NewRegistryFromConfig(cfg, p)
+	assert.EqualError(t, err, "unknown registry: unknown")
+}
+
+func TestNewImpactModelFromConfig(t *testing.T) {
+	cfg := apis.NewConfig()
+	cfg.Provider = "aws"
+	cfg.Registry = "txt"
+	cfg.AWSBatchChangeSize = 1000
+	cfg.AWSBatchChangeInterval = time.Second
+	assert.Equal(t, &plan.ImpactModel{BatchSize: 1000, CallInterval: time.Second, RecordsPerEndpoint: 2}, NewImpactModelFromConfig(cfg))
+
+	cfg.Provider = "cloudflare"
+	cfg.Registry = "noop"
+	assert.Equal(t, &plan.ImpactModel{RecordsPerEndpoint: 1}, NewImpactModelFromConfig(cfg))
+}
+
+func TestManagedRecordTypesFromConfig(t *testing.T) {
+	cfg := apis.NewConfig()
+	assert.Nil(t, ManagedRecordTypesFromConfig(cfg))
+
+	cfg.ManagedRecordTypes = []string{"a, AAAA", "cname", " "}
+	assert.Equal(t, []string{"A", "AAAA", "CNAME"}, ManagedRecordTypesFromConfig(cfg))
+
+	// the records of the CAA policies are managed in addition to the other types
+	cfg.CAAPolicies = []string{"example.org=letsencrypt.org"}
+	assert.Equal(t, []string{"A", "AAAA", "CNAME", "CAA"}, ManagedRecordTypesFromConfig(cfg))
+	cfg.ManagedRecordTypes = []string{"caa"}
+	assert.Equal(t, []string{"CAA"}, ManagedRecordTypesFromConfig(cfg))
+	cfg.ManagedRecordTypes = nil
+	assert.Equal(t, []string{"A", "CNAME", "CAA"}, ManagedRecordTypesFromConfig(cfg))
+}
+
+func TestProviderTimeoutFromConfig(t *testing.T) {
+	cfg := apis.NewConfig()
+	cfg.Provider = "pdns"
+	cfg.Registry = "noop"
+	timeout, err := ProviderTimeoutFromConfig(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(0), timeout)
+
+	cfg.ProviderTimeout = time.Minute
+	cfg.ProviderTimeoutOverrides = []string{"aws=2m"}
+	timeout, err = ProviderTimeoutFromConfig(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, timeout)
+
+	cfg.ProviderTimeoutOverrides = []string{"aws=2m", "pdns = 10s"}
+	timeout, err = ProviderTimeoutFromConfig(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, 10*time.Second, timeout)
+
+	cfg.ProviderTimeoutOverrides = []string{"pdns"}
+	_, err = ProviderTimeoutFromConfig(cfg)
+	assert.EqualError(t, err, "invalid provider timeout override (provider=timeout) found 'pdns'")
+	cfg.ProviderTimeoutOverrides = []string{"aws=soon"}
+	_, err = ProviderTimeoutFromConfig(cfg)
+	assert.Error(t, err)
+
+	cfg.ProviderTimeoutOverrides = []string{"pdns=1m"}
+	_, err = NewRegistryFromConfig(cfg, provider.NewInMemoryProvider())
+	assert.NoError(t, err)
+	cfg.ProviderTimeoutOverrides = []string{"pdns=1"}
+	_, err = NewRegistryFromConfig(cfg, provider.NewInMemoryProvider())
+	assert.Error(t, err)
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package externaldns
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/template"
+
+	"golang.org/x/net/http/httpproxy"
+
+	apis "sigs.k8s.io/external-dns/pkg/apis/externaldns"
+)
+
+// The address of the instance metadata services of the clouds, which is never reached through the proxy
+const instanceMetadataAddress = "169.254.169.254"
+
+// configureProviderClient makes the client send its requests with the transport, the User-Agent and the
+// retry budget of the configuration. Every client gets a retry budget of its own.
+func configureProviderClient(client *http.Client, transport http.RoundTripper, userAgent string, cfg *apis.Config) {
+	client.Transport = transport
+	setProviderUserAgent(client, userAgent)
+	setProviderRetryBudget(client, cfg.ProviderRetryBudget, cfg.ProviderRetryBudgetRatio)
+}
+
+// newProviderTransport returns a copy of the default transport configured for the provider API clients.
+// The default transport itself is left alone, so the other HTTP clients of the process, e.g. of the
+// webhooks, aren't sent through the proxy of the provider.
+func newProviderTransport(proxy, caBundle string) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if err := configureProviderTransport(transport, proxy, caBundle); err != nil {
+		return nil, err
+	}
+	return transport, nil
+}
+
+// configureProviderTransport sends the requests of the transport through the proxy and makes it trust the
+// certificate authorities of the bundle in addition to the system roots, so the provider APIs can be reached
+// through an egress proxy intercepting TLS. The hosts of the NO_PROXY environment variable, localhost and the
+// instance metadata service are still reached directly. The Kubernetes clients have their own transport and
+// aren't affected.
+func configureProviderTransport(transport *http.Transport, proxy, caBundle string) error {
+	if proxy != "" {
+		proxyURL, err := url.Parse(proxy)
+		if err != nil || proxyURL.Scheme == "" || proxyURL.Host == "" {
+			return fmt.Errorf("invalid provider HTTP proxy %q", proxy)
+		}
+		transport.Proxy = providerProxy(proxyURL)
+	}
+
+	if caBundle != "" {
+		pem, err := ioutil.ReadFile(caBundle)
+		if err != nil {
+			return fmt.Errorf("error reading the provider CA bundle: %v", err)
+		}
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+		if !roots.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in the provider CA bundle %s", caBundle)
+		}
+		if transport.TLSClientConfig == nil {
+			transport.TLSClientConfig = &tls.Config{}
+		}
+		transport.TLSClientConfig.RootCAs = roots
+	}
+	return nil
+}
+
+// providerProxy returns the proxy function sending the requests through the proxy unless their host is
+// excluded by NO_PROXY or is the instance metadata service.
+func providerProxy(proxyURL *url.URL) func(*http.Request) (*url.URL, error) {
+	noProxy := []string{instanceMetadataAddress}
+	for _, key := range []string{"NO_PROXY", "no_proxy"} {
+		if value := os.Getenv(key); value != "" {
+			noProxy = append(noProxy, value)
+			break
+		}
+	}
+	proxyFunc := (&httpproxy.Config{
+		HTTPProxy:  proxyURL.String(),
+		HTTPSProxy: proxyURL.String(),
+		NoProxy:    strings.Join(noProxy, ","),
+	}).ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxyFunc(req.URL)
+	}
+}
+
+// providerUserAgent returns the User-Agent of the provider API clients from the template of the configuration.
+func providerUserAgent(cfg *apis.Config) (string, error) {
+	tmpl, err := template.New("user-agent").Parse(cfg.ProviderUserAgent)
+	if err != nil {
+		return "", fmt.Errorf("invalid provider User-Agent template: %v", err)
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, struct {
+		Version     string
+		OwnerID     string
+		ClusterName string
+	}{
+		Version:     apis.Version,
+		OwnerID:     cfg.TXTOwnerID,
+		ClusterName: cfg.ClusterName,
+	})
+	if err != nil {
+		return "", fmt.Errorf("invalid provider User-Agent template: %v", err)
+	}
+	return buf.String(), nil
+}
+
+// userAgentTransport appends its User-Agent to the one of every request, so the SDK of the provider is
+// still identified. It's set on the HTTP client of the provider.
+type userAgentTransport struct {
+	next      http.RoundTripper
+	userAgent string
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	if userAgent := req.Header.Get("User-Agent"); userAgent != "" {
+		req.Header.Set("User-Agent", userAgent+" "+t.userAgent)
+	} else {
+		req.Header.Set("User-Agent", t.userAgent)
+	}
+	return t.next.RoundTrip(req)
+}
+
+// setProviderUserAgent makes the client append the User-Agent to its requests, replacing the one set before.
+func setProviderUserAgent(client *http.Client, userAgent string) {
+	next := client.Transport
+	if transport, ok := next.(*userAgentTransport); ok {
+		next = transport.next
+	}
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if userAgent == "" {
+		client.Transport = next
+		return
+	}
+	client.Transport = &userAgentTransport{next: next, userAgent: userAgent}
+}
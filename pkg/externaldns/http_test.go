@@ -0,0 +1,148 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package externaldns
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apis "sigs.k8s.io/external-dns/pkg/apis/externaldns"
+)
+
+func TestConfigureProviderTransportProxy(t *testing.T) {
+	transport := &http.Transport{}
+	require.NoError(t, configureProviderTransport(transport, "http://proxy.example.org:3128", ""))
+
+	req, err := http.NewRequest(http.MethodGet, "https://route53.amazonaws.com/", nil)
+	require.NoError(t, err)
+	proxyURL, err := transport.Proxy(req)
+	require.NoError(t, err)
+	assert.Equal(t, "http://proxy.example.org:3128", proxyURL.String())
+	assert.Nil(t, transport.TLSClientConfig)
+
+	assert.EqualError(t, configureProviderTransport(&http.Transport{}, "proxy.example.org", ""), `invalid provider HTTP proxy "proxy.example.org"`)
+}
+
+func TestConfigureProviderTransportNoProxy(t *testing.T) {
+	defer os.Setenv("NO_PROXY", os.Getenv("NO_PROXY"))
+	require.NoError(t, os.Setenv("NO_PROXY", ".internal.example.org"))
+
+	transport, err := newProviderTransport("http://proxy.example.org:3128", "")
+	require.NoError(t, err)
+
+	for url, proxied := range map[string]bool{
+		"https://route53.amazonaws.com/":                   true,
+		"https://dns.internal.example.org/":                false,
+		"http://169.254.169.254/latest/meta-data/":         false,
+		"http://localhost:8888/records":                    false,
+		"http://127.0.0.1:8888/adjustendpoints":            false,
+		"https://designate.openstack.internal.example.org": false,
+	} {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		require.NoError(t, err)
+		proxyURL, err := transport.Proxy(req)
+		require.NoError(t, err)
+		assert.Equal(t, proxied, proxyURL != nil, url)
+	}
+}
+
+func TestConfigureProviderTransportCABundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "ca-bundle")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	bundle := filepath.Join(dir, "ca.crt")
+	require.NoError(t, ioutil.WriteFile(bundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600))
+
+	// the certificate of the test server is self-signed, so it's only trusted with the bundle
+	_, err = (&http.Client{Transport: &http.Transport{}}).Get(server.URL)
+	assert.Error(t, err)
+
+	transport := &http.Transport{}
+	require.NoError(t, configureProviderTransport(transport, "", bundle))
+	resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	empty := filepath.Join(dir, "empty.crt")
+	require.NoError(t, ioutil.WriteFile(empty, nil, 0600))
+	assert.EqualError(t, configureProviderTransport(&http.Transport{}, "", empty), "no certificates found in the provider CA bundle "+empty)
+	assert.Error(t, configureProviderTransport(&http.Transport{}, "", filepath.Join(dir, "missing.crt")))
+}
+
+func TestProviderUserAgent(t *testing.T) {
+	cfg := apis.NewConfig()
+	require.NoError(t, cfg.ParseFlags([]string{"--txt-owner-id=owner-1"}))
+	userAgent, err := providerUserAgent(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "ExternalDNS/"+apis.Version+" (owner owner-1)", userAgent)
+
+	cfg.ClusterName = "cluster-a"
+	userAgent, err = providerUserAgent(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "ExternalDNS/"+apis.Version+" (owner owner-1, cluster cluster-a)", userAgent)
+
+	cfg.ProviderUserAgent = "{{.Unknown}}"
+	_, err = providerUserAgent(cfg)
+	assert.Error(t, err)
+	cfg.ProviderUserAgent = "{{"
+	_, err = providerUserAgent(cfg)
+	assert.Error(t, err)
+}
+
+func TestSetProviderUserAgent(t *testing.T) {
+	var userAgents []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgents = append(userAgents, r.Header.Get("User-Agent"))
+	}))
+	defer server.Close()
+
+	client := &http.Client{}
+	setProviderUserAgent(client, "ExternalDNS/test")
+	// setting it again replaces the previous User-Agent instead of appending both
+	setProviderUserAgent(client, "ExternalDNS/v1 (owner owner-1)")
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+	req.Header.Set("User-Agent", "aws-sdk-go/1.0")
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "aws-sdk-go/1.0", req.Header.Get("User-Agent"))
+
+	req.Header.Del("User-Agent")
+	resp, err = client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	setProviderUserAgent(client, "")
+	_, ok := client.Transport.(*userAgentTransport)
+	assert.False(t, ok)
+
+	assert.Equal(t, []string{"aws-sdk-go/1.0 ExternalDNS/v1 (owner owner-1)", "ExternalDNS/v1 (owner owner-1)"}, userAgents)
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package externaldns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	apis "sigs.k8s.io/external-dns/pkg/apis/externaldns"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+	"sigs.k8s.io/external-dns/registry"
+)
+
+// MigrateRegistry copies the ownership of the records the TXT registry of the owner id records to the
+// registry of the configuration, e.g. the dynamodb registry, and deletes the ownership TXT records
+// afterwards if requested. In dry-run mode the records are only printed.
+func MigrateRegistry(ctx context.Context, w io.Writer, cfg *apis.Config) error {
+	if cfg.Registry == "txt" {
+		return errors.New("the records are already owned by the txt registry, migrate to another registry with --registry")
+	}
+	p, err := NewProviderFromConfig(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	from, err := registry.NewTXTRegistry(p, cfg.TXTPrefix, cfg.TXTOwnerID, 0, "", nil, 0)
+	if err != nil {
+		return err
+	}
+	to, err := NewRegistryFromConfig(cfg, p)
+	if err != nil {
+		return err
+	}
+	return migrateRegistry(ctx, w, p, from, to, cfg.TXTOwnerID, cfg.MigrateDeleteTXTRecords, cfg.DryRun)
+}
+
+func migrateRegistry(ctx context.Context, w io.Writer, p provider.Provider, from registry.Registry, to registry.Registry, ownerID string, deleteTXTRecords, dryRun bool) error {
+	adopter, ok := to.(registry.Adopter)
+	if !ok {
+		return errors.New("the registry can't adopt records, use the dynamodb registry")
+	}
+
+	records, err := from.Records(ctx)
+	if err != nil {
+		return err
+	}
+	var owned []*endpoint.Endpoint
+	for _, record := range records {
+		// the TXT registry doesn't return the ownership records, so the TXT records are owned ones, e.g. SPF
+		if record.Labels[endpoint.OwnerLabelKey] != ownerID {
+			continue
+		}
+		fmt.Fprintf(w, "MIGRATE %s\n", record)
+		owned = append(owned, record)
+	}
+
+	// the ownership records are the TXT records whose value has the heritage of ExternalDNS and the owner id
+	var ownership []*endpoint.Endpoint
+	if deleteTXTRecords {
+		all, err := p.Records(ctx)
+		if err != nil {
+			return err
+		}
+		for _, record := range all {
+			if record.RecordType != endpoint.RecordTypeTXT || len(record.Targets) == 0 {
+				continue
+			}
+			labels, err := endpoint.NewLabelsFromString(record.Targets[0])
+			if err != nil || labels[endpoint.OwnerLabelKey] != ownerID {
+				continue
+			}
+			fmt.Fprintf(w, "DELETE %s\n", record)
+			ownership = append(ownership, record)
+		}
+	}
+
+	if dryRun {
+		fmt.Fprintf(w, "%d records would be migrated, %d ownership records would be deleted\n", len(owned), len(ownership))
+		return nil
+	}
+	if len(owned) > 0 {
+		if err := adopter.AdoptRecords(ctx, owned); err != nil {
+			return err
+		}
+	}
+	if len(ownership) > 0 {
+		if err := p.ApplyChanges(ctx, &plan.Changes{Delete: ownership}); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(w, "%d records migrated, %d ownership records deleted\n", len(owned), len(ownership))
+	return nil
+}
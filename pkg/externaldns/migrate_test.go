@@ -0,0 +1,135 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package externaldns
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+	"sigs.k8s.io/external-dns/registry"
+)
+
+// adoptingRegistry records the records it's asked to adopt.
+type adoptingRegistry struct {
+	registry.Registry
+	adopted []*endpoint.Endpoint
+}
+
+func (r *adoptingRegistry) AdoptRecords(ctx context.Context, records []*endpoint.Endpoint) error {
+	r.adopted = append(r.adopted, records...)
+	return nil
+}
+
+func TestMigrateRegistry(t *testing.T) {
+	ctx := context.Background()
+	newProvider := func() provider.Provider {
+		p := provider.NewInMemoryProvider(provider.InMemoryInitZones([]string{"example.org"}))
+		for owner, name := range map[string]string{"owner": "owned.example.org", "other": "other.example.org"} {
+			r, err := registry.NewTXTRegistry(p, "", owner, 0, "", nil, 0)
+			require.NoError(t, err)
+			require.NoError(t, r.ApplyChanges(ctx, &plan.Changes{
+				Create: []*endpoint.Endpoint{endpoint.NewEndpoint(name, endpoint.RecordTypeA, "1.2.3.4")},
+			}))
+		}
+		require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{
+			Create: []*endpoint.Endpoint{endpoint.NewEndpoint("unowned.example.org", endpoint.RecordTypeA, "1.2.3.4")},
+		}))
+		return p
+	}
+	txtRecords := func(p provider.Provider) []string {
+		records, err := p.Records(ctx)
+		require.NoError(t, err)
+		var names []string
+		for _, record := range records {
+			if record.RecordType == endpoint.RecordTypeTXT {
+				names = append(names, record.DNSName)
+			}
+		}
+		return names
+	}
+
+	t.Run("dry run", func(t *testing.T) {
+		p := newProvider()
+		from, err := registry.NewTXTRegistry(p, "", "owner", 0, "", nil, 0)
+		require.NoError(t, err)
+		to := &adoptingRegistry{}
+
+		var out bytes.Buffer
+		require.NoError(t, migrateRegistry(ctx, &out, p, from, to, "owner", true, true))
+		assert.Contains(t, out.String(), "MIGRATE owned.example.org")
+		assert.Contains(t, out.String(), "1 records would be migrated, 1 ownership records would be deleted\n")
+		assert.Empty(t, to.adopted)
+		assert.Len(t, txtRecords(p), 2)
+	})
+
+	t.Run("migrate", func(t *testing.T) {
+		p := newProvider()
+		from, err := registry.NewTXTRegistry(p, "", "owner", 0, "", nil, 0)
+		require.NoError(t, err)
+		to := &adoptingRegistry{}
+
+		var out bytes.Buffer
+		require.NoError(t, migrateRegistry(ctx, &out, p, from, to, "owner", false, false))
+		assert.Contains(t, out.String(), "1 records migrated, 0 ownership records deleted\n")
+		require.Len(t, to.adopted, 1)
+		assert.Equal(t, "owned.example.org", to.adopted[0].DNSName)
+		assert.Equal(t, "owner", to.adopted[0].Labels[endpoint.OwnerLabelKey])
+		assert.Len(t, txtRecords(p), 2)
+	})
+
+	t.Run("migrate and delete the ownership records", func(t *testing.T) {
+		p := newProvider()
+		from, err := registry.NewTXTRegistry(p, "", "owner", 0, "", nil, 0)
+		require.NoError(t, err)
+		to := &adoptingRegistry{}
+
+		require.NoError(t, migrateRegistry(ctx, &bytes.Buffer{}, p, from, to, "owner", true, false))
+		require.Len(t, to.adopted, 1)
+		assert.Equal(t, []string{"other.example.org"}, txtRecords(p))
+	})
+
+	t.Run("owned TXT records are migrated", func(t *testing.T) {
+		p := provider.NewInMemoryProvider(provider.InMemoryInitZones([]string{"example.org"}))
+		from, err := registry.NewTXTRegistry(p, "txt.", "owner", 0, "", nil, 0)
+		require.NoError(t, err)
+		require.NoError(t, from.ApplyChanges(ctx, &plan.Changes{
+			Create: []*endpoint.Endpoint{endpoint.NewEndpoint("example.org", endpoint.RecordTypeTXT, "v=spf1 -all")},
+		}))
+		to := &adoptingRegistry{}
+
+		require.NoError(t, migrateRegistry(ctx, &bytes.Buffer{}, p, from, to, "owner", false, false))
+		require.Len(t, to.adopted, 1)
+		assert.Equal(t, "example.org", to.adopted[0].DNSName)
+		assert.Equal(t, endpoint.RecordTypeTXT, to.adopted[0].RecordType)
+	})
+
+	t.Run("unsupported registry", func(t *testing.T) {
+		p := newProvider()
+		from, err := registry.NewTXTRegistry(p, "", "owner", 0, "", nil, 0)
+		require.NoError(t, err)
+		to, err := registry.NewNoopRegistry(p)
+		require.NoError(t, err)
+		assert.EqualError(t, migrateRegistry(ctx, &bytes.Buffer{}, p, from, to, "owner", false, false), "the registry can't adopt records, use the dynamodb registry")
+	})
+}
@@ -0,0 +1,41 @@
+// +build !no_aws_sd
+
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package externaldns
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+
+	apis "sigs.k8s.io/external-dns/pkg/apis/externaldns"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+func init() {
+	provider.Register("aws-sd", newAWSSDProvider)
+}
+
+func newAWSSDProvider(ctx context.Context, cfg *apis.Config) (provider.Provider, error) {
+	// Check that only compatible Registry is used with AWS-SD
+	if cfg.Registry != "noop" && cfg.Registry != "aws-sd" {
+		log.Infof("Registry \"%s\" cannot be used with AWS Cloud Map. Switching to \"aws-sd\".", cfg.Registry)
+		cfg.Registry = "aws-sd"
+	}
+	return provider.NewAWSSDProvider(domainFilterFromConfig(cfg), cfg.AWSZoneType, cfg.AWSAssumeRole, cfg.DryRun, provider.HTTPClient(ctx))
+}
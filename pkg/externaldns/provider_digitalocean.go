@@ -0,0 +1,34 @@
+// +build !no_digitalocean
+
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package externaldns
+
+import (
+	"context"
+
+	apis "sigs.k8s.io/external-dns/pkg/apis/externaldns"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+func init() {
+	provider.Register("digitalocean", newDigitalOceanProvider)
+}
+
+func newDigitalOceanProvider(ctx context.Context, cfg *apis.Config) (provider.Provider, error) {
+	return provider.NewDigitalOceanProvider(ctx, domainFilterFromConfig(cfg), cfg.DryRun)
+}
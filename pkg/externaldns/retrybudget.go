@@ -0,0 +1,193 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package externaldns
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// retryWindow is how long a failed request is remembered to recognize its retries
+const retryWindow = time.Minute
+
+var retriesThrottled = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "external_dns",
+		Subsystem: "provider",
+		Name:      "retries_throttled_total",
+		Help:      "Number of retries of provider API requests rejected because the retry budget of the host was exhausted.",
+	},
+	[]string{"host"},
+)
+
+func init() {
+	prometheus.MustRegister(retriesThrottled)
+}
+
+// retryBudgetTransport limits the retries of the requests to each provider API host with a token bucket
+// like the retry throttling of gRPC. Every host starts with maxTokens tokens; a failed request, i.e. a
+// connection error, 429 or 5xx response, costs a token and a successful one returns ratio tokens. While a
+// host has half of its tokens or less, requests repeating a request which failed within the last minute
+// are rejected without reaching the host, so the retries of the SDKs and of concurrent zone workers don't
+// add up during an outage. First attempts are always sent, their successes refill the budget. The budgets
+// of the hosts are shared by all transports of the process, so the clients of several providers or
+// pipelines calling the same host draw from the same bucket.
+type retryBudgetTransport struct {
+	next      http.RoundTripper
+	maxTokens float64
+	ratio     float64
+	now       func() time.Time
+	budgets   *retryBudgets
+}
+
+// retryBudgets are the retry budgets of the provider API hosts.
+type retryBudgets struct {
+	sync.Mutex
+	hosts map[string]*hostRetryBudget
+}
+
+// providerRetryBudgets are the budgets of the hosts shared by the transports of the process.
+var providerRetryBudgets = &retryBudgets{hosts: map[string]*hostRetryBudget{}}
+
+type hostRetryBudget struct {
+	tokens float64
+	// the time of the last failure of each request, by requestKey
+	failed map[string]time.Time
+}
+
+func newRetryBudgetTransport(next http.RoundTripper, maxTokens int, ratio float64) *retryBudgetTransport {
+	return &retryBudgetTransport{
+		next:      next,
+		maxTokens: float64(maxTokens),
+		ratio:     ratio,
+		now:       time.Now,
+		budgets:   providerRetryBudgets,
+	}
+}
+
+func (t *retryBudgetTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	key := requestKey(req)
+
+	t.budgets.Lock()
+	budget := t.budgets.budget(host, t.maxTokens)
+	failedAt, retry := budget.failed[key]
+	retry = retry && t.now().Sub(failedAt) < retryWindow
+	if retry && budget.tokens <= t.maxTokens/2 {
+		t.budgets.Unlock()
+		// a RoundTripper must close the body of the request, even when it's not sent
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		retriesThrottled.WithLabelValues(host).Inc()
+		log.Debugf("Not retrying %s %s, the retry budget of %s is exhausted", req.Method, req.URL, host)
+		return nil, fmt.Errorf("retry budget of %s exhausted, not retrying %s %s", host, req.Method, req.URL)
+	}
+	t.budgets.Unlock()
+
+	resp, err := t.next.RoundTrip(req)
+
+	t.budgets.Lock()
+	defer t.budgets.Unlock()
+	if err != nil || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError {
+		budget.tokens--
+		if budget.tokens < 0 {
+			budget.tokens = 0
+		}
+		budget.failed[key] = t.now()
+		budget.forgetExpired(t.now())
+	} else {
+		budget.tokens += t.ratio
+		if budget.tokens > t.maxTokens {
+			budget.tokens = t.maxTokens
+		}
+		delete(budget.failed, key)
+	}
+	return resp, err
+}
+
+// requestKey identifies a request by its method, URL and body, so e.g. different change batches posted to
+// the same zone aren't mistaken for retries of each other. The body is read from a copy, requests whose
+// body can't be copied are identified by method and URL only.
+func requestKey(req *http.Request) string {
+	key := req.Method + " " + req.URL.String()
+	if req.Body == nil || req.Body == http.NoBody || req.GetBody == nil {
+		return key
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return key
+	}
+	defer body.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, body); err != nil {
+		return key
+	}
+	return key + " " + hex.EncodeToString(hash.Sum(nil))
+}
+
+// budget returns the retry budget of a host, starting with maxTokens tokens. It must be called with the
+// lock held.
+func (b *retryBudgets) budget(host string, maxTokens float64) *hostRetryBudget {
+	budget, ok := b.hosts[host]
+	if !ok {
+		budget = &hostRetryBudget{tokens: maxTokens, failed: map[string]time.Time{}}
+		b.hosts[host] = budget
+	}
+	return budget
+}
+
+func (b *hostRetryBudget) forgetExpired(now time.Time) {
+	for key, failedAt := range b.failed {
+		if now.Sub(failedAt) >= retryWindow {
+			delete(b.failed, key)
+		}
+	}
+}
+
+// setProviderRetryBudget limits the retries of the requests of the client, replacing the budget set before,
+// or removes the limit if maxTokens is 0. The budget applies below the User-Agent of the client.
+func setProviderRetryBudget(client *http.Client, maxTokens int, ratio float64) {
+	next := client.Transport
+	userAgent, hasUserAgent := next.(*userAgentTransport)
+	if hasUserAgent {
+		next = userAgent.next
+	}
+	if transport, ok := next.(*retryBudgetTransport); ok {
+		next = transport.next
+	}
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if maxTokens > 0 {
+		next = newRetryBudgetTransport(next, maxTokens, ratio)
+	}
+
+	if hasUserAgent {
+		client.Transport = &userAgentTransport{next: next, userAgent: userAgent.userAgent}
+		return
+	}
+	client.Transport = next
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package externaldns
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryBudgetTransport(t *testing.T) {
+	status := http.StatusServiceUnavailable
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	transport := newRetryBudgetTransport(http.DefaultTransport, 4, 0.5)
+	transport.now = func() time.Time { return now }
+	client := &http.Client{Transport: transport}
+
+	get := func(path string) error {
+		resp, err := client.Get(server.URL + path)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	// the retries are sent while more than half of the tokens are left
+	require.NoError(t, get("/zones/a"))
+	require.NoError(t, get("/zones/a"))
+	assert.Equal(t, 2, requests)
+
+	// retries of all requests to the host are rejected once the budget is exhausted
+	assert.Error(t, get("/zones/a"))
+	assert.Equal(t, 2, requests)
+	require.NoError(t, get("/zones/b"))
+	assert.Error(t, get("/zones/b"))
+	assert.Equal(t, 3, requests)
+
+	// first attempts are still sent and their successes refill the budget
+	status = http.StatusOK
+	require.NoError(t, get("/zones/c"))
+	require.NoError(t, get("/zones/d"))
+	assert.Equal(t, 5, requests)
+	assert.Error(t, get("/zones/a"))
+	require.NoError(t, get("/zones/e"))
+	require.NoError(t, get("/zones/a"))
+	assert.Equal(t, 7, requests)
+
+	// failures are forgotten after a minute
+	status = http.StatusTooManyRequests
+	for i := 0; i < 4; i++ {
+		require.NoError(t, get("/zones/f"))
+		now = now.Add(retryWindow)
+	}
+	assert.Equal(t, 11, requests)
+}
+
+// closeRecordingBody records whether the transport closed the body of a request.
+type closeRecordingBody struct {
+	io.Reader
+	closed bool
+}
+
+func (b *closeRecordingBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestRetryBudgetTransportBodies(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = ioutil.ReadAll(r.Body)
+		requests++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	transport := newRetryBudgetTransport(http.DefaultTransport, 2, 0.5)
+	post := func(body string) (*closeRecordingBody, error) {
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/zones/a/changes", strings.NewReader(body))
+		require.NoError(t, err)
+		recording := &closeRecordingBody{Reader: strings.NewReader(body)}
+		req.Body = recording
+		resp, err := transport.RoundTrip(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return recording, err
+	}
+
+	_, err := post("batch-1")
+	require.NoError(t, err)
+	// another change batch to the same zone isn't a retry
+	_, err = post("batch-2")
+	require.NoError(t, err)
+	assert.Equal(t, 2, requests)
+
+	// the body of a throttled retry is closed
+	body, err := post("batch-1")
+	assert.Error(t, err)
+	assert.True(t, body.closed)
+	assert.Equal(t, 2, requests)
+}
+
+func TestRetryBudgetTransportShared(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	// the retry of the client of another pipeline is rejected by the budget the first client exhausted
+	first := &http.Client{Transport: newRetryBudgetTransport(http.DefaultTransport, 2, 0.5)}
+	second := &http.Client{Transport: newRetryBudgetTransport(http.DefaultTransport, 2, 0.5)}
+	resp, err := first.Get(server.URL + "/zones/a")
+	require.NoError(t, err)
+	resp.Body.Close()
+	_, err = second.Get(server.URL + "/zones/a")
+	assert.Error(t, err)
+	assert.Equal(t, 1, requests)
+}
+
+func TestSetProviderRetryBudget(t *testing.T) {
+	client := &http.Client{}
+	setProviderUserAgent(client, "ExternalDNS/test")
+
+	setProviderRetryBudget(client, 10, 0.1)
+	userAgent, ok := client.Transport.(*userAgentTransport)
+	require.True(t, ok)
+	assert.Equal(t, "ExternalDNS/test", userAgent.userAgent)
+	budget, ok := userAgent.next.(*retryBudgetTransport)
+	require.True(t, ok)
+	assert.Equal(t, float64(10), budget.maxTokens)
+	assert.Equal(t, http.DefaultTransport, budget.next)
+
+	// setting it again replaces the previous budget instead of stacking both
+	setProviderRetryBudget(client, 20, 0.1)
+	budget = client.Transport.(*userAgentTransport).next.(*retryBudgetTransport)
+	assert.Equal(t, float64(20), budget.maxTokens)
+	assert.Equal(t, http.DefaultTransport, budget.next)
+
+	setProviderRetryBudget(client, 0, 0.1)
+	assert.Equal(t, http.DefaultTransport, client.Transport.(*userAgentTransport).next)
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package externaldns
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"sigs.k8s.io/external-dns/controller"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// Simulate replays a synchronization recorded with --state-dump-file: it calculates the changes of the
+// recorded records and endpoints with the named policy and prints them, without any access to the
+// cluster or the provider. Only the records of the managed types are planned, see plan.Plan.
+func Simulate(w io.Writer, path, policyName string, managedRecordTypes []string) error {
+	policy, exists := plan.Policies[policyName]
+	if !exists {
+		return fmt.Errorf("unknown policy: %s", policyName)
+	}
+	state, err := controller.ReadStateFile(path)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Simulating %d records and %d endpoints with the %s policy\n", len(state.Records), len(state.Endpoints), policyName)
+	for _, zone := range state.FailedZones {
+		fmt.Fprintf(w, "Skipping the changes of zone %s, its records couldn't be listed\n", zone)
+	}
+
+	changes, err := controller.Simulate(state, policy, managedRecordTypes)
+	if err != nil {
+		return err
+	}
+	printEndpoints(w, "CREATE", changes.Create)
+	// the old and new versions of the updates are paired by plan.Changes.Filter
+	for i, ep := range changes.UpdateNew {
+		fmt.Fprintf(w, "UPDATE %s -> %s\n", changes.UpdateOld[i], ep)
+	}
+	printEndpoints(w, "DELETE", changes.Delete)
+	fmt.Fprintf(w, "%d creates, %d updates, %d deletes\n", len(changes.Create), len(changes.UpdateNew), len(changes.Delete))
+	return nil
+}
+
+func printEndpoints(w io.Writer, action string, endpoints []*endpoint.Endpoint) {
+	sorted := append([]*endpoint.Endpoint{}, endpoints...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].DNSName < sorted[j].DNSName
+	})
+	for _, ep := range sorted {
+		fmt.Fprintf(w, "%s %s\n", action, ep)
+	}
+}
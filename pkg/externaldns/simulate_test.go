@@ -0,0 +1,65 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package externaldns
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/controller"
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestSimulate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "simulate")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.json")
+
+	require.NoError(t, controller.WriteStateFile(path, &controller.State{
+		Records: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("old.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+			endpoint.NewEndpoint("update.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+		},
+		Endpoints: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("update.example.org", endpoint.RecordTypeA, "4.3.2.1"),
+			endpoint.NewEndpoint("b.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+			endpoint.NewEndpoint("a.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+		},
+		FailedZones: []string{"broken.org"},
+	}))
+
+	var out bytes.Buffer
+	require.NoError(t, Simulate(&out, path, "sync", nil))
+	assert.Equal(t, `Simulating 2 records and 3 endpoints with the sync policy
+Skipping the changes of zone broken.org, its records couldn't be listed
+CREATE a.example.org 0 IN A  1.2.3.4 []
+CREATE b.example.org 0 IN A  1.2.3.4 []
+UPDATE update.example.org 0 IN A  1.2.3.4 [] -> update.example.org 0 IN A  4.3.2.1 []
+DELETE old.example.org 0 IN A  1.2.3.4 []
+2 creates, 1 updates, 1 deletes
+`, out.String())
+
+	assert.EqualError(t, Simulate(&out, path, "unknown", nil), "unknown policy: unknown")
+	assert.Error(t, Simulate(&out, filepath.Join(dir, "missing.json"), "sync", nil))
+}
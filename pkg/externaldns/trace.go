@@ -0,0 +1,262 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package externaldns
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"sigs.k8s.io/external-dns/controller"
+	"sigs.k8s.io/external-dns/endpoint"
+	apis "sigs.k8s.io/external-dns/pkg/apis/externaldns"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+// hostnameTracer explains the decisions a synchronization takes for the records of a hostname.
+type hostnameTracer struct {
+	// The controller planning the synchronization, nothing is applied
+	controller         *controller.Controller
+	domainFilter       []string
+	excludeDomains     []string
+	ownerID            string
+	policy             string
+	managedRecordTypes []string
+}
+
+// Trace prints every decision a synchronization takes for the records of the hostname: the endpoints
+// of the sources, the endpoint filters, the domain filter, the managed record types, the zone, the
+// ownership of the existing records and the planned changes. The changes are planned by the controller
+// like every synchronization, nothing is changed.
+func Trace(ctx context.Context, w io.Writer, cfg *apis.Config) error {
+	// the parts of the controller which change anything, even in dry-run mode, are left out
+	traceCfg := *cfg
+	traceCfg.DryRun = true
+	traceCfg.StateDumpFile = ""
+	traceCfg.DelegateNamespaceSubzones = false
+	traceCfg.TakeoverScanWebhook = ""
+	traceCfg.TrackChangeLatency = false
+	ctrl, err := NewControllerFromConfig(ctx, &traceCfg)
+	if err != nil {
+		return err
+	}
+	tracer := &hostnameTracer{
+		controller:         ctrl,
+		domainFilter:       cfg.DomainFilter,
+		excludeDomains:     cfg.ExcludeDomains,
+		ownerID:            cfg.TXTOwnerID,
+		policy:             cfg.Policy,
+		managedRecordTypes: ManagedRecordTypesFromConfig(cfg),
+	}
+	return tracer.trace(ctx, w, cfg.TraceHostname)
+}
+
+func (t *hostnameTracer) trace(ctx context.Context, w io.Writer, hostname string) error {
+	hostname = strings.ToLower(strings.TrimSuffix(hostname, "."))
+	matches := func(ep *endpoint.Endpoint) bool {
+		return strings.ToLower(strings.TrimSuffix(ep.DNSName, ".")) == hostname
+	}
+
+	syncPlan, err := t.controller.PlanChanges(ctx)
+	if err != nil {
+		return err
+	}
+
+	desired := filterEndpoints(syncPlan.SourceEndpoints, matches)
+	if len(desired) == 0 {
+		fmt.Fprintf(w, "source: no endpoints of %s, check the hostname annotations, the annotation filter and the FQDN template\n", hostname)
+	}
+	for _, ep := range desired {
+		fmt.Fprintf(w, "source: %s from %s\n", ep, resourceOf(ep))
+	}
+
+	if len(desired) > 0 {
+		for _, trace := range syncPlan.FilterTraces {
+			dropped := filterEndpoints(trace.Dropped, matches)
+			if len(dropped) == 0 {
+				fmt.Fprintf(w, "endpoint filter %s: kept\n", trace.Filter)
+				continue
+			}
+			for _, ep := range dropped {
+				fmt.Fprintf(w, "endpoint filter %s: dropped %s\n", trace.Filter, ep)
+			}
+			desired = removeEndpoints(desired, dropped)
+		}
+	}
+
+	domainFilter := provider.NewDomainFilter(t.domainFilter)
+	excluded := provider.NewDomainFilterWithExclusions(nil, t.excludeDomains)
+	switch {
+	case !domainFilter.Match(hostname):
+		fmt.Fprintf(w, "domain filter: %s doesn't match the domain filter %v, the provider ignores it\n", hostname, t.domainFilter)
+	case !excluded.Match(hostname):
+		fmt.Fprintf(w, "domain filter: %s is excluded by %v, the provider ignores it\n", hostname, t.excludeDomains)
+	default:
+		fmt.Fprintf(w, "domain filter: %s matches\n", hostname)
+	}
+
+	if len(t.managedRecordTypes) > 0 {
+		for _, ep := range desired {
+			if !contains(t.managedRecordTypes, ep.RecordType) {
+				fmt.Fprintf(w, "record type: %s records aren't managed, see --managed-record-types %v\n", ep.RecordType, t.managedRecordTypes)
+			}
+		}
+	}
+
+	zoneErrors := syncPlan.ZoneErrors
+	if zoneErrors.Contains(hostname) {
+		fmt.Fprintf(w, "zone: the records of the zone of %s couldn't be listed, its changes are skipped: %v\n", hostname, zoneErrors)
+	} else if parent, count := parentDomainRecords(syncPlan.Records, hostname); count == 0 {
+		fmt.Fprintf(w, "zone: no records of %s are visible, check that the provider has a zone for %s\n", parent, hostname)
+	} else {
+		fmt.Fprintf(w, "zone: %d records of %s are visible\n", count, parent)
+	}
+
+	current := filterEndpoints(syncPlan.Records, matches)
+	if len(current) == 0 {
+		fmt.Fprintf(w, "registry: no records of %s exist\n", hostname)
+	}
+	for _, record := range current {
+		switch owner := record.Labels[endpoint.OwnerLabelKey]; owner {
+		case "":
+			fmt.Fprintf(w, "registry: %s isn't owned by any instance, it's left alone\n", record)
+		case t.ownerID:
+			fmt.Fprintf(w, "registry: %s is owned by this instance (%s)\n", record, owner)
+		default:
+			fmt.Fprintf(w, "registry: %s is owned by %s, not by this instance (%s), it's left alone\n", record, owner, t.ownerID)
+		}
+	}
+
+	// the planned changes of the hostname are compared with the changes which are applied now
+	owned := func(ep *endpoint.Endpoint) bool {
+		return ep.Labels[endpoint.OwnerLabelKey] == t.ownerID
+	}
+	applied := changeKeys(syncPlan.Changes.Create, syncPlan.Changes.UpdateNew, syncPlan.Changes.Delete)
+	renamed := changeKeys(syncPlan.Renamed)
+	heldBack := func(action string, ep *endpoint.Endpoint) bool {
+		switch {
+		case syncPlan.Debounced:
+			fmt.Fprintf(w, "plan: %s %s is held back until --change-debounce has passed\n", action, ep)
+		case renamed[changeKey(ep)]:
+			fmt.Fprintf(w, "plan: %s %s follows once the new records of its resource exist\n", action, ep)
+		case !applied[changeKey(ep)]:
+			fmt.Fprintf(w, "plan: %s %s is held back, e.g. by a freeze, a grace period or a check of its targets, see the logs\n", action, ep)
+		default:
+			return false
+		}
+		return true
+	}
+	planned, err := syncPlan.Planned.Filter(true, func(_, ep *endpoint.Endpoint) bool {
+		return matches(ep)
+	})
+	if err != nil {
+		return err
+	}
+	for _, ep := range planned.Create {
+		if !heldBack("CREATE", ep) {
+			fmt.Fprintf(w, "plan: CREATE %s\n", ep)
+		}
+	}
+	for i, ep := range planned.UpdateNew {
+		if !owned(planned.UpdateOld[i]) {
+			fmt.Fprintf(w, "plan: no UPDATE of %s, it isn't owned by this instance\n", planned.UpdateOld[i])
+		} else if !heldBack("UPDATE", ep) {
+			fmt.Fprintf(w, "plan: UPDATE %s -> %s\n", planned.UpdateOld[i], ep)
+		}
+	}
+	for _, ep := range planned.Delete {
+		if !owned(ep) {
+			fmt.Fprintf(w, "plan: no DELETE of %s, it isn't owned by this instance\n", ep)
+		} else if !heldBack("DELETE", ep) {
+			fmt.Fprintf(w, "plan: DELETE %s\n", ep)
+		}
+	}
+	if len(planned.Create)+len(planned.UpdateNew)+len(planned.Delete) == 0 {
+		fmt.Fprintf(w, "plan: no changes with the %s policy\n", t.policy)
+	}
+	return nil
+}
+
+// changeKey identifies the record of a change by its name, type and set identifier.
+func changeKey(ep *endpoint.Endpoint) string {
+	return fmt.Sprintf("%s::%s::%s", strings.ToLower(strings.TrimSuffix(ep.DNSName, ".")), ep.RecordType, ep.SetIdentifier)
+}
+
+func changeKeys(endpoints ...[]*endpoint.Endpoint) map[string]bool {
+	keys := map[string]bool{}
+	for _, eps := range endpoints {
+		for _, ep := range eps {
+			keys[changeKey(ep)] = true
+		}
+	}
+	return keys
+}
+
+func filterEndpoints(endpoints []*endpoint.Endpoint, keep func(*endpoint.Endpoint) bool) []*endpoint.Endpoint {
+	var filtered []*endpoint.Endpoint
+	for _, ep := range endpoints {
+		if keep(ep) {
+			filtered = append(filtered, ep)
+		}
+	}
+	return filtered
+}
+
+func removeEndpoints(endpoints, removed []*endpoint.Endpoint) []*endpoint.Endpoint {
+	return filterEndpoints(endpoints, func(ep *endpoint.Endpoint) bool {
+		for _, r := range removed {
+			if r == ep {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// parentDomainRecords returns the parent domain of the hostname and the number of its records, which
+// are only visible if the provider has a zone for the hostname.
+func parentDomainRecords(records []*endpoint.Endpoint, hostname string) (string, int) {
+	parent := hostname
+	if i := strings.Index(hostname, "."); i >= 0 {
+		parent = hostname[i+1:]
+	}
+	count := 0
+	for _, record := range records {
+		name := strings.ToLower(strings.TrimSuffix(record.DNSName, "."))
+		if name == parent || strings.HasSuffix(name, "."+parent) {
+			count++
+		}
+	}
+	return parent, count
+}
+
+func resourceOf(ep *endpoint.Endpoint) string {
+	if resource := ep.Labels[endpoint.ResourceLabelKey]; resource != "" {
+		return resource
+	}
+	return "an unknown resource"
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
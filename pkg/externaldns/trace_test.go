@@ -0,0 +1,134 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package externaldns
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/controller"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/internal/testutils"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+	"sigs.k8s.io/external-dns/registry"
+)
+
+func TestTraceHostname(t *testing.T) {
+	ctx := context.Background()
+	p := provider.NewInMemoryProvider(provider.InMemoryInitZones([]string{"example.org"}))
+	other, err := registry.NewTXTRegistry(p, "", "other", 0, "", nil, 0)
+	require.NoError(t, err)
+	require.NoError(t, other.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{endpoint.NewEndpoint("taken.example.org", endpoint.RecordTypeA, "1.1.1.1")},
+	}))
+	r, err := registry.NewTXTRegistry(p, "", "owner", 0, "", nil, 0)
+	require.NoError(t, err)
+
+	newEndpoint := func(dnsName, target string) *endpoint.Endpoint {
+		ep := endpoint.NewEndpoint(dnsName, endpoint.RecordTypeA, target)
+		ep.Labels[endpoint.ResourceLabelKey] = "ingress/default/shop"
+		return ep
+	}
+	src := new(testutils.MockSource)
+	src.On("Endpoints").Return([]*endpoint.Endpoint{
+		newEndpoint("shop.example.org", "1.2.3.4"),
+		newEndpoint("taken.example.org", "2.2.2.2"),
+		newEndpoint("test-api.example.org", "1.2.3.4"),
+		newEndpoint("shop.example.com", "1.2.3.4"),
+	}, nil)
+	filters, err := controller.NewEndpointFilterChain([]string{"exclude-regex=^test-"}, nil)
+	require.NoError(t, err)
+
+	managedRecordTypes := []string{endpoint.RecordTypeA, endpoint.RecordTypeCNAME}
+	tracer := &hostnameTracer{
+		controller: &controller.Controller{
+			Source:             src,
+			Registry:           r,
+			Policy:             &plan.SyncPolicy{},
+			ManagedRecordTypes: managedRecordTypes,
+			EndpointFilters:    filters,
+		},
+		domainFilter:       []string{"example.org"},
+		ownerID:            "owner",
+		policy:             "sync",
+		managedRecordTypes: managedRecordTypes,
+	}
+
+	for _, tc := range []struct {
+		hostname string
+		expected []string
+	}{
+		{
+			hostname: "shop.example.org",
+			expected: []string{
+				"source: shop.example.org 0 IN A  1.2.3.4 [] from ingress/default/shop\n",
+				"endpoint filter exclude-regex=^test-: kept\n",
+				"domain filter: shop.example.org matches\n",
+				"zone: 1 records of example.org are visible\n",
+				"registry: no records of shop.example.org exist\n",
+				"plan: CREATE shop.example.org 0 IN A  1.2.3.4 []\n",
+			},
+		},
+		{
+			hostname: "taken.example.org.",
+			expected: []string{
+				"registry: taken.example.org 0 IN A  1.1.1.1 [] is owned by other, not by this instance (owner), it's left alone\n",
+				"plan: no UPDATE of taken.example.org 0 IN A  1.1.1.1 [], it isn't owned by this instance\n",
+			},
+		},
+		{
+			hostname: "test-api.example.org",
+			expected: []string{
+				"endpoint filter exclude-regex=^test-: dropped test-api.example.org 0 IN A  1.2.3.4 []\n",
+				"plan: no changes with the sync policy\n",
+			},
+		},
+		{
+			hostname: "shop.example.com",
+			expected: []string{
+				"domain filter: shop.example.com doesn't match the domain filter [example.org], the provider ignores it\n",
+				"zone: no records of example.com are visible, check that the provider has a zone for shop.example.com\n",
+			},
+		},
+		{
+			hostname: "missing.example.org",
+			expected: []string{
+				"source: no endpoints of missing.example.org, check the hostname annotations, the annotation filter and the FQDN template\n",
+			},
+		},
+	} {
+		t.Run(tc.hostname, func(t *testing.T) {
+			var out bytes.Buffer
+			require.NoError(t, tracer.trace(ctx, &out, tc.hostname))
+			for _, line := range tc.expected {
+				assert.Contains(t, out.String(), line)
+			}
+		})
+	}
+
+	// the changes the controller holds back are explained
+	tracer.controller.ChangeDebounce = time.Hour
+	var out bytes.Buffer
+	require.NoError(t, tracer.trace(ctx, &out, "shop.example.org"))
+	assert.Contains(t, out.String(), "plan: CREATE shop.example.org 0 IN A  1.2.3.4 [] is held back until --change-debounce has passed\n")
+}
@@ -0,0 +1,309 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package externaldns
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	apis "sigs.k8s.io/external-dns/pkg/apis/externaldns"
+	"sigs.k8s.io/external-dns/pkg/apis/externaldns/validation"
+	"sigs.k8s.io/external-dns/provider"
+	"sigs.k8s.io/external-dns/source"
+)
+
+// CheckStatus is the outcome of a check of the readiness report.
+type CheckStatus string
+
+const (
+	// CheckPassed means the check succeeded
+	CheckPassed CheckStatus = "OK"
+	// CheckWarning means the check found something which may be intended but should be looked at
+	CheckWarning CheckStatus = "WARNING"
+	// CheckFailed means the controller won't work with the configuration
+	CheckFailed CheckStatus = "FAILED"
+	// CheckSkipped means the check couldn't be run
+	CheckSkipped CheckStatus = "SKIPPED"
+)
+
+// Check is a single check of the readiness report.
+type Check struct {
+	Name   string
+	Status CheckStatus
+	Detail string
+}
+
+// ReadinessReport is the result of Validate.
+type ReadinessReport struct {
+	Checks []Check
+}
+
+func (r *ReadinessReport) add(name string, status CheckStatus, format string, args ...interface{}) {
+	r.Checks = append(r.Checks, Check{Name: name, Status: status, Detail: fmt.Sprintf(format, args...)})
+}
+
+// Ready returns true if none of the checks failed.
+func (r *ReadinessReport) Ready() bool {
+	for _, check := range r.Checks {
+		if check.Status == CheckFailed {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the report with one line per check.
+func (r *ReadinessReport) String() string {
+	var b strings.Builder
+	for _, check := range r.Checks {
+		fmt.Fprintf(&b, "%-9s %s: %s\n", "["+string(check.Status)+"]", check.Name, check.Detail)
+	}
+	if r.Ready() {
+		b.WriteString("Ready to deploy\n")
+	} else {
+		b.WriteString("Not ready to deploy\n")
+	}
+	return b.String()
+}
+
+// sourceResources are the resources the sources list and watch, the permissions of the sources not
+// listed here aren't checked.
+var sourceResources = map[string][]authorizationv1.ResourceAttributes{
+	"service":                           {{Resource: "services"}, {Resource: "pods"}, {Resource: "nodes"}},
+	"ingress":                           {{Group: "extensions", Resource: "ingresses"}},
+	"node":                              {{Resource: "nodes"}},
+	"statefulset":                       {{Group: "apps", Resource: "statefulsets"}, {Resource: "pods"}, {Resource: "nodes"}, {Resource: "services"}},
+	"istio-gateway":                     {{Group: "networking.istio.io", Resource: "gateways"}, {Resource: "services"}},
+	"istio-virtualservice":              {{Group: "networking.istio.io", Resource: "virtualservices"}, {Group: "networking.istio.io", Resource: "gateways", Verb: "get"}, {Resource: "services"}},
+	"contour-ingressroute":              {{Group: "contour.heptio.com", Resource: "ingressroutes"}, {Resource: "services"}},
+	"multicluster-service":              {{Group: "multicluster.x-k8s.io", Resource: "serviceexports"}, {Group: "multicluster.x-k8s.io", Resource: "serviceimports"}, {Resource: "services"}},
+	"aws-target-group-binding":          {{Group: "elbv2.k8s.aws", Resource: "targetgroupbindings"}, {Resource: "services"}},
+	"gke-ingress":                       {{Group: "extensions", Resource: "ingresses"}},
+	"emissary-host":                     {{Group: "getambassador.io", Resource: "hosts"}, {Resource: "services", Verb: "get"}},
+	"argo-rollout":                      {{Group: "argoproj.io", Resource: "rollouts"}, {Resource: "services"}},
+	"cert-manager-challenge-delegation": {{Group: "cert-manager.io", Resource: "certificates"}},
+	"domain-verification":               {{Group: "externaldns.k8s.io", Resource: "domainverifications"}},
+	"api-server":                        {{Resource: "endpoints", Verb: "get"}, {Resource: "services", Verb: "get"}},
+}
+
+// Validate checks the configuration, the filters, the RBAC permissions of the sources and the
+// credentials and zones of the provider without changing any records, so problems are found before
+// the controller is deployed.
+func Validate(ctx context.Context, cfg *apis.Config) *ReadinessReport {
+	report := &ReadinessReport{}
+
+	if err := validation.ValidateConfig(cfg); err != nil {
+		report.add("config", CheckFailed, "%v", err)
+	} else {
+		report.add("config", CheckPassed, "valid")
+	}
+	validateFilters(report, cfg)
+
+	if needsKubernetes(cfg.Sources) || cfg.CutoverConfigMap != "" {
+		client, err := source.NewKubeClient(cfg.KubeConfig, cfg.Master, cfg.RequestTimeout)
+		if err != nil {
+			report.add("kubernetes", CheckFailed, "unable to create a client: %v", err)
+		} else {
+			validateSourcePermissions(report, client, cfg)
+		}
+	}
+
+	p, err := NewProviderFromConfig(ctx, cfg)
+	if err != nil {
+		report.add("provider credentials", CheckFailed, "%v", err)
+	} else {
+		validateProvider(ctx, report, cfg, p)
+	}
+
+	return report
+}
+
+// validateFilters checks the syntax of the filters and templates applied to the endpoints.
+func validateFilters(report *ReadinessReport, cfg *apis.Config) {
+	if _, err := metav1.ParseToLabelSelector(cfg.AnnotationFilter); err != nil {
+		report.add("annotation filter", CheckFailed, "%v", err)
+	} else {
+		report.add("annotation filter", CheckPassed, "%q", cfg.AnnotationFilter)
+	}
+
+	invalid := []string{}
+	for _, domain := range append(append([]string{}, cfg.DomainFilter...), cfg.ExcludeDomains...) {
+		// the default of the flags is a single empty domain, which is skipped like by provider.NewDomainFilter
+		if strings.TrimSpace(domain) == "" {
+			continue
+		}
+		if strings.ContainsAny(domain, " */,:") {
+			invalid = append(invalid, fmt.Sprintf("%q", domain))
+		}
+	}
+	if len(invalid) > 0 {
+		report.add("domain filter", CheckFailed, "invalid domains %s", strings.Join(invalid, ", "))
+	} else {
+		report.add("domain filter", CheckPassed, "%v, excluding %v", cfg.DomainFilter, cfg.ExcludeDomains)
+	}
+
+	serviceTypes := map[string]bool{
+		string(v1.ServiceTypeClusterIP):    true,
+		string(v1.ServiceTypeNodePort):     true,
+		string(v1.ServiceTypeLoadBalancer): true,
+		string(v1.ServiceTypeExternalName): true,
+	}
+	for _, serviceType := range cfg.ServiceTypeFilter {
+		if !serviceTypes[serviceType] {
+			report.add("service type filter", CheckFailed, "unknown service type %q", serviceType)
+		}
+	}
+
+	if cfg.FQDNTemplate != "" {
+		if _, err := template.New("endpoint").Funcs(template.FuncMap{"trimPrefix": strings.TrimPrefix}).Parse(cfg.FQDNTemplate); err != nil {
+			report.add("fqdn template", CheckFailed, "%v", err)
+		} else {
+			report.add("fqdn template", CheckPassed, "%q", cfg.FQDNTemplate)
+		}
+	}
+}
+
+func needsKubernetes(sources []string) bool {
+	for _, name := range sources {
+		switch name {
+		case "fake", "connector", "cloudfoundry", "empty":
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// validateSourcePermissions checks with SelfSubjectAccessReviews that the service account of the
+// controller may read the resources of the sources.
+func validateSourcePermissions(report *ReadinessReport, client kubernetes.Interface, cfg *apis.Config) {
+	for _, name := range cfg.Sources {
+		resources, ok := sourceResources[name]
+		if !ok {
+			report.add("source "+name, CheckSkipped, "the permissions of the source aren't checked")
+			continue
+		}
+
+		var denied []string
+		for _, resource := range resources {
+			verbs := []string{"list", "watch"}
+			if resource.Verb != "" {
+				verbs = []string{resource.Verb}
+			}
+			for _, verb := range verbs {
+				attributes := resource
+				attributes.Verb = verb
+				if attributes.Resource != "nodes" {
+					attributes.Namespace = cfg.Namespace
+				}
+				allowed, err := accessAllowed(client, attributes)
+				if err != nil {
+					report.add("source "+name, CheckFailed, "unable to check the permissions: %v", err)
+					return
+				}
+				if !allowed {
+					denied = append(denied, describeAccess(attributes))
+				}
+			}
+		}
+		if len(denied) > 0 {
+			report.add("source "+name, CheckFailed, "missing permissions to %s", strings.Join(denied, ", "))
+		} else {
+			report.add("source "+name, CheckPassed, "all permissions granted")
+		}
+	}
+
+	if cfg.CutoverConfigMap != "" {
+		namespace := strings.SplitN(cfg.CutoverConfigMap, "/", 2)[0]
+		attributes := authorizationv1.ResourceAttributes{Namespace: namespace, Verb: "get", Resource: "configmaps"}
+		allowed, err := accessAllowed(client, attributes)
+		switch {
+		case err != nil:
+			report.add("cutover config map", CheckFailed, "unable to check the permissions: %v", err)
+		case !allowed:
+			report.add("cutover config map", CheckFailed, "missing permissions to %s", describeAccess(attributes))
+		default:
+			report.add("cutover config map", CheckPassed, "all permissions granted")
+		}
+	}
+}
+
+func accessAllowed(client kubernetes.Interface, attributes authorizationv1.ResourceAttributes) (bool, error) {
+	review, err := client.AuthorizationV1().SelfSubjectAccessReviews().Create(&authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: &attributes},
+	})
+	if err != nil {
+		return false, err
+	}
+	return review.Status.Allowed, nil
+}
+
+func describeAccess(attributes authorizationv1.ResourceAttributes) string {
+	resource := attributes.Resource
+	if attributes.Group != "" {
+		resource += "." + attributes.Group
+	}
+	if attributes.Namespace != "" {
+		return fmt.Sprintf("%s %s in namespace %s", attributes.Verb, resource, attributes.Namespace)
+	}
+	return attributes.Verb + " " + resource
+}
+
+// validateProvider lists the records of the provider, which verifies its credentials and shows which
+// domains of the domain filter are visible. The permissions to change records can't be verified
+// without changing them.
+func validateProvider(ctx context.Context, report *ReadinessReport, cfg *apis.Config, p provider.Provider) {
+	records, err := p.Records(ctx)
+	zoneErrors, partial := err.(provider.ZoneErrors)
+	if err != nil && !partial {
+		report.add("provider records", CheckFailed, "%v", err)
+		return
+	}
+	for _, zone := range zoneErrors.Zones() {
+		report.add("zone "+zone, CheckFailed, "unable to list the records: %v", zoneErrors[zone])
+	}
+	report.add("provider records", CheckPassed, "listed %d records", len(records))
+
+	for _, domain := range cfg.DomainFilter {
+		domain = strings.ToLower(strings.Trim(domain, "."))
+		// the default domain filter is empty and includes all domains
+		if domain == "" || zoneErrors.Contains(domain) {
+			continue
+		}
+		count := 0
+		for _, ep := range records {
+			name := strings.ToLower(strings.TrimSuffix(ep.DNSName, "."))
+			if name == domain || strings.HasSuffix(name, "."+domain) {
+				count++
+			}
+		}
+		if count == 0 {
+			report.add("domain "+domain, CheckWarning, "no records visible, check that the zone exists and the credentials have access to it")
+		} else {
+			report.add("domain "+domain, CheckPassed, "%d records visible", count)
+		}
+	}
+
+	report.add("provider permissions", CheckSkipped, "the permissions to change records aren't verified, run with --dry-run to review the planned changes")
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package externaldns
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	apis "sigs.k8s.io/external-dns/pkg/apis/externaldns"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+func findCheck(t *testing.T, report *ReadinessReport, name string) Check {
+	for _, check := range report.Checks {
+		if check.Name == name {
+			return check
+		}
+	}
+	t.Fatalf("check %s not found in %s", name, report)
+	return Check{}
+}
+
+func TestReadinessReport(t *testing.T) {
+	report := &ReadinessReport{}
+	report.add("config", CheckPassed, "valid")
+	report.add("domain example.org", CheckWarning, "no records visible")
+	assert.True(t, report.Ready())
+	assert.Equal(t, "[OK]      config: valid\n[WARNING] domain example.org: no records visible\nReady to deploy\n", report.String())
+
+	report.add("provider records", CheckFailed, "403 Forbidden")
+	assert.False(t, report.Ready())
+	assert.True(t, strings.HasSuffix(report.String(), "[FAILED]  provider records: 403 Forbidden\nNot ready to deploy\n"))
+}
+
+func TestValidateFilters(t *testing.T) {
+	cfg := &apis.Config{
+		AnnotationFilter:  "kubernetes.io/ingress.class in (nginx",
+		DomainFilter:      []string{"example.org", "*.example.com"},
+		ServiceTypeFilter: []string{"LoadBalancer", "Headless"},
+		FQDNTemplate:      "{{.Name}.example.org",
+	}
+	report := &ReadinessReport{}
+	validateFilters(report, cfg)
+
+	assert.Equal(t, CheckFailed, findCheck(t, report, "annotation filter").Status)
+	assert.Equal(t, Check{Name: "domain filter", Status: CheckFailed, Detail: `invalid domains "*.example.com"`}, findCheck(t, report, "domain filter"))
+	assert.Equal(t, Check{Name: "service type filter", Status: CheckFailed, Detail: `unknown service type "Headless"`}, findCheck(t, report, "service type filter"))
+	assert.Equal(t, CheckFailed, findCheck(t, report, "fqdn template").Status)
+
+	cfg = &apis.Config{
+		AnnotationFilter: "kubernetes.io/ingress.class in (nginx)",
+		DomainFilter:     []string{"example.org"},
+		FQDNTemplate:     "{{.Name}}.example.org",
+	}
+	report = &ReadinessReport{}
+	validateFilters(report, cfg)
+	assert.True(t, report.Ready(), report.String())
+
+	// the default domain filters are a single empty domain
+	cfg = &apis.Config{DomainFilter: []string{""}, ExcludeDomains: []string{""}}
+	report = &ReadinessReport{}
+	validateFilters(report, cfg)
+	assert.Equal(t, CheckPassed, findCheck(t, report, "domain filter").Status)
+}
+
+func TestValidateSourcePermissions(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		attributes := review.Spec.ResourceAttributes
+		// the service account can't watch pods and can't read config maps
+		review.Status.Allowed = !(attributes.Resource == "pods" && attributes.Verb == "watch") && attributes.Resource != "configmaps"
+		return true, review, nil
+	})
+	cfg := &apis.Config{
+		Sources:          []string{"node", "service", "crd"},
+		Namespace:        "team-a",
+		CutoverConfigMap: "external-dns/cutover",
+	}
+
+	report := &ReadinessReport{}
+	validateSourcePermissions(report, client, cfg)
+
+	assert.Equal(t, []Check{
+		{Name: "source node", Status: CheckPassed, Detail: "all permissions granted"},
+		{Name: "source service", Status: CheckFailed, Detail: "missing permissions to watch pods in namespace team-a"},
+		{Name: "source crd", Status: CheckSkipped, Detail: "the permissions of the source aren't checked"},
+		{Name: "cutover config map", Status: CheckFailed, Detail: "missing permissions to get configmaps in namespace external-dns"},
+	}, report.Checks)
+}
+
+type zoneErrorsProvider struct {
+	provider.Provider
+	zoneErrors provider.ZoneErrors
+}
+
+func (p zoneErrorsProvider) Records(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	records, err := p.Provider.Records(ctx)
+	if err != nil || len(p.zoneErrors) == 0 {
+		return records, err
+	}
+	return records, p.zoneErrors
+}
+
+func TestValidateProvider(t *testing.T) {
+	p := provider.NewInMemoryProvider(provider.InMemoryInitZones([]string{"example.org", "example.com", "broken.org"}))
+	require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+		Create: []*endpoint.Endpoint{endpoint.NewEndpoint("www.example.org", endpoint.RecordTypeA, "1.2.3.4")},
+	}))
+	cfg := &apis.Config{DomainFilter: []string{"example.org", "example.com", "broken.org"}}
+
+	report := &ReadinessReport{}
+	validateProvider(context.Background(), report, cfg, zoneErrorsProvider{p, provider.ZoneErrors{"broken.org": errors.New("403 Forbidden")}})
+
+	assert.Equal(t, []Check{
+		{Name: "zone broken.org", Status: CheckFailed, Detail: "unable to list the records: 403 Forbidden"},
+		{Name: "provider records", Status: CheckPassed, Detail: "listed 1 records"},
+		{Name: "domain example.org", Status: CheckPassed, Detail: "1 records visible"},
+		{Name: "domain example.com", Status: CheckWarning, Detail: "no records visible, check that the zone exists and the credentials have access to it"},
+		{Name: "provider permissions", Status: CheckSkipped, Detail: "the permissions to change records aren't verified, run with --dry-run to review the planned changes"},
+	}, report.Checks)
+
+	report = &ReadinessReport{}
+	validateProvider(context.Background(), report, cfg, zoneErrorsProvider{p, nil})
+	assert.True(t, report.Ready())
+
+	// the default domain filter includes all domains and has no domain to check
+	report = &ReadinessReport{}
+	validateProvider(context.Background(), report, &apis.Config{DomainFilter: []string{""}}, zoneErrorsProvider{p, nil})
+	assert.Equal(t, []Check{
+		{Name: "provider records", Status: CheckPassed, Detail: "listed 1 records"},
+		{Name: "provider permissions", Status: CheckSkipped, Detail: "the permissions to change records aren't verified, run with --dry-run to review the planned changes"},
+	}, report.Checks)
+}
+
+func TestValidateProviderError(t *testing.T) {
+	report := &ReadinessReport{}
+	validateProvider(context.Background(), report, &apis.Config{}, failingProvider{})
+	assert.Equal(t, []Check{{Name: "provider records", Status: CheckFailed, Detail: "401 Unauthorized"}}, report.Checks)
+}
+
+type failingProvider struct {
+	provider.Provider
+}
+
+func (failingProvider) Records(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	return nil, errors.New("401 Unauthorized")
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plan
+
+import (
+	"fmt"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// Filter returns the changes of the endpoints keep returns true for. keep is called with the created
+// endpoints, the new versions of the updates along with their old versions and, if deletions is set,
+// the deleted endpoints, otherwise all deletions are kept. The old version is nil unless it's an update.
+// The old and new versions of an update are at the same position of UpdateOld and UpdateNew and are
+// kept or dropped together, so the changes can't be filtered if their numbers differ.
+func (c *Changes) Filter(deletions bool, keep func(old, ep *endpoint.Endpoint) bool) (*Changes, error) {
+	if len(c.UpdateOld) != len(c.UpdateNew) {
+		return nil, fmt.Errorf("unable to pair %d old with %d new versions of the updates", len(c.UpdateOld), len(c.UpdateNew))
+	}
+
+	filtered := &Changes{}
+	for _, ep := range c.Create {
+		if keep(nil, ep) {
+			filtered.Create = append(filtered.Create, ep)
+		}
+	}
+	for i, ep := range c.UpdateNew {
+		if keep(c.UpdateOld[i], ep) {
+			filtered.UpdateOld = append(filtered.UpdateOld, c.UpdateOld[i])
+			filtered.UpdateNew = append(filtered.UpdateNew, ep)
+		}
+	}
+	if !deletions {
+		filtered.Delete = c.Delete
+		return filtered, nil
+	}
+	for _, ep := range c.Delete {
+		if keep(nil, ep) {
+			filtered.Delete = append(filtered.Delete, ep)
+		}
+	}
+	return filtered, nil
+}
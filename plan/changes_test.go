@@ -0,0 +1,61 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plan
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestChangesFilter(t *testing.T) {
+	changes := &Changes{
+		Create:    []*endpoint.Endpoint{endpoint.NewEndpoint("keep-create", "A", "1.2.3.4"), endpoint.NewEndpoint("drop-create", "A", "1.2.3.4")},
+		UpdateOld: []*endpoint.Endpoint{endpoint.NewEndpoint("drop-update", "A", "1.2.3.4"), endpoint.NewEndpoint("keep-update", "A", "1.2.3.4")},
+		UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpoint("drop-update", "A", "5.6.7.8"), endpoint.NewEndpoint("keep-update", "A", "5.6.7.8")},
+		Delete:    []*endpoint.Endpoint{endpoint.NewEndpoint("drop-delete", "A", "1.2.3.4")},
+	}
+	var olds []*endpoint.Endpoint
+	keep := func(old, ep *endpoint.Endpoint) bool {
+		olds = append(olds, old)
+		return strings.HasPrefix(ep.DNSName, "keep-")
+	}
+
+	filtered, err := changes.Filter(true, keep)
+	require.NoError(t, err)
+	assert.Equal(t, []*endpoint.Endpoint{changes.Create[0]}, filtered.Create)
+	// the old version is kept with the new version at the same position
+	assert.Equal(t, []*endpoint.Endpoint{changes.UpdateOld[1]}, filtered.UpdateOld)
+	assert.Equal(t, []*endpoint.Endpoint{changes.UpdateNew[1]}, filtered.UpdateNew)
+	assert.Empty(t, filtered.Delete)
+	assert.Equal(t, []*endpoint.Endpoint{nil, nil, changes.UpdateOld[0], changes.UpdateOld[1], nil}, olds)
+
+	// the deletions are kept without calling keep
+	olds = nil
+	filtered, err = changes.Filter(false, keep)
+	require.NoError(t, err)
+	assert.Equal(t, changes.Delete, filtered.Delete)
+	assert.Len(t, olds, 4)
+
+	changes.UpdateOld = changes.UpdateOld[:1]
+	_, err = changes.Filter(true, keep)
+	assert.EqualError(t, err, "unable to pair 1 old with 2 new versions of the updates")
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plan
+
+import (
+	"fmt"
+	"time"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// ImpactModel describes how a provider applies changes, so the impact of a plan can be estimated
+// before it's applied, e.g. in dry-run mode when evaluating a large migration.
+type ImpactModel struct {
+	// The maximum number of changes submitted in one API call, 0 if every change is a separate call
+	BatchSize int
+	// The time waited between two API calls, e.g. to stay below the rate limit of the provider
+	CallInterval time.Duration
+	// The number of records written for each endpoint, e.g. 2 with the ownership records of the TXT registry
+	RecordsPerEndpoint int
+}
+
+// Impact is the estimated impact of applying a plan with a provider.
+type Impact struct {
+	// The number of records created, updated and deleted
+	Creates, Updates, Deletes int
+	// The number of API calls needed to apply the changes
+	APICalls int
+	// The number of records in the provider once the changes are applied, which is what providers
+	// billing per record charge for
+	Records int
+	// The minimum time it takes to apply the changes
+	TimeToApply time.Duration
+}
+
+// Estimate returns the impact of applying the changes on top of the current records.
+func (m ImpactModel) Estimate(current []*endpoint.Endpoint, changes *Changes) Impact {
+	perEndpoint := m.RecordsPerEndpoint
+	if perEndpoint < 1 {
+		perEndpoint = 1
+	}
+
+	impact := Impact{
+		Creates: len(changes.Create) * perEndpoint,
+		Updates: len(changes.UpdateNew) * perEndpoint,
+		Deletes: len(changes.Delete) * perEndpoint,
+	}
+	impact.Records = (len(current) + len(changes.Create) - len(changes.Delete)) * perEndpoint
+	if impact.Records < 0 {
+		impact.Records = 0
+	}
+
+	total := impact.Creates + impact.Updates + impact.Deletes
+	if m.BatchSize > 0 {
+		impact.APICalls = (total + m.BatchSize - 1) / m.BatchSize
+	} else {
+		impact.APICalls = total
+	}
+	if impact.APICalls > 1 {
+		impact.TimeToApply = time.Duration(impact.APICalls-1) * m.CallInterval
+	}
+
+	return impact
+}
+
+func (i Impact) String() string {
+	return fmt.Sprintf("%d creates, %d updates, %d deletes in %d API calls taking at least %s, %d records afterwards",
+		i.Creates, i.Updates, i.Deletes, i.APICalls, i.TimeToApply, i.Records)
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plan
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestImpactModelEstimate(t *testing.T) {
+	newEndpoints := func(n int) []*endpoint.Endpoint {
+		endpoints := make([]*endpoint.Endpoint, 0, n)
+		for i := 0; i < n; i++ {
+			endpoints = append(endpoints, endpoint.NewEndpoint(fmt.Sprintf("host-%d.example.org", i), endpoint.RecordTypeA, "1.2.3.4"))
+		}
+		return endpoints
+	}
+
+	changes := &Changes{
+		Create:    newEndpoints(5),
+		UpdateOld: newEndpoints(2),
+		UpdateNew: newEndpoints(2),
+		Delete:    newEndpoints(1),
+	}
+
+	for _, tc := range []struct {
+		title    string
+		model    ImpactModel
+		current  []*endpoint.Endpoint
+		changes  *Changes
+		expected Impact
+	}{
+		{
+			title:    "no changes",
+			model:    ImpactModel{BatchSize: 100, CallInterval: time.Second},
+			current:  newEndpoints(3),
+			changes:  &Changes{},
+			expected: Impact{Records: 3},
+		},
+		{
+			title:    "one call per change",
+			model:    ImpactModel{},
+			current:  newEndpoints(3),
+			changes:  changes,
+			expected: Impact{Creates: 5, Updates: 2, Deletes: 1, APICalls: 8, Records: 7},
+		},
+		{
+			title:    "batches with interval",
+			model:    ImpactModel{BatchSize: 3, CallInterval: time.Second},
+			current:  newEndpoints(3),
+			changes:  changes,
+			expected: Impact{Creates: 5, Updates: 2, Deletes: 1, APICalls: 3, Records: 7, TimeToApply: 2 * time.Second},
+		},
+		{
+			title:    "ownership records",
+			model:    ImpactModel{BatchSize: 1000, CallInterval: time.Second, RecordsPerEndpoint: 2},
+			current:  newEndpoints(3),
+			changes:  changes,
+			expected: Impact{Creates: 10, Updates: 4, Deletes: 2, APICalls: 1, Records: 14},
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.model.Estimate(tc.current, tc.changes))
+		})
+	}
+}
+
+func TestImpactString(t *testing.T) {
+	impact := Impact{Creates: 5, Updates: 2, Deletes: 1, APICalls: 3, Records: 7, TimeToApply: 2 * time.Second}
+	assert.Equal(t, "5 creates, 2 updates, 1 deletes in 3 API calls taking at least 2s, 7 records afterwards", impact.String())
+}
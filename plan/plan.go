@@ -32,11 +32,20 @@ type Plan struct {
 	Desired []*endpoint.Endpoint
 	// Policies under which the desired changes are calculated
 	Policies []Policy
+	// The record types the planner manages, records of other types are left alone. Defaults to
+	// DefaultManagedRecordTypes if empty.
+	ManagedRecords []string
+	// Whether the provider materializes the health checks of the endpoints, see provider.HealthCheckProvider.
+	// Otherwise the properties of the health checks aren't compared, as the provider ignores them.
+	HealthChecks bool
 	// List of changes necessary to move towards desired state
 	// Populated after calling Calculate()
 	Changes *Changes
 }
 
+// DefaultManagedRecordTypes are the record types the planner manages by default.
+var DefaultManagedRecordTypes = []string{endpoint.RecordTypeA, endpoint.RecordTypeCNAME}
+
 // Changes holds lists of actions to be executed by dns providers
 type Changes struct {
 	// Records that need to be created
@@ -84,25 +93,31 @@ func (t planTableRow) String() string {
 }
 
 func (t planTable) addCurrent(e *endpoint.Endpoint) {
-	dnsName := normalizeDNSName(e.DNSName)
-	if _, ok := t.rows[dnsName]; !ok {
-		t.rows[dnsName] = make(map[string]*planTableRow)
-	}
-	if _, ok := t.rows[dnsName][e.SetIdentifier]; !ok {
-		t.rows[dnsName][e.SetIdentifier] = &planTableRow{}
-	}
-	t.rows[dnsName][e.SetIdentifier].current = e
+	t.row(e).current = e
 }
 
 func (t planTable) addCandidate(e *endpoint.Endpoint) {
+	row := t.row(e)
+	row.candidates = append(row.candidates, e)
+}
+
+// row returns the row of the DNS name, set identifier and record type of the endpoint. A and CNAME
+// records share a row as they can't coexist, so a change between them is an update. The records of
+// the other types only compete with records of the same type, e.g. the AAAA records of a dual-stack
+// name are resolved independently of its A records.
+func (t planTable) row(e *endpoint.Endpoint) *planTableRow {
 	dnsName := normalizeDNSName(e.DNSName)
 	if _, ok := t.rows[dnsName]; !ok {
 		t.rows[dnsName] = make(map[string]*planTableRow)
 	}
-	if _, ok := t.rows[dnsName][e.SetIdentifier]; !ok {
-		t.rows[dnsName][e.SetIdentifier] = &planTableRow{}
+	key := e.SetIdentifier
+	if e.RecordType != endpoint.RecordTypeA && e.RecordType != endpoint.RecordTypeCNAME {
+		key += "::" + e.RecordType
+	}
+	if _, ok := t.rows[dnsName][key]; !ok {
+		t.rows[dnsName][key] = &planTableRow{}
 	}
-	t.rows[dnsName][e.SetIdentifier].candidates = append(t.rows[dnsName][e.SetIdentifier].candidates, e)
+	return t.rows[dnsName][key]
 }
 
 // Calculate computes the actions needed to move current state towards desired
@@ -111,10 +126,14 @@ func (t planTable) addCandidate(e *endpoint.Endpoint) {
 func (p *Plan) Calculate() *Plan {
 	t := newPlanTable()
 
-	for _, current := range filterRecordsForPlan(p.Current) {
+	managed := p.ManagedRecords
+	if len(managed) == 0 {
+		managed = DefaultManagedRecordTypes
+	}
+	for _, current := range filterRecordsForPlan(p.Current, managed) {
 		t.addCurrent(current)
 	}
-	for _, desired := range filterRecordsForPlan(p.Desired) {
+	for _, desired := range filterRecordsForPlan(p.Desired, managed) {
 		t.addCandidate(desired)
 	}
 
@@ -133,7 +152,7 @@ func (p *Plan) Calculate() *Plan {
 			if row.current != nil && len(row.candidates) > 0 { //dns name is taken
 				update := t.resolver.ResolveUpdate(row.current, row.candidates)
 				// compare "update" to "current" to figure out if actual update is required
-				if shouldUpdateTTL(update, row.current) || targetChanged(update, row.current) || shouldUpdateProviderSpecific(update, row.current) {
+				if shouldUpdateTTL(update, row.current) || targetChanged(update, row.current) || shouldUpdateTargetMetadata(update, row.current) || shouldUpdateProviderSpecific(update, row.current, p.HealthChecks) {
 					inheritOwner(row.current, update)
 					changes.UpdateNew = append(changes.UpdateNew, update)
 					changes.UpdateOld = append(changes.UpdateOld, row.current)
@@ -147,9 +166,11 @@ func (p *Plan) Calculate() *Plan {
 	}
 
 	plan := &Plan{
-		Current: p.Current,
-		Desired: p.Desired,
-		Changes: changes,
+		Current:        p.Current,
+		Desired:        p.Desired,
+		ManagedRecords: p.ManagedRecords,
+		HealthChecks:   p.HealthChecks,
+		Changes:        changes,
 	}
 
 	return plan
@@ -176,14 +197,27 @@ func shouldUpdateTTL(desired, current *endpoint.Endpoint) bool {
 	return desired.RecordTTL != current.RecordTTL
 }
 
-func shouldUpdateProviderSpecific(desired, current *endpoint.Endpoint) bool {
+// shouldUpdateTargetMetadata compares the structured attributes of the targets like TTLs, i.e. only if the
+// desired endpoint specifies any and the provider returns them for the current record. Providers which don't
+// read them back would otherwise update the record in every synchronization.
+func shouldUpdateTargetMetadata(desired, current *endpoint.Endpoint) bool {
+	if len(desired.TargetMetadata) == 0 || len(current.TargetMetadata) == 0 {
+		return false
+	}
+	return !desired.SameTargetMetadata(current)
+}
+
+func shouldUpdateProviderSpecific(desired, current *endpoint.Endpoint, healthChecks bool) bool {
 	if current.ProviderSpecific == nil && len(desired.ProviderSpecific) == 0 {
 		return false
 	}
+	ignored := func(name string) bool {
+		return !healthChecks && strings.HasPrefix(name, endpoint.HealthCheckKeyPrefix)
+	}
 	for _, c := range current.ProviderSpecific {
 		// don't consider target health when detecting changes
 		// see: https://github.com/kubernetes-sigs/external-dns/issues/869#issuecomment-458576954
-		if c.Name == "aws/evaluate-target-health" {
+		if c.Name == "aws/evaluate-target-health" || ignored(c.Name) {
 			continue
 		}
 
@@ -204,6 +238,10 @@ func shouldUpdateProviderSpecific(desired, current *endpoint.Endpoint) bool {
 		}
 	}
 	for _, d := range desired.ProviderSpecific {
+		// most providers can't report descriptions, they are only compared if the current record has one
+		if d.Name == endpoint.DescriptionProperty || ignored(d.Name) {
+			continue
+		}
 		found := false
 		for _, c := range current.ProviderSpecific {
 			if d.Name == c.Name {
@@ -220,30 +258,34 @@ func shouldUpdateProviderSpecific(desired, current *endpoint.Endpoint) bool {
 	return false
 }
 
-// filterRecordsForPlan removes records that are not relevant to the planner.
-// Currently this just removes TXT records to prevent them from being
-// deleted erroneously by the planner (only the TXT registry should do this.)
+// filterRecordsForPlan removes records that are not relevant to the planner, i.e. the records of
+// types which aren't managed. By default only A and CNAME records are managed, which e.g. prevents
+// TXT records from being deleted erroneously by the planner (only the TXT registry should do this.)
 //
 // Per RFC 1034, CNAME records conflict with all other records - it is the
 // only record with this property. The behavior of the planner may need to be
 // made more sophisticated to codify this.
-func filterRecordsForPlan(records []*endpoint.Endpoint) []*endpoint.Endpoint {
+func filterRecordsForPlan(records []*endpoint.Endpoint, managed []string) []*endpoint.Endpoint {
 	filtered := []*endpoint.Endpoint{}
 
 	for _, record := range records {
-		// Explicitly specify which records we want to use for planning.
-		// TODO: Add AAAA records as well when they are supported.
-		switch record.RecordType {
-		case endpoint.RecordTypeA, endpoint.RecordTypeCNAME:
+		if isManagedRecordType(record.RecordType, managed) {
 			filtered = append(filtered, record)
-		default:
-			continue
 		}
 	}
 
 	return filtered
 }
 
+func isManagedRecordType(recordType string, managed []string) bool {
+	for _, t := range managed {
+		if t == recordType {
+			return true
+		}
+	}
+	return false
+}
+
 // normalizeDNSName converts a DNS name to a canonical form, so that we can use string equality
 // it: removes space, converts to lower case, ensures there is a trailing dot
 func normalizeDNSName(dnsName string) string {
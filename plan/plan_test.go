@@ -267,6 +267,65 @@ func (suite *PlanTestSuite) TestSyncSecondRoundWithProviderSpecificChange() {
 	validateEntries(suite.T(), changes.Delete, expectedDelete)
 }
 
+func (suite *PlanTestSuite) TestSyncSecondRoundWithTargetMetadataChange() {
+	current := []*endpoint.Endpoint{
+		endpoint.NewEndpoint("bar", endpoint.RecordTypeA, "127.0.0.1").WithTargetMetadata("127.0.0.1", endpoint.TargetMetadata{Weight: 10}),
+		endpoint.NewEndpoint("foo", endpoint.RecordTypeA, "127.0.0.1"),
+		endpoint.NewEndpoint("baz", endpoint.RecordTypeA, "127.0.0.1"),
+	}
+	desired := []*endpoint.Endpoint{
+		endpoint.NewEndpoint("bar", endpoint.RecordTypeA, "127.0.0.1").WithTargetMetadata("127.0.0.1", endpoint.TargetMetadata{Weight: 20}),
+		// target metadata is only compared if the desired endpoint specifies any
+		endpoint.NewEndpoint("foo", endpoint.RecordTypeA, "127.0.0.1"),
+		// and the provider returns it for the current record
+		endpoint.NewEndpoint("baz", endpoint.RecordTypeA, "127.0.0.1").WithTargetMetadata("127.0.0.1", endpoint.TargetMetadata{Weight: 20}),
+	}
+	expectedCreate := []*endpoint.Endpoint{}
+	expectedUpdateOld := []*endpoint.Endpoint{current[0]}
+	expectedUpdateNew := []*endpoint.Endpoint{desired[0]}
+	expectedDelete := []*endpoint.Endpoint{}
+
+	p := &Plan{
+		Policies: []Policy{&SyncPolicy{}},
+		Current:  current,
+		Desired:  desired,
+	}
+
+	changes := p.Calculate().Changes
+	validateEntries(suite.T(), changes.Create, expectedCreate)
+	validateEntries(suite.T(), changes.UpdateNew, expectedUpdateNew)
+	validateEntries(suite.T(), changes.UpdateOld, expectedUpdateOld)
+	validateEntries(suite.T(), changes.Delete, expectedDelete)
+}
+
+func (suite *PlanTestSuite) TestSyncSecondRoundWithDescription() {
+	current := []*endpoint.Endpoint{
+		endpoint.NewEndpoint("bar", endpoint.RecordTypeA, "127.0.0.1").WithProviderSpecific(endpoint.DescriptionProperty, "old"),
+		endpoint.NewEndpoint("foo", endpoint.RecordTypeA, "127.0.0.1"),
+	}
+	desired := []*endpoint.Endpoint{
+		endpoint.NewEndpoint("bar", endpoint.RecordTypeA, "127.0.0.1").WithProviderSpecific(endpoint.DescriptionProperty, "new"),
+		// descriptions are only compared if the current record reports one
+		endpoint.NewEndpoint("foo", endpoint.RecordTypeA, "127.0.0.1").WithProviderSpecific(endpoint.DescriptionProperty, "new"),
+	}
+	expectedCreate := []*endpoint.Endpoint{}
+	expectedUpdateOld := []*endpoint.Endpoint{current[0]}
+	expectedUpdateNew := []*endpoint.Endpoint{desired[0]}
+	expectedDelete := []*endpoint.Endpoint{}
+
+	p := &Plan{
+		Policies: []Policy{&SyncPolicy{}},
+		Current:  current,
+		Desired:  desired,
+	}
+
+	changes := p.Calculate().Changes
+	validateEntries(suite.T(), changes.Create, expectedCreate)
+	validateEntries(suite.T(), changes.UpdateNew, expectedUpdateNew)
+	validateEntries(suite.T(), changes.UpdateOld, expectedUpdateOld)
+	validateEntries(suite.T(), changes.Delete, expectedDelete)
+}
+
 func (suite *PlanTestSuite) TestSyncSecondRoundWithOwnerInherited() {
 	current := []*endpoint.Endpoint{suite.fooV1Cname}
 	desired := []*endpoint.Endpoint{suite.fooV2Cname}
@@ -492,6 +551,73 @@ func (suite *PlanTestSuite) TestSetIdentifierUpdateCreatesAndDeletes() {
 	validateEntries(suite.T(), changes.Delete, expectedDelete)
 }
 
+func (suite *PlanTestSuite) TestDualStack() {
+	labels := func(resource string) endpoint.Labels {
+		return endpoint.Labels{endpoint.ResourceLabelKey: resource, endpoint.OwnerLabelKey: "owner"}
+	}
+	currentA := &endpoint.Endpoint{DNSName: "dual", Targets: endpoint.Targets{"1.2.3.4"}, RecordType: endpoint.RecordTypeA, Labels: labels("service/default/a")}
+	currentAAAA := &endpoint.Endpoint{DNSName: "dual", Targets: endpoint.Targets{"2001:db8::1"}, RecordType: endpoint.RecordTypeAAAA, Labels: labels("service/default/a")}
+	desiredA := &endpoint.Endpoint{DNSName: "dual", Targets: endpoint.Targets{"1.2.3.4"}, RecordType: endpoint.RecordTypeA, Labels: labels("service/default/a")}
+	// another resource only competing for the AAAA record
+	desiredAAAA := &endpoint.Endpoint{DNSName: "dual", Targets: endpoint.Targets{"2001:db8::2"}, RecordType: endpoint.RecordTypeAAAA, Labels: labels("service/default/b")}
+	newA := &endpoint.Endpoint{DNSName: "new", Targets: endpoint.Targets{"1.2.3.5"}, RecordType: endpoint.RecordTypeA}
+	newAAAA := &endpoint.Endpoint{DNSName: "new", Targets: endpoint.Targets{"2001:db8::3"}, RecordType: endpoint.RecordTypeAAAA}
+
+	p := &Plan{
+		Policies:       []Policy{&SyncPolicy{}},
+		Current:        []*endpoint.Endpoint{currentA, currentAAAA},
+		Desired:        []*endpoint.Endpoint{desiredA, desiredAAAA, newA, newAAAA},
+		ManagedRecords: []string{endpoint.RecordTypeA, endpoint.RecordTypeAAAA, endpoint.RecordTypeCNAME},
+	}
+	changes := p.Calculate().Changes
+	validateEntries(suite.T(), changes.Create, []*endpoint.Endpoint{newA, newAAAA})
+	validateEntries(suite.T(), changes.UpdateNew, []*endpoint.Endpoint{desiredAAAA})
+	validateEntries(suite.T(), changes.UpdateOld, []*endpoint.Endpoint{currentAAAA})
+	validateEntries(suite.T(), changes.Delete, []*endpoint.Endpoint{})
+
+	// the AAAA record is deleted once the name is IPv4 only, the A record is left alone
+	p.Desired = []*endpoint.Endpoint{desiredA}
+	changes = p.Calculate().Changes
+	validateEntries(suite.T(), changes.Create, []*endpoint.Endpoint{})
+	validateEntries(suite.T(), changes.UpdateNew, []*endpoint.Endpoint{})
+	validateEntries(suite.T(), changes.Delete, []*endpoint.Endpoint{currentAAAA})
+}
+
+func (suite *PlanTestSuite) TestManagedRecordTypes() {
+	currentTXT := &endpoint.Endpoint{DNSName: "foo", Targets: endpoint.Targets{"v1"}, RecordType: endpoint.RecordTypeTXT}
+	desiredTXT := &endpoint.Endpoint{DNSName: "foo", Targets: endpoint.Targets{"v2"}, RecordType: endpoint.RecordTypeTXT}
+	currentMX := &endpoint.Endpoint{DNSName: "foo", Targets: endpoint.Targets{"10 mail.example.org"}, RecordType: "MX"}
+	current := []*endpoint.Endpoint{suite.fooV1Cname, currentTXT, currentMX}
+	desired := []*endpoint.Endpoint{suite.fooV1Cname, desiredTXT}
+
+	// TXT records aren't managed by default
+	p := &Plan{
+		Policies: []Policy{&SyncPolicy{}},
+		Current:  current,
+		Desired:  desired,
+	}
+	changes := p.Calculate().Changes
+	validateEntries(suite.T(), changes.Create, []*endpoint.Endpoint{})
+	validateEntries(suite.T(), changes.UpdateNew, []*endpoint.Endpoint{})
+	validateEntries(suite.T(), changes.UpdateOld, []*endpoint.Endpoint{})
+	validateEntries(suite.T(), changes.Delete, []*endpoint.Endpoint{})
+
+	// the TXT record is planned next to the CNAME record of the same name, the MX record is left alone
+	p.ManagedRecords = []string{endpoint.RecordTypeCNAME, endpoint.RecordTypeTXT}
+	calculated := p.Calculate()
+	suite.Equal(p.ManagedRecords, calculated.ManagedRecords)
+	validateEntries(suite.T(), calculated.Changes.Create, []*endpoint.Endpoint{})
+	validateEntries(suite.T(), calculated.Changes.UpdateNew, []*endpoint.Endpoint{desiredTXT})
+	validateEntries(suite.T(), calculated.Changes.UpdateOld, []*endpoint.Endpoint{currentTXT})
+	validateEntries(suite.T(), calculated.Changes.Delete, []*endpoint.Endpoint{})
+
+	// records of types which are no longer managed aren't deleted
+	p.Desired = []*endpoint.Endpoint{suite.fooV1Cname}
+	p.ManagedRecords = []string{endpoint.RecordTypeCNAME}
+	changes = p.Calculate().Changes
+	validateEntries(suite.T(), changes.Delete, []*endpoint.Endpoint{})
+}
+
 func TestPlan(t *testing.T) {
 	suite.Run(t, new(PlanTestSuite))
 }
@@ -558,3 +684,27 @@ func TestNormalizeDNSName(t *testing.T) {
 		assert.Equal(t, r.expect, gotName)
 	}
 }
+
+func (suite *PlanTestSuite) TestSyncSecondRoundWithHealthCheckChange() {
+	current := []*endpoint.Endpoint{
+		endpoint.NewEndpoint("foo", endpoint.RecordTypeA, "127.0.0.1").WithHealthCheck(&endpoint.HealthCheck{Protocol: "HTTP", Port: 80}),
+	}
+	desired := []*endpoint.Endpoint{
+		endpoint.NewEndpoint("foo", endpoint.RecordTypeA, "127.0.0.1").WithHealthCheck(&endpoint.HealthCheck{Protocol: "HTTPS", Port: 443}),
+	}
+
+	// the health checks are ignored unless the provider materializes them
+	p := &Plan{
+		Policies: []Policy{&SyncPolicy{}},
+		Current:  current,
+		Desired:  desired,
+	}
+	changes := p.Calculate().Changes
+	validateEntries(suite.T(), changes.UpdateNew, []*endpoint.Endpoint{})
+	validateEntries(suite.T(), changes.UpdateOld, []*endpoint.Endpoint{})
+
+	p.HealthChecks = true
+	changes = p.Calculate().Changes
+	validateEntries(suite.T(), changes.UpdateNew, []*endpoint.Endpoint{desired[0]})
+	validateEntries(suite.T(), changes.UpdateOld, []*endpoint.Endpoint{current[0]})
+}
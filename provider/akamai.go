@@ -37,14 +37,23 @@ type akamaiClient interface {
 	Do(config edgegrid.Config, req *http.Request) (*http.Response, error)
 }
 
-type akamaiOpenClient struct{}
+type akamaiOpenClient struct {
+	client *http.Client
+}
 
 func (*akamaiOpenClient) NewRequest(config edgegrid.Config, method, path string, body io.Reader) (*http.Request, error) {
 	return c.NewRequest(config, method, path, body)
 }
 
-func (*akamaiOpenClient) Do(config edgegrid.Config, req *http.Request) (*http.Response, error) {
-	return c.Do(config, req)
+// Do signs and sends the request like the client of the library, but with the HTTP client of the provider
+// instead of the global one of the library.
+func (o *akamaiOpenClient) Do(config edgegrid.Config, req *http.Request) (*http.Response, error) {
+	client := *o.client
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		edgegrid.AddRequestHeader(config, req)
+		return nil
+	}
+	return client.Do(edgegrid.AddRequestHeader(config, req))
 }
 
 // AkamaiConfig clarifies the method signature
@@ -56,6 +65,8 @@ type AkamaiConfig struct {
 	ClientSecret          string
 	AccessToken           string
 	DryRun                bool
+	// The client of the API requests, defaults to the default HTTP client
+	HTTPClient *http.Client
 }
 
 // AkamaiProvider implements the DNS provider for Akamai.
@@ -101,12 +112,17 @@ func NewAkamaiProvider(akamaiConfig AkamaiConfig) *AkamaiProvider {
 		Debug: false,
 	}
 
+	client := akamaiConfig.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
 	provider := &AkamaiProvider{
 		domainFilter: akamaiConfig.DomainFilter,
 		zoneIDFilter: akamaiConfig.ZoneIDFilter,
 		config:       edgeGridConfig,
 		dryRun:       akamaiConfig.DryRun,
-		client:       &akamaiOpenClient{},
+		client:       &akamaiOpenClient{client: client},
 	}
 	return provider
 }
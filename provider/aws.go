@@ -19,9 +19,13 @@ package provider
 import (
 	"context"
 	"fmt"
+	"hash/fnv"
+	"net"
+	"net/http"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -48,9 +52,23 @@ const (
 	providerSpecificGeolocationCountryCode     = "aws/geolocation-country-code"
 	providerSpecificGeolocationSubdivisionCode = "aws/geolocation-subdivision-code"
 	providerSpecificMultiValueAnswer           = "aws/multi-value-answer"
+	// provider specific key of the ID of a health check which isn't managed by ExternalDNS
+	providerSpecificHealthCheckID = "aws/health-check-id"
+	// the caller reference of the health checks created by ExternalDNS starts with this prefix
+	healthCheckCallerReferencePrefix = "external-dns-"
+	// the tag of the health checks created by ExternalDNS carrying the owner ID
+	healthCheckOwnerTagKey = "external-dns/owner"
+	// the tag of the health checks created by ExternalDNS carrying the ID of the hosted zone of their record
+	healthCheckHostedZoneTagKey = "external-dns/hosted-zone"
+	// the VPC associations of a private hosted zone are checked again after this interval, e.g. in
+	// case an association was removed by hand
+	privateZoneVPCsRecheckInterval = time.Hour
 )
 
 var (
+	// the request intervals of the health checks supported by Route53
+	route53HealthCheckIntervals = map[int]bool{10: true, 30: true}
+
 	// see: https://docs.aws.amazon.com/general/latest/gr/rande.html#elb_region
 	// and: https://docs.aws.amazon.com/govcloud-us/latest/UserGuide/using-govcloud-endpoints.html
 	canonicalHostedZones = map[string]string{
@@ -106,7 +124,15 @@ type Route53API interface {
 	ChangeResourceRecordSetsWithContext(ctx context.Context, input *route53.ChangeResourceRecordSetsInput, opts ...request.Option) (*route53.ChangeResourceRecordSetsOutput, error)
 	CreateHostedZoneWithContext(ctx context.Context, input *route53.CreateHostedZoneInput, opts ...request.Option) (*route53.CreateHostedZoneOutput, error)
 	ListHostedZonesPagesWithContext(ctx context.Context, input *route53.ListHostedZonesInput, fn func(resp *route53.ListHostedZonesOutput, lastPage bool) (shouldContinue bool), opts ...request.Option) error
+	ListHostedZonesByNameWithContext(ctx context.Context, input *route53.ListHostedZonesByNameInput, opts ...request.Option) (*route53.ListHostedZonesByNameOutput, error)
 	ListTagsForResourceWithContext(ctx context.Context, input *route53.ListTagsForResourceInput, opts ...request.Option) (*route53.ListTagsForResourceOutput, error)
+	ListHealthChecksPagesWithContext(ctx context.Context, input *route53.ListHealthChecksInput, fn func(resp *route53.ListHealthChecksOutput, lastPage bool) (shouldContinue bool), opts ...request.Option) error
+	CreateHealthCheckWithContext(ctx context.Context, input *route53.CreateHealthCheckInput, opts ...request.Option) (*route53.CreateHealthCheckOutput, error)
+	DeleteHealthCheckWithContext(ctx context.Context, input *route53.DeleteHealthCheckInput, opts ...request.Option) (*route53.DeleteHealthCheckOutput, error)
+	ChangeTagsForResourceWithContext(ctx context.Context, input *route53.ChangeTagsForResourceInput, opts ...request.Option) (*route53.ChangeTagsForResourceOutput, error)
+	ListTagsForResourcesWithContext(ctx context.Context, input *route53.ListTagsForResourcesInput, opts ...request.Option) (*route53.ListTagsForResourcesOutput, error)
+	GetHostedZoneWithContext(ctx context.Context, input *route53.GetHostedZoneInput, opts ...request.Option) (*route53.GetHostedZoneOutput, error)
+	AssociateVPCWithHostedZoneWithContext(ctx context.Context, input *route53.AssociateVPCWithHostedZoneInput, opts ...request.Option) (*route53.AssociateVPCWithHostedZoneOutput, error)
 }
 
 // AWSProvider is an implementation of Provider for AWS Route53.
@@ -125,6 +151,20 @@ type AWSProvider struct {
 	// filter hosted zones by tags
 	zoneTagFilter ZoneTagFilter
 	preferCNAME   bool
+	// the VPCs the private hosted zones which records are changed in are associated with
+	privateZoneVPCs []*route53.VPC
+	// the private hosted zones known to be associated with all privateZoneVPCs by the time they were
+	// last checked
+	associatedZones     map[string]time.Time
+	associatedZonesLock sync.Mutex
+	// the owner ID the created health checks are tagged with and scoped to
+	healthCheckOwnerID string
+	// whether the orphaned health checks of the owner in the managed zones are deleted
+	cleanUpOrphanedHealthChecks bool
+	// the IDs of the health checks referenced by the record sets of the last complete listing, nil if
+	// the last listing failed
+	referencedHealthChecks     map[string]bool
+	referencedHealthChecksLock sync.Mutex
 }
 
 // AWSConfig contains configuration to create a new AWS provider.
@@ -139,12 +179,29 @@ type AWSConfig struct {
 	AssumeRole           string
 	APIRetries           int
 	PreferCNAME          bool
+	PrivateZoneVPCs      []string
 	DryRun               bool
+	// The client of the API requests, defaults to the default HTTP client
+	HTTPClient *http.Client
+	// The owner ID the created health checks are tagged with. A health check is only reused and deleted
+	// by the instances of the same owner managing the hosted zone of its record.
+	HealthCheckOwnerID string
+	// Delete the health checks of the owner in the managed zones which no record set references anymore.
+	// Requires HealthCheckOwnerID.
+	DeleteOrphanedHealthChecks bool
 }
 
 // NewAWSProvider initializes a new AWS Route53 based Provider.
 func NewAWSProvider(awsConfig AWSConfig) (*AWSProvider, error) {
+	privateZoneVPCs, err := parseRoute53VPCs(awsConfig.PrivateZoneVPCs)
+	if err != nil {
+		return nil, err
+	}
+
 	config := aws.NewConfig().WithMaxRetries(awsConfig.APIRetries)
+	if awsConfig.HTTPClient != nil {
+		config.WithHTTPClient(awsConfig.HTTPClient)
+	}
 
 	config.WithHTTPClient(
 		instrumented_http.NewClient(config.HTTPClient, &instrumented_http.Callbacks{
@@ -178,7 +235,12 @@ func NewAWSProvider(awsConfig AWSConfig) (*AWSProvider, error) {
 		batchChangeInterval:  awsConfig.BatchChangeInterval,
 		evaluateTargetHealth: awsConfig.EvaluateTargetHealth,
 		preferCNAME:          awsConfig.PreferCNAME,
+		privateZoneVPCs:      privateZoneVPCs,
+		associatedZones:      map[string]time.Time{},
 		dryRun:               awsConfig.DryRun,
+		healthCheckOwnerID:   awsConfig.HealthCheckOwnerID,
+
+		cleanUpOrphanedHealthChecks: awsConfig.DeleteOrphanedHealthChecks,
 	}
 
 	return provider, nil
@@ -245,6 +307,19 @@ func wildcardUnescape(s string) string {
 	return s
 }
 
+// ZoneNames returns the names of the hosted zones.
+func (p *AWSProvider) ZoneNames(ctx context.Context) ([]string, error) {
+	zones, err := p.Zones(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(zones))
+	for _, zone := range zones {
+		names = append(names, aws.StringValue(zone.Name))
+	}
+	return names, nil
+}
+
 // Records returns the list of records in a given hosted zone.
 func (p *AWSProvider) Records(ctx context.Context) (endpoints []*endpoint.Endpoint, _ error) {
 	zones, err := p.Zones(ctx)
@@ -255,16 +330,50 @@ func (p *AWSProvider) Records(ctx context.Context) (endpoints []*endpoint.Endpoi
 	return p.records(ctx, zones)
 }
 
+// RecordsPages passes the records of each page of the record sets of the hosted zones to fn, so the
+// records of all zones don't have to be held at once.
+func (p *AWSProvider) RecordsPages(ctx context.Context, fn func(page []*endpoint.Endpoint) bool) error {
+	zones, err := p.Zones(ctx)
+	if err != nil {
+		return err
+	}
+
+	return p.recordsPages(ctx, zones, fn)
+}
+
 func (p *AWSProvider) records(ctx context.Context, zones map[string]*route53.HostedZone) ([]*endpoint.Endpoint, error) {
 	endpoints := make([]*endpoint.Endpoint, 0)
+	err := p.recordsPages(ctx, zones, func(page []*endpoint.Endpoint) bool {
+		endpoints = append(endpoints, page...)
+		return true
+	})
+	zoneErrors, partial := err.(ZoneErrors)
+	if err != nil && !partial {
+		return nil, err
+	}
+	return recordsResult(endpoints, zoneErrors)
+}
+
+func (p *AWSProvider) recordsPages(ctx context.Context, zones map[string]*route53.HostedZone, fn func(page []*endpoint.Endpoint) bool) error {
+	// set once fn stopped the listing
+	stopped := false
+
+	// health checks are only listed once a record set references one
+	var (
+		healthChecks    map[string]*route53.HealthCheck
+		healthChecksErr error
+	)
+	referenced := make(map[string]bool)
 	f := func(resp *route53.ListResourceRecordSetsOutput, lastPage bool) (shouldContinue bool) {
+		endpoints := make([]*endpoint.Endpoint, 0, len(resp.ResourceRecordSets))
 		for _, r := range resp.ResourceRecordSets {
 			newEndpoints := make([]*endpoint.Endpoint, 0)
 
 			// TODO(linki, ownership): Remove once ownership system is in place.
 			// See: https://github.com/kubernetes-sigs/external-dns/pull/122/files/74e2c3d3e237411e619aefc5aab694742001cdec#r109863370
 
-			if !supportedRecordType(aws.StringValue(r.Type)) {
+			// CAA records are managed for the CAA policies of the zones
+			if !supportedRecordType(aws.StringValue(r.Type)) && aws.StringValue(r.Type) != route53.RRTypeCaa {
 				continue
 			}
 
@@ -294,6 +403,13 @@ func (p *AWSProvider) records(ctx context.Context, zones map[string]*route53.Hos
 			}
 
 			for _, ep := range newEndpoints {
+				if r.HealthCheckId != nil {
+					referenced[aws.StringValue(r.HealthCheckId)] = true
+					if healthChecks == nil && healthChecksErr == nil {
+						healthChecks, healthChecksErr = p.healthChecks(ctx)
+					}
+					withRoute53HealthCheck(ep, aws.StringValue(r.HealthCheckId), healthChecks)
+				}
 				if r.SetIdentifier != nil {
 					ep.SetIdentifier = aws.StringValue(r.SetIdentifier)
 					switch {
@@ -324,20 +440,40 @@ func (p *AWSProvider) records(ctx context.Context, zones map[string]*route53.Hos
 			}
 		}
 
-		return true
+		stopped = !fn(endpoints)
+		return !stopped
 	}
 
+	zoneErrors := ZoneErrors{}
 	for _, z := range zones {
+		if stopped {
+			break
+		}
 		params := &route53.ListResourceRecordSetsInput{
 			HostedZoneId: z.Id,
 		}
 
 		if err := p.client.ListResourceRecordSetsPagesWithContext(ctx, params, f); err != nil {
-			return nil, err
+			zoneErrors[aws.StringValue(z.Name)] = fmt.Errorf("failed to list the records: %v", err)
 		}
 	}
+	if healthChecksErr != nil {
+		return healthChecksErr
+	}
 
-	return endpoints, nil
+	// the orphaned health checks are only known if the record sets of all zones were listed
+	p.referencedHealthChecksLock.Lock()
+	if len(zoneErrors) == 0 && !stopped {
+		p.referencedHealthChecks = referenced
+	} else {
+		p.referencedHealthChecks = nil
+	}
+	p.referencedHealthChecksLock.Unlock()
+
+	if len(zoneErrors) > 0 {
+		return zoneErrors
+	}
+	return nil
 }
 
 // CreateRecords creates a given set of DNS records in the given hosted zone.
@@ -365,7 +501,12 @@ func (p *AWSProvider) doRecords(ctx context.Context, action string, endpoints []
 	if err != nil {
 		log.Errorf("getting records failed: %v", err)
 	}
-	return p.submitChanges(ctx, p.newChanges(action, endpoints, records, zones), zones)
+	if action == route53.ChangeActionDelete {
+		p.attachHealthChecks(ctx, nil, endpoints, zones)
+	} else {
+		p.attachHealthChecks(ctx, endpoints, nil, zones)
+	}
+	return p.submitChanges(ctx, p.newChanges(action, endpoints, records, zones), zones, ownedRecordNames(endpoints))
 }
 
 // ApplyChanges applies a given set of changes in a given zone.
@@ -384,38 +525,54 @@ func (p *AWSProvider) ApplyChanges(ctx context.Context, changes *plan.Changes) e
 		}
 	}
 
+	desired := make([]*endpoint.Endpoint, 0, len(changes.Create)+len(changes.UpdateNew))
+	desired = append(desired, changes.Create...)
+	desired = append(desired, changes.UpdateNew...)
+	healthChecks := p.attachHealthChecks(ctx, desired, changes.Delete, zones)
+
 	combinedChanges := make([]*route53.Change, 0, len(changes.Create)+len(changes.UpdateNew)+len(changes.Delete))
 
 	combinedChanges = append(combinedChanges, p.newChanges(route53.ChangeActionCreate, changes.Create, records, zones)...)
 	combinedChanges = append(combinedChanges, p.newChanges(route53.ChangeActionUpsert, changes.UpdateNew, records, zones)...)
 	combinedChanges = append(combinedChanges, p.newChanges(route53.ChangeActionDelete, changes.Delete, records, zones)...)
 
-	return p.submitChanges(ctx, combinedChanges, zones)
+	if err := p.submitChanges(ctx, combinedChanges, zones, ownedRecordNames(changes.Create, changes.UpdateNew, changes.Delete)); err != nil {
+		return err
+	}
+
+	p.deleteUnusedHealthChecks(ctx, changes, records, healthChecks, zones)
+	p.deleteOrphanedHealthChecks(ctx, desired, healthChecks, zones)
+	return nil
 }
 
 // submitChanges takes a zone and a collection of Changes and sends them as a single transaction.
-func (p *AWSProvider) submitChanges(ctx context.Context, changes []*route53.Change, zones map[string]*route53.HostedZone) error {
+// The changes of ownership records are submitted in the same batch as the records they own.
+func (p *AWSProvider) submitChanges(ctx context.Context, changes []*route53.Change, zones map[string]*route53.HostedZone, ownedNames map[string]string) error {
 	// return early if there is nothing to change
 	if len(changes) == 0 {
-		log.Info("All records are already up to date")
+		log.Debug("All records are already up to date")
 		return nil
 	}
 
 	// separate into per-zone change sets to be passed to the API.
 	changesByZone := changesByZone(zones, changes)
 	if len(changesByZone) == 0 {
-		log.Info("All records are already up to date, there are no changes for the matching hosted zones")
+		log.Debug("All records are already up to date, there are no changes for the matching hosted zones")
 	}
 
-	var failedZones []string
+	failedZones := ZoneErrors{}
 	for z, cs := range changesByZone {
 		var failedUpdate bool
 
-		batchCs := batchChangeSet(cs, p.batchChangeSize)
+		if err := p.associateVPCs(ctx, zones[z]); err != nil {
+			log.Errorf("Failed to associate the private zone %s [Id: %s] with the VPCs: %v", aws.StringValue(zones[z].Name), z, err)
+		}
+
+		batchCs := batchChangeSet(cs, p.batchChangeSize, ownedNames)
 
 		for i, b := range batchCs {
 			for _, c := range b {
-				log.Infof("Desired change: %s %s %s [Id: %s]", *c.Action, *c.ResourceRecordSet.Name, *c.ResourceRecordSet.Type, z)
+				log.Debugf("Desired change: %s %s %s [Id: %s]", *c.Action, *c.ResourceRecordSet.Name, *c.ResourceRecordSet.Type, z)
 			}
 
 			if !p.dryRun {
@@ -442,12 +599,12 @@ func (p *AWSProvider) submitChanges(ctx context.Context, changes []*route53.Chan
 		}
 
 		if failedUpdate {
-			failedZones = append(failedZones, z)
+			failedZones[aws.StringValue(zones[z].Name)] = fmt.Errorf("failed to submit all changes [Id: %s]", z)
 		}
 	}
 
 	if len(failedZones) > 0 {
-		return fmt.Errorf("Failed to submit all changes for the following zones: %v", failedZones)
+		return failedZones
 	}
 
 	return nil
@@ -525,6 +682,10 @@ func (p *AWSProvider) newChange(action string, ep *endpoint.Endpoint, recordsCac
 		}
 	}
 
+	if prop, ok := ep.GetProviderSpecificProperty(providerSpecificHealthCheckID); ok {
+		change.ResourceRecordSet.HealthCheckId = aws.String(prop.Value)
+	}
+
 	setIdentifier := ep.SetIdentifier
 	if setIdentifier != "" {
 		change.ResourceRecordSet.SetIdentifier = aws.String(setIdentifier)
@@ -569,6 +730,446 @@ func (p *AWSProvider) newChange(action string, ep *endpoint.Endpoint, recordsCac
 	return change, dualstack
 }
 
+// awsProviderSpecificSchema describes the provider-specific properties of Route53 records.
+var awsProviderSpecificSchema = ProviderSpecificSchema{
+	Prefix: "aws/",
+	Properties: map[string]func(string) error{
+		providerSpecificEvaluateTargetHealth:       boolPropertyValue,
+		providerSpecificWeight:                     intPropertyValue(0, 255),
+		providerSpecificRegion:                     nonEmptyPropertyValue,
+		providerSpecificFailover:                   enumPropertyValue(route53.ResourceRecordSetFailoverPrimary, route53.ResourceRecordSetFailoverSecondary),
+		providerSpecificGeolocationContinentCode:   nonEmptyPropertyValue,
+		providerSpecificGeolocationCountryCode:     nonEmptyPropertyValue,
+		providerSpecificGeolocationSubdivisionCode: nonEmptyPropertyValue,
+		providerSpecificMultiValueAnswer:           anyPropertyValue,
+		providerSpecificHealthCheckID:              nonEmptyPropertyValue,
+	},
+}
+
+// awsRoutingPolicyProperties are the properties which only apply to records with a set identifier.
+var awsRoutingPolicyProperties = []string{
+	providerSpecificWeight,
+	providerSpecificRegion,
+	providerSpecificFailover,
+	providerSpecificGeolocationContinentCode,
+	providerSpecificGeolocationCountryCode,
+	providerSpecificGeolocationSubdivisionCode,
+	providerSpecificMultiValueAnswer,
+}
+
+// ValidateProviderSpecific validates the aws/ properties and the health check of an endpoint. Routing
+// policies like aws/weight require a set identifier, since newChange would silently ignore them otherwise.
+func (p *AWSProvider) ValidateProviderSpecific(ep *endpoint.Endpoint) error {
+	if err := awsProviderSpecificSchema.Validate(ep); err != nil {
+		return err
+	}
+	if ep.SetIdentifier == "" {
+		for _, name := range awsRoutingPolicyProperties {
+			if _, ok := ep.GetProviderSpecificProperty(name); ok {
+				return fmt.Errorf("%s record %s has provider-specific property %s but no set identifier", ep.RecordType, ep.DNSName, name)
+			}
+		}
+	}
+	hc, err := ep.HealthCheck()
+	if err != nil {
+		return fmt.Errorf("%s record %s has an invalid health check: %v", ep.RecordType, ep.DNSName, err)
+	}
+	if hc != nil && !route53HealthCheckIntervals[hc.Interval] {
+		return fmt.Errorf("%s record %s has an invalid health check interval %d, must be 10 or 30", ep.RecordType, ep.DNSName, hc.Interval)
+	}
+	return nil
+}
+
+// SupportsHealthChecks returns true as the health checks of the endpoints are materialized as Route53 health checks.
+func (p *AWSProvider) SupportsHealthChecks() bool {
+	return true
+}
+
+// healthChecks returns all health checks by their ID.
+func (p *AWSProvider) healthChecks(ctx context.Context) (map[string]*route53.HealthCheck, error) {
+	healthChecks := make(map[string]*route53.HealthCheck)
+
+	f := func(resp *route53.ListHealthChecksOutput, lastPage bool) (shouldContinue bool) {
+		for _, hc := range resp.HealthChecks {
+			healthChecks[aws.StringValue(hc.Id)] = hc
+		}
+		return true
+	}
+
+	if err := p.client.ListHealthChecksPagesWithContext(ctx, &route53.ListHealthChecksInput{}, f); err != nil {
+		return nil, err
+	}
+	return healthChecks, nil
+}
+
+// attachHealthChecks sets the IDs of the Route53 health checks matching the health checks of the endpoints.
+// Missing health checks of the desired endpoints are created, the ones of deleted endpoints are looked up
+// as Route53 only deletes record sets referencing the same health check. Only the health checks of the owner
+// and the hosted zone of an endpoint are reused. Endpoints which reference a health check by its ID are left
+// untouched. It returns all health checks, or nil if none of the endpoints has one.
+func (p *AWSProvider) attachHealthChecks(ctx context.Context, desired, deleted []*endpoint.Endpoint, zones map[string]*route53.HostedZone) map[string]*route53.HealthCheck {
+	var healthChecks map[string]*route53.HealthCheck
+
+	for _, eps := range []struct {
+		endpoints []*endpoint.Endpoint
+		create    bool
+	}{
+		{desired, true},
+		{deleted, false},
+	} {
+		for i, ep := range eps.endpoints {
+			if _, ok := ep.GetProviderSpecificProperty(providerSpecificHealthCheckID); ok {
+				continue
+			}
+			config := route53HealthCheckConfig(ep)
+			if config == nil {
+				continue
+			}
+			zoneID := healthCheckZoneID(ep, zones)
+			if zoneID == "" {
+				continue
+			}
+			scope := p.healthCheckScope(zoneID)
+
+			if healthChecks == nil {
+				var err error
+				if healthChecks, err = p.healthChecks(ctx); err != nil {
+					log.Errorf("getting health checks failed: %v", err)
+					return nil
+				}
+			}
+
+			id := findRoute53HealthCheck(healthChecks, config, map[string]string{scope: zoneID})
+			if id == "" && eps.create {
+				log.Debugf("Desired change: CREATE health check %s %s", ep.DNSName, route53HealthCheckString(config))
+				if p.dryRun {
+					continue
+				}
+				out, err := p.client.CreateHealthCheckWithContext(ctx, &route53.CreateHealthCheckInput{
+					CallerReference:   aws.String(fmt.Sprintf("%s%s-%d-%d", healthCheckCallerReferencePrefix, scope, time.Now().UnixNano(), i)),
+					HealthCheckConfig: config,
+				})
+				if err != nil {
+					log.Errorf("Failed to create health check for %s: %v", ep.DNSName, err)
+					continue
+				}
+				id = aws.StringValue(out.HealthCheck.Id)
+				healthChecks[id] = out.HealthCheck
+				p.tagHealthCheck(ctx, id, zoneID)
+				// the listing of the records may be cached, so the new health check isn't seen as orphan
+				p.referencedHealthChecksLock.Lock()
+				if p.referencedHealthChecks != nil {
+					p.referencedHealthChecks[id] = true
+				}
+				p.referencedHealthChecksLock.Unlock()
+			}
+			if id != "" {
+				ep.WithProviderSpecific(providerSpecificHealthCheckID, id)
+			}
+		}
+	}
+
+	return healthChecks
+}
+
+// deleteUnusedHealthChecks deletes the health checks created by ExternalDNS for deleted or updated
+// endpoints unless another record still uses them. Only the health checks of the owner and the managed
+// zones are considered, as the records of other zones aren't known.
+func (p *AWSProvider) deleteUnusedHealthChecks(ctx context.Context, changes *plan.Changes, records []*endpoint.Endpoint, healthChecks map[string]*route53.HealthCheck, zones map[string]*route53.HostedZone) {
+	var unused []*route53.HealthCheckConfig
+	replaced := make(map[string]bool)
+	for _, ep := range append(append([]*endpoint.Endpoint{}, changes.Delete...), changes.UpdateOld...) {
+		replaced[ep.DNSName+"/"+ep.RecordType+"/"+ep.SetIdentifier] = true
+		if config := route53HealthCheckConfig(ep); config != nil {
+			unused = append(unused, config)
+		}
+	}
+	if len(unused) == 0 {
+		return
+	}
+
+	used := make(map[string]bool)
+	for _, ep := range append(append([]*endpoint.Endpoint{}, changes.Create...), changes.UpdateNew...) {
+		if config := route53HealthCheckConfig(ep); config != nil {
+			used[route53HealthCheckString(config)] = true
+		}
+	}
+	for _, ep := range records {
+		if replaced[ep.DNSName+"/"+ep.RecordType+"/"+ep.SetIdentifier] {
+			continue
+		}
+		if config := route53HealthCheckConfig(ep); config != nil {
+			used[route53HealthCheckString(config)] = true
+		}
+	}
+
+	if healthChecks == nil {
+		var err error
+		if healthChecks, err = p.healthChecks(ctx); err != nil {
+			log.Errorf("getting health checks failed: %v", err)
+			return
+		}
+	}
+
+	scopes := p.healthCheckScopes(zones)
+	for _, config := range unused {
+		if used[route53HealthCheckString(config)] {
+			continue
+		}
+		id := findRoute53HealthCheck(healthChecks, config, scopes)
+		if id == "" {
+			continue
+		}
+
+		log.Debugf("Desired change: DELETE health check %s %s", id, route53HealthCheckString(config))
+		if p.dryRun {
+			continue
+		}
+		if _, err := p.client.DeleteHealthCheckWithContext(ctx, &route53.DeleteHealthCheckInput{HealthCheckId: aws.String(id)}); err != nil {
+			log.Errorf("Failed to delete health check %s: %v", id, err)
+			continue
+		}
+		delete(healthChecks, id)
+	}
+}
+
+// tagHealthCheck tags a created health check with the owner ID and the ID of the hosted zone of its record,
+// so it's deleted once it's orphaned.
+func (p *AWSProvider) tagHealthCheck(ctx context.Context, id, zoneID string) {
+	if p.healthCheckOwnerID == "" {
+		return
+	}
+	_, err := p.client.ChangeTagsForResourceWithContext(ctx, &route53.ChangeTagsForResourceInput{
+		ResourceType: aws.String(route53.TagResourceTypeHealthcheck),
+		ResourceId:   aws.String(id),
+		AddTags: []*route53.Tag{
+			{Key: aws.String(healthCheckOwnerTagKey), Value: aws.String(p.healthCheckOwnerID)},
+			{Key: aws.String(healthCheckHostedZoneTagKey), Value: aws.String(zoneID)},
+		},
+	})
+	if err != nil {
+		log.Errorf("Failed to tag health check %s: %v", id, err)
+	}
+}
+
+// deleteOrphanedHealthChecks deletes the health checks created by ExternalDNS for the owner and one of the
+// managed zones which neither a record set of the last complete listing nor one of the desired endpoints
+// references, e.g. because their records were deleted outside of ExternalDNS or their deletion failed.
+// Health checks of other owners or zones are left alone, as their records aren't listed.
+func (p *AWSProvider) deleteOrphanedHealthChecks(ctx context.Context, desired []*endpoint.Endpoint, healthChecks map[string]*route53.HealthCheck, zones map[string]*route53.HostedZone) {
+	if !p.cleanUpOrphanedHealthChecks || p.healthCheckOwnerID == "" {
+		return
+	}
+	p.referencedHealthChecksLock.Lock()
+	if p.referencedHealthChecks == nil {
+		p.referencedHealthChecksLock.Unlock()
+		return
+	}
+	used := make(map[string]bool, len(p.referencedHealthChecks))
+	for id := range p.referencedHealthChecks {
+		used[id] = true
+	}
+	p.referencedHealthChecksLock.Unlock()
+
+	for _, ep := range desired {
+		if prop, ok := ep.GetProviderSpecificProperty(providerSpecificHealthCheckID); ok {
+			used[prop.Value] = true
+		}
+	}
+
+	if healthChecks == nil {
+		var err error
+		if healthChecks, err = p.healthChecks(ctx); err != nil {
+			log.Errorf("getting health checks failed: %v", err)
+			return
+		}
+	}
+	scopes := p.healthCheckScopes(zones)
+	var candidates []string
+	for id, hc := range healthChecks {
+		if _, ok := scopes[route53HealthCheckScope(hc)]; ok && !used[id] {
+			candidates = append(candidates, id)
+		}
+	}
+	sort.Strings(candidates)
+
+	// the tags of at most 10 health checks are listed per request
+	for len(candidates) > 0 {
+		n := len(candidates)
+		if n > 10 {
+			n = 10
+		}
+		batch := candidates[:n]
+		candidates = candidates[n:]
+
+		out, err := p.client.ListTagsForResourcesWithContext(ctx, &route53.ListTagsForResourcesInput{
+			ResourceType: aws.String(route53.TagResourceTypeHealthcheck),
+			ResourceIds:  aws.StringSlice(batch),
+		})
+		if err != nil {
+			log.Errorf("Failed to list the tags of the health checks: %v", err)
+			return
+		}
+		for _, tagSet := range out.ResourceTagSets {
+			id := aws.StringValue(tagSet.ResourceId)
+			zoneID := scopes[route53HealthCheckScope(healthChecks[id])]
+			if !route53TagsContain(tagSet.Tags, healthCheckOwnerTagKey, p.healthCheckOwnerID) ||
+				!route53TagsContain(tagSet.Tags, healthCheckHostedZoneTagKey, zoneID) {
+				continue
+			}
+			log.Debugf("Desired change: DELETE orphaned health check %s %s", id, route53HealthCheckString(healthChecks[id].HealthCheckConfig))
+			if p.dryRun {
+				continue
+			}
+			if _, err := p.client.DeleteHealthCheckWithContext(ctx, &route53.DeleteHealthCheckInput{HealthCheckId: aws.String(id)}); err != nil {
+				log.Errorf("Failed to delete health check %s: %v", id, err)
+				continue
+			}
+			delete(healthChecks, id)
+		}
+	}
+}
+
+func route53TagsContain(tags []*route53.Tag, key, value string) bool {
+	for _, tag := range tags {
+		if aws.StringValue(tag.Key) == key && aws.StringValue(tag.Value) == value {
+			return true
+		}
+	}
+	return false
+}
+
+// route53HealthCheckConfig returns the Route53 health check config for the health check of an endpoint.
+// Route53 associates one health check with a record set, so it monitors the first target of the endpoint.
+// HTTP and HTTPS health checks of IP targets send the DNS name of the endpoint as host header.
+func route53HealthCheckConfig(ep *endpoint.Endpoint) *route53.HealthCheckConfig {
+	hc, err := ep.HealthCheck()
+	if err != nil {
+		log.Warnf("Ignoring the health check of %s: %v", ep.DNSName, err)
+		return nil
+	}
+	if hc == nil || len(ep.Targets) == 0 {
+		return nil
+	}
+	if !route53HealthCheckIntervals[hc.Interval] {
+		log.Warnf("Ignoring the health check of %s: invalid interval %d, must be 10 or 30", ep.DNSName, hc.Interval)
+		return nil
+	}
+
+	config := &route53.HealthCheckConfig{
+		Type:             aws.String(hc.Protocol),
+		Port:             aws.Int64(int64(hc.Port)),
+		RequestInterval:  aws.Int64(int64(hc.Interval)),
+		FailureThreshold: aws.Int64(int64(hc.FailureThreshold)),
+	}
+	if hc.Protocol != endpoint.HealthCheckProtocolTCP {
+		config.ResourcePath = aws.String(hc.Path)
+	}
+
+	target := ep.Targets[0]
+	if net.ParseIP(target) != nil {
+		config.IPAddress = aws.String(target)
+		if hc.Protocol != endpoint.HealthCheckProtocolTCP {
+			config.FullyQualifiedDomainName = aws.String(strings.TrimSuffix(ep.DNSName, "."))
+		}
+	} else {
+		config.FullyQualifiedDomainName = aws.String(strings.TrimSuffix(target, "."))
+	}
+	return config
+}
+
+// findRoute53HealthCheck returns the ID of a health check created by ExternalDNS in one of the scopes with
+// the given config.
+func findRoute53HealthCheck(healthChecks map[string]*route53.HealthCheck, config *route53.HealthCheckConfig, scopes map[string]string) string {
+	for id, hc := range healthChecks {
+		if _, ok := scopes[route53HealthCheckScope(hc)]; !ok {
+			continue
+		}
+		if route53HealthCheckString(hc.HealthCheckConfig) == route53HealthCheckString(config) {
+			return id
+		}
+	}
+	return ""
+}
+
+// healthCheckZoneID returns the ID of the hosted zone the health check of an endpoint is scoped to, the most
+// suitable public zone or else a private one, empty if none of the zones is suitable.
+func healthCheckZoneID(ep *endpoint.Endpoint, zones map[string]*route53.HostedZone) string {
+	suitable := suitableZones(ensureTrailingDot(ep.DNSName), zones)
+	if len(suitable) == 0 {
+		return ""
+	}
+	return aws.StringValue(suitable[len(suitable)-1].Id)
+}
+
+// healthCheckScope returns the scope of the health checks of the owner for the records of a hosted zone. It's
+// part of their caller reference, so the health checks of other owners and zones are neither reused nor
+// deleted, e.g. by another instance sharing the owner ID but managing other zones.
+func (p *AWSProvider) healthCheckScope(zoneID string) string {
+	h := fnv.New32a()
+	h.Write([]byte(p.healthCheckOwnerID + "/" + zoneID))
+	return fmt.Sprintf("%08x", h.Sum32())
+}
+
+// healthCheckScopes returns the IDs of the zones by the scopes of their health checks.
+func (p *AWSProvider) healthCheckScopes(zones map[string]*route53.HostedZone) map[string]string {
+	scopes := make(map[string]string, len(zones))
+	for _, z := range zones {
+		scopes[p.healthCheckScope(aws.StringValue(z.Id))] = aws.StringValue(z.Id)
+	}
+	return scopes
+}
+
+// route53HealthCheckScope returns the scope of a health check created by ExternalDNS, empty for other
+// health checks and the ones created without a scope.
+func route53HealthCheckScope(hc *route53.HealthCheck) string {
+	if !managedRoute53HealthCheck(hc) {
+		return ""
+	}
+	ref := strings.TrimPrefix(aws.StringValue(hc.CallerReference), healthCheckCallerReferencePrefix)
+	parts := strings.SplitN(ref, "-", 2)
+	if len(parts) != 2 || len(parts[0]) != 8 {
+		return ""
+	}
+	return parts[0]
+}
+
+// managedRoute53HealthCheck returns true if the health check was created by ExternalDNS.
+func managedRoute53HealthCheck(hc *route53.HealthCheck) bool {
+	return hc != nil && hc.HealthCheckConfig != nil && strings.HasPrefix(aws.StringValue(hc.CallerReference), healthCheckCallerReferencePrefix)
+}
+
+func route53HealthCheckString(config *route53.HealthCheckConfig) string {
+	return fmt.Sprintf("%s://%s[%s]:%d%s (interval %ds, failure threshold %d)",
+		aws.StringValue(config.Type),
+		aws.StringValue(config.FullyQualifiedDomainName),
+		aws.StringValue(config.IPAddress),
+		aws.Int64Value(config.Port),
+		aws.StringValue(config.ResourcePath),
+		aws.Int64Value(config.RequestInterval),
+		aws.Int64Value(config.FailureThreshold),
+	)
+}
+
+// withRoute53HealthCheck attaches the health check of a record set to its endpoint. The health checks created
+// by ExternalDNS are attached as provider-agnostic health check, others are referenced by their ID.
+func withRoute53HealthCheck(ep *endpoint.Endpoint, id string, healthChecks map[string]*route53.HealthCheck) {
+	hc := healthChecks[id]
+	if !managedRoute53HealthCheck(hc) {
+		ep.WithProviderSpecific(providerSpecificHealthCheckID, id)
+		return
+	}
+
+	config := hc.HealthCheckConfig
+	ep.WithHealthCheck(&endpoint.HealthCheck{
+		Protocol:         aws.StringValue(config.Type),
+		Port:             int(aws.Int64Value(config.Port)),
+		Path:             aws.StringValue(config.ResourcePath),
+		Interval:         int(aws.Int64Value(config.RequestInterval)),
+		FailureThreshold: int(aws.Int64Value(config.FailureThreshold)),
+	})
+}
+
 func (p *AWSProvider) tagsForZone(ctx context.Context, zoneID string) (map[string]string, error) {
 	response, err := p.client.ListTagsForResourceWithContext(ctx, &route53.ListTagsForResourceInput{
 		ResourceType: aws.String("hostedzone"),
@@ -584,7 +1185,191 @@ func (p *AWSProvider) tagsForZone(ctx context.Context, zoneID string) (map[strin
 	return tagMap, nil
 }
 
-func batchChangeSet(cs []*route53.Change, batchSize int) [][]*route53.Change {
+// DelegateZone creates the public hosted zone if it doesn't exist yet and upserts the NS record delegating
+// to its name servers in the most specific public parent zone.
+func (p *AWSProvider) DelegateZone(ctx context.Context, zone string) error {
+	name := ensureTrailingDot(zone)
+	zones, err := p.Zones(ctx)
+	if err != nil {
+		return err
+	}
+
+	// the parent has to be a zone of the provider, the subzone may exist outside of the filters
+	var parent *route53.HostedZone
+	for _, z := range zones {
+		if z.Config != nil && aws.BoolValue(z.Config.PrivateZone) {
+			continue
+		}
+		zoneName := aws.StringValue(z.Name)
+		if strings.HasSuffix(name, "."+zoneName) && (parent == nil || len(zoneName) > len(aws.StringValue(parent.Name))) {
+			parent = z
+		}
+	}
+	if parent == nil {
+		return fmt.Errorf("no public parent zone of %s found", name)
+	}
+	existing, err := p.publicZoneByName(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	var delegationSet *route53.DelegationSet
+	if existing == nil {
+		log.Infof("Desired zone: %s delegated from zone %s [Id: %s]", name, aws.StringValue(parent.Name), aws.StringValue(parent.Id))
+		if p.dryRun {
+			return nil
+		}
+		resp, err := p.client.CreateHostedZoneWithContext(ctx, &route53.CreateHostedZoneInput{
+			Name:             aws.String(name),
+			CallerReference:  aws.String(fmt.Sprintf("external-dns-%s-%d", strings.TrimSuffix(name, "."), time.Now().Unix())),
+			HostedZoneConfig: &route53.HostedZoneConfig{Comment: aws.String("created by ExternalDNS")},
+		})
+		if err != nil {
+			return err
+		}
+		delegationSet = resp.DelegationSet
+	} else {
+		resp, err := p.client.GetHostedZoneWithContext(ctx, &route53.GetHostedZoneInput{Id: aws.String(cleanZoneID(aws.StringValue(existing.Id)))})
+		if err != nil {
+			return err
+		}
+		delegationSet = resp.DelegationSet
+	}
+	if delegationSet == nil || len(delegationSet.NameServers) == 0 {
+		return fmt.Errorf("zone %s has no name servers", name)
+	}
+
+	records := make([]*route53.ResourceRecord, 0, len(delegationSet.NameServers))
+	for _, ns := range delegationSet.NameServers {
+		records = append(records, &route53.ResourceRecord{Value: aws.String(ensureTrailingDot(aws.StringValue(ns)))})
+	}
+	log.Debugf("Desired change: %s %s %s [Id: %s]", route53.ChangeActionUpsert, name, route53.RRTypeNs, aws.StringValue(parent.Id))
+	if p.dryRun {
+		return nil
+	}
+	_, err = p.client.ChangeResourceRecordSetsWithContext(ctx, &route53.ChangeResourceRecordSetsInput{
+		HostedZoneId: parent.Id,
+		ChangeBatch: &route53.ChangeBatch{
+			Changes: []*route53.Change{{
+				Action: aws.String(route53.ChangeActionUpsert),
+				ResourceRecordSet: &route53.ResourceRecordSet{
+					Name:            aws.String(name),
+					Type:            aws.String(route53.RRTypeNs),
+					TTL:             aws.Int64(recordTTL),
+					ResourceRecords: records,
+				},
+			}},
+		},
+	})
+	return err
+}
+
+// publicZoneByName returns the public hosted zone with the given name regardless of the filters of the
+// provider, or nil if there is none.
+func (p *AWSProvider) publicZoneByName(ctx context.Context, name string) (*route53.HostedZone, error) {
+	input := &route53.ListHostedZonesByNameInput{DNSName: aws.String(name)}
+	for {
+		resp, err := p.client.ListHostedZonesByNameWithContext(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		// the zones are sorted by name, starting with the given one
+		for _, z := range resp.HostedZones {
+			if aws.StringValue(z.Name) != name {
+				return nil, nil
+			}
+			if z.Config == nil || !aws.BoolValue(z.Config.PrivateZone) {
+				return z, nil
+			}
+		}
+		if !aws.BoolValue(resp.IsTruncated) {
+			return nil, nil
+		}
+		input.DNSName, input.HostedZoneId = resp.NextDNSName, resp.NextHostedZoneId
+	}
+}
+
+// associateVPCs associates a private hosted zone with the configured VPCs it isn't associated with yet,
+// so the records are resolvable from new VPCs without manual steps. Zones are checked again with their
+// changes once the recheck interval passed, and a failed association is retried with the next changes
+// of the zone.
+func (p *AWSProvider) associateVPCs(ctx context.Context, zone *route53.HostedZone) error {
+	if len(p.privateZoneVPCs) == 0 || zone.Config == nil || !aws.BoolValue(zone.Config.PrivateZone) {
+		return nil
+	}
+	// the zones may be changed concurrently, e.g. by the pipelines sharing the provider
+	p.associatedZonesLock.Lock()
+	defer p.associatedZonesLock.Unlock()
+
+	zoneID := aws.StringValue(zone.Id)
+	if checkedAt, ok := p.associatedZones[zoneID]; ok && time.Since(checkedAt) < privateZoneVPCsRecheckInterval {
+		return nil
+	}
+
+	resp, err := p.client.GetHostedZoneWithContext(ctx, &route53.GetHostedZoneInput{Id: aws.String(cleanZoneID(zoneID))})
+	if err != nil {
+		return err
+	}
+	associated := map[string]bool{}
+	for _, vpc := range resp.VPCs {
+		associated[aws.StringValue(vpc.VPCRegion)+":"+aws.StringValue(vpc.VPCId)] = true
+	}
+
+	for _, vpc := range p.privateZoneVPCs {
+		if associated[aws.StringValue(vpc.VPCRegion)+":"+aws.StringValue(vpc.VPCId)] {
+			continue
+		}
+		log.Infof("Desired VPC association: %s %s with zone %s [Id: %s]", aws.StringValue(vpc.VPCRegion), aws.StringValue(vpc.VPCId), aws.StringValue(zone.Name), zoneID)
+		if p.dryRun {
+			continue
+		}
+		if _, err := p.client.AssociateVPCWithHostedZoneWithContext(ctx, &route53.AssociateVPCWithHostedZoneInput{
+			HostedZoneId: aws.String(cleanZoneID(zoneID)),
+			VPC:          vpc,
+			Comment:      aws.String("associated by ExternalDNS"),
+		}); err != nil {
+			return err
+		}
+	}
+
+	if !p.dryRun {
+		if p.associatedZones == nil {
+			p.associatedZones = map[string]time.Time{}
+		}
+		p.associatedZones[zoneID] = time.Now()
+	}
+	return nil
+}
+
+// parseRoute53VPCs parses VPCs in the format <region>:<VPC ID>, e.g. us-east-1:vpc-0123456789abcdef0.
+func parseRoute53VPCs(vpcs []string) ([]*route53.VPC, error) {
+	var parsed []*route53.VPC
+	for _, vpc := range vpcs {
+		parts := strings.Split(vpc, ":")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid VPC (region:vpc-id) found '%v'", vpc)
+		}
+		parsed = append(parsed, &route53.VPC{VPCRegion: aws.String(parts[0]), VPCId: aws.String(parts[1])})
+	}
+	return parsed, nil
+}
+
+// ownedRecordNames maps the names of the ownership records among the endpoints to the names of the records they own.
+func ownedRecordNames(endpoints ...[]*endpoint.Endpoint) map[string]string {
+	ownedNames := map[string]string{}
+	for _, eps := range endpoints {
+		for _, ep := range eps {
+			if owned, ok := ep.Labels[endpoint.OwnedRecordLabelKey]; ok && owned != ep.DNSName {
+				ownedNames[ep.DNSName] = owned
+			}
+		}
+	}
+	return ownedNames
+}
+
+// batchChangeSet splits the changes into batches of at most batchSize changes. All changes of a name
+// and of the ownership records of that name, given by ownedNames, are kept in the same batch.
+func batchChangeSet(cs []*route53.Change, batchSize int, ownedNames map[string]string) [][]*route53.Change {
 	if len(cs) <= batchSize {
 		return [][]*route53.Change{cs}
 	}
@@ -593,7 +1378,11 @@ func batchChangeSet(cs []*route53.Change, batchSize int) [][]*route53.Change {
 
 	changesByName := make(map[string][]*route53.Change)
 	for _, v := range cs {
-		changesByName[*v.ResourceRecordSet.Name] = append(changesByName[*v.ResourceRecordSet.Name], v)
+		name := *v.ResourceRecordSet.Name
+		if owned, ok := ownedNames[name]; ok {
+			name = owned
+		}
+		changesByName[name] = append(changesByName[name], v)
 	}
 
 	names := make([]string, 0)
@@ -18,6 +18,7 @@ package provider
 
 import (
 	"context"
+	"net/http"
 	"strings"
 
 	"crypto/sha256"
@@ -81,9 +82,13 @@ type AWSSDProvider struct {
 	namespaceTypeFilter *sd.NamespaceFilter
 }
 
-// NewAWSSDProvider initializes a new AWS Cloud Map based Provider.
-func NewAWSSDProvider(domainFilter DomainFilter, namespaceType string, assumeRole string, dryRun bool) (*AWSSDProvider, error) {
+// NewAWSSDProvider initializes a new AWS Cloud Map based Provider. The API requests are sent with the HTTP
+// client, nil for the default HTTP client.
+func NewAWSSDProvider(domainFilter DomainFilter, namespaceType string, assumeRole string, dryRun bool, client *http.Client) (*AWSSDProvider, error) {
 	config := aws.NewConfig()
+	if client != nil {
+		config.WithHTTPClient(client)
+	}
 
 	config = config.WithHTTPClient(
 		instrumented_http.NewClient(config.HTTPClient, &instrumented_http.Callbacks{
@@ -207,7 +212,7 @@ func (p *AWSSDProvider) instancesToEndpoint(ns *sd.NamespaceSummary, srv *sd.Ser
 func (p *AWSSDProvider) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
 	// return early if there is nothing to change
 	if len(changes.Create) == 0 && len(changes.Delete) == 0 && len(changes.UpdateNew) == 0 {
-		log.Info("All records are already up to date")
+		log.Debug("All records are already up to date")
 		return nil
 	}
 
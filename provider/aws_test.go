@@ -51,10 +51,14 @@ var _ Route53API = &Route53APIStub{}
 // of all of its methods.
 // mostly taken from: https://github.com/kubernetes/kubernetes/blob/853167624edb6bc0cfdcdfb88e746e178f5db36c/federation/pkg/dnsprovider/providers/aws/route53/stubs/route53api.go
 type Route53APIStub struct {
-	zones      map[string]*route53.HostedZone
-	recordSets map[string]map[string][]*route53.ResourceRecordSet
-	zoneTags   map[string][]*route53.Tag
-	m          dynamicMock
+	zones        map[string]*route53.HostedZone
+	recordSets   map[string]map[string][]*route53.ResourceRecordSet
+	zoneTags     map[string][]*route53.Tag
+	healthChecks map[string]*route53.HealthCheck
+	// the tags of the health checks by their ID
+	healthCheckTags map[string][]*route53.Tag
+	zoneVPCs        map[string][]*route53.VPC
+	m               dynamicMock
 }
 
 // MockMethod starts a description of an expectation of the specified method
@@ -68,9 +72,12 @@ func (r *Route53APIStub) MockMethod(method string, args ...interface{}) *mock.Ca
 // NewRoute53APIStub returns an initialized Route53APIStub
 func NewRoute53APIStub() *Route53APIStub {
 	return &Route53APIStub{
-		zones:      make(map[string]*route53.HostedZone),
-		recordSets: make(map[string]map[string][]*route53.ResourceRecordSet),
-		zoneTags:   make(map[string][]*route53.Tag),
+		zones:           make(map[string]*route53.HostedZone),
+		recordSets:      make(map[string]map[string][]*route53.ResourceRecordSet),
+		zoneTags:        make(map[string][]*route53.Tag),
+		zoneVPCs:        make(map[string][]*route53.VPC),
+		healthChecks:    make(map[string]*route53.HealthCheck),
+		healthCheckTags: make(map[string][]*route53.Tag),
 	}
 }
 
@@ -122,11 +129,51 @@ func (c *Route53APICounter) ListHostedZonesPagesWithContext(ctx context.Context,
 	return c.wrapped.ListHostedZonesPagesWithContext(ctx, input, fn)
 }
 
+func (c *Route53APICounter) ListHostedZonesByNameWithContext(ctx context.Context, input *route53.ListHostedZonesByNameInput, opts ...request.Option) (*route53.ListHostedZonesByNameOutput, error) {
+	c.calls["ListHostedZonesByName"]++
+	return c.wrapped.ListHostedZonesByNameWithContext(ctx, input)
+}
+
 func (c *Route53APICounter) ListTagsForResourceWithContext(ctx context.Context, input *route53.ListTagsForResourceInput, opts ...request.Option) (*route53.ListTagsForResourceOutput, error) {
 	c.calls["ListTagsForResource"]++
 	return c.wrapped.ListTagsForResourceWithContext(ctx, input)
 }
 
+func (c *Route53APICounter) ListHealthChecksPagesWithContext(ctx context.Context, input *route53.ListHealthChecksInput, fn func(resp *route53.ListHealthChecksOutput, lastPage bool) (shouldContinue bool), opts ...request.Option) error {
+	c.calls["ListHealthChecksPages"]++
+	return c.wrapped.ListHealthChecksPagesWithContext(ctx, input, fn)
+}
+
+func (c *Route53APICounter) CreateHealthCheckWithContext(ctx context.Context, input *route53.CreateHealthCheckInput, opts ...request.Option) (*route53.CreateHealthCheckOutput, error) {
+	c.calls["CreateHealthCheck"]++
+	return c.wrapped.CreateHealthCheckWithContext(ctx, input)
+}
+
+func (c *Route53APICounter) DeleteHealthCheckWithContext(ctx context.Context, input *route53.DeleteHealthCheckInput, opts ...request.Option) (*route53.DeleteHealthCheckOutput, error) {
+	c.calls["DeleteHealthCheck"]++
+	return c.wrapped.DeleteHealthCheckWithContext(ctx, input)
+}
+
+func (c *Route53APICounter) ChangeTagsForResourceWithContext(ctx context.Context, input *route53.ChangeTagsForResourceInput, opts ...request.Option) (*route53.ChangeTagsForResourceOutput, error) {
+	c.calls["ChangeTagsForResource"]++
+	return c.wrapped.ChangeTagsForResourceWithContext(ctx, input)
+}
+
+func (c *Route53APICounter) ListTagsForResourcesWithContext(ctx context.Context, input *route53.ListTagsForResourcesInput, opts ...request.Option) (*route53.ListTagsForResourcesOutput, error) {
+	c.calls["ListTagsForResources"]++
+	return c.wrapped.ListTagsForResourcesWithContext(ctx, input)
+}
+
+func (c *Route53APICounter) GetHostedZoneWithContext(ctx context.Context, input *route53.GetHostedZoneInput, opts ...request.Option) (*route53.GetHostedZoneOutput, error) {
+	c.calls["GetHostedZone"]++
+	return c.wrapped.GetHostedZoneWithContext(ctx, input)
+}
+
+func (c *Route53APICounter) AssociateVPCWithHostedZoneWithContext(ctx context.Context, input *route53.AssociateVPCWithHostedZoneInput, opts ...request.Option) (*route53.AssociateVPCWithHostedZoneOutput, error) {
+	c.calls["AssociateVPCWithHostedZone"]++
+	return c.wrapped.AssociateVPCWithHostedZoneWithContext(ctx, input)
+}
+
 // Route53 stores wildcards escaped: http://docs.aws.amazon.com/Route53/latest/DeveloperGuide/DomainNameFormat.html?shortFooter=true#domain-name-format-asterisk
 func wildcardEscape(s string) string {
 	if strings.Contains(s, "*") {
@@ -218,6 +265,19 @@ func (r *Route53APIStub) ListHostedZonesPagesWithContext(ctx context.Context, in
 	return nil
 }
 
+func (r *Route53APIStub) ListHostedZonesByNameWithContext(ctx context.Context, input *route53.ListHostedZonesByNameInput, opts ...request.Option) (*route53.ListHostedZonesByNameOutput, error) {
+	output := &route53.ListHostedZonesByNameOutput{}
+	for _, zone := range r.zones {
+		if aws.StringValue(zone.Name) >= aws.StringValue(input.DNSName) {
+			output.HostedZones = append(output.HostedZones, zone)
+		}
+	}
+	sort.Slice(output.HostedZones, func(i, j int) bool {
+		return aws.StringValue(output.HostedZones[i].Name) < aws.StringValue(output.HostedZones[j].Name)
+	})
+	return output, nil
+}
+
 func (r *Route53APIStub) CreateHostedZoneWithContext(ctx context.Context, input *route53.CreateHostedZoneInput, opts ...request.Option) (*route53.CreateHostedZoneOutput, error) {
 	name := aws.StringValue(input.Name)
 	id := "/hostedzone/" + name
@@ -229,7 +289,93 @@ func (r *Route53APIStub) CreateHostedZoneWithContext(ctx context.Context, input
 		Name:   aws.String(name),
 		Config: input.HostedZoneConfig,
 	}
-	return &route53.CreateHostedZoneOutput{HostedZone: r.zones[id]}, nil
+	return &route53.CreateHostedZoneOutput{HostedZone: r.zones[id], DelegationSet: stubDelegationSet}, nil
+}
+
+// stubDelegationSet holds the name servers of every zone of the stub
+var stubDelegationSet = &route53.DelegationSet{NameServers: aws.StringSlice([]string{"ns-1.awsdns-01.com", "ns-2.awsdns-02.net"})}
+
+func (r *Route53APIStub) GetHostedZoneWithContext(ctx context.Context, input *route53.GetHostedZoneInput, opts ...request.Option) (*route53.GetHostedZoneOutput, error) {
+	id := "/hostedzone/" + aws.StringValue(input.Id)
+	zone, ok := r.zones[id]
+	if !ok {
+		return nil, fmt.Errorf("Hosted zone doesn't exist: %s", id)
+	}
+	return &route53.GetHostedZoneOutput{HostedZone: zone, DelegationSet: stubDelegationSet, VPCs: r.zoneVPCs[id]}, nil
+}
+
+func (r *Route53APIStub) AssociateVPCWithHostedZoneWithContext(ctx context.Context, input *route53.AssociateVPCWithHostedZoneInput, opts ...request.Option) (*route53.AssociateVPCWithHostedZoneOutput, error) {
+	id := "/hostedzone/" + aws.StringValue(input.HostedZoneId)
+	if _, ok := r.zones[id]; !ok {
+		return nil, fmt.Errorf("Hosted zone doesn't exist: %s", id)
+	}
+	r.zoneVPCs[id] = append(r.zoneVPCs[id], input.VPC)
+	return &route53.AssociateVPCWithHostedZoneOutput{}, nil
+}
+
+func (r *Route53APIStub) ListHealthChecksPagesWithContext(ctx context.Context, input *route53.ListHealthChecksInput, fn func(p *route53.ListHealthChecksOutput, lastPage bool) (shouldContinue bool), opts ...request.Option) error {
+	output := &route53.ListHealthChecksOutput{}
+	for _, hc := range r.healthChecks {
+		output.HealthChecks = append(output.HealthChecks, hc)
+	}
+	lastPage := true
+	fn(output, lastPage)
+	return nil
+}
+
+func (r *Route53APIStub) CreateHealthCheckWithContext(ctx context.Context, input *route53.CreateHealthCheckInput, opts ...request.Option) (*route53.CreateHealthCheckOutput, error) {
+	for _, hc := range r.healthChecks {
+		if aws.StringValue(hc.CallerReference) == aws.StringValue(input.CallerReference) {
+			return nil, fmt.Errorf("Health check with caller reference %s already exists", aws.StringValue(input.CallerReference))
+		}
+	}
+	id := fmt.Sprintf("health-check-%d", len(r.healthChecks)+1)
+	for n := len(r.healthChecks) + 2; r.healthChecks[id] != nil; n++ {
+		id = fmt.Sprintf("health-check-%d", n)
+	}
+	r.healthChecks[id] = &route53.HealthCheck{
+		Id:                aws.String(id),
+		CallerReference:   input.CallerReference,
+		HealthCheckConfig: input.HealthCheckConfig,
+	}
+	return &route53.CreateHealthCheckOutput{HealthCheck: r.healthChecks[id]}, nil
+}
+
+func (r *Route53APIStub) DeleteHealthCheckWithContext(ctx context.Context, input *route53.DeleteHealthCheckInput, opts ...request.Option) (*route53.DeleteHealthCheckOutput, error) {
+	id := aws.StringValue(input.HealthCheckId)
+	if _, ok := r.healthChecks[id]; !ok {
+		return nil, fmt.Errorf("Health check doesn't exist: %s", id)
+	}
+	delete(r.healthChecks, id)
+	delete(r.healthCheckTags, id)
+	return &route53.DeleteHealthCheckOutput{}, nil
+}
+
+func (r *Route53APIStub) ChangeTagsForResourceWithContext(ctx context.Context, input *route53.ChangeTagsForResourceInput, opts ...request.Option) (*route53.ChangeTagsForResourceOutput, error) {
+	if aws.StringValue(input.ResourceType) != route53.TagResourceTypeHealthcheck {
+		return nil, fmt.Errorf("Tagging %s resources isn't supported", aws.StringValue(input.ResourceType))
+	}
+	id := aws.StringValue(input.ResourceId)
+	if _, ok := r.healthChecks[id]; !ok {
+		return nil, fmt.Errorf("Health check doesn't exist: %s", id)
+	}
+	r.healthCheckTags[id] = append(r.healthCheckTags[id], input.AddTags...)
+	return &route53.ChangeTagsForResourceOutput{}, nil
+}
+
+func (r *Route53APIStub) ListTagsForResourcesWithContext(ctx context.Context, input *route53.ListTagsForResourcesInput, opts ...request.Option) (*route53.ListTagsForResourcesOutput, error) {
+	if len(input.ResourceIds) > 10 {
+		return nil, fmt.Errorf("At most 10 resources can be listed, got %d", len(input.ResourceIds))
+	}
+	output := &route53.ListTagsForResourcesOutput{}
+	for _, id := range input.ResourceIds {
+		output.ResourceTagSets = append(output.ResourceTagSets, &route53.ResourceTagSet{
+			ResourceId:   id,
+			ResourceType: input.ResourceType,
+			Tags:         r.healthCheckTags[aws.StringValue(id)],
+		})
+	}
+	return output, nil
 }
 
 type dynamicMock struct {
@@ -318,6 +464,7 @@ func TestAWSRecords(t *testing.T) {
 		endpoint.NewEndpoint("list-test-alias-evaluate.zone-1.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeCNAME, "foo.eu-central-1.elb.amazonaws.com").WithProviderSpecific(providerSpecificEvaluateTargetHealth, "true"),
 		endpoint.NewEndpointWithTTL("list-test-multiple.zone-1.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeA, endpoint.TTL(recordTTL), "8.8.8.8", "8.8.4.4"),
 		endpoint.NewEndpointWithTTL("prefix-*.wildcard.zone-1.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeTXT, endpoint.TTL(recordTTL), "random"),
+		endpoint.NewEndpointWithTTL("zone-1.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeCAA, endpoint.TTL(recordTTL), "0 issue \"letsencrypt.org\""),
 		endpoint.NewEndpointWithTTL("weight-test.zone-1.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeA, endpoint.TTL(recordTTL), "1.2.3.4").WithSetIdentifier("test-set-1").WithProviderSpecific(providerSpecificWeight, "10"),
 		endpoint.NewEndpointWithTTL("weight-test.zone-1.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeA, endpoint.TTL(recordTTL), "4.3.2.1").WithSetIdentifier("test-set-2").WithProviderSpecific(providerSpecificWeight, "20"),
 		endpoint.NewEndpointWithTTL("latency-test.zone-1.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeA, endpoint.TTL(recordTTL), "1.2.3.4").WithSetIdentifier("test-set").WithProviderSpecific(providerSpecificRegion, "us-east-1"),
@@ -339,6 +486,7 @@ func TestAWSRecords(t *testing.T) {
 		endpoint.NewEndpointWithTTL("list-test-alias-evaluate.zone-1.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeCNAME, endpoint.TTL(recordTTL), "foo.eu-central-1.elb.amazonaws.com").WithProviderSpecific(providerSpecificEvaluateTargetHealth, "true"),
 		endpoint.NewEndpointWithTTL("list-test-multiple.zone-1.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeA, endpoint.TTL(recordTTL), "8.8.8.8", "8.8.4.4"),
 		endpoint.NewEndpointWithTTL("prefix-*.wildcard.zone-1.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeTXT, endpoint.TTL(recordTTL), "random"),
+		endpoint.NewEndpointWithTTL("zone-1.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeCAA, endpoint.TTL(recordTTL), "0 issue \"letsencrypt.org\""),
 		endpoint.NewEndpointWithTTL("weight-test.zone-1.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeA, endpoint.TTL(recordTTL), "1.2.3.4").WithSetIdentifier("test-set-1").WithProviderSpecific(providerSpecificWeight, "10"),
 		endpoint.NewEndpointWithTTL("weight-test.zone-1.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeA, endpoint.TTL(recordTTL), "4.3.2.1").WithSetIdentifier("test-set-2").WithProviderSpecific(providerSpecificWeight, "20"),
 		endpoint.NewEndpointWithTTL("latency-test.zone-1.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeA, endpoint.TTL(recordTTL), "1.2.3.4").WithSetIdentifier("test-set").WithProviderSpecific(providerSpecificRegion, "us-east-1"),
@@ -349,6 +497,36 @@ func TestAWSRecords(t *testing.T) {
 	})
 }
 
+func TestAWSRecordsPages(t *testing.T) {
+	provider, _ := newAWSProvider(t, NewDomainFilter([]string{"ext-dns-test-2.teapot.zalan.do."}), NewZoneIDFilter([]string{}), NewZoneTypeFilter(""), false, false, []*endpoint.Endpoint{
+		endpoint.NewEndpointWithTTL("list-test.zone-1.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeA, endpoint.TTL(recordTTL), "1.2.3.4"),
+		endpoint.NewEndpointWithTTL("list-test.zone-2.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeA, endpoint.TTL(recordTTL), "8.8.8.8"),
+	})
+
+	// the stub returns the record sets of each zone as a single page
+	zones, err := provider.Zones(context.Background())
+	require.NoError(t, err)
+	var records []*endpoint.Endpoint
+	pages := 0
+	require.NoError(t, provider.RecordsPages(context.Background(), func(page []*endpoint.Endpoint) bool {
+		pages++
+		records = append(records, page...)
+		return true
+	}))
+	assert.Equal(t, len(zones), pages)
+	validateEndpoints(t, records, []*endpoint.Endpoint{
+		endpoint.NewEndpointWithTTL("list-test.zone-1.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeA, endpoint.TTL(recordTTL), "1.2.3.4"),
+		endpoint.NewEndpointWithTTL("list-test.zone-2.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeA, endpoint.TTL(recordTTL), "8.8.8.8"),
+	})
+
+	pages = 0
+	require.NoError(t, provider.RecordsPages(context.Background(), func(page []*endpoint.Endpoint) bool {
+		pages++
+		return false
+	}))
+	assert.Equal(t, 1, pages)
+}
+
 func TestAWSCreateRecords(t *testing.T) {
 	customTTL := endpoint.TTL(60)
 	provider, _ := newAWSProvider(t, NewDomainFilter([]string{"ext-dns-test-2.teapot.zalan.do."}), NewZoneIDFilter([]string{}), NewZoneTypeFilter(""), defaultEvaluateTargetHealth, false, []*endpoint.Endpoint{})
@@ -375,6 +553,24 @@ func TestAWSCreateRecords(t *testing.T) {
 	})
 }
 
+// TestAWSCreateRecordsDualStack tests that the AAAA records of a dual-stack name are listed again, so they
+// aren't created on every synchronization.
+func TestAWSCreateRecordsDualStack(t *testing.T) {
+	provider, _ := newAWSProvider(t, NewDomainFilter([]string{"ext-dns-test-2.teapot.zalan.do."}), NewZoneIDFilter([]string{}), NewZoneTypeFilter(""), defaultEvaluateTargetHealth, false, []*endpoint.Endpoint{})
+
+	require.NoError(t, provider.ApplyChanges(context.Background(), &plan.Changes{Create: []*endpoint.Endpoint{
+		endpoint.NewEndpoint("dual.zone-1.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeA, "1.2.3.4"),
+		endpoint.NewEndpoint("dual.zone-1.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeAAAA, "2001:db8::1"),
+	}}))
+
+	records, err := provider.Records(context.Background())
+	require.NoError(t, err)
+	validateEndpoints(t, records, []*endpoint.Endpoint{
+		endpoint.NewEndpointWithTTL("dual.zone-1.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeA, endpoint.TTL(recordTTL), "1.2.3.4"),
+		endpoint.NewEndpointWithTTL("dual.zone-1.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeAAAA, endpoint.TTL(recordTTL), "2001:db8::1"),
+	})
+}
+
 func TestAWSUpdateRecords(t *testing.T) {
 	provider, _ := newAWSProvider(t, NewDomainFilter([]string{"ext-dns-test-2.teapot.zalan.do."}), NewZoneIDFilter([]string{}), NewZoneTypeFilter(""), defaultEvaluateTargetHealth, false, []*endpoint.Endpoint{
 		endpoint.NewEndpointWithTTL("update-test.zone-1.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeA, endpoint.TTL(recordTTL), "8.8.8.8"),
@@ -524,6 +720,141 @@ func TestAWSApplyChanges(t *testing.T) {
 	}
 }
 
+func TestAWSHealthChecks(t *testing.T) {
+	provider, client := newAWSProvider(t, NewDomainFilter([]string{"ext-dns-test-2.teapot.zalan.do."}), NewZoneIDFilter([]string{}), NewZoneTypeFilter(""), defaultEvaluateTargetHealth, false, []*endpoint.Endpoint{})
+	client.healthChecks["unmanaged"] = &route53.HealthCheck{
+		Id:                aws.String("unmanaged"),
+		CallerReference:   aws.String("console"),
+		HealthCheckConfig: &route53.HealthCheckConfig{Type: aws.String("TCP"), IPAddress: aws.String("8.8.8.8"), Port: aws.Int64(53)},
+	}
+
+	healthCheck := &endpoint.HealthCheck{Protocol: endpoint.HealthCheckProtocolHTTP, Port: 80, Path: "/healthz", Interval: 30, FailureThreshold: 3}
+	desired := []*endpoint.Endpoint{
+		endpoint.NewEndpoint("health-check.zone-1.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeA, "1.2.3.4").WithHealthCheck(healthCheck),
+		endpoint.NewEndpoint("health-check-cname.zone-1.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeCNAME, "foo.example.com").WithHealthCheck(healthCheck),
+		endpoint.NewEndpoint("health-check-id.zone-1.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeA, "8.8.8.8").WithProviderSpecific(providerSpecificHealthCheckID, "unmanaged"),
+	}
+	require.NoError(t, provider.ApplyChanges(context.Background(), &plan.Changes{Create: desired}))
+
+	require.Len(t, client.healthChecks, 3)
+	var configs []string
+	for _, hc := range client.healthChecks {
+		if managedRoute53HealthCheck(hc) {
+			configs = append(configs, route53HealthCheckString(hc.HealthCheckConfig))
+		}
+	}
+	assert.ElementsMatch(t, []string{
+		"HTTP://health-check.zone-1.ext-dns-test-2.teapot.zalan.do[1.2.3.4]:80/healthz (interval 30s, failure threshold 3)",
+		"HTTP://foo.example.com[]:80/healthz (interval 30s, failure threshold 3)",
+	}, configs)
+
+	records, err := provider.Records(context.Background())
+	require.NoError(t, err)
+	validateEndpoints(t, records, []*endpoint.Endpoint{
+		endpoint.NewEndpointWithTTL("health-check.zone-1.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeA, endpoint.TTL(recordTTL), "1.2.3.4").WithHealthCheck(healthCheck),
+		endpoint.NewEndpointWithTTL("health-check-cname.zone-1.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeCNAME, endpoint.TTL(recordTTL), "foo.example.com").WithHealthCheck(healthCheck),
+		endpoint.NewEndpointWithTTL("health-check-id.zone-1.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeA, endpoint.TTL(recordTTL), "8.8.8.8").WithProviderSpecific(providerSpecificHealthCheckID, "unmanaged"),
+	})
+
+	require.NoError(t, provider.ApplyChanges(context.Background(), &plan.Changes{Delete: records}))
+
+	records, err = provider.Records(context.Background())
+	require.NoError(t, err)
+	validateEndpoints(t, records, []*endpoint.Endpoint{})
+	assert.Len(t, client.healthChecks, 1)
+	assert.Contains(t, client.healthChecks, "unmanaged")
+}
+
+func TestAWSOrphanedHealthChecks(t *testing.T) {
+	provider, client := newAWSProvider(t, NewDomainFilter([]string{"ext-dns-test-2.teapot.zalan.do."}), NewZoneIDFilter([]string{}), NewZoneTypeFilter(""), defaultEvaluateTargetHealth, false, []*endpoint.Endpoint{})
+	provider.healthCheckOwnerID = "owner"
+	provider.cleanUpOrphanedHealthChecks = true
+
+	healthCheck := &endpoint.HealthCheck{Protocol: endpoint.HealthCheckProtocolTCP, Port: 5432, Interval: 30, FailureThreshold: 3}
+	ctx := context.Background()
+	_, err := provider.Records(ctx)
+	require.NoError(t, err)
+	require.NoError(t, provider.ApplyChanges(ctx, &plan.Changes{Create: []*endpoint.Endpoint{
+		endpoint.NewEndpoint("db.zone-1.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeA, "1.2.3.4").WithHealthCheck(healthCheck),
+		endpoint.NewEndpoint("orphan.zone-1.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeA, "4.3.2.1").WithHealthCheck(healthCheck),
+	}}))
+	require.Len(t, client.healthChecks, 2)
+	for id := range client.healthChecks {
+		assert.Equal(t, []*route53.Tag{
+			{Key: aws.String(healthCheckOwnerTagKey), Value: aws.String("owner")},
+			{Key: aws.String(healthCheckHostedZoneTagKey), Value: aws.String("/hostedzone/zone-1.ext-dns-test-2.teapot.zalan.do.")},
+		}, client.healthCheckTags[id])
+	}
+
+	// the record of a health check is deleted outside of ExternalDNS
+	delete(client.recordSets["/hostedzone/zone-1.ext-dns-test-2.teapot.zalan.do."], "orphan.zone-1.ext-dns-test-2.teapot.zalan.do.::A::")
+	// health checks of other owners and untagged ones are kept
+	client.healthChecks["other"] = &route53.HealthCheck{
+		Id:                aws.String("other"),
+		CallerReference:   aws.String(healthCheckCallerReferencePrefix + provider.healthCheckScope("/hostedzone/zone-1.ext-dns-test-2.teapot.zalan.do.") + "-other"),
+		HealthCheckConfig: &route53.HealthCheckConfig{Type: aws.String("TCP"), IPAddress: aws.String("8.8.8.8"), Port: aws.Int64(53)},
+	}
+	client.healthCheckTags["other"] = []*route53.Tag{{Key: aws.String(healthCheckOwnerTagKey), Value: aws.String("other")}}
+	// health checks of the owner in zones of other instances are kept
+	otherZone := "/hostedzone/zone-4.ext-dns-test-3.teapot.zalan.do."
+	client.healthChecks["other-zone"] = &route53.HealthCheck{
+		Id:                aws.String("other-zone"),
+		CallerReference:   aws.String(healthCheckCallerReferencePrefix + provider.healthCheckScope(otherZone) + "-1-0"),
+		HealthCheckConfig: &route53.HealthCheckConfig{Type: aws.String("TCP"), IPAddress: aws.String("9.9.9.9"), Port: aws.Int64(53)},
+	}
+	client.healthCheckTags["other-zone"] = []*route53.Tag{
+		{Key: aws.String(healthCheckOwnerTagKey), Value: aws.String("owner")},
+		{Key: aws.String(healthCheckHostedZoneTagKey), Value: aws.String(otherZone)},
+	}
+	client.healthChecks["untagged"] = &route53.HealthCheck{
+		Id:                aws.String("untagged"),
+		CallerReference:   aws.String(healthCheckCallerReferencePrefix + provider.healthCheckScope("/hostedzone/zone-1.ext-dns-test-2.teapot.zalan.do.") + "-untagged"),
+		HealthCheckConfig: &route53.HealthCheckConfig{Type: aws.String("TCP"), IPAddress: aws.String("8.8.4.4"), Port: aws.Int64(53)},
+	}
+
+	records, err := provider.Records(ctx)
+	require.NoError(t, err)
+	validateEndpoints(t, records, []*endpoint.Endpoint{
+		endpoint.NewEndpointWithTTL("db.zone-1.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeA, endpoint.TTL(recordTTL), "1.2.3.4").WithHealthCheck(healthCheck),
+	})
+	require.NoError(t, provider.ApplyChanges(ctx, &plan.Changes{Create: []*endpoint.Endpoint{
+		endpoint.NewEndpoint("new.zone-1.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeA, "8.8.8.8"),
+	}}))
+
+	var remaining []string
+	for _, hc := range client.healthChecks {
+		remaining = append(remaining, route53HealthCheckString(hc.HealthCheckConfig))
+	}
+	assert.ElementsMatch(t, []string{
+		"TCP://[1.2.3.4]:5432 (interval 30s, failure threshold 3)",
+		"TCP://[8.8.8.8]:53 (interval 0s, failure threshold 0)",
+		"TCP://[9.9.9.9]:53 (interval 0s, failure threshold 0)",
+		"TCP://[8.8.4.4]:53 (interval 0s, failure threshold 0)",
+	}, remaining)
+}
+
+func TestAWSOrphanedHealthChecksDisabled(t *testing.T) {
+	provider, client := newAWSProvider(t, NewDomainFilter([]string{"ext-dns-test-2.teapot.zalan.do."}), NewZoneIDFilter([]string{}), NewZoneTypeFilter(""), defaultEvaluateTargetHealth, false, []*endpoint.Endpoint{})
+	provider.healthCheckOwnerID = "owner"
+
+	healthCheck := &endpoint.HealthCheck{Protocol: endpoint.HealthCheckProtocolTCP, Port: 5432, Interval: 30, FailureThreshold: 3}
+	ctx := context.Background()
+	_, err := provider.Records(ctx)
+	require.NoError(t, err)
+	require.NoError(t, provider.ApplyChanges(ctx, &plan.Changes{Create: []*endpoint.Endpoint{
+		endpoint.NewEndpoint("orphan.zone-1.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeA, "4.3.2.1").WithHealthCheck(healthCheck),
+	}}))
+	require.Len(t, client.healthChecks, 1)
+
+	delete(client.recordSets["/hostedzone/zone-1.ext-dns-test-2.teapot.zalan.do."], "orphan.zone-1.ext-dns-test-2.teapot.zalan.do.::A::")
+	_, err = provider.Records(ctx)
+	require.NoError(t, err)
+	require.NoError(t, provider.ApplyChanges(ctx, &plan.Changes{Create: []*endpoint.Endpoint{
+		endpoint.NewEndpoint("new.zone-1.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeA, "8.8.8.8"),
+	}}))
+	assert.Len(t, client.healthChecks, 1)
+}
+
 func TestAWSApplyChangesDryRun(t *testing.T) {
 	originalEndpoints := []*endpoint.Endpoint{
 		endpoint.NewEndpointWithTTL("update-test.zone-1.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeA, endpoint.TTL(recordTTL), "8.8.8.8"),
@@ -706,7 +1037,7 @@ func TestAWSsubmitChanges(t *testing.T) {
 	cs := make([]*route53.Change, 0, len(endpoints))
 	cs = append(cs, provider.newChanges(route53.ChangeActionCreate, endpoints, records, zones)...)
 
-	require.NoError(t, provider.submitChanges(ctx, cs, zones))
+	require.NoError(t, provider.submitChanges(ctx, cs, zones, nil))
 
 	records, err := provider.Records(ctx)
 	require.NoError(t, err)
@@ -727,7 +1058,9 @@ func TestAWSsubmitChangesError(t *testing.T) {
 	ep := endpoint.NewEndpointWithTTL("fail.zone-1.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeA, endpoint.TTL(recordTTL), "1.0.0.1")
 	cs := provider.newChanges(route53.ChangeActionCreate, []*endpoint.Endpoint{ep}, records, zones)
 
-	require.Error(t, provider.submitChanges(ctx, cs, zones))
+	err = provider.submitChanges(ctx, cs, zones, nil)
+	require.IsType(t, ZoneErrors{}, err)
+	assert.Equal(t, []string{"zone-1.ext-dns-test-2.teapot.zalan.do."}, err.(ZoneErrors).Zones())
 }
 
 func TestAWSBatchChangeSet(t *testing.T) {
@@ -750,7 +1083,7 @@ func TestAWSBatchChangeSet(t *testing.T) {
 		})
 	}
 
-	batchCs := batchChangeSet(cs, defaultBatchChangeSize)
+	batchCs := batchChangeSet(cs, defaultBatchChangeSize, nil)
 
 	require.Equal(t, 1, len(batchCs))
 
@@ -782,7 +1115,7 @@ func TestAWSBatchChangeSetExceeding(t *testing.T) {
 		})
 	}
 
-	batchCs := batchChangeSet(cs, testLimit)
+	batchCs := batchChangeSet(cs, testLimit, nil)
 
 	require.Equal(t, expectedBatchCount, len(batchCs))
 
@@ -814,11 +1147,59 @@ func TestAWSBatchChangeSetExceedingNameChange(t *testing.T) {
 		})
 	}
 
-	batchCs := batchChangeSet(cs, testLimit)
+	batchCs := batchChangeSet(cs, testLimit, nil)
 
 	require.Equal(t, 0, len(batchCs))
 }
 
+func TestAWSBatchChangeSetOwnershipRecords(t *testing.T) {
+	var cs []*route53.Change
+	const testCount = 10
+	const testLimit = 4
+
+	ownedNames := map[string]string{}
+	for i := 1; i <= testCount; i++ {
+		cs = append(cs, &route53.Change{
+			Action: aws.String(route53.ChangeActionCreate),
+			ResourceRecordSet: &route53.ResourceRecordSet{
+				Name: aws.String(fmt.Sprintf("host-%d", i)),
+				Type: aws.String("A"),
+			},
+		})
+		cs = append(cs, &route53.Change{
+			Action: aws.String(route53.ChangeActionCreate),
+			ResourceRecordSet: &route53.ResourceRecordSet{
+				Name: aws.String(fmt.Sprintf("txt-host-%d", i)),
+				Type: aws.String("TXT"),
+			},
+		})
+		ownedNames[fmt.Sprintf("txt-host-%d", i)] = fmt.Sprintf("host-%d", i)
+	}
+
+	batchCs := batchChangeSet(cs, testLimit, ownedNames)
+
+	require.Len(t, batchCs, testCount/2)
+	for _, batch := range batchCs {
+		names := map[string]bool{}
+		for _, c := range batch {
+			names[aws.StringValue(c.ResourceRecordSet.Name)] = true
+		}
+		for txtName, owned := range ownedNames {
+			assert.Equal(t, names[owned], names[txtName], "%s must be in the same batch as %s", txtName, owned)
+		}
+	}
+}
+
+func TestAWSOwnedRecordNames(t *testing.T) {
+	owned := endpoint.NewEndpoint("foo.example.org", endpoint.RecordTypeA, "1.2.3.4")
+	txt := endpoint.NewEndpoint("txt-foo.example.org", endpoint.RecordTypeTXT, "\"heritage=external-dns\"")
+	txt.Labels[endpoint.OwnedRecordLabelKey] = "foo.example.org"
+	sameName := endpoint.NewEndpoint("bar.example.org", endpoint.RecordTypeTXT, "\"heritage=external-dns\"")
+	sameName.Labels[endpoint.OwnedRecordLabelKey] = "bar.example.org"
+
+	assert.Equal(t, map[string]string{"txt-foo.example.org": "foo.example.org"}, ownedRecordNames([]*endpoint.Endpoint{owned, txt}, []*endpoint.Endpoint{sameName}))
+}
+
 func validateEndpoints(t *testing.T, endpoints []*endpoint.Endpoint, expected []*endpoint.Endpoint) {
 	assert.True(t, testutils.SameEndpoints(endpoints, expected), "actual and expected endpoints don't match. %s:%s", endpoints, expected)
 }
@@ -850,6 +1231,114 @@ func validateAWSChangeRecord(t *testing.T, record *route53.Change, expected *rou
 	assert.Equal(t, aws.StringValue(expected.ResourceRecordSet.Type), aws.StringValue(record.ResourceRecordSet.Type))
 }
 
+func TestAWSAssociatePrivateZoneVPCs(t *testing.T) {
+	provider, client := newAWSProvider(t, NewDomainFilter([]string{"ext-dns-test-2.teapot.zalan.do."}), NewZoneIDFilter([]string{}), NewZoneTypeFilter(""), defaultEvaluateTargetHealth, false, []*endpoint.Endpoint{})
+	vpcs, err := parseRoute53VPCs([]string{"eu-central-1:vpc-1", "eu-west-1:vpc-2"})
+	require.NoError(t, err)
+	provider.privateZoneVPCs = vpcs
+	client.zoneVPCs["/hostedzone/zone-3.ext-dns-test-2.teapot.zalan.do."] = []*route53.VPC{vpcs[0]}
+	counter := NewRoute53APICounter(client)
+	provider.client = counter
+
+	require.NoError(t, provider.CreateRecords(context.Background(), []*endpoint.Endpoint{
+		endpoint.NewEndpoint("create-test.zone-1.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeA, "1.2.3.4"),
+		endpoint.NewEndpoint("create-test.zone-3.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeA, "1.2.3.4"),
+	}))
+	assert.Equal(t, vpcs, client.zoneVPCs["/hostedzone/zone-3.ext-dns-test-2.teapot.zalan.do."])
+	assert.Empty(t, client.zoneVPCs["/hostedzone/zone-1.ext-dns-test-2.teapot.zalan.do."])
+	assert.Equal(t, 1, counter.calls["GetHostedZone"])
+	assert.Equal(t, 1, counter.calls["AssociateVPCWithHostedZone"])
+
+	// the associations of a zone are only checked once within the recheck interval
+	require.NoError(t, provider.CreateRecords(context.Background(), []*endpoint.Endpoint{
+		endpoint.NewEndpoint("create-test-2.zone-3.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeA, "1.2.3.4"),
+	}))
+	assert.Equal(t, 1, counter.calls["GetHostedZone"])
+	assert.Equal(t, 1, counter.calls["AssociateVPCWithHostedZone"])
+
+	// an association removed by hand is restored once the interval passed
+	client.zoneVPCs["/hostedzone/zone-3.ext-dns-test-2.teapot.zalan.do."] = []*route53.VPC{vpcs[0]}
+	provider.associatedZones["/hostedzone/zone-3.ext-dns-test-2.teapot.zalan.do."] = time.Now().Add(-privateZoneVPCsRecheckInterval)
+	require.NoError(t, provider.CreateRecords(context.Background(), []*endpoint.Endpoint{
+		endpoint.NewEndpoint("create-test-3.zone-3.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeA, "1.2.3.4"),
+	}))
+	assert.Equal(t, 2, counter.calls["GetHostedZone"])
+	assert.Equal(t, 2, counter.calls["AssociateVPCWithHostedZone"])
+	assert.Equal(t, vpcs, client.zoneVPCs["/hostedzone/zone-3.ext-dns-test-2.teapot.zalan.do."])
+}
+
+func TestAWSAssociatePrivateZoneVPCsDryRun(t *testing.T) {
+	provider, client := newAWSProvider(t, NewDomainFilter([]string{"ext-dns-test-2.teapot.zalan.do."}), NewZoneIDFilter([]string{}), NewZoneTypeFilter(""), defaultEvaluateTargetHealth, false, []*endpoint.Endpoint{})
+	vpcs, err := parseRoute53VPCs([]string{"eu-central-1:vpc-1"})
+	require.NoError(t, err)
+	provider.privateZoneVPCs = vpcs
+	provider.dryRun = true
+
+	require.NoError(t, provider.CreateRecords(context.Background(), []*endpoint.Endpoint{
+		endpoint.NewEndpoint("create-test.zone-3.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeA, "1.2.3.4"),
+	}))
+	assert.Empty(t, client.zoneVPCs["/hostedzone/zone-3.ext-dns-test-2.teapot.zalan.do."])
+}
+
+func TestAWSDelegateZone(t *testing.T) {
+	provider, client := newAWSProvider(t, NewDomainFilter([]string{"ext-dns-test-2.teapot.zalan.do."}), NewZoneIDFilter([]string{}), NewZoneTypeFilter(""), defaultEvaluateTargetHealth, false, []*endpoint.Endpoint{})
+
+	require.NoError(t, provider.DelegateZone(context.Background(), "team-a.zone-1.ext-dns-test-2.teapot.zalan.do"))
+	zone, ok := client.zones["/hostedzone/team-a.zone-1.ext-dns-test-2.teapot.zalan.do."]
+	require.True(t, ok)
+	assert.False(t, aws.BoolValue(zone.Config.PrivateZone))
+
+	expected := []*route53.ResourceRecordSet{
+		{
+			Name: aws.String("team-a.zone-1.ext-dns-test-2.teapot.zalan.do."),
+			Type: aws.String(route53.RRTypeNs),
+			TTL:  aws.Int64(recordTTL),
+			ResourceRecords: []*route53.ResourceRecord{
+				{Value: aws.String("ns-1.awsdns-01.com.")},
+				{Value: aws.String("ns-2.awsdns-02.net.")},
+			},
+		},
+	}
+	validateRecords(t, listAWSRecords(t, provider.client, "/hostedzone/zone-1.ext-dns-test-2.teapot.zalan.do."), expected)
+
+	// delegating an existing zone only upserts the NS record
+	require.NoError(t, provider.DelegateZone(context.Background(), "team-a.zone-1.ext-dns-test-2.teapot.zalan.do."))
+	validateRecords(t, listAWSRecords(t, provider.client, "/hostedzone/zone-1.ext-dns-test-2.teapot.zalan.do."), expected)
+
+	// existing zones outside of the filters aren't created again
+	provider.zoneIDFilter = NewZoneIDFilter([]string{"/hostedzone/zone-1.ext-dns-test-2.teapot.zalan.do."})
+	zones := len(client.zones)
+	require.NoError(t, provider.DelegateZone(context.Background(), "team-a.zone-1.ext-dns-test-2.teapot.zalan.do"))
+	assert.Len(t, client.zones, zones)
+	validateRecords(t, listAWSRecords(t, provider.client, "/hostedzone/zone-1.ext-dns-test-2.teapot.zalan.do."), expected)
+	provider.zoneIDFilter = NewZoneIDFilter([]string{})
+
+	assert.EqualError(t, provider.DelegateZone(context.Background(), "team-a.example.org"), "no public parent zone of team-a.example.org. found")
+	// private zones aren't parents
+	assert.EqualError(t, provider.DelegateZone(context.Background(), "team-a.zone-3.ext-dns-test-2.teapot.zalan.do"), "no public parent zone of team-a.zone-3.ext-dns-test-2.teapot.zalan.do. found")
+}
+
+func TestAWSDelegateZoneDryRun(t *testing.T) {
+	provider, client := newAWSProvider(t, NewDomainFilter([]string{"ext-dns-test-2.teapot.zalan.do."}), NewZoneIDFilter([]string{}), NewZoneTypeFilter(""), defaultEvaluateTargetHealth, false, []*endpoint.Endpoint{})
+	provider.dryRun = true
+
+	require.NoError(t, provider.DelegateZone(context.Background(), "team-a.zone-1.ext-dns-test-2.teapot.zalan.do"))
+	_, ok := client.zones["/hostedzone/team-a.zone-1.ext-dns-test-2.teapot.zalan.do."]
+	assert.False(t, ok)
+	assert.Empty(t, listAWSRecords(t, provider.client, "/hostedzone/zone-1.ext-dns-test-2.teapot.zalan.do."))
+}
+
+func TestAWSParseRoute53VPCs(t *testing.T) {
+	vpcs, err := parseRoute53VPCs([]string{"us-east-1:vpc-0123456789abcdef0"})
+	require.NoError(t, err)
+	assert.Equal(t, []*route53.VPC{{VPCRegion: aws.String("us-east-1"), VPCId: aws.String("vpc-0123456789abcdef0")}}, vpcs)
+
+	for _, vpc := range []string{"vpc-0123456789abcdef0", "us-east-1:", ":vpc-1", "us-east-1:vpc-1:extra"} {
+		_, err := parseRoute53VPCs([]string{vpc})
+		assert.EqualError(t, err, fmt.Sprintf("invalid VPC (region:vpc-id) found '%v'", vpc))
+	}
+}
+
 func TestAWSCreateRecordsWithCNAME(t *testing.T) {
 	provider, _ := newAWSProvider(t, NewDomainFilter([]string{"ext-dns-test-2.teapot.zalan.do."}), NewZoneIDFilter([]string{}), NewZoneTypeFilter(""), defaultEvaluateTargetHealth, false, []*endpoint.Endpoint{})
 
@@ -1270,3 +1759,65 @@ func addZoneTags(tagMap map[string][]*route53.Tag, zoneID string, tags map[strin
 func validateRecords(t *testing.T, records []*route53.ResourceRecordSet, expected []*route53.ResourceRecordSet) {
 	assert.ElementsMatch(t, expected, records)
 }
+
+func TestAWSValidateProviderSpecific(t *testing.T) {
+	provider := &AWSProvider{}
+
+	for _, tc := range []struct {
+		title    string
+		endpoint *endpoint.Endpoint
+		err      string
+	}{
+		{
+			title:    "weighted record",
+			endpoint: endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeA, "1.2.3.4").WithSetIdentifier("blue").WithProviderSpecific(providerSpecificWeight, "10"),
+		},
+		{
+			title:    "alias record",
+			endpoint: endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeCNAME, "lb.eu-central-1.elb.amazonaws.com").WithProviderSpecific(providerSpecificEvaluateTargetHealth, "false"),
+		},
+		{
+			title:    "properties of other providers",
+			endpoint: endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeA, "1.2.3.4").WithProviderSpecific("external-dns.alpha.kubernetes.io/cloudflare-proxied", "true"),
+		},
+		{
+			title:    "misspelled property",
+			endpoint: endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeA, "1.2.3.4").WithSetIdentifier("blue").WithProviderSpecific("aws/wieght", "10"),
+			err:      "A record app.example.org has unknown provider-specific property aws/wieght, known properties: aws/evaluate-target-health, aws/failover, aws/geolocation-continent-code, aws/geolocation-country-code, aws/geolocation-subdivision-code, aws/health-check-id, aws/multi-value-answer, aws/region, aws/weight",
+		},
+		{
+			title:    "invalid weight",
+			endpoint: endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeA, "1.2.3.4").WithSetIdentifier("blue").WithProviderSpecific(providerSpecificWeight, "heavy"),
+			err:      `A record app.example.org has invalid provider-specific property aws/weight="heavy": not an integer`,
+		},
+		{
+			title:    "invalid failover",
+			endpoint: endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeA, "1.2.3.4").WithSetIdentifier("blue").WithProviderSpecific(providerSpecificFailover, "primary"),
+			err:      `A record app.example.org has invalid provider-specific property aws/failover="primary": not one of PRIMARY, SECONDARY`,
+		},
+		{
+			title:    "routing policy without set identifier",
+			endpoint: endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeA, "1.2.3.4").WithProviderSpecific(providerSpecificWeight, "10"),
+			err:      "A record app.example.org has provider-specific property aws/weight but no set identifier",
+		},
+		{
+			title:    "invalid health check",
+			endpoint: endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeA, "1.2.3.4").WithProviderSpecific(endpoint.HealthCheckProtocolKey, "UDP"),
+			err:      `A record app.example.org has an invalid health check: unsupported health check protocol "UDP"`,
+		},
+		{
+			title:    "unsupported health check interval",
+			endpoint: endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeA, "1.2.3.4").WithHealthCheck(&endpoint.HealthCheck{Protocol: endpoint.HealthCheckProtocolTCP, Port: 22, Interval: 60, FailureThreshold: 3}),
+			err:      "A record app.example.org has an invalid health check interval 60, must be 10 or 30",
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			err := provider.ValidateProviderSpecific(tc.endpoint)
+			if tc.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// axfrProvider is a read-only provider obtaining the records of a zone with a zone transfer (AXFR),
+// optionally signed with TSIG. It's meant for auditing the zone against the desired state when the
+// authoritative server offers zone transfers but the records are written through another mechanism:
+// the differences are logged in every synchronization but never applied.
+type axfrProvider struct {
+	Provider
+	zoneName string
+}
+
+// NewAXFRProvider creates a new read-only provider transferring the given zone from the nameserver.
+func NewAXFRProvider(host string, port int, zoneName string, insecure bool, keyName string, secret string, secretAlg string, domainFilter DomainFilter, actions rfc2136Actions) (Provider, error) {
+	transfer, err := NewRfc2136Provider(host, port, zoneName, insecure, keyName, secret, secretAlg, true, nil, domainFilter, true, actions)
+	if err != nil {
+		return nil, err
+	}
+	return &axfrProvider{
+		Provider: transfer,
+		zoneName: zoneName,
+	}, nil
+}
+
+// ApplyChanges logs the differences between the zone and the desired state without applying them.
+func (p *axfrProvider) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	if len(changes.Create) == 0 && len(changes.UpdateNew) == 0 && len(changes.Delete) == 0 {
+		log.Debugf("Zone %s matches the desired state", p.zoneName)
+		return nil
+	}
+	log.Infof("Zone %s differs from the desired state: %d records missing, %d records outdated and %d records unexpected (read-only, not applied)",
+		p.zoneName, len(changes.Create), len(changes.UpdateNew), len(changes.Delete))
+	return nil
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/internal/testutils"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestAXFRProviderRecords(t *testing.T) {
+	stub := newStub()
+	require.NoError(t, stub.setOutput([]string{
+		"foo.com 3600 IN A 1.1.1.1",
+		"foo.com 3600 IN A 2.2.2.2",
+		"www.foo.com 300 IN CNAME foo.com.",
+	}))
+
+	provider, err := NewAXFRProvider("", 0, "foo.com", false, "key", "secret", "hmac-sha512", DomainFilter{}, stub)
+	require.NoError(t, err)
+
+	recs, err := provider.Records(context.Background())
+	require.NoError(t, err)
+	assert.True(t, testutils.SameEndpoints(recs, []*endpoint.Endpoint{
+		endpoint.NewEndpointWithTTL("foo.com", endpoint.RecordTypeA, 3600, "1.1.1.1", "2.2.2.2"),
+		endpoint.NewEndpointWithTTL("www.foo.com", endpoint.RecordTypeCNAME, 300, "foo.com"),
+	}), "unexpected records: %v", recs)
+}
+
+func TestAXFRProviderApplyChanges(t *testing.T) {
+	stub := newStub()
+	provider, err := NewAXFRProvider("", 0, "foo.com", false, "key", "secret", "hmac-sha512", DomainFilter{}, stub)
+	require.NoError(t, err)
+
+	err = provider.ApplyChanges(context.Background(), &plan.Changes{
+		Create:    []*endpoint.Endpoint{endpoint.NewEndpoint("v1.foo.com", endpoint.RecordTypeA, "1.2.3.4")},
+		UpdateOld: []*endpoint.Endpoint{endpoint.NewEndpoint("v2.foo.com", endpoint.RecordTypeA, "1.2.3.4")},
+		UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpoint("v2.foo.com", endpoint.RecordTypeA, "5.6.7.8")},
+		Delete:    []*endpoint.Endpoint{endpoint.NewEndpoint("v3.foo.com", endpoint.RecordTypeA, "1.2.3.4")},
+	})
+	require.NoError(t, err)
+
+	assert.Empty(t, stub.createMsgs)
+	assert.Empty(t, stub.updateMsgs)
+}
+
+func TestAXFRProviderInvalidTSIGAlgorithm(t *testing.T) {
+	_, err := NewAXFRProvider("", 0, "foo.com", false, "key", "secret", "hmac-unknown", DomainFilter{}, nil)
+	assert.Error(t, err)
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"strings"
 
 	log "github.com/sirupsen/logrus"
@@ -76,9 +77,10 @@ type AzureProvider struct {
 }
 
 // NewAzureProvider creates a new Azure provider.
+// The API requests are sent with the HTTP client, nil for the default sender of the Azure SDK.
 //
 // Returns the provider or an error if a provider could not be created.
-func NewAzureProvider(configFile string, domainFilter DomainFilter, zoneIDFilter ZoneIDFilter, resourceGroup string, userAssignedIdentityClientID string, dryRun bool) (*AzureProvider, error) {
+func NewAzureProvider(configFile string, domainFilter DomainFilter, zoneIDFilter ZoneIDFilter, resourceGroup string, userAssignedIdentityClientID string, dryRun bool, client *http.Client) (*AzureProvider, error) {
 	contents, err := ioutil.ReadFile(configFile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read Azure config file '%s': %v", configFile, err)
@@ -117,6 +119,10 @@ func NewAzureProvider(configFile string, domainFilter DomainFilter, zoneIDFilter
 	zonesClient.Authorizer = autorest.NewBearerAuthorizer(token)
 	recordSetsClient := dns.NewRecordSetsClientWithBaseURI(environment.ResourceManagerEndpoint, cfg.SubscriptionID)
 	recordSetsClient.Authorizer = autorest.NewBearerAuthorizer(token)
+	if client != nil {
+		zonesClient.Sender = client
+		recordSetsClient.Sender = client
+	}
 
 	provider := &AzureProvider{
 		domainFilter:                 domainFilter,
@@ -436,6 +442,19 @@ func (p *AzureProvider) newRecordSet(endpoint *endpoint.Endpoint) (dns.RecordSet
 				ARecords: &aRecords,
 			},
 		}, nil
+	case dns.AAAA:
+		aaaaRecords := make([]dns.AaaaRecord, len(endpoint.Targets))
+		for i, target := range endpoint.Targets {
+			aaaaRecords[i] = dns.AaaaRecord{
+				Ipv6Address: to.StringPtr(target),
+			}
+		}
+		return dns.RecordSet{
+			RecordSetProperties: &dns.RecordSetProperties{
+				TTL:         to.Int64Ptr(ttl),
+				AaaaRecords: &aaaaRecords,
+			},
+		}, nil
 	case dns.CNAME:
 		return dns.RecordSet{
 			RecordSetProperties: &dns.RecordSetProperties{
@@ -487,6 +506,16 @@ func extractAzureTargets(recordSet *dns.RecordSet) []string {
 		return targets
 	}
 
+	// Check for AAAA records
+	aaaaRecords := properties.AaaaRecords
+	if aaaaRecords != nil && len(*aaaaRecords) > 0 && (*aaaaRecords)[0].Ipv6Address != nil {
+		targets := make([]string, len(*aaaaRecords))
+		for i, aaaaRecord := range *aaaaRecords {
+			targets[i] = *aaaaRecord.Ipv6Address
+		}
+		return targets
+	}
+
 	// Check for CNAME records
 	cnameRecord := properties.CnameRecord
 	if cnameRecord != nil && cnameRecord.Cname != nil {
@@ -19,6 +19,7 @@ package provider
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"strings"
 
 	"github.com/Azure/azure-sdk-for-go/profiles/latest/privatedns/mgmt/privatedns"
@@ -56,8 +57,10 @@ type AzurePrivateDNSProvider struct {
 
 // NewAzurePrivateDNSProvider creates a new Azure Private DNS provider.
 //
+// The API requests are sent with the HTTP client, nil for the default sender of the Azure SDK.
+//
 // Returns the provider or an error if a provider could not be created.
-func NewAzurePrivateDNSProvider(domainFilter DomainFilter, zoneIDFilter ZoneIDFilter, resourceGroup string, subscriptionID string, dryRun bool) (*AzurePrivateDNSProvider, error) {
+func NewAzurePrivateDNSProvider(domainFilter DomainFilter, zoneIDFilter ZoneIDFilter, resourceGroup string, subscriptionID string, dryRun bool, client *http.Client) (*AzurePrivateDNSProvider, error) {
 	authorizer, err := auth.NewAuthorizerFromEnvironment()
 	if err != nil {
 		return nil, err
@@ -67,6 +70,10 @@ func NewAzurePrivateDNSProvider(domainFilter DomainFilter, zoneIDFilter ZoneIDFi
 	zonesClient.Authorizer = authorizer
 	recordSetsClient := privatedns.NewRecordSetsClient(subscriptionID)
 	recordSetsClient.Authorizer = authorizer
+	if client != nil {
+		zonesClient.Sender = client
+		recordSetsClient.Sender = client
+	}
 
 	provider := &AzurePrivateDNSProvider{
 		domainFilter:     domainFilter,
@@ -352,6 +359,19 @@ func (p *AzurePrivateDNSProvider) newRecordSet(endpoint *endpoint.Endpoint) (pri
 				ARecords: &aRecords,
 			},
 		}, nil
+	case privatedns.AAAA:
+		aaaaRecords := make([]privatedns.AaaaRecord, len(endpoint.Targets))
+		for i, target := range endpoint.Targets {
+			aaaaRecords[i] = privatedns.AaaaRecord{
+				Ipv6Address: to.StringPtr(target),
+			}
+		}
+		return privatedns.RecordSet{
+			RecordSetProperties: &privatedns.RecordSetProperties{
+				TTL:         to.Int64Ptr(ttl),
+				AaaaRecords: &aaaaRecords,
+			},
+		}, nil
 	case privatedns.CNAME:
 		return privatedns.RecordSet{
 			RecordSetProperties: &privatedns.RecordSetProperties{
@@ -395,6 +415,16 @@ func extractAzurePrivateDNSTargets(recordSet *privatedns.RecordSet) []string {
 		return targets
 	}
 
+	// Check for AAAA records
+	aaaaRecords := properties.AaaaRecords
+	if aaaaRecords != nil && len(*aaaaRecords) > 0 && (*aaaaRecords)[0].Ipv6Address != nil {
+		targets := make([]string, len(*aaaaRecords))
+		for i, aaaaRecord := range *aaaaRecords {
+			targets[i] = *aaaaRecord.Ipv6Address
+		}
+		return targets
+	}
+
 	// Check for CNAME records
 	cnameRecord := properties.CnameRecord
 	if cnameRecord != nil && cnameRecord.Cname != nil {
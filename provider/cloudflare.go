@@ -19,6 +19,7 @@ package provider
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"sort"
 	"strconv"
@@ -41,6 +42,8 @@ const (
 	cloudFlareUpdate = "UPDATE"
 	// defaultCloudFlareRecordTTL 1 = automatic
 	defaultCloudFlareRecordTTL = 1
+	// the seconds a monitor waits for a response, i.e. the timeout of a check
+	cloudFlareMonitorTimeout = 5
 )
 
 var cloudFlareTypeNotSupported = map[string]bool{
@@ -62,6 +65,9 @@ type cloudFlareDNS interface {
 	CreateDNSRecord(zoneID string, rr cloudflare.DNSRecord) (*cloudflare.DNSRecordResponse, error)
 	DeleteDNSRecord(zoneID, recordID string) error
 	UpdateDNSRecord(zoneID, recordID string, rr cloudflare.DNSRecord) error
+	ListLoadBalancerMonitors() ([]cloudflare.LoadBalancerMonitor, error)
+	CreateLoadBalancerMonitor(monitor cloudflare.LoadBalancerMonitor) (cloudflare.LoadBalancerMonitor, error)
+	DeleteLoadBalancerMonitor(monitorID string) error
 }
 
 type zoneService struct {
@@ -98,6 +104,18 @@ func (z zoneService) ListZonesContext(ctx context.Context, opts ...cloudflare.Re
 	return z.service.ListZonesContext(ctx, opts...)
 }
 
+func (z zoneService) ListLoadBalancerMonitors() ([]cloudflare.LoadBalancerMonitor, error) {
+	return z.service.ListLoadBalancerMonitors()
+}
+
+func (z zoneService) CreateLoadBalancerMonitor(monitor cloudflare.LoadBalancerMonitor) (cloudflare.LoadBalancerMonitor, error) {
+	return z.service.CreateLoadBalancerMonitor(monitor)
+}
+
+func (z zoneService) DeleteLoadBalancerMonitor(monitorID string) error {
+	return z.service.DeleteLoadBalancerMonitor(monitorID)
+}
+
 // CloudFlareProvider is an implementation of Provider for CloudFlare DNS.
 type CloudFlareProvider struct {
 	Client cloudFlareDNS
@@ -115,17 +133,22 @@ type cloudFlareChange struct {
 	ResourceRecordSet []cloudflare.DNSRecord
 }
 
-// NewCloudFlareProvider initializes a new CloudFlare DNS based Provider.
-func NewCloudFlareProvider(domainFilter DomainFilter, zoneIDFilter ZoneIDFilter, zonesPerPage int, proxiedByDefault bool, dryRun bool) (*CloudFlareProvider, error) {
+// NewCloudFlareProvider initializes a new CloudFlare DNS based Provider. The API requests are sent with the
+// HTTP client, nil for the default HTTP client.
+func NewCloudFlareProvider(domainFilter DomainFilter, zoneIDFilter ZoneIDFilter, zonesPerPage int, proxiedByDefault bool, dryRun bool, client *http.Client) (*CloudFlareProvider, error) {
 	// initialize via chosen auth method and returns new API object
 	var (
 		config *cloudflare.API
 		err    error
+		opts   []cloudflare.Option
 	)
+	if client != nil {
+		opts = append(opts, cloudflare.HTTPClient(client))
+	}
 	if os.Getenv("CF_API_TOKEN") != "" {
-		config, err = cloudflare.NewWithAPIToken(os.Getenv("CF_API_TOKEN"))
+		config, err = cloudflare.NewWithAPIToken(os.Getenv("CF_API_TOKEN"), opts...)
 	} else {
-		config, err = cloudflare.New(os.Getenv("CF_API_KEY"), os.Getenv("CF_API_EMAIL"))
+		config, err = cloudflare.New(os.Getenv("CF_API_KEY"), os.Getenv("CF_API_EMAIL"), opts...)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize cloudflare provider: %v", err)
@@ -148,10 +171,12 @@ func NewCloudFlareProvider(domainFilter DomainFilter, zoneIDFilter ZoneIDFilter,
 // Zones returns the list of hosted zones.
 func (p *CloudFlareProvider) Zones(ctx context.Context) ([]cloudflare.Zone, error) {
 	result := []cloudflare.Zone{}
-	p.PaginationOptions.Page = 1
+	// the pagination options are copied, so zones can be listed concurrently
+	paginationOptions := p.PaginationOptions
+	paginationOptions.Page = 1
 
 	for {
-		zonesResponse, err := p.Client.ListZonesContext(ctx, cloudflare.WithPagination(p.PaginationOptions))
+		zonesResponse, err := p.Client.ListZonesContext(ctx, cloudflare.WithPagination(paginationOptions))
 		if err != nil {
 			return nil, err
 		}
@@ -166,14 +191,27 @@ func (p *CloudFlareProvider) Zones(ctx context.Context) ([]cloudflare.Zone, erro
 			}
 			result = append(result, zone)
 		}
-		if p.PaginationOptions.Page == zonesResponse.ResultInfo.TotalPages {
+		if paginationOptions.Page == zonesResponse.ResultInfo.TotalPages {
 			break
 		}
-		p.PaginationOptions.Page++
+		paginationOptions.Page++
 	}
 	return result, nil
 }
 
+// ZoneNames returns the names of the zones.
+func (p *CloudFlareProvider) ZoneNames(ctx context.Context) ([]string, error) {
+	zones, err := p.Zones(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(zones))
+	for _, zone := range zones {
+		names = append(names, zone.Name)
+	}
+	return names, nil
+}
+
 // Records returns the list of records.
 func (p *CloudFlareProvider) Records(ctx context.Context) ([]*endpoint.Endpoint, error) {
 	zones, err := p.Zones(ctx)
@@ -182,10 +220,12 @@ func (p *CloudFlareProvider) Records(ctx context.Context) ([]*endpoint.Endpoint,
 	}
 
 	endpoints := []*endpoint.Endpoint{}
+	zoneErrors := ZoneErrors{}
 	for _, zone := range zones {
 		records, err := p.Client.DNSRecords(zone.ID, cloudflare.DNSRecord{})
 		if err != nil {
-			return nil, err
+			zoneErrors[zone.Name] = fmt.Errorf("failed to list the records: %v", err)
+			continue
 		}
 
 		// As CloudFlare does not support "sets" of targets, but instead returns
@@ -194,7 +234,18 @@ func (p *CloudFlareProvider) Records(ctx context.Context) ([]*endpoint.Endpoint,
 		endpoints = append(endpoints, groupByNameAndType(records)...)
 	}
 
-	return endpoints, nil
+	// the monitors require the load balancing permissions, which accounts without health checks may lack
+	monitors, err := p.monitors()
+	if err != nil {
+		log.Debugf("Failed to list the load balancer monitors: %v", err)
+	}
+	for _, ep := range endpoints {
+		if monitor, ok := monitors[healthCheckMonitorName(ep)]; ok {
+			withCloudFlareMonitor(ep, monitor)
+		}
+	}
+
+	return recordsResult(endpoints, zoneErrors)
 }
 
 // ApplyChanges applies a given set of changes in a given zone.
@@ -207,7 +258,135 @@ func (p *CloudFlareProvider) ApplyChanges(ctx context.Context, changes *plan.Cha
 	combinedChanges = append(combinedChanges, newCloudFlareChanges(cloudFlareUpdate, changes.UpdateNew, proxiedByDefault)...)
 	combinedChanges = append(combinedChanges, newCloudFlareChanges(cloudFlareDelete, changes.Delete, proxiedByDefault)...)
 
-	return p.submitChanges(ctx, combinedChanges)
+	if err := p.submitChanges(ctx, combinedChanges); err != nil {
+		return err
+	}
+	p.applyMonitorChanges(changes)
+	return nil
+}
+
+// SupportsHealthChecks returns true as the health checks of the endpoints are materialized as load balancer
+// monitors, which the load balancer pools of the records can use.
+func (p *CloudFlareProvider) SupportsHealthChecks() bool {
+	return true
+}
+
+// monitors returns the load balancer monitors created by ExternalDNS by their description.
+func (p *CloudFlareProvider) monitors() (map[string]cloudflare.LoadBalancerMonitor, error) {
+	list, err := p.Client.ListLoadBalancerMonitors()
+	if err != nil {
+		return nil, err
+	}
+	monitors := make(map[string]cloudflare.LoadBalancerMonitor)
+	for _, monitor := range list {
+		if strings.HasPrefix(monitor.Description, healthCheckMonitorNamePrefix) {
+			monitors[monitor.Description] = monitor
+		}
+	}
+	return monitors, nil
+}
+
+// applyMonitorChanges creates the load balancer monitors of the health checks of the desired endpoints,
+// replaces the ones whose health check changed and deletes the ones of the deleted endpoints.
+func (p *CloudFlareProvider) applyMonitorChanges(changes *plan.Changes) {
+	desired := make(map[string]*cloudflare.LoadBalancerMonitor)
+	for _, ep := range append(append([]*endpoint.Endpoint{}, changes.Create...), changes.UpdateNew...) {
+		if monitor := cloudFlareMonitor(ep); monitor != nil {
+			desired[monitor.Description] = monitor
+		}
+	}
+	var deleted []string
+	for _, ep := range append(append([]*endpoint.Endpoint{}, changes.UpdateOld...), changes.Delete...) {
+		if description := healthCheckMonitorName(ep); desired[description] == nil {
+			deleted = append(deleted, description)
+		}
+	}
+	if len(desired) == 0 && len(deleted) == 0 {
+		return
+	}
+
+	monitors, err := p.monitors()
+	if err != nil {
+		log.Errorf("Failed to list the load balancer monitors: %v", err)
+		return
+	}
+	for description, monitor := range desired {
+		current, ok := monitors[description]
+		if ok && cloudFlareMonitorsEqual(current, *monitor) {
+			continue
+		}
+		if ok {
+			deleted = append(deleted, description)
+		}
+		log.Debugf("Desired change: CREATE load balancer monitor %q", description)
+		if p.DryRun {
+			continue
+		}
+		// the replaced monitor is deleted once the new one was created
+		if _, err := p.Client.CreateLoadBalancerMonitor(*monitor); err != nil {
+			log.Errorf("Failed to create load balancer monitor %q: %v", description, err)
+		}
+	}
+	for _, description := range deleted {
+		current, ok := monitors[description]
+		if !ok {
+			continue
+		}
+		log.Debugf("Desired change: DELETE load balancer monitor %q", description)
+		if p.DryRun {
+			continue
+		}
+		if err := p.Client.DeleteLoadBalancerMonitor(current.ID); err != nil {
+			log.Errorf("Failed to delete load balancer monitor %q: %v", description, err)
+		}
+	}
+}
+
+// cloudFlareMonitor returns the load balancer monitor of the health check of an endpoint, or nil if it
+// doesn't have one. HTTP and HTTPS monitors send the DNS name of the endpoint as host header.
+func cloudFlareMonitor(ep *endpoint.Endpoint) *cloudflare.LoadBalancerMonitor {
+	hc, err := ep.HealthCheck()
+	if err != nil {
+		log.Warnf("Ignoring the health check of %s: %v", ep.DNSName, err)
+		return nil
+	}
+	if hc == nil {
+		return nil
+	}
+
+	monitor := &cloudflare.LoadBalancerMonitor{
+		Type:        strings.ToLower(hc.Protocol),
+		Description: healthCheckMonitorName(ep),
+		Port:        uint16(hc.Port),
+		Interval:    hc.Interval,
+		Timeout:     cloudFlareMonitorTimeout,
+		// the first failed check counts towards the threshold, the retries are the other ones
+		Retries: hc.FailureThreshold - 1,
+	}
+	if hc.Protocol != endpoint.HealthCheckProtocolTCP {
+		monitor.Method = "GET"
+		monitor.Path = hc.Path
+		monitor.Header = map[string][]string{"Host": {strings.TrimSuffix(ep.DNSName, ".")}}
+	}
+	return monitor
+}
+
+func cloudFlareMonitorsEqual(a, b cloudflare.LoadBalancerMonitor) bool {
+	return a.Type == b.Type && a.Port == b.Port && a.Path == b.Path && a.Interval == b.Interval && a.Retries == b.Retries
+}
+
+// withCloudFlareMonitor attaches the load balancer monitor of a record to its endpoint as health check.
+func withCloudFlareMonitor(ep *endpoint.Endpoint, monitor cloudflare.LoadBalancerMonitor) {
+	hc := &endpoint.HealthCheck{
+		Protocol:         strings.ToUpper(monitor.Type),
+		Port:             int(monitor.Port),
+		Interval:         monitor.Interval,
+		FailureThreshold: monitor.Retries + 1,
+	}
+	if hc.Protocol != endpoint.HealthCheckProtocolTCP {
+		hc.Path = monitor.Path
+	}
+	ep.WithHealthCheck(hc)
 }
 
 // submitChanges takes a zone and a collection of Changes and sends them as a single transaction.
@@ -340,6 +519,19 @@ func newCloudFlareChange(action string, endpoint *endpoint.Endpoint, proxiedByDe
 	}
 }
 
+// cloudFlareProviderSpecificSchema describes the provider-specific properties of CloudFlare records.
+var cloudFlareProviderSpecificSchema = ProviderSpecificSchema{
+	Prefix: "external-dns.alpha.kubernetes.io/cloudflare-",
+	Properties: map[string]func(string) error{
+		source.CloudflareProxiedKey: boolPropertyValue,
+	},
+}
+
+// ValidateProviderSpecific validates the CloudFlare properties of an endpoint.
+func (p *CloudFlareProvider) ValidateProviderSpecific(ep *endpoint.Endpoint) error {
+	return cloudFlareProviderSpecificSchema.Validate(ep)
+}
+
 func shouldBeProxied(endpoint *endpoint.Endpoint, proxiedByDefault bool) bool {
 	proxied := proxiedByDefault
 
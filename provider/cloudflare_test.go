@@ -78,6 +78,58 @@ func (m *mockCloudFlareClient) ListZonesContext(ctx context.Context, opts ...clo
 	}, nil
 }
 
+func (m *mockCloudFlareClient) ListLoadBalancerMonitors() ([]cloudflare.LoadBalancerMonitor, error) {
+	return nil, nil
+}
+
+func (m *mockCloudFlareClient) CreateLoadBalancerMonitor(monitor cloudflare.LoadBalancerMonitor) (cloudflare.LoadBalancerMonitor, error) {
+	return monitor, nil
+}
+
+func (m *mockCloudFlareClient) DeleteLoadBalancerMonitor(monitorID string) error {
+	return nil
+}
+
+// mockCloudFlareMonitors keeps the load balancer monitors which are created and deleted.
+type mockCloudFlareMonitors struct {
+	mockCloudFlareClient
+	monitors map[string]cloudflare.LoadBalancerMonitor
+}
+
+func (m *mockCloudFlareMonitors) ListLoadBalancerMonitors() ([]cloudflare.LoadBalancerMonitor, error) {
+	monitors := []cloudflare.LoadBalancerMonitor{}
+	for _, monitor := range m.monitors {
+		monitors = append(monitors, monitor)
+	}
+	return monitors, nil
+}
+
+func (m *mockCloudFlareMonitors) CreateLoadBalancerMonitor(monitor cloudflare.LoadBalancerMonitor) (cloudflare.LoadBalancerMonitor, error) {
+	monitor.ID = fmt.Sprintf("monitor-%d", len(m.monitors)+1)
+	m.monitors[monitor.ID] = monitor
+	return monitor, nil
+}
+
+func (m *mockCloudFlareMonitors) DeleteLoadBalancerMonitor(monitorID string) error {
+	if _, ok := m.monitors[monitorID]; !ok {
+		return fmt.Errorf("monitor %s doesn't exist", monitorID)
+	}
+	delete(m.monitors, monitorID)
+	return nil
+}
+
+// mockCloudFlareDNSRecordsFailForZone fails to list the records of the foo.com zone only.
+type mockCloudFlareDNSRecordsFailForZone struct {
+	mockCloudFlareClient
+}
+
+func (m *mockCloudFlareDNSRecordsFailForZone) DNSRecords(zoneID string, rr cloudflare.DNSRecord) ([]cloudflare.DNSRecord, error) {
+	if zoneID == "1234567891" {
+		return nil, fmt.Errorf("500 Internal Server Error")
+	}
+	return m.mockCloudFlareClient.DNSRecords(zoneID, rr)
+}
+
 type mockCloudFlareDNSRecordsFail struct{}
 
 func (m *mockCloudFlareDNSRecordsFail) CreateDNSRecord(zoneID string, rr cloudflare.DNSRecord) (*cloudflare.DNSRecordResponse, error) {
@@ -118,6 +170,18 @@ func (m *mockCloudFlareDNSRecordsFail) ListZonesContext(ctx context.Context, opt
 	}, nil
 }
 
+func (m *mockCloudFlareDNSRecordsFail) ListLoadBalancerMonitors() ([]cloudflare.LoadBalancerMonitor, error) {
+	return nil, nil
+}
+
+func (m *mockCloudFlareDNSRecordsFail) CreateLoadBalancerMonitor(monitor cloudflare.LoadBalancerMonitor) (cloudflare.LoadBalancerMonitor, error) {
+	return monitor, nil
+}
+
+func (m *mockCloudFlareDNSRecordsFail) DeleteLoadBalancerMonitor(monitorID string) error {
+	return nil
+}
+
 type mockCloudFlareListZonesFail struct{}
 
 func (m *mockCloudFlareListZonesFail) CreateDNSRecord(zoneID string, rr cloudflare.DNSRecord) (*cloudflare.DNSRecordResponse, error) {
@@ -152,6 +216,18 @@ func (m *mockCloudFlareListZonesFail) ListZonesContext(ctx context.Context, opts
 	return cloudflare.ZonesResponse{}, fmt.Errorf("no zones available")
 }
 
+func (m *mockCloudFlareListZonesFail) ListLoadBalancerMonitors() ([]cloudflare.LoadBalancerMonitor, error) {
+	return nil, nil
+}
+
+func (m *mockCloudFlareListZonesFail) CreateLoadBalancerMonitor(monitor cloudflare.LoadBalancerMonitor) (cloudflare.LoadBalancerMonitor, error) {
+	return monitor, nil
+}
+
+func (m *mockCloudFlareListZonesFail) DeleteLoadBalancerMonitor(monitorID string) error {
+	return nil
+}
+
 func TestNewCloudFlareChanges(t *testing.T) {
 	expect := []struct {
 		Name string
@@ -285,6 +361,18 @@ func TestRecords(t *testing.T) {
 	}
 }
 
+func TestCloudflareRecordsZoneErrors(t *testing.T) {
+	provider := &CloudFlareProvider{
+		Client: &mockCloudFlareDNSRecordsFailForZone{},
+	}
+
+	records, err := provider.Records(context.Background())
+	require.Error(t, err)
+	require.IsType(t, ZoneErrors{}, err)
+	assert.Equal(t, []string{"foo.com."}, err.(ZoneErrors).Zones())
+	assert.Equal(t, 1, len(records))
+}
+
 func TestNewCloudFlareProvider(t *testing.T) {
 	_ = os.Setenv("CF_API_TOKEN", "abc123def")
 	_, err := NewCloudFlareProvider(
@@ -292,7 +380,8 @@ func TestNewCloudFlareProvider(t *testing.T) {
 		NewZoneIDFilter([]string{""}),
 		25,
 		false,
-		true)
+		true,
+		nil)
 	if err != nil {
 		t.Errorf("should not fail, %s", err)
 	}
@@ -304,7 +393,8 @@ func TestNewCloudFlareProvider(t *testing.T) {
 		NewZoneIDFilter([]string{""}),
 		1,
 		false,
-		true)
+		true,
+		nil)
 	if err != nil {
 		t.Errorf("should not fail, %s", err)
 	}
@@ -315,7 +405,8 @@ func TestNewCloudFlareProvider(t *testing.T) {
 		NewZoneIDFilter([]string{""}),
 		50,
 		false,
-		true)
+		true,
+		nil)
 	if err == nil {
 		t.Errorf("expected to fail")
 	}
@@ -605,3 +696,79 @@ func TestGroupByNameAndType(t *testing.T) {
 		assert.ElementsMatch(t, groupByNameAndType(tc.Records), tc.ExpectedEndpoints)
 	}
 }
+
+func TestCloudFlareValidateProviderSpecific(t *testing.T) {
+	provider := &CloudFlareProvider{Client: &mockCloudFlareClient{}}
+
+	valid := endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeA, "1.2.3.4").
+		WithProviderSpecific("external-dns.alpha.kubernetes.io/cloudflare-proxied", "true").
+		WithProviderSpecific("aws/weight", "10")
+	assert.NoError(t, provider.ValidateProviderSpecific(valid))
+
+	invalid := endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeA, "1.2.3.4").
+		WithProviderSpecific("external-dns.alpha.kubernetes.io/cloudflare-proxied", "on")
+	assert.EqualError(t, provider.ValidateProviderSpecific(invalid), `A record app.example.org has invalid provider-specific property external-dns.alpha.kubernetes.io/cloudflare-proxied="on": not a boolean`)
+}
+
+func TestCloudFlareHealthChecks(t *testing.T) {
+	client := &mockCloudFlareMonitors{monitors: map[string]cloudflare.LoadBalancerMonitor{
+		"unmanaged": {ID: "unmanaged", Type: "tcp", Description: "console", Port: 53},
+	}}
+	provider := &CloudFlareProvider{Client: client}
+	assert.True(t, provider.SupportsHealthChecks())
+
+	healthCheck := &endpoint.HealthCheck{Protocol: endpoint.HealthCheckProtocolHTTPS, Port: 443, Path: "/healthz", Interval: 60, FailureThreshold: 3}
+	desired := endpoint.NewEndpoint("foobar.ext-dns-test.zalando.to.", endpoint.RecordTypeA, "1.2.3.4").WithHealthCheck(healthCheck)
+	require.NoError(t, provider.ApplyChanges(context.Background(), &plan.Changes{Create: []*endpoint.Endpoint{desired}}))
+
+	require.Len(t, client.monitors, 2)
+	assert.Equal(t, cloudflare.LoadBalancerMonitor{
+		ID:          "monitor-2",
+		Type:        "https",
+		Description: "external-dns: foobar.ext-dns-test.zalando.to A",
+		Method:      "GET",
+		Path:        "/healthz",
+		Header:      map[string][]string{"Host": {"foobar.ext-dns-test.zalando.to"}},
+		Timeout:     cloudFlareMonitorTimeout,
+		Retries:     2,
+		Interval:    60,
+		Port:        443,
+	}, client.monitors["monitor-2"])
+
+	// the monitor is attached to the record as health check
+	records, err := provider.Records(context.Background())
+	require.NoError(t, err)
+	var found bool
+	for _, record := range records {
+		if record.DNSName == "foobar.ext-dns-test.zalando.to" {
+			found = true
+			hc, err := record.HealthCheck()
+			require.NoError(t, err)
+			assert.Equal(t, healthCheck, hc)
+		}
+	}
+	assert.True(t, found)
+
+	// changed health checks replace the monitor
+	updated := endpoint.NewEndpoint("foobar.ext-dns-test.zalando.to.", endpoint.RecordTypeA, "1.2.3.4").WithHealthCheck(&endpoint.HealthCheck{Protocol: endpoint.HealthCheckProtocolTCP, Port: 5432, Interval: 60, FailureThreshold: 1})
+	require.NoError(t, provider.ApplyChanges(context.Background(), &plan.Changes{UpdateOld: []*endpoint.Endpoint{desired}, UpdateNew: []*endpoint.Endpoint{updated}}))
+	require.Len(t, client.monitors, 2)
+	assert.NotContains(t, client.monitors, "monitor-2")
+	for id, monitor := range client.monitors {
+		if id != "unmanaged" {
+			assert.Equal(t, "tcp", monitor.Type)
+			assert.Equal(t, uint16(5432), monitor.Port)
+			assert.Equal(t, 0, monitor.Retries)
+		}
+	}
+
+	// the monitors of deleted records are deleted, others are kept
+	require.NoError(t, provider.ApplyChanges(context.Background(), &plan.Changes{Delete: []*endpoint.Endpoint{updated}}))
+	assert.Equal(t, []string{"unmanaged"}, func() []string {
+		var ids []string
+		for id := range client.monitors {
+			ids = append(ids, id)
+		}
+		return ids
+	}())
+}
@@ -0,0 +1,480 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/route53"
+	cloudflare "github.com/cloudflare/cloudflare-go"
+	"github.com/gophercloud/gophercloud/openstack/dns/v2/recordsets"
+	"github.com/gophercloud/gophercloud/openstack/dns/v2/zones"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/layer3/floatingips"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	dns "google.golang.org/api/dns/v1"
+	"google.golang.org/api/googleapi"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+const concurrentCalls = 10
+
+// testProviderConcurrency calls Records and ApplyChanges of the provider from several goroutines at once
+// and modifies the returned records like the controller does. Shared state which isn't synchronized is
+// reported when the tests run with the race detector, which `make test` enables.
+func testProviderConcurrency(t *testing.T, p Provider, newChanges func(i int) *plan.Changes) {
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*concurrentCalls)
+
+	for i := 0; i < concurrentCalls; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			records, err := p.Records(context.Background())
+			if err != nil {
+				errs <- err
+				return
+			}
+			for _, record := range records {
+				record.DNSName = record.DNSName + "."
+				if record.Labels != nil {
+					record.Labels[endpoint.OwnerLabelKey] = "concurrency-test"
+				}
+			}
+		}()
+		go func(i int) {
+			defer wg.Done()
+			if err := p.ApplyChanges(context.Background(), newChanges(i)); err != nil {
+				errs <- err
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+}
+
+func TestInMemoryProviderConcurrency(t *testing.T) {
+	p := NewInMemoryProvider()
+	require.NoError(t, p.CreateZone("example.org"))
+
+	testProviderConcurrency(t, p, func(i int) *plan.Changes {
+		ep := endpoint.NewEndpoint(fmt.Sprintf("host-%d.example.org", i), endpoint.RecordTypeA, "1.2.3.4")
+		ep.Labels[endpoint.OwnerLabelKey] = "owner"
+		return &plan.Changes{Create: []*endpoint.Endpoint{ep}}
+	})
+
+	records, err := p.Records(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, records, concurrentCalls)
+	for _, record := range records {
+		assert.Equal(t, "owner", record.Labels[endpoint.OwnerLabelKey])
+	}
+}
+
+func TestCloudFlareProviderConcurrency(t *testing.T) {
+	p := &CloudFlareProvider{
+		Client: &mockCloudFlareClient{},
+		PaginationOptions: cloudflare.PaginationOptions{
+			PerPage: 50,
+			Page:    1,
+		},
+	}
+
+	testProviderConcurrency(t, p, func(i int) *plan.Changes {
+		return &plan.Changes{
+			Create: []*endpoint.Endpoint{
+				endpoint.NewEndpoint(fmt.Sprintf("host-%d.ext-dns-test.zalando.to", i), endpoint.RecordTypeA, "1.2.3.4"),
+			},
+		}
+	})
+}
+
+// lockedRoute53API serializes the calls of the Route53 stub, which isn't safe for concurrent use itself, so
+// the race detector only reports the state shared by the provider. The pages are passed to the callbacks
+// outside of the lock, like the pages of the API, so the callbacks may call the API again.
+type lockedRoute53API struct {
+	Route53API
+	lock sync.Mutex
+}
+
+func (c *lockedRoute53API) ListResourceRecordSetsPagesWithContext(ctx context.Context, input *route53.ListResourceRecordSetsInput, fn func(resp *route53.ListResourceRecordSetsOutput, lastPage bool) (shouldContinue bool), opts ...request.Option) error {
+	var pages []*route53.ListResourceRecordSetsOutput
+	c.lock.Lock()
+	err := c.Route53API.ListResourceRecordSetsPagesWithContext(ctx, input, func(resp *route53.ListResourceRecordSetsOutput, lastPage bool) bool {
+		pages = append(pages, resp)
+		return true
+	}, opts...)
+	c.lock.Unlock()
+	for i, page := range pages {
+		if !fn(page, i == len(pages)-1) {
+			break
+		}
+	}
+	return err
+}
+
+func (c *lockedRoute53API) ListHostedZonesPagesWithContext(ctx context.Context, input *route53.ListHostedZonesInput, fn func(resp *route53.ListHostedZonesOutput, lastPage bool) (shouldContinue bool), opts ...request.Option) error {
+	var pages []*route53.ListHostedZonesOutput
+	c.lock.Lock()
+	err := c.Route53API.ListHostedZonesPagesWithContext(ctx, input, func(resp *route53.ListHostedZonesOutput, lastPage bool) bool {
+		pages = append(pages, resp)
+		return true
+	}, opts...)
+	c.lock.Unlock()
+	for i, page := range pages {
+		if !fn(page, i == len(pages)-1) {
+			break
+		}
+	}
+	return err
+}
+
+func (c *lockedRoute53API) ListHealthChecksPagesWithContext(ctx context.Context, input *route53.ListHealthChecksInput, fn func(resp *route53.ListHealthChecksOutput, lastPage bool) (shouldContinue bool), opts ...request.Option) error {
+	var pages []*route53.ListHealthChecksOutput
+	c.lock.Lock()
+	err := c.Route53API.ListHealthChecksPagesWithContext(ctx, input, func(resp *route53.ListHealthChecksOutput, lastPage bool) bool {
+		pages = append(pages, resp)
+		return true
+	}, opts...)
+	c.lock.Unlock()
+	for i, page := range pages {
+		if !fn(page, i == len(pages)-1) {
+			break
+		}
+	}
+	return err
+}
+
+func (c *lockedRoute53API) ChangeResourceRecordSetsWithContext(ctx context.Context, input *route53.ChangeResourceRecordSetsInput, opts ...request.Option) (*route53.ChangeResourceRecordSetsOutput, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.Route53API.ChangeResourceRecordSetsWithContext(ctx, input, opts...)
+}
+
+func (c *lockedRoute53API) ListHostedZonesByNameWithContext(ctx context.Context, input *route53.ListHostedZonesByNameInput, opts ...request.Option) (*route53.ListHostedZonesByNameOutput, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.Route53API.ListHostedZonesByNameWithContext(ctx, input, opts...)
+}
+
+func (c *lockedRoute53API) ListTagsForResourceWithContext(ctx context.Context, input *route53.ListTagsForResourceInput, opts ...request.Option) (*route53.ListTagsForResourceOutput, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.Route53API.ListTagsForResourceWithContext(ctx, input, opts...)
+}
+
+func (c *lockedRoute53API) CreateHealthCheckWithContext(ctx context.Context, input *route53.CreateHealthCheckInput, opts ...request.Option) (*route53.CreateHealthCheckOutput, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.Route53API.CreateHealthCheckWithContext(ctx, input, opts...)
+}
+
+func (c *lockedRoute53API) DeleteHealthCheckWithContext(ctx context.Context, input *route53.DeleteHealthCheckInput, opts ...request.Option) (*route53.DeleteHealthCheckOutput, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.Route53API.DeleteHealthCheckWithContext(ctx, input, opts...)
+}
+
+func (c *lockedRoute53API) ChangeTagsForResourceWithContext(ctx context.Context, input *route53.ChangeTagsForResourceInput, opts ...request.Option) (*route53.ChangeTagsForResourceOutput, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.Route53API.ChangeTagsForResourceWithContext(ctx, input, opts...)
+}
+
+func (c *lockedRoute53API) ListTagsForResourcesWithContext(ctx context.Context, input *route53.ListTagsForResourcesInput, opts ...request.Option) (*route53.ListTagsForResourcesOutput, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.Route53API.ListTagsForResourcesWithContext(ctx, input, opts...)
+}
+
+func (c *lockedRoute53API) GetHostedZoneWithContext(ctx context.Context, input *route53.GetHostedZoneInput, opts ...request.Option) (*route53.GetHostedZoneOutput, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.Route53API.GetHostedZoneWithContext(ctx, input, opts...)
+}
+
+func (c *lockedRoute53API) AssociateVPCWithHostedZoneWithContext(ctx context.Context, input *route53.AssociateVPCWithHostedZoneInput, opts ...request.Option) (*route53.AssociateVPCWithHostedZoneOutput, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.Route53API.AssociateVPCWithHostedZoneWithContext(ctx, input, opts...)
+}
+
+func TestAWSProviderConcurrency(t *testing.T) {
+	p, client := newAWSProvider(t, NewDomainFilter([]string{"ext-dns-test-2.teapot.zalan.do."}), NewZoneIDFilter([]string{}), NewZoneTypeFilter(""), defaultEvaluateTargetHealth, false, []*endpoint.Endpoint{})
+	vpcs, err := parseRoute53VPCs([]string{"eu-central-1:vpc-1"})
+	require.NoError(t, err)
+	// the associated private zones and the referenced health checks are shared by the calls
+	p.privateZoneVPCs = vpcs
+	p.healthCheckOwnerID = "owner"
+	p.cleanUpOrphanedHealthChecks = true
+	p.client = &lockedRoute53API{Route53API: client}
+
+	healthCheck := &endpoint.HealthCheck{Protocol: endpoint.HealthCheckProtocolTCP, Port: 5432, Interval: 30, FailureThreshold: 3}
+	testProviderConcurrency(t, p, func(i int) *plan.Changes {
+		return &plan.Changes{
+			Create: []*endpoint.Endpoint{
+				endpoint.NewEndpoint(fmt.Sprintf("host-%d.zone-1.ext-dns-test-2.teapot.zalan.do", i), endpoint.RecordTypeA, fmt.Sprintf("10.0.0.%d", i)).WithHealthCheck(healthCheck),
+				endpoint.NewEndpoint(fmt.Sprintf("host-%d.zone-3.ext-dns-test-2.teapot.zalan.do", i), endpoint.RecordTypeA, "1.2.3.4"),
+			},
+		}
+	})
+
+	records, err := p.Records(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, records, 2*concurrentCalls)
+	assert.Len(t, client.healthChecks, concurrentCalls)
+	assert.Equal(t, vpcs, client.zoneVPCs["/hostedzone/zone-3.ext-dns-test-2.teapot.zalan.do."])
+}
+
+// googleMockLock serializes the calls of the Google mocks, which share their zones and records.
+var googleMockLock sync.Mutex
+
+type lockedGoogleManagedZonesClient struct {
+	client managedZonesServiceInterface
+}
+
+func (c *lockedGoogleManagedZonesClient) Create(project string, managedZone *dns.ManagedZone) managedZonesCreateCallInterface {
+	return c.client.Create(project, managedZone)
+}
+
+func (c *lockedGoogleManagedZonesClient) List(project string) managedZonesListCallInterface {
+	return &lockedGoogleManagedZonesListCall{call: c.client.List(project)}
+}
+
+type lockedGoogleManagedZonesListCall struct {
+	call managedZonesListCallInterface
+}
+
+func (c *lockedGoogleManagedZonesListCall) Pages(ctx context.Context, f func(*dns.ManagedZonesListResponse) error) error {
+	var pages []*dns.ManagedZonesListResponse
+	googleMockLock.Lock()
+	err := c.call.Pages(ctx, func(resp *dns.ManagedZonesListResponse) error {
+		pages = append(pages, resp)
+		return nil
+	})
+	googleMockLock.Unlock()
+	if err != nil {
+		return err
+	}
+	for _, page := range pages {
+		if err := f(page); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type lockedGoogleResourceRecordSetsClient struct {
+	client resourceRecordSetsClientInterface
+}
+
+func (c *lockedGoogleResourceRecordSetsClient) List(project string, managedZone string) resourceRecordSetsListCallInterface {
+	return &lockedGoogleResourceRecordSetsListCall{call: c.client.List(project, managedZone)}
+}
+
+type lockedGoogleResourceRecordSetsListCall struct {
+	call resourceRecordSetsListCallInterface
+}
+
+func (c *lockedGoogleResourceRecordSetsListCall) Pages(ctx context.Context, f func(*dns.ResourceRecordSetsListResponse) error) error {
+	var pages []*dns.ResourceRecordSetsListResponse
+	googleMockLock.Lock()
+	err := c.call.Pages(ctx, func(resp *dns.ResourceRecordSetsListResponse) error {
+		pages = append(pages, resp)
+		return nil
+	})
+	googleMockLock.Unlock()
+	if err != nil {
+		return err
+	}
+	for _, page := range pages {
+		if err := f(page); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type lockedGoogleChangesClient struct {
+	client changesServiceInterface
+}
+
+func (c *lockedGoogleChangesClient) Create(project string, managedZone string, change *dns.Change) changesCreateCallInterface {
+	return &lockedGoogleChangesCreateCall{call: c.client.Create(project, managedZone, change)}
+}
+
+type lockedGoogleChangesCreateCall struct {
+	call changesCreateCallInterface
+}
+
+func (c *lockedGoogleChangesCreateCall) Do(opts ...googleapi.CallOption) (*dns.Change, error) {
+	googleMockLock.Lock()
+	defer googleMockLock.Unlock()
+	return c.call.Do(opts...)
+}
+
+func TestGoogleProviderConcurrency(t *testing.T) {
+	p := newGoogleProvider(t, NewDomainFilter([]string{"ext-dns-test-2.gcp.zalan.do."}), NewZoneIDFilter([]string{""}), false, []*endpoint.Endpoint{})
+	p.managedZonesClient = &lockedGoogleManagedZonesClient{client: p.managedZonesClient}
+	p.resourceRecordSetsClient = &lockedGoogleResourceRecordSetsClient{client: p.resourceRecordSetsClient}
+	p.changesClient = &lockedGoogleChangesClient{client: p.changesClient}
+
+	testProviderConcurrency(t, p, func(i int) *plan.Changes {
+		return &plan.Changes{
+			Create: []*endpoint.Endpoint{
+				endpoint.NewEndpoint(fmt.Sprintf("host-%d.zone-1.ext-dns-test-2.gcp.zalan.do", i), endpoint.RecordTypeA, "1.2.3.4"),
+			},
+		}
+	})
+
+	records, err := p.Records(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, records, concurrentCalls)
+}
+
+// lockedDesignateClient serializes the calls of the fake Designate client and passes copies of its record
+// sets to the handlers, so the race detector only reports the state shared by the provider.
+type lockedDesignateClient struct {
+	client designateClientInterface
+	lock   sync.Mutex
+}
+
+func (c *lockedDesignateClient) ForEachZone(handler func(zone *zones.Zone) error) error {
+	var all []zones.Zone
+	c.lock.Lock()
+	err := c.client.ForEachZone(func(zone *zones.Zone) error {
+		all = append(all, *zone)
+		return nil
+	})
+	c.lock.Unlock()
+	if err != nil {
+		return err
+	}
+	for i := range all {
+		if err := handler(&all[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *lockedDesignateClient) ForEachRecordSet(zoneID string, handler func(recordSet *recordsets.RecordSet) error) error {
+	var all []recordsets.RecordSet
+	c.lock.Lock()
+	err := c.client.ForEachRecordSet(zoneID, func(recordSet *recordsets.RecordSet) error {
+		all = append(all, *recordSet)
+		return nil
+	})
+	c.lock.Unlock()
+	if err != nil {
+		return err
+	}
+	for i := range all {
+		if err := handler(&all[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *lockedDesignateClient) CreateRecordSet(zoneID string, opts recordsets.CreateOpts) (string, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.client.CreateRecordSet(zoneID, opts)
+}
+
+func (c *lockedDesignateClient) UpdateRecordSet(zoneID, recordSetID string, opts recordsets.UpdateOpts) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.client.UpdateRecordSet(zoneID, recordSetID, opts)
+}
+
+func (c *lockedDesignateClient) DeleteRecordSet(zoneID, recordSetID string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.client.DeleteRecordSet(zoneID, recordSetID)
+}
+
+func (c *lockedDesignateClient) ForEachFloatingIP(handler func(floatingIP *floatingips.FloatingIP) error) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.client.ForEachFloatingIP(handler)
+}
+
+func TestDesignateProviderConcurrency(t *testing.T) {
+	client := newFakeDesignateClient()
+	client.AddZone(zones.Zone{Name: "example.com.", Type: "PRIMARY", Status: "ACTIVE"})
+	p := &designateProvider{client: &lockedDesignateClient{client: client}}
+
+	testProviderConcurrency(t, p, func(i int) *plan.Changes {
+		return &plan.Changes{
+			Create: []*endpoint.Endpoint{
+				{
+					DNSName:    fmt.Sprintf("host-%d.example.com", i),
+					RecordType: endpoint.RecordTypeA,
+					Targets:    endpoint.Targets{"10.1.1.1"},
+					Labels:     map[string]string{},
+				},
+			},
+		}
+	})
+
+	records, err := p.Records(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, records, concurrentCalls)
+}
+
+func TestDynSnapshotConcurrency(t *testing.T) {
+	snap := &ZoneSnapshot{
+		serials:   map[string]int{},
+		endpoints: map[string][]*endpoint.Endpoint{},
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrentCalls; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			snap.StoreRecordsForSerial("example.org", i, []*endpoint.Endpoint{
+				endpoint.NewEndpoint("foo.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+			})
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			for _, record := range snap.GetRecordsForSerial("example.org", i) {
+				record.DNSName = "modified.example.org"
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < concurrentCalls; i++ {
+		for _, record := range snap.GetRecordsForSerial("example.org", i) {
+			assert.Equal(t, "foo.example.org", record.DNSName)
+		}
+	}
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/gophercloud/gophercloud/openstack"
 	"github.com/gophercloud/gophercloud/openstack/dns/v2/recordsets"
 	"github.com/gophercloud/gophercloud/openstack/dns/v2/zones"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/layer3/floatingips"
 	"github.com/gophercloud/gophercloud/pagination"
 	log "github.com/sirupsen/logrus"
 
@@ -65,20 +66,43 @@ type designateClientInterface interface {
 
 	// DeleteRecordSet deletes recordset in the given DNS zone
 	DeleteRecordSet(zoneID, recordSetID string) error
+
+	// ForEachFloatingIP calls handler for each Neutron floating IP of the project
+	ForEachFloatingIP(handler func(floatingIP *floatingips.FloatingIP) error) error
 }
 
 // implementation of the designateClientInterface
 type designateClient struct {
 	serviceClient *gophercloud.ServiceClient
+	networkClient *gophercloud.ServiceClient
 }
 
-// factory function for the designateClientInterface
-func newDesignateClient() (designateClientInterface, error) {
-	serviceClient, err := createDesignateServiceClient()
+// factory function for the designateClientInterface, the Neutron client is only created if it's needed
+func newDesignateClient(withNetwork bool, providerTransport *http.Transport) (designateClientInterface, error) {
+	authProvider, err := createOpenStackProviderClient(providerTransport)
+	if err != nil {
+		return nil, err
+	}
+
+	eo := gophercloud.EndpointOpts{
+		Region: os.Getenv("OS_REGION_NAME"),
+	}
+
+	serviceClient, err := openstack.NewDNSV2(authProvider, eo)
 	if err != nil {
 		return nil, err
 	}
-	return &designateClient{serviceClient}, nil
+	log.Infof("Found OpenStack Designate service at %s", serviceClient.Endpoint)
+
+	client := &designateClient{serviceClient: serviceClient}
+	if withNetwork {
+		client.networkClient, err = openstack.NewNetworkV2(authProvider, eo)
+		if err != nil {
+			return nil, err
+		}
+		log.Infof("Found OpenStack Neutron service at %s", client.networkClient.Endpoint)
+	}
+	return client, nil
 }
 
 // copies environment variables to new names without overwriting existing values
@@ -117,8 +141,8 @@ func getAuthSettings() (gophercloud.AuthOptions, error) {
 	return opts, nil
 }
 
-// authenticate in OpenStack and obtain Designate service endpoint
-func createDesignateServiceClient() (*gophercloud.ServiceClient, error) {
+// authenticate in OpenStack, through the proxy and with the certificate authorities of the provider transport
+func createOpenStackProviderClient(providerTransport *http.Transport) (*gophercloud.ProviderClient, error) {
 	opts, err := getAuthSettings()
 	if err != nil {
 		return nil, err
@@ -135,7 +159,7 @@ func createDesignateServiceClient() (*gophercloud.ServiceClient, error) {
 	}
 
 	transport := &http.Transport{
-		Proxy: http.ProxyFromEnvironment,
+		Proxy: transportProxy(providerTransport),
 		DialContext: (&net.Dialer{
 			Timeout:   30 * time.Second,
 			KeepAlive: 30 * time.Second,
@@ -144,24 +168,14 @@ func createDesignateServiceClient() (*gophercloud.ServiceClient, error) {
 		IdleConnTimeout:       90 * time.Second,
 		TLSHandshakeTimeout:   10 * time.Second,
 		ExpectContinueTimeout: 1 * time.Second,
-		TLSClientConfig:       tlsConfig,
+		TLSClientConfig:       withTransportRootCAs(providerTransport, tlsConfig),
 	}
 	authProvider.HTTPClient.Transport = transport
 
 	if err = openstack.Authenticate(authProvider, opts); err != nil {
 		return nil, err
 	}
-
-	eo := gophercloud.EndpointOpts{
-		Region: os.Getenv("OS_REGION_NAME"),
-	}
-
-	client, err := openstack.NewDNSV2(authProvider, eo)
-	if err != nil {
-		return nil, err
-	}
-	log.Infof("Found OpenStack Designate service at %s", client.Endpoint)
-	return client, nil
+	return authProvider, nil
 }
 
 // ForEachZone calls handler for each zone managed by the Designate
@@ -224,25 +238,52 @@ func (c designateClient) DeleteRecordSet(zoneID, recordSetID string) error {
 	return recordsets.Delete(c.serviceClient, zoneID, recordSetID).ExtractErr()
 }
 
+// ForEachFloatingIP calls handler for each Neutron floating IP of the project
+func (c designateClient) ForEachFloatingIP(handler func(floatingIP *floatingips.FloatingIP) error) error {
+	if c.networkClient == nil {
+		return fmt.Errorf("no OpenStack Neutron client was created")
+	}
+	pager := floatingips.List(c.networkClient, floatingips.ListOpts{})
+	return pager.EachPage(
+		func(page pagination.Page) (bool, error) {
+			list, err := floatingips.ExtractFloatingIPs(page)
+			if err != nil {
+				return false, err
+			}
+			for _, floatingIP := range list {
+				err := handler(&floatingIP)
+				if err != nil {
+					return false, err
+				}
+			}
+			return true, nil
+		},
+	)
+}
+
 // designate provider type
 type designateProvider struct {
 	client designateClientInterface
 
 	// only consider hosted zones managing domains ending in this suffix
 	domainFilter DomainFilter
-	dryRun       bool
+	// publish the floating IPs bound to the A record targets instead of the targets, e.g. Octavia VIPs
+	floatingIPTargets bool
+	dryRun            bool
 }
 
-// NewDesignateProvider is a factory function for OpenStack designate providers
-func NewDesignateProvider(domainFilter DomainFilter, dryRun bool) (Provider, error) {
-	client, err := newDesignateClient()
+// NewDesignateProvider is a factory function for OpenStack designate providers. The OpenStack clients use the
+// proxy and the certificate authorities of the transport, nil for the default transport.
+func NewDesignateProvider(domainFilter DomainFilter, floatingIPTargets, dryRun bool, transport *http.Transport) (Provider, error) {
+	client, err := newDesignateClient(floatingIPTargets, transport)
 	if err != nil {
 		return nil, err
 	}
 	return &designateProvider{
-		client:       client,
-		domainFilter: domainFilter,
-		dryRun:       dryRun,
+		client:            client,
+		domainFilter:      domainFilter,
+		floatingIPTargets: floatingIPTargets,
+		dryRun:            dryRun,
 	}, nil
 }
 
@@ -314,6 +355,10 @@ func (p designateProvider) Records(ctx context.Context) ([]*endpoint.Endpoint, e
 	if err != nil {
 		return nil, err
 	}
+	_, floatingToFixed, err := p.floatingIPs()
+	if err != nil {
+		return nil, err
+	}
 	for zoneID := range managedZones {
 		err = p.client.ForEachRecordSet(zoneID,
 			func(recordSet *recordsets.RecordSet) error {
@@ -321,6 +366,10 @@ func (p designateProvider) Records(ctx context.Context) ([]*endpoint.Endpoint, e
 					return nil
 				}
 				for _, record := range recordSet.Records {
+					// a floating IP is listed as the fixed IP it's bound to, like the sources know the target
+					if fixedIP, ok := floatingToFixed[record]; ok && recordSet.Type == endpoint.RecordTypeA {
+						record = fixedIP
+					}
 					ep := endpoint.NewEndpoint(recordSet.Name, recordSet.Type, record)
 					ep.Labels[designateRecordSetID] = recordSet.ID
 					ep.Labels[designateZoneID] = recordSet.ZoneID
@@ -338,6 +387,51 @@ func (p designateProvider) Records(ctx context.Context) ([]*endpoint.Endpoint, e
 	return result, nil
 }
 
+// floatingIPs returns the fixed IPs floating IPs are bound to mapped to the floating IPs and the other way
+// around, if the floating IPs are published instead of the targets. As the binding is looked up on every
+// synchronization, the records keep pointing at a floating IP when it's moved to a new Octavia VIP.
+func (p designateProvider) floatingIPs() (fixedToFloating, floatingToFixed map[string]string, err error) {
+	if !p.floatingIPTargets {
+		return nil, nil, nil
+	}
+	fixedToFloating = map[string]string{}
+	floatingToFixed = map[string]string{}
+	err = p.client.ForEachFloatingIP(func(floatingIP *floatingips.FloatingIP) error {
+		if floatingIP.FixedIP == "" || floatingIP.FloatingIP == "" {
+			return nil
+		}
+		fixedToFloating[floatingIP.FixedIP] = floatingIP.FloatingIP
+		floatingToFixed[floatingIP.FloatingIP] = floatingIP.FixedIP
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list the floating IPs: %v", err)
+	}
+	return fixedToFloating, floatingToFixed, nil
+}
+
+// withFloatingIPTargets returns copies of the endpoints with the A record targets replaced by the floating
+// IPs bound to them.
+func withFloatingIPTargets(endpoints []*endpoint.Endpoint, fixedToFloating map[string]string) []*endpoint.Endpoint {
+	result := make([]*endpoint.Endpoint, 0, len(endpoints))
+	for _, ep := range endpoints {
+		if ep.RecordType != endpoint.RecordTypeA {
+			result = append(result, ep)
+			continue
+		}
+		ep = ep.DeepCopy()
+		for i, target := range ep.Targets {
+			if floatingIP, ok := fixedToFloating[target]; ok {
+				ep.Targets[i] = floatingIP
+			} else {
+				log.Debugf("No floating IP is bound to %s of %s, publishing it as it is", target, ep.DNSName)
+			}
+		}
+		result = append(result, ep)
+	}
+	return result
+}
+
 // temporary structure to hold recordset parameters so that we could aggregate endpoints into recordsets
 type recordSet struct {
 	dnsName     string
@@ -385,6 +479,18 @@ func (p designateProvider) ApplyChanges(ctx context.Context, changes *plan.Chang
 	if err != nil {
 		return err
 	}
+	fixedToFloating, _, err := p.floatingIPs()
+	if err != nil {
+		return err
+	}
+	if len(fixedToFloating) > 0 {
+		changes = &plan.Changes{
+			Create:    withFloatingIPTargets(changes.Create, fixedToFloating),
+			UpdateOld: withFloatingIPTargets(changes.UpdateOld, fixedToFloating),
+			UpdateNew: withFloatingIPTargets(changes.UpdateNew, fixedToFloating),
+			Delete:    withFloatingIPTargets(changes.Delete, fixedToFloating),
+		}
+	}
 	recordSets := map[string]*recordSet{}
 	for _, ep := range changes.Create {
 		addEndpoint(ep, recordSets, false)
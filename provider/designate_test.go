@@ -31,6 +31,7 @@ import (
 
 	"github.com/gophercloud/gophercloud/openstack/dns/v2/recordsets"
 	"github.com/gophercloud/gophercloud/openstack/dns/v2/zones"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/layer3/floatingips"
 
 	"sigs.k8s.io/external-dns/endpoint"
 	"sigs.k8s.io/external-dns/plan"
@@ -47,6 +48,7 @@ type fakeDesignateClient struct {
 		zone       *zones.Zone
 		recordSets map[string]*recordsets.RecordSet
 	}
+	floatingIPs []floatingips.FloatingIP
 }
 
 func (c fakeDesignateClient) AddZone(zone zones.Zone) string {
@@ -130,13 +132,22 @@ func (c fakeDesignateClient) DeleteRecordSet(zoneID, recordSetID string) error {
 	return nil
 }
 
+func (c fakeDesignateClient) ForEachFloatingIP(handler func(floatingIP *floatingips.FloatingIP) error) error {
+	for i := range c.floatingIPs {
+		if err := handler(&c.floatingIPs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (c fakeDesignateClient) ToProvider() Provider {
 	return &designateProvider{client: c}
 }
 
 func newFakeDesignateClient() *fakeDesignateClient {
 	return &fakeDesignateClient{
-		make(map[string]*struct {
+		managedZones: make(map[string]*struct {
 			zone       *zones.Zone
 			recordSets map[string]*recordsets.RecordSet
 		}),
@@ -191,7 +202,7 @@ func TestNewDesignateProvider(t *testing.T) {
 	os.Setenv("OS_USER_DOMAIN_NAME", "Default")
 	os.Setenv("OPENSTACK_CA_FILE", tmpfile.Name())
 
-	if _, err := NewDesignateProvider(DomainFilter{}, true); err != nil {
+	if _, err := NewDesignateProvider(DomainFilter{}, false, true, nil); err != nil {
 		t.Fatalf("Failed to initialize Designate provider: %s", err)
 	}
 }
@@ -580,3 +591,93 @@ func testDesignateDeleteRecords(t *testing.T, client *fakeDesignateClient) {
 		t.Errorf("not all expected record-sets were deleted. Remained: %v", expected)
 	}
 }
+
+func TestDesignateFloatingIPs(t *testing.T) {
+	client := newFakeDesignateClient()
+	client.floatingIPs = []floatingips.FloatingIP{
+		{FloatingIP: "203.0.113.10", FixedIP: "10.0.0.10"},
+		{FloatingIP: "203.0.113.11", FixedIP: "10.0.0.11"},
+		{FloatingIP: "203.0.113.12"},
+	}
+	zoneID := client.AddZone(zones.Zone{
+		Name:   "example.com.",
+		Type:   "PRIMARY",
+		Status: "ACTIVE",
+	})
+	rsID, _ := client.CreateRecordSet(zoneID, recordsets.CreateOpts{
+		Name:    "www.example.com.",
+		Type:    endpoint.RecordTypeA,
+		Records: []string{"203.0.113.10"},
+	})
+	p := &designateProvider{client: client, floatingIPTargets: true}
+
+	// floating IPs are listed as the fixed IPs they are bound to
+	endpoints, err := p.Records(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &endpoint.Endpoint{
+		DNSName:    "www.example.com",
+		RecordType: endpoint.RecordTypeA,
+		Targets:    endpoint.Targets{"10.0.0.10"},
+		Labels: map[string]string{
+			designateRecordSetID:     rsID,
+			designateZoneID:          zoneID,
+			designateOriginalRecords: "203.0.113.10",
+		},
+	}
+	if len(endpoints) != 1 || !reflect.DeepEqual(endpoints[0], expected) {
+		t.Fatalf("expected %v, got %v", expected, endpoints)
+	}
+
+	// the floating IP bound to a target is published instead of it
+	create := endpoint.NewEndpoint("api.example.com.", endpoint.RecordTypeA, "10.0.0.11", "10.0.0.99")
+	err = p.ApplyChanges(context.Background(), &plan.Changes{
+		Create:    []*endpoint.Endpoint{create},
+		UpdateOld: []*endpoint.Endpoint{endpoints[0]},
+		UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpoint("www.example.com", endpoint.RecordTypeA, "10.0.0.11")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(create.Targets, endpoint.Targets{"10.0.0.11", "10.0.0.99"}) {
+		t.Errorf("the changes were modified: %v", create.Targets)
+	}
+
+	records := map[string][]string{}
+	client.ForEachRecordSet(zoneID, func(recordSet *recordsets.RecordSet) error {
+		sort.Strings(recordSet.Records)
+		records[recordSet.Name] = recordSet.Records
+		return nil
+	})
+	expectedRecords := map[string][]string{
+		"api.example.com.": {"10.0.0.99", "203.0.113.11"},
+		"www.example.com.": {"203.0.113.11"},
+	}
+	if !reflect.DeepEqual(records, expectedRecords) {
+		t.Errorf("expected records %v, got %v", expectedRecords, records)
+	}
+}
+
+func TestDesignateFloatingIPsDisabled(t *testing.T) {
+	client := newFakeDesignateClient()
+	client.floatingIPs = []floatingips.FloatingIP{{FloatingIP: "203.0.113.10", FixedIP: "10.0.0.10"}}
+	zoneID := client.AddZone(zones.Zone{
+		Name:   "example.com.",
+		Type:   "PRIMARY",
+		Status: "ACTIVE",
+	})
+	client.CreateRecordSet(zoneID, recordsets.CreateOpts{
+		Name:    "www.example.com.",
+		Type:    endpoint.RecordTypeA,
+		Records: []string{"203.0.113.10"},
+	})
+
+	endpoints, err := client.ToProvider().Records(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(endpoints) != 1 || !reflect.DeepEqual(endpoints[0].Targets, endpoint.Targets{"203.0.113.10"}) {
+		t.Errorf("expected the floating IP to be listed as it is, got %v", endpoints)
+	}
+}
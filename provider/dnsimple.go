@@ -19,6 +19,7 @@ package provider
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"strconv"
 	"strings"
@@ -103,13 +104,17 @@ const (
 	dnsimpleUpdate = "UPDATE"
 )
 
-// NewDnsimpleProvider initializes a new Dnsimple based provider
-func NewDnsimpleProvider(domainFilter DomainFilter, zoneIDFilter ZoneIDFilter, dryRun bool) (Provider, error) {
+// NewDnsimpleProvider initializes a new Dnsimple based provider, sending the API requests with the HTTP client
+// unless it's nil
+func NewDnsimpleProvider(domainFilter DomainFilter, zoneIDFilter ZoneIDFilter, dryRun bool, httpClient *http.Client) (Provider, error) {
 	oauthToken := os.Getenv("DNSIMPLE_OAUTH")
 	if len(oauthToken) == 0 {
 		return nil, fmt.Errorf("No dnsimple oauth token provided")
 	}
 	client := dnsimple.NewClient(dnsimple.NewOauthTokenCredentials(oauthToken))
+	if httpClient != nil {
+		client.HttpClient = httpClient
+	}
 	provider := &dnsimpleProvider{
 		client:       dnsimpleZoneService{service: client.Zones},
 		identity:     identityService{service: client.Identity},
@@ -226,7 +231,7 @@ func newDnsimpleChanges(action string, endpoints []*endpoint.Endpoint) []*dnsimp
 // submitChanges takes a zone and a collection of changes and makes all changes from the collection
 func (p *dnsimpleProvider) submitChanges(changes []*dnsimpleChange) error {
 	if len(changes) == 0 {
-		log.Infof("All records are already up to date")
+		log.Debug("All records are already up to date")
 		return nil
 	}
 	zones, err := p.Zones()
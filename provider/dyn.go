@@ -19,9 +19,11 @@ package provider
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -65,16 +67,23 @@ type DynConfig struct {
 	MinTTLSeconds int
 	AppVersion    string
 	DynVersion    string
+	// The client whose transport sends the REST API requests, defaults to the transport of the SDK
+	HTTPClient *http.Client
 }
 
-// ZoneSnapshot stores a single recordset for a zone for a single serial
+// ZoneSnapshot stores a single recordset for a zone for a single serial. It's safe for concurrent use,
+// the records are copied when they are stored and retrieved, so callers may modify them.
 type ZoneSnapshot struct {
+	lock      sync.RWMutex
 	serials   map[string]int
 	endpoints map[string][]*endpoint.Endpoint
 }
 
 // GetRecordsForSerial retrieves from memory the last known recordset for the (zone, serial) tuple
 func (snap *ZoneSnapshot) GetRecordsForSerial(zone string, serial int) []*endpoint.Endpoint {
+	snap.lock.RLock()
+	defer snap.lock.RUnlock()
+
 	lastSerial, ok := snap.serials[zone]
 	if !ok {
 		// no mapping
@@ -92,18 +101,31 @@ func (snap *ZoneSnapshot) GetRecordsForSerial(zone string, serial int) []*endpoi
 		return nil
 	}
 
-	return endpoints
+	return copyEndpoints(endpoints)
 }
 
 // StoreRecordsForSerial associates a result set with a (zone, serial)
 func (snap *ZoneSnapshot) StoreRecordsForSerial(zone string, serial int, records []*endpoint.Endpoint) {
+	snap.lock.Lock()
+	defer snap.lock.Unlock()
+
 	snap.serials[zone] = serial
-	snap.endpoints[zone] = records
+	snap.endpoints[zone] = copyEndpoints(records)
+}
+
+func copyEndpoints(endpoints []*endpoint.Endpoint) []*endpoint.Endpoint {
+	copies := make([]*endpoint.Endpoint, 0, len(endpoints))
+	for _, ep := range endpoints {
+		copies = append(copies, ep.DeepCopy())
+	}
+	return copies
 }
 
 // DynProvider is the actual interface impl.
 type dynProviderState struct {
 	DynConfig
+	// guards LastLoginErrorTime and DynVersion, which are changed by every login
+	loginLock          sync.Mutex
 	LastLoginErrorTime int64
 
 	ZoneSnapshot *ZoneSnapshot
@@ -428,13 +450,19 @@ func (d *dynProviderState) buildLinkToRecord(ep *endpoint.Endpoint) string {
 // This method also stores the DynAPI version.
 // Don't user the dynect.Client.Login()
 func (d *dynProviderState) login() (*dynect.Client, error) {
-	if d.LastLoginErrorTime != 0 {
-		secondsSinceLastError := unixNow() - d.LastLoginErrorTime
+	d.loginLock.Lock()
+	lastLoginErrorTime := d.LastLoginErrorTime
+	d.loginLock.Unlock()
+	if lastLoginErrorTime != 0 {
+		secondsSinceLastError := unixNow() - lastLoginErrorTime
 		if secondsSinceLastError < badLoginMinIntervalSeconds {
 			return nil, fmt.Errorf("will not attempt an API call as the last login failure occurred just %ds ago", secondsSinceLastError)
 		}
 	}
 	client := dynect.NewClient(d.CustomerName)
+	if d.HTTPClient != nil {
+		client.SetTransport(d.HTTPClient.Transport)
+	}
 
 	var req = dynect.LoginBlock{
 		Username:     d.Username,
@@ -444,6 +472,8 @@ func (d *dynProviderState) login() (*dynect.Client, error) {
 	var resp dynect.LoginResponse
 
 	err := client.Do("POST", "Session", req, &resp)
+	d.loginLock.Lock()
+	defer d.loginLock.Unlock()
 	if err != nil {
 		d.LastLoginErrorTime = unixNow()
 		return nil, err
@@ -457,6 +487,13 @@ func (d *dynProviderState) login() (*dynect.Client, error) {
 	return client, nil
 }
 
+// dynVersion returns the DynAPI version of the last login.
+func (d *dynProviderState) dynVersion() string {
+	d.loginLock.Lock()
+	defer d.loginLock.Unlock()
+	return d.DynVersion
+}
+
 // the zones we are allowed to touch. Currently only exact matches are considered, not all
 // zones with the given suffix
 func (d *dynProviderState) zones(client *dynect.Client) []string {
@@ -543,7 +580,7 @@ func (d *dynProviderState) commit(client *dynect.Client) error {
 		}
 		notes := fmt.Sprintf("Change by external-dns@%s, DynAPI@%s, %s on %s",
 			d.AppVersion,
-			d.DynVersion,
+			d.dynVersion(),
 			time.Now().Format(time.RFC3339),
 			h,
 		)
@@ -582,7 +619,7 @@ func (d *dynProviderState) Records(ctx context.Context) ([]*endpoint.Endpoint, e
 	}
 	defer client.Logout()
 
-	log.Debugf("Using DynAPI@%s", d.DynVersion)
+	log.Debugf("Using DynAPI@%s", d.dynVersion())
 
 	var result []*endpoint.Endpoint
 
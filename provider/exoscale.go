@@ -18,6 +18,7 @@ package provider
 
 import (
 	"context"
+	"net/http"
 	"strings"
 
 	"github.com/exoscale/egoscale"
@@ -48,9 +49,13 @@ type ExoscaleProvider struct {
 // ExoscaleOption for Provider options
 type ExoscaleOption func(*ExoscaleProvider)
 
-// NewExoscaleProvider returns ExoscaleProvider DNS provider interface implementation
-func NewExoscaleProvider(endpoint, apiKey, apiSecret string, dryRun bool, opts ...ExoscaleOption) *ExoscaleProvider {
+// NewExoscaleProvider returns ExoscaleProvider DNS provider interface implementation, sending the API requests
+// with the HTTP client unless it's nil
+func NewExoscaleProvider(endpoint, apiKey, apiSecret string, dryRun bool, httpClient *http.Client, opts ...ExoscaleOption) *ExoscaleProvider {
 	client := egoscale.NewClient(endpoint, apiKey, apiSecret)
+	if httpClient != nil {
+		client.HTTPClient = httpClient
+	}
 	return NewExoscaleProviderWithClient(endpoint, apiKey, apiSecret, client, dryRun, opts...)
 }
 
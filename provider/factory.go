@@ -0,0 +1,76 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"sigs.k8s.io/external-dns/pkg/apis/externaldns"
+)
+
+// Factory creates a provider from the ExternalDNS configuration.
+type Factory func(ctx context.Context, cfg *externaldns.Config) (Provider, error)
+
+var (
+	factoriesLock sync.RWMutex
+	factories     = map[string]Factory{}
+)
+
+// Register makes a provider available under the given name, usually from the init function of the
+// package implementing it, so forks can add providers without changing the wiring of ExternalDNS.
+// Register panics if a provider is registered twice under the same name or the factory is nil.
+func Register(name string, factory Factory) {
+	factoriesLock.Lock()
+	defer factoriesLock.Unlock()
+
+	if factory == nil {
+		panic(fmt.Sprintf("provider %s registered without factory", name))
+	}
+	if _, exists := factories[name]; exists {
+		panic(fmt.Sprintf("provider %s registered twice", name))
+	}
+	factories[name] = factory
+}
+
+// Registered returns the sorted names of the registered providers.
+func Registered() []string {
+	factoriesLock.RLock()
+	defer factoriesLock.RUnlock()
+
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New creates the provider registered under the given name.
+func New(ctx context.Context, name string, cfg *externaldns.Config) (Provider, error) {
+	factoriesLock.RLock()
+	factory, ok := factories[name]
+	factoriesLock.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown dns provider: %s (registered providers: %s)", name, strings.Join(Registered(), ", "))
+	}
+	return factory(ctx, cfg)
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/pkg/apis/externaldns"
+)
+
+func TestRegister(t *testing.T) {
+	defer func() {
+		factoriesLock.Lock()
+		delete(factories, "test-register")
+		factoriesLock.Unlock()
+	}()
+
+	var zones []string
+	Register("test-register", func(ctx context.Context, cfg *externaldns.Config) (Provider, error) {
+		zones = cfg.InMemoryZones
+		return NewInMemoryProvider(InMemoryInitZones(cfg.InMemoryZones)), nil
+	})
+	assert.Contains(t, Registered(), "test-register")
+
+	cfg := externaldns.NewConfig()
+	cfg.InMemoryZones = []string{"example.org"}
+	p, err := New(context.Background(), "test-register", cfg)
+	require.NoError(t, err)
+	assert.IsType(t, &InMemoryProvider{}, p)
+	assert.Equal(t, []string{"example.org"}, zones)
+
+	assert.Panics(t, func() {
+		Register("test-register", func(ctx context.Context, cfg *externaldns.Config) (Provider, error) {
+			return nil, nil
+		})
+	})
+	assert.Panics(t, func() {
+		Register("test-register-nil", nil)
+	})
+}
+
+func TestNewUnknownProvider(t *testing.T) {
+	_, err := New(context.Background(), "test-unknown", externaldns.NewConfig())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown dns provider: test-unknown")
+}
@@ -200,6 +200,19 @@ func (p *GoogleProvider) Zones(ctx context.Context) (map[string]*dns.ManagedZone
 	return zones, nil
 }
 
+// ZoneNames returns the DNS names of the managed zones.
+func (p *GoogleProvider) ZoneNames(ctx context.Context) ([]string, error) {
+	zones, err := p.Zones(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(zones))
+	for _, zone := range zones {
+		names = append(names, zone.DnsName)
+	}
+	return names, nil
+}
+
 // Records returns the list of records in all relevant zones.
 func (p *GoogleProvider) Records(ctx context.Context) (endpoints []*endpoint.Endpoint, _ error) {
 	zones, err := p.Zones(ctx)
@@ -218,13 +231,14 @@ func (p *GoogleProvider) Records(ctx context.Context) (endpoints []*endpoint.End
 		return nil
 	}
 
+	zoneErrors := ZoneErrors{}
 	for _, z := range zones {
 		if err := p.resourceRecordSetsClient.List(p.project, z.Name).Pages(ctx, f); err != nil {
-			return nil, err
+			zoneErrors[z.DnsName] = fmt.Errorf("failed to list the records: %v", err)
 		}
 	}
 
-	return endpoints, nil
+	return recordsResult(endpoints, zoneErrors)
 }
 
 // CreateRecords creates a given set of DNS records in the given hosted zone.
@@ -285,7 +299,7 @@ func (p *GoogleProvider) newFilteredRecords(endpoints []*endpoint.Endpoint) []*d
 // submitChange takes a zone and a Change and sends it to Google.
 func (p *GoogleProvider) submitChange(ctx context.Context, change *dns.Change) error {
 	if len(change.Additions) == 0 && len(change.Deletions) == 0 {
-		log.Info("All records are already up to date")
+		log.Debug("All records are already up to date")
 		return nil
 	}
 
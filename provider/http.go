@@ -0,0 +1,78 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/url"
+)
+
+type httpClientContextKey struct{}
+
+type httpTransportContextKey struct{}
+
+// WithHTTPClient returns a context passing the HTTP client of its API requests to the factory of a provider,
+// so the providers of several pipelines don't share the default HTTP client.
+func WithHTTPClient(ctx context.Context, client *http.Client) context.Context {
+	return context.WithValue(ctx, httpClientContextKey{}, client)
+}
+
+// HTTPClient returns the HTTP client of the context passed to the factory of a provider, or the default HTTP
+// client if there's none.
+func HTTPClient(ctx context.Context) *http.Client {
+	if client, ok := ctx.Value(httpClientContextKey{}).(*http.Client); ok && client != nil {
+		return client
+	}
+	return http.DefaultClient
+}
+
+// WithHTTPTransport returns a context passing the transport configured with --provider-http-proxy and
+// --provider-ca-bundle to the factory of a provider, for the providers building their own transport.
+func WithHTTPTransport(ctx context.Context, transport *http.Transport) context.Context {
+	return context.WithValue(ctx, httpTransportContextKey{}, transport)
+}
+
+// HTTPTransport returns the transport of the context passed to the factory of a provider, or the default
+// transport if there's none.
+func HTTPTransport(ctx context.Context) *http.Transport {
+	if transport, ok := ctx.Value(httpTransportContextKey{}).(*http.Transport); ok && transport != nil {
+		return transport
+	}
+	transport, _ := http.DefaultTransport.(*http.Transport)
+	return transport
+}
+
+// transportProxy returns the proxy of the transport of the provider API clients, so the providers building
+// their own transport use the same proxy.
+func transportProxy(transport *http.Transport) func(*http.Request) (*url.URL, error) {
+	if transport != nil && transport.Proxy != nil {
+		return transport.Proxy
+	}
+	return http.ProxyFromEnvironment
+}
+
+// withTransportRootCAs makes a TLS config without certificate authorities of its own trust those of the
+// transport of the provider API clients, which include the bundle of --provider-ca-bundle.
+func withTransportRootCAs(transport *http.Transport, config *tls.Config) *tls.Config {
+	if transport == nil || transport.TLSClientConfig == nil || config.RootCAs != nil {
+		return config
+	}
+	config.RootCAs = transport.TLSClientConfig.RootCAs
+	return config
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"errors"
 	"strings"
+	"sync"
 
 	log "github.com/sirupsen/logrus"
 
@@ -113,6 +114,15 @@ func (im *InMemoryProvider) CreateZone(newZone string) error {
 	return im.client.CreateZone(newZone)
 }
 
+// DelegateZone creates the zone if it doesn't exist yet. The in-memory zones don't have name servers to
+// delegate to.
+func (im *InMemoryProvider) DelegateZone(ctx context.Context, zone string) error {
+	if err := im.CreateZone(strings.TrimSuffix(zone, ".")); err != nil && err != ErrZoneAlreadyExists {
+		return err
+	}
+	return nil
+}
+
 // Zones returns filtered zones as specified by domain
 func (im *InMemoryProvider) Zones() map[string]string {
 	return im.filter.Zones(im.client.Zones())
@@ -208,12 +218,25 @@ func convertToInMemoryRecord(endpoints []*endpoint.Endpoint) []*inMemoryRecord {
 			Name:          ep.DNSName,
 			Target:        ep.Targets[0],
 			SetIdentifier: ep.SetIdentifier,
-			Labels:        ep.Labels,
+			Labels:        copyLabels(ep.Labels),
 		})
 	}
 	return records
 }
 
+// copyLabels copies the labels, so records stored or returned by the client don't share them with
+// the endpoints of the caller.
+func copyLabels(labels endpoint.Labels) endpoint.Labels {
+	if labels == nil {
+		return nil
+	}
+	copied := make(endpoint.Labels, len(labels))
+	for key, value := range labels {
+		copied[key] = value
+	}
+	return copied
+}
+
 type filter struct {
 	domain string
 }
@@ -264,26 +287,39 @@ type inMemoryChange struct {
 }
 
 type inMemoryClient struct {
-	zones map[string]zone
+	// guards zones, so records can be listed and changed concurrently
+	zonesLock sync.RWMutex
+	zones     map[string]zone
 }
 
 func newInMemoryClient() *inMemoryClient {
-	return &inMemoryClient{map[string]zone{}}
+	return &inMemoryClient{zones: map[string]zone{}}
 }
 
+// Records returns copies of the records of the zone, which aren't affected by later changes.
 func (c *inMemoryClient) Records(zone string) ([]*inMemoryRecord, error) {
+	c.zonesLock.RLock()
+	defer c.zonesLock.RUnlock()
+
 	if _, ok := c.zones[zone]; !ok {
 		return nil, ErrZoneNotFound
 	}
 
 	records := []*inMemoryRecord{}
-	for _, rec := range c.zones[zone] {
-		records = append(records, rec...)
+	for _, recs := range c.zones[zone] {
+		for _, rec := range recs {
+			record := *rec
+			record.Labels = copyLabels(rec.Labels)
+			records = append(records, &record)
+		}
 	}
 	return records, nil
 }
 
 func (c *inMemoryClient) Zones() map[string]string {
+	c.zonesLock.RLock()
+	defer c.zonesLock.RUnlock()
+
 	zones := map[string]string{}
 	for zone := range c.zones {
 		zones[zone] = zone
@@ -292,6 +328,9 @@ func (c *inMemoryClient) Zones() map[string]string {
 }
 
 func (c *inMemoryClient) CreateZone(zone string) error {
+	c.zonesLock.Lock()
+	defer c.zonesLock.Unlock()
+
 	if _, ok := c.zones[zone]; ok {
 		return ErrZoneAlreadyExists
 	}
@@ -301,6 +340,9 @@ func (c *inMemoryClient) CreateZone(zone string) error {
 }
 
 func (c *inMemoryClient) ApplyChanges(ctx context.Context, zoneID string, changes *inMemoryChange) error {
+	c.zonesLock.Lock()
+	defer c.zonesLock.Unlock()
+
 	if err := c.validateChangeBatch(zoneID, changes); err != nil {
 		return err
 	}
@@ -29,7 +29,8 @@ import (
 )
 
 var (
-	_ Provider = &InMemoryProvider{}
+	_ Provider      = &InMemoryProvider{}
+	_ ZoneDelegator = &InMemoryProvider{}
 )
 
 func TestInMemoryProvider(t *testing.T) {
@@ -820,3 +821,11 @@ func testInMemoryCreateZone(t *testing.T) {
 	err = im.CreateZone("zone")
 	assert.EqualError(t, err, ErrZoneAlreadyExists.Error())
 }
+
+func TestInMemoryDelegateZone(t *testing.T) {
+	im := NewInMemoryProvider(InMemoryInitZones([]string{"example.org"}))
+
+	require.NoError(t, im.DelegateZone(context.Background(), "team-a.example.org."))
+	require.NoError(t, im.DelegateZone(context.Background(), "team-a.example.org"))
+	assert.Equal(t, map[string]string{"example.org": "example.org", "team-a.example.org": "team-a.example.org"}, im.Zones())
+}
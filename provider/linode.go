@@ -74,8 +74,9 @@ type LinodeChangeDelete struct {
 	DomainRecord *linodego.DomainRecord
 }
 
-// NewLinodeProvider initializes a new Linode DNS based Provider.
-func NewLinodeProvider(domainFilter DomainFilter, dryRun bool, appVersion string) (*LinodeProvider, error) {
+// NewLinodeProvider initializes a new Linode DNS based Provider. The API requests are sent with the transport
+// of the HTTP client, nil for the default transport.
+func NewLinodeProvider(domainFilter DomainFilter, dryRun bool, appVersion string, client *http.Client) (*LinodeProvider, error) {
 	token, ok := os.LookupEnv("LINODE_TOKEN")
 	if !ok {
 		return nil, fmt.Errorf("no token found")
@@ -88,6 +89,9 @@ func NewLinodeProvider(domainFilter DomainFilter, dryRun bool, appVersion string
 			Source: tokenSource,
 		},
 	}
+	if client != nil {
+		oauth2Client.Transport.(*oauth2.Transport).Base = client.Transport
+	}
 
 	linodeClient := linodego.NewClient(oauth2Client)
 	linodeClient.SetUserAgent(fmt.Sprintf("ExternalDNS/%s linodego/%s", appVersion, linodego.Version))
@@ -135,7 +139,11 @@ func (p *LinodeProvider) Records(ctx context.Context) ([]*endpoint.Endpoint, err
 					name = zone.Domain
 				}
 
-				endpoints = append(endpoints, endpoint.NewEndpointWithTTL(name, string(r.Type), endpoint.TTL(r.TTLSec), r.Target))
+				ep := endpoint.NewEndpointWithTTL(name, string(r.Type), endpoint.TTL(r.TTLSec), linodeEndpointTarget(r))
+				if string(r.Type) == endpoint.RecordTypeSRV {
+					ep.WithTargetMetadata(ep.Targets[0], endpoint.TargetMetadata{Priority: int64(r.Priority), Weight: int64(r.Weight), Port: int64(r.Port)})
+				}
+				endpoints = append(endpoints, ep)
 			}
 		}
 	}
@@ -255,6 +263,33 @@ func getPriority() *int {
 	return &priority
 }
 
+// getTargetOptions returns the target, weight, port and priority of the Linode record of an endpoint target.
+// They are taken from the structured attributes of the target, if it has any. The priority, weight and port
+// of an SRV target, e.g. 0 50 80 api.example.org, are separate fields of the record.
+func getTargetOptions(ep *endpoint.Endpoint, target string) (string, *int, *int, *int) {
+	recordTarget := target
+	if ep.RecordType == endpoint.RecordTypeSRV {
+		if fields := strings.Fields(target); len(fields) == 4 {
+			recordTarget = fields[3]
+		}
+	}
+
+	metadata, ok := ep.GetTargetMetadata(target)
+	if !ok {
+		return recordTarget, getWeight(), getPort(), getPriority()
+	}
+	weight, port, priority := int(metadata.Weight), int(metadata.Port), int(metadata.Priority)
+	return recordTarget, &weight, &port, &priority
+}
+
+// linodeEndpointTarget returns the endpoint target of a Linode record, the reverse of getTargetOptions.
+func linodeEndpointTarget(r *linodego.DomainRecord) string {
+	if string(r.Type) == endpoint.RecordTypeSRV {
+		return fmt.Sprintf("%d %d %d %s", r.Priority, r.Weight, r.Port, r.Target)
+	}
+	return r.Target
+}
+
 // ApplyChanges applies a given set of changes in a given zone.
 func (p *LinodeProvider) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
 	recordsByZoneID := make(map[string][]*linodego.DomainRecord)
@@ -326,15 +361,16 @@ func (p *LinodeProvider) ApplyChanges(ctx context.Context, changes *plan.Changes
 			}
 
 			for _, target := range ep.Targets {
+				recordTarget, weight, port, priority := getTargetOptions(ep, target)
 				linodeCreates = append(linodeCreates, &LinodeChangeCreate{
 					Domain: zone,
 					Options: linodego.DomainRecordCreateOptions{
-						Target:   target,
+						Target:   recordTarget,
 						Name:     getStrippedRecordName(zone, ep),
 						Type:     recordType,
-						Weight:   getWeight(),
-						Port:     getPort(),
-						Priority: getPriority(),
+						Weight:   weight,
+						Port:     port,
+						Priority: priority,
 						TTLSec:   int(ep.RecordTTL),
 					},
 				})
@@ -377,10 +413,11 @@ func (p *LinodeProvider) ApplyChanges(ctx context.Context, changes *plan.Changes
 			matchedRecordsByTarget := make(map[string]*linodego.DomainRecord)
 
 			for _, record := range matchedRecords {
-				matchedRecordsByTarget[record.Target] = record
+				matchedRecordsByTarget[linodeEndpointTarget(record)] = record
 			}
 
 			for _, target := range ep.Targets {
+				recordTarget, weight, port, priority := getTargetOptions(ep, target)
 				if record, ok := matchedRecordsByTarget[target]; ok {
 					log.WithFields(log.Fields{
 						"zoneID":     zoneID,
@@ -394,12 +431,12 @@ func (p *LinodeProvider) ApplyChanges(ctx context.Context, changes *plan.Changes
 						Domain:       zone,
 						DomainRecord: record,
 						Options: linodego.DomainRecordUpdateOptions{
-							Target:   target,
+							Target:   recordTarget,
 							Name:     getStrippedRecordName(zone, ep),
 							Type:     recordType,
-							Weight:   getWeight(),
-							Port:     getPort(),
-							Priority: getPriority(),
+							Weight:   weight,
+							Port:     port,
+							Priority: priority,
 							TTLSec:   int(ep.RecordTTL),
 						},
 					})
@@ -418,12 +455,12 @@ func (p *LinodeProvider) ApplyChanges(ctx context.Context, changes *plan.Changes
 					linodeCreates = append(linodeCreates, &LinodeChangeCreate{
 						Domain: zone,
 						Options: linodego.DomainRecordCreateOptions{
-							Target:   target,
+							Target:   recordTarget,
 							Name:     getStrippedRecordName(zone, ep),
 							Type:     recordType,
-							Weight:   getWeight(),
-							Port:     getPort(),
-							Priority: getPriority(),
+							Weight:   weight,
+							Port:     port,
+							Priority: priority,
 							TTLSec:   int(ep.RecordTTL),
 						},
 					})
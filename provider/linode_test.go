@@ -138,11 +138,11 @@ func TestLinodeConvertRecordType(t *testing.T) {
 
 func TestNewLinodeProvider(t *testing.T) {
 	_ = os.Setenv("LINODE_TOKEN", "xxxxxxxxxxxxxxxxx")
-	_, err := NewLinodeProvider(NewDomainFilter([]string{"ext-dns-test.zalando.to."}), true, "1.0")
+	_, err := NewLinodeProvider(NewDomainFilter([]string{"ext-dns-test.zalando.to."}), true, "1.0", nil)
 	require.NoError(t, err)
 
 	_ = os.Unsetenv("LINODE_TOKEN")
-	_, err = NewLinodeProvider(NewDomainFilter([]string{"ext-dns-test.zalando.to."}), true, "1.0")
+	_, err = NewLinodeProvider(NewDomainFilter([]string{"ext-dns-test.zalando.to."}), true, "1.0", nil)
 	require.Error(t, err)
 }
 
@@ -499,6 +499,67 @@ func TestLinodeApplyChangesTargetRemoved(t *testing.T) {
 	mockDomainClient.AssertExpectations(t)
 }
 
+func TestLinodeSRVTargetMetadata(t *testing.T) {
+	mockDomainClient := MockDomainClient{}
+
+	provider := &LinodeProvider{
+		Client:       &mockDomainClient,
+		domainFilter: NewDomainFilter([]string{"bar.io"}),
+		DryRun:       false,
+	}
+
+	records := []*linodego.DomainRecord{{
+		ID:       21,
+		Type:     linodego.RecordTypeSRV,
+		Name:     "_sip._udp",
+		Target:   "sip.bar.io",
+		Priority: 0,
+		Weight:   50,
+		Port:     5060,
+	}}
+	mockDomainClient.On(
+		"ListDomains",
+		mock.Anything,
+		mock.Anything,
+	).Return(createZones(), nil)
+	mockDomainClient.On(
+		"ListDomainRecords",
+		mock.Anything,
+		2,
+		mock.Anything,
+	).Return(records, nil)
+
+	actual, err := provider.Records(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []*endpoint.Endpoint{
+		endpoint.NewEndpoint("_sip._udp.bar.io", endpoint.RecordTypeSRV, "0 50 5060 sip.bar.io").
+			WithTargetMetadata("0 50 5060 sip.bar.io", endpoint.TargetMetadata{Weight: 50, Port: 5060}),
+	}, actual)
+
+	// the weight is taken from the structured attributes of the target
+	weight, port, priority := 10, 5060, 0
+	mockDomainClient.On(
+		"UpdateDomainRecord",
+		mock.Anything,
+		2,
+		21,
+		linodego.DomainRecordUpdateOptions{
+			Type: "SRV", Name: "_sip._udp", Target: "sip.bar.io",
+			Priority: &priority, Weight: &weight, Port: &port, TTLSec: 0,
+		},
+	).Return(&linodego.DomainRecord{}, nil).Once()
+
+	err = provider.ApplyChanges(context.Background(), &plan.Changes{
+		UpdateNew: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("_sip._udp.bar.io", endpoint.RecordTypeSRV, "0 50 5060 sip.bar.io").
+				WithTargetMetadata("0 50 5060 sip.bar.io", endpoint.TargetMetadata{Weight: 10, Port: 5060}),
+		},
+	})
+	require.NoError(t, err)
+
+	mockDomainClient.AssertExpectations(t)
+}
+
 func TestLinodeApplyChangesNoChanges(t *testing.T) {
 	mockDomainClient := MockDomainClient{}
 
@@ -20,13 +20,18 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
 	"strings"
 
 	log "github.com/sirupsen/logrus"
 	api "gopkg.in/ns1/ns1-go.v2/rest"
+	"gopkg.in/ns1/ns1-go.v2/rest/model/data"
 	"gopkg.in/ns1/ns1-go.v2/rest/model/dns"
+	"gopkg.in/ns1/ns1-go.v2/rest/model/monitor"
 
 	"sigs.k8s.io/external-dns/endpoint"
 	"sigs.k8s.io/external-dns/plan"
@@ -41,8 +46,14 @@ const (
 	ns1Update = "UPDATE"
 	// ns1DefaultTTL is the default ttl for ttls that are not set
 	ns1DefaultTTL = 10
+	// the prefix of the notes of the monitoring jobs carrying the failure threshold of their health check,
+	// which NS1 doesn't have
+	ns1JobFailureThresholdNote = "failure threshold "
 )
 
+// the regions the monitoring jobs of the health checks run in, a quorum of them decides the status
+var ns1JobRegions = []string{"lga", "sjc", "ams"}
+
 // NS1DomainClient is a subset of the NS1 API the the provider uses, to ease testing
 type NS1DomainClient interface {
 	CreateRecord(r *dns.Record) (*http.Response, error)
@@ -50,6 +61,9 @@ type NS1DomainClient interface {
 	UpdateRecord(r *dns.Record) (*http.Response, error)
 	GetZone(zone string) (*dns.Zone, *http.Response, error)
 	ListZones() ([]*dns.Zone, *http.Response, error)
+	ListMonitoringJobs() ([]*monitor.Job, *http.Response, error)
+	CreateMonitoringJob(job *monitor.Job) (*http.Response, error)
+	DeleteMonitoringJob(id string) (*http.Response, error)
 }
 
 // NS1DomainService wraps the API and fulfills the NS1DomainClient interface
@@ -82,6 +96,21 @@ func (n NS1DomainService) ListZones() ([]*dns.Zone, *http.Response, error) {
 	return n.service.Zones.List()
 }
 
+// ListMonitoringJobs wraps the List method of the API's Jobs service
+func (n NS1DomainService) ListMonitoringJobs() ([]*monitor.Job, *http.Response, error) {
+	return n.service.Jobs.List()
+}
+
+// CreateMonitoringJob wraps the Create method of the API's Jobs service
+func (n NS1DomainService) CreateMonitoringJob(job *monitor.Job) (*http.Response, error) {
+	return n.service.Jobs.Create(job)
+}
+
+// DeleteMonitoringJob wraps the Delete method of the API's Jobs service
+func (n NS1DomainService) DeleteMonitoringJob(id string) (*http.Response, error) {
+	return n.service.Jobs.Delete(id)
+}
+
 // NS1Config passes cli args to the NS1Provider
 type NS1Config struct {
 	DomainFilter DomainFilter
@@ -89,6 +118,10 @@ type NS1Config struct {
 	NS1Endpoint  string
 	NS1IgnoreSSL bool
 	DryRun       bool
+	// The client of the API requests, defaults to the default HTTP client
+	HTTPClient *http.Client
+	// The transport whose proxy the insecure transport of --ns1-ignoressl uses, defaults to the environment
+	HTTPTransport *http.Transport
 }
 
 // NS1Provider is the NS1 provider
@@ -101,7 +134,11 @@ type NS1Provider struct {
 
 // NewNS1Provider creates a new NS1 Provider
 func NewNS1Provider(config NS1Config) (*NS1Provider, error) {
-	return newNS1ProviderWithHTTPClient(config, http.DefaultClient)
+	client := config.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return newNS1ProviderWithHTTPClient(config, client)
 }
 
 func newNS1ProviderWithHTTPClient(config NS1Config, client *http.Client) (*NS1Provider, error) {
@@ -119,7 +156,7 @@ func newNS1ProviderWithHTTPClient(config NS1Config, client *http.Client) (*NS1Pr
 		log.Info("ns1-ignoressl flag is True, skipping SSL verification")
 		defaultTransport := http.DefaultTransport.(*http.Transport)
 		tr := &http.Transport{
-			Proxy:                 defaultTransport.Proxy,
+			Proxy:                 transportProxy(config.HTTPTransport),
 			DialContext:           defaultTransport.DialContext,
 			MaxIdleConns:          defaultTransport.MaxIdleConns,
 			IdleConnTimeout:       defaultTransport.IdleConnTimeout,
@@ -127,7 +164,10 @@ func newNS1ProviderWithHTTPClient(config NS1Config, client *http.Client) (*NS1Pr
 			TLSHandshakeTimeout:   defaultTransport.TLSHandshakeTimeout,
 			TLSClientConfig:       &tls.Config{InsecureSkipVerify: true},
 		}
-		client.Transport = tr
+		// the client may be shared, so a copy gets the insecure transport
+		insecure := *client
+		insecure.Transport = tr
+		client = &insecure
 	}
 
 	apiClient := api.NewClient(client, clientArgs...)
@@ -170,6 +210,17 @@ func (p *NS1Provider) Records(ctx context.Context) ([]*endpoint.Endpoint, error)
 		}
 	}
 
+	// the monitoring jobs require the monitoring permissions, which accounts without health checks may lack
+	jobs, err := p.monitoringJobs()
+	if err != nil {
+		log.Debugf("Failed to list the monitoring jobs: %v", err)
+	}
+	for _, ep := range endpoints {
+		if job, ok := jobs[healthCheckMonitorName(ep)]; ok {
+			withNS1MonitoringJob(ep, job)
+		}
+	}
+
 	return endpoints, nil
 }
 
@@ -186,6 +237,11 @@ func ns1BuildRecord(zoneName string, change *ns1Change) *dns.Record {
 	}
 	record.TTL = ttl
 
+	// the description is written as note of the record, NS1 doesn't return it when listing a zone
+	if description, ok := change.Endpoint.GetProviderSpecificProperty(endpoint.DescriptionProperty); ok {
+		record.Meta = &data.Meta{Note: description.Value}
+	}
+
 	return record
 }
 
@@ -278,7 +334,163 @@ func (p *NS1Provider) ApplyChanges(ctx context.Context, changes *plan.Changes) e
 	combinedChanges = append(combinedChanges, newNS1Changes(ns1Update, changes.UpdateNew)...)
 	combinedChanges = append(combinedChanges, newNS1Changes(ns1Delete, changes.Delete)...)
 
-	return p.ns1SubmitChanges(combinedChanges)
+	if err := p.ns1SubmitChanges(combinedChanges); err != nil {
+		return err
+	}
+	p.applyMonitoringJobChanges(changes)
+	return nil
+}
+
+// SupportsHealthChecks returns true as the health checks of the endpoints are materialized as monitoring jobs,
+// which the filter chains of the records can use.
+func (p *NS1Provider) SupportsHealthChecks() bool {
+	return true
+}
+
+// monitoringJobs returns the monitoring jobs created by ExternalDNS by their name.
+func (p *NS1Provider) monitoringJobs() (map[string]*monitor.Job, error) {
+	list, _, err := p.client.ListMonitoringJobs()
+	if err != nil {
+		return nil, err
+	}
+	jobs := make(map[string]*monitor.Job)
+	for _, job := range list {
+		if strings.HasPrefix(job.Name, healthCheckMonitorNamePrefix) {
+			jobs[job.Name] = job
+		}
+	}
+	return jobs, nil
+}
+
+// applyMonitoringJobChanges creates the monitoring jobs of the health checks of the desired endpoints,
+// replaces the ones whose health check changed and deletes the ones of the deleted endpoints.
+func (p *NS1Provider) applyMonitoringJobChanges(changes *plan.Changes) {
+	desired := make(map[string]*monitor.Job)
+	for _, ep := range append(append([]*endpoint.Endpoint{}, changes.Create...), changes.UpdateNew...) {
+		if job := ns1MonitoringJob(ep); job != nil {
+			desired[job.Name] = job
+		}
+	}
+	var deleted []string
+	for _, ep := range append(append([]*endpoint.Endpoint{}, changes.UpdateOld...), changes.Delete...) {
+		if name := healthCheckMonitorName(ep); desired[name] == nil {
+			deleted = append(deleted, name)
+		}
+	}
+	if len(desired) == 0 && len(deleted) == 0 {
+		return
+	}
+
+	jobs, err := p.monitoringJobs()
+	if err != nil {
+		log.Errorf("Failed to list the monitoring jobs: %v", err)
+		return
+	}
+	for name, job := range desired {
+		current, ok := jobs[name]
+		if ok && ns1MonitoringJobString(current) == ns1MonitoringJobString(job) {
+			continue
+		}
+		if ok {
+			deleted = append(deleted, name)
+		}
+		log.Debugf("Desired change: CREATE monitoring job %q %s", name, ns1MonitoringJobString(job))
+		if p.dryRun {
+			continue
+		}
+		// the replaced job is deleted once the new one was created
+		if _, err := p.client.CreateMonitoringJob(job); err != nil {
+			log.Errorf("Failed to create monitoring job %q: %v", name, err)
+		}
+	}
+	for _, name := range deleted {
+		current, ok := jobs[name]
+		if !ok {
+			continue
+		}
+		log.Debugf("Desired change: DELETE monitoring job %q", name)
+		if p.dryRun {
+			continue
+		}
+		if _, err := p.client.DeleteMonitoringJob(current.ID); err != nil {
+			log.Errorf("Failed to delete monitoring job %q: %v", name, err)
+		}
+	}
+}
+
+// ns1MonitoringJob returns the monitoring job of the health check of an endpoint, or nil if it doesn't
+// have one. Like a Route53 health check, the job monitors the first target of the endpoint.
+func ns1MonitoringJob(ep *endpoint.Endpoint) *monitor.Job {
+	hc, err := ep.HealthCheck()
+	if err != nil {
+		log.Warnf("Ignoring the health check of %s: %v", ep.DNSName, err)
+		return nil
+	}
+	if hc == nil || len(ep.Targets) == 0 {
+		return nil
+	}
+
+	host := strings.TrimSuffix(ep.Targets[0], ".")
+	job := &monitor.Job{
+		Name:      healthCheckMonitorName(ep),
+		Active:    true,
+		Regions:   ns1JobRegions,
+		Frequency: hc.Interval,
+		Policy:    "quorum",
+		Notes:     ns1JobFailureThresholdNote + strconv.Itoa(hc.FailureThreshold),
+		// the checks are repeated before the job fails, unless a single failure is the threshold
+		RapidRecheck: hc.FailureThreshold > 1,
+	}
+	if hc.Protocol == endpoint.HealthCheckProtocolTCP {
+		job.Type = "tcp"
+		job.Config = monitor.Config{"host": host, "port": hc.Port}
+	} else {
+		job.Type = "http"
+		u := url.URL{Scheme: strings.ToLower(hc.Protocol), Host: net.JoinHostPort(host, strconv.Itoa(hc.Port)), Path: hc.Path}
+		job.Config = monitor.Config{"url": u.String(), "method": "GET"}
+	}
+	return job
+}
+
+func ns1MonitoringJobString(job *monitor.Job) string {
+	return fmt.Sprintf("%s %v every %ds (%s)", job.Type, job.Config, job.Frequency, job.Notes)
+}
+
+// withNS1MonitoringJob attaches the monitoring job of a record to its endpoint as health check.
+func withNS1MonitoringJob(ep *endpoint.Endpoint, job *monitor.Job) {
+	hc := &endpoint.HealthCheck{Interval: job.Frequency}
+	if threshold, err := strconv.Atoi(strings.TrimPrefix(job.Notes, ns1JobFailureThresholdNote)); err == nil {
+		hc.FailureThreshold = threshold
+	}
+	switch job.Type {
+	case "tcp":
+		hc.Protocol = endpoint.HealthCheckProtocolTCP
+		hc.Port = ns1ConfigInt(job.Config["port"])
+	case "http":
+		raw, _ := job.Config["url"].(string)
+		u, err := url.Parse(raw)
+		if err != nil {
+			log.Warnf("Ignoring monitoring job %q with invalid url %q: %v", job.Name, raw, err)
+			return
+		}
+		hc.Protocol = strings.ToUpper(u.Scheme)
+		hc.Port, _ = strconv.Atoi(u.Port())
+		hc.Path = u.Path
+	default:
+		return
+	}
+	ep.WithHealthCheck(hc)
+}
+
+// ns1ConfigInt returns an integer of the config of a monitoring job, which is a float once decoded from JSON.
+func ns1ConfigInt(value interface{}) int {
+	switch v := value.(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	}
+	return 0
 }
 
 // newNS1Changes returns a collection of Changes based on the given records and action.
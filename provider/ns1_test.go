@@ -28,6 +28,7 @@ import (
 	"github.com/stretchr/testify/require"
 	api "gopkg.in/ns1/ns1-go.v2/rest"
 	"gopkg.in/ns1/ns1-go.v2/rest/model/dns"
+	"gopkg.in/ns1/ns1-go.v2/rest/model/monitor"
 
 	"sigs.k8s.io/external-dns/endpoint"
 	"sigs.k8s.io/external-dns/plan"
@@ -78,6 +79,46 @@ func (m *MockNS1DomainClient) ListZones() ([]*dns.Zone, *http.Response, error) {
 	return zones, nil, nil
 }
 
+func (m *MockNS1DomainClient) ListMonitoringJobs() ([]*monitor.Job, *http.Response, error) {
+	return nil, nil, nil
+}
+
+func (m *MockNS1DomainClient) CreateMonitoringJob(job *monitor.Job) (*http.Response, error) {
+	return nil, nil
+}
+
+func (m *MockNS1DomainClient) DeleteMonitoringJob(id string) (*http.Response, error) {
+	return nil, nil
+}
+
+// MockNS1MonitoringJobs keeps the monitoring jobs which are created and deleted.
+type MockNS1MonitoringJobs struct {
+	MockNS1DomainClient
+	jobs map[string]*monitor.Job
+}
+
+func (m *MockNS1MonitoringJobs) ListMonitoringJobs() ([]*monitor.Job, *http.Response, error) {
+	jobs := []*monitor.Job{}
+	for _, job := range m.jobs {
+		jobs = append(jobs, job)
+	}
+	return jobs, nil, nil
+}
+
+func (m *MockNS1MonitoringJobs) CreateMonitoringJob(job *monitor.Job) (*http.Response, error) {
+	job.ID = fmt.Sprintf("job-%d", len(m.jobs)+1)
+	m.jobs[job.ID] = job
+	return nil, nil
+}
+
+func (m *MockNS1MonitoringJobs) DeleteMonitoringJob(id string) (*http.Response, error) {
+	if _, ok := m.jobs[id]; !ok {
+		return nil, fmt.Errorf("job %s doesn't exist", id)
+	}
+	delete(m.jobs, id)
+	return nil, nil
+}
+
 type MockNS1GetZoneFail struct{}
 
 func (m *MockNS1GetZoneFail) CreateRecord(r *dns.Record) (*http.Response, error) {
@@ -104,6 +145,18 @@ func (m *MockNS1GetZoneFail) ListZones() ([]*dns.Zone, *http.Response, error) {
 	return zones, nil, nil
 }
 
+func (m *MockNS1GetZoneFail) ListMonitoringJobs() ([]*monitor.Job, *http.Response, error) {
+	return nil, nil, nil
+}
+
+func (m *MockNS1GetZoneFail) CreateMonitoringJob(job *monitor.Job) (*http.Response, error) {
+	return nil, nil
+}
+
+func (m *MockNS1GetZoneFail) DeleteMonitoringJob(id string) (*http.Response, error) {
+	return nil, nil
+}
+
 type MockNS1ListZonesFail struct{}
 
 func (m *MockNS1ListZonesFail) CreateRecord(r *dns.Record) (*http.Response, error) {
@@ -126,6 +179,18 @@ func (m *MockNS1ListZonesFail) ListZones() ([]*dns.Zone, *http.Response, error)
 	return nil, nil, fmt.Errorf("no zones available")
 }
 
+func (m *MockNS1ListZonesFail) ListMonitoringJobs() ([]*monitor.Job, *http.Response, error) {
+	return nil, nil, nil
+}
+
+func (m *MockNS1ListZonesFail) CreateMonitoringJob(job *monitor.Job) (*http.Response, error) {
+	return nil, nil
+}
+
+func (m *MockNS1ListZonesFail) DeleteMonitoringJob(id string) (*http.Response, error) {
+	return nil, nil
+}
+
 func TestNS1Records(t *testing.T) {
 	provider := &NS1Provider{
 		client:       &MockNS1DomainClient{},
@@ -212,6 +277,16 @@ func TestNS1BuildRecord(t *testing.T) {
 	assert.Equal(t, "foo.com", record.Zone)
 	assert.Equal(t, "new-b.foo.com", record.Domain)
 	assert.Equal(t, 100, record.TTL)
+	require.NotNil(t, record.Meta)
+	assert.Nil(t, record.Meta.Note)
+
+	changeWithDescription := &ns1Change{
+		Action:   ns1Create,
+		Endpoint: endpoint.NewEndpoint("new-c", "A", "target").WithProviderSpecific(endpoint.DescriptionProperty, "Checkout frontend"),
+	}
+	record = ns1BuildRecord("foo.com", changeWithDescription)
+	require.NotNil(t, record.Meta)
+	assert.Equal(t, "Checkout frontend", record.Meta.Note)
 }
 
 func TestNS1ApplyChanges(t *testing.T) {
@@ -308,3 +383,53 @@ func TestNewNS1ChangesByZone(t *testing.T) {
 	assert.Len(t, changes["bar.com"], 1)
 	assert.Len(t, changes["foo.com"], 3)
 }
+
+func TestNS1HealthChecks(t *testing.T) {
+	client := &MockNS1MonitoringJobs{jobs: map[string]*monitor.Job{
+		"unmanaged": {ID: "unmanaged", Type: "tcp", Name: "console", Config: monitor.Config{"host": "8.8.8.8", "port": 53}},
+	}}
+	provider := &NS1Provider{
+		client:       client,
+		domainFilter: NewDomainFilter([]string{"foo.com."}),
+		zoneIDFilter: NewZoneIDFilter([]string{""}),
+	}
+	assert.True(t, provider.SupportsHealthChecks())
+
+	healthCheck := &endpoint.HealthCheck{Protocol: endpoint.HealthCheckProtocolHTTP, Port: 8080, Path: "/healthz", Interval: 30, FailureThreshold: 3}
+	desired := endpoint.NewEndpoint("test.foo.com", endpoint.RecordTypeA, "2.2.2.2").WithHealthCheck(healthCheck)
+	require.NoError(t, provider.ApplyChanges(context.Background(), &plan.Changes{Create: []*endpoint.Endpoint{desired}}))
+
+	require.Len(t, client.jobs, 2)
+	assert.Equal(t, &monitor.Job{
+		ID:           "job-2",
+		Type:         "http",
+		Name:         "external-dns: test.foo.com A",
+		Active:       true,
+		Regions:      ns1JobRegions,
+		Frequency:    30,
+		RapidRecheck: true,
+		Policy:       "quorum",
+		Config:       monitor.Config{"url": "http://2.2.2.2:8080/healthz", "method": "GET"},
+		Notes:        "failure threshold 3",
+	}, client.jobs["job-2"])
+
+	// the job is attached to the record as health check
+	records, err := provider.Records(context.Background())
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	hc, err := records[0].HealthCheck()
+	require.NoError(t, err)
+	assert.Equal(t, healthCheck, hc)
+
+	// changed health checks replace the job, the jobs of deleted records are deleted
+	updated := endpoint.NewEndpoint("test.foo.com", endpoint.RecordTypeA, "2.2.2.2").WithHealthCheck(&endpoint.HealthCheck{Protocol: endpoint.HealthCheckProtocolTCP, Port: 5432, Interval: 10, FailureThreshold: 1})
+	require.NoError(t, provider.ApplyChanges(context.Background(), &plan.Changes{UpdateOld: []*endpoint.Endpoint{desired}, UpdateNew: []*endpoint.Endpoint{updated}}))
+	require.Len(t, client.jobs, 2)
+	assert.NotContains(t, client.jobs, "job-2")
+	assert.Equal(t, monitor.Config{"host": "2.2.2.2", "port": 5432}, client.jobs["job-3"].Config)
+	assert.False(t, client.jobs["job-3"].RapidRecheck)
+
+	require.NoError(t, provider.ApplyChanges(context.Background(), &plan.Changes{Delete: []*endpoint.Endpoint{updated}}))
+	assert.Len(t, client.jobs, 1)
+	assert.Contains(t, client.jobs, "unmanaged")
+}
@@ -19,6 +19,7 @@ package provider
 import (
 	"context"
 	"io/ioutil"
+	"net/http"
 	"strings"
 
 	"github.com/oracle/oci-go-sdk/common"
@@ -82,10 +83,10 @@ func LoadOCIConfig(path string) (*OCIConfig, error) {
 	return &cfg, nil
 }
 
-// NewOCIProvider initialises a new OCI DNS based Provider.
-func NewOCIProvider(cfg OCIConfig, domainFilter DomainFilter, zoneIDFilter ZoneIDFilter, dryRun bool) (*OCIProvider, error) {
-	var client ociDNSClient
-	client, err := dns.NewDnsClientWithConfigurationProvider(common.NewRawConfigurationProvider(
+// NewOCIProvider initialises a new OCI DNS based Provider. The API requests are sent with the HTTP client, nil
+// for the default client of the OCI SDK.
+func NewOCIProvider(cfg OCIConfig, domainFilter DomainFilter, zoneIDFilter ZoneIDFilter, dryRun bool, httpClient *http.Client) (*OCIProvider, error) {
+	dnsClient, err := dns.NewDnsClientWithConfigurationProvider(common.NewRawConfigurationProvider(
 		cfg.Auth.TenancyID,
 		cfg.Auth.UserID,
 		cfg.Auth.Region,
@@ -96,6 +97,10 @@ func NewOCIProvider(cfg OCIConfig, domainFilter DomainFilter, zoneIDFilter ZoneI
 	if err != nil {
 		return nil, errors.Wrap(err, "initialising OCI DNS API client")
 	}
+	if httpClient != nil {
+		dnsClient.HTTPClient = httpClient
+	}
+	var client ociDNSClient = dnsClient
 
 	return &OCIProvider{
 		client:       client,
@@ -212,7 +217,7 @@ func (p *OCIProvider) ApplyChanges(ctx context.Context, changes *plan.Changes) e
 	ops = append(ops, p.newFilteredRecordOperations(changes.Delete, dns.RecordOperationOperationRemove)...)
 
 	if len(ops) == 0 {
-		log.Info("All records are already up to date")
+		log.Debug("All records are already up to date")
 		return nil
 	}
 
@@ -186,6 +186,7 @@ hKRtDhmSdWBo3tJK12RrAe4t7CUe8gMgTvU7ExlcA3xQkseFPx9K
 				NewDomainFilter([]string{"com"}),
 				NewZoneIDFilter([]string{""}),
 				false,
+				nil,
 			)
 			if err == nil {
 				require.NoError(t, err)
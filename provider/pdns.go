@@ -77,9 +77,10 @@ type TLSConfig struct {
 	ClientCertKeyFilePath string
 }
 
-func (tlsConfig *TLSConfig) setHTTPClient(pdnsClientConfig *pgo.Configuration) error {
+func (tlsConfig *TLSConfig) setHTTPClient(ctx context.Context, pdnsClientConfig *pgo.Configuration) error {
 	if !tlsConfig.TLSEnabled {
 		log.Debug("Skipping TLS for PDNS Provider.")
+		pdnsClientConfig.HTTPClient = HTTPClient(ctx)
 		return nil
 	}
 
@@ -94,8 +95,9 @@ func (tlsConfig *TLSConfig) setHTTPClient(pdnsClientConfig *pgo.Configuration) e
 	}
 
 	// Timeouts taken from net.http.DefaultTransport
+	providerTransport := HTTPTransport(ctx)
 	transporter := &http.Transport{
-		Proxy: http.ProxyFromEnvironment,
+		Proxy: transportProxy(providerTransport),
 		DialContext: (&net.Dialer{
 			Timeout:   30 * time.Second,
 			KeepAlive: 30 * time.Second,
@@ -105,7 +107,7 @@ func (tlsConfig *TLSConfig) setHTTPClient(pdnsClientConfig *pgo.Configuration) e
 		IdleConnTimeout:       90 * time.Second,
 		TLSHandshakeTimeout:   10 * time.Second,
 		ExpectContinueTimeout: 1 * time.Second,
-		TLSClientConfig:       tlsClientConfig,
+		TLSClientConfig:       withTransportRootCAs(providerTransport, tlsClientConfig),
 	}
 	pdnsClientConfig.HTTPClient = &http.Client{
 		Transport: transporter,
@@ -245,7 +247,7 @@ func NewPDNSProvider(ctx context.Context, config PDNSConfig) (*PDNSProvider, err
 
 	pdnsClientConfig := pgo.NewConfiguration()
 	pdnsClientConfig.BasePath = config.Server + apiBase
-	if err := config.TLSConfig.setHTTPClient(pdnsClientConfig); err != nil {
+	if err := config.TLSConfig.setHTTPClient(ctx, pdnsClientConfig); err != nil {
 		return nil, err
 	}
 
@@ -26,11 +26,67 @@ import (
 )
 
 // Provider defines the interface DNS providers should implement.
+//
+// Implementations must be safe for concurrent use: Records and ApplyChanges may be called from several
+// goroutines at once, e.g. when zones are reconciled in parallel. State shared between calls, like
+// caches, sessions or pagination options, has to be synchronized or kept local to a call. The endpoints
+// returned by Records belong to the caller, which may modify them.
 type Provider interface {
 	Records(ctx context.Context) ([]*endpoint.Endpoint, error)
 	ApplyChanges(ctx context.Context, changes *plan.Changes) error
 }
 
+// RecordsPager is implemented by providers which can list their records page by page, e.g. as the pages
+// of their API are returned, so the records of huge zones aren't held in one slice of the provider.
+type RecordsPager interface {
+	// RecordsPages calls fn with each page of the records until fn returns false. The pages belong to
+	// the caller like the records returned by Records. The records of the zones which could be listed
+	// are passed to fn before ZoneErrors is returned.
+	RecordsPages(ctx context.Context, fn func(page []*endpoint.Endpoint) bool) error
+}
+
+// RecordsPages lists the records of the provider page by page if it implements RecordsPager. The records
+// of the other providers are passed to fn as a single page.
+func RecordsPages(ctx context.Context, p Provider, fn func(page []*endpoint.Endpoint) bool) error {
+	if pager, ok := p.(RecordsPager); ok {
+		return pager.RecordsPages(ctx, fn)
+	}
+	records, err := p.Records(ctx)
+	if _, partial := err.(ZoneErrors); err != nil && !partial {
+		return err
+	}
+	fn(records)
+	return err
+}
+
+// ZoneDelegator is implemented by providers which can create zones and delegate them from their parent
+// zones, e.g. per-team subzones.
+type ZoneDelegator interface {
+	// DelegateZone creates the zone if it doesn't exist yet and creates or updates the NS records
+	// delegating it in its most specific parent zone.
+	DelegateZone(ctx context.Context, zone string) error
+}
+
+// ZoneLister is implemented by providers which can list the names of the zones they manage, e.g. to
+// track the synchronization of each zone.
+type ZoneLister interface {
+	ZoneNames(ctx context.Context) ([]string, error)
+}
+
+// HealthCheckProvider is implemented by providers which materialize the health checks of the endpoints,
+// see endpoint.HealthCheck, and report them with their records. The health checks are ignored for the others.
+type HealthCheckProvider interface {
+	SupportsHealthChecks() bool
+}
+
+// the prefix of the names of the monitors created by ExternalDNS for the health checks of endpoints
+const healthCheckMonitorNamePrefix = "external-dns: "
+
+// healthCheckMonitorName returns the name identifying the monitor created for the health check of an endpoint.
+func healthCheckMonitorName(ep *endpoint.Endpoint) string {
+	return healthCheckMonitorNamePrefix + strings.TrimSuffix(ep.DNSName, ".") + " " + ep.RecordType
+}
+
 type contextKey struct {
 	name string
 }
@@ -0,0 +1,114 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// ProviderSpecificValidator is implemented by providers which validate the provider-specific properties
+// of the desired endpoints, so endpoints with unknown or invalid properties, e.g. a typo in aws/weight,
+// are rejected with a warning naming their resource instead of being applied without the property.
+type ProviderSpecificValidator interface {
+	ValidateProviderSpecific(ep *endpoint.Endpoint) error
+}
+
+// ProviderSpecificSchema describes the provider-specific properties of a provider by the prefix of their
+// names, e.g. aws/, and a validation of the value of each known property. Properties with other prefixes
+// belong to other providers or to ExternalDNS itself and aren't validated.
+type ProviderSpecificSchema struct {
+	Prefix     string
+	Properties map[string]func(value string) error
+}
+
+// Validate returns an error for the first property of the endpoint with the prefix of the schema which
+// is unknown or has an invalid value.
+func (s ProviderSpecificSchema) Validate(ep *endpoint.Endpoint) error {
+	for _, property := range ep.ProviderSpecific {
+		if !strings.HasPrefix(property.Name, s.Prefix) {
+			continue
+		}
+		validate, ok := s.Properties[property.Name]
+		if !ok {
+			return fmt.Errorf("%s record %s has unknown provider-specific property %s, known properties: %s", ep.RecordType, ep.DNSName, property.Name, strings.Join(s.names(), ", "))
+		}
+		if err := validate(property.Value); err != nil {
+			return fmt.Errorf("%s record %s has invalid provider-specific property %s=%q: %v", ep.RecordType, ep.DNSName, property.Name, property.Value, err)
+		}
+	}
+	return nil
+}
+
+func (s ProviderSpecificSchema) names() []string {
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// anyPropertyValue accepts every value, e.g. of properties only checked for presence.
+func anyPropertyValue(string) error {
+	return nil
+}
+
+func boolPropertyValue(value string) error {
+	if _, err := strconv.ParseBool(value); err != nil {
+		return fmt.Errorf("not a boolean")
+	}
+	return nil
+}
+
+// intPropertyValue accepts integers between min and max.
+func intPropertyValue(min, max int64) func(string) error {
+	return func(value string) error {
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("not an integer")
+		}
+		if i < min || i > max {
+			return fmt.Errorf("not between %d and %d", min, max)
+		}
+		return nil
+	}
+}
+
+// enumPropertyValue accepts one of the given values.
+func enumPropertyValue(values ...string) func(string) error {
+	return func(value string) error {
+		for _, v := range values {
+			if value == v {
+				return nil
+			}
+		}
+		return fmt.Errorf("not one of %s", strings.Join(values, ", "))
+	}
+}
+
+// nonEmptyPropertyValue accepts every value but the empty one.
+func nonEmptyPropertyValue(value string) error {
+	if value == "" {
+		return fmt.Errorf("empty value")
+	}
+	return nil
+}
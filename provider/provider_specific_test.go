@@ -0,0 +1,98 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestProviderSpecificSchemaValidate(t *testing.T) {
+	schema := ProviderSpecificSchema{
+		Prefix: "example/",
+		Properties: map[string]func(string) error{
+			"example/enabled":  boolPropertyValue,
+			"example/priority": intPropertyValue(1, 10),
+			"example/mode":     enumPropertyValue("fast", "safe"),
+			"example/zone":     nonEmptyPropertyValue,
+			"example/flag":     anyPropertyValue,
+		},
+	}
+
+	for _, tc := range []struct {
+		title      string
+		properties endpoint.ProviderSpecific
+		err        string
+	}{
+		{
+			title: "no properties",
+		},
+		{
+			title: "valid properties",
+			properties: endpoint.ProviderSpecific{
+				{Name: "example/enabled", Value: "true"},
+				{Name: "example/priority", Value: "10"},
+				{Name: "example/mode", Value: "safe"},
+				{Name: "example/zone", Value: "eu"},
+				{Name: "example/flag", Value: ""},
+			},
+		},
+		{
+			title:      "properties of other providers",
+			properties: endpoint.ProviderSpecific{{Name: "other/priority", Value: "high"}},
+		},
+		{
+			title:      "unknown property",
+			properties: endpoint.ProviderSpecific{{Name: "example/priorty", Value: "1"}},
+			err:        "A record app.example.org has unknown provider-specific property example/priorty, known properties: example/enabled, example/flag, example/mode, example/priority, example/zone",
+		},
+		{
+			title:      "invalid boolean",
+			properties: endpoint.ProviderSpecific{{Name: "example/enabled", Value: "yes"}},
+			err:        `A record app.example.org has invalid provider-specific property example/enabled="yes": not a boolean`,
+		},
+		{
+			title:      "integer out of range",
+			properties: endpoint.ProviderSpecific{{Name: "example/priority", Value: "11"}},
+			err:        `A record app.example.org has invalid provider-specific property example/priority="11": not between 1 and 10`,
+		},
+		{
+			title:      "unknown enum value",
+			properties: endpoint.ProviderSpecific{{Name: "example/mode", Value: "slow"}},
+			err:        `A record app.example.org has invalid provider-specific property example/mode="slow": not one of fast, safe`,
+		},
+		{
+			title:      "empty value",
+			properties: endpoint.ProviderSpecific{{Name: "example/zone", Value: ""}},
+			err:        `A record app.example.org has invalid provider-specific property example/zone="": empty value`,
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			ep := endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeA, "1.2.3.4")
+			ep.ProviderSpecific = tc.properties
+			err := schema.Validate(ep)
+			if tc.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.err)
+			}
+		})
+	}
+}
@@ -17,9 +17,78 @@ limitations under the License.
 package provider
 
 import (
+	"context"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
 )
 
+// pagingProvider returns its records in pages of the given size.
+type pagingProvider struct {
+	recordingProvider
+	pageSize int
+}
+
+func (p *pagingProvider) RecordsPages(ctx context.Context, fn func(page []*endpoint.Endpoint) bool) error {
+	for i := 0; i < len(p.records); i += p.pageSize {
+		end := i + p.pageSize
+		if end > len(p.records) {
+			end = len(p.records)
+		}
+		if !fn(p.records[i:end]) {
+			return nil
+		}
+	}
+	return p.err
+}
+
+// collectPages returns the pages passed to fn by RecordsPages.
+func collectPages(t *testing.T, p Provider) ([][]*endpoint.Endpoint, error) {
+	var pages [][]*endpoint.Endpoint
+	err := RecordsPages(context.Background(), p, func(page []*endpoint.Endpoint) bool {
+		pages = append(pages, page)
+		return true
+	})
+	return pages, err
+}
+
+func TestRecordsPages(t *testing.T) {
+	a := endpoint.NewEndpoint("a.example.org", endpoint.RecordTypeA, "1.2.3.4")
+	b := endpoint.NewEndpoint("b.example.org", endpoint.RecordTypeA, "1.2.3.4")
+	c := endpoint.NewEndpoint("c.example.org", endpoint.RecordTypeA, "1.2.3.4")
+
+	// the records of providers without pages are a single page
+	pages, err := collectPages(t, &recordingProvider{records: []*endpoint.Endpoint{a, b, c}})
+	require.NoError(t, err)
+	assert.Equal(t, [][]*endpoint.Endpoint{{a, b, c}}, pages)
+
+	pager := &pagingProvider{recordingProvider: recordingProvider{records: []*endpoint.Endpoint{a, b, c}}, pageSize: 2}
+	pages, err = collectPages(t, pager)
+	require.NoError(t, err)
+	assert.Equal(t, [][]*endpoint.Endpoint{{a, b}, {c}}, pages)
+
+	// the listing stops once fn returns false
+	calls := 0
+	require.NoError(t, RecordsPages(context.Background(), pager, func(page []*endpoint.Endpoint) bool {
+		calls++
+		return false
+	}))
+	assert.Equal(t, 1, calls)
+
+	// the records of the zones which could be listed are passed along with ZoneErrors
+	zoneErrors := ZoneErrors{"other.org": assert.AnError}
+	pages, err = collectPages(t, &recordingProvider{records: []*endpoint.Endpoint{a}, err: zoneErrors})
+	assert.Equal(t, zoneErrors, err)
+	assert.Equal(t, [][]*endpoint.Endpoint{{a}}, pages)
+
+	pages, err = collectPages(t, &recordingProvider{records: []*endpoint.Endpoint{a}, err: assert.AnError})
+	assert.Equal(t, assert.AnError, err)
+	assert.Empty(t, pages)
+}
+
 func TestEnsureTrailingDot(t *testing.T) {
 	for _, tc := range []struct {
 		input, expected string
@@ -0,0 +1,95 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// recordTypeFilter is a Provider which hides the records of unmanaged types of another provider and
+// refuses to change them, so they are safe even if the planner or a registry misbehaves.
+type recordTypeFilter struct {
+	provider    Provider
+	recordTypes map[string]bool
+}
+
+// NewRecordTypeFilter returns a Provider which only lists and changes the records of the given types
+// of the provider. Without any types the provider is returned as it is.
+func NewRecordTypeFilter(p Provider, recordTypes []string) Provider {
+	if len(recordTypes) == 0 {
+		return p
+	}
+	filter := &recordTypeFilter{provider: p, recordTypes: map[string]bool{}}
+	for _, recordType := range recordTypes {
+		filter.recordTypes[recordType] = true
+	}
+	return filter
+}
+
+// Records returns the records of the managed types. The records of the zones which could be listed are
+// returned together with ZoneErrors.
+func (f *recordTypeFilter) Records(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	records, err := f.provider.Records(ctx)
+	if _, partial := err.(ZoneErrors); err != nil && !partial {
+		return nil, err
+	}
+
+	filtered := make([]*endpoint.Endpoint, 0, len(records))
+	for _, ep := range records {
+		if f.recordTypes[ep.RecordType] {
+			filtered = append(filtered, ep)
+		}
+	}
+	return filtered, err
+}
+
+// RecordsPages passes the records of the managed types of each page of the provider to fn.
+func (f *recordTypeFilter) RecordsPages(ctx context.Context, fn func(page []*endpoint.Endpoint) bool) error {
+	return RecordsPages(ctx, f.provider, func(page []*endpoint.Endpoint) bool {
+		filtered := make([]*endpoint.Endpoint, 0, len(page))
+		for _, ep := range page {
+			if f.recordTypes[ep.RecordType] {
+				filtered = append(filtered, ep)
+			}
+		}
+		return fn(filtered)
+	})
+}
+
+// ApplyChanges applies the changes of records of the managed types and drops the others.
+func (f *recordTypeFilter) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	filtered, err := changes.Filter(true, func(old, ep *endpoint.Endpoint) bool {
+		if old != nil && (!f.recordTypes[ep.RecordType] || !f.recordTypes[old.RecordType]) {
+			log.Warnf("Refusing to update %s (%s -> %s), the record type isn't managed", ep.DNSName, old.RecordType, ep.RecordType)
+			return false
+		}
+		if !f.recordTypes[ep.RecordType] {
+			log.Warnf("Refusing to change %s (%s), the record type isn't managed", ep.DNSName, ep.RecordType)
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	return f.provider.ApplyChanges(ctx, filtered)
+}
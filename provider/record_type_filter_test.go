@@ -0,0 +1,109 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// recordingProvider returns the given records and records the changes it is asked to apply.
+type recordingProvider struct {
+	records []*endpoint.Endpoint
+	err     error
+	applied []*plan.Changes
+}
+
+func (p *recordingProvider) Records(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	return p.records, p.err
+}
+
+func (p *recordingProvider) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	p.applied = append(p.applied, changes)
+	return nil
+}
+
+func TestNewRecordTypeFilter(t *testing.T) {
+	a := endpoint.NewEndpoint("www.example.org", endpoint.RecordTypeA, "1.2.3.4")
+	cname := endpoint.NewEndpoint("web.example.org", endpoint.RecordTypeCNAME, "www.example.org")
+	mx := endpoint.NewEndpoint("example.org", "MX", "10 mail.example.org")
+	ns := endpoint.NewEndpoint("sub.example.org", "NS", "ns1.example.com")
+	p := &recordingProvider{records: []*endpoint.Endpoint{a, cname, mx, ns}}
+	filter := NewRecordTypeFilter(p, []string{endpoint.RecordTypeA, endpoint.RecordTypeCNAME})
+
+	records, err := filter.Records(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []*endpoint.Endpoint{a, cname}, records)
+
+	require.NoError(t, filter.ApplyChanges(context.Background(), &plan.Changes{
+		Create:    []*endpoint.Endpoint{a, mx},
+		UpdateOld: []*endpoint.Endpoint{cname, ns, a},
+		UpdateNew: []*endpoint.Endpoint{cname, ns, mx},
+		Delete:    []*endpoint.Endpoint{ns, cname},
+	}))
+	require.Len(t, p.applied, 1)
+	assert.Equal(t, &plan.Changes{
+		Create:    []*endpoint.Endpoint{a},
+		UpdateOld: []*endpoint.Endpoint{cname},
+		UpdateNew: []*endpoint.Endpoint{cname},
+		Delete:    []*endpoint.Endpoint{cname},
+	}, p.applied[0])
+
+	// the updates can't be paired without their old versions
+	assert.Error(t, filter.ApplyChanges(context.Background(), &plan.Changes{UpdateNew: []*endpoint.Endpoint{cname}}))
+	assert.Len(t, p.applied, 1)
+}
+
+func TestRecordTypeFilterRecordsPages(t *testing.T) {
+	a := endpoint.NewEndpoint("www.example.org", endpoint.RecordTypeA, "1.2.3.4")
+	mx := endpoint.NewEndpoint("example.org", "MX", "10 mail.example.org")
+	cname := endpoint.NewEndpoint("web.example.org", endpoint.RecordTypeCNAME, "www.example.org")
+	p := &pagingProvider{recordingProvider: recordingProvider{records: []*endpoint.Endpoint{a, mx, cname}}, pageSize: 2}
+
+	pages, err := collectPages(t, NewRecordTypeFilter(p, []string{endpoint.RecordTypeA, endpoint.RecordTypeCNAME}))
+	require.NoError(t, err)
+	assert.Equal(t, [][]*endpoint.Endpoint{{a}, {cname}}, pages)
+}
+
+func TestRecordTypeFilterErrors(t *testing.T) {
+	a := endpoint.NewEndpoint("www.example.org", endpoint.RecordTypeA, "1.2.3.4")
+	mx := endpoint.NewEndpoint("example.org", "MX", "10 mail.example.org")
+	zoneErrors := ZoneErrors{"broken.org": errors.New("500 Internal Server Error")}
+	p := &recordingProvider{records: []*endpoint.Endpoint{a, mx}, err: zoneErrors}
+	filter := NewRecordTypeFilter(p, []string{endpoint.RecordTypeA})
+
+	records, err := filter.Records(context.Background())
+	assert.Equal(t, zoneErrors, err)
+	assert.Equal(t, []*endpoint.Endpoint{a}, records)
+
+	p.err = errors.New("401 Unauthorized")
+	records, err = filter.Records(context.Background())
+	assert.EqualError(t, err, "401 Unauthorized")
+	assert.Nil(t, records)
+}
+
+func TestRecordTypeFilterWithoutTypes(t *testing.T) {
+	p := &recordingProvider{}
+	assert.Equal(t, p, NewRecordTypeFilter(p, nil))
+}
@@ -27,6 +27,10 @@ func TestRecordTypeFilter(t *testing.T) {
 			"A",
 			true,
 		},
+		{
+			"AAAA",
+			true,
+		},
 		{
 			"CNAME",
 			true,
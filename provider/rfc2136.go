@@ -42,6 +42,9 @@ type rfc2136Provider struct {
 	insecure      bool
 	axfr          bool
 
+	// secondaries notified after the zone was updated
+	notify []string
+
 	// only consider hosted zones managing domains ending in this suffix
 	domainFilter DomainFilter
 	dryRun       bool
@@ -61,10 +64,11 @@ var (
 type rfc2136Actions interface {
 	SendMessage(msg *dns.Msg) error
 	IncomeTransfer(m *dns.Msg, a string) (env chan *dns.Envelope, err error)
+	SendNotify(msg *dns.Msg, secondary string) error
 }
 
 // NewRfc2136Provider is a factory function for OpenStack rfc2136 providers
-func NewRfc2136Provider(host string, port int, zoneName string, insecure bool, keyName string, secret string, secretAlg string, axfr bool, domainFilter DomainFilter, dryRun bool, actions rfc2136Actions) (Provider, error) {
+func NewRfc2136Provider(host string, port int, zoneName string, insecure bool, keyName string, secret string, secretAlg string, axfr bool, notify []string, domainFilter DomainFilter, dryRun bool, actions rfc2136Actions) (Provider, error) {
 	secretAlgChecked, ok := tsigAlgs[secretAlg]
 	if !ok && !insecure {
 		return nil, errors.Errorf("%s is not supported TSIG algorithm", secretAlg)
@@ -78,6 +82,12 @@ func NewRfc2136Provider(host string, port int, zoneName string, insecure bool, k
 		dryRun:       dryRun,
 		axfr:         axfr,
 	}
+	for _, secondary := range notify {
+		if _, _, err := net.SplitHostPort(secondary); err != nil {
+			secondary = net.JoinHostPort(secondary, "53")
+		}
+		r.notify = append(r.notify, secondary)
+	}
 	if actions != nil {
 		r.actions = actions
 	} else {
@@ -237,11 +247,34 @@ func (r rfc2136Provider) ApplyChanges(ctx context.Context, changes *plan.Changes
 		if err != nil {
 			return fmt.Errorf("RFC2136 update failed: %v", err)
 		}
+		r.notifySecondaries()
 	}
 
 	return nil
 }
 
+// notifySecondaries sends a NOTIFY for the zone to the configured secondaries, so they transfer the changes
+// right away instead of at their next refresh. Failures are only logged, the secondaries still pick up the
+// changes once the refresh interval of the zone has passed.
+func (r rfc2136Provider) notifySecondaries() {
+	if r.dryRun {
+		return
+	}
+	for _, secondary := range r.notify {
+		m := new(dns.Msg)
+		m.SetNotify(r.zoneName)
+		if !r.insecure {
+			m.SetTsig(r.tsigKeyName, r.tsigSecretAlg, 300, time.Now().Unix())
+		}
+
+		if err := r.actions.SendNotify(m, secondary); err != nil {
+			log.Warnf("Unable to notify secondary %s of the changes of zone %s: %v", secondary, r.zoneName, err)
+			continue
+		}
+		log.Debugf("Notified secondary %s of the changes of zone %s", secondary, r.zoneName)
+	}
+}
+
 func (r rfc2136Provider) UpdateRecord(m *dns.Msg, ep *endpoint.Endpoint) error {
 	err := r.RemoveRecord(m, ep)
 	if err != nil {
@@ -313,3 +346,19 @@ func (r rfc2136Provider) SendMessage(msg *dns.Msg) error {
 	log.Debugf("SendMessage.success")
 	return nil
 }
+
+func (r rfc2136Provider) SendNotify(msg *dns.Msg, secondary string) error {
+	c := new(dns.Client)
+	if !r.insecure {
+		c.TsigSecret = map[string]string{r.tsigKeyName: r.tsigSecret}
+	}
+
+	resp, _, err := c.Exchange(msg, secondary)
+	if err != nil {
+		return err
+	}
+	if resp != nil && resp.Rcode != dns.RcodeSuccess {
+		return fmt.Errorf("bad return code: %s", dns.RcodeToString[resp.Rcode])
+	}
+	return nil
+}
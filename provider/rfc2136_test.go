@@ -18,6 +18,8 @@ package provider
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strings"
 	"testing"
 
@@ -30,9 +32,11 @@ import (
 )
 
 type rfc2136Stub struct {
-	output     []*dns.Envelope
-	updateMsgs []*dns.Msg
-	createMsgs []*dns.Msg
+	output      []*dns.Envelope
+	updateMsgs  []*dns.Msg
+	createMsgs  []*dns.Msg
+	notified    []string
+	notifyError error
 }
 
 func newStub() *rfc2136Stub {
@@ -71,6 +75,14 @@ func (r *rfc2136Stub) SendMessage(msg *dns.Msg) error {
 	return nil
 }
 
+func (r *rfc2136Stub) SendNotify(msg *dns.Msg, secondary string) error {
+	if msg.Opcode != dns.OpcodeNotify {
+		return fmt.Errorf("unexpected opcode %s", dns.OpcodeToString[msg.Opcode])
+	}
+	r.notified = append(r.notified, secondary)
+	return r.notifyError
+}
+
 func (r *rfc2136Stub) setOutput(output []string) error {
 	r.output = make([]*dns.Envelope, len(output))
 	for i, e := range output {
@@ -98,7 +110,7 @@ func (r *rfc2136Stub) IncomeTransfer(m *dns.Msg, a string) (env chan *dns.Envelo
 }
 
 func createRfc2136StubProvider(stub *rfc2136Stub) (Provider, error) {
-	return NewRfc2136Provider("", 0, "", false, "key", "secret", "hmac-sha512", true, DomainFilter{}, false, stub)
+	return NewRfc2136Provider("", 0, "", false, "key", "secret", "hmac-sha512", true, nil, DomainFilter{}, false, stub)
 }
 
 // TestRfc2136GetRecordsMultipleTargets simulates a single record with multiple targets.
@@ -198,3 +210,54 @@ func TestRfc2136ApplyChanges(t *testing.T) {
 	assert.True(t, strings.Contains(stub.updateMsgs[1].String(), "v2.foobar.com"))
 
 }
+
+func TestRfc2136NotifySecondaries(t *testing.T) {
+	changes := &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			{
+				DNSName:    "v1.foo.com",
+				RecordType: "A",
+				Targets:    []string{"1.2.3.4"},
+			},
+		},
+	}
+
+	for _, tc := range []struct {
+		title       string
+		changes     *plan.Changes
+		dryRun      bool
+		notifyError error
+		expected    []string
+	}{
+		{
+			title:    "secondaries are notified after the zone was updated",
+			changes:  changes,
+			expected: []string{"10.0.0.1:53", "10.0.0.2:5353"},
+		},
+		{
+			title:       "notification failures don't fail the update",
+			changes:     changes,
+			notifyError: errors.New("connection refused"),
+			expected:    []string{"10.0.0.1:53", "10.0.0.2:5353"},
+		},
+		{
+			title:   "secondaries aren't notified without changes",
+			changes: &plan.Changes{},
+		},
+		{
+			title:   "secondaries aren't notified in dry-run mode",
+			changes: changes,
+			dryRun:  true,
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			stub := newStub()
+			stub.notifyError = tc.notifyError
+			provider, err := NewRfc2136Provider("", 0, "foo.com", false, "key", "secret", "hmac-sha512", true, []string{"10.0.0.1", "10.0.0.2:5353"}, DomainFilter{}, tc.dryRun, stub)
+			assert.NoError(t, err)
+
+			assert.NoError(t, provider.ApplyChanges(context.Background(), tc.changes))
+			assert.Equal(t, tc.expected, stub.notified)
+		})
+	}
+}
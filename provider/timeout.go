@@ -0,0 +1,188 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// timeoutProvider is a Provider which gives up on the calls of another provider which take longer than the
+// timeout, so a hung API doesn't stall the synchronization. The context of a call is cancelled when it times
+// out; a provider ignoring its context may still finish the call in the background. The next changes aren't
+// applied until such a call returned, so the changes of two synchronizations never overlap.
+type timeoutProvider struct {
+	provider Provider
+	timeout  time.Duration
+
+	// Closed when the last ApplyChanges call of the provider returned
+	applied     chan struct{}
+	appliedLock sync.Mutex
+}
+
+// NewTimeoutProvider returns a Provider whose calls of the provider time out after the given duration.
+// Without a timeout the provider is returned as it is.
+func NewTimeoutProvider(p Provider, timeout time.Duration) Provider {
+	if timeout <= 0 {
+		return p
+	}
+	return &timeoutProvider{provider: p, timeout: timeout}
+}
+
+// Records returns the records of the provider unless listing them times out.
+func (p *timeoutProvider) Records(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	type result struct {
+		records []*endpoint.Endpoint
+		err     error
+	}
+	done := make(chan result, 1)
+	go func() {
+		records, err := p.provider.Records(ctx)
+		done <- result{records, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.records, r.err
+	case <-ctx.Done():
+		return nil, p.timeoutError(ctx, "listing the records")
+	}
+}
+
+// RecordsPages passes the pages of the records of the provider to fn unless listing them times out. The
+// pages are listed in the background and passed to fn by the calling goroutine, so fn isn't called once
+// the listing timed out.
+func (p *timeoutProvider) RecordsPages(ctx context.Context, fn func(page []*endpoint.Endpoint) bool) error {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	pages := make(chan []*endpoint.Endpoint)
+	done := make(chan error, 1)
+	go func() {
+		done <- RecordsPages(ctx, p.provider, func(page []*endpoint.Endpoint) bool {
+			select {
+			case pages <- page:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+
+	for {
+		select {
+		case page := <-pages:
+			if !fn(page) {
+				return nil
+			}
+		case err := <-done:
+			return err
+		case <-ctx.Done():
+			return p.timeoutError(ctx, "listing the records")
+		}
+	}
+}
+
+// ApplyChanges applies the changes with the provider unless applying them times out.
+func (p *timeoutProvider) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	p.appliedLock.Lock()
+	defer p.appliedLock.Unlock()
+	if p.applied != nil {
+		select {
+		case <-p.applied:
+		case <-ctx.Done():
+			return p.timeoutError(ctx, "waiting for the changes of a previous synchronization")
+		}
+	}
+
+	applied := make(chan struct{})
+	p.applied = applied
+	done := make(chan error, 1)
+	go func() {
+		done <- p.provider.ApplyChanges(ctx, changes)
+		close(applied)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return p.timeoutError(ctx, "applying the changes")
+	}
+}
+
+func (p *timeoutProvider) timeoutError(ctx context.Context, operation string) error {
+	return timeoutError(ctx, p.timeout, operation)
+}
+
+// timeoutEndpointAdjuster is an EndpointAdjuster which gives up on the calls of another adjuster which take
+// longer than the timeout, like timeoutProvider.
+type timeoutEndpointAdjuster struct {
+	adjuster EndpointAdjuster
+	timeout  time.Duration
+}
+
+// NewTimeoutEndpointAdjuster returns an EndpointAdjuster whose calls of the adjuster time out after the given
+// duration, e.g. of a provider wrapped by NewTimeoutProvider. Without a timeout the adjuster is returned as
+// it is.
+func NewTimeoutEndpointAdjuster(a EndpointAdjuster, timeout time.Duration) EndpointAdjuster {
+	if timeout <= 0 {
+		return a
+	}
+	return &timeoutEndpointAdjuster{adjuster: a, timeout: timeout}
+}
+
+// AdjustEndpoints returns the endpoints adjusted by the adjuster unless adjusting them times out.
+func (a *timeoutEndpointAdjuster) AdjustEndpoints(ctx context.Context, endpoints []*endpoint.Endpoint) ([]*endpoint.Endpoint, error) {
+	ctx, cancel := context.WithTimeout(ctx, a.timeout)
+	defer cancel()
+
+	type result struct {
+		endpoints []*endpoint.Endpoint
+		err       error
+	}
+	done := make(chan result, 1)
+	go func() {
+		adjusted, err := a.adjuster.AdjustEndpoints(ctx, endpoints)
+		done <- result{adjusted, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.endpoints, r.err
+	case <-ctx.Done():
+		return nil, timeoutError(ctx, a.timeout, "adjusting the endpoints")
+	}
+}
+
+func timeoutError(ctx context.Context, timeout time.Duration, operation string) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("the provider timed out %s after %s", operation, timeout)
+	}
+	return ctx.Err()
+}
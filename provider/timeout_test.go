@@ -0,0 +1,129 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// hangingProvider blocks every call until it's released, ignoring the context like a hung API client.
+type hangingProvider struct {
+	release chan struct{}
+}
+
+func (p *hangingProvider) Records(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	<-p.release
+	return nil, nil
+}
+
+func (p *hangingProvider) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	<-p.release
+	return nil
+}
+
+func (p *hangingProvider) AdjustEndpoints(ctx context.Context, endpoints []*endpoint.Endpoint) ([]*endpoint.Endpoint, error) {
+	<-p.release
+	return endpoints, nil
+}
+
+func TestTimeoutProvider(t *testing.T) {
+	p := &recordingProvider{records: []*endpoint.Endpoint{endpoint.NewEndpoint("www.example.org", endpoint.RecordTypeA, "1.2.3.4")}}
+	assert.Equal(t, p, NewTimeoutProvider(p, 0))
+
+	timeout := NewTimeoutProvider(p, time.Minute)
+	records, err := timeout.Records(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, p.records, records)
+	require.NoError(t, timeout.ApplyChanges(context.Background(), &plan.Changes{}))
+	assert.Len(t, p.applied, 1)
+
+	// the records of other zones are returned together with ZoneErrors
+	p.err = ZoneErrors{"other.org": assert.AnError}
+	records, err = timeout.Records(context.Background())
+	assert.Equal(t, p.err, err)
+	assert.Equal(t, p.records, records)
+}
+
+func TestTimeoutProviderRecordsPages(t *testing.T) {
+	a := endpoint.NewEndpoint("a.example.org", endpoint.RecordTypeA, "1.2.3.4")
+	b := endpoint.NewEndpoint("b.example.org", endpoint.RecordTypeA, "1.2.3.4")
+	p := &pagingProvider{recordingProvider: recordingProvider{records: []*endpoint.Endpoint{a, b}}, pageSize: 1}
+	timeout := NewTimeoutProvider(p, time.Minute)
+
+	pages, err := collectPages(t, timeout)
+	require.NoError(t, err)
+	assert.Equal(t, [][]*endpoint.Endpoint{{a}, {b}}, pages)
+
+	calls := 0
+	require.NoError(t, RecordsPages(context.Background(), timeout, func(page []*endpoint.Endpoint) bool {
+		calls++
+		return false
+	}))
+	assert.Equal(t, 1, calls)
+
+	hanging := &hangingProvider{release: make(chan struct{})}
+	defer close(hanging.release)
+	pages, err = collectPages(t, NewTimeoutProvider(hanging, 10*time.Millisecond))
+	assert.EqualError(t, err, "the provider timed out listing the records after 10ms")
+	assert.Empty(t, pages)
+}
+
+func TestTimeoutProviderTimesOut(t *testing.T) {
+	p := &hangingProvider{release: make(chan struct{})}
+	defer close(p.release)
+	timeout := NewTimeoutProvider(p, 10*time.Millisecond)
+
+	records, err := timeout.Records(context.Background())
+	assert.EqualError(t, err, "the provider timed out listing the records after 10ms")
+	assert.Nil(t, records)
+	assert.EqualError(t, timeout.ApplyChanges(context.Background(), &plan.Changes{}), "the provider timed out applying the changes after 10ms")
+	// the abandoned changes are still being applied
+	assert.EqualError(t, timeout.ApplyChanges(context.Background(), &plan.Changes{}), "the provider timed out waiting for the changes of a previous synchronization after 10ms")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, timeout.ApplyChanges(ctx, &plan.Changes{}))
+}
+
+func TestTimeoutProviderWaitsForAbandonedChanges(t *testing.T) {
+	hanging := &hangingProvider{release: make(chan struct{})}
+	timeout := NewTimeoutProvider(hanging, 10*time.Millisecond).(*timeoutProvider)
+	assert.Error(t, timeout.ApplyChanges(context.Background(), &plan.Changes{}))
+
+	// once the abandoned call returned, the next changes are applied
+	close(hanging.release)
+	timeout.timeout = time.Minute
+	assert.NoError(t, timeout.ApplyChanges(context.Background(), &plan.Changes{}))
+}
+
+func TestTimeoutEndpointAdjusterTimesOut(t *testing.T) {
+	p := &hangingProvider{release: make(chan struct{})}
+	defer close(p.release)
+	assert.Equal(t, p, NewTimeoutEndpointAdjuster(p, 0))
+
+	endpoints, err := NewTimeoutEndpointAdjuster(p, 10*time.Millisecond).AdjustEndpoints(context.Background(), []*endpoint.Endpoint{endpoint.NewEndpoint("www.example.org", endpoint.RecordTypeA, "1.2.3.4")})
+	assert.EqualError(t, err, "the provider timed out adjusting the endpoints after 10ms")
+	assert.Nil(t, endpoints)
+}
@@ -19,6 +19,7 @@ package provider
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"strings"
 
@@ -58,14 +59,18 @@ type vinyldnsChange struct {
 	ResourceRecordSet vinyldns.RecordSet
 }
 
-// NewVinylDNSProvider provides support for VinylDNS records
-func NewVinylDNSProvider(domainFilter DomainFilter, zoneFilter ZoneIDFilter, dryRun bool) (Provider, error) {
+// NewVinylDNSProvider provides support for VinylDNS records, sending the API requests with the HTTP client
+// unless it's nil
+func NewVinylDNSProvider(domainFilter DomainFilter, zoneFilter ZoneIDFilter, dryRun bool, httpClient *http.Client) (Provider, error) {
 	_, ok := os.LookupEnv("VINYLDNS_ACCESS_KEY")
 	if !ok {
 		return nil, fmt.Errorf("no vinyldns access key found")
 	}
 
 	client := vinyldns.NewClientFromEnv()
+	if httpClient != nil {
+		client.HTTPClient = httpClient
+	}
 
 	return &vinyldnsProvider{
 		client:       client,
@@ -139,7 +144,7 @@ func vinyldnsSuitableZone(hostname string, zones []vinyldns.Zone) *vinyldns.Zone
 
 func (p *vinyldnsProvider) submitChanges(changes []*vinyldnsChange) error {
 	if len(changes) == 0 {
-		log.Infof("All records are already up to date")
+		log.Debug("All records are already up to date")
 		return nil
 	}
 
@@ -0,0 +1,211 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+const (
+	webhookRecordsPath         = "/records"
+	webhookAdjustEndpointsPath = "/adjustendpoints"
+	webhookApplyChangesPath    = "/applychanges"
+	// The number of bytes of an error response included in the error
+	webhookErrorBodyLimit = 512
+	// The time the server has to answer a request, unless the client has a timeout of its own
+	webhookRequestTimeout = time.Minute
+)
+
+// EndpointAdjuster is implemented by providers which adjust the endpoints of the sources to what they
+// support before the changes are planned, e.g. by dropping unsupported provider-specific properties or
+// normalizing the targets, so the adjusted endpoints don't differ from the records in every synchronization.
+type EndpointAdjuster interface {
+	AdjustEndpoints(ctx context.Context, endpoints []*endpoint.Endpoint) ([]*endpoint.Endpoint, error)
+}
+
+// WebhookProvider is an implementation of Provider delegating to an HTTP server, usually a sidecar,
+// so providers for in-house DNS systems can be written in any language. The server implements:
+//
+//	GET  /records         answers with the records as a JSON list of endpoints
+//	POST /adjustendpoints receives the endpoints of the sources as a JSON list and answers with the
+//	                      adjusted endpoints, optional if the server answers with 404 Not Found
+//	POST /applychanges    receives the changes as a JSON object with the Create, UpdateOld, UpdateNew
+//	                      and Delete lists of endpoints and answers with 204 No Content or 200 OK
+//
+// Other status codes fail the call with the beginning of the response body as error message.
+type WebhookProvider struct {
+	client       *http.Client
+	url          string
+	domainFilter DomainFilter
+	dryRun       bool
+}
+
+// NewWebhookProvider creates a new WebhookProvider for the server at the given URL, which is called with
+// a copy of the client, or of the default HTTP client if it's nil. The requests of the copy time out, so a
+// hung server doesn't stall the synchronization even without --provider-timeout.
+func NewWebhookProvider(serverURL string, domainFilter DomainFilter, dryRun bool, client *http.Client) (*WebhookProvider, error) {
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook provider URL %q: %v", serverURL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid webhook provider URL %q: the scheme must be http or https", serverURL)
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+	withTimeout := *client
+	if withTimeout.Timeout <= 0 {
+		withTimeout.Timeout = webhookRequestTimeout
+	}
+	return &WebhookProvider{
+		client:       &withTimeout,
+		url:          strings.TrimSuffix(serverURL, "/"),
+		domainFilter: domainFilter,
+		dryRun:       dryRun,
+	}, nil
+}
+
+// Records returns the records of the server matching the domain filter.
+func (p *WebhookProvider) Records(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	resp, err := p.do(ctx, http.MethodGet, webhookRecordsPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err := webhookResponseError(resp, http.StatusOK); err != nil {
+		return nil, fmt.Errorf("failed to list the records: %v", err)
+	}
+
+	var records []*endpoint.Endpoint
+	if err := json.NewDecoder(resp.Body).Decode(&records); err != nil {
+		return nil, fmt.Errorf("failed to decode the records: %v", err)
+	}
+	return p.filterEndpoints(records), nil
+}
+
+// AdjustEndpoints returns the endpoints adjusted by the server or the endpoints unchanged if the server
+// doesn't implement the adjustment.
+func (p *WebhookProvider) AdjustEndpoints(ctx context.Context, endpoints []*endpoint.Endpoint) ([]*endpoint.Endpoint, error) {
+	resp, err := p.do(ctx, http.MethodPost, webhookAdjustEndpointsPath, endpoints)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return endpoints, nil
+	}
+	if err := webhookResponseError(resp, http.StatusOK); err != nil {
+		return nil, fmt.Errorf("failed to adjust the endpoints: %v", err)
+	}
+
+	var adjusted []*endpoint.Endpoint
+	if err := json.NewDecoder(resp.Body).Decode(&adjusted); err != nil {
+		return nil, fmt.Errorf("failed to decode the adjusted endpoints: %v", err)
+	}
+	return adjusted, nil
+}
+
+// ApplyChanges sends the changes of the names matching the domain filter to the server.
+func (p *WebhookProvider) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	filtered := &plan.Changes{
+		Create:    p.filterEndpoints(changes.Create),
+		UpdateOld: p.filterEndpoints(changes.UpdateOld),
+		UpdateNew: p.filterEndpoints(changes.UpdateNew),
+		Delete:    p.filterEndpoints(changes.Delete),
+	}
+	if len(filtered.Create) == 0 && len(filtered.UpdateNew) == 0 && len(filtered.Delete) == 0 {
+		log.Debug("All records are already up to date")
+		return nil
+	}
+	if p.dryRun {
+		log.Infof("Would apply %d creates, %d updates and %d deletes with the webhook provider", len(filtered.Create), len(filtered.UpdateNew), len(filtered.Delete))
+		return nil
+	}
+
+	resp, err := p.do(ctx, http.MethodPost, webhookApplyChangesPath, filtered)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := webhookResponseError(resp, http.StatusNoContent, http.StatusOK); err != nil {
+		return fmt.Errorf("failed to apply the changes: %v", err)
+	}
+	return nil
+}
+
+func (p *WebhookProvider) filterEndpoints(endpoints []*endpoint.Endpoint) []*endpoint.Endpoint {
+	var filtered []*endpoint.Endpoint
+	for _, ep := range endpoints {
+		if p.domainFilter.Match(ep.DNSName) {
+			filtered = append(filtered, ep)
+		}
+	}
+	return filtered
+}
+
+// do sends a request to the server with the value encoded as JSON body unless it's nil.
+func (p *WebhookProvider) do(ctx context.Context, method, path string, value interface{}) (*http.Response, error) {
+	var body io.Reader
+	if value != nil {
+		b, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, p.url+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return p.client.Do(req)
+}
+
+// webhookResponseError returns an error with the beginning of the response body unless the response has
+// one of the expected status codes.
+func webhookResponseError(resp *http.Response, expected ...int) error {
+	for _, status := range expected {
+		if resp.StatusCode == status {
+			return nil
+		}
+	}
+	message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, webhookErrorBodyLimit))
+	if len(bytes.TrimSpace(message)) == 0 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return fmt.Errorf("unexpected status %s: %s", resp.Status, bytes.TrimSpace(message))
+}
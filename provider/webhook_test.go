@@ -0,0 +1,174 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// webhookServer is a minimal implementation of the webhook provider protocol recording the applied changes.
+type webhookServer struct {
+	records  []*endpoint.Endpoint
+	applied  []*plan.Changes
+	noAdjust bool
+	status   int
+}
+
+func (s *webhookServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.status != 0 {
+		http.Error(w, "zone is locked", s.status)
+		return
+	}
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == webhookRecordsPath:
+		_ = json.NewEncoder(w).Encode(s.records)
+	case r.Method == http.MethodPost && r.URL.Path == webhookAdjustEndpointsPath && !s.noAdjust:
+		var endpoints []*endpoint.Endpoint
+		if err := json.NewDecoder(r.Body).Decode(&endpoints); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, ep := range endpoints {
+			ep.ProviderSpecific = nil
+		}
+		_ = json.NewEncoder(w).Encode(endpoints)
+	case r.Method == http.MethodPost && r.URL.Path == webhookApplyChangesPath:
+		changes := &plan.Changes{}
+		if err := json.NewDecoder(r.Body).Decode(changes); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.applied = append(s.applied, changes)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newWebhookTest(t *testing.T, s *webhookServer, dryRun bool) (*WebhookProvider, func()) {
+	server := httptest.NewServer(s)
+	p, err := NewWebhookProvider(server.URL+"/", NewDomainFilter([]string{"example.org"}), dryRun, nil)
+	require.NoError(t, err)
+	return p, server.Close
+}
+
+func TestNewWebhookProvider(t *testing.T) {
+	_, err := NewWebhookProvider("http://localhost:8888", NewDomainFilter(nil), false, nil)
+	assert.NoError(t, err)
+	_, err = NewWebhookProvider("localhost:8888", NewDomainFilter(nil), false, nil)
+	assert.Error(t, err)
+	_, err = NewWebhookProvider("ftp://localhost", NewDomainFilter(nil), false, nil)
+	assert.Error(t, err)
+	// the requests time out even with the default HTTP client
+	p, err := NewWebhookProvider("http://localhost:8888", NewDomainFilter(nil), false, nil)
+	require.NoError(t, err)
+	assert.Equal(t, webhookRequestTimeout, p.client.Timeout)
+	assert.Zero(t, http.DefaultClient.Timeout)
+	p, err = NewWebhookProvider("http://localhost:8888", NewDomainFilter(nil), false, &http.Client{Timeout: time.Second})
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, p.client.Timeout)
+}
+
+func TestWebhookProviderRecords(t *testing.T) {
+	s := &webhookServer{records: []*endpoint.Endpoint{
+		endpoint.NewEndpointWithTTL("app.example.org", endpoint.RecordTypeA, 300, "1.2.3.4"),
+		endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "4.3.2.1"),
+	}}
+	p, stop := newWebhookTest(t, s, false)
+	defer stop()
+
+	records, err := p.Records(context.Background())
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "app.example.org", records[0].DNSName)
+	assert.Equal(t, endpoint.TTL(300), records[0].RecordTTL)
+	assert.Equal(t, endpoint.Targets{"1.2.3.4"}, records[0].Targets)
+
+	s.status = http.StatusServiceUnavailable
+	_, err = p.Records(context.Background())
+	assert.EqualError(t, err, "failed to list the records: unexpected status 503 Service Unavailable: zone is locked")
+}
+
+func TestWebhookProviderAdjustEndpoints(t *testing.T) {
+	s := &webhookServer{}
+	p, stop := newWebhookTest(t, s, false)
+	defer stop()
+
+	endpoints := []*endpoint.Endpoint{
+		endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeA, "1.2.3.4").WithProviderSpecific("weight", "10"),
+	}
+	adjusted, err := p.AdjustEndpoints(context.Background(), endpoints)
+	require.NoError(t, err)
+	require.Len(t, adjusted, 1)
+	assert.Empty(t, adjusted[0].ProviderSpecific)
+
+	// servers without adjustment leave the endpoints unchanged
+	s.noAdjust = true
+	adjusted, err = p.AdjustEndpoints(context.Background(), endpoints)
+	require.NoError(t, err)
+	assert.Equal(t, endpoints, adjusted)
+}
+
+func TestWebhookProviderApplyChanges(t *testing.T) {
+	s := &webhookServer{}
+	p, stop := newWebhookTest(t, s, false)
+	defer stop()
+
+	changes := &plan.Changes{
+		Create:    []*endpoint.Endpoint{endpoint.NewEndpoint("new.example.org", endpoint.RecordTypeA, "1.2.3.4"), endpoint.NewEndpoint("new.example.com", endpoint.RecordTypeA, "1.2.3.4")},
+		UpdateOld: []*endpoint.Endpoint{endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeA, "1.1.1.1")},
+		UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeA, "2.2.2.2")},
+		Delete:    []*endpoint.Endpoint{endpoint.NewEndpoint("old.example.org", endpoint.RecordTypeCNAME, "app.example.org")},
+	}
+	require.NoError(t, p.ApplyChanges(context.Background(), changes))
+	require.Len(t, s.applied, 1)
+	applied := s.applied[0]
+	require.Len(t, applied.Create, 1)
+	assert.Equal(t, "new.example.org", applied.Create[0].DNSName)
+	assert.Equal(t, endpoint.Targets{"1.1.1.1"}, applied.UpdateOld[0].Targets)
+	assert.Equal(t, endpoint.Targets{"2.2.2.2"}, applied.UpdateNew[0].Targets)
+	assert.Equal(t, endpoint.RecordTypeCNAME, applied.Delete[0].RecordType)
+
+	// changes outside of the domain filter aren't sent
+	require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{Create: changes.Create[1:]}))
+	assert.Len(t, s.applied, 1)
+
+	s.status = http.StatusConflict
+	err := p.ApplyChanges(context.Background(), changes)
+	assert.EqualError(t, err, "failed to apply the changes: unexpected status 409 Conflict: zone is locked")
+}
+
+func TestWebhookProviderApplyChangesDryRun(t *testing.T) {
+	s := &webhookServer{}
+	p, stop := newWebhookTest(t, s, true)
+	defer stop()
+
+	changes := &plan.Changes{Create: []*endpoint.Endpoint{endpoint.NewEndpoint("new.example.org", endpoint.RecordTypeA, "1.2.3.4")}}
+	require.NoError(t, p.ApplyChanges(context.Background(), changes))
+	assert.Empty(t, s.applied)
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// ZoneErrors is returned by Records together with the records of the other zones if the records of some
+// zones couldn't be listed, so a single broken zone doesn't stop the others from being synchronized. It
+// maps the names of the failed zones to their errors. The records of these zones are unknown, so no
+// changes should be applied to them, in particular no deletions. ApplyChanges returns it if only the
+// changes of some zones failed, the changes of the other zones were applied.
+type ZoneErrors map[string]error
+
+func (e ZoneErrors) Error() string {
+	zones := e.Zones()
+	messages := make([]string, 0, len(zones))
+	for _, zone := range zones {
+		messages = append(messages, fmt.Sprintf("zone %s: %v", zone, e[zone]))
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Zones returns the sorted names of the failed zones.
+func (e ZoneErrors) Zones() []string {
+	zones := make([]string, 0, len(e))
+	for zone := range e {
+		zones = append(zones, zone)
+	}
+	sort.Strings(zones)
+	return zones
+}
+
+// Contains returns true if the DNS name belongs to one of the failed zones.
+func (e ZoneErrors) Contains(dnsName string) bool {
+	name := strings.ToLower(strings.TrimSuffix(dnsName, "."))
+	for zone := range e {
+		zone = strings.ToLower(strings.TrimSuffix(zone, "."))
+		if name == zone || strings.HasSuffix(name, "."+zone) {
+			return true
+		}
+	}
+	return false
+}
+
+// recordsResult returns the records of the zones which could be listed and the errors of the others, if any.
+func recordsResult(endpoints []*endpoint.Endpoint, zoneErrors ZoneErrors) ([]*endpoint.Endpoint, error) {
+	if len(zoneErrors) > 0 {
+		return endpoints, zoneErrors
+	}
+	return endpoints, nil
+}
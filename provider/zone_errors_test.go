@@ -0,0 +1,58 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestZoneErrors(t *testing.T) {
+	zoneErrors := ZoneErrors{
+		"example.org.": errors.New("500 Internal Server Error"),
+		"broken.org":   errors.New("timeout"),
+	}
+
+	assert.Equal(t, []string{"broken.org", "example.org."}, zoneErrors.Zones())
+	assert.EqualError(t, zoneErrors, "zone broken.org: timeout; zone example.org.: 500 Internal Server Error")
+
+	for _, tc := range []struct {
+		dnsName  string
+		expected bool
+	}{
+		{"example.org", true},
+		{"foo.example.org", true},
+		{"Foo.Example.org.", true},
+		{"a.b.broken.org", true},
+		{"myexample.org", false},
+		{"example.com", false},
+		{"org", false},
+	} {
+		assert.Equal(t, tc.expected, zoneErrors.Contains(tc.dnsName), tc.dnsName)
+	}
+}
+
+func TestRecordsResult(t *testing.T) {
+	records, err := recordsResult(nil, ZoneErrors{})
+	assert.NoError(t, err)
+	assert.Nil(t, records)
+
+	_, err = recordsResult(nil, ZoneErrors{"example.org": errors.New("timeout")})
+	assert.Error(t, err)
+}
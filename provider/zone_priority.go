@@ -0,0 +1,130 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// zonePriorityProvider is a Provider which applies the changes of the prioritized zones of another
+// provider one zone after the other, in the order of their priority, followed by the changes of all
+// other zones. With fail fast, the remaining zones are skipped after the first zone failed.
+type zonePriorityProvider struct {
+	provider Provider
+	zones    []string
+	failFast bool
+}
+
+// NewZonePriorityProvider returns a Provider which applies the changes of the given zones of the provider
+// first and in the given order. Without any zones the provider is returned as it is.
+func NewZonePriorityProvider(p Provider, zones []string, failFast bool) Provider {
+	if len(zones) == 0 {
+		return p
+	}
+	prioritized := &zonePriorityProvider{provider: p, failFast: failFast}
+	for _, zone := range zones {
+		prioritized.zones = append(prioritized.zones, strings.ToLower(strings.Trim(zone, ".")))
+	}
+	return prioritized
+}
+
+// Records returns the records of the provider.
+func (p *zonePriorityProvider) Records(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	return p.provider.Records(ctx)
+}
+
+// RecordsPages passes the pages of the records of the provider to fn.
+func (p *zonePriorityProvider) RecordsPages(ctx context.Context, fn func(page []*endpoint.Endpoint) bool) error {
+	return RecordsPages(ctx, p.provider, fn)
+}
+
+// ApplyChanges applies the changes zone by zone in the order of their priority. The errors of all zones
+// are returned together, unless fail fast stops at the first one.
+func (p *zonePriorityProvider) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	// the changes of the other zones are the last group
+	groups := make([]*plan.Changes, len(p.zones)+1)
+	for i := range groups {
+		group, err := changes.Filter(true, func(_, ep *endpoint.Endpoint) bool {
+			return p.priority(ep.DNSName) == i
+		})
+		if err != nil {
+			return err
+		}
+		groups[i] = group
+	}
+
+	var messages []string
+	// the errors naming their zones are kept, unless another group failed as a whole
+	zoneErrors := ZoneErrors{}
+	for i, group := range groups {
+		if len(group.Create) == 0 && len(group.UpdateNew) == 0 && len(group.Delete) == 0 {
+			continue
+		}
+		zone := "the other zones"
+		if i < len(p.zones) {
+			zone = "zone " + p.zones[i]
+		}
+
+		err := p.provider.ApplyChanges(ctx, group)
+		if err == nil {
+			continue
+		}
+		if p.failFast && i < len(groups)-1 {
+			return fmt.Errorf("failed to apply the changes of %s, skipping the remaining zones: %v", zone, err)
+		}
+		log.Errorf("Failed to apply the changes of %s: %v", zone, err)
+		messages = append(messages, fmt.Sprintf("failed to apply the changes of %s: %v", zone, err))
+		if partial, ok := err.(ZoneErrors); ok && zoneErrors != nil {
+			for name, zoneErr := range partial {
+				zoneErrors[name] = zoneErr
+			}
+		} else {
+			zoneErrors = nil
+		}
+	}
+	if len(zoneErrors) > 0 {
+		return zoneErrors
+	}
+	if len(messages) > 0 {
+		return errors.New(strings.Join(messages, "; "))
+	}
+	return nil
+}
+
+// priority returns the position of the most specific prioritized zone of the DNS name, or the number of
+// zones if it doesn't belong to any.
+func (p *zonePriorityProvider) priority(dnsName string) int {
+	name := strings.ToLower(strings.TrimSuffix(dnsName, "."))
+	priority := len(p.zones)
+	for i, zone := range p.zones {
+		if name != zone && !strings.HasSuffix(name, "."+zone) {
+			continue
+		}
+		if priority == len(p.zones) || len(zone) > len(p.zones[priority]) {
+			priority = i
+		}
+	}
+	return priority
+}
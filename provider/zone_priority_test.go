@@ -0,0 +1,122 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// failingZoneProvider records the changes it is asked to apply and fails for the changes of one zone,
+// naming the zone if partial is set.
+type failingZoneProvider struct {
+	recordingProvider
+	failingZone string
+	partial     bool
+}
+
+func (p *failingZoneProvider) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	p.applied = append(p.applied, changes)
+	for _, eps := range [][]*endpoint.Endpoint{changes.Create, changes.UpdateNew, changes.Delete} {
+		for _, ep := range eps {
+			if strings.HasSuffix(ep.DNSName, p.failingZone) && p.partial {
+				return ZoneErrors{p.failingZone: errors.New("throttled")}
+			}
+			if strings.HasSuffix(ep.DNSName, p.failingZone) {
+				return errors.New("throttled")
+			}
+		}
+	}
+	return nil
+}
+
+func newZonePriorityTestChanges() *plan.Changes {
+	return &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("www.other.org", endpoint.RecordTypeA, "1.2.3.4"),
+			endpoint.NewEndpoint("www.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+		},
+		UpdateOld: []*endpoint.Endpoint{endpoint.NewEndpoint("api.critical.example.org", endpoint.RecordTypeA, "1.2.3.4")},
+		UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpoint("api.critical.example.org", endpoint.RecordTypeA, "4.3.2.1")},
+		Delete:    []*endpoint.Endpoint{endpoint.NewEndpoint("old.Example.org.", endpoint.RecordTypeA, "1.2.3.4")},
+	}
+}
+
+func TestZonePriorityProvider(t *testing.T) {
+	changes := newZonePriorityTestChanges()
+	p := &recordingProvider{records: []*endpoint.Endpoint{changes.Create[0]}}
+	prioritized := NewZonePriorityProvider(p, []string{"critical.example.org", "example.org."}, false)
+
+	records, err := prioritized.Records(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, p.records, records)
+
+	require.NoError(t, prioritized.ApplyChanges(context.Background(), changes))
+	require.Len(t, p.applied, 3)
+	assert.Equal(t, &plan.Changes{UpdateOld: changes.UpdateOld, UpdateNew: changes.UpdateNew}, p.applied[0])
+	assert.Equal(t, &plan.Changes{Create: changes.Create[1:], Delete: changes.Delete}, p.applied[1])
+	assert.Equal(t, &plan.Changes{Create: changes.Create[:1]}, p.applied[2])
+
+	// the updates can't be paired without their old versions
+	changes.UpdateOld = nil
+	assert.Error(t, prioritized.ApplyChanges(context.Background(), changes))
+	assert.Len(t, p.applied, 3)
+}
+
+func TestZonePriorityProviderErrors(t *testing.T) {
+	p := &failingZoneProvider{failingZone: "critical.example.org"}
+	prioritized := NewZonePriorityProvider(p, []string{"critical.example.org", "example.org"}, false)
+
+	err := prioritized.ApplyChanges(context.Background(), newZonePriorityTestChanges())
+	require.Error(t, err)
+	assert.Equal(t, "failed to apply the changes of zone critical.example.org: throttled", err.Error())
+	// the other zones are still applied
+	assert.Len(t, p.applied, 3)
+}
+
+func TestZonePriorityProviderZoneErrors(t *testing.T) {
+	p := &failingZoneProvider{failingZone: "critical.example.org", partial: true}
+	prioritized := NewZonePriorityProvider(p, []string{"critical.example.org", "example.org"}, false)
+
+	err := prioritized.ApplyChanges(context.Background(), newZonePriorityTestChanges())
+	require.IsType(t, ZoneErrors{}, err)
+	assert.Equal(t, []string{"critical.example.org"}, err.(ZoneErrors).Zones())
+	assert.Len(t, p.applied, 3)
+}
+
+func TestZonePriorityProviderFailFast(t *testing.T) {
+	p := &failingZoneProvider{failingZone: "critical.example.org"}
+	prioritized := NewZonePriorityProvider(p, []string{"critical.example.org", "example.org"}, true)
+
+	err := prioritized.ApplyChanges(context.Background(), newZonePriorityTestChanges())
+	require.Error(t, err)
+	assert.Equal(t, "failed to apply the changes of zone critical.example.org, skipping the remaining zones: throttled", err.Error())
+	assert.Len(t, p.applied, 1)
+}
+
+func TestZonePriorityProviderWithoutZones(t *testing.T) {
+	p := &recordingProvider{}
+	assert.Equal(t, p, NewZonePriorityProvider(p, nil, true))
+}
@@ -0,0 +1,459 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+const (
+	// The partition key of the ownership table, a string
+	dynamoDBKeyAttribute = "id"
+	// The key of the record of the item, a string, see dynamoDBRecordKey
+	dynamoDBRecordAttribute = "record"
+	// The labels of the record, a map of strings
+	dynamoDBLabelsAttribute = "labels"
+	// The maximum number of writes of a BatchWriteItem request
+	dynamoDBBatchSize = 25
+	// The number of times the unprocessed writes of a batch are retried and the backoff of the first retry
+	dynamoDBBatchRetries       = 5
+	dynamoDBBatchRetryInterval = 100 * time.Millisecond
+)
+
+// DynamoDBRegistry implements registry interface with ownership information stored in a DynamoDB table
+// instead of TXT records, which keeps large zones free of ownership records. Every owned record has an
+// item with its labels, keyed by the owner and the normalized DNS name, record type and set identifier of
+// the record. The table needs a string partition key named id and can be shared by several owners, e.g.
+// by the instances of the private and public zones of a split-horizon setup, which own the records of the
+// same name in their zones. The items are cached and only scanned again once the cache interval passed,
+// the writes of the registry itself update the cache.
+type DynamoDBRegistry struct {
+	provider provider.Provider
+	client   dynamodbiface.DynamoDBAPI
+	table    string
+	ownerID  string
+
+	// the items by their key, scanned at itemsRefreshTime
+	items            map[string]dynamoDBItem
+	itemsRefreshTime time.Time
+	cacheInterval    time.Duration
+	// the keys of the records of the last complete listing of the provider, whose items without a record
+	// are pruned by the next ApplyChanges. Nil if the listing failed for some zones.
+	listedRecords map[string]bool
+}
+
+// dynamoDBItem is an item of the ownership table.
+type dynamoDBItem struct {
+	// the key of the record, see dynamoDBRecordKey
+	record string
+	labels endpoint.Labels
+}
+
+// NewDynamoDBClient returns a DynamoDB client with the default credentials of the AWS SDK, assuming the
+// given role if it isn't empty.
+func NewDynamoDBClient(region, assumeRole string) (dynamodbiface.DynamoDBAPI, error) {
+	config := aws.NewConfig()
+	if region != "" {
+		config = config.WithRegion(region)
+	}
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            *config,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if assumeRole != "" {
+		log.Infof("Assuming role: %s", assumeRole)
+		sess.Config.WithCredentials(stscreds.NewCredentials(sess, assumeRole))
+	}
+	return dynamodb.New(sess), nil
+}
+
+// NewDynamoDBRegistry returns new DynamoDBRegistry object. The table is scanned in every synchronization
+// without a cache interval.
+func NewDynamoDBRegistry(provider provider.Provider, client dynamodbiface.DynamoDBAPI, table, ownerID string, cacheInterval time.Duration) (*DynamoDBRegistry, error) {
+	if ownerID == "" {
+		return nil, errors.New("owner id cannot be empty")
+	}
+	if table == "" {
+		return nil, errors.New("table name cannot be empty")
+	}
+	return &DynamoDBRegistry{
+		provider:      provider,
+		client:        client,
+		table:         table,
+		ownerID:       ownerID,
+		cacheInterval: cacheInterval,
+	}, nil
+}
+
+// Records returns the current records of the provider with the labels of their items in the table.
+// Records without an item aren't owned by anyone. A record with an item of the current owner has its
+// labels, otherwise the labels of the item of another owner, if any.
+func (im *DynamoDBRegistry) Records(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	// the records of the zones which could be listed are returned along with the errors of the others
+	records, err := im.provider.Records(ctx)
+	zoneErrors, partial := err.(provider.ZoneErrors)
+	if err != nil && !partial {
+		return nil, err
+	}
+
+	items, err := im.scanItems(ctx)
+	if err != nil {
+		return nil, err
+	}
+	labelMap := im.labelsByRecord(items)
+
+	listed := make(map[string]bool, len(records))
+	for _, record := range records {
+		if record.Labels == nil {
+			record.Labels = endpoint.NewLabels()
+		}
+		key := dynamoDBRecordKey(record)
+		listed[key] = true
+		if labels, ok := labelMap[key]; ok {
+			for k, v := range labels {
+				record.Labels[k] = v
+			}
+		}
+	}
+
+	if partial {
+		im.listedRecords = nil
+		return records, zoneErrors
+	}
+	im.listedRecords = listed
+	return records, nil
+}
+
+// labelsByRecord returns the labels of the items by the keys of their records, preferring the items of
+// the current owner. Of the items of other owners, the one of the first owner in order is returned.
+func (im *DynamoDBRegistry) labelsByRecord(items map[string]dynamoDBItem) map[string]endpoint.Labels {
+	labelMap := map[string]endpoint.Labels{}
+	for _, item := range items {
+		current, ok := labelMap[item.record]
+		switch {
+		case !ok:
+		case current[endpoint.OwnerLabelKey] == im.ownerID:
+			continue
+		case item.labels[endpoint.OwnerLabelKey] != im.ownerID && item.labels[endpoint.OwnerLabelKey] > current[endpoint.OwnerLabelKey]:
+			continue
+		}
+		labelMap[item.record] = item.labels
+	}
+	return labelMap
+}
+
+// scanItems returns the items by their key from the cache, or scans the table if the cache is disabled
+// or expired.
+func (im *DynamoDBRegistry) scanItems(ctx context.Context) (map[string]dynamoDBItem, error) {
+	if im.items != nil && time.Since(im.itemsRefreshTime) < im.cacheInterval {
+		log.Debug("Using cached items of the DynamoDB table")
+		return im.items, nil
+	}
+
+	items := map[string]dynamoDBItem{}
+	err := im.client.ScanPagesWithContext(ctx, &dynamodb.ScanInput{
+		TableName:      aws.String(im.table),
+		ConsistentRead: aws.Bool(true),
+	}, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		for _, attributes := range page.Items {
+			key := attributes[dynamoDBKeyAttribute]
+			if key == nil || key.S == nil {
+				continue
+			}
+			item := dynamoDBItem{labels: endpoint.NewLabels()}
+			if attribute := attributes[dynamoDBLabelsAttribute]; attribute != nil {
+				for k, v := range attribute.M {
+					if v != nil && v.S != nil {
+						item.labels[k] = *v.S
+					}
+				}
+			}
+			if record := attributes[dynamoDBRecordAttribute]; record != nil && record.S != nil {
+				item.record = *record.S
+			} else {
+				// the items written before the record attribute was added are keyed by their record only
+				item.record = normalizeDynamoDBRecordKey(*key.S)
+			}
+			items[*key.S] = item
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan the DynamoDB table %s: %v", im.table, err)
+	}
+
+	// the items are kept even without a cache interval, so ApplyChanges knows the keys of the items
+	im.items = items
+	im.itemsRefreshTime = time.Now()
+	return items, nil
+}
+
+// ApplyChanges updates the records of the provider and their ownership items. The items of created and
+// updated records are written first, so a record is never left without its owner, and the items of
+// deleted records are removed once the provider deleted them. If the provider fails, the items of the
+// records which weren't created are removed again. The items of the owner whose records were missing in
+// the last complete listing are pruned.
+func (im *DynamoDBRegistry) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	filteredChanges := &plan.Changes{
+		Create:    changes.Create,
+		UpdateNew: filterOwnedRecords(im.ownerID, changes.UpdateNew),
+		UpdateOld: filterOwnedRecords(im.ownerID, changes.UpdateOld),
+		Delete:    filterOwnedRecords(im.ownerID, changes.Delete),
+	}
+	for _, r := range filteredChanges.Create {
+		if r.Labels == nil {
+			r.Labels = endpoint.NewLabels()
+		}
+		r.Labels[endpoint.OwnerLabelKey] = im.ownerID
+	}
+	listed := im.listedRecords
+	// the listing is only pruned once, the next one may be partial
+	im.listedRecords = nil
+
+	written := map[string]bool{}
+	var puts []*dynamodb.WriteRequest
+	for _, r := range append(append([]*endpoint.Endpoint{}, filteredChanges.Create...), filteredChanges.UpdateNew...) {
+		key := dynamoDBRecordKey(r)
+		if written[key] {
+			continue
+		}
+		written[key] = true
+		puts = append(puts, im.putRequest(r))
+	}
+	// the items written before the owner was part of the key are replaced
+	var legacy []string
+	for key, item := range im.items {
+		if written[item.record] && item.labels[endpoint.OwnerLabelKey] == im.ownerID && key != im.ownerID+"#"+item.record {
+			legacy = append(legacy, key)
+		}
+	}
+	sort.Strings(legacy)
+	for _, key := range legacy {
+		puts = append(puts, dynamoDBDeleteRequest(key))
+	}
+	if err := im.writeItems(ctx, puts); err != nil {
+		return err
+	}
+
+	if err := im.provider.ApplyChanges(ctx, filteredChanges); err != nil {
+		im.deleteFailedCreates(ctx, filteredChanges.Create)
+		return err
+	}
+
+	deleted := map[string]bool{}
+	for _, r := range filteredChanges.Delete {
+		key := dynamoDBRecordKey(r)
+		// the item of a record which is created again is kept
+		if !written[key] {
+			deleted[key] = true
+		}
+	}
+	if listed != nil {
+		for _, item := range im.items {
+			if item.labels[endpoint.OwnerLabelKey] == im.ownerID && !listed[item.record] && !written[item.record] {
+				log.Infof("Pruning the DynamoDB item of %s, its record doesn't exist anymore", item.record)
+				deleted[item.record] = true
+			}
+		}
+	}
+	return im.writeItems(ctx, im.deleteRequests(deleted))
+}
+
+// deleteFailedCreates deletes the items of the created records which the provider didn't create. The
+// records are listed again to tell them apart, the items are kept if the listing fails and are pruned
+// with a later complete listing.
+func (im *DynamoDBRegistry) deleteFailedCreates(ctx context.Context, created []*endpoint.Endpoint) {
+	if len(created) == 0 {
+		return
+	}
+	records, err := im.provider.Records(ctx)
+	if err != nil {
+		log.Warnf("Failed to list the records to delete the DynamoDB items of the failed creations, they're pruned later: %v", err)
+		return
+	}
+	existing := make(map[string]bool, len(records))
+	for _, record := range records {
+		existing[dynamoDBRecordKey(record)] = true
+	}
+	failed := map[string]bool{}
+	for _, r := range created {
+		if key := dynamoDBRecordKey(r); !existing[key] {
+			failed[key] = true
+		}
+	}
+	if err := im.writeItems(ctx, im.deleteRequests(failed)); err != nil {
+		log.Warnf("Failed to delete the DynamoDB items of the failed creations, they're pruned later: %v", err)
+	}
+}
+
+// AdoptRecords writes the items of the given records with the owner of the current instance, keeping
+// their other labels.
+func (im *DynamoDBRegistry) AdoptRecords(ctx context.Context, records []*endpoint.Endpoint) error {
+	var puts []*dynamodb.WriteRequest
+	for _, r := range records {
+		r = r.DeepCopy()
+		if r.Labels == nil {
+			r.Labels = endpoint.NewLabels()
+		}
+		r.Labels[endpoint.OwnerLabelKey] = im.ownerID
+		puts = append(puts, im.putRequest(r))
+	}
+	return im.writeItems(ctx, puts)
+}
+
+/**
+  DynamoDB registry specific private methods
+*/
+
+func (im *DynamoDBRegistry) putRequest(r *endpoint.Endpoint) *dynamodb.WriteRequest {
+	labels := map[string]*dynamodb.AttributeValue{}
+	for k, v := range r.Labels {
+		if k == endpoint.OwnedRecordLabelKey {
+			continue
+		}
+		labels[k] = &dynamodb.AttributeValue{S: aws.String(v)}
+	}
+	return &dynamodb.WriteRequest{
+		PutRequest: &dynamodb.PutRequest{Item: map[string]*dynamodb.AttributeValue{
+			dynamoDBKeyAttribute:    {S: aws.String(dynamoDBItemKey(im.ownerID, r))},
+			dynamoDBRecordAttribute: {S: aws.String(dynamoDBRecordKey(r))},
+			dynamoDBLabelsAttribute: {M: labels},
+		}},
+	}
+}
+
+// deleteRequests returns the requests deleting the items of the owner of the given records, including
+// the ones written before the record attribute was added.
+func (im *DynamoDBRegistry) deleteRequests(records map[string]bool) []*dynamodb.WriteRequest {
+	keys := map[string]bool{}
+	for record := range records {
+		keys[im.ownerID+"#"+record] = true
+	}
+	for key, item := range im.items {
+		if records[item.record] && item.labels[endpoint.OwnerLabelKey] == im.ownerID {
+			keys[key] = true
+		}
+	}
+
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+	requests := make([]*dynamodb.WriteRequest, 0, len(sorted))
+	for _, key := range sorted {
+		requests = append(requests, dynamoDBDeleteRequest(key))
+	}
+	return requests
+}
+
+func dynamoDBDeleteRequest(key string) *dynamodb.WriteRequest {
+	return &dynamodb.WriteRequest{
+		DeleteRequest: &dynamodb.DeleteRequest{Key: map[string]*dynamodb.AttributeValue{
+			dynamoDBKeyAttribute: {S: aws.String(key)},
+		}},
+	}
+}
+
+// writeItems writes the requests in batches of the maximum size and retries the unprocessed ones, e.g.
+// when the table is throttled. The cached items are updated with the written items, or dropped if the
+// items may be written partially.
+func (im *DynamoDBRegistry) writeItems(ctx context.Context, requests []*dynamodb.WriteRequest) error {
+	if err := im.writeBatches(ctx, requests); err != nil {
+		im.items = nil
+		return err
+	}
+	if im.items == nil {
+		return nil
+	}
+	for _, r := range requests {
+		if r.DeleteRequest != nil {
+			delete(im.items, aws.StringValue(r.DeleteRequest.Key[dynamoDBKeyAttribute].S))
+			continue
+		}
+		item := dynamoDBItem{record: aws.StringValue(r.PutRequest.Item[dynamoDBRecordAttribute].S), labels: endpoint.NewLabels()}
+		for k, v := range r.PutRequest.Item[dynamoDBLabelsAttribute].M {
+			item.labels[k] = aws.StringValue(v.S)
+		}
+		im.items[aws.StringValue(r.PutRequest.Item[dynamoDBKeyAttribute].S)] = item
+	}
+	return nil
+}
+
+func (im *DynamoDBRegistry) writeBatches(ctx context.Context, requests []*dynamodb.WriteRequest) error {
+	for start := 0; start < len(requests); start += dynamoDBBatchSize {
+		end := start + dynamoDBBatchSize
+		if end > len(requests) {
+			end = len(requests)
+		}
+		batch := requests[start:end]
+
+		for retry := 0; len(batch) > 0; retry++ {
+			if retry > dynamoDBBatchRetries {
+				return fmt.Errorf("failed to write %d items to the DynamoDB table %s: too many retries", len(batch), im.table)
+			}
+			if retry > 0 {
+				time.Sleep(dynamoDBBatchRetryInterval << uint(retry-1))
+			}
+			output, err := im.client.BatchWriteItemWithContext(ctx, &dynamodb.BatchWriteItemInput{
+				RequestItems: map[string][]*dynamodb.WriteRequest{im.table: batch},
+			})
+			if err != nil {
+				return fmt.Errorf("failed to write to the DynamoDB table %s: %v", im.table, err)
+			}
+			batch = output.UnprocessedItems[im.table]
+		}
+	}
+	return nil
+}
+
+// dynamoDBRecordKey returns the key of a record, its DNS name is normalized to lower case without the
+// trailing dot like the names of the providers.
+func dynamoDBRecordKey(ep *endpoint.Endpoint) string {
+	return normalizeDynamoDBRecordKey(fmt.Sprintf("%s#%s#%s", ep.DNSName, ep.RecordType, ep.SetIdentifier))
+}
+
+func normalizeDynamoDBRecordKey(key string) string {
+	parts := strings.SplitN(key, "#", 2)
+	parts[0] = strings.TrimSuffix(strings.ToLower(parts[0]), ".")
+	return strings.Join(parts, "#")
+}
+
+// dynamoDBItemKey returns the key of the item of a record of the given owner. The owner is part of the
+// key, so the instances owning records of the same name in different zones don't overwrite their items.
+func dynamoDBItemKey(owner string, ep *endpoint.Endpoint) string {
+	return owner + "#" + dynamoDBRecordKey(ep)
+}
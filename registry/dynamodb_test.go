@@ -0,0 +1,365 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+// dynamoDBStub stores the items of a single table in memory. It leaves the first write of every batch
+// unprocessed once, like a throttled table. The items without a record attribute are written before it
+// was added.
+type dynamoDBStub struct {
+	dynamodbiface.DynamoDBAPI
+	items   map[string]endpoint.Labels
+	records map[string]string
+	batches int
+	scans   int
+}
+
+func newDynamoDBStub() *dynamoDBStub {
+	return &dynamoDBStub{items: map[string]endpoint.Labels{}, records: map[string]string{}}
+}
+
+func (s *dynamoDBStub) ScanPagesWithContext(ctx aws.Context, input *dynamodb.ScanInput, fn func(*dynamodb.ScanOutput, bool) bool, opts ...request.Option) error {
+	s.scans++
+	// every item is returned on its own page
+	var pages []*dynamodb.ScanOutput
+	for key, labels := range s.items {
+		attributes := map[string]*dynamodb.AttributeValue{}
+		for k, v := range labels {
+			attributes[k] = &dynamodb.AttributeValue{S: aws.String(v)}
+		}
+		item := map[string]*dynamodb.AttributeValue{
+			dynamoDBKeyAttribute:    {S: aws.String(key)},
+			dynamoDBLabelsAttribute: {M: attributes},
+		}
+		if record, ok := s.records[key]; ok {
+			item[dynamoDBRecordAttribute] = &dynamodb.AttributeValue{S: aws.String(record)}
+		}
+		pages = append(pages, &dynamodb.ScanOutput{Items: []map[string]*dynamodb.AttributeValue{item}})
+	}
+	for i, page := range pages {
+		if !fn(page, i == len(pages)-1) {
+			break
+		}
+	}
+	return nil
+}
+
+func (s *dynamoDBStub) BatchWriteItemWithContext(ctx aws.Context, input *dynamodb.BatchWriteItemInput, opts ...request.Option) (*dynamodb.BatchWriteItemOutput, error) {
+	s.batches++
+	requests := input.RequestItems["ownership"]
+	if len(requests) > dynamoDBBatchSize {
+		return nil, errors.New("too many items")
+	}
+
+	output := &dynamodb.BatchWriteItemOutput{UnprocessedItems: map[string][]*dynamodb.WriteRequest{}}
+	for i, r := range requests {
+		if i == 0 && s.batches%2 == 1 {
+			output.UnprocessedItems["ownership"] = append(output.UnprocessedItems["ownership"], r)
+			continue
+		}
+		if r.DeleteRequest != nil {
+			delete(s.items, *r.DeleteRequest.Key[dynamoDBKeyAttribute].S)
+			delete(s.records, *r.DeleteRequest.Key[dynamoDBKeyAttribute].S)
+			continue
+		}
+		labels := endpoint.NewLabels()
+		for k, v := range r.PutRequest.Item[dynamoDBLabelsAttribute].M {
+			labels[k] = *v.S
+		}
+		key := *r.PutRequest.Item[dynamoDBKeyAttribute].S
+		s.items[key] = labels
+		s.records[key] = *r.PutRequest.Item[dynamoDBRecordAttribute].S
+	}
+	return output, nil
+}
+
+// put stores the item of the given owner and record like the registry.
+func (s *dynamoDBStub) put(owner, record string, labels endpoint.Labels) {
+	s.items[owner+"#"+record] = labels
+	s.records[owner+"#"+record] = record
+}
+
+// failingProvider applies the changes of its provider except the creations of the failing records and
+// returns an error if any of them failed.
+type failingProvider struct {
+	provider.Provider
+	failing map[string]bool
+}
+
+func (p *failingProvider) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	applied := *changes
+	applied.Create = nil
+	for _, r := range changes.Create {
+		if !p.failing[r.DNSName] {
+			applied.Create = append(applied.Create, r)
+		}
+	}
+	if err := p.Provider.ApplyChanges(ctx, &applied); err != nil {
+		return err
+	}
+	if len(applied.Create) < len(changes.Create) {
+		return errors.New("failed to create the records")
+	}
+	return nil
+}
+
+func TestDynamoDBRegistryNew(t *testing.T) {
+	p := provider.NewInMemoryProvider()
+	_, err := NewDynamoDBRegistry(p, newDynamoDBStub(), "ownership", "", 0)
+	require.Error(t, err)
+
+	_, err = NewDynamoDBRegistry(p, newDynamoDBStub(), "", "owner", 0)
+	require.Error(t, err)
+
+	r, err := NewDynamoDBRegistry(p, newDynamoDBStub(), "ownership", "owner", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, "owner", r.ownerID)
+	assert.Equal(t, "ownership", r.table)
+	assert.Equal(t, time.Hour, r.cacheInterval)
+}
+
+func TestDynamoDBRegistryRecords(t *testing.T) {
+	ctx := context.Background()
+	p := provider.NewInMemoryProvider()
+	p.CreateZone(testZone)
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("foo.test-zone.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+			endpoint.NewEndpoint("bar.test-zone.example.org", endpoint.RecordTypeCNAME, "bar.loadbalancer.com"),
+			endpoint.NewEndpoint("unowned.test-zone.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+			endpoint.NewEndpoint("legacy.test-zone.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+			endpoint.NewEndpoint("shared.test-zone.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+		},
+	}))
+	client := newDynamoDBStub()
+	client.put("owner", "foo.test-zone.example.org#A#", endpoint.Labels{endpoint.OwnerLabelKey: "owner", endpoint.ResourceLabelKey: "ingress/default/foo"})
+	client.put("other", "bar.test-zone.example.org#CNAME#", endpoint.Labels{endpoint.OwnerLabelKey: "other"})
+	client.put("owner", "gone.test-zone.example.org#A#", endpoint.Labels{endpoint.OwnerLabelKey: "owner"})
+	// the items written before the owner was part of the key, with the names of the desired endpoints
+	client.items["Legacy.Test-Zone.example.org.#A#"] = endpoint.Labels{endpoint.OwnerLabelKey: "owner", endpoint.ResourceLabelKey: "ingress/default/legacy"}
+	// the record of the same name of the other zone of a split-horizon setup has an item of its owner
+	client.put("other", "shared.test-zone.example.org#A#", endpoint.Labels{endpoint.OwnerLabelKey: "other", endpoint.ResourceLabelKey: "ingress/private/shared"})
+	client.put("owner", "shared.test-zone.example.org#A#", endpoint.Labels{endpoint.OwnerLabelKey: "owner", endpoint.ResourceLabelKey: "ingress/public/shared"})
+
+	r, _ := NewDynamoDBRegistry(p, client, "ownership", "owner", 0)
+	records, err := r.Records(ctx)
+	require.NoError(t, err)
+
+	labels := map[string]endpoint.Labels{}
+	for _, record := range records {
+		labels[record.DNSName] = record.Labels
+	}
+	assert.Len(t, records, 5)
+	assert.Equal(t, "owner", labels["foo.test-zone.example.org"][endpoint.OwnerLabelKey])
+	assert.Equal(t, "ingress/default/foo", labels["foo.test-zone.example.org"][endpoint.ResourceLabelKey])
+	assert.Equal(t, "other", labels["bar.test-zone.example.org"][endpoint.OwnerLabelKey])
+	assert.Empty(t, labels["unowned.test-zone.example.org"][endpoint.OwnerLabelKey])
+	assert.Equal(t, "ingress/default/legacy", labels["legacy.test-zone.example.org"][endpoint.ResourceLabelKey])
+	assert.Equal(t, "owner", labels["shared.test-zone.example.org"][endpoint.OwnerLabelKey])
+	assert.Equal(t, "ingress/public/shared", labels["shared.test-zone.example.org"][endpoint.ResourceLabelKey])
+}
+
+func TestDynamoDBRegistryApplyChanges(t *testing.T) {
+	ctx := context.Background()
+	p := provider.NewInMemoryProvider()
+	p.CreateZone(testZone)
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("update.test-zone.example.org", endpoint.RecordTypeA, "1.1.1.1"),
+			endpoint.NewEndpoint("delete.test-zone.example.org", endpoint.RecordTypeA, "1.1.1.1"),
+			endpoint.NewEndpoint("other.test-zone.example.org", endpoint.RecordTypeA, "1.1.1.1"),
+		},
+	}))
+	client := newDynamoDBStub()
+	// the items written before the owner was part of the key are replaced
+	client.items["update.test-zone.example.org#A#"] = endpoint.Labels{endpoint.OwnerLabelKey: "owner"}
+	client.items["delete.test-zone.example.org#A#"] = endpoint.Labels{endpoint.OwnerLabelKey: "owner"}
+	client.put("other", "other.test-zone.example.org#A#", endpoint.Labels{endpoint.OwnerLabelKey: "other"})
+
+	var applied *plan.Changes
+	p.OnApplyChanges = func(ctx context.Context, changes *plan.Changes) {
+		applied = changes
+	}
+	r, _ := NewDynamoDBRegistry(p, client, "ownership", "owner", 0)
+	_, err := r.Records(ctx)
+	require.NoError(t, err)
+
+	create := endpoint.NewEndpoint("create.test-zone.example.org", endpoint.RecordTypeA, "2.2.2.2")
+	create.Labels[endpoint.ResourceLabelKey] = "ingress/default/create"
+	err = r.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{create},
+		UpdateOld: []*endpoint.Endpoint{
+			newEndpointWithOwner("update.test-zone.example.org", "1.1.1.1", endpoint.RecordTypeA, "owner"),
+		},
+		UpdateNew: []*endpoint.Endpoint{
+			newEndpointWithOwnerResource("update.test-zone.example.org", "2.2.2.2", endpoint.RecordTypeA, "owner", "ingress/default/update"),
+		},
+		Delete: []*endpoint.Endpoint{
+			newEndpointWithOwner("delete.test-zone.example.org", "1.1.1.1", endpoint.RecordTypeA, "owner"),
+			newEndpointWithOwner("other.test-zone.example.org", "1.1.1.1", endpoint.RecordTypeA, "other"),
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Len(t, applied.Create, 1)
+	assert.Len(t, applied.UpdateNew, 1)
+	require.Len(t, applied.Delete, 1)
+	assert.Equal(t, "delete.test-zone.example.org", applied.Delete[0].DNSName)
+
+	assert.Equal(t, map[string]endpoint.Labels{
+		"owner#create.test-zone.example.org#A#": {endpoint.OwnerLabelKey: "owner", endpoint.ResourceLabelKey: "ingress/default/create"},
+		"owner#update.test-zone.example.org#A#": {endpoint.OwnerLabelKey: "owner", endpoint.ResourceLabelKey: "ingress/default/update"},
+		"other#other.test-zone.example.org#A#":  {endpoint.OwnerLabelKey: "other"},
+	}, client.items)
+}
+
+func TestDynamoDBRegistryFailedCreates(t *testing.T) {
+	ctx := context.Background()
+	inMemory := provider.NewInMemoryProvider()
+	inMemory.CreateZone(testZone)
+	p := &failingProvider{Provider: inMemory, failing: map[string]bool{"failed.test-zone.example.org": true}}
+	client := newDynamoDBStub()
+	r, _ := NewDynamoDBRegistry(p, client, "ownership", "owner", 0)
+	_, err := r.Records(ctx)
+	require.NoError(t, err)
+
+	err = r.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("created.test-zone.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+			endpoint.NewEndpoint("failed.test-zone.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+		},
+	})
+	require.Error(t, err)
+
+	// the item of the record which wasn't created is deleted again
+	assert.Equal(t, map[string]endpoint.Labels{
+		"owner#created.test-zone.example.org#A#": {endpoint.OwnerLabelKey: "owner"},
+	}, client.items)
+}
+
+func TestDynamoDBRegistryPruning(t *testing.T) {
+	ctx := context.Background()
+	inMemory := provider.NewInMemoryProvider()
+	inMemory.CreateZone(testZone)
+	require.NoError(t, inMemory.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("foo.test-zone.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+		},
+	}))
+	p := &zoneErrorsProvider{
+		Provider:   inMemory,
+		zoneErrors: provider.ZoneErrors{"broken.example.org": errors.New("500 Internal Server Error")},
+	}
+	client := newDynamoDBStub()
+	client.put("owner", "foo.test-zone.example.org#A#", endpoint.Labels{endpoint.OwnerLabelKey: "owner"})
+	client.put("owner", "gone.test-zone.example.org#A#", endpoint.Labels{endpoint.OwnerLabelKey: "owner"})
+	client.put("other", "gone.test-zone.example.org#A#", endpoint.Labels{endpoint.OwnerLabelKey: "other"})
+	client.items["legacy.test-zone.example.org#A#"] = endpoint.Labels{endpoint.OwnerLabelKey: "owner"}
+	r, _ := NewDynamoDBRegistry(p, client, "ownership", "owner", 0)
+
+	// the items are kept while the records of some zones couldn't be listed
+	_, err := r.Records(ctx)
+	require.Equal(t, p.zoneErrors, err)
+	require.NoError(t, r.ApplyChanges(ctx, &plan.Changes{}))
+	assert.Len(t, client.items, 4)
+
+	// the items of the owner without a record are pruned with a complete listing
+	p.zoneErrors = nil
+	_, err = r.Records(ctx)
+	require.NoError(t, err)
+	require.NoError(t, r.ApplyChanges(ctx, &plan.Changes{}))
+	assert.Equal(t, map[string]endpoint.Labels{
+		"owner#foo.test-zone.example.org#A#":  {endpoint.OwnerLabelKey: "owner"},
+		"other#gone.test-zone.example.org#A#": {endpoint.OwnerLabelKey: "other"},
+	}, client.items)
+}
+
+func TestDynamoDBRegistryBatches(t *testing.T) {
+	client := newDynamoDBStub()
+	r, _ := NewDynamoDBRegistry(provider.NewInMemoryProvider(), client, "ownership", "owner", 0)
+
+	var records []*endpoint.Endpoint
+	for i := 0; i < 60; i++ {
+		records = append(records, endpoint.NewEndpoint(fmt.Sprintf("host-%d.test-zone.example.org", i), endpoint.RecordTypeA, "1.2.3.4"))
+	}
+	require.NoError(t, r.AdoptRecords(context.Background(), records))
+
+	assert.Len(t, client.items, 60)
+	// three batches, each retried once for its unprocessed write
+	assert.Equal(t, 6, client.batches)
+	for _, labels := range client.items {
+		assert.Equal(t, "owner", labels[endpoint.OwnerLabelKey])
+	}
+}
+
+func TestDynamoDBRegistryCache(t *testing.T) {
+	ctx := context.Background()
+	p := provider.NewInMemoryProvider()
+	p.CreateZone(testZone)
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("foo.test-zone.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+		},
+	}))
+	client := newDynamoDBStub()
+	client.put("owner", "foo.test-zone.example.org#A#", endpoint.Labels{endpoint.OwnerLabelKey: "owner"})
+	r, _ := NewDynamoDBRegistry(p, client, "ownership", "owner", time.Hour)
+
+	owners := func() map[string]string {
+		records, err := r.Records(ctx)
+		require.NoError(t, err)
+		owners := map[string]string{}
+		for _, record := range records {
+			owners[record.DNSName] = record.Labels[endpoint.OwnerLabelKey]
+		}
+		return owners
+	}
+
+	assert.Equal(t, map[string]string{"foo.test-zone.example.org": "owner"}, owners())
+	assert.Equal(t, 1, client.scans)
+
+	// the writes of the registry update the cache instead of scanning the table again
+	require.NoError(t, r.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{endpoint.NewEndpoint("bar.test-zone.example.org", endpoint.RecordTypeA, "1.2.3.4")},
+		Delete: []*endpoint.Endpoint{newEndpointWithOwner("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "owner")},
+	}))
+	assert.Equal(t, map[string]string{"bar.test-zone.example.org": "owner"}, owners())
+	assert.Equal(t, 1, client.scans)
+
+	// the table is scanned again once the interval passed
+	r.itemsRefreshTime = time.Now().Add(-time.Hour)
+	assert.Equal(t, map[string]string{"bar.test-zone.example.org": "owner"}, owners())
+	assert.Equal(t, 2, client.scans)
+}
@@ -34,6 +34,13 @@ type Registry interface {
 	ApplyChanges(ctx context.Context, changes *plan.Changes) error
 }
 
+// Adopter is implemented by the registries which can take over the ownership of existing records without
+// changing them, e.g. to bring a zone maintained by hand under management.
+type Adopter interface {
+	// AdoptRecords records the ownership of the given records, keeping their other labels.
+	AdoptRecords(ctx context.Context, records []*endpoint.Endpoint) error
+}
+
 //TODO(ideahitme): consider moving this to Plan
 func filterOwnedRecords(ownerID string, eps []*endpoint.Endpoint) []*endpoint.Endpoint {
 	filtered := []*endpoint.Endpoint{}
@@ -23,6 +23,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
 
 	"sigs.k8s.io/external-dns/endpoint"
@@ -30,34 +31,103 @@ import (
 	"sigs.k8s.io/external-dns/provider"
 )
 
+var clusterConflicts = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Namespace: "external_dns",
+		Subsystem: "registry",
+		Name:      "cluster_conflicts",
+		Help:      "Number of records the last synchronization wanted to change while another cluster keeps them with a different content.",
+	},
+	[]string{"pipeline", "cluster"},
+)
+
+func init() {
+	prometheus.MustRegister(clusterConflicts)
+}
+
+// The reason of the events reporting the records the current cluster and another one disagree on
+const clusterConflictReason = "ClusterConflict"
+
+// EventRecorder reports the warnings about the records of a resource to it, e.g. as Kubernetes events.
+type EventRecorder interface {
+	RecordWarning(resource, reason, message string)
+}
+
 // TXTRegistry implements registry interface with ownership implemented via associated TXT records
 type TXTRegistry struct {
 	provider provider.Provider
 	ownerID  string //refers to the owner id of the current instance
 	mapper   nameMapper
 
+	// the cluster of the current instance, the records of other clusters with the same owner id are
+	// left alone unless they may be taken over
+	clusterID           string
+	takeoverFrom        map[string]bool
+	takeoverGracePeriod time.Duration
+	// since when the changes of the records of other clusters are held back
+	heldBackSince map[string]time.Time
+	now           func() time.Time
+	// the clusters whose conflicts the metric was set for by the last synchronization
+	conflictClusters map[string]bool
+	// the pipeline labeling the metrics
+	pipeline string
+	// the recorder of the conflicts, nil to only log them
+	eventRecorder EventRecorder
+
+	// the number of owned records sharing each TXT record, counted from the last listing of the records
+	// and kept up to date by the applied changes, nil to list the records again
+	ownershipHolders map[string]int
+
 	// cache the records in memory and update on an interval instead.
 	recordsCache            []*endpoint.Endpoint
 	recordsCacheRefreshTime time.Time
 	cacheInterval           time.Duration
 }
 
-// NewTXTRegistry returns new TXTRegistry object
-func NewTXTRegistry(provider provider.Provider, txtPrefix, ownerID string, cacheInterval time.Duration) (*TXTRegistry, error) {
+// NewTXTRegistry returns new TXTRegistry object. With a cluster id, the records of the instances of other
+// clusters sharing the owner id are only updated when they may be taken over from their cluster and the
+// conflict lasted for the grace period.
+func NewTXTRegistry(provider provider.Provider, txtPrefix, ownerID string, cacheInterval time.Duration, clusterID string, takeoverFrom []string, takeoverGracePeriod time.Duration) (*TXTRegistry, error) {
 	if ownerID == "" {
 		return nil, errors.New("owner id cannot be empty")
 	}
+	if strings.ContainsAny(clusterID, ",=\" \t") {
+		return nil, fmt.Errorf("cluster id %q must not contain commas, equal signs, quotes or whitespace", clusterID)
+	}
+	if clusterID == "" && len(takeoverFrom) > 0 {
+		return nil, errors.New("taking over records from other clusters requires a cluster id")
+	}
 
 	mapper := newPrefixNameMapper(txtPrefix)
 
+	takeover := map[string]bool{}
+	for _, cluster := range takeoverFrom {
+		takeover[cluster] = true
+	}
+
 	return &TXTRegistry{
-		provider:      provider,
-		ownerID:       ownerID,
-		mapper:        mapper,
-		cacheInterval: cacheInterval,
+		provider:            provider,
+		ownerID:             ownerID,
+		mapper:              mapper,
+		clusterID:           clusterID,
+		takeoverFrom:        takeover,
+		takeoverGracePeriod: takeoverGracePeriod,
+		heldBackSince:       map[string]time.Time{},
+		now:                 time.Now,
+		cacheInterval:       cacheInterval,
 	}, nil
 }
 
+// SetPipeline labels the metrics of the registry with the pipeline of its controller.
+func (im *TXTRegistry) SetPipeline(name string) {
+	im.pipeline = name
+}
+
+// SetEventRecorder reports the conflicts with other clusters to the resources of the records.
+func (im *TXTRegistry) SetEventRecorder(recorder EventRecorder) {
+	im.eventRecorder = recorder
+}
+
 // Records returns the current records from the registry excluding TXT Records
 // If TXT records was created previously to indicate ownership its corresponding value
 // will be added to the endpoints Labels map
@@ -69,34 +139,44 @@ func (im *TXTRegistry) Records(ctx context.Context) ([]*endpoint.Endpoint, error
 		return im.recordsCache, nil
 	}
 
-	records, err := im.provider.Records(ctx)
-	if err != nil {
-		return nil, err
-	}
-
+	// the records of the zones which could be listed are returned along with the errors of the others.
+	// The records are listed page by page if the provider supports it, so only the labels of the TXT
+	// records are held while the remaining pages are listed.
 	endpoints := []*endpoint.Endpoint{}
 
 	labelMap := map[string]endpoint.Labels{}
 
-	for _, record := range records {
-		if record.RecordType != endpoint.RecordTypeTXT {
-			endpoints = append(endpoints, record)
-			continue
-		}
-		// We simply assume that TXT records for the registry will always have only one target.
-		labels, err := endpoint.NewLabelsFromString(record.Targets[0])
-		if err == endpoint.ErrInvalidHeritage {
-			//if no heritage is found or it is invalid
-			//case when value of txt record cannot be identified
-			//record will not be removed as it will have empty owner
-			endpoints = append(endpoints, record)
-			continue
-		}
-		if err != nil {
-			return nil, err
+	var labelsErr error
+	err := provider.RecordsPages(ctx, im.provider, func(page []*endpoint.Endpoint) bool {
+		for _, record := range page {
+			if record.RecordType != endpoint.RecordTypeTXT {
+				endpoints = append(endpoints, record)
+				continue
+			}
+			// We simply assume that TXT records for the registry will always have only one target.
+			labels, err := endpoint.NewLabelsFromString(record.Targets[0])
+			if err == endpoint.ErrInvalidHeritage {
+				//if no heritage is found or it is invalid
+				//case when value of txt record cannot be identified
+				//record will not be removed as it will have empty owner
+				endpoints = append(endpoints, record)
+				continue
+			}
+			if err != nil {
+				labelsErr = err
+				return false
+			}
+			key := fmt.Sprintf("%s::%s", im.mapper.toEndpointName(record.DNSName), record.SetIdentifier)
+			labelMap[key] = labels
 		}
-		key := fmt.Sprintf("%s::%s", im.mapper.toEndpointName(record.DNSName), record.SetIdentifier)
-		labelMap[key] = labels
+		return true
+	})
+	if labelsErr != nil {
+		return nil, labelsErr
+	}
+	zoneErrors, partial := err.(provider.ZoneErrors)
+	if err != nil && !partial {
+		return nil, err
 	}
 
 	for _, ep := range endpoints {
@@ -111,6 +191,13 @@ func (im *TXTRegistry) Records(ctx context.Context) ([]*endpoint.Endpoint, error
 		}
 	}
 
+	im.ownershipHolders = countOwnershipHolders(im.ownerID, endpoints)
+
+	if partial {
+		// not cached, so the failed zones are listed again next time
+		return endpoints, zoneErrors
+	}
+
 	// Update the cache.
 	if im.cacheInterval > 0 {
 		im.recordsCache = endpoints
@@ -124,59 +211,84 @@ func (im *TXTRegistry) Records(ctx context.Context) ([]*endpoint.Endpoint, error
 // for each created/deleted record it will also take into account TXT records for creation/deletion
 func (im *TXTRegistry) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
 	filteredChanges := &plan.Changes{
-		Create:    changes.Create,
-		UpdateNew: filterOwnedRecords(im.ownerID, changes.UpdateNew),
-		UpdateOld: filterOwnedRecords(im.ownerID, changes.UpdateOld),
-		Delete:    filterOwnedRecords(im.ownerID, changes.Delete),
+		Create:    make([]*endpoint.Endpoint, 0, 2*len(changes.Create)),
+		UpdateNew: []*endpoint.Endpoint{},
+		UpdateOld: []*endpoint.Endpoint{},
+		Delete:    []*endpoint.Endpoint{},
 	}
-	for _, r := range filteredChanges.Create {
+
+	// the records of a name share its TXT record, e.g. the A and AAAA records of a dual-stack name,
+	// so it's created with the first and deleted with the last of them
+	holders, err := im.currentOwnershipHolders(ctx)
+	if err != nil {
+		return err
+	}
+
+	// every TXT record directly follows the record it owns, so providers which split the changes
+	// into batches submit ownership together with the data change.
+	for _, r := range changes.Create {
 		if r.Labels == nil {
 			r.Labels = make(map[string]string)
 		}
 		r.Labels[endpoint.OwnerLabelKey] = im.ownerID
-		txt := endpoint.NewEndpoint(im.mapper.toTXTName(r.DNSName), endpoint.RecordTypeTXT, r.Labels.Serialize(true)).WithSetIdentifier(r.SetIdentifier)
-		txt.ProviderSpecific = r.ProviderSpecific
-		filteredChanges.Create = append(filteredChanges.Create, txt)
+		if im.clusterID != "" {
+			r.Labels[endpoint.ClusterLabelKey] = im.clusterID
+		}
+		filteredChanges.Create = append(filteredChanges.Create, r)
+		key := ownershipKey(r)
+		if holders[key] == 0 {
+			filteredChanges.Create = append(filteredChanges.Create, im.newOwnershipRecord(r))
+		}
+		holders[key]++
 
 		if im.cacheInterval > 0 {
 			im.addToCache(r)
 		}
 	}
 
-	for _, r := range filteredChanges.Delete {
-		txt := endpoint.NewEndpoint(im.mapper.toTXTName(r.DNSName), endpoint.RecordTypeTXT, r.Labels.Serialize(true)).WithSetIdentifier(r.SetIdentifier)
-		txt.ProviderSpecific = r.ProviderSpecific
+	deletes := filterOwnedRecords(im.ownerID, changes.Delete)
+	updateOld, updateNew := filterOwnedRecords(im.ownerID, changes.UpdateOld), filterOwnedRecords(im.ownerID, changes.UpdateNew)
+	if im.clusterID != "" {
+		deletes, updateOld, updateNew = im.filterClusterChanges(deletes, updateOld, updateNew)
+	}
 
-		// when we delete TXT records for which value has changed (due to new label) this would still work because
-		// !!! TXT record value is uniquely generated from the Labels of the endpoint. Hence old TXT record can be uniquely reconstructed
-		filteredChanges.Delete = append(filteredChanges.Delete, txt)
+	deletedOwnership := map[string]bool{}
+	for _, r := range deletes {
+		filteredChanges.Delete = append(filteredChanges.Delete, r)
+		key := ownershipKey(r)
+		if holders[key]--; holders[key] <= 0 && !deletedOwnership[key] {
+			// when we delete TXT records for which value has changed (due to new label) this would still work because
+			// !!! TXT record value is uniquely generated from the Labels of the endpoint. Hence old TXT record can be uniquely reconstructed
+			filteredChanges.Delete = append(filteredChanges.Delete, im.newOwnershipRecord(r))
+			deletedOwnership[key] = true
+		}
 
 		if im.cacheInterval > 0 {
 			im.removeFromCache(r)
 		}
 	}
 
-	// make sure TXT records are consistently updated as well
-	for _, r := range filteredChanges.UpdateOld {
-		txt := endpoint.NewEndpoint(im.mapper.toTXTName(r.DNSName), endpoint.RecordTypeTXT, r.Labels.Serialize(true)).WithSetIdentifier(r.SetIdentifier)
-		txt.ProviderSpecific = r.ProviderSpecific
+	// make sure TXT records are consistently updated as well, the shared TXT record of a name only once
+	updatedOwnership := map[string]bool{}
+	for i, r := range updateOld {
+		if i >= len(updateNew) {
+			break
+		}
 		// when we updateOld TXT records for which value has changed (due to new label) this would still work because
 		// !!! TXT record value is uniquely generated from the Labels of the endpoint. Hence old TXT record can be uniquely reconstructed
-		filteredChanges.UpdateOld = append(filteredChanges.UpdateOld, txt)
-		// remove old version of record from cache
-		if im.cacheInterval > 0 {
-			im.removeFromCache(r)
+		oldOwnership, newOwnership := im.newOwnershipRecord(r), im.newOwnershipRecord(updateNew[i])
+		filteredChanges.UpdateOld = append(filteredChanges.UpdateOld, r)
+		filteredChanges.UpdateNew = append(filteredChanges.UpdateNew, updateNew[i])
+		if key := ownershipKey(r) + "::" + oldOwnership.Targets[0] + "::" + newOwnership.Targets[0]; !updatedOwnership[key] {
+			filteredChanges.UpdateOld = append(filteredChanges.UpdateOld, oldOwnership)
+			filteredChanges.UpdateNew = append(filteredChanges.UpdateNew, newOwnership)
+			updatedOwnership[key] = true
 		}
-	}
 
-	// make sure TXT records are consistently updated as well
-	for _, r := range filteredChanges.UpdateNew {
-		txt := endpoint.NewEndpoint(im.mapper.toTXTName(r.DNSName), endpoint.RecordTypeTXT, r.Labels.Serialize(true)).WithSetIdentifier(r.SetIdentifier)
-		txt.ProviderSpecific = r.ProviderSpecific
-		filteredChanges.UpdateNew = append(filteredChanges.UpdateNew, txt)
-		// add new version of record to cache
+		// replace the old version of the record with the new one in the cache
 		if im.cacheInterval > 0 {
-			im.addToCache(r)
+			im.removeFromCache(r)
+			im.addToCache(updateNew[i])
 		}
 	}
 
@@ -184,13 +296,204 @@ func (im *TXTRegistry) ApplyChanges(ctx context.Context, changes *plan.Changes)
 	if im.cacheInterval > 0 {
 		ctx = context.WithValue(ctx, provider.RecordsContextKey, nil)
 	}
-	return im.provider.ApplyChanges(ctx, filteredChanges)
+	err = im.provider.ApplyChanges(ctx, filteredChanges)
+	// the changes may be applied partially or not at all, so the cache doesn't know the records anymore
+	if err != nil {
+		im.recordsCache = nil
+		im.ownershipHolders = nil
+		return err
+	}
+	im.ownershipHolders = holders
+	return nil
+}
+
+// AdoptRecords creates the ownership TXT records of existing records, the records themselves are left alone.
+func (im *TXTRegistry) AdoptRecords(ctx context.Context, records []*endpoint.Endpoint) error {
+	changes := &plan.Changes{Create: make([]*endpoint.Endpoint, 0, len(records))}
+	// the records of a name share its TXT record, e.g. the A and AAAA records of a dual-stack name
+	created := map[string]bool{}
+	for _, r := range records {
+		if r.Labels == nil {
+			r.Labels = make(map[string]string)
+		}
+		r.Labels[endpoint.OwnerLabelKey] = im.ownerID
+		if key := ownershipKey(r); !created[key] {
+			changes.Create = append(changes.Create, im.newOwnershipRecord(r))
+			created[key] = true
+		}
+	}
+
+	// the cached records don't know about their new owner yet
+	im.recordsCache = nil
+	im.ownershipHolders = nil
+	return im.provider.ApplyChanges(ctx, changes)
 }
 
 /**
   TXT registry specific private methods
 */
 
+// currentOwnershipHolders returns a copy of the number of owned records sharing each TXT record. The records
+// are listed if they weren't listed yet, or the last changes failed.
+func (im *TXTRegistry) currentOwnershipHolders(ctx context.Context) (map[string]int, error) {
+	if im.ownershipHolders == nil {
+		im.recordsCache = nil
+		if _, err := im.Records(ctx); err != nil {
+			// the records of the failed zones aren't changed
+			if _, partial := err.(provider.ZoneErrors); !partial {
+				return nil, err
+			}
+		}
+	}
+	holders := make(map[string]int, len(im.ownershipHolders))
+	for key, count := range im.ownershipHolders {
+		holders[key] = count
+	}
+	return holders, nil
+}
+
+// countOwnershipHolders returns the number of records of the owner sharing each TXT record.
+func countOwnershipHolders(ownerID string, records []*endpoint.Endpoint) map[string]int {
+	holders := map[string]int{}
+	for _, r := range records {
+		if r.RecordType != endpoint.RecordTypeTXT && r.Labels[endpoint.OwnerLabelKey] == ownerID {
+			holders[ownershipKey(r)]++
+		}
+	}
+	return holders
+}
+
+// ownershipKey identifies the TXT record owning a record, which is shared by all records of its name and set identifier.
+func ownershipKey(r *endpoint.Endpoint) string {
+	return strings.ToLower(strings.TrimSuffix(r.DNSName, ".")) + "::" + r.SetIdentifier
+}
+
+// filterClusterChanges drops the deletions and updates of the records of other clusters, unless they may be
+// taken over and the change was held back for the grace period. Records without a cluster, e.g. created before
+// the cluster id was set, are changed. Updated records are labeled with the cluster of the current instance.
+// Only updates changing the content of a record are conflicts, i.e. both clusters want the record with a
+// different content; they're reported as warnings, events of the resources and, by cluster, as a metric. The
+// other cluster still wants the records it keeps, so their deletions and updates of the labels only aren't
+// conflicts and are only logged at debug level.
+func (im *TXTRegistry) filterClusterChanges(deletes, updateOld, updateNew []*endpoint.Endpoint) ([]*endpoint.Endpoint, []*endpoint.Endpoint, []*endpoint.Endpoint) {
+	now := im.now()
+	conflicts := map[string]int{}
+	seen := map[string]bool{}
+
+	filteredDeletes := []*endpoint.Endpoint{}
+	for _, ep := range deletes {
+		cluster := ep.Labels[endpoint.ClusterLabelKey]
+		if cluster != "" && cluster != im.clusterID && im.holdBack(ep, nil, "deletion", cluster, now, seen) {
+			continue
+		}
+		filteredDeletes = append(filteredDeletes, ep)
+	}
+
+	filteredOld, filteredNew := []*endpoint.Endpoint{}, []*endpoint.Endpoint{}
+	// the old and new versions of an update are at the same position as both have the same owner
+	for i, current := range updateOld {
+		if i >= len(updateNew) {
+			break
+		}
+		cluster := current.Labels[endpoint.ClusterLabelKey]
+		if cluster != "" && cluster != im.clusterID {
+			var desired *endpoint.Endpoint
+			if !sameContent(current, updateNew[i]) {
+				desired = updateNew[i]
+			}
+			if im.holdBack(current, desired, "update", cluster, now, seen) {
+				if desired != nil {
+					conflicts[cluster]++
+				}
+				continue
+			}
+		}
+		if updateNew[i].Labels == nil {
+			updateNew[i].Labels = endpoint.NewLabels()
+		}
+		updateNew[i].Labels[endpoint.ClusterLabelKey] = im.clusterID
+		filteredOld = append(filteredOld, current)
+		filteredNew = append(filteredNew, updateNew[i])
+	}
+
+	// the changes which aren't held back anymore start over
+	for key := range im.heldBackSince {
+		if !seen[key] {
+			delete(im.heldBackSince, key)
+		}
+	}
+
+	// only the clusters of this registry are removed, the registries of other pipelines have their own
+	for cluster := range im.conflictClusters {
+		if conflicts[cluster] == 0 {
+			clusterConflicts.DeleteLabelValues(im.pipeline, cluster)
+		}
+	}
+	im.conflictClusters = make(map[string]bool, len(conflicts))
+	for cluster, count := range conflicts {
+		clusterConflicts.WithLabelValues(im.pipeline, cluster).Set(float64(count))
+		im.conflictClusters[cluster] = true
+	}
+	return filteredDeletes, filteredOld, filteredNew
+}
+
+// holdBack returns true if the given change, i.e. an update or deletion, of the record of another cluster
+// is held back now, because the record may not be taken over or the grace period hasn't passed yet. The
+// desired record is given if the change is a conflict, it's reported to its resource when the change is
+// first held back.
+func (im *TXTRegistry) holdBack(ep, desired *endpoint.Endpoint, change, cluster string, now time.Time, seen map[string]bool) bool {
+	logf := log.Debugf
+	if desired != nil {
+		logf = log.Warnf
+	}
+
+	key := fmt.Sprintf("%s::%s::%s", ep.DNSName, ep.SetIdentifier, ep.RecordType)
+	since, ok := im.heldBackSince[key]
+	if !ok {
+		since = now
+		im.heldBackSince[key] = now
+	}
+	var message string
+	if !im.takeoverFrom[cluster] {
+		message = fmt.Sprintf(`Skipping %s of %v because it's owned by cluster "%s"`, change, ep, cluster)
+	} else if remaining := im.takeoverGracePeriod - now.Sub(since); remaining > 0 {
+		message = fmt.Sprintf(`Skipping %s of %v because it's owned by cluster "%s", taking it over in %s`, change, ep, cluster, remaining)
+	} else {
+		log.Infof(`Taking over %v from cluster "%s"`, ep, cluster)
+		return false
+	}
+
+	seen[key] = true
+	logf("%s", message)
+	if desired != nil && !ok && im.eventRecorder != nil && desired.Labels[endpoint.ResourceLabelKey] != "" {
+		im.eventRecorder.RecordWarning(desired.Labels[endpoint.ResourceLabelKey], clusterConflictReason,
+			fmt.Sprintf(`%s (%s) is kept by cluster "%s" with the targets %s, not %s`, ep.DNSName, ep.RecordType, cluster, ep.Targets, desired.Targets))
+	}
+	return true
+}
+
+// sameContent returns true if an update changes the labels of a record only.
+func sameContent(current, desired *endpoint.Endpoint) bool {
+	if !current.Targets.Same(desired.Targets) || current.RecordTTL != desired.RecordTTL || len(current.ProviderSpecific) != len(desired.ProviderSpecific) {
+		return false
+	}
+	for _, property := range current.ProviderSpecific {
+		if value, ok := desired.GetProviderSpecificProperty(property.Name); !ok || value.Value != property.Value {
+			return false
+		}
+	}
+	return true
+}
+
+// newOwnershipRecord returns the TXT record storing the labels of the given record. It's labeled with
+// the name of the record it owns, so providers can keep both in the same batch.
+func (im *TXTRegistry) newOwnershipRecord(r *endpoint.Endpoint) *endpoint.Endpoint {
+	txt := endpoint.NewEndpoint(im.mapper.toTXTName(r.DNSName), endpoint.RecordTypeTXT, r.Labels.Serialize(true)).WithSetIdentifier(r.SetIdentifier)
+	txt.ProviderSpecific = r.ProviderSpecific
+	txt.Labels[endpoint.OwnedRecordLabelKey] = r.DNSName
+	return txt
+}
+
 /**
   nameMapper defines interface which maps the dns name defined for the source
   to the dns name which TXT record will be created with
@@ -18,10 +18,12 @@ package registry
 
 import (
 	"context"
+	"errors"
 	"reflect"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -39,14 +41,19 @@ func TestTXTRegistry(t *testing.T) {
 	t.Run("TestNewTXTRegistry", testTXTRegistryNew)
 	t.Run("TestRecords", testTXTRegistryRecords)
 	t.Run("TestApplyChanges", testTXTRegistryApplyChanges)
+	t.Run("TestRecordsRewrittenByProvider", testTXTRegistryRecordsRewrittenByProvider)
+	t.Run("TestRecordsZoneErrors", testTXTRegistryRecordsZoneErrors)
+	t.Run("TestRecordsPages", testTXTRegistryRecordsPages)
+	t.Run("TestAdoptRecords", testTXTRegistryAdoptRecords)
+	t.Run("TestClusters", testTXTRegistryClusters)
 }
 
 func testTXTRegistryNew(t *testing.T) {
 	p := provider.NewInMemoryProvider()
-	_, err := NewTXTRegistry(p, "txt", "", time.Hour)
+	_, err := NewTXTRegistry(p, "txt", "", time.Hour, "", nil, 0)
 	require.Error(t, err)
 
-	r, err := NewTXTRegistry(p, "txt", "owner", time.Hour)
+	r, err := NewTXTRegistry(p, "txt", "owner", time.Hour, "", nil, 0)
 	require.NoError(t, err)
 
 	_, ok := r.mapper.(prefixNameMapper)
@@ -54,7 +61,7 @@ func testTXTRegistryNew(t *testing.T) {
 	assert.Equal(t, "owner", r.ownerID)
 	assert.Equal(t, p, r.provider)
 
-	r, err = NewTXTRegistry(p, "", "owner", time.Hour)
+	r, err = NewTXTRegistry(p, "", "owner", time.Hour, "", nil, 0)
 	require.NoError(t, err)
 
 	_, ok = r.mapper.(prefixNameMapper)
@@ -159,13 +166,13 @@ func testTXTRegistryRecordsPrefixed(t *testing.T) {
 		},
 	}
 
-	r, _ := NewTXTRegistry(p, "txt.", "owner", time.Hour)
+	r, _ := NewTXTRegistry(p, "txt.", "owner", time.Hour, "", nil, 0)
 	records, _ := r.Records(ctx)
 
 	assert.True(t, testutils.SameEndpoints(records, expectedRecords))
 
 	// Ensure prefix is case-insensitive
-	r, _ = NewTXTRegistry(p, "TxT.", "owner", time.Hour)
+	r, _ = NewTXTRegistry(p, "TxT.", "owner", time.Hour, "", nil, 0)
 	records, _ = r.Records(ctx)
 
 	assert.True(t, testutils.SameEndpointLabels(records, expectedRecords))
@@ -240,15 +247,197 @@ func testTXTRegistryRecordsNoPrefix(t *testing.T) {
 		},
 	}
 
-	r, _ := NewTXTRegistry(p, "", "owner", time.Hour)
+	r, _ := NewTXTRegistry(p, "", "owner", time.Hour, "", nil, 0)
 	records, _ := r.Records(ctx)
 
 	assert.True(t, testutils.SameEndpoints(records, expectedRecords))
 }
 
+// testTXTRegistryRecordsRewrittenByProvider checks that the owner is still found when a provider
+// escapes or splits the content of the TXT records.
+func testTXTRegistryRecordsRewrittenByProvider(t *testing.T) {
+	p := provider.NewInMemoryProvider()
+	ctx := context.Background()
+	p.CreateZone(testZone)
+	p.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("foo.test-zone.example.org", "foo.loadbalancer.com", endpoint.RecordTypeCNAME, ""),
+			newEndpointWithOwner("foo.test-zone.example.org", `"\"heritage=external-dns,external-dns/owner=owner\""`, endpoint.RecordTypeTXT, ""),
+			newEndpointWithOwner("bar.test-zone.example.org", "my-domain.com", endpoint.RecordTypeCNAME, ""),
+			newEndpointWithOwner("bar.test-zone.example.org", `"heritage=external-dns,external-dns/own" "er=owner,external-dns/resource=ingress/default/my-ingress"`, endpoint.RecordTypeTXT, ""),
+		},
+	})
+	expectedRecords := []*endpoint.Endpoint{
+		{
+			DNSName:    "foo.test-zone.example.org",
+			Targets:    endpoint.Targets{"foo.loadbalancer.com"},
+			RecordType: endpoint.RecordTypeCNAME,
+			Labels: map[string]string{
+				endpoint.OwnerLabelKey: "owner",
+			},
+		},
+		{
+			DNSName:    "bar.test-zone.example.org",
+			Targets:    endpoint.Targets{"my-domain.com"},
+			RecordType: endpoint.RecordTypeCNAME,
+			Labels: map[string]string{
+				endpoint.OwnerLabelKey:    "owner",
+				endpoint.ResourceLabelKey: "ingress/default/my-ingress",
+			},
+		},
+	}
+
+	r, _ := NewTXTRegistry(p, "", "owner", time.Hour, "", nil, 0)
+	records, _ := r.Records(ctx)
+
+	assert.True(t, testutils.SameEndpoints(records, expectedRecords))
+}
+
+func testTXTRegistryAdoptRecords(t *testing.T) {
+	p := provider.NewInMemoryProvider()
+	ctx := context.Background()
+	p.CreateZone(testZone)
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("foo.test-zone.example.org", "foo.loadbalancer.com", endpoint.RecordTypeCNAME, ""),
+			newEndpointWithOwner("dual.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+			newEndpointWithOwner("dual.test-zone.example.org", "2001:db8::1", endpoint.RecordTypeAAAA, ""),
+		},
+	}))
+
+	r, _ := NewTXTRegistry(p, "txt.", "owner", time.Hour, "", nil, 0)
+	records, err := r.Records(ctx)
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	adopted := []*endpoint.Endpoint{}
+	for _, record := range records {
+		assert.Empty(t, record.Labels[endpoint.OwnerLabelKey])
+		record = record.DeepCopy()
+		record.Labels[endpoint.ResourceLabelKey] = "ingress/default/my-ingress"
+		adopted = append(adopted, record)
+	}
+
+	// the records of the dual-stack name share a single TXT record, which is created once
+	require.NoError(t, r.AdoptRecords(ctx, adopted))
+
+	expectedRecords := []*endpoint.Endpoint{
+		newEndpointWithOwnerResource("foo.test-zone.example.org", "foo.loadbalancer.com", endpoint.RecordTypeCNAME, "owner", "ingress/default/my-ingress"),
+		newEndpointWithOwnerResource("dual.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "owner", "ingress/default/my-ingress"),
+		newEndpointWithOwnerResource("dual.test-zone.example.org", "2001:db8::1", endpoint.RecordTypeAAAA, "owner", "ingress/default/my-ingress"),
+	}
+	records, err = r.Records(ctx)
+	require.NoError(t, err)
+	assert.True(t, testutils.SameEndpoints(records, expectedRecords))
+}
+
+// zoneErrorsProvider returns the records of its provider along with the errors of the failed zones.
+type zoneErrorsProvider struct {
+	provider.Provider
+	zoneErrors provider.ZoneErrors
+	calls      int
+}
+
+func (p *zoneErrorsProvider) Records(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	p.calls++
+	records, err := p.Provider.Records(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(p.zoneErrors) > 0 {
+		return records, p.zoneErrors
+	}
+	return records, nil
+}
+
+// pagingProvider passes the records of its provider to RecordsPages one record per page.
+type pagingProvider struct {
+	provider.Provider
+	pages int
+}
+
+func (p *pagingProvider) RecordsPages(ctx context.Context, fn func(page []*endpoint.Endpoint) bool) error {
+	records, err := p.Provider.Records(ctx)
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		p.pages++
+		if !fn([]*endpoint.Endpoint{record}) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func testTXTRegistryRecordsPages(t *testing.T) {
+	inMemory := provider.NewInMemoryProvider()
+	ctx := context.Background()
+	inMemory.CreateZone(testZone)
+	inMemory.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("foo.test-zone.example.org", "foo.loadbalancer.com", endpoint.RecordTypeCNAME, ""),
+			newEndpointWithOwner("foo.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner\"", endpoint.RecordTypeTXT, ""),
+			newEndpointWithOwner("bar.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner\"", endpoint.RecordTypeTXT, ""),
+			newEndpointWithOwner("bar.test-zone.example.org", "bar.loadbalancer.com", endpoint.RecordTypeCNAME, ""),
+		},
+	})
+	p := &pagingProvider{Provider: inMemory}
+	expectedRecords := []*endpoint.Endpoint{
+		newEndpointWithOwner("foo.test-zone.example.org", "foo.loadbalancer.com", endpoint.RecordTypeCNAME, "owner"),
+		newEndpointWithOwner("bar.test-zone.example.org", "bar.loadbalancer.com", endpoint.RecordTypeCNAME, "owner"),
+	}
+
+	// the labels of the TXT records are applied regardless of the page they are listed on
+	r, _ := NewTXTRegistry(p, "", "owner", 0, "", nil, 0)
+	records, err := r.Records(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 4, p.pages)
+	assert.True(t, testutils.SameEndpoints(records, expectedRecords))
+}
+
+func testTXTRegistryRecordsZoneErrors(t *testing.T) {
+	inMemory := provider.NewInMemoryProvider()
+	ctx := context.Background()
+	inMemory.CreateZone(testZone)
+	inMemory.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("foo.test-zone.example.org", "foo.loadbalancer.com", endpoint.RecordTypeCNAME, ""),
+			newEndpointWithOwner("foo.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner\"", endpoint.RecordTypeTXT, ""),
+		},
+	})
+	p := &zoneErrorsProvider{
+		Provider:   inMemory,
+		zoneErrors: provider.ZoneErrors{"broken.example.org": errors.New("500 Internal Server Error")},
+	}
+	expectedRecords := []*endpoint.Endpoint{
+		{
+			DNSName:    "foo.test-zone.example.org",
+			Targets:    endpoint.Targets{"foo.loadbalancer.com"},
+			RecordType: endpoint.RecordTypeCNAME,
+			Labels: map[string]string{
+				endpoint.OwnerLabelKey: "owner",
+			},
+		},
+	}
+
+	r, _ := NewTXTRegistry(p, "", "owner", time.Hour, "", nil, 0)
+	records, err := r.Records(ctx)
+	assert.Equal(t, p.zoneErrors, err)
+	assert.True(t, testutils.SameEndpoints(records, expectedRecords))
+
+	// partial results aren't cached
+	p.zoneErrors = nil
+	records, err = r.Records(ctx)
+	assert.NoError(t, err)
+	assert.True(t, testutils.SameEndpoints(records, expectedRecords))
+	assert.Equal(t, 2, p.calls)
+}
+
 func testTXTRegistryApplyChanges(t *testing.T) {
 	t.Run("With Prefix", testTXTRegistryApplyChangesWithPrefix)
 	t.Run("No prefix", testTXTRegistryApplyChangesNoPrefix)
+	t.Run("Ownership records follow their records", testTXTRegistryApplyChangesOwnershipOrder)
+	t.Run("Dual-stack names share their ownership record", testTXTRegistryApplyChangesDualStack)
 }
 
 func testTXTRegistryApplyChangesWithPrefix(t *testing.T) {
@@ -276,7 +465,7 @@ func testTXTRegistryApplyChangesWithPrefix(t *testing.T) {
 			newEndpointWithOwner("txt.multiple.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner\"", endpoint.RecordTypeTXT, "").WithSetIdentifier("test-set-2"),
 		},
 	})
-	r, _ := NewTXTRegistry(p, "txt.", "owner", time.Hour)
+	r, _ := NewTXTRegistry(p, "txt.", "owner", time.Hour, "", nil, 0)
 
 	changes := &plan.Changes{
 		Create: []*endpoint.Endpoint{
@@ -363,7 +552,7 @@ func testTXTRegistryApplyChangesNoPrefix(t *testing.T) {
 			newEndpointWithOwner("foobar.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner\"", endpoint.RecordTypeTXT, ""),
 		},
 	})
-	r, _ := NewTXTRegistry(p, "", "owner", time.Hour)
+	r, _ := NewTXTRegistry(p, "", "owner", time.Hour, "", nil, 0)
 
 	changes := &plan.Changes{
 		Create: []*endpoint.Endpoint{
@@ -411,6 +600,247 @@ func testTXTRegistryApplyChangesNoPrefix(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func testTXTRegistryApplyChangesOwnershipOrder(t *testing.T) {
+	p := provider.NewInMemoryProvider()
+	p.CreateZone(testZone)
+	ctx := context.Background()
+	r, _ := NewTXTRegistry(p, "txt.", "owner", time.Hour, "", nil, 0)
+
+	var applied *plan.Changes
+	p.OnApplyChanges = func(ctx context.Context, got *plan.Changes) {
+		applied = got
+	}
+	err := r.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("foo.test-zone.example.org", "foo.loadbalancer.com", endpoint.RecordTypeCNAME, ""),
+			newEndpointWithOwner("bar.test-zone.example.org", "bar.loadbalancer.com", endpoint.RecordTypeCNAME, ""),
+		},
+	})
+	require.NoError(t, err)
+
+	require.Len(t, applied.Create, 4)
+	for i := 0; i < len(applied.Create); i += 2 {
+		record, txt := applied.Create[i], applied.Create[i+1]
+		assert.Equal(t, endpoint.RecordTypeTXT, txt.RecordType)
+		assert.Equal(t, "txt."+record.DNSName, txt.DNSName)
+		assert.Equal(t, record.DNSName, txt.Labels[endpoint.OwnedRecordLabelKey])
+	}
+}
+
+func testTXTRegistryApplyChangesDualStack(t *testing.T) {
+	var applied *plan.Changes
+	p := newInMemoryProvider(nil, func(changes *plan.Changes) {
+		applied = changes
+	})
+	r, _ := NewTXTRegistry(p, "", "owner", 0, "", nil, 0)
+	newA := func() *endpoint.Endpoint {
+		return newEndpointWithOwnerResource("dual.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "owner", "service/default/dual")
+	}
+	newAAAA := func() *endpoint.Endpoint {
+		return newEndpointWithOwnerResource("dual.test-zone.example.org", "2001:db8::1", endpoint.RecordTypeAAAA, "owner", "service/default/dual")
+	}
+	types := func(endpoints []*endpoint.Endpoint) []string {
+		var recordTypes []string
+		for _, ep := range endpoints {
+			recordTypes = append(recordTypes, ep.RecordType)
+		}
+		return recordTypes
+	}
+
+	// the TXT record is only created once for both records
+	require.NoError(t, r.ApplyChanges(context.Background(), &plan.Changes{Create: []*endpoint.Endpoint{newA(), newAAAA()}}))
+	assert.Equal(t, []string{endpoint.RecordTypeA, endpoint.RecordTypeTXT, endpoint.RecordTypeAAAA}, types(applied.Create))
+
+	// the AAAA record of a name with an A record reuses its TXT record
+	p.endpoints = []*endpoint.Endpoint{newA()}
+	_, err := r.Records(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, r.ApplyChanges(context.Background(), &plan.Changes{Create: []*endpoint.Endpoint{newAAAA()}}))
+	assert.Equal(t, []string{endpoint.RecordTypeAAAA}, types(applied.Create))
+
+	// the TXT record is kept until the last record of the name is deleted, a registry which didn't list
+	// the records yet lists them first
+	p.endpoints = []*endpoint.Endpoint{newA(), newAAAA()}
+	r, _ = NewTXTRegistry(p, "", "owner", 0, "", nil, 0)
+	require.NoError(t, r.ApplyChanges(context.Background(), &plan.Changes{Delete: []*endpoint.Endpoint{newAAAA()}}))
+	assert.Equal(t, []string{endpoint.RecordTypeAAAA}, types(applied.Delete))
+	require.NoError(t, r.ApplyChanges(context.Background(), &plan.Changes{Delete: []*endpoint.Endpoint{newA()}}))
+	assert.Equal(t, []string{endpoint.RecordTypeA, endpoint.RecordTypeTXT}, types(applied.Delete))
+	_, err = r.Records(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, r.ApplyChanges(context.Background(), &plan.Changes{Delete: []*endpoint.Endpoint{newA(), newAAAA()}}))
+	assert.Equal(t, []string{endpoint.RecordTypeA, endpoint.RecordTypeAAAA, endpoint.RecordTypeTXT}, types(applied.Delete))
+
+	// the shared TXT record is updated once
+	updatedA, updatedAAAA := newA(), newAAAA()
+	updatedA.Labels[endpoint.ResourceLabelKey] = "service/default/dual-v2"
+	updatedAAAA.Labels[endpoint.ResourceLabelKey] = "service/default/dual-v2"
+	require.NoError(t, r.ApplyChanges(context.Background(), &plan.Changes{UpdateOld: []*endpoint.Endpoint{newA(), newAAAA()}, UpdateNew: []*endpoint.Endpoint{updatedA, updatedAAAA}}))
+	assert.Equal(t, []string{endpoint.RecordTypeA, endpoint.RecordTypeTXT, endpoint.RecordTypeAAAA}, types(applied.UpdateOld))
+	assert.Equal(t, []string{endpoint.RecordTypeA, endpoint.RecordTypeTXT, endpoint.RecordTypeAAAA}, types(applied.UpdateNew))
+	assert.Equal(t, "\"heritage=external-dns,external-dns/owner=owner,external-dns/resource=service/default/dual-v2\"", applied.UpdateNew[1].Targets[0])
+}
+
+func testTXTRegistryClusters(t *testing.T) {
+	t.Run("New", testTXTRegistryClustersNew)
+	t.Run("Records of other clusters are kept", testTXTRegistryClustersKeepRecords)
+	t.Run("Records are taken over after the grace period", testTXTRegistryClustersTakeover)
+	t.Run("Conflicts are reported to the resources", testTXTRegistryClustersConflicts)
+}
+
+func testTXTRegistryClustersNew(t *testing.T) {
+	p := provider.NewInMemoryProvider()
+	_, err := NewTXTRegistry(p, "", "owner", 0, "cluster a", nil, 0)
+	require.Error(t, err)
+
+	_, err = NewTXTRegistry(p, "", "owner", 0, "", []string{"cluster-b"}, 0)
+	require.Error(t, err)
+
+	r, err := NewTXTRegistry(p, "", "owner", 0, "cluster-a", []string{"cluster-b"}, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, "cluster-a", r.clusterID)
+	assert.True(t, r.takeoverFrom["cluster-b"])
+	assert.Equal(t, time.Minute, r.takeoverGracePeriod)
+}
+
+func testTXTRegistryClustersKeepRecords(t *testing.T) {
+	var applied *plan.Changes
+	p := newInMemoryProvider(nil, func(changes *plan.Changes) {
+		applied = changes
+	})
+	r, _ := NewTXTRegistry(p, "", "owner", 0, "cluster-a", nil, 0)
+
+	err := r.ApplyChanges(context.Background(), &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("new.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+		},
+		UpdateOld: []*endpoint.Endpoint{
+			newEndpointWithOwnerAndLabels("own.test-zone.example.org", "1.1.1.1", endpoint.RecordTypeA, "owner", endpoint.Labels{endpoint.ClusterLabelKey: "cluster-a"}),
+			newEndpointWithOwnerAndLabels("other.test-zone.example.org", "1.1.1.1", endpoint.RecordTypeA, "owner", endpoint.Labels{endpoint.ClusterLabelKey: "cluster-b"}),
+			newEndpointWithOwner("legacy.test-zone.example.org", "1.1.1.1", endpoint.RecordTypeA, "owner"),
+		},
+		UpdateNew: []*endpoint.Endpoint{
+			newEndpointWithOwnerAndLabels("own.test-zone.example.org", "2.2.2.2", endpoint.RecordTypeA, "owner", endpoint.Labels{endpoint.ClusterLabelKey: "cluster-a"}),
+			newEndpointWithOwnerAndLabels("other.test-zone.example.org", "2.2.2.2", endpoint.RecordTypeA, "owner", endpoint.Labels{endpoint.ClusterLabelKey: "cluster-b"}),
+			newEndpointWithOwner("legacy.test-zone.example.org", "2.2.2.2", endpoint.RecordTypeA, "owner"),
+		},
+		Delete: []*endpoint.Endpoint{
+			newEndpointWithOwnerAndLabels("gone.test-zone.example.org", "1.1.1.1", endpoint.RecordTypeA, "owner", endpoint.Labels{endpoint.ClusterLabelKey: "cluster-a"}),
+			newEndpointWithOwnerAndLabels("wanted.test-zone.example.org", "1.1.1.1", endpoint.RecordTypeA, "owner", endpoint.Labels{endpoint.ClusterLabelKey: "cluster-b"}),
+		},
+	})
+	require.NoError(t, err)
+
+	require.Len(t, applied.Create, 2)
+	assert.Equal(t, "cluster-a", applied.Create[0].Labels[endpoint.ClusterLabelKey])
+
+	require.Len(t, applied.UpdateNew, 4)
+	assert.Equal(t, "own.test-zone.example.org", applied.UpdateNew[0].DNSName)
+	assert.Equal(t, "legacy.test-zone.example.org", applied.UpdateNew[2].DNSName)
+	assert.Equal(t, "cluster-a", applied.UpdateNew[2].Labels[endpoint.ClusterLabelKey])
+	require.Len(t, applied.UpdateOld, 4)
+	assert.Equal(t, "legacy.test-zone.example.org", applied.UpdateOld[2].DNSName)
+
+	require.Len(t, applied.Delete, 2)
+	assert.Equal(t, "gone.test-zone.example.org", applied.Delete[0].DNSName)
+
+	// the deletion of a record the other cluster keeps isn't a conflict
+	assert.Equal(t, 1.0, testutil.ToFloat64(clusterConflicts.WithLabelValues("", "cluster-b")))
+}
+
+func testTXTRegistryClustersTakeover(t *testing.T) {
+	var applied *plan.Changes
+	p := newInMemoryProvider(nil, func(changes *plan.Changes) {
+		applied = changes
+	})
+	r, _ := NewTXTRegistry(p, "", "owner", 0, "cluster-a", []string{"cluster-b"}, time.Hour)
+	now := time.Now()
+	r.now = func() time.Time { return now }
+
+	changes := func() *plan.Changes {
+		return &plan.Changes{
+			UpdateOld: []*endpoint.Endpoint{
+				newEndpointWithOwnerAndLabels("other.test-zone.example.org", "1.1.1.1", endpoint.RecordTypeA, "owner", endpoint.Labels{endpoint.ClusterLabelKey: "cluster-b"}),
+				newEndpointWithOwnerAndLabels("foreign.test-zone.example.org", "1.1.1.1", endpoint.RecordTypeA, "owner", endpoint.Labels{endpoint.ClusterLabelKey: "cluster-c"}),
+			},
+			UpdateNew: []*endpoint.Endpoint{
+				newEndpointWithOwnerAndLabels("other.test-zone.example.org", "2.2.2.2", endpoint.RecordTypeA, "owner", endpoint.Labels{endpoint.ClusterLabelKey: "cluster-b"}),
+				newEndpointWithOwnerAndLabels("foreign.test-zone.example.org", "2.2.2.2", endpoint.RecordTypeA, "owner", endpoint.Labels{endpoint.ClusterLabelKey: "cluster-c"}),
+			},
+			Delete: []*endpoint.Endpoint{
+				newEndpointWithOwnerAndLabels("gone.test-zone.example.org", "1.1.1.1", endpoint.RecordTypeA, "owner", endpoint.Labels{endpoint.ClusterLabelKey: "cluster-b"}),
+				newEndpointWithOwnerAndLabels("wanted.test-zone.example.org", "1.1.1.1", endpoint.RecordTypeA, "owner", endpoint.Labels{endpoint.ClusterLabelKey: "cluster-c"}),
+			},
+		}
+	}
+
+	require.NoError(t, r.ApplyChanges(context.Background(), changes()))
+	assert.Empty(t, applied.UpdateNew)
+	assert.Empty(t, applied.Delete)
+	assert.Equal(t, 1.0, testutil.ToFloat64(clusterConflicts.WithLabelValues("", "cluster-b")))
+
+	// the registry of another pipeline leaves the conflicts of this one alone
+	other, _ := NewTXTRegistry(newInMemoryProvider(nil, func(*plan.Changes) {}), "", "owner", 0, "cluster-a", nil, 0)
+	other.SetPipeline("internal")
+	require.NoError(t, other.ApplyChanges(context.Background(), &plan.Changes{}))
+	assert.Equal(t, 1.0, testutil.ToFloat64(clusterConflicts.WithLabelValues("", "cluster-b")))
+
+	now = now.Add(30 * time.Minute)
+	require.NoError(t, r.ApplyChanges(context.Background(), changes()))
+	assert.Empty(t, applied.UpdateNew)
+	assert.Empty(t, applied.Delete)
+
+	now = now.Add(30 * time.Minute)
+	require.NoError(t, r.ApplyChanges(context.Background(), changes()))
+	require.Len(t, applied.UpdateNew, 2)
+	assert.Equal(t, "other.test-zone.example.org", applied.UpdateNew[0].DNSName)
+	assert.Equal(t, "cluster-a", applied.UpdateNew[0].Labels[endpoint.ClusterLabelKey])
+	// the deletion is taken over after the grace period as well, along with its TXT record
+	require.Len(t, applied.Delete, 2)
+	assert.Equal(t, "gone.test-zone.example.org", applied.Delete[0].DNSName)
+	assert.Equal(t, 0.0, testutil.ToFloat64(clusterConflicts.WithLabelValues("", "cluster-b")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(clusterConflicts.WithLabelValues("", "cluster-c")))
+}
+
+// recordedWarnings records the warnings of the registry by resource.
+type recordedWarnings map[string][]string
+
+func (w recordedWarnings) RecordWarning(resource, reason, message string) {
+	w[resource] = append(w[resource], reason+": "+message)
+}
+
+func testTXTRegistryClustersConflicts(t *testing.T) {
+	var applied *plan.Changes
+	p := newInMemoryProvider(nil, func(changes *plan.Changes) {
+		applied = changes
+	})
+	r, _ := NewTXTRegistry(p, "", "owner", 0, "cluster-a", nil, 0)
+	warnings := recordedWarnings{}
+	r.SetEventRecorder(warnings)
+
+	changes := func() *plan.Changes {
+		return &plan.Changes{
+			UpdateOld: []*endpoint.Endpoint{
+				newEndpointWithOwnerAndLabels("conflict.test-zone.example.org", "1.1.1.1", endpoint.RecordTypeA, "owner", endpoint.Labels{endpoint.ClusterLabelKey: "cluster-b"}),
+				newEndpointWithOwnerAndLabels("labels.test-zone.example.org", "1.1.1.1", endpoint.RecordTypeA, "owner", endpoint.Labels{endpoint.ClusterLabelKey: "cluster-b"}),
+			},
+			UpdateNew: []*endpoint.Endpoint{
+				newEndpointWithOwnerResource("conflict.test-zone.example.org", "2.2.2.2", endpoint.RecordTypeA, "owner", "ingress/default/conflict"),
+				newEndpointWithOwnerResource("labels.test-zone.example.org", "1.1.1.1", endpoint.RecordTypeA, "owner", "ingress/default/labels"),
+			},
+		}
+	}
+
+	// the conflict is reported once while it lasts, the update of the labels only isn't a conflict
+	require.NoError(t, r.ApplyChanges(context.Background(), changes()))
+	require.NoError(t, r.ApplyChanges(context.Background(), changes()))
+	assert.Empty(t, applied.UpdateNew)
+	assert.Equal(t, recordedWarnings{
+		"ingress/default/conflict": {`ClusterConflict: conflict.test-zone.example.org (A) is kept by cluster "cluster-b" with the targets 1.1.1.1, not 2.2.2.2`},
+	}, warnings)
+	assert.Equal(t, 1.0, testutil.ToFloat64(clusterConflicts.WithLabelValues("", "cluster-b")))
+}
+
 func TestCacheMethods(t *testing.T) {
 	cache := []*endpoint.Endpoint{
 		newEndpointWithOwner("thing.com", "1.2.3.4", "A", "owner"),
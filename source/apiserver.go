@@ -0,0 +1,169 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+const (
+	apiServerEndpointsNamespace = "default"
+	apiServerEndpointsName      = "kubernetes"
+)
+
+// apiServerSource is an implementation of Source publishing the control plane endpoint of the cluster,
+// e.g. for fleet bootstrap automation. The API server is published with the addresses of the
+// default/kubernetes endpoints. Additional control plane services like the konnectivity server or an
+// ingress gateway are published with their load balancer and external IPs. The FQDN template is applied
+// to the endpoints and services, so e.g. {{.Name}}.cluster-a.example.org results in
+// kubernetes.cluster-a.example.org.
+type apiServerSource struct {
+	client             kubernetes.Interface
+	fqdnTemplate       *template.Template
+	additionalServices []string
+}
+
+// NewAPIServerSource creates a new apiServerSource with the given config.
+func NewAPIServerSource(kubeClient kubernetes.Interface, fqdnTemplate string, additionalServices []string) (Source, error) {
+	if fqdnTemplate == "" {
+		return nil, errors.New("the api-server source requires an FQDN template")
+	}
+	tmpl, err := template.New("endpoint").Funcs(template.FuncMap{
+		"trimPrefix": strings.TrimPrefix,
+	}).Parse(fqdnTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, service := range additionalServices {
+		if _, _, err := parseAPIServerAdditionalService(service); err != nil {
+			return nil, err
+		}
+	}
+
+	return &apiServerSource{
+		client:             kubeClient,
+		fqdnTemplate:       tmpl,
+		additionalServices: additionalServices,
+	}, nil
+}
+
+// Endpoints returns endpoint objects for the API server and the additional control plane services.
+func (sc *apiServerSource) Endpoints() ([]*endpoint.Endpoint, error) {
+	endpoints := []*endpoint.Endpoint{}
+
+	apiServer, err := sc.client.CoreV1().Endpoints(apiServerEndpointsNamespace).Get(apiServerEndpointsName, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		log.Warnf("Unable to find the API server endpoints %s/%s", apiServerEndpointsNamespace, apiServerEndpointsName)
+	case err != nil:
+		return nil, err
+	default:
+		var targets endpoint.Targets
+		for _, subset := range apiServer.Subsets {
+			for _, address := range subset.Addresses {
+				targets = append(targets, address.IP)
+			}
+		}
+
+		apiServerEndpoints, err := sc.endpointsFromTemplate(apiServer, targets, fmt.Sprintf("endpoints/%s/%s", apiServer.Namespace, apiServer.Name))
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, apiServerEndpoints...)
+	}
+
+	for _, service := range sc.additionalServices {
+		namespace, name, _ := parseAPIServerAdditionalService(service)
+		svc, err := sc.client.CoreV1().Services(namespace).Get(name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			log.Warnf("Unable to find the control plane service %s", service)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		targets := extractLoadBalancerTargets(svc)
+		targets = append(targets, svc.Spec.ExternalIPs...)
+
+		serviceEndpoints, err := sc.endpointsFromTemplate(svc, targets, fmt.Sprintf("service/%s/%s", svc.Namespace, svc.Name))
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, serviceEndpoints...)
+	}
+
+	for _, ep := range endpoints {
+		sort.Sort(ep.Targets)
+	}
+
+	return endpoints, nil
+}
+
+func (sc *apiServerSource) endpointsFromTemplate(obj metav1.Object, targets endpoint.Targets, resource string) ([]*endpoint.Endpoint, error) {
+	if len(targets) == 0 {
+		log.Debugf("No endpoints could be generated from %s because it has no address", resource)
+		return nil, nil
+	}
+
+	var buf bytes.Buffer
+	if err := sc.fqdnTemplate.Execute(&buf, obj); err != nil {
+		return nil, fmt.Errorf("failed to apply template on %s: %v", resource, err)
+	}
+
+	var endpoints []*endpoint.Endpoint
+	for _, hostname := range strings.Split(strings.Replace(buf.String(), " ", "", -1), ",") {
+		if hostname == "" {
+			continue
+		}
+		endpoints = append(endpoints, endpointsForHostname(hostname, targets, endpoint.TTL(0), endpoint.ProviderSpecific{}, "")...)
+	}
+	for _, ep := range endpoints {
+		ep.Labels[endpoint.ResourceLabelKey] = resource
+	}
+
+	log.Debugf("Endpoints generated from %s: %v", resource, endpoints)
+	return endpoints, nil
+}
+
+func (sc *apiServerSource) AddEventHandler(handler func() error, stopChan <-chan struct{}, minInterval time.Duration) {
+}
+
+func parseAPIServerAdditionalService(service string) (namespace, name string, err error) {
+	parts := strings.Split(service, "/")
+	if len(parts) != 2 {
+		err = fmt.Errorf("invalid control plane service (namespace/name) found '%v'", service)
+	} else {
+		namespace, name = parts[0], parts[1]
+	}
+
+	return
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestAPIServerSource(t *testing.T) {
+	kubernetes := fake.NewSimpleClientset()
+
+	_, err := kubernetes.CoreV1().Endpoints("default").Create(&v1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "kubernetes"},
+		Subsets: []v1.EndpointSubset{{
+			Addresses: []v1.EndpointAddress{{IP: "10.0.0.1"}, {IP: "10.0.0.2"}},
+		}},
+	})
+	require.NoError(t, err)
+
+	_, err = kubernetes.CoreV1().Services("kube-system").Create(&v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "konnectivity-server"},
+		Spec:       v1.ServiceSpec{Type: v1.ServiceTypeLoadBalancer},
+		Status: v1.ServiceStatus{
+			LoadBalancer: v1.LoadBalancerStatus{Ingress: []v1.LoadBalancerIngress{{Hostname: "konnectivity.elb.example.com"}}},
+		},
+	})
+	require.NoError(t, err)
+
+	client, err := NewAPIServerSource(kubernetes, "{{.Name}}.cluster-a.example.org", []string{"kube-system/konnectivity-server", "kube-system/missing"})
+	require.NoError(t, err)
+
+	endpoints, err := client.Endpoints()
+	require.NoError(t, err)
+
+	validateEndpoints(t, endpoints, []*endpoint.Endpoint{
+		{DNSName: "kubernetes.cluster-a.example.org", Targets: endpoint.Targets{"10.0.0.1", "10.0.0.2"}, RecordType: endpoint.RecordTypeA},
+		{DNSName: "konnectivity-server.cluster-a.example.org", Targets: endpoint.Targets{"konnectivity.elb.example.com"}, RecordType: endpoint.RecordTypeCNAME},
+	})
+}
+
+func TestAPIServerSourceRequiresTemplate(t *testing.T) {
+	_, err := NewAPIServerSource(fake.NewSimpleClientset(), "", nil)
+	assert.Error(t, err)
+
+	_, err = NewAPIServerSource(fake.NewSimpleClientset(), "{{.Name}}.example.org", []string{"konnectivity-server"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// caaPolicySource is an implementation of Source publishing the CAA records of the certificate issuance
+// policy of each configured zone, e.g. example.org=letsencrypt.org,digicert.com results in
+//
+//	example.org CAA 0 issue "letsencrypt.org"
+//	example.org CAA 0 issue "digicert.com"
+//
+// The records are owned by the registry like all other records, so they are recreated or repaired
+// whenever they are removed or changed. A zone without issuers forbids issuance with 0 issue ";".
+type caaPolicySource struct {
+	endpoints []*endpoint.Endpoint
+}
+
+// NewCAAPolicySource creates a new caaPolicySource for policies of the form <zone>=<issuer>[,<issuer>...].
+func NewCAAPolicySource(policies []string) (Source, error) {
+	sc := &caaPolicySource{}
+	zones := map[string]bool{}
+	for _, policy := range policies {
+		zone, issuers, err := parseCAAPolicy(policy)
+		if err != nil {
+			return nil, err
+		}
+		if zones[zone] {
+			return nil, fmt.Errorf("duplicate CAA policy of zone %s", zone)
+		}
+		zones[zone] = true
+
+		targets := make([]string, 0, len(issuers))
+		for _, issuer := range issuers {
+			targets = append(targets, fmt.Sprintf("0 issue %q", issuer))
+		}
+		ep := endpoint.NewEndpoint(zone, endpoint.RecordTypeCAA, targets...)
+		if err := ep.ValidateTargets(); err != nil {
+			return nil, fmt.Errorf("invalid CAA policy '%v': %v", policy, err)
+		}
+		sc.endpoints = append(sc.endpoints, ep)
+	}
+	return sc, nil
+}
+
+// Endpoints returns the CAA records of the policies.
+func (sc *caaPolicySource) Endpoints() ([]*endpoint.Endpoint, error) {
+	endpoints := make([]*endpoint.Endpoint, 0, len(sc.endpoints))
+	for _, ep := range sc.endpoints {
+		endpoints = append(endpoints, ep.DeepCopy())
+	}
+	return endpoints, nil
+}
+
+func (sc *caaPolicySource) AddEventHandler(handler func() error, stopChan <-chan struct{}, minInterval time.Duration) {
+}
+
+// parseCAAPolicy returns the zone and the issuers of a policy, ";" if none are allowed.
+func parseCAAPolicy(policy string) (zone string, issuers []string, err error) {
+	parts := strings.SplitN(policy, "=", 2)
+	zone = strings.ToLower(strings.Trim(strings.TrimSpace(parts[0]), "."))
+	if len(parts) != 2 || zone == "" {
+		return "", nil, fmt.Errorf("invalid CAA policy (zone=issuer,...) found '%v'", policy)
+	}
+
+	for _, issuer := range strings.Split(parts[1], ",") {
+		if issuer = strings.TrimSpace(issuer); issuer != "" {
+			issuers = append(issuers, issuer)
+		}
+	}
+	if len(issuers) == 0 {
+		issuers = []string{";"}
+	}
+	return zone, issuers, nil
+}
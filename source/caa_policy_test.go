@@ -0,0 +1,81 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestCAAPolicySource(t *testing.T) {
+	sc, err := NewCAAPolicySource([]string{
+		"Example.org.=letsencrypt.org, digicert.com",
+		"internal.example.org=",
+	})
+	require.NoError(t, err)
+
+	endpoints, err := sc.Endpoints()
+	require.NoError(t, err)
+	validateEndpoints(t, endpoints, []*endpoint.Endpoint{
+		{DNSName: "example.org", Targets: endpoint.Targets{`0 issue "letsencrypt.org"`, `0 issue "digicert.com"`}, RecordType: endpoint.RecordTypeCAA},
+		{DNSName: "internal.example.org", Targets: endpoint.Targets{`0 issue ";"`}, RecordType: endpoint.RecordTypeCAA},
+	})
+
+	// the endpoints can be changed by the controller without affecting the next synchronization
+	endpoints[0].Targets = nil
+	endpoints, err = sc.Endpoints()
+	require.NoError(t, err)
+	assert.Len(t, endpoints[0].Targets, 2)
+}
+
+func TestCAAPolicySourceInvalidPolicies(t *testing.T) {
+	for _, tc := range []struct {
+		title    string
+		policies []string
+		err      string
+	}{
+		{
+			title:    "missing issuers",
+			policies: []string{"example.org"},
+			err:      "invalid CAA policy (zone=issuer,...) found 'example.org'",
+		},
+		{
+			title:    "missing zone",
+			policies: []string{"=letsencrypt.org"},
+			err:      "invalid CAA policy (zone=issuer,...) found '=letsencrypt.org'",
+		},
+		{
+			title:    "duplicate zone",
+			policies: []string{"example.org=letsencrypt.org", "example.org.=digicert.com"},
+			err:      "duplicate CAA policy of zone example.org",
+		},
+		{
+			title:    "quoted issuer",
+			policies: []string{`example.org="letsencrypt.org"`},
+			err:      `invalid CAA policy 'example.org="letsencrypt.org"': CAA record example.org has invalid target "0 issue \"\\\"letsencrypt.org\\\"\"": invalid property "issue \"\\\"letsencrypt.org\\\"\"", expected a tag and a quoted value`,
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			_, err := NewCAAPolicySource(tc.policies)
+			assert.EqualError(t, err, tc.err)
+		})
+	}
+}
@@ -0,0 +1,189 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+const (
+	// The annotation used for defining the zone the DNS01 challenges of a certificate are delegated to
+	challengeDelegationAnnotationKey = "external-dns.alpha.kubernetes.io/acme-challenge-delegation"
+	// The label prefixed to a domain by the ACME DNS01 challenge
+	acmeChallengeLabel = "_acme-challenge"
+)
+
+var certificateGVR = schema.GroupVersionResource{Group: "cert-manager.io", Version: "v1alpha2", Resource: "certificates"}
+
+// certManagerChallengeSource is an implementation of Source publishing the CNAME records which delegate the
+// ACME DNS01 challenges of annotated cert-manager Certificates to another zone, e.g. one served by acme-dns or
+// a zone cert-manager has credentials for. The challenge of every DNS name of the certificate is delegated:
+//
+//	_acme-challenge.<dns name> CNAME <dns name>.<delegation zone>
+//
+// Wildcard names share the challenge record of their base domain.
+type certManagerChallengeSource struct {
+	dynamicKubeClient   dynamic.Interface
+	namespace           string
+	annotationFilter    string
+	certificateInformer kubeinformers.GenericInformer
+}
+
+// NewCertManagerChallengeSource creates a new certManagerChallengeSource with the given config.
+func NewCertManagerChallengeSource(informers *InformerFactories, dynamicKubeClient dynamic.Interface, namespace, annotationFilter string) (Source, error) {
+	// Use shared informer to listen for add/update/delete of certificates in the specified namespace.
+	// Set resync period to 0, to prevent processing when nothing has changed.
+	informerFactory := informers.Dynamic(dynamicKubeClient, namespace)
+	certificateInformer := informerFactory.ForResource(certificateGVR)
+
+	// Add default resource event handlers to properly initialize informer.
+	certificateInformer.Informer().AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+			},
+		},
+	)
+
+	// TODO informer is not explicitly stopped since controller is not passing in its channel.
+	informerFactory.Start(wait.NeverStop)
+
+	// wait for the local cache to be populated.
+	err := wait.Poll(time.Second, 60*time.Second, func() (bool, error) {
+		return certificateInformer.Informer().HasSynced(), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sync cache: %v", err)
+	}
+
+	return &certManagerChallengeSource{
+		dynamicKubeClient:   dynamicKubeClient,
+		namespace:           namespace,
+		annotationFilter:    annotationFilter,
+		certificateInformer: certificateInformer,
+	}, nil
+}
+
+// Endpoints returns the challenge delegation records of each annotated certificate.
+func (sc *certManagerChallengeSource) Endpoints() ([]*endpoint.Endpoint, error) {
+	objects, err := sc.certificateInformer.Lister().ByNamespace(sc.namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	labelSelector, err := metav1.ParseToLabelSelector(sc.annotationFilter)
+	if err != nil {
+		return nil, err
+	}
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := []*endpoint.Endpoint{}
+
+	for _, obj := range objects {
+		cert, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		if !selector.Empty() && !selector.Matches(labels.Set(cert.GetAnnotations())) {
+			continue
+		}
+
+		// Check controller annotation to see if we are responsible.
+		controller, ok := cert.GetAnnotations()[controllerAnnotationKey]
+		if ok && controller != controllerAnnotationValue {
+			log.Debugf("Skipping certificate %s/%s because controller value does not match, found: %s, required: %s",
+				cert.GetNamespace(), cert.GetName(), controller, controllerAnnotationValue)
+			continue
+		}
+
+		certEndpoints := challengeDelegationEndpoints(cert)
+		if len(certEndpoints) == 0 {
+			continue
+		}
+
+		log.Debugf("Endpoints generated from certificate: %s/%s: %v", cert.GetNamespace(), cert.GetName(), certEndpoints)
+		setUnstructuredResourceLabel("certificate", cert, certEndpoints)
+		endpoints = append(endpoints, certEndpoints...)
+	}
+
+	for _, ep := range endpoints {
+		sort.Sort(ep.Targets)
+	}
+
+	return endpoints, nil
+}
+
+func (sc *certManagerChallengeSource) AddEventHandler(handler func() error, stopChan <-chan struct{}, minInterval time.Duration) {
+}
+
+// challengeDelegationEndpoints returns the CNAME records delegating the DNS01 challenges of a certificate.
+func challengeDelegationEndpoints(cert *unstructured.Unstructured) []*endpoint.Endpoint {
+	annotations := cert.GetAnnotations()
+	zone := strings.Trim(annotations[challengeDelegationAnnotationKey], ".")
+	if zone == "" {
+		return nil
+	}
+
+	dnsNames, _, err := unstructured.NestedStringSlice(cert.Object, "spec", "dnsNames")
+	if err != nil {
+		log.Warnf("Unable to read the DNS names of certificate %s/%s: %v", cert.GetNamespace(), cert.GetName(), err)
+		return nil
+	}
+	if commonName, _, _ := unstructured.NestedString(cert.Object, "spec", "commonName"); commonName != "" {
+		dnsNames = append(dnsNames, commonName)
+	}
+
+	ttl, err := getTTLFromAnnotations(annotations)
+	if err != nil {
+		log.Warn(err)
+	}
+
+	seen := map[string]bool{}
+	var endpoints []*endpoint.Endpoint
+	for _, name := range dnsNames {
+		name = strings.TrimSuffix(strings.TrimPrefix(name, "*."), ".")
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		endpoints = append(endpoints, endpoint.NewEndpointWithTTL(
+			acmeChallengeLabel+"."+name,
+			endpoint.RecordTypeCNAME,
+			ttl,
+			name+"."+zone,
+		))
+	}
+	return endpoints
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestChallengeDelegationEndpoints(t *testing.T) {
+	for _, tc := range []struct {
+		title       string
+		annotations map[string]string
+		spec        map[string]interface{}
+		expected    []*endpoint.Endpoint
+	}{
+		{
+			title: "challenges of all DNS names are delegated",
+			annotations: map[string]string{
+				challengeDelegationAnnotationKey: "challenges.example.net.",
+				ttlAnnotationKey:                 "300",
+			},
+			spec: map[string]interface{}{
+				"commonName": "example.org",
+				"dnsNames":   []interface{}{"example.org", "*.example.org", "www.example.com"},
+			},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "_acme-challenge.example.org", Targets: endpoint.Targets{"example.org.challenges.example.net"}, RecordType: endpoint.RecordTypeCNAME, RecordTTL: 300},
+				{DNSName: "_acme-challenge.www.example.com", Targets: endpoint.Targets{"www.example.com.challenges.example.net"}, RecordType: endpoint.RecordTypeCNAME, RecordTTL: 300},
+			},
+		},
+		{
+			title: "certificates without delegation are ignored",
+			spec: map[string]interface{}{
+				"dnsNames": []interface{}{"example.org"},
+			},
+			expected: []*endpoint.Endpoint{},
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			cert := &unstructured.Unstructured{Object: map[string]interface{}{"spec": tc.spec}}
+			cert.SetNamespace("testing")
+			cert.SetName("foo")
+			cert.SetAnnotations(tc.annotations)
+
+			validateEndpoints(t, challengeDelegationEndpoints(cert), tc.expected)
+		})
+	}
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"reflect"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/cache"
+)
+
+// ResourceChangeTimes tells when Services, Ingresses and DNSEndpoints were last changed. It watches the
+// resources and the time of a change is when it was observed, i.e. when a resource was added or any of
+// its spec, metadata or status changed, e.g. a new load balancer address. The resources which exist on
+// start are changed at that time. The applied records annotation written by ExternalDNS itself isn't a
+// change.
+type ResourceChangeTimes struct {
+	changes map[string]time.Time
+	lock    sync.Mutex
+	now     func() time.Time
+}
+
+// NewResourceChangeTimes creates a new ResourceChangeTimes and starts watching the resources. The
+// DNSEndpoints are the resources of the given API version and kind of the crd source.
+func NewResourceChangeTimes(informers *InformerFactories, client dynamic.Interface, crdAPIVersion, crdKind string) (*ResourceChangeTimes, error) {
+	resources, err := writableResources(crdAPIVersion, crdKind)
+	if err != nil {
+		return nil, err
+	}
+	r := &ResourceChangeTimes{
+		changes: map[string]time.Time{},
+		now:     time.Now,
+	}
+
+	informerFactory := informers.Dynamic(client, "")
+	for kind, gvr := range resources {
+		kind := kind
+		informerFactory.ForResource(gvr).Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				r.add(kind, obj)
+			},
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				r.update(kind, oldObj, newObj)
+			},
+			DeleteFunc: func(obj interface{}) {
+				r.delete(kind, obj)
+			},
+		})
+	}
+	// the changes are only observed, so the resources which can't be watched, e.g. without the crd, are
+	// unknown instead of failing the start
+	informerFactory.Start(wait.NeverStop)
+	return r, nil
+}
+
+// LastChanged returns the time of the last observed change of the resource. Resources which are gone,
+// not observed yet or of other kinds are unknown.
+func (r *ResourceChangeTimes) LastChanged(resource string) (time.Time, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	changedAt, ok := r.changes[resource]
+	return changedAt, ok
+}
+
+func (r *ResourceChangeTimes) add(kind string, obj interface{}) {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		r.changed(changeKey(kind, u))
+	}
+}
+
+func (r *ResourceChangeTimes) update(kind string, oldObj, newObj interface{}) {
+	oldU, ok := oldObj.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+	newU, ok := newObj.(*unstructured.Unstructured)
+	if !ok || reflect.DeepEqual(changedContent(oldU), changedContent(newU)) {
+		return
+	}
+	r.changed(changeKey(kind, newU))
+}
+
+func (r *ResourceChangeTimes) delete(kind string, obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.changes, changeKey(kind, u))
+}
+
+func (r *ResourceChangeTimes) changed(resource string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.changes[resource] = r.now()
+}
+
+// changeKey returns the resource label of the endpoints of an object, e.g. service/default/foo.
+func changeKey(kind string, obj *unstructured.Unstructured) string {
+	return kind + "/" + obj.GetNamespace() + "/" + obj.GetName()
+}
+
+// changedContent returns the content of an object without the fields written on every update by the
+// API server and the applied records annotation, so writing the annotation isn't a change.
+func changedContent(obj *unstructured.Unstructured) map[string]interface{} {
+	content := obj.DeepCopy().Object
+	unstructured.RemoveNestedField(content, "metadata", "resourceVersion")
+	unstructured.RemoveNestedField(content, "metadata", "managedFields")
+	unstructured.RemoveNestedField(content, "metadata", "annotations", appliedRecordsAnnotationKey)
+	if annotations, _, _ := unstructured.NestedMap(content, "metadata", "annotations"); len(annotations) == 0 {
+		unstructured.RemoveNestedField(content, "metadata", "annotations")
+	}
+	return content
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestResourceChangeTimes(t *testing.T) {
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	times := &ResourceChangeTimes{changes: map[string]time.Time{}, now: func() time.Time { return now }}
+
+	svc := &unstructured.Unstructured{}
+	svc.SetAPIVersion("v1")
+	svc.SetKind("Service")
+	svc.SetNamespace("default")
+	svc.SetName("foo")
+	svc.SetResourceVersion("1")
+	times.add("service", svc)
+
+	changedAt, ok := times.LastChanged("service/default/foo")
+	assert.True(t, ok)
+	assert.Equal(t, now, changedAt)
+
+	// writing the applied records annotation isn't a change
+	now = now.Add(time.Minute)
+	annotated := svc.DeepCopy()
+	annotated.SetResourceVersion("2")
+	annotated.SetAnnotations(map[string]string{appliedRecordsAnnotationKey: "{}"})
+	times.update("service", svc, annotated)
+	changedAt, _ = times.LastChanged("service/default/foo")
+	assert.Equal(t, now.Add(-time.Minute), changedAt)
+
+	// a new load balancer address is
+	now = now.Add(time.Minute)
+	balanced := annotated.DeepCopy()
+	balanced.SetResourceVersion("3")
+	balanced.Object["status"] = map[string]interface{}{"loadBalancer": map[string]interface{}{"ingress": []interface{}{map[string]interface{}{"ip": "1.2.3.4"}}}}
+	times.update("service", annotated, balanced)
+	changedAt, _ = times.LastChanged("service/default/foo")
+	assert.Equal(t, now, changedAt)
+
+	times.delete("service", cache.DeletedFinalStateUnknown{Key: "default/foo", Obj: balanced})
+	_, ok = times.LastChanged("service/default/foo")
+	assert.False(t, ok)
+	_, ok = times.LastChanged("node/node-1")
+	assert.False(t, ok)
+}
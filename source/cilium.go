@@ -0,0 +1,65 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"net"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+const (
+	// The annotation used by Cilium LB IPAM to request specific LoadBalancer IPs
+	ciliumLBIPAMIPsAnnotationKey = "lbipam.cilium.io/ips"
+	// The annotation used by older Cilium releases to request specific LoadBalancer IPs
+	ciliumLegacyLBIPAMIPsAnnotationKey = "io.cilium/lb-ipam-ips"
+)
+
+// extractCiliumLBIPAMTargets returns the VIPs requested from Cilium's LB IPAM.
+// Cilium allocates the requested addresses and starts announcing them (via BGP or L2)
+// before the allocation is reflected in the service status, so these are used to
+// publish a service whose status doesn't carry any address yet.
+func extractCiliumLBIPAMTargets(svc *v1.Service) endpoint.Targets {
+	var targets endpoint.Targets
+
+	for _, key := range []string{ciliumLBIPAMIPsAnnotationKey, ciliumLegacyLBIPAMIPsAnnotationKey} {
+		value, exists := svc.Annotations[key]
+		if !exists {
+			continue
+		}
+		for _, ip := range strings.Split(strings.Replace(value, " ", "", -1), ",") {
+			if ip == "" {
+				continue
+			}
+			if net.ParseIP(ip) == nil {
+				log.Warnf("Ignoring invalid Cilium LB IPAM address %q on service %s/%s", ip, svc.Namespace, svc.Name)
+				continue
+			}
+			targets = append(targets, ip)
+		}
+		// the current annotation takes precedence over the legacy one
+		if len(targets) > 0 {
+			break
+		}
+	}
+
+	return targets
+}
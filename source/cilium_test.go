@@ -0,0 +1,110 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestCiliumLBIPAMServices(t *testing.T) {
+	for _, tc := range []struct {
+		title       string
+		annotations map[string]string
+		lbs         []v1.LoadBalancerIngress
+		expected    []*endpoint.Endpoint
+	}{
+		{
+			"status addresses are published when present",
+			map[string]string{
+				hostnameAnnotationKey:        "foo.example.org",
+				ciliumLBIPAMIPsAnnotationKey: "10.0.0.1",
+			},
+			[]v1.LoadBalancerIngress{{IP: "10.0.0.2"}},
+			[]*endpoint.Endpoint{
+				{DNSName: "foo.example.org", Targets: endpoint.Targets{"10.0.0.2"}, RecordType: endpoint.RecordTypeA},
+			},
+		},
+		{
+			"requested addresses are published while the status is empty",
+			map[string]string{
+				hostnameAnnotationKey:        "foo.example.org",
+				ciliumLBIPAMIPsAnnotationKey: "10.0.0.1, 10.0.0.3",
+			},
+			[]v1.LoadBalancerIngress{},
+			[]*endpoint.Endpoint{
+				{DNSName: "foo.example.org", Targets: endpoint.Targets{"10.0.0.1", "10.0.0.3"}, RecordType: endpoint.RecordTypeA},
+			},
+		},
+		{
+			"legacy annotation is used when the current one is missing",
+			map[string]string{
+				hostnameAnnotationKey:              "foo.example.org",
+				ciliumLegacyLBIPAMIPsAnnotationKey: "10.0.0.4",
+			},
+			[]v1.LoadBalancerIngress{},
+			[]*endpoint.Endpoint{
+				{DNSName: "foo.example.org", Targets: endpoint.Targets{"10.0.0.4"}, RecordType: endpoint.RecordTypeA},
+			},
+		},
+		{
+			"invalid addresses are ignored",
+			map[string]string{
+				hostnameAnnotationKey:        "foo.example.org",
+				ciliumLBIPAMIPsAnnotationKey: "not-an-ip",
+			},
+			[]v1.LoadBalancerIngress{},
+			[]*endpoint.Endpoint{},
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			kubernetes := fake.NewSimpleClientset()
+
+			service := &v1.Service{
+				Spec: v1.ServiceSpec{
+					Type: v1.ServiceTypeLoadBalancer,
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:   "testing",
+					Name:        "foo",
+					Annotations: tc.annotations,
+				},
+				Status: v1.ServiceStatus{
+					LoadBalancer: v1.LoadBalancerStatus{
+						Ingress: tc.lbs,
+					},
+				},
+			}
+			_, err := kubernetes.CoreV1().Services(service.Namespace).Create(service)
+			require.NoError(t, err)
+
+			client, err := NewServiceSource(NewInformerFactories(), kubernetes, "", "", "", false, "", false, false, []string{}, false, false, false, "", false)
+			require.NoError(t, err)
+
+			endpoints, err := client.Endpoints()
+			require.NoError(t, err)
+
+			validateEndpoints(t, endpoints, tc.expected)
+		})
+	}
+}
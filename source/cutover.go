@@ -0,0 +1,184 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+const (
+	// The annotation used for adding the records of a resource to a blue/green cutover group
+	cutoverGroupAnnotationKey = "external-dns.alpha.kubernetes.io/cutover-group"
+	// The annotation used for defining the standby targets the records point at once their group is promoted
+	standbyTargetAnnotationKey = "external-dns.alpha.kubernetes.io/standby-target"
+
+	// The provider-specific properties carrying the cutover annotations to the cutoverSource
+	cutoverGroupProperty         = "cutover/group"
+	cutoverStandbyTargetProperty = "cutover/standby-target"
+
+	// The value of a cutover group in the promotion ConfigMap switching its records to the standby targets
+	cutoverStandby = "standby"
+)
+
+// cutoverSource is a Source orchestrating blue/green cutovers of its wrapped source. The records of
+// resources annotated with a cutover group point at their targets, i.e. the active set, until the group
+// is promoted in the promotion ConfigMap:
+//
+//	data:
+//	  payments: standby
+//
+// All records of the group then point at their standby targets at once, so several clusters or
+// services are cut over with a single change. Setting the group back to active, or removing it,
+// rolls the records back.
+type cutoverSource struct {
+	source             Source
+	client             kubernetes.Interface
+	configMapNamespace string
+	configMapName      string
+}
+
+// NewCutoverSource creates a new cutoverSource wrapping the provided Source. The promotion ConfigMap is
+// given as namespace/name; without one, all records point at their active targets.
+func NewCutoverSource(source Source, kubeClient kubernetes.Interface, configMap string) (Source, error) {
+	cs := &cutoverSource{source: source, client: kubeClient}
+	if configMap == "" {
+		return cs, nil
+	}
+
+	parts := strings.Split(configMap, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("invalid cutover ConfigMap (namespace/name) found '%v'", configMap)
+	}
+	if kubeClient == nil {
+		return nil, fmt.Errorf("the cutover ConfigMap %s requires a Kubernetes client", configMap)
+	}
+	cs.configMapNamespace, cs.configMapName = parts[0], parts[1]
+
+	return cs, nil
+}
+
+// Endpoints collects endpoints from its wrapped source and points the records of promoted cutover
+// groups at their standby targets.
+func (cs *cutoverSource) Endpoints() ([]*endpoint.Endpoint, error) {
+	endpoints, err := cs.source.Endpoints()
+	if err != nil {
+		return nil, err
+	}
+
+	promoted, err := cs.promotedGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*endpoint.Endpoint, 0, len(endpoints))
+	swapped := map[string]bool{}
+
+	for _, ep := range endpoints {
+		group, ok := ep.GetProviderSpecificProperty(cutoverGroupProperty)
+		if !ok {
+			result = append(result, ep)
+			continue
+		}
+		standby, _ := ep.GetProviderSpecificProperty(cutoverStandbyTargetProperty)
+		ep.ProviderSpecific = withoutCutoverProperties(ep.ProviderSpecific)
+
+		if !promoted[group.Value] || standby.Value == "" {
+			result = append(result, ep)
+			continue
+		}
+
+		// the A and CNAME records of a name are replaced by the records of the standby targets together
+		key := ep.DNSName + " / " + ep.SetIdentifier
+		if swapped[key] {
+			continue
+		}
+		swapped[key] = true
+
+		standbyTargets := endpoint.Targets(strings.Split(strings.Replace(standby.Value, " ", "", -1), ","))
+		for _, standbyEndpoint := range endpointsForHostname(ep.DNSName, standbyTargets, ep.RecordTTL, ep.ProviderSpecific, ep.SetIdentifier) {
+			for k, v := range ep.Labels {
+				standbyEndpoint.Labels[k] = v
+			}
+			log.Debugf("Pointing %s at the standby targets %s of promoted cutover group %s", standbyEndpoint.DNSName, standbyEndpoint.Targets, group.Value)
+			result = append(result, standbyEndpoint)
+		}
+	}
+
+	return result, nil
+}
+
+// promotedGroups returns the cutover groups set to standby in the promotion ConfigMap.
+func (cs *cutoverSource) promotedGroups() (map[string]bool, error) {
+	promoted := map[string]bool{}
+	if cs.configMapName == "" {
+		return promoted, nil
+	}
+
+	configMap, err := cs.client.CoreV1().ConfigMaps(cs.configMapNamespace).Get(cs.configMapName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		log.Debugf("Unable to find the cutover ConfigMap %s/%s, all cutover groups are active", cs.configMapNamespace, cs.configMapName)
+		return promoted, nil
+	}
+	if err != nil {
+		// don't fall back to the active targets, which would roll back promoted groups
+		return nil, fmt.Errorf("failed to get the cutover ConfigMap %s/%s: %v", cs.configMapNamespace, cs.configMapName, err)
+	}
+
+	for group, state := range configMap.Data {
+		if strings.TrimSpace(state) == cutoverStandby {
+			promoted[group] = true
+		}
+	}
+	return promoted, nil
+}
+
+func (cs *cutoverSource) AddEventHandler(handler func() error, stopChan <-chan struct{}, minInterval time.Duration) {
+	cs.source.AddEventHandler(handler, stopChan, minInterval)
+}
+
+// withoutCutoverProperties returns a copy of the provider-specific properties without the cutover ones,
+// which aren't meant for the providers.
+func withoutCutoverProperties(providerSpecific endpoint.ProviderSpecific) endpoint.ProviderSpecific {
+	result := endpoint.ProviderSpecific{}
+	for _, prop := range providerSpecific {
+		if prop.Name != cutoverGroupProperty && prop.Name != cutoverStandbyTargetProperty {
+			result = append(result, prop)
+		}
+	}
+	return result
+}
+
+// getCutoverFromAnnotations returns the provider-specific properties carrying the cutover annotations.
+func getCutoverFromAnnotations(annotations map[string]string) endpoint.ProviderSpecific {
+	group, exists := annotations[cutoverGroupAnnotationKey]
+	if !exists || group == "" {
+		return nil
+	}
+	return endpoint.ProviderSpecific{
+		{Name: cutoverGroupProperty, Value: group},
+		{Name: cutoverStandbyTargetProperty, Value: annotations[standbyTargetAnnotationKey]},
+	}
+}
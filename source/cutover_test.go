@@ -0,0 +1,142 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/internal/testutils"
+)
+
+// Validates that cutoverSource is a Source
+var _ Source = &cutoverSource{}
+
+func TestNewCutoverSource(t *testing.T) {
+	for _, tc := range []struct {
+		title     string
+		configMap string
+		expectErr bool
+	}{
+		{"no ConfigMap", "", false},
+		{"namespaced ConfigMap", "default/cutover", false},
+		{"ConfigMap without namespace", "cutover", true},
+		{"ConfigMap with empty name", "default/", true},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			_, err := NewCutoverSource(new(testutils.MockSource), fake.NewSimpleClientset(), tc.configMap)
+			if tc.expectErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCutoverSourceEndpoints(t *testing.T) {
+	annotations := map[string]string{
+		cutoverGroupAnnotationKey:  "payments",
+		standbyTargetAnnotationKey: "10.0.0.2, lb-green.example.org",
+	}
+	newEndpoints := func() []*endpoint.Endpoint {
+		endpoints := endpointsForHostnames([]string{"pay.example.org"}, endpoint.Targets{"10.0.0.1"}, annotations, "service default/pay")
+		for _, ep := range endpoints {
+			ep.Labels[endpoint.ResourceLabelKey] = "service/default/pay"
+		}
+		endpoints = append(endpoints, endpointsForHostnames([]string{"api.example.org"}, endpoint.Targets{"10.0.1.1"}, map[string]string{cutoverGroupAnnotationKey: "payments"}, "service default/api")...)
+		endpoints = append(endpoints, endpoint.NewEndpoint("other.example.org", endpoint.RecordTypeA, "10.0.9.9"))
+		return endpoints
+	}
+
+	for _, tc := range []struct {
+		title     string
+		configMap *v1.ConfigMap
+		expected  []*endpoint.Endpoint
+	}{
+		{
+			"missing ConfigMap keeps the active targets",
+			nil,
+			[]*endpoint.Endpoint{
+				{DNSName: "pay.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"10.0.0.1"}},
+				{DNSName: "api.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"10.0.1.1"}},
+				{DNSName: "other.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"10.0.9.9"}},
+			},
+		},
+		{
+			"active group keeps the active targets",
+			&v1.ConfigMap{Data: map[string]string{"payments": "active"}},
+			[]*endpoint.Endpoint{
+				{DNSName: "pay.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"10.0.0.1"}},
+				{DNSName: "api.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"10.0.1.1"}},
+				{DNSName: "other.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"10.0.9.9"}},
+			},
+		},
+		{
+			"promoted group points at the standby targets",
+			&v1.ConfigMap{Data: map[string]string{"payments": "standby"}},
+			[]*endpoint.Endpoint{
+				{DNSName: "pay.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"10.0.0.2"}},
+				{DNSName: "pay.example.org", RecordType: endpoint.RecordTypeCNAME, Targets: endpoint.Targets{"lb-green.example.org"}},
+				{DNSName: "api.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"10.0.1.1"}},
+				{DNSName: "other.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"10.0.9.9"}},
+			},
+		},
+		{
+			"other promoted group keeps the active targets",
+			&v1.ConfigMap{Data: map[string]string{"checkout": "standby"}},
+			[]*endpoint.Endpoint{
+				{DNSName: "pay.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"10.0.0.1"}},
+				{DNSName: "api.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"10.0.1.1"}},
+				{DNSName: "other.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"10.0.9.9"}},
+			},
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			kubeClient := fake.NewSimpleClientset()
+			if tc.configMap != nil {
+				tc.configMap.ObjectMeta = metav1.ObjectMeta{Namespace: "default", Name: "cutover"}
+				_, err := kubeClient.CoreV1().ConfigMaps("default").Create(tc.configMap)
+				require.NoError(t, err)
+			}
+
+			mockSource := new(testutils.MockSource)
+			mockSource.On("Endpoints").Return(newEndpoints(), nil)
+
+			cs, err := NewCutoverSource(mockSource, kubeClient, "default/cutover")
+			require.NoError(t, err)
+
+			endpoints, err := cs.Endpoints()
+			require.NoError(t, err)
+
+			validateEndpoints(t, endpoints, tc.expected)
+			for _, ep := range endpoints {
+				_, ok := ep.GetProviderSpecificProperty(cutoverGroupProperty)
+				assert.False(t, ok, "cutover properties must not reach the providers")
+				if ep.DNSName == "pay.example.org" {
+					assert.Equal(t, "service/default/pay", ep.Labels[endpoint.ResourceLabelKey])
+				}
+			}
+		})
+	}
+}
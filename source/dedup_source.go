@@ -17,6 +17,7 @@ limitations under the License.
 package source
 
 import (
+	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -24,9 +25,19 @@ import (
 	"sigs.k8s.io/external-dns/endpoint"
 )
 
+// DuplicateReporter is implemented by sources which drop duplicate endpoints.
+type DuplicateReporter interface {
+	// DroppedDuplicates returns the endpoints dropped by the last call of Endpoints because an endpoint
+	// with the same DNS name, set identifier and targets was returned before.
+	DroppedDuplicates() []*endpoint.Endpoint
+}
+
 // dedupSource is a Source that removes duplicate endpoints from its wrapped source.
 type dedupSource struct {
 	source Source
+
+	sync.Mutex
+	dropped []*endpoint.Endpoint
 }
 
 // NewDedupSource creates a new dedupSource wrapping the provided Source.
@@ -38,6 +49,7 @@ func NewDedupSource(source Source) Source {
 func (ms *dedupSource) Endpoints() ([]*endpoint.Endpoint, error) {
 	result := []*endpoint.Endpoint{}
 	collected := map[string]bool{}
+	var dropped []*endpoint.Endpoint
 
 	endpoints, err := ms.source.Endpoints()
 	if err != nil {
@@ -49,6 +61,7 @@ func (ms *dedupSource) Endpoints() ([]*endpoint.Endpoint, error) {
 
 		if _, ok := collected[identifier]; ok {
 			log.Debugf("Removing duplicate endpoint %s", ep)
+			dropped = append(dropped, ep)
 			continue
 		}
 
@@ -56,9 +69,20 @@ func (ms *dedupSource) Endpoints() ([]*endpoint.Endpoint, error) {
 		result = append(result, ep)
 	}
 
+	ms.Lock()
+	ms.dropped = dropped
+	ms.Unlock()
+
 	return result, nil
 }
 
+// DroppedDuplicates returns the duplicate endpoints removed by the last call of Endpoints.
+func (ms *dedupSource) DroppedDuplicates() []*endpoint.Endpoint {
+	ms.Lock()
+	defer ms.Unlock()
+	return ms.dropped
+}
+
 func (ms *dedupSource) AddEventHandler(handler func() error, stopChan <-chan struct{}, minInterval time.Duration) {
 	ms.source.AddEventHandler(handler, stopChan, minInterval)
 }
@@ -26,8 +26,12 @@ import (
 // Validates that dedupSource is a Source
 var _ Source = &dedupSource{}
 
+// Validates that dedupSource is a DuplicateReporter
+var _ DuplicateReporter = &dedupSource{}
+
 func TestDedup(t *testing.T) {
 	t.Run("Endpoints", testDedupEndpoints)
+	t.Run("DroppedDuplicates", testDedupDroppedDuplicates)
 }
 
 // testDedupEndpoints tests that duplicates from the wrapped source are removed.
@@ -110,3 +114,28 @@ func testDedupEndpoints(t *testing.T) {
 		})
 	}
 }
+
+// testDedupDroppedDuplicates tests that the duplicates removed by the last call of Endpoints are reported.
+func testDedupDroppedDuplicates(t *testing.T) {
+	first := &endpoint.Endpoint{DNSName: "foo.example.org", Targets: endpoint.Targets{"1.2.3.4"}, Labels: map[string]string{endpoint.ResourceLabelKey: "service/default/a"}}
+	duplicate := &endpoint.Endpoint{DNSName: "foo.example.org", Targets: endpoint.Targets{"1.2.3.4"}, Labels: map[string]string{endpoint.ResourceLabelKey: "service/default/b"}}
+
+	mockSource := new(testutils.MockSource)
+	mockSource.On("Endpoints").Return([]*endpoint.Endpoint{first, duplicate}, nil).Once()
+	mockSource.On("Endpoints").Return([]*endpoint.Endpoint{first}, nil).Once()
+
+	source := NewDedupSource(mockSource).(*dedupSource)
+	if _, err := source.Endpoints(); err != nil {
+		t.Fatal(err)
+	}
+	if dropped := source.DroppedDuplicates(); len(dropped) != 1 || dropped[0] != duplicate {
+		t.Errorf("expected the duplicate of service/default/b to be dropped, got %v", dropped)
+	}
+
+	if _, err := source.Endpoints(); err != nil {
+		t.Fatal(err)
+	}
+	if dropped := source.DroppedDuplicates(); len(dropped) != 0 {
+		t.Errorf("expected no dropped duplicates, got %v", dropped)
+	}
+}
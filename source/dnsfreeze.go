@@ -0,0 +1,89 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"errors"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	kubeinformers "k8s.io/client-go/informers"
+)
+
+var dnsFreezeGVR = schema.GroupVersionResource{Group: "externaldns.k8s.io", Version: "v1alpha1", Resource: "dnsfreezes"}
+
+// DNSFreezeLister lists the cluster-scoped DNSFreeze resources, which on-call creates to halt the DNS
+// automation during an incident without editing the deployment. While a DNSFreeze exists, the changes of
+// the DNS names in its spec.zones, including their subdomains, and of the names matching the patterns of
+// its spec.names, e.g. *.api.example.org, are planned but not applied. A DNSFreeze without zones and names
+// freezes all changes.
+type DNSFreezeLister struct {
+	informer kubeinformers.GenericInformer
+}
+
+// NewDNSFreezeLister creates a new DNSFreezeLister watching the DNSFreezes with a shared informer. The
+// informer isn't waited for, so a missing DNSFreeze CRD doesn't block the start: until it has synced,
+// Freezes returns an error and all changes are held back.
+func NewDNSFreezeLister(informers *InformerFactories, client dynamic.Interface) *DNSFreezeLister {
+	informerFactory := informers.Dynamic(client, "")
+	informer := informerFactory.ForResource(dnsFreezeGVR)
+	informer.Informer()
+
+	// TODO informer is not explicitly stopped since controller is not passing in its channel.
+	informerFactory.Start(wait.NeverStop)
+
+	return &DNSFreezeLister{informer: informer}
+}
+
+// Freezes returns the DNS name patterns of each DNSFreeze by its name. The freezes are read from the
+// cache of the informer, so creating or deleting one takes effect in the next synchronization.
+func (l *DNSFreezeLister) Freezes() (map[string][]string, error) {
+	if !l.informer.Informer().HasSynced() {
+		return nil, errors.New("the DNSFreezes haven't been synced yet")
+	}
+	objects, err := l.informer.Lister().List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	freezes := map[string][]string{}
+	for _, object := range objects {
+		freeze, ok := object.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		var patterns []string
+		zones, _, _ := unstructured.NestedStringSlice(freeze.Object, "spec", "zones")
+		for _, zone := range zones {
+			if zone = strings.Trim(zone, "."); zone != "" {
+				patterns = append(patterns, zone, "*."+zone)
+			}
+		}
+		names, _, _ := unstructured.NestedStringSlice(freeze.Object, "spec", "names")
+		for _, name := range names {
+			if name = strings.TrimSuffix(name, "."); name != "" {
+				patterns = append(patterns, name)
+			}
+		}
+		freezes[freeze.GetName()] = patterns
+	}
+	return freezes, nil
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+)
+
+func newTestDNSFreeze(name string, spec map[string]interface{}) unstructured.Unstructured {
+	freeze := unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	freeze.SetAPIVersion("externaldns.k8s.io/v1alpha1")
+	freeze.SetKind("DNSFreeze")
+	freeze.SetName(name)
+	return freeze
+}
+
+func TestDNSFreezeLister(t *testing.T) {
+	client := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme())
+	client.PrependReactor("list", "dnsfreezes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &unstructured.UnstructuredList{Object: map[string]interface{}{}, Items: []unstructured.Unstructured{
+			newTestDNSFreeze("incident-42", map[string]interface{}{}),
+			newTestDNSFreeze("prod", map[string]interface{}{
+				"zones": []interface{}{"prod.example.org.", ""},
+				"names": []interface{}{"*.api.example.org."},
+			}),
+		}}, nil
+	})
+
+	lister := NewDNSFreezeLister(NewInformerFactories(), client)
+	stop := make(chan struct{})
+	time.AfterFunc(10*time.Second, func() { close(stop) })
+	require.True(t, cache.WaitForCacheSync(stop, lister.informer.Informer().HasSynced))
+
+	freezes, err := lister.Freezes()
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"incident-42": nil,
+		"prod":        {"prod.example.org", "*.prod.example.org", "*.api.example.org"},
+	}, freezes)
+}
+
+func TestDNSFreezeListerError(t *testing.T) {
+	client := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme())
+	client.PrependReactor("list", "dnsfreezes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("forbidden")
+	})
+
+	_, err := NewDNSFreezeLister(NewInformerFactories(), client).Freezes()
+	assert.EqualError(t, err, "the DNSFreezes haven't been synced yet")
+}
@@ -0,0 +1,225 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+var domainVerificationGVR = schema.GroupVersionResource{Group: "externaldns.k8s.io", Version: "v1alpha1", Resource: "domainverifications"}
+
+// verificationTemplate is the name, relative to the verified domain, and the value of a verification record.
+type verificationTemplate struct {
+	name  string
+	value string
+}
+
+// verificationTemplates are the built-in templates of well-known verification records.
+var verificationTemplates = map[string]verificationTemplate{
+	"google-site-verification": {name: "", value: "google-site-verification={{.Token}}"},
+	"microsoft":                {name: "", value: "MS={{.Token}}"},
+	"acme-challenge":           {name: "_acme-challenge", value: "{{.Token}}"},
+}
+
+// verificationData is passed to the templates of the verification records.
+type verificationData struct {
+	Domain string
+	Token  string
+}
+
+// domainVerificationSource is an implementation of Source publishing the TXT records proving the ownership
+// of a domain, e.g. to Google or Microsoft, defined by DomainVerification objects:
+//
+//	spec:
+//	  domain: example.org
+//	  ttl: 3600
+//	  records:
+//	  - template: google-site-verification
+//	    token: abc123
+//	  - name: _custom-verification
+//	    value: "id={{.Token}}"
+//	    token: def456
+//
+// The records of a name are published as a single TXT record with one value each. The records are
+// owned through the registry like all other records and removed together with the object.
+type domainVerificationSource struct {
+	dynamicKubeClient    dynamic.Interface
+	namespace            string
+	verificationInformer kubeinformers.GenericInformer
+}
+
+// NewDomainVerificationSource creates a new domainVerificationSource with the given config.
+func NewDomainVerificationSource(informers *InformerFactories, dynamicKubeClient dynamic.Interface, namespace string) (Source, error) {
+	// Use shared informer to listen for add/update/delete of domain verifications in the specified namespace.
+	// Set resync period to 0, to prevent processing when nothing has changed.
+	informerFactory := informers.Dynamic(dynamicKubeClient, namespace)
+	verificationInformer := informerFactory.ForResource(domainVerificationGVR)
+
+	// Add default resource event handlers to properly initialize informer.
+	verificationInformer.Informer().AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+			},
+		},
+	)
+
+	// TODO informer is not explicitly stopped since controller is not passing in its channel.
+	informerFactory.Start(wait.NeverStop)
+
+	// wait for the local cache to be populated.
+	err := wait.Poll(time.Second, 60*time.Second, func() (bool, error) {
+		return verificationInformer.Informer().HasSynced(), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sync cache: %v", err)
+	}
+
+	return &domainVerificationSource{
+		dynamicKubeClient:    dynamicKubeClient,
+		namespace:            namespace,
+		verificationInformer: verificationInformer,
+	}, nil
+}
+
+// Endpoints returns the verification records of each DomainVerification.
+func (sc *domainVerificationSource) Endpoints() ([]*endpoint.Endpoint, error) {
+	objects, err := sc.verificationInformer.Lister().ByNamespace(sc.namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := []*endpoint.Endpoint{}
+
+	for _, obj := range objects {
+		verification, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+
+		verificationEndpoints, err := domainVerificationEndpoints(verification)
+		if err != nil {
+			log.Warnf("Skipping domainverification %s/%s: %v", verification.GetNamespace(), verification.GetName(), err)
+			continue
+		}
+		if len(verificationEndpoints) == 0 {
+			log.Debugf("No endpoints could be generated from domainverification %s/%s", verification.GetNamespace(), verification.GetName())
+			continue
+		}
+
+		log.Debugf("Endpoints generated from domainverification: %s/%s: %v", verification.GetNamespace(), verification.GetName(), verificationEndpoints)
+		setUnstructuredResourceLabel("domainverification", verification, verificationEndpoints)
+		endpoints = append(endpoints, verificationEndpoints...)
+	}
+
+	for _, ep := range endpoints {
+		sort.Sort(ep.Targets)
+	}
+
+	return endpoints, nil
+}
+
+func (sc *domainVerificationSource) AddEventHandler(handler func() error, stopChan <-chan struct{}, minInterval time.Duration) {
+}
+
+// domainVerificationEndpoints returns the TXT records of a DomainVerification, one per name.
+func domainVerificationEndpoints(verification *unstructured.Unstructured) ([]*endpoint.Endpoint, error) {
+	domain, _, _ := unstructured.NestedString(verification.Object, "spec", "domain")
+	domain = strings.Trim(domain, ".")
+	if domain == "" {
+		return nil, errors.New("spec.domain is required")
+	}
+	ttl, _, _ := unstructured.NestedInt64(verification.Object, "spec", "ttl")
+	records, _, err := unstructured.NestedSlice(verification.Object, "spec", "records")
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	values := map[string][]string{}
+	for i, record := range records {
+		fields, ok := record.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("spec.records[%d] is not an object", i)
+		}
+		name, value, err := verificationRecord(domain, fields)
+		if err != nil {
+			return nil, fmt.Errorf("spec.records[%d]: %v", i, err)
+		}
+		if _, ok := values[name]; !ok {
+			names = append(names, name)
+		}
+		values[name] = append(values[name], value)
+	}
+
+	endpoints := make([]*endpoint.Endpoint, 0, len(names))
+	for _, name := range names {
+		endpoints = append(endpoints, endpoint.NewEndpointWithTTL(name, endpoint.RecordTypeTXT, endpoint.TTL(ttl), values[name]...))
+	}
+	return endpoints, nil
+}
+
+// verificationRecord returns the DNS name and the value of a verification record, either of a built-in
+// template or of its own name and value template.
+func verificationRecord(domain string, fields map[string]interface{}) (string, string, error) {
+	templateName, _ := fields["template"].(string)
+	token, _ := fields["token"].(string)
+	name, _ := fields["name"].(string)
+	value, _ := fields["value"].(string)
+
+	if templateName != "" {
+		builtin, ok := verificationTemplates[templateName]
+		if !ok {
+			return "", "", fmt.Errorf("unknown template %q", templateName)
+		}
+		name, value = builtin.name, builtin.value
+	}
+	if value == "" {
+		return "", "", errors.New("a template or value is required")
+	}
+
+	tmpl, err := template.New("value").Parse(value)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid value template: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, verificationData{Domain: domain, Token: token}); err != nil {
+		return "", "", fmt.Errorf("failed to apply the value template: %v", err)
+	}
+
+	dnsName := domain
+	if name = strings.Trim(name, "."); name != "" && name != "@" {
+		dnsName = name + "." + domain
+	}
+	return dnsName, buf.String(), nil
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestDomainVerificationEndpoints(t *testing.T) {
+	for _, tc := range []struct {
+		title    string
+		spec     map[string]interface{}
+		expected []*endpoint.Endpoint
+		err      string
+	}{
+		{
+			title: "records of the same name are combined",
+			spec: map[string]interface{}{
+				"domain": "example.org.",
+				"ttl":    int64(3600),
+				"records": []interface{}{
+					map[string]interface{}{"template": "google-site-verification", "token": "abc123"},
+					map[string]interface{}{"template": "microsoft", "token": "ms12345"},
+					map[string]interface{}{"template": "acme-challenge", "token": "thumbprint"},
+					map[string]interface{}{"name": "_custom-verification", "value": "id={{.Token}} for {{.Domain}}", "token": "def456"},
+					map[string]interface{}{"name": "@", "value": "v=spf1 -all"},
+				},
+			},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "example.org", Targets: endpoint.Targets{"google-site-verification=abc123", "MS=ms12345", "v=spf1 -all"}, RecordType: endpoint.RecordTypeTXT, RecordTTL: 3600},
+				{DNSName: "_acme-challenge.example.org", Targets: endpoint.Targets{"thumbprint"}, RecordType: endpoint.RecordTypeTXT, RecordTTL: 3600},
+				{DNSName: "_custom-verification.example.org", Targets: endpoint.Targets{"id=def456 for example.org"}, RecordType: endpoint.RecordTypeTXT, RecordTTL: 3600},
+			},
+		},
+		{
+			title:    "without records nothing is published",
+			spec:     map[string]interface{}{"domain": "example.org"},
+			expected: []*endpoint.Endpoint{},
+		},
+		{
+			title: "the domain is required",
+			spec:  map[string]interface{}{},
+			err:   "spec.domain is required",
+		},
+		{
+			title: "unknown templates are rejected",
+			spec: map[string]interface{}{
+				"domain":  "example.org",
+				"records": []interface{}{map[string]interface{}{"template": "unknown", "token": "abc123"}},
+			},
+			err: `spec.records[0]: unknown template "unknown"`,
+		},
+		{
+			title: "a value is required",
+			spec: map[string]interface{}{
+				"domain":  "example.org",
+				"records": []interface{}{map[string]interface{}{"name": "_custom", "token": "abc123"}},
+			},
+			err: "spec.records[0]: a template or value is required",
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			verification := &unstructured.Unstructured{Object: map[string]interface{}{"spec": tc.spec}}
+			verification.SetNamespace("testing")
+			verification.SetName("foo")
+
+			endpoints, err := domainVerificationEndpoints(verification)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+			validateEndpoints(t, endpoints, tc.expected)
+		})
+	}
+}
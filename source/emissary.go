@@ -0,0 +1,226 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	kubeinformers "k8s.io/client-go/informers"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// The annotation used for defining the Emissary load balancer service (namespace/name) of a Host
+const emissaryServiceAnnotationKey = "external-dns.alpha.kubernetes.io/emissary-service"
+
+var emissaryHostGVR = schema.GroupVersionResource{Group: "getambassador.io", Version: "v2", Resource: "hosts"}
+
+// emissaryHostSource is an implementation of Source for the Host resources of Emissary-ingress,
+// formerly Ambassador. The spec.hostname of every Host is published pointing at the load balancer of the
+// Emissary service, which is either the one of the Host's emissary-service annotation or the default one.
+// The target, TTL and provider-specific annotations of the Host are respected.
+type emissaryHostSource struct {
+	namespace        string
+	annotationFilter string
+	emissaryService  string
+	hostInformer     kubeinformers.GenericInformer
+	serviceInformer  coreinformers.ServiceInformer
+}
+
+// NewEmissaryHostSource creates a new emissaryHostSource with the given config.
+func NewEmissaryHostSource(informers *InformerFactories, kubeClient kubernetes.Interface, dynamicKubeClient dynamic.Interface, emissaryService, namespace, annotationFilter string) (Source, error) {
+	if _, _, err := parseEmissaryService(emissaryService); err != nil {
+		return nil, err
+	}
+
+	// Use shared informer to listen for add/update/delete of hosts in the specified namespace.
+	// Set resync period to 0, to prevent processing when nothing has changed.
+	informerFactory := informers.Dynamic(dynamicKubeClient, namespace)
+	hostInformer := informerFactory.ForResource(emissaryHostGVR)
+
+	// Add default resource event handlers to properly initialize informer.
+	hostInformer.Informer().AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+			},
+		},
+	)
+
+	// The Emissary services can be in any namespace, not only the one of the hosts.
+	serviceInformerFactory := informers.Kube(kubeClient, "")
+	serviceInformer := serviceInformerFactory.Core().V1().Services()
+	serviceInformer.Informer().AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+			},
+		},
+	)
+
+	// TODO informer is not explicitly stopped since controller is not passing in its channel.
+	informerFactory.Start(wait.NeverStop)
+	serviceInformerFactory.Start(wait.NeverStop)
+
+	// wait for the local cache to be populated.
+	err := wait.Poll(time.Second, 60*time.Second, func() (bool, error) {
+		return hostInformer.Informer().HasSynced() && serviceInformer.Informer().HasSynced(), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sync cache: %v", err)
+	}
+
+	return &emissaryHostSource{
+		namespace:        namespace,
+		annotationFilter: annotationFilter,
+		emissaryService:  emissaryService,
+		hostInformer:     hostInformer,
+		serviceInformer:  serviceInformer,
+	}, nil
+}
+
+// Endpoints returns endpoint objects for the hostname of each Host.
+func (sc *emissaryHostSource) Endpoints() ([]*endpoint.Endpoint, error) {
+	objects, err := sc.hostInformer.Lister().ByNamespace(sc.namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	labelSelector, err := metav1.ParseToLabelSelector(sc.annotationFilter)
+	if err != nil {
+		return nil, err
+	}
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := []*endpoint.Endpoint{}
+	// the targets of each Emissary service, so a service shared by many hosts is only read once
+	serviceTargets := map[string]endpoint.Targets{}
+
+	for _, obj := range objects {
+		host, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		if !selector.Empty() && !selector.Matches(labels.Set(host.GetAnnotations())) {
+			continue
+		}
+
+		// Check controller annotation to see if we are responsible.
+		controller, ok := host.GetAnnotations()[controllerAnnotationKey]
+		if ok && controller != controllerAnnotationValue {
+			log.Debugf("Skipping host %s/%s because controller value does not match, found: %s, required: %s",
+				host.GetNamespace(), host.GetName(), controller, controllerAnnotationValue)
+			continue
+		}
+
+		hostEndpoints, err := sc.endpointsFromHost(host, serviceTargets)
+		if err != nil {
+			return nil, err
+		}
+		if len(hostEndpoints) == 0 {
+			log.Debugf("No endpoints could be generated from host %s/%s", host.GetNamespace(), host.GetName())
+			continue
+		}
+
+		log.Debugf("Endpoints generated from host: %s/%s: %v", host.GetNamespace(), host.GetName(), hostEndpoints)
+		setUnstructuredResourceLabel("host", host, hostEndpoints)
+		endpoints = append(endpoints, hostEndpoints...)
+	}
+
+	for _, ep := range endpoints {
+		sort.Sort(ep.Targets)
+	}
+
+	return endpoints, nil
+}
+
+func (sc *emissaryHostSource) endpointsFromHost(host *unstructured.Unstructured, serviceTargets map[string]endpoint.Targets) ([]*endpoint.Endpoint, error) {
+	annotations := host.GetAnnotations()
+	resource := fmt.Sprintf("host %s/%s", host.GetNamespace(), host.GetName())
+
+	hostname, _, _ := unstructured.NestedString(host.Object, "spec", "hostname")
+	hostname = strings.TrimSuffix(hostname, ".")
+	if hostname == "" || hostname == "*" {
+		log.Debugf("Skipping %s because it doesn't have a DNS hostname", resource)
+		return nil, nil
+	}
+
+	targets := getTargetsFromTargetAnnotation(annotations)
+	if len(targets) == 0 {
+		service := sc.emissaryService
+		if annotated, ok := annotations[emissaryServiceAnnotationKey]; ok {
+			service = annotated
+		}
+		namespace, name, err := parseEmissaryService(service)
+		if err != nil {
+			log.Warnf("Unable to find the targets of %s: %v", resource, err)
+			return nil, nil
+		}
+		if _, ok := serviceTargets[service]; !ok {
+			lbTargets, err := sc.targetsFromEmissaryService(namespace, name)
+			if err != nil {
+				return nil, err
+			}
+			serviceTargets[service] = lbTargets
+		}
+		targets = serviceTargets[service]
+	}
+
+	return endpointsForHostnames([]string{hostname}, targets, annotations, resource), nil
+}
+
+// targetsFromEmissaryService returns the load balancer addresses of an Emissary service, none if it's
+// missing.
+func (sc *emissaryHostSource) targetsFromEmissaryService(namespace, name string) (endpoint.Targets, error) {
+	svc, err := sc.serviceInformer.Lister().Services(namespace).Get(name)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return extractLoadBalancerTargets(svc), nil
+}
+
+func (sc *emissaryHostSource) AddEventHandler(handler func() error, stopChan <-chan struct{}, minInterval time.Duration) {
+}
+
+func parseEmissaryService(service string) (namespace, name string, err error) {
+	parts := strings.Split(service, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		err = fmt.Errorf("invalid emissary service (namespace/name) found '%v'", service)
+	} else {
+		namespace, name = parts[0], parts[1]
+	}
+
+	return
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func newTestEmissaryHost(hostname string, annotations map[string]string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{"hostname": hostname},
+	}}
+	u.SetAPIVersion("getambassador.io/v2")
+	u.SetKind("Host")
+	u.SetNamespace("default")
+	u.SetName("foo")
+	u.SetAnnotations(annotations)
+	return u
+}
+
+func TestNewEmissaryHostSource(t *testing.T) {
+	for _, service := range []string{"", "emissary", "emissary/", "a/b/c"} {
+		_, err := NewEmissaryHostSource(NewInformerFactories(), fake.NewSimpleClientset(), nil, service, "", "")
+		assert.Error(t, err, service)
+	}
+}
+
+func TestEmissaryHostEndpoints(t *testing.T) {
+	for _, tc := range []struct {
+		title    string
+		host     *unstructured.Unstructured
+		expected []*endpoint.Endpoint
+	}{
+		{
+			title: "hostname points at the default emissary service",
+			host:  newTestEmissaryHost("foo.example.org", nil),
+			expected: []*endpoint.Endpoint{
+				{DNSName: "foo.example.org", Targets: endpoint.Targets{"1.1.1.1"}, RecordType: endpoint.RecordTypeA},
+			},
+		},
+		{
+			title: "hostname points at the annotated emissary service",
+			host:  newTestEmissaryHost("foo.example.org.", map[string]string{emissaryServiceAnnotationKey: "internal/emissary-internal"}),
+			expected: []*endpoint.Endpoint{
+				{DNSName: "foo.example.org", Targets: endpoint.Targets{"internal.elb.amazonaws.com"}, RecordType: endpoint.RecordTypeCNAME},
+			},
+		},
+		{
+			title: "target and TTL annotations",
+			host:  newTestEmissaryHost("foo.example.org", map[string]string{targetAnnotationKey: "2.2.2.2", ttlAnnotationKey: "60"}),
+			expected: []*endpoint.Endpoint{
+				{DNSName: "foo.example.org", Targets: endpoint.Targets{"2.2.2.2"}, RecordType: endpoint.RecordTypeA, RecordTTL: 60},
+			},
+		},
+		{
+			title:    "missing emissary service",
+			host:     newTestEmissaryHost("foo.example.org", map[string]string{emissaryServiceAnnotationKey: "internal/missing"}),
+			expected: []*endpoint.Endpoint{},
+		},
+		{
+			title:    "invalid emissary service annotation",
+			host:     newTestEmissaryHost("foo.example.org", map[string]string{emissaryServiceAnnotationKey: "missing"}),
+			expected: []*endpoint.Endpoint{},
+		},
+		{
+			title:    "wildcard host without DNS hostname",
+			host:     newTestEmissaryHost("*", nil),
+			expected: []*endpoint.Endpoint{},
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			serviceInformer := kubeinformers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0).Core().V1().Services()
+			for _, svc := range []*v1.Service{
+				{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "emissary", Name: "emissary-ingress"},
+					Status: v1.ServiceStatus{
+						LoadBalancer: v1.LoadBalancerStatus{Ingress: []v1.LoadBalancerIngress{{IP: "1.1.1.1"}}},
+					},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "internal", Name: "emissary-internal"},
+					Status: v1.ServiceStatus{
+						LoadBalancer: v1.LoadBalancerStatus{Ingress: []v1.LoadBalancerIngress{{Hostname: "internal.elb.amazonaws.com"}}},
+					},
+				},
+			} {
+				require.NoError(t, serviceInformer.Informer().GetIndexer().Add(svc))
+			}
+
+			sc := &emissaryHostSource{emissaryService: "emissary/emissary-ingress", serviceInformer: serviceInformer}
+
+			endpoints, err := sc.endpointsFromHost(tc.host, map[string]endpoint.Targets{})
+			require.NoError(t, err)
+
+			validateEndpoints(t, endpoints, tc.expected)
+		})
+	}
+}
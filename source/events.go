@@ -0,0 +1,106 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// The component the events of ExternalDNS are reported by
+const eventSourceComponent = "external-dns"
+
+// ResourceEventRecorder reports warnings about the endpoints of Services, Ingresses and DNSEndpoints
+// as Kubernetes events of the resources, so they show up with kubectl describe.
+type ResourceEventRecorder struct {
+	kubeClient    kubernetes.Interface
+	dynamicClient dynamic.Interface
+	resources     map[string]schema.GroupVersionResource
+	now           func() time.Time
+}
+
+// NewResourceEventRecorder creates a new ResourceEventRecorder. The DNSEndpoints are the resources of the
+// given API version and kind of the crd source.
+func NewResourceEventRecorder(kubeClient kubernetes.Interface, dynamicClient dynamic.Interface, crdAPIVersion, crdKind string) (*ResourceEventRecorder, error) {
+	resources, err := writableResources(crdAPIVersion, crdKind)
+	if err != nil {
+		return nil, err
+	}
+	return &ResourceEventRecorder{
+		kubeClient:    kubeClient,
+		dynamicClient: dynamicClient,
+		resources:     resources,
+		now:           time.Now,
+	}, nil
+}
+
+// RecordWarning creates a warning event of the resource. Resources which are gone or of other kinds are
+// skipped, failures are logged.
+func (r *ResourceEventRecorder) RecordWarning(resource, reason, message string) {
+	parts := strings.Split(resource, "/")
+	if len(parts) != 3 {
+		return
+	}
+	gvr, ok := r.resources[parts[0]]
+	if !ok {
+		return
+	}
+
+	obj, err := r.dynamicClient.Resource(gvr).Namespace(parts[1]).Get(parts[2], metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return
+	}
+	if err != nil {
+		log.Warnf("Unable to report %s to %s: %v", reason, resource, err)
+		return
+	}
+
+	now := metav1.NewTime(r.now())
+	event := &v1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s.%x", obj.GetName(), now.UnixNano()),
+			Namespace: obj.GetNamespace(),
+		},
+		InvolvedObject: v1.ObjectReference{
+			APIVersion:      obj.GetAPIVersion(),
+			Kind:            obj.GetKind(),
+			Namespace:       obj.GetNamespace(),
+			Name:            obj.GetName(),
+			UID:             obj.GetUID(),
+			ResourceVersion: obj.GetResourceVersion(),
+		},
+		Reason:         reason,
+		Message:        message,
+		Type:           v1.EventTypeWarning,
+		Source:         v1.EventSource{Component: eventSourceComponent},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	}
+	if _, err := r.kubeClient.CoreV1().Events(obj.GetNamespace()).Create(event); err != nil {
+		log.Warnf("Unable to report %s to %s: %v", reason, resource, err)
+	}
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestResourceEventRecorder(t *testing.T) {
+	dynamicClient := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme())
+	dynamicClient.PrependReactor("get", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		get := action.(k8stesting.GetAction)
+		switch get.GetName() {
+		case "gone":
+			return true, nil, apierrors.NewNotFound(schema.GroupResource{Resource: get.GetResource().Resource}, get.GetName())
+		case "broken":
+			return true, nil, errors.New("unavailable")
+		}
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion("extensions/v1beta1")
+		obj.SetKind("Ingress")
+		obj.SetNamespace(get.GetNamespace())
+		obj.SetName(get.GetName())
+		obj.SetUID(types.UID("uid-1"))
+		return true, obj, nil
+	})
+	kubeClient := fake.NewSimpleClientset()
+
+	recorder, err := NewResourceEventRecorder(kubeClient, dynamicClient, "externaldns.k8s.io/v1alpha1", "DNSEndpoint")
+	require.NoError(t, err)
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	recorder.now = func() time.Time { return now }
+
+	for _, resource := range []string{"ingress/default/web", "ingress/default/gone", "ingress/default/broken", "node//worker", "invalid"} {
+		recorder.RecordWarning(resource, "TTLClamped", "The TTL was changed")
+	}
+
+	events, err := kubeClient.CoreV1().Events("default").List(metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, events.Items, 1)
+	event := events.Items[0]
+	assert.Equal(t, v1.ObjectReference{
+		APIVersion: "extensions/v1beta1",
+		Kind:       "Ingress",
+		Namespace:  "default",
+		Name:       "web",
+		UID:        "uid-1",
+	}, event.InvolvedObject)
+	assert.Equal(t, "TTLClamped", event.Reason)
+	assert.Equal(t, "The TTL was changed", event.Message)
+	assert.Equal(t, v1.EventTypeWarning, event.Type)
+	assert.Equal(t, "external-dns", event.Source.Component)
+	assert.Equal(t, now, event.LastTimestamp.Time)
+
+	_, err = NewResourceEventRecorder(kubeClient, dynamicClient, "invalid/api/version", "DNSEndpoint")
+	assert.Error(t, err)
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"net"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// The annotation used for defining the DNS name whose canonical name an ExternalName service follows
+const externalNameLookupAnnotationKey = "external-dns.alpha.kubernetes.io/external-name-lookup"
+
+// externalNameSync keeps the externalName of annotated ExternalName services up to date with the canonical
+// name of a DNS name, e.g. the CNAME a managed database publishes for its current primary. The CNAME records
+// of the service are generated from the updated externalName, so the alias inside the cluster and the
+// records in the external zone point at the same target.
+type externalNameSync struct {
+	client      kubernetes.Interface
+	lookupCNAME func(host string) (string, error)
+}
+
+func newExternalNameSync(client kubernetes.Interface) *externalNameSync {
+	return &externalNameSync{
+		client:      client,
+		lookupCNAME: net.LookupCNAME,
+	}
+}
+
+// sync returns the service with its externalName updated to the canonical name of the DNS name it follows.
+// The service is returned unchanged if it doesn't follow a DNS name, the lookup fails or it can't be updated.
+func (s *externalNameSync) sync(svc *v1.Service) *v1.Service {
+	if s == nil || svc.Spec.Type != v1.ServiceTypeExternalName {
+		return svc
+	}
+	host := strings.TrimSpace(svc.Annotations[externalNameLookupAnnotationKey])
+	if host == "" {
+		return svc
+	}
+
+	cname, err := s.lookupCNAME(host)
+	if err != nil {
+		log.Warnf("Unable to look up the canonical name of %s for service %s/%s: %v", host, svc.Namespace, svc.Name, err)
+		return svc
+	}
+	externalName := strings.TrimSuffix(cname, ".")
+	if externalName == "" || strings.EqualFold(externalName, strings.TrimSuffix(svc.Spec.ExternalName, ".")) {
+		return svc
+	}
+
+	updated := svc.DeepCopy()
+	updated.Spec.ExternalName = externalName
+	updated, err = s.client.CoreV1().Services(svc.Namespace).Update(updated)
+	if err != nil {
+		log.Errorf("Unable to update the externalName of service %s/%s to %s: %v", svc.Namespace, svc.Name, externalName, err)
+		return svc
+	}
+	log.Infof("Updated the externalName of service %s/%s from %s to %s", svc.Namespace, svc.Name, svc.Spec.ExternalName, externalName)
+	return updated
+}
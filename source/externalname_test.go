@@ -0,0 +1,156 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestExternalNameSync(t *testing.T) {
+	for _, tc := range []struct {
+		title        string
+		svcType      v1.ServiceType
+		annotations  map[string]string
+		cname        string
+		lookupErr    error
+		expected     string
+		expectUpdate bool
+	}{
+		{
+			title:        "the externalName follows the canonical name",
+			svcType:      v1.ServiceTypeExternalName,
+			annotations:  map[string]string{externalNameLookupAnnotationKey: "db.example.com"},
+			cname:        "db-2.eu-west-1.example.net.",
+			expected:     "db-2.eu-west-1.example.net",
+			expectUpdate: true,
+		},
+		{
+			title:       "an up to date externalName isn't updated",
+			svcType:     v1.ServiceTypeExternalName,
+			annotations: map[string]string{externalNameLookupAnnotationKey: "db.example.com"},
+			cname:       "DB-1.eu-west-1.example.net.",
+			expected:    "db-1.eu-west-1.example.net",
+		},
+		{
+			title:       "the externalName is kept if the lookup fails",
+			svcType:     v1.ServiceTypeExternalName,
+			annotations: map[string]string{externalNameLookupAnnotationKey: "db.example.com"},
+			lookupErr:   errors.New("no such host"),
+			expected:    "db-1.eu-west-1.example.net",
+		},
+		{
+			title:    "services without the annotation are left alone",
+			svcType:  v1.ServiceTypeExternalName,
+			cname:    "db-2.eu-west-1.example.net.",
+			expected: "db-1.eu-west-1.example.net",
+		},
+		{
+			title:       "other service types are left alone",
+			svcType:     v1.ServiceTypeClusterIP,
+			annotations: map[string]string{externalNameLookupAnnotationKey: "db.example.com"},
+			cname:       "db-2.eu-west-1.example.net.",
+			expected:    "db-1.eu-west-1.example.net",
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			svc := &v1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:   "testing",
+					Name:        "db",
+					Annotations: tc.annotations,
+				},
+				Spec: v1.ServiceSpec{
+					Type:         tc.svcType,
+					ExternalName: "db-1.eu-west-1.example.net",
+				},
+			}
+			client := fake.NewSimpleClientset(svc)
+			s := newExternalNameSync(client)
+			s.lookupCNAME = func(host string) (string, error) {
+				assert.Equal(t, "db.example.com", host)
+				return tc.cname, tc.lookupErr
+			}
+
+			assert.Equal(t, tc.expected, s.sync(svc).Spec.ExternalName)
+
+			stored, err := client.CoreV1().Services("testing").Get("db", metav1.GetOptions{})
+			require.NoError(t, err)
+			if tc.expectUpdate {
+				assert.Equal(t, tc.expected, stored.Spec.ExternalName)
+			} else {
+				assert.Equal(t, "db-1.eu-west-1.example.net", stored.Spec.ExternalName)
+			}
+		})
+	}
+}
+
+func TestExternalNameSyncDisabled(t *testing.T) {
+	svc := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{externalNameLookupAnnotationKey: "db.example.com"},
+		},
+		Spec: v1.ServiceSpec{Type: v1.ServiceTypeExternalName, ExternalName: "db-1.example.net"},
+	}
+
+	var s *externalNameSync
+	assert.Equal(t, svc, s.sync(svc))
+}
+
+func TestServiceSourceSyncExternalNames(t *testing.T) {
+	kubernetes := fake.NewSimpleClientset()
+
+	_, err := kubernetes.CoreV1().Services("testing").Create(&v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "testing",
+			Name:      "db",
+			Annotations: map[string]string{
+				hostnameAnnotationKey:           "db.example.org",
+				externalNameLookupAnnotationKey: "db.example.com",
+			},
+		},
+		Spec: v1.ServiceSpec{
+			Type:         v1.ServiceTypeExternalName,
+			ExternalName: "db-1.example.net",
+		},
+	})
+	require.NoError(t, err)
+
+	client, err := NewServiceSource(NewInformerFactories(), kubernetes, "", "", "", false, "", false, false, []string{}, false, false, false, "", true)
+	require.NoError(t, err)
+	client.(*serviceSource).externalNames.lookupCNAME = func(host string) (string, error) {
+		return "db-2.example.net.", nil
+	}
+
+	endpoints, err := client.Endpoints()
+	require.NoError(t, err)
+	validateEndpoints(t, endpoints, []*endpoint.Endpoint{
+		{DNSName: "db.example.org", Targets: endpoint.Targets{"db-2.example.net"}, RecordType: endpoint.RecordTypeCNAME},
+	})
+
+	stored, err := kubernetes.CoreV1().Services("testing").Get("db", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "db-2.example.net", stored.Spec.ExternalName)
+}
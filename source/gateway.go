@@ -30,7 +30,6 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/wait"
-	kubeinformers "k8s.io/client-go/informers"
 	coreinformers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
@@ -54,6 +53,7 @@ type gatewaySource struct {
 
 // NewIstioGatewaySource creates a new gatewaySource with the given config.
 func NewIstioGatewaySource(
+	informers *InformerFactories,
 	kubeClient kubernetes.Interface,
 	istioClient istiomodel.ConfigStore,
 	namespace string,
@@ -78,7 +78,7 @@ func NewIstioGatewaySource(
 
 	// Use shared informers to listen for add/update/delete of services/pods/nodes in the specified namespace.
 	// Set resync period to 0, to prevent processing when nothing has changed
-	informerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(kubeClient, 0, kubeinformers.WithNamespace(namespace))
+	informerFactory := informers.Kube(kubeClient, namespace)
 	serviceInformer := informerFactory.Core().V1().Services()
 
 	// Add default resource event handlers to properly initialize informer.
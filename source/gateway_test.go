@@ -72,6 +72,7 @@ func (suite *GatewaySuite) SetupTest() {
 	}
 
 	suite.source, err = NewIstioGatewaySource(
+		NewInformerFactories(),
 		fakeKubernetesClient,
 		fakeIstioClient,
 		"",
@@ -145,6 +146,7 @@ func TestNewIstioGatewaySource(t *testing.T) {
 	} {
 		t.Run(ti.title, func(t *testing.T) {
 			_, err := NewIstioGatewaySource(
+				NewInformerFactories(),
 				fake.NewSimpleClientset(),
 				NewFakeConfigStore(),
 				"",
@@ -1106,6 +1108,7 @@ func testGatewayEndpoints(t *testing.T) {
 			}
 
 			gatewaySource, err := NewIstioGatewaySource(
+				NewInformerFactories(),
 				fakeKubernetesClient,
 				fakeIstioClient,
 				ti.targetNamespace,
@@ -1142,6 +1145,7 @@ func newTestGatewaySource(loadBalancerList []fakeIngressGatewayService) (*gatewa
 	}
 
 	src, err := NewIstioGatewaySource(
+		NewInformerFactories(),
 		fakeKubernetesClient,
 		fakeIstioClient,
 		"",
@@ -1167,6 +1171,7 @@ type fakeIngressGatewayService struct {
 	hostnames []string
 	namespace string
 	name      string
+	labels    map[string]string
 }
 
 func (ig fakeIngressGatewayService) Service() *v1.Service {
@@ -1174,6 +1179,7 @@ func (ig fakeIngressGatewayService) Service() *v1.Service {
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: ig.namespace,
 			Name:      ig.name,
+			Labels:    ig.labels,
 		},
 		Status: v1.ServiceStatus{
 			LoadBalancer: v1.LoadBalancerStatus{
@@ -1240,6 +1246,7 @@ func NewFakeConfigStore() istiomodel.ConfigStore {
 	return &fakeConfigStore{
 		descriptor: istiomodel.ConfigDescriptor{
 			istiomodel.Gateway,
+			istiomodel.VirtualService,
 		},
 		configs: make([]*istiomodel.Config, 0),
 	}
@@ -1274,16 +1281,10 @@ func (f *fakeConfigStore) List(typ, namespace string) (configs []istiomodel.Conf
 	f.RLock()
 	defer f.RUnlock()
 
-	if namespace == "" {
-		for _, cfg := range f.configs {
+	for _, cfg := range f.configs {
+		if cfg.Type == typ && (namespace == "" || cfg.Namespace == namespace) {
 			configs = append(configs, *cfg)
 		}
-	} else {
-		for _, cfg := range f.configs {
-			if cfg.Type == typ && cfg.Namespace == namespace {
-				configs = append(configs, *cfg)
-			}
-		}
 	}
 
 	return
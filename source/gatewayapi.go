@@ -0,0 +1,357 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/cache"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+const gatewayAPIGroup = "gateway.networking.k8s.io"
+
+var (
+	gatewayGVR = schema.GroupVersionResource{Group: gatewayAPIGroup, Version: "v1alpha2", Resource: "gateways"}
+	// the routes of the Gateway API, by kind
+	gatewayRouteGVRs = map[string]schema.GroupVersionResource{
+		"HTTPRoute": {Group: gatewayAPIGroup, Version: "v1alpha2", Resource: "httproutes"},
+		"TLSRoute":  {Group: gatewayAPIGroup, Version: "v1alpha2", Resource: "tlsroutes"},
+		"GRPCRoute": {Group: gatewayAPIGroup, Version: "v1alpha2", Resource: "grpcroutes"},
+	}
+)
+
+// gatewayRouteSource is an implementation of Source for the routes of the Gateway API. Every route is
+// published with its hostnames and those of the hostname annotation, pointing at the addresses of each
+// Gateway which accepted it. The hostnames of the route are limited to those matching the hostnames of
+// the listeners it's attached to, e.g. a listener for *.example.org only accepts the hostnames of the
+// route in example.org, and a route without hostnames gets the hostnames of its listeners. The target,
+// TTL and provider-specific annotations of the route are respected.
+type gatewayRouteSource struct {
+	namespace        string
+	annotationFilter string
+	kind             string
+	routeLister      cache.GenericLister
+	gatewayLister    cache.GenericLister
+}
+
+// NewGatewayRouteSource creates a new gatewayRouteSource for the routes of the given kind, e.g. HTTPRoute.
+func NewGatewayRouteSource(informers *InformerFactories, dynamicKubeClient dynamic.Interface, namespace, annotationFilter, kind string) (Source, error) {
+	routeGVR, ok := gatewayRouteGVRs[kind]
+	if !ok {
+		return nil, fmt.Errorf("unsupported Gateway API route kind %q", kind)
+	}
+
+	// Use shared informers to listen for add/update/delete of the routes in the specified namespace.
+	// Set resync period to 0, to prevent processing when nothing has changed.
+	informerFactory := informers.Dynamic(dynamicKubeClient, namespace)
+	routeInformer := informerFactory.ForResource(routeGVR)
+	// The Gateways can be in any namespace, not only the one of the routes.
+	gatewayInformerFactory := informers.Dynamic(dynamicKubeClient, "")
+	gatewayInformer := gatewayInformerFactory.ForResource(gatewayGVR)
+
+	// Add default resource event handlers to properly initialize informer.
+	for _, informer := range []cache.SharedIndexInformer{routeInformer.Informer(), gatewayInformer.Informer()} {
+		informer.AddEventHandler(
+			cache.ResourceEventHandlerFuncs{
+				AddFunc: func(obj interface{}) {
+				},
+			},
+		)
+	}
+
+	// TODO informer is not explicitly stopped since controller is not passing in its channel.
+	informerFactory.Start(wait.NeverStop)
+	gatewayInformerFactory.Start(wait.NeverStop)
+
+	// wait for the local cache to be populated.
+	err := wait.Poll(time.Second, 60*time.Second, func() (bool, error) {
+		return routeInformer.Informer().HasSynced() && gatewayInformer.Informer().HasSynced(), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sync cache: %v", err)
+	}
+
+	return &gatewayRouteSource{
+		namespace:        namespace,
+		annotationFilter: annotationFilter,
+		kind:             strings.ToLower(kind),
+		routeLister:      routeInformer.Lister(),
+		gatewayLister:    gatewayInformer.Lister(),
+	}, nil
+}
+
+// Endpoints returns endpoint objects for the hostnames of each route accepted by a Gateway.
+func (sc *gatewayRouteSource) Endpoints() ([]*endpoint.Endpoint, error) {
+	objects, err := sc.routeLister.ByNamespace(sc.namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	labelSelector, err := metav1.ParseToLabelSelector(sc.annotationFilter)
+	if err != nil {
+		return nil, err
+	}
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := []*endpoint.Endpoint{}
+
+	for _, obj := range objects {
+		route, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		if !selector.Empty() && !selector.Matches(labels.Set(route.GetAnnotations())) {
+			continue
+		}
+
+		// Check controller annotation to see if we are responsible.
+		controller, ok := route.GetAnnotations()[controllerAnnotationKey]
+		if ok && controller != controllerAnnotationValue {
+			log.Debugf("Skipping %s %s/%s because controller value does not match, found: %s, required: %s",
+				sc.kind, route.GetNamespace(), route.GetName(), controller, controllerAnnotationValue)
+			continue
+		}
+
+		routeEndpoints, err := sc.endpointsFromRoute(route)
+		if err != nil {
+			return nil, err
+		}
+		if len(routeEndpoints) == 0 {
+			log.Debugf("No endpoints could be generated from %s %s/%s", sc.kind, route.GetNamespace(), route.GetName())
+			continue
+		}
+
+		log.Debugf("Endpoints generated from %s: %s/%s: %v", sc.kind, route.GetNamespace(), route.GetName(), routeEndpoints)
+		setUnstructuredResourceLabel(sc.kind, route, routeEndpoints)
+		endpoints = append(endpoints, routeEndpoints...)
+	}
+
+	for _, ep := range endpoints {
+		sort.Sort(ep.Targets)
+	}
+
+	return endpoints, nil
+}
+
+// endpointsFromRoute returns the endpoints of a route for the Gateways which accepted it.
+func (sc *gatewayRouteSource) endpointsFromRoute(route *unstructured.Unstructured) ([]*endpoint.Endpoint, error) {
+	annotations := route.GetAnnotations()
+	routeHostnames, _, _ := unstructured.NestedStringSlice(route.Object, "spec", "hostnames")
+	annotationHostnames := getHostnamesFromAnnotations(annotations)
+	overrideTargets := getTargetsFromTargetAnnotation(annotations)
+
+	parentRefs, _, _ := unstructured.NestedSlice(route.Object, "spec", "parentRefs")
+
+	targetsByHostname := map[string]endpoint.Targets{}
+	seen := map[string]bool{}
+	for _, item := range parentRefs {
+		ref, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		namespace, name, sectionName, ok := gatewayParentRef(ref, route.GetNamespace())
+		if !ok {
+			continue
+		}
+		if !gatewayRouteAccepted(route, namespace, name, sectionName) {
+			log.Debugf("Skipping Gateway %s/%s of %s %s/%s because it didn't accept the route", namespace, name, sc.kind, route.GetNamespace(), route.GetName())
+			continue
+		}
+
+		obj, err := sc.gatewayLister.ByNamespace(namespace).Get(name)
+		if errors.IsNotFound(err) {
+			log.Debugf("Unable to find Gateway %s/%s of %s %s/%s", namespace, name, sc.kind, route.GetNamespace(), route.GetName())
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		gateway, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+
+		targets := overrideTargets
+		if len(targets) == 0 {
+			targets = gatewayAddresses(gateway)
+		}
+		hostnames := gatewayRouteHostnames(routeHostnames, gatewayListenerHostnames(gateway, sectionName))
+		hostnames = append(hostnames, annotationHostnames...)
+		for _, hostname := range hostnames {
+			hostname = strings.TrimSuffix(hostname, ".")
+			for _, target := range targets {
+				if !seen[hostname+"/"+target] {
+					seen[hostname+"/"+target] = true
+					targetsByHostname[hostname] = append(targetsByHostname[hostname], target)
+				}
+			}
+		}
+	}
+
+	hostnames := make([]string, 0, len(targetsByHostname))
+	for hostname := range targetsByHostname {
+		hostnames = append(hostnames, hostname)
+	}
+	sort.Strings(hostnames)
+
+	resource := fmt.Sprintf("%s %s/%s", sc.kind, route.GetNamespace(), route.GetName())
+	var endpoints []*endpoint.Endpoint
+	for _, hostname := range hostnames {
+		endpoints = append(endpoints, endpointsForHostnames([]string{hostname}, targetsByHostname[hostname], annotations, resource)...)
+	}
+	return endpoints, nil
+}
+
+func (sc *gatewayRouteSource) AddEventHandler(handler func() error, stopChan <-chan struct{}, minInterval time.Duration) {
+}
+
+// gatewayParentRef returns the Gateway a parent reference of a route points at. Other kinds of parents
+// aren't supported.
+func gatewayParentRef(ref map[string]interface{}, routeNamespace string) (namespace, name, sectionName string, ok bool) {
+	group, found, _ := unstructured.NestedString(ref, "group")
+	if found && group != gatewayAPIGroup {
+		return "", "", "", false
+	}
+	kind, found, _ := unstructured.NestedString(ref, "kind")
+	if found && kind != "Gateway" {
+		return "", "", "", false
+	}
+	name, _, _ = unstructured.NestedString(ref, "name")
+	if name == "" {
+		return "", "", "", false
+	}
+	namespace, _, _ = unstructured.NestedString(ref, "namespace")
+	if namespace == "" {
+		namespace = routeNamespace
+	}
+	sectionName, _, _ = unstructured.NestedString(ref, "sectionName")
+	return namespace, name, sectionName, true
+}
+
+// gatewayRouteAccepted returns true if the status of a route reports it as accepted by the given parent.
+func gatewayRouteAccepted(route *unstructured.Unstructured, namespace, name, sectionName string) bool {
+	parents, _, _ := unstructured.NestedSlice(route.Object, "status", "parents")
+	for _, item := range parents {
+		parent, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		ref, _, _ := unstructured.NestedMap(parent, "parentRef")
+		refNamespace, refName, refSectionName, ok := gatewayParentRef(ref, route.GetNamespace())
+		if !ok || refNamespace != namespace || refName != name || refSectionName != sectionName {
+			continue
+		}
+
+		conditions, _, _ := unstructured.NestedSlice(parent, "conditions")
+		for _, item := range conditions {
+			condition, ok := item.(map[string]interface{})
+			if ok && condition["type"] == "Accepted" && condition["status"] == "True" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// gatewayAddresses returns the addresses of a Gateway from its status.
+func gatewayAddresses(gateway *unstructured.Unstructured) endpoint.Targets {
+	addresses, _, _ := unstructured.NestedSlice(gateway.Object, "status", "addresses")
+
+	var targets endpoint.Targets
+	for _, item := range addresses {
+		address, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if value, ok := address["value"].(string); ok && value != "" {
+			targets = append(targets, strings.TrimSuffix(value, "."))
+		}
+	}
+	return targets
+}
+
+// gatewayRouteHostnames returns the hostnames of a route accepted by the listeners with the given
+// hostnames, empty for a listener accepting all hostnames. A route without hostnames gets the hostnames
+// of the listeners.
+func gatewayRouteHostnames(routeHostnames, listenerHostnames []string) []string {
+	var hostnames []string
+	for _, listenerHostname := range listenerHostnames {
+		if len(routeHostnames) == 0 {
+			if listenerHostname != "" {
+				hostnames = append(hostnames, listenerHostname)
+			}
+			continue
+		}
+		for _, routeHostname := range routeHostnames {
+			if hostname, ok := intersectGatewayHostnames(strings.TrimSuffix(routeHostname, "."), listenerHostname); ok {
+				hostnames = append(hostnames, hostname)
+			}
+		}
+	}
+	return hostnames
+}
+
+// intersectGatewayHostnames returns the more specific of a route and a listener hostname, if they match.
+// A wildcard hostname like *.example.org matches the hostnames below example.org, but not example.org.
+func intersectGatewayHostnames(routeHostname, listenerHostname string) (string, bool) {
+	listenerHostname = strings.TrimSuffix(listenerHostname, ".")
+	switch {
+	case listenerHostname == "" || routeHostname == listenerHostname:
+		return routeHostname, true
+	case strings.HasPrefix(listenerHostname, "*.") && strings.HasSuffix(routeHostname, listenerHostname[1:]):
+		return routeHostname, true
+	case strings.HasPrefix(routeHostname, "*.") && strings.HasSuffix(listenerHostname, routeHostname[1:]):
+		return listenerHostname, true
+	}
+	return "", false
+}
+
+// gatewayListenerHostnames returns the hostnames of the listeners of a Gateway, or only of the named one.
+// The hostname of a listener without one is empty.
+func gatewayListenerHostnames(gateway *unstructured.Unstructured, sectionName string) []string {
+	listeners, _, _ := unstructured.NestedSlice(gateway.Object, "spec", "listeners")
+
+	var hostnames []string
+	for _, item := range listeners {
+		listener, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if name, _ := listener["name"].(string); sectionName != "" && name != sectionName {
+			continue
+		}
+		hostname, _ := listener["hostname"].(string)
+		hostnames = append(hostnames, hostname)
+	}
+	return hostnames
+}
@@ -0,0 +1,216 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func newTestGateway(namespace, name string, listeners []interface{}, addresses ...string) *unstructured.Unstructured {
+	var status []interface{}
+	for _, address := range addresses {
+		status = append(status, map[string]interface{}{"type": "IPAddress", "value": address})
+	}
+	u := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec":   map[string]interface{}{"listeners": listeners},
+		"status": map[string]interface{}{"addresses": status},
+	}}
+	u.SetAPIVersion("gateway.networking.k8s.io/v1alpha2")
+	u.SetKind("Gateway")
+	u.SetNamespace(namespace)
+	u.SetName(name)
+	return u
+}
+
+func newTestHTTPRoute(name string, annotations map[string]string, hostnames []interface{}, parentRefs []interface{}, acceptedBy ...map[string]interface{}) *unstructured.Unstructured {
+	var parents []interface{}
+	for _, ref := range acceptedBy {
+		parents = append(parents, map[string]interface{}{
+			"parentRef":  ref,
+			"conditions": []interface{}{map[string]interface{}{"type": "Accepted", "status": "True"}},
+		})
+	}
+	u := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec":   map[string]interface{}{"hostnames": hostnames, "parentRefs": parentRefs},
+		"status": map[string]interface{}{"parents": parents},
+	}}
+	u.SetAPIVersion("gateway.networking.k8s.io/v1alpha2")
+	u.SetKind("HTTPRoute")
+	u.SetNamespace("testing")
+	u.SetName(name)
+	u.SetAnnotations(annotations)
+	return u
+}
+
+func newTestGenericLister(resource string, objects ...*unstructured.Unstructured) cache.GenericLister {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, obj := range objects {
+		indexer.Add(obj)
+	}
+	return cache.NewGenericLister(indexer, schema.GroupResource{Group: gatewayAPIGroup, Resource: resource})
+}
+
+func TestGatewayRouteEndpoints(t *testing.T) {
+	listeners := []interface{}{
+		map[string]interface{}{"name": "web", "hostname": "*.example.org"},
+		map[string]interface{}{"name": "api", "hostname": "api.example.org"},
+		map[string]interface{}{"name": "any"},
+	}
+	gateways := []*unstructured.Unstructured{
+		newTestGateway("testing", "internal", listeners, "10.0.0.1"),
+		newTestGateway("infra", "public", listeners, "1.2.3.4", "lb.example.net."),
+	}
+	internal := map[string]interface{}{"name": "internal"}
+	public := map[string]interface{}{"name": "public", "namespace": "infra"}
+	publicAPI := map[string]interface{}{"name": "public", "namespace": "infra", "sectionName": "api"}
+	publicAny := map[string]interface{}{"name": "public", "namespace": "infra", "sectionName": "any"}
+
+	for _, tc := range []struct {
+		title            string
+		annotationFilter string
+		route            *unstructured.Unstructured
+		expected         []*endpoint.Endpoint
+	}{
+		{
+			title: "hostnames point at the addresses of the accepting gateways",
+			route: newTestHTTPRoute("foo", nil,
+				[]interface{}{"foo.example.org", "bar.example.org."},
+				[]interface{}{internal, public}, internal, public),
+			expected: []*endpoint.Endpoint{
+				{DNSName: "bar.example.org", Targets: endpoint.Targets{"10.0.0.1", "1.2.3.4"}, RecordType: endpoint.RecordTypeA},
+				{DNSName: "bar.example.org", Targets: endpoint.Targets{"lb.example.net"}, RecordType: endpoint.RecordTypeCNAME},
+				{DNSName: "foo.example.org", Targets: endpoint.Targets{"10.0.0.1", "1.2.3.4"}, RecordType: endpoint.RecordTypeA},
+				{DNSName: "foo.example.org", Targets: endpoint.Targets{"lb.example.net"}, RecordType: endpoint.RecordTypeCNAME},
+			},
+		},
+		{
+			title: "gateways which didn't accept the route are ignored",
+			route: newTestHTTPRoute("foo", nil,
+				[]interface{}{"foo.example.org"},
+				[]interface{}{internal, public}, internal),
+			expected: []*endpoint.Endpoint{
+				{DNSName: "foo.example.org", Targets: endpoint.Targets{"10.0.0.1"}, RecordType: endpoint.RecordTypeA},
+			},
+		},
+		{
+			title: "route without hostnames gets the hostnames of its listener",
+			route: newTestHTTPRoute("foo", nil, nil,
+				[]interface{}{publicAPI}, publicAPI),
+			expected: []*endpoint.Endpoint{
+				{DNSName: "api.example.org", Targets: endpoint.Targets{"1.2.3.4"}, RecordType: endpoint.RecordTypeA},
+				{DNSName: "api.example.org", Targets: endpoint.Targets{"lb.example.net"}, RecordType: endpoint.RecordTypeCNAME},
+			},
+		},
+		{
+			title: "route hostnames not matching the hostname of the listener are ignored",
+			route: newTestHTTPRoute("foo", nil,
+				[]interface{}{"foo.example.com", "api.example.org"},
+				[]interface{}{publicAPI}, publicAPI),
+			expected: []*endpoint.Endpoint{
+				{DNSName: "api.example.org", Targets: endpoint.Targets{"1.2.3.4"}, RecordType: endpoint.RecordTypeA},
+				{DNSName: "api.example.org", Targets: endpoint.Targets{"lb.example.net"}, RecordType: endpoint.RecordTypeCNAME},
+			},
+		},
+		{
+			title: "wildcard route hostnames are narrowed to the hostname of the listener",
+			route: newTestHTTPRoute("foo", nil,
+				[]interface{}{"*.example.org"},
+				[]interface{}{publicAPI}, publicAPI),
+			expected: []*endpoint.Endpoint{
+				{DNSName: "api.example.org", Targets: endpoint.Targets{"1.2.3.4"}, RecordType: endpoint.RecordTypeA},
+				{DNSName: "api.example.org", Targets: endpoint.Targets{"lb.example.net"}, RecordType: endpoint.RecordTypeCNAME},
+			},
+		},
+		{
+			title: "listeners without a hostname accept all route hostnames",
+			route: newTestHTTPRoute("foo", nil,
+				[]interface{}{"foo.example.com"},
+				[]interface{}{publicAny}, publicAny),
+			expected: []*endpoint.Endpoint{
+				{DNSName: "foo.example.com", Targets: endpoint.Targets{"1.2.3.4"}, RecordType: endpoint.RecordTypeA},
+				{DNSName: "foo.example.com", Targets: endpoint.Targets{"lb.example.net"}, RecordType: endpoint.RecordTypeCNAME},
+			},
+		},
+		{
+			title: "annotations set the hostnames, targets, TTL and provider-specific properties",
+			route: newTestHTTPRoute("foo", map[string]string{
+				hostnameAnnotationKey: "extra.example.org",
+				targetAnnotationKey:   "5.6.7.8",
+				ttlAnnotationKey:      "60",
+				CloudflareProxiedKey:  "true",
+			}, []interface{}{"foo.example.org"},
+				[]interface{}{internal}, internal),
+			expected: []*endpoint.Endpoint{
+				{DNSName: "extra.example.org", Targets: endpoint.Targets{"5.6.7.8"}, RecordType: endpoint.RecordTypeA, RecordTTL: 60,
+					ProviderSpecific: endpoint.ProviderSpecific{{Name: CloudflareProxiedKey, Value: "true"}}},
+				{DNSName: "foo.example.org", Targets: endpoint.Targets{"5.6.7.8"}, RecordType: endpoint.RecordTypeA, RecordTTL: 60,
+					ProviderSpecific: endpoint.ProviderSpecific{{Name: CloudflareProxiedKey, Value: "true"}}},
+			},
+		},
+		{
+			title: "parents which aren't gateways are ignored",
+			route: newTestHTTPRoute("foo", nil,
+				[]interface{}{"foo.example.org"},
+				[]interface{}{map[string]interface{}{"name": "internal", "kind": "Service", "group": ""}}),
+			expected: []*endpoint.Endpoint{},
+		},
+		{
+			title: "routes of another controller are ignored",
+			route: newTestHTTPRoute("foo", map[string]string{controllerAnnotationKey: "other"},
+				[]interface{}{"foo.example.org"},
+				[]interface{}{internal}, internal),
+			expected: []*endpoint.Endpoint{},
+		},
+		{
+			title:            "routes not matching the annotation filter are ignored",
+			annotationFilter: "team=web",
+			route: newTestHTTPRoute("foo", nil,
+				[]interface{}{"foo.example.org"},
+				[]interface{}{internal}, internal),
+			expected: []*endpoint.Endpoint{},
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			sc := &gatewayRouteSource{
+				annotationFilter: tc.annotationFilter,
+				kind:             "httproute",
+				routeLister:      newTestGenericLister("httproutes", tc.route),
+				gatewayLister:    newTestGenericLister("gateways", gateways...),
+			}
+
+			endpoints, err := sc.Endpoints()
+			require.NoError(t, err)
+
+			validateEndpoints(t, endpoints, tc.expected)
+			for _, ep := range endpoints {
+				require.Equal(t, "httproute/testing/foo", ep.Labels[endpoint.ResourceLabelKey])
+			}
+		})
+	}
+}
+
+func TestNewGatewayRouteSourceUnsupportedKind(t *testing.T) {
+	_, err := NewGatewayRouteSource(NewInformerFactories(), nil, "", "", "TCPRoute")
+	require.Error(t, err)
+}
@@ -0,0 +1,263 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/compute/metadata"
+	log "github.com/sirupsen/logrus"
+	compute "google.golang.org/api/compute/v1"
+	"google.golang.org/api/option"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	extinformers "k8s.io/client-go/informers/extensions/v1beta1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+const (
+	// The annotation naming the reserved global address a GCE ingress is going to use
+	gceStaticIPNameAnnotationKey = "kubernetes.io/ingress.global-static-ip-name"
+	// The annotations set by ingress-gce to the forwarding rules of the provisioned load balancer
+	gceForwardingRuleAnnotationKey      = "ingress.kubernetes.io/forwarding-rule"
+	gceHTTPSForwardingRuleAnnotationKey = "ingress.kubernetes.io/https-forwarding-rule"
+	// The annotation selecting the ingress controller
+	ingressClassAnnotationKey = "kubernetes.io/ingress.class"
+)
+
+// GCEAddressResolver looks up the IP addresses of global GCE load balancer resources.
+type GCEAddressResolver interface {
+	GlobalAddressIP(name string) (string, error)
+	GlobalForwardingRuleIP(name string) (string, error)
+}
+
+// gkeIngressSource is an implementation of Source for ingresses provisioned by the GKE ingress controller.
+// While the ingress status doesn't carry any address yet, the address is looked up through the Compute API
+// from the reserved static IP or the forwarding rules ingress-gce annotates the ingress with. BackendConfig
+// objects only configure the backend services and don't carry any address.
+type gkeIngressSource struct {
+	client                   kubernetes.Interface
+	resolver                 GCEAddressResolver
+	namespace                string
+	annotationFilter         string
+	ignoreHostnameAnnotation bool
+	ingressInformer          extinformers.IngressInformer
+}
+
+// NewGKEIngressSource creates a new gkeIngressSource with the given config.
+func NewGKEIngressSource(informers *InformerFactories, kubeClient kubernetes.Interface, resolver GCEAddressResolver, namespace, annotationFilter string, ignoreHostnameAnnotation bool) (Source, error) {
+	// Use shared informer to listen for add/update/delete of ingresses in the specified namespace.
+	// Set resync period to 0, to prevent processing when nothing has changed.
+	informerFactory := informers.Kube(kubeClient, namespace)
+	ingressInformer := informerFactory.Extensions().V1beta1().Ingresses()
+
+	// Add default resource event handlers to properly initialize informer.
+	ingressInformer.Informer().AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+			},
+		},
+	)
+
+	// TODO informer is not explicitly stopped since controller is not passing in its channel.
+	informerFactory.Start(wait.NeverStop)
+
+	// wait for the local cache to be populated.
+	err := wait.Poll(time.Second, 60*time.Second, func() (bool, error) {
+		return ingressInformer.Informer().HasSynced(), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sync cache: %v", err)
+	}
+
+	return &gkeIngressSource{
+		client:                   kubeClient,
+		resolver:                 resolver,
+		namespace:                namespace,
+		annotationFilter:         annotationFilter,
+		ignoreHostnameAnnotation: ignoreHostnameAnnotation,
+		ingressInformer:          ingressInformer,
+	}, nil
+}
+
+// Endpoints returns endpoint objects for each GCE ingress that should be processed.
+func (sc *gkeIngressSource) Endpoints() ([]*endpoint.Endpoint, error) {
+	ingresses, err := sc.ingressInformer.Lister().Ingresses(sc.namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	ingresses, err = sc.filterByAnnotations(ingresses)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := []*endpoint.Endpoint{}
+
+	for _, ing := range ingresses {
+		if class, ok := ing.Annotations[ingressClassAnnotationKey]; ok && class != "gce" {
+			log.Debugf("Skipping ingress %s/%s because it isn't served by a global GCE load balancer", ing.Namespace, ing.Name)
+			continue
+		}
+
+		// Check controller annotation to see if we are responsible.
+		controller, ok := ing.Annotations[controllerAnnotationKey]
+		if ok && controller != controllerAnnotationValue {
+			log.Debugf("Skipping ingress %s/%s because controller value does not match, found: %s, required: %s",
+				ing.Namespace, ing.Name, controller, controllerAnnotationValue)
+			continue
+		}
+
+		ingEndpoints := endpointsFromIngress(sc.withResolvedStatus(ing), sc.ignoreHostnameAnnotation)
+		if len(ingEndpoints) == 0 {
+			log.Debugf("No endpoints could be generated from ingress %s/%s", ing.Namespace, ing.Name)
+			continue
+		}
+
+		log.Debugf("Endpoints generated from ingress: %s/%s: %v", ing.Namespace, ing.Name, ingEndpoints)
+		for _, ep := range ingEndpoints {
+			ep.Labels[endpoint.ResourceLabelKey] = fmt.Sprintf("ingress/%s/%s", ing.Namespace, ing.Name)
+		}
+		endpoints = append(endpoints, ingEndpoints...)
+	}
+
+	for _, ep := range endpoints {
+		sort.Sort(ep.Targets)
+	}
+
+	return endpoints, nil
+}
+
+// withResolvedStatus returns the ingress with the looked up address in its status if the status is still empty.
+func (sc *gkeIngressSource) withResolvedStatus(ing *v1beta1.Ingress) *v1beta1.Ingress {
+	if len(ing.Status.LoadBalancer.Ingress) > 0 {
+		return ing
+	}
+
+	var ip string
+	if name := ing.Annotations[gceStaticIPNameAnnotationKey]; name != "" {
+		addr, err := sc.resolver.GlobalAddressIP(name)
+		if err != nil {
+			log.Warnf("Unable to look up static IP %s of ingress %s/%s: %v", name, ing.Namespace, ing.Name, err)
+		}
+		ip = addr
+	}
+	for _, key := range []string{gceForwardingRuleAnnotationKey, gceHTTPSForwardingRuleAnnotationKey} {
+		name := ing.Annotations[key]
+		if ip != "" || name == "" {
+			continue
+		}
+		addr, err := sc.resolver.GlobalForwardingRuleIP(name)
+		if err != nil {
+			log.Warnf("Unable to look up forwarding rule %s of ingress %s/%s: %v", name, ing.Namespace, ing.Name, err)
+		}
+		ip = addr
+	}
+	if ip == "" {
+		return ing
+	}
+
+	resolved := ing.DeepCopy()
+	resolved.Status.LoadBalancer.Ingress = []v1.LoadBalancerIngress{{IP: ip}}
+	return resolved
+}
+
+// filterByAnnotations filters a list of ingresses by a given annotation selector.
+func (sc *gkeIngressSource) filterByAnnotations(ingresses []*v1beta1.Ingress) ([]*v1beta1.Ingress, error) {
+	labelSelector, err := metav1.ParseToLabelSelector(sc.annotationFilter)
+	if err != nil {
+		return nil, err
+	}
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	// empty filter returns original list
+	if selector.Empty() {
+		return ingresses, nil
+	}
+
+	filteredList := []*v1beta1.Ingress{}
+
+	for _, ingress := range ingresses {
+		// include ingress if its annotations match the selector
+		if selector.Matches(labels.Set(ingress.Annotations)) {
+			filteredList = append(filteredList, ingress)
+		}
+	}
+
+	return filteredList, nil
+}
+
+func (sc *gkeIngressSource) AddEventHandler(handler func() error, stopChan <-chan struct{}, minInterval time.Duration) {
+}
+
+// computeAddressResolver resolves global GCE addresses and forwarding rules through the Compute API.
+type computeAddressResolver struct {
+	service *compute.Service
+	project string
+}
+
+// NewComputeAddressResolver returns a GCEAddressResolver using the given Compute API client.
+func NewComputeAddressResolver(service *compute.Service, project string) GCEAddressResolver {
+	return &computeAddressResolver{service: service, project: project}
+}
+
+// NewDefaultComputeAddressResolver returns a GCEAddressResolver using the application default credentials.
+// The project is auto-detected when running on GCP and not specified.
+func NewDefaultComputeAddressResolver(project string) (GCEAddressResolver, error) {
+	service, err := compute.NewService(context.Background(), option.WithScopes(compute.ComputeReadonlyScope))
+	if err != nil {
+		return nil, err
+	}
+	if project == "" {
+		mProject, mErr := metadata.ProjectID()
+		if mErr != nil {
+			return nil, fmt.Errorf("unable to detect the Google project: %v", mErr)
+		}
+		log.Infof("Google project auto-detected: %s", mProject)
+		project = mProject
+	}
+	return NewComputeAddressResolver(service, project), nil
+}
+
+// GlobalAddressIP returns the IP of a reserved global address.
+func (r *computeAddressResolver) GlobalAddressIP(name string) (string, error) {
+	addr, err := r.service.GlobalAddresses.Get(r.project, name).Do()
+	if err != nil {
+		return "", err
+	}
+	return addr.Address, nil
+}
+
+// GlobalForwardingRuleIP returns the IP of a global forwarding rule.
+func (r *computeAddressResolver) GlobalForwardingRuleIP(name string) (string, error) {
+	rule, err := r.service.GlobalForwardingRules.Get(r.project, name).Do()
+	if err != nil {
+		return "", err
+	}
+	return rule.IPAddress, nil
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+type fakeGCEAddressResolver struct {
+	addresses       map[string]string
+	forwardingRules map[string]string
+}
+
+func (r *fakeGCEAddressResolver) GlobalAddressIP(name string) (string, error) {
+	if ip, ok := r.addresses[name]; ok {
+		return ip, nil
+	}
+	return "", errors.New("not found")
+}
+
+func (r *fakeGCEAddressResolver) GlobalForwardingRuleIP(name string) (string, error) {
+	if ip, ok := r.forwardingRules[name]; ok {
+		return ip, nil
+	}
+	return "", errors.New("not found")
+}
+
+func TestGKEIngressEndpoints(t *testing.T) {
+	resolver := &fakeGCEAddressResolver{
+		addresses:       map[string]string{"foo-ip": "34.1.1.1"},
+		forwardingRules: map[string]string{"k8s-fw-testing-foo": "34.2.2.2"},
+	}
+
+	for _, tc := range []struct {
+		title       string
+		annotations map[string]string
+		lbs         []v1.LoadBalancerIngress
+		expected    []*endpoint.Endpoint
+	}{
+		{
+			title: "status address is preferred",
+			annotations: map[string]string{
+				gceStaticIPNameAnnotationKey: "foo-ip",
+			},
+			lbs: []v1.LoadBalancerIngress{{IP: "34.3.3.3"}},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "foo.example.org", Targets: endpoint.Targets{"34.3.3.3"}, RecordType: endpoint.RecordTypeA},
+			},
+		},
+		{
+			title: "reserved static IP is looked up while the status is empty",
+			annotations: map[string]string{
+				gceStaticIPNameAnnotationKey: "foo-ip",
+			},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "foo.example.org", Targets: endpoint.Targets{"34.1.1.1"}, RecordType: endpoint.RecordTypeA},
+			},
+		},
+		{
+			title: "forwarding rule is looked up while the status is empty",
+			annotations: map[string]string{
+				gceForwardingRuleAnnotationKey: "k8s-fw-testing-foo",
+			},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "foo.example.org", Targets: endpoint.Targets{"34.2.2.2"}, RecordType: endpoint.RecordTypeA},
+			},
+		},
+		{
+			title: "nothing is published while the load balancer isn't provisioned",
+			annotations: map[string]string{
+				gceForwardingRuleAnnotationKey: "k8s-fw-testing-unknown",
+			},
+			expected: []*endpoint.Endpoint{},
+		},
+		{
+			title: "ingresses of other controllers are skipped",
+			annotations: map[string]string{
+				ingressClassAnnotationKey:    "nginx",
+				gceStaticIPNameAnnotationKey: "foo-ip",
+			},
+			expected: []*endpoint.Endpoint{},
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			kubernetes := fake.NewSimpleClientset()
+
+			ingress := &v1beta1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:   "testing",
+					Name:        "foo",
+					Annotations: tc.annotations,
+				},
+				Spec: v1beta1.IngressSpec{
+					Rules: []v1beta1.IngressRule{{Host: "foo.example.org"}},
+				},
+				Status: v1beta1.IngressStatus{
+					LoadBalancer: v1.LoadBalancerStatus{Ingress: tc.lbs},
+				},
+			}
+			_, err := kubernetes.ExtensionsV1beta1().Ingresses(ingress.Namespace).Create(ingress)
+			require.NoError(t, err)
+
+			client, err := NewGKEIngressSource(NewInformerFactories(), kubernetes, resolver, "", "", false)
+			require.NoError(t, err)
+
+			endpoints, err := client.Endpoints()
+			require.NoError(t, err)
+
+			validateEndpoints(t, endpoints, tc.expected)
+		})
+	}
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"sync"
+
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+)
+
+// informerFactoryKey identifies the informers of a client in a namespace, empty for all namespaces.
+type informerFactoryKey struct {
+	client    interface{}
+	namespace string
+}
+
+// InformerFactories holds the informer factories shared by the sources of a pipeline. An informer factory
+// creates a single informer per resource, so sources watching the same resources with the same client, e.g.
+// the services of the service, rollout and gateway sources, share their watch and cache instead of each
+// listing and watching them on its own.
+type InformerFactories struct {
+	kube    map[informerFactoryKey]kubeinformers.SharedInformerFactory
+	dynamic map[informerFactoryKey]dynamicinformer.DynamicSharedInformerFactory
+	lock    sync.Mutex
+}
+
+// NewInformerFactories creates an empty set of informer factories.
+func NewInformerFactories() *InformerFactories {
+	return &InformerFactories{
+		kube:    map[informerFactoryKey]kubeinformers.SharedInformerFactory{},
+		dynamic: map[informerFactoryKey]dynamicinformer.DynamicSharedInformerFactory{},
+	}
+}
+
+// Kube returns the shared informer factory of the client in the namespace. The resync period is 0, to
+// prevent processing when nothing has changed. Starting the factory again only starts the informers which
+// were added since.
+func (f *InformerFactories) Kube(kubeClient kubernetes.Interface, namespace string) kubeinformers.SharedInformerFactory {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	key := informerFactoryKey{client: kubeClient, namespace: namespace}
+	factory, ok := f.kube[key]
+	if !ok {
+		factory = kubeinformers.NewSharedInformerFactoryWithOptions(kubeClient, 0, kubeinformers.WithNamespace(namespace))
+		f.kube[key] = factory
+	}
+	return factory
+}
+
+// Dynamic returns the shared dynamic informer factory of the client in the namespace.
+func (f *InformerFactories) Dynamic(dynamicKubeClient dynamic.Interface, namespace string) dynamicinformer.DynamicSharedInformerFactory {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	key := informerFactoryKey{client: dynamicKubeClient, namespace: namespace}
+	factory, ok := f.dynamic[key]
+	if !ok {
+		factory = dynamicinformer.NewFilteredDynamicSharedInformerFactory(dynamicKubeClient, 0, namespace, nil)
+		f.dynamic[key] = factory
+	}
+	return factory
+}
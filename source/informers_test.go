@@ -0,0 +1,52 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestInformerFactoriesKube(t *testing.T) {
+	informers := NewInformerFactories()
+	client := fake.NewSimpleClientset()
+
+	factory := informers.Kube(client, "default")
+	assert.True(t, factory == informers.Kube(client, "default"))
+	assert.True(t, factory.Core().V1().Services().Informer() == informers.Kube(client, "default").Core().V1().Services().Informer())
+
+	assert.False(t, factory == informers.Kube(client, ""))
+	assert.False(t, factory == informers.Kube(fake.NewSimpleClientset(), "default"))
+	assert.False(t, factory == NewInformerFactories().Kube(client, "default"))
+}
+
+func TestInformerFactoriesDynamic(t *testing.T) {
+	informers := NewInformerFactories()
+	client := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme())
+
+	factory := informers.Dynamic(client, "default")
+	assert.True(t, factory == informers.Dynamic(client, "default"))
+	assert.True(t, factory.ForResource(rolloutGVR).Informer() == informers.Dynamic(client, "default").ForResource(rolloutGVR).Informer())
+
+	assert.False(t, factory == informers.Dynamic(client, ""))
+	assert.False(t, factory == informers.Dynamic(fakedynamic.NewSimpleDynamicClient(runtime.NewScheme()), "default"))
+	assert.False(t, factory == NewInformerFactories().Dynamic(client, "default"))
+}
@@ -28,8 +28,8 @@ import (
 	"k8s.io/api/extensions/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
-	kubeinformers "k8s.io/client-go/informers"
 	extinformers "k8s.io/client-go/informers/extensions/v1beta1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
@@ -43,12 +43,17 @@ const (
 	ALBDualstackAnnotationKey = "alb.ingress.kubernetes.io/ip-address-type"
 	// ALBDualstackAnnotationValue is the value of the ALB dualstack annotation that indicates it is dualstack
 	ALBDualstackAnnotationValue = "dualstack"
+
+	// The annotation used for overriding the targets of single hostnames of an ingress with host=target pairs,
+	// e.g. for ingresses fronted by a load balancer per hostname
+	hostTargetsAnnotationKey = "external-dns.alpha.kubernetes.io/host-targets"
 )
 
 // ingressSource is an implementation of Source for Kubernetes ingress objects.
-// Ingress implementation will use the spec.rules.host value for the hostname
+// Ingress implementation will use the spec.rules.host and spec.tls.hosts values for the hostnames
 // Use targetAnnotationKey to explicitly set Endpoint. (useful if the ingress
 // controller does not update, or to override with alternative endpoint)
+// Use hostTargetsAnnotationKey to set the Endpoint of single hostnames.
 type ingressSource struct {
 	client                   kubernetes.Interface
 	namespace                string
@@ -57,11 +62,13 @@ type ingressSource struct {
 	combineFQDNAnnotation    bool
 	ignoreHostnameAnnotation bool
 	ingressInformer          extinformers.IngressInformer
+	readinessGate            *readinessGate
+	namespaceDefaults        *namespaceAnnotationDefaults
 	runner                   *async.BoundedFrequencyRunner
 }
 
 // NewIngressSource creates a new ingressSource with the given config.
-func NewIngressSource(kubeClient kubernetes.Interface, namespace, annotationFilter string, fqdnTemplate string, combineFqdnAnnotation bool, ignoreHostnameAnnotation bool) (Source, error) {
+func NewIngressSource(informers *InformerFactories, kubeClient kubernetes.Interface, namespace, annotationFilter string, fqdnTemplate string, combineFqdnAnnotation bool, ignoreHostnameAnnotation bool, inheritNamespaceDefaults bool) (Source, error) {
 	var (
 		tmpl *template.Template
 		err  error
@@ -77,7 +84,7 @@ func NewIngressSource(kubeClient kubernetes.Interface, namespace, annotationFilt
 
 	// Use shared informer to listen for add/update/delete of ingresses in the specified namespace.
 	// Set resync period to 0, to prevent processing when nothing has changed.
-	informerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(kubeClient, 0, kubeinformers.WithNamespace(namespace))
+	informerFactory := informers.Kube(kubeClient, namespace)
 	ingressInformer := informerFactory.Extensions().V1beta1().Ingresses()
 
 	// Add default resource event handlers to properly initialize informer.
@@ -87,13 +94,21 @@ func NewIngressSource(kubeClient kubernetes.Interface, namespace, annotationFilt
 			},
 		},
 	)
+	var namespaceDefaults *namespaceAnnotationDefaults
+	if inheritNamespaceDefaults {
+		namespaceDefaults = newNamespaceAnnotationDefaults(informerFactory)
+	}
+	readinessGate := newReadinessGate(informerFactory, func(namespace, name string, data []byte) error {
+		_, err := kubeClient.ExtensionsV1beta1().Ingresses(namespace).Patch(name, types.MergePatchType, data)
+		return err
+	})
 
 	// TODO informer is not explicitly stopped since controller is not passing in its channel.
 	informerFactory.Start(wait.NeverStop)
 
 	// wait for the local cache to be populated.
 	err = wait.Poll(time.Second, 60*time.Second, func() (bool, error) {
-		return ingressInformer.Informer().HasSynced(), nil
+		return ingressInformer.Informer().HasSynced() && namespaceDefaults.hasSynced(), nil
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to sync cache: %v", err)
@@ -107,6 +122,8 @@ func NewIngressSource(kubeClient kubernetes.Interface, namespace, annotationFilt
 		combineFQDNAnnotation:    combineFqdnAnnotation,
 		ignoreHostnameAnnotation: ignoreHostnameAnnotation,
 		ingressInformer:          ingressInformer,
+		readinessGate:            readinessGate,
+		namespaceDefaults:        namespaceDefaults,
 	}
 	return sc, nil
 }
@@ -133,6 +150,14 @@ func (sc *ingressSource) Endpoints() ([]*endpoint.Endpoint, error) {
 				ing.Namespace, ing.Name, controller, controllerAnnotationValue)
 			continue
 		}
+		if annotations := sc.namespaceDefaults.annotations(ing.Namespace, ing.Annotations); annotations != nil {
+			ing = ing.DeepCopy()
+			ing.Annotations = annotations
+		}
+
+		if !sc.readinessGate.open(ing, ing.Namespace, ingressBackendServices(ing)) {
+			continue
+		}
 
 		ingEndpoints := endpointsFromIngress(ing, sc.ignoreHostnameAnnotation)
 
@@ -160,6 +185,7 @@ func (sc *ingressSource) Endpoints() ([]*endpoint.Endpoint, error) {
 		sc.setDualstackLabel(ing, ingEndpoints)
 		endpoints = append(endpoints, ingEndpoints...)
 	}
+	sc.readinessGate.prune()
 
 	for _, ep := range endpoints {
 		sort.Sort(ep.Targets)
@@ -265,32 +291,97 @@ func endpointsFromIngress(ing *v1beta1.Ingress, ignoreHostnameAnnotation bool) [
 
 	providerSpecific, setIdentifier := getProviderSpecificAnnotations(ing.Annotations)
 
-	for _, rule := range ing.Spec.Rules {
-		if rule.Host == "" {
-			continue
+	hostTargets, err := getHostTargetsFromAnnotations(ing.Annotations)
+	if err != nil {
+		log.Warnf("Ignoring the host targets of ingress %s/%s: %v", ing.Namespace, ing.Name, err)
+	}
+
+	// the hostnames of the rules, the TLS hosts and the hostname annotation are published once each
+	var hostnames []string
+	seen := map[string]bool{}
+	add := func(hostname string) {
+		key := strings.TrimSuffix(hostname, ".")
+		if key == "" || seen[key] {
+			return
 		}
-		endpoints = append(endpoints, endpointsForHostname(rule.Host, targets, ttl, providerSpecific, setIdentifier)...)
+		seen[key] = true
+		hostnames = append(hostnames, hostname)
+	}
+
+	for _, rule := range ing.Spec.Rules {
+		add(rule.Host)
 	}
 
 	for _, tls := range ing.Spec.TLS {
 		for _, host := range tls.Hosts {
-			if host == "" {
-				continue
-			}
-			endpoints = append(endpoints, endpointsForHostname(host, targets, ttl, providerSpecific, setIdentifier)...)
+			add(host)
 		}
 	}
 
 	// Skip endpoints if we do not want entries from annotations
 	if !ignoreHostnameAnnotation {
-		hostnameList := getHostnamesFromAnnotations(ing.Annotations)
-		for _, hostname := range hostnameList {
-			endpoints = append(endpoints, endpointsForHostname(hostname, targets, ttl, providerSpecific, setIdentifier)...)
+		for _, hostname := range getHostnamesFromAnnotations(ing.Annotations) {
+			add(hostname)
+		}
+	}
+
+	for _, hostname := range hostnames {
+		hostnameTargets := targets
+		if override, ok := hostTargets[strings.TrimSuffix(hostname, ".")]; ok {
+			hostnameTargets = override
 		}
+		endpoints = append(endpoints, endpointsForHostname(hostname, hostnameTargets, ttl, providerSpecific, setIdentifier)...)
 	}
 	return endpoints
 }
 
+// getHostTargetsFromAnnotations returns the targets of the hostnames of the host targets annotation, a comma
+// separated list of host=target pairs. A hostname with several targets is listed once per target.
+func getHostTargetsFromAnnotations(annotations map[string]string) (map[string]endpoint.Targets, error) {
+	annotation := strings.Replace(annotations[hostTargetsAnnotationKey], " ", "", -1)
+	if annotation == "" {
+		return nil, nil
+	}
+
+	hostTargets := map[string]endpoint.Targets{}
+	for _, pair := range strings.Split(annotation, ",") {
+		if pair == "" {
+			continue
+		}
+		parts := strings.Split(pair, "=")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid host target (host=target) found '%v'", pair)
+		}
+		host := strings.TrimSuffix(parts[0], ".")
+		hostTargets[host] = append(hostTargets[host], strings.TrimSuffix(parts[1], "."))
+	}
+	return hostTargets, nil
+}
+
+// ingressBackendServices returns the names of the services an ingress routes traffic to.
+func ingressBackendServices(ing *v1beta1.Ingress) []string {
+	var services []string
+	seen := map[string]bool{}
+	add := func(backend *v1beta1.IngressBackend) {
+		if backend == nil || backend.ServiceName == "" || seen[backend.ServiceName] {
+			return
+		}
+		seen[backend.ServiceName] = true
+		services = append(services, backend.ServiceName)
+	}
+
+	add(ing.Spec.Backend)
+	for _, rule := range ing.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+		for i := range rule.HTTP.Paths {
+			add(&rule.HTTP.Paths[i].Backend)
+		}
+	}
+	return services
+}
+
 func targetsFromIngressStatus(status v1beta1.IngressStatus) endpoint.Targets {
 	var targets endpoint.Targets
 
@@ -46,12 +46,14 @@ func (suite *IngressSuite) SetupTest() {
 	var err error
 
 	suite.sc, err = NewIngressSource(
+		NewInformerFactories(),
 		fakeClient,
 		"",
 		"",
 		"{{.Name}}",
 		false,
 		false,
+		false,
 	)
 	suite.NoError(err, "should initialize ingress source")
 
@@ -128,12 +130,14 @@ func TestNewIngressSource(t *testing.T) {
 	} {
 		t.Run(ti.title, func(t *testing.T) {
 			_, err := NewIngressSource(
+				NewInformerFactories(),
 				fake.NewSimpleClientset(),
 				"",
 				ti.annotationFilter,
 				ti.fqdnTemplate,
 				ti.combineFQDNAndAnnotation,
 				false,
+				false,
 			)
 			if ti.expectError {
 				assert.Error(t, err)
@@ -218,6 +222,48 @@ func testEndpointsFromIngress(t *testing.T) {
 			},
 			expected: []*endpoint.Endpoint{},
 		},
+		{
+			title: "union of rule.hosts, tls.hosts and hostname annotation",
+			ingress: fakeIngress{
+				dnsnames:    []string{"foo.bar", "www.foo.bar"},
+				tlsdnsnames: [][]string{{"foo.bar", "api.foo.bar"}},
+				annotations: map[string]string{hostnameAnnotationKey: "www.foo.bar., admin.foo.bar"},
+				ips:         []string{"8.8.8.8"},
+			},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "foo.bar", Targets: endpoint.Targets{"8.8.8.8"}},
+				{DNSName: "www.foo.bar", Targets: endpoint.Targets{"8.8.8.8"}},
+				{DNSName: "api.foo.bar", Targets: endpoint.Targets{"8.8.8.8"}},
+				{DNSName: "admin.foo.bar", Targets: endpoint.Targets{"8.8.8.8"}},
+			},
+		},
+		{
+			title: "host targets override the targets of single hostnames",
+			ingress: fakeIngress{
+				dnsnames:    []string{"foo.bar", "api.foo.bar"},
+				tlsdnsnames: [][]string{{"www.foo.bar"}},
+				annotations: map[string]string{
+					hostTargetsAnnotationKey: "api.foo.bar=lb-1.com, api.foo.bar=lb-2.com., www.foo.bar.=1.2.3.4",
+				},
+				ips: []string{"8.8.8.8"},
+			},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "foo.bar", Targets: endpoint.Targets{"8.8.8.8"}},
+				{DNSName: "api.foo.bar", Targets: endpoint.Targets{"lb-1.com", "lb-2.com"}},
+				{DNSName: "www.foo.bar", Targets: endpoint.Targets{"1.2.3.4"}},
+			},
+		},
+		{
+			title: "invalid host targets are ignored",
+			ingress: fakeIngress{
+				dnsnames:    []string{"foo.bar"},
+				annotations: map[string]string{hostTargetsAnnotationKey: "foo.bar=lb-1.com,lb-2.com"},
+				ips:         []string{"8.8.8.8"},
+			},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "foo.bar", Targets: endpoint.Targets{"8.8.8.8"}},
+			},
+		},
 	} {
 		t.Run(ti.title, func(t *testing.T) {
 			realIngress := ti.ingress.Ingress()
@@ -1002,12 +1048,14 @@ func testIngressEndpoints(t *testing.T) {
 
 			fakeClient := fake.NewSimpleClientset()
 			ingressSource, _ := NewIngressSource(
+				NewInformerFactories(),
 				fakeClient,
 				ti.targetNamespace,
 				ti.annotationFilter,
 				ti.fqdnTemplate,
 				ti.combineFQDNAndAnnotation,
 				ti.ignoreHostnameAnnotation,
+				false,
 			)
 			for _, ingress := range ingresses {
 				_, err := fakeClient.Extensions().Ingresses(ingress.Namespace).Create(ingress)
@@ -0,0 +1,261 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/jsonpath"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// jsonPathSource is a generic implementation of Source for any resource. The hostnames and targets
+// of each object are extracted with JSONPath expressions, e.g. `{.spec.hosts[*]}` and
+// `{.status.addresses[*].value}`, so custom resources can be published without writing a source.
+// The target annotation overrides the extracted targets and the TTL and provider-specific annotations
+// are respected.
+type jsonPathSource struct {
+	dynamicKubeClient dynamic.Interface
+	namespace         string
+	annotationFilter  string
+	kind              string
+	hostnamePath      *jsonpath.JSONPath
+	targetPath        *jsonpath.JSONPath
+	informer          kubeinformers.GenericInformer
+}
+
+// NewJSONPathSource creates a new jsonPathSource for the resources of the given API version and kind.
+func NewJSONPathSource(informers *InformerFactories, kubeClient kubernetes.Interface, dynamicKubeClient dynamic.Interface, namespace, annotationFilter, apiVersion, kind, hostnameJSONPath, targetJSONPath string) (Source, error) {
+	if apiVersion == "" || kind == "" {
+		return nil, errors.New("the jsonpath source requires an API version and kind")
+	}
+	hostnamePath, err := parseJSONPath("hostname", hostnameJSONPath)
+	if err != nil {
+		return nil, err
+	}
+	targetPath, err := parseJSONPath("target", targetJSONPath)
+	if err != nil {
+		return nil, err
+	}
+
+	groupVersion, err := schema.ParseGroupVersion(apiVersion)
+	if err != nil {
+		return nil, err
+	}
+	apiResourceList, err := kubeClient.Discovery().ServerResourcesForGroupVersion(groupVersion.String())
+	if err != nil {
+		return nil, fmt.Errorf("error listing resources in GroupVersion %q: %s", groupVersion.String(), err)
+	}
+
+	var apiResource *metav1.APIResource
+	for i := range apiResourceList.APIResources {
+		// skip subresources like <resource>/status
+		if apiResourceList.APIResources[i].Kind == kind && !strings.Contains(apiResourceList.APIResources[i].Name, "/") {
+			apiResource = &apiResourceList.APIResources[i]
+			break
+		}
+	}
+	if apiResource == nil {
+		return nil, fmt.Errorf("unable to find Resource Kind %q in GroupVersion %q", kind, apiVersion)
+	}
+	if !apiResource.Namespaced {
+		namespace = metav1.NamespaceAll
+	}
+
+	// Use shared informer to listen for add/update/delete of the resources in the specified namespace.
+	// Set resync period to 0, to prevent processing when nothing has changed.
+	informerFactory := informers.Dynamic(dynamicKubeClient, namespace)
+	informer := informerFactory.ForResource(groupVersion.WithResource(apiResource.Name))
+
+	// Add default resource event handlers to properly initialize informer.
+	informer.Informer().AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+			},
+		},
+	)
+
+	// TODO informer is not explicitly stopped since controller is not passing in its channel.
+	informerFactory.Start(wait.NeverStop)
+
+	// wait for the local cache to be populated.
+	err = wait.Poll(time.Second, 60*time.Second, func() (bool, error) {
+		return informer.Informer().HasSynced(), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sync cache: %v", err)
+	}
+
+	return &jsonPathSource{
+		dynamicKubeClient: dynamicKubeClient,
+		namespace:         namespace,
+		annotationFilter:  annotationFilter,
+		kind:              strings.ToLower(kind),
+		hostnamePath:      hostnamePath,
+		targetPath:        targetPath,
+		informer:          informer,
+	}, nil
+}
+
+// Endpoints returns endpoint objects for each object of the configured resource.
+func (sc *jsonPathSource) Endpoints() ([]*endpoint.Endpoint, error) {
+	objects, err := sc.informer.Lister().ByNamespace(sc.namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	labelSelector, err := metav1.ParseToLabelSelector(sc.annotationFilter)
+	if err != nil {
+		return nil, err
+	}
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := []*endpoint.Endpoint{}
+
+	for _, obj := range objects {
+		u, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		if !selector.Empty() && !selector.Matches(labels.Set(u.GetAnnotations())) {
+			continue
+		}
+
+		// Check controller annotation to see if we are responsible.
+		controller, ok := u.GetAnnotations()[controllerAnnotationKey]
+		if ok && controller != controllerAnnotationValue {
+			log.Debugf("Skipping %s %s/%s because controller value does not match, found: %s, required: %s",
+				sc.kind, u.GetNamespace(), u.GetName(), controller, controllerAnnotationValue)
+			continue
+		}
+
+		objEndpoints := sc.endpointsFromObject(u)
+		if len(objEndpoints) == 0 {
+			log.Debugf("No endpoints could be generated from %s %s/%s", sc.kind, u.GetNamespace(), u.GetName())
+			continue
+		}
+
+		log.Debugf("Endpoints generated from %s: %s/%s: %v", sc.kind, u.GetNamespace(), u.GetName(), objEndpoints)
+		setUnstructuredResourceLabel(sc.kind, u, objEndpoints)
+		endpoints = append(endpoints, objEndpoints...)
+	}
+
+	for _, ep := range endpoints {
+		sort.Sort(ep.Targets)
+	}
+
+	return endpoints, nil
+}
+
+func (sc *jsonPathSource) endpointsFromObject(u *unstructured.Unstructured) []*endpoint.Endpoint {
+	resource := fmt.Sprintf("%s %s/%s", sc.kind, u.GetNamespace(), u.GetName())
+
+	hostnames, err := findJSONPathStrings(sc.hostnamePath, u)
+	if err != nil {
+		log.Warnf("Unable to extract the hostnames of %s: %v", resource, err)
+		return nil
+	}
+
+	targets := getTargetsFromTargetAnnotation(u.GetAnnotations())
+	if len(targets) == 0 {
+		values, err := findJSONPathStrings(sc.targetPath, u)
+		if err != nil {
+			log.Warnf("Unable to extract the targets of %s: %v", resource, err)
+			return nil
+		}
+		for _, value := range values {
+			targets = append(targets, strings.TrimSuffix(value, "."))
+		}
+	}
+
+	return endpointsForHostnames(hostnames, targets, u.GetAnnotations(), resource)
+}
+
+func (sc *jsonPathSource) AddEventHandler(handler func() error, stopChan <-chan struct{}, minInterval time.Duration) {
+}
+
+// parseJSONPath parses a JSONPath expression; the surrounding braces are optional like for kubectl.
+func parseJSONPath(name, expression string) (*jsonpath.JSONPath, error) {
+	if expression == "" {
+		return nil, fmt.Errorf("the jsonpath source requires a %s JSONPath expression", name)
+	}
+	if !strings.HasPrefix(expression, "{") {
+		expression = "{" + expression + "}"
+	}
+
+	path := jsonpath.New(name).AllowMissingKeys(true)
+	if err := path.Parse(expression); err != nil {
+		return nil, fmt.Errorf("invalid %s JSONPath expression %q: %v", name, expression, err)
+	}
+	return path, nil
+}
+
+// findJSONPathStrings returns the non-empty string values the JSONPath expression matches in the object.
+// Lists of strings are flattened and comma separated values are split like for annotations.
+func findJSONPathStrings(path *jsonpath.JSONPath, u *unstructured.Unstructured) ([]string, error) {
+	results, err := path.FindResults(u.Object)
+	if err != nil {
+		return nil, err
+	}
+
+	var values []string
+	add := func(v interface{}) {
+		s, ok := v.(string)
+		if !ok {
+			return
+		}
+		for _, value := range strings.Split(strings.Replace(s, " ", "", -1), ",") {
+			if value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	for _, result := range results {
+		for _, value := range result {
+			if !value.IsValid() || !value.CanInterface() {
+				continue
+			}
+			switch v := value.Interface().(type) {
+			case []interface{}:
+				for _, item := range v {
+					add(item)
+				}
+			default:
+				add(v)
+			}
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestParseJSONPath(t *testing.T) {
+	for _, tc := range []struct {
+		title      string
+		expression string
+		expectErr  bool
+	}{
+		{"expression with braces", "{.spec.hosts[*]}", false},
+		{"expression without braces", ".spec.hosts[*]", false},
+		{"empty expression", "", true},
+		{"invalid expression", "{.spec.hosts[}", true},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			_, err := parseJSONPath("hostname", tc.expression)
+			if tc.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestJSONPathEndpointsFromObject(t *testing.T) {
+	for _, tc := range []struct {
+		title        string
+		hostnamePath string
+		targetPath   string
+		annotations  map[string]string
+		object       map[string]interface{}
+		expected     []*endpoint.Endpoint
+	}{
+		{
+			title:        "hostnames and targets are extracted from lists",
+			hostnamePath: "{.spec.hosts[*]}",
+			targetPath:   "{.status.addresses[*].value}",
+			annotations: map[string]string{
+				ttlAnnotationKey: "300",
+			},
+			object: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"hosts": []interface{}{"a.example.org", "b.example.org"},
+				},
+				"status": map[string]interface{}{
+					"addresses": []interface{}{
+						map[string]interface{}{"value": "1.2.3.4"},
+						map[string]interface{}{"value": "5.6.7.8"},
+					},
+				},
+			},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "a.example.org", Targets: endpoint.Targets{"1.2.3.4", "5.6.7.8"}, RecordType: endpoint.RecordTypeA, RecordTTL: 300},
+				{DNSName: "b.example.org", Targets: endpoint.Targets{"1.2.3.4", "5.6.7.8"}, RecordType: endpoint.RecordTypeA, RecordTTL: 300},
+			},
+		},
+		{
+			title:        "comma separated hostnames and hostname targets",
+			hostnamePath: ".spec.domains",
+			targetPath:   ".status.loadBalancer",
+			object: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"domains": "a.example.org, b.example.org",
+				},
+				"status": map[string]interface{}{
+					"loadBalancer": "lb.example.com.",
+				},
+			},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "a.example.org", Targets: endpoint.Targets{"lb.example.com"}, RecordType: endpoint.RecordTypeCNAME},
+				{DNSName: "b.example.org", Targets: endpoint.Targets{"lb.example.com"}, RecordType: endpoint.RecordTypeCNAME},
+			},
+		},
+		{
+			title:        "target annotation overrides the extracted targets",
+			hostnamePath: "{.spec.hosts[*]}",
+			targetPath:   "{.status.addresses[*].value}",
+			annotations: map[string]string{
+				targetAnnotationKey: "9.9.9.9",
+			},
+			object: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"hosts": []interface{}{"a.example.org"},
+				},
+				"status": map[string]interface{}{
+					"addresses": []interface{}{
+						map[string]interface{}{"value": "1.2.3.4"},
+					},
+				},
+			},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "a.example.org", Targets: endpoint.Targets{"9.9.9.9"}, RecordType: endpoint.RecordTypeA},
+			},
+		},
+		{
+			title:        "objects without status are ignored",
+			hostnamePath: "{.spec.hosts[*]}",
+			targetPath:   "{.status.addresses[*].value}",
+			object: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"hosts": []interface{}{"a.example.org"},
+				},
+			},
+			expected: []*endpoint.Endpoint{},
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			hostnamePath, err := parseJSONPath("hostname", tc.hostnamePath)
+			require.NoError(t, err)
+			targetPath, err := parseJSONPath("target", tc.targetPath)
+			require.NoError(t, err)
+
+			sc := &jsonPathSource{
+				kind:         "gateway",
+				hostnamePath: hostnamePath,
+				targetPath:   targetPath,
+			}
+
+			obj := &unstructured.Unstructured{Object: tc.object}
+			obj.SetNamespace("testing")
+			obj.SetName("foo")
+			obj.SetAnnotations(tc.annotations)
+
+			validateEndpoints(t, sc.endpointsFromObject(obj), tc.expected)
+		})
+	}
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"net"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+const (
+	// The annotation used by kube-vip to carry the VIPs of a service
+	kubeVIPLoadBalancerIPsAnnotationKey = "kube-vip.io/loadbalancerIPs"
+	// The annotation set by kube-vip to the node which currently holds the VIPs of a service
+	kubeVIPHostAnnotationKey = "kube-vip.io/vipHost"
+)
+
+// extractKubeVIPTargets returns the VIPs kube-vip is going to bind for a service.
+// kube-vip writes the addresses to an annotation before the service status, so they
+// are used while the status doesn't carry any address yet. To avoid publishing an
+// address nobody answers for, they are only returned once kube-vip reports a ready
+// node holding them.
+func (sc *serviceSource) extractKubeVIPTargets(svc *v1.Service) endpoint.Targets {
+	value, exists := svc.Annotations[kubeVIPLoadBalancerIPsAnnotationKey]
+	if !exists {
+		return nil
+	}
+
+	host := svc.Annotations[kubeVIPHostAnnotationKey]
+	if host == "" {
+		log.Debugf("Skipping kube-vip addresses of service %s/%s because no node holds them yet", svc.Namespace, svc.Name)
+		return nil
+	}
+	node, err := sc.nodeInformer.Lister().Get(host)
+	if err != nil {
+		log.Debugf("Unable to find node %s holding the kube-vip addresses of service %s/%s", host, svc.Namespace, svc.Name)
+		return nil
+	}
+	if !isNodeReady(node) {
+		log.Debugf("Skipping kube-vip addresses of service %s/%s because node %s is not ready", svc.Namespace, svc.Name, host)
+		return nil
+	}
+
+	var targets endpoint.Targets
+	for _, ip := range strings.Split(strings.Replace(value, " ", "", -1), ",") {
+		if ip == "" {
+			continue
+		}
+		if net.ParseIP(ip) == nil {
+			log.Warnf("Ignoring invalid kube-vip address %q on service %s/%s", ip, svc.Namespace, svc.Name)
+			continue
+		}
+		targets = append(targets, ip)
+	}
+	return targets
+}
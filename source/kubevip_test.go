@@ -0,0 +1,145 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestKubeVIPServices(t *testing.T) {
+	for _, tc := range []struct {
+		title       string
+		annotations map[string]string
+		lbs         []v1.LoadBalancerIngress
+		nodeReady   map[string]v1.ConditionStatus
+		expected    []*endpoint.Endpoint
+	}{
+		{
+			title: "status addresses are published when present",
+			annotations: map[string]string{
+				hostnameAnnotationKey:               "foo.example.org",
+				kubeVIPLoadBalancerIPsAnnotationKey: "10.0.0.1",
+			},
+			lbs: []v1.LoadBalancerIngress{{IP: "10.0.0.2"}},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "foo.example.org", Targets: endpoint.Targets{"10.0.0.2"}, RecordType: endpoint.RecordTypeA},
+			},
+		},
+		{
+			title: "annotated addresses are published while a ready node holds them",
+			annotations: map[string]string{
+				hostnameAnnotationKey:               "foo.example.org",
+				kubeVIPLoadBalancerIPsAnnotationKey: "10.0.0.1, 10.0.0.3",
+				kubeVIPHostAnnotationKey:            "node-a",
+			},
+			nodeReady: map[string]v1.ConditionStatus{"node-a": v1.ConditionTrue},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "foo.example.org", Targets: endpoint.Targets{"10.0.0.1", "10.0.0.3"}, RecordType: endpoint.RecordTypeA},
+			},
+		},
+		{
+			title: "annotated addresses are withheld while no node holds them",
+			annotations: map[string]string{
+				hostnameAnnotationKey:               "foo.example.org",
+				kubeVIPLoadBalancerIPsAnnotationKey: "10.0.0.1",
+			},
+			nodeReady: map[string]v1.ConditionStatus{"node-a": v1.ConditionTrue},
+			expected:  []*endpoint.Endpoint{},
+		},
+		{
+			title: "annotated addresses are withheld while the node holding them is not ready",
+			annotations: map[string]string{
+				hostnameAnnotationKey:               "foo.example.org",
+				kubeVIPLoadBalancerIPsAnnotationKey: "10.0.0.1",
+				kubeVIPHostAnnotationKey:            "node-a",
+			},
+			nodeReady: map[string]v1.ConditionStatus{"node-a": v1.ConditionFalse},
+			expected:  []*endpoint.Endpoint{},
+		},
+		{
+			title: "invalid annotated addresses are ignored",
+			annotations: map[string]string{
+				hostnameAnnotationKey:               "foo.example.org",
+				kubeVIPLoadBalancerIPsAnnotationKey: "not-an-ip",
+				kubeVIPHostAnnotationKey:            "node-a",
+			},
+			nodeReady: map[string]v1.ConditionStatus{"node-a": v1.ConditionTrue},
+			expected:  []*endpoint.Endpoint{},
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			kubernetes := fake.NewSimpleClientset()
+
+			for name, status := range tc.nodeReady {
+				node := &v1.Node{
+					ObjectMeta: metav1.ObjectMeta{Name: name},
+					Status: v1.NodeStatus{
+						Conditions: []v1.NodeCondition{{Type: v1.NodeReady, Status: status}},
+					},
+				}
+				_, err := kubernetes.CoreV1().Nodes().Create(node)
+				require.NoError(t, err)
+			}
+
+			service := &v1.Service{
+				Spec: v1.ServiceSpec{
+					Type: v1.ServiceTypeLoadBalancer,
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:   "testing",
+					Name:        "foo",
+					Annotations: tc.annotations,
+				},
+				Status: v1.ServiceStatus{
+					LoadBalancer: v1.LoadBalancerStatus{
+						Ingress: tc.lbs,
+					},
+				},
+			}
+			_, err := kubernetes.CoreV1().Services(service.Namespace).Create(service)
+			require.NoError(t, err)
+
+			client, err := NewServiceSource(NewInformerFactories(), kubernetes, "", "", "", false, "", false, false, []string{}, false, false, false, "", false)
+			require.NoError(t, err)
+
+			var endpoints []*endpoint.Endpoint
+
+			// wait up to a few seconds for the nodes to appear in the informer cache.
+			err = wait.Poll(time.Second, 3*time.Second, func() (bool, error) {
+				endpoints, err = client.Endpoints()
+				if err != nil {
+					return true, err
+				}
+				return len(endpoints) >= len(tc.expected) && len(tc.expected) > 0, nil
+			})
+			if err != wait.ErrWaitTimeout {
+				require.NoError(t, err)
+			}
+
+			validateEndpoints(t, endpoints, tc.expected)
+		})
+	}
+}
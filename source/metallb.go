@@ -0,0 +1,134 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"net"
+
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// metalLBSpeakerSelectors match the speaker pods of the upstream manifests and the Helm chart.
+var metalLBSpeakerSelectors = []labels.Selector{
+	labels.SelectorFromSet(labels.Set{"app": "metallb", "component": "speaker"}),
+	labels.SelectorFromSet(labels.Set{"app.kubernetes.io/name": "metallb", "app.kubernetes.io/component": "speaker"}),
+}
+
+// filterMetalLBAnnouncedTargets drops the IP targets of a service while no MetalLB speaker
+// is able to announce them, e.g. because the speaker holding the address is failing over.
+// Hostname targets aren't assigned by MetalLB and are always kept. While the state of the speakers is
+// unknown, e.g. before their informers synced, all the targets are kept so the records aren't deleted.
+func (sc *serviceSource) filterMetalLBAnnouncedTargets(svc *v1.Service, targets endpoint.Targets) endpoint.Targets {
+	if !sc.metalLBSynced() {
+		log.Debugf("Keeping the addresses of service %s/%s because the MetalLB speakers aren't known yet", svc.Namespace, svc.Name)
+		return targets
+	}
+	announced, err := sc.metalLBAnnounced(svc)
+	if err != nil {
+		log.Errorf("Unable to determine whether the addresses of service %s/%s are announced: %v", svc.Namespace, svc.Name, err)
+		return targets
+	}
+	if announced {
+		return targets
+	}
+
+	var filtered endpoint.Targets
+	for _, t := range targets {
+		if net.ParseIP(t) != nil {
+			log.Debugf("Skipping address %s of service %s/%s because no ready MetalLB speaker announces it", t, svc.Namespace, svc.Name)
+			continue
+		}
+		filtered = append(filtered, t)
+	}
+	return filtered
+}
+
+// metalLBSynced returns true once the speakers, pods and nodes are in the informer caches.
+func (sc *serviceSource) metalLBSynced() bool {
+	return sc.speakerInformer.Informer().HasSynced() && sc.podInformer.Informer().HasSynced() && sc.nodeInformer.Informer().HasSynced()
+}
+
+// metalLBAnnounced returns true if a ready MetalLB speaker runs on a ready node which is eligible to
+// announce the service. With the Local external traffic policy only nodes running a ready pod of the
+// service are eligible, otherwise every node is.
+func (sc *serviceSource) metalLBAnnounced(svc *v1.Service) (bool, error) {
+	var eligible map[string]bool
+	if svc.Spec.ExternalTrafficPolicy == v1.ServiceExternalTrafficPolicyTypeLocal {
+		pods, err := sc.podInformer.Lister().Pods(svc.Namespace).List(labels.SelectorFromSet(svc.Spec.Selector))
+		if err != nil {
+			return false, err
+		}
+		eligible = map[string]bool{}
+		for _, pod := range pods {
+			if isPodReady(pod) {
+				eligible[pod.Spec.NodeName] = true
+			}
+		}
+	}
+
+	for _, selector := range metalLBSpeakerSelectors {
+		speakers, err := sc.speakerInformer.Lister().List(selector)
+		if err != nil {
+			return false, err
+		}
+		for _, speaker := range speakers {
+			if !isPodReady(speaker) {
+				continue
+			}
+			if eligible != nil && !eligible[speaker.Spec.NodeName] {
+				continue
+			}
+			node, err := sc.nodeInformer.Lister().Get(speaker.Spec.NodeName)
+			if err != nil {
+				log.Debugf("Unable to find node where MetalLB speaker %s/%s is running", speaker.Namespace, speaker.Name)
+				continue
+			}
+			if isNodeReady(node) {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// isPodReady returns true if the pod is running and reports the Ready condition.
+func isPodReady(pod *v1.Pod) bool {
+	if pod.Status.Phase != v1.PodRunning {
+		return false
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// isNodeReady returns true if the node reports the Ready condition.
+func isNodeReady(node *v1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == v1.NodeReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
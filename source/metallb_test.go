@@ -0,0 +1,194 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestMetalLBAnnouncedServices(t *testing.T) {
+	speakerLabels := map[string]string{"app": "metallb", "component": "speaker"}
+	backendLabels := map[string]string{"app": "foo"}
+
+	for _, tc := range []struct {
+		title         string
+		trafficPolicy v1.ServiceExternalTrafficPolicyType
+		pods          []*v1.Pod
+		nodeReady     map[string]v1.ConditionStatus
+		expected      []*endpoint.Endpoint
+	}{
+		{
+			title: "address is published when a ready speaker runs on a ready node",
+			pods: []*v1.Pod{
+				newMetalLBTestPod("metallb-system", "speaker-a", "node-a", speakerLabels, true),
+			},
+			nodeReady: map[string]v1.ConditionStatus{"node-a": v1.ConditionTrue},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "foo.example.org", Targets: endpoint.Targets{"192.168.10.1"}, RecordType: endpoint.RecordTypeA},
+			},
+		},
+		{
+			title: "address is withheld when no speaker is ready",
+			pods: []*v1.Pod{
+				newMetalLBTestPod("metallb-system", "speaker-a", "node-a", speakerLabels, false),
+			},
+			nodeReady: map[string]v1.ConditionStatus{"node-a": v1.ConditionTrue},
+			expected:  []*endpoint.Endpoint{},
+		},
+		{
+			title: "address is withheld when the speaker's node is not ready",
+			pods: []*v1.Pod{
+				newMetalLBTestPod("metallb-system", "speaker-a", "node-a", speakerLabels, true),
+			},
+			nodeReady: map[string]v1.ConditionStatus{"node-a": v1.ConditionUnknown},
+			expected:  []*endpoint.Endpoint{},
+		},
+		{
+			title:         "address is withheld when no speaker runs next to a ready backend with local traffic policy",
+			trafficPolicy: v1.ServiceExternalTrafficPolicyTypeLocal,
+			pods: []*v1.Pod{
+				newMetalLBTestPod("metallb-system", "speaker-a", "node-a", speakerLabels, true),
+				newMetalLBTestPod("testing", "foo-0", "node-b", backendLabels, true),
+			},
+			nodeReady: map[string]v1.ConditionStatus{"node-a": v1.ConditionTrue, "node-b": v1.ConditionTrue},
+			expected:  []*endpoint.Endpoint{},
+		},
+		{
+			title:         "address is published when a speaker runs next to a ready backend with local traffic policy",
+			trafficPolicy: v1.ServiceExternalTrafficPolicyTypeLocal,
+			pods: []*v1.Pod{
+				newMetalLBTestPod("metallb-system", "speaker-a", "node-a", speakerLabels, true),
+				newMetalLBTestPod("metallb-system", "speaker-b", "node-b", speakerLabels, true),
+				newMetalLBTestPod("testing", "foo-0", "node-b", backendLabels, true),
+			},
+			nodeReady: map[string]v1.ConditionStatus{"node-a": v1.ConditionTrue, "node-b": v1.ConditionTrue},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "foo.example.org", Targets: endpoint.Targets{"192.168.10.1"}, RecordType: endpoint.RecordTypeA},
+			},
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			kubernetes := fake.NewSimpleClientset()
+
+			for name, status := range tc.nodeReady {
+				node := &v1.Node{
+					ObjectMeta: metav1.ObjectMeta{Name: name},
+					Status: v1.NodeStatus{
+						Conditions: []v1.NodeCondition{{Type: v1.NodeReady, Status: status}},
+					},
+				}
+				_, err := kubernetes.CoreV1().Nodes().Create(node)
+				require.NoError(t, err)
+			}
+
+			for _, pod := range tc.pods {
+				_, err := kubernetes.CoreV1().Pods(pod.Namespace).Create(pod)
+				require.NoError(t, err)
+			}
+
+			service := &v1.Service{
+				Spec: v1.ServiceSpec{
+					Type:                  v1.ServiceTypeLoadBalancer,
+					Selector:              backendLabels,
+					ExternalTrafficPolicy: tc.trafficPolicy,
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "testing",
+					Name:      "foo",
+					Annotations: map[string]string{
+						hostnameAnnotationKey: "foo.example.org",
+					},
+				},
+				Status: v1.ServiceStatus{
+					LoadBalancer: v1.LoadBalancerStatus{
+						Ingress: []v1.LoadBalancerIngress{{IP: "192.168.10.1"}},
+					},
+				},
+			}
+			_, err := kubernetes.CoreV1().Services(service.Namespace).Create(service)
+			require.NoError(t, err)
+
+			client, err := NewServiceSource(NewInformerFactories(), kubernetes, "testing", "", "", false, "", false, false, []string{}, false, true, false, "", false)
+			require.NoError(t, err)
+
+			var endpoints []*endpoint.Endpoint
+
+			// wait up to a few seconds for the pods and nodes to appear in the informer cache.
+			err = wait.Poll(time.Second, 3*time.Second, func() (bool, error) {
+				endpoints, err = client.Endpoints()
+				if err != nil {
+					return true, err
+				}
+				return len(endpoints) >= len(tc.expected) && len(tc.expected) > 0, nil
+			})
+			if err != wait.ErrWaitTimeout {
+				require.NoError(t, err)
+			}
+
+			validateEndpoints(t, endpoints, tc.expected)
+		})
+	}
+}
+
+func TestMetalLBUnsyncedSpeakersKeepTargets(t *testing.T) {
+	kubernetes := fake.NewSimpleClientset()
+	factory := informers.NewSharedInformerFactory(kubernetes, 0)
+
+	// The informers are never started, so the speakers are unknown.
+	sc := &serviceSource{
+		podInformer:     factory.Core().V1().Pods(),
+		speakerInformer: factory.Core().V1().Pods(),
+		nodeInformer:    factory.Core().V1().Nodes(),
+	}
+	service := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "testing", Name: "foo"},
+	}
+
+	targets := sc.filterMetalLBAnnouncedTargets(service, endpoint.Targets{"192.168.10.1", "lb.example.org"})
+	require.Equal(t, endpoint.Targets{"192.168.10.1", "lb.example.org"}, targets)
+}
+
+func newMetalLBTestPod(namespace, name, nodeName string, podLabels map[string]string, ready bool) *v1.Pod {
+	status := v1.ConditionFalse
+	if ready {
+		status = v1.ConditionTrue
+	}
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+			Labels:    podLabels,
+		},
+		Spec: v1.PodSpec{
+			NodeName: nodeName,
+		},
+		Status: v1.PodStatus{
+			Phase:      v1.PodRunning,
+			Conditions: []v1.PodCondition{{Type: v1.PodReady, Status: status}},
+		},
+	}
+}
@@ -0,0 +1,328 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	kubeinformers "k8s.io/client-go/informers"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+var (
+	serviceExportGVR = schema.GroupVersionResource{Group: "multicluster.x-k8s.io", Version: "v1alpha1", Resource: "serviceexports"}
+	serviceImportGVR = schema.GroupVersionResource{Group: "multicluster.x-k8s.io", Version: "v1alpha1", Resource: "serviceimports"}
+)
+
+// multiClusterServiceSource is an implementation of Source for the Multi-Cluster Services API
+// (e.g. implemented by Submariner). Every cluster exporting a service publishes the global hostnames
+// of its ServiceExport objects pointing at the load balancer addresses of the exported service, so the
+// records of all clusters together lead to every cluster's ingress address. ServiceImport objects
+// are published with their cluster set IPs.
+type multiClusterServiceSource struct {
+	kubeClient               kubernetes.Interface
+	dynamicKubeClient        dynamic.Interface
+	namespace                string
+	annotationFilter         string
+	fqdnTemplate             *template.Template
+	combineFQDNAnnotation    bool
+	ignoreHostnameAnnotation bool
+	serviceInformer          coreinformers.ServiceInformer
+	serviceExportInformer    kubeinformers.GenericInformer
+	serviceImportInformer    kubeinformers.GenericInformer
+}
+
+// NewMultiClusterServiceSource creates a new multiClusterServiceSource with the given config.
+func NewMultiClusterServiceSource(
+	informers *InformerFactories,
+	kubeClient kubernetes.Interface,
+	dynamicKubeClient dynamic.Interface,
+	namespace string,
+	annotationFilter string,
+	fqdnTemplate string,
+	combineFqdnAnnotation bool,
+	ignoreHostnameAnnotation bool,
+) (Source, error) {
+	var (
+		tmpl *template.Template
+		err  error
+	)
+	if fqdnTemplate != "" {
+		tmpl, err = template.New("endpoint").Funcs(template.FuncMap{
+			"trimPrefix": strings.TrimPrefix,
+		}).Parse(fqdnTemplate)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Use shared informers to listen for add/update/delete of services and service exports/imports in the specified namespace.
+	// Set resync period to 0, to prevent processing when nothing has changed.
+	informerFactory := informers.Kube(kubeClient, namespace)
+	serviceInformer := informerFactory.Core().V1().Services()
+	dynamicInformerFactory := informers.Dynamic(dynamicKubeClient, namespace)
+	serviceExportInformer := dynamicInformerFactory.ForResource(serviceExportGVR)
+	serviceImportInformer := dynamicInformerFactory.ForResource(serviceImportGVR)
+
+	// Add default resource event handlers to properly initialize informer.
+	for _, informer := range []cache.SharedIndexInformer{serviceInformer.Informer(), serviceExportInformer.Informer(), serviceImportInformer.Informer()} {
+		informer.AddEventHandler(
+			cache.ResourceEventHandlerFuncs{
+				AddFunc: func(obj interface{}) {
+				},
+			},
+		)
+	}
+
+	// TODO informer is not explicitly stopped since controller is not passing in its channel.
+	informerFactory.Start(wait.NeverStop)
+	dynamicInformerFactory.Start(wait.NeverStop)
+
+	// wait for the local cache to be populated.
+	err = wait.Poll(time.Second, 60*time.Second, func() (bool, error) {
+		return serviceInformer.Informer().HasSynced() &&
+			serviceExportInformer.Informer().HasSynced() &&
+			serviceImportInformer.Informer().HasSynced(), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sync cache: %v", err)
+	}
+
+	return &multiClusterServiceSource{
+		kubeClient:               kubeClient,
+		dynamicKubeClient:        dynamicKubeClient,
+		namespace:                namespace,
+		annotationFilter:         annotationFilter,
+		fqdnTemplate:             tmpl,
+		combineFQDNAnnotation:    combineFqdnAnnotation,
+		ignoreHostnameAnnotation: ignoreHostnameAnnotation,
+		serviceInformer:          serviceInformer,
+		serviceExportInformer:    serviceExportInformer,
+		serviceImportInformer:    serviceImportInformer,
+	}, nil
+}
+
+// Endpoints returns endpoint objects for each ServiceExport and ServiceImport that should be processed.
+func (sc *multiClusterServiceSource) Endpoints() ([]*endpoint.Endpoint, error) {
+	exports, err := sc.listFiltered(sc.serviceExportInformer)
+	if err != nil {
+		return nil, err
+	}
+	imports, err := sc.listFiltered(sc.serviceImportInformer)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := []*endpoint.Endpoint{}
+
+	for _, export := range exports {
+		if !serviceExportValid(export) {
+			log.Debugf("Skipping serviceexport %s/%s because it is not valid", export.GetNamespace(), export.GetName())
+			continue
+		}
+
+		svc, err := sc.serviceInformer.Lister().Services(export.GetNamespace()).Get(export.GetName())
+		if errors.IsNotFound(err) {
+			log.Debugf("Skipping serviceexport %s/%s because the exported service doesn't exist", export.GetNamespace(), export.GetName())
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		exportEndpoints, err := sc.endpointsFromServiceExport(export, svc)
+		if err != nil {
+			return nil, err
+		}
+		if len(exportEndpoints) == 0 {
+			log.Debugf("No endpoints could be generated from serviceexport %s/%s", export.GetNamespace(), export.GetName())
+			continue
+		}
+
+		log.Debugf("Endpoints generated from serviceexport: %s/%s: %v", export.GetNamespace(), export.GetName(), exportEndpoints)
+		setUnstructuredResourceLabel("serviceexport", export, exportEndpoints)
+		endpoints = append(endpoints, exportEndpoints...)
+	}
+
+	for _, imp := range imports {
+		importEndpoints := sc.endpointsFromServiceImport(imp)
+		if len(importEndpoints) == 0 {
+			log.Debugf("No endpoints could be generated from serviceimport %s/%s", imp.GetNamespace(), imp.GetName())
+			continue
+		}
+
+		log.Debugf("Endpoints generated from serviceimport: %s/%s: %v", imp.GetNamespace(), imp.GetName(), importEndpoints)
+		setUnstructuredResourceLabel("serviceimport", imp, importEndpoints)
+		endpoints = append(endpoints, importEndpoints...)
+	}
+
+	for _, ep := range endpoints {
+		sort.Sort(ep.Targets)
+	}
+
+	return endpoints, nil
+}
+
+// endpointsFromServiceExport returns the endpoints of a ServiceExport pointing at the load balancer
+// addresses of the exported service in this cluster unless the target annotation overrides them.
+// The FQDN template is applied to the exported service.
+func (sc *multiClusterServiceSource) endpointsFromServiceExport(export *unstructured.Unstructured, svc *v1.Service) ([]*endpoint.Endpoint, error) {
+	annotations := export.GetAnnotations()
+
+	targets := getTargetsFromTargetAnnotation(annotations)
+	if len(targets) == 0 {
+		targets = extractLoadBalancerTargets(svc)
+	}
+	if len(targets) == 0 {
+		log.Debugf("Skipping serviceexport %s/%s because the exported service has no load balancer address", export.GetNamespace(), export.GetName())
+		return nil, nil
+	}
+
+	var hostnames []string
+	if !sc.ignoreHostnameAnnotation {
+		hostnames = getHostnamesFromAnnotations(annotations)
+	}
+
+	// apply template if no hostname is annotated
+	if (sc.combineFQDNAnnotation || len(hostnames) == 0) && sc.fqdnTemplate != nil {
+		var buf bytes.Buffer
+		if err := sc.fqdnTemplate.Execute(&buf, svc); err != nil {
+			return nil, fmt.Errorf("failed to apply template on serviceexport %s/%s: %v", export.GetNamespace(), export.GetName(), err)
+		}
+		tmplHostnames := strings.Split(strings.Replace(buf.String(), " ", "", -1), ",")
+		if sc.combineFQDNAnnotation {
+			hostnames = append(hostnames, tmplHostnames...)
+		} else {
+			hostnames = tmplHostnames
+		}
+	}
+
+	return endpointsForHostnames(hostnames, targets, annotations, fmt.Sprintf("serviceexport %s/%s", export.GetNamespace(), export.GetName())), nil
+}
+
+// endpointsFromServiceImport returns the endpoints of an annotated ServiceImport pointing at its cluster set IPs.
+// Headless imports don't have any cluster set IP and aren't published.
+func (sc *multiClusterServiceSource) endpointsFromServiceImport(imp *unstructured.Unstructured) []*endpoint.Endpoint {
+	if sc.ignoreHostnameAnnotation {
+		return nil
+	}
+	annotations := imp.GetAnnotations()
+
+	targets := getTargetsFromTargetAnnotation(annotations)
+	if len(targets) == 0 {
+		ips, _, err := unstructured.NestedStringSlice(imp.Object, "spec", "ips")
+		if err != nil {
+			log.Warnf("Unable to read the IPs of serviceimport %s/%s: %v", imp.GetNamespace(), imp.GetName(), err)
+			return nil
+		}
+		for _, ip := range ips {
+			if net.ParseIP(ip) != nil {
+				targets = append(targets, ip)
+			}
+		}
+	}
+
+	return endpointsForHostnames(getHostnamesFromAnnotations(annotations), targets, annotations, fmt.Sprintf("serviceimport %s/%s", imp.GetNamespace(), imp.GetName()))
+}
+
+// listFiltered lists the objects of an informer in the source's namespace(s) matching the annotation filter.
+func (sc *multiClusterServiceSource) listFiltered(informer kubeinformers.GenericInformer) ([]*unstructured.Unstructured, error) {
+	objects, err := informer.Lister().ByNamespace(sc.namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	labelSelector, err := metav1.ParseToLabelSelector(sc.annotationFilter)
+	if err != nil {
+		return nil, err
+	}
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	filteredList := []*unstructured.Unstructured{}
+
+	for _, obj := range objects {
+		u, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+
+		// Check controller annotation to see if we are responsible.
+		controller, ok := u.GetAnnotations()[controllerAnnotationKey]
+		if ok && controller != controllerAnnotationValue {
+			log.Debugf("Skipping %s %s/%s because controller value does not match, found: %s, required: %s",
+				strings.ToLower(u.GetKind()), u.GetNamespace(), u.GetName(), controller, controllerAnnotationValue)
+			continue
+		}
+
+		// include object if its annotations match the selector
+		if selector.Empty() || selector.Matches(labels.Set(u.GetAnnotations())) {
+			filteredList = append(filteredList, u)
+		}
+	}
+
+	return filteredList, nil
+}
+
+func (sc *multiClusterServiceSource) AddEventHandler(handler func() error, stopChan <-chan struct{}, minInterval time.Duration) {
+}
+
+// serviceExportValid returns false if the MCS implementation reports the ServiceExport as invalid,
+// e.g. because the exported service conflicts with the one of another cluster.
+func serviceExportValid(export *unstructured.Unstructured) bool {
+	conditions, _, err := unstructured.NestedSlice(export.Object, "status", "conditions")
+	if err != nil {
+		return false
+	}
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if condition["type"] == "Valid" {
+			return condition["status"] != "False"
+		}
+	}
+	return true
+}
+
+func setUnstructuredResourceLabel(kind string, obj *unstructured.Unstructured, endpoints []*endpoint.Endpoint) {
+	for _, ep := range endpoints {
+		ep.Labels[endpoint.ResourceLabelKey] = fmt.Sprintf("%s/%s/%s", kind, obj.GetNamespace(), obj.GetName())
+	}
+}
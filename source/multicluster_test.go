@@ -0,0 +1,188 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func newTestMultiClusterObject(kind string, annotations map[string]string, fields map[string]interface{}) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: fields}
+	if u.Object == nil {
+		u.Object = map[string]interface{}{}
+	}
+	u.SetAPIVersion("multicluster.x-k8s.io/v1alpha1")
+	u.SetKind(kind)
+	u.SetNamespace("testing")
+	u.SetName("foo")
+	u.SetAnnotations(annotations)
+	return u
+}
+
+func TestServiceExportEndpoints(t *testing.T) {
+	lbService := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "testing", Name: "foo"},
+		Spec:       v1.ServiceSpec{Type: v1.ServiceTypeLoadBalancer},
+		Status: v1.ServiceStatus{
+			LoadBalancer: v1.LoadBalancerStatus{
+				Ingress: []v1.LoadBalancerIngress{{IP: "1.2.3.4"}, {Hostname: "lb.example.com"}},
+			},
+		},
+	}
+	pendingService := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "testing", Name: "foo"},
+		Spec:       v1.ServiceSpec{Type: v1.ServiceTypeLoadBalancer},
+	}
+
+	for _, tc := range []struct {
+		title        string
+		fqdnTemplate string
+		annotations  map[string]string
+		svc          *v1.Service
+		expected     []*endpoint.Endpoint
+	}{
+		{
+			title: "annotated hostname points at the load balancer of the exported service",
+			annotations: map[string]string{
+				hostnameAnnotationKey: "foo.global.example.org",
+			},
+			svc: lbService,
+			expected: []*endpoint.Endpoint{
+				{DNSName: "foo.global.example.org", Targets: endpoint.Targets{"1.2.3.4"}, RecordType: endpoint.RecordTypeA},
+				{DNSName: "foo.global.example.org", Targets: endpoint.Targets{"lb.example.com"}, RecordType: endpoint.RecordTypeCNAME},
+			},
+		},
+		{
+			title: "target annotation overrides the load balancer",
+			annotations: map[string]string{
+				hostnameAnnotationKey: "foo.global.example.org",
+				targetAnnotationKey:   "5.6.7.8",
+			},
+			svc: lbService,
+			expected: []*endpoint.Endpoint{
+				{DNSName: "foo.global.example.org", Targets: endpoint.Targets{"5.6.7.8"}, RecordType: endpoint.RecordTypeA},
+			},
+		},
+		{
+			title:        "template is applied to the exported service",
+			fqdnTemplate: "{{.Name}}.{{.Namespace}}.clusterset.example.org",
+			svc:          lbService,
+			expected: []*endpoint.Endpoint{
+				{DNSName: "foo.testing.clusterset.example.org", Targets: endpoint.Targets{"1.2.3.4"}, RecordType: endpoint.RecordTypeA},
+				{DNSName: "foo.testing.clusterset.example.org", Targets: endpoint.Targets{"lb.example.com"}, RecordType: endpoint.RecordTypeCNAME},
+			},
+		},
+		{
+			title: "nothing is published while the load balancer is pending",
+			annotations: map[string]string{
+				hostnameAnnotationKey: "foo.global.example.org",
+			},
+			svc:      pendingService,
+			expected: []*endpoint.Endpoint{},
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			sc := &multiClusterServiceSource{}
+			if tc.fqdnTemplate != "" {
+				sc.fqdnTemplate = template.Must(template.New("endpoint").Parse(tc.fqdnTemplate))
+			}
+
+			endpoints, err := sc.endpointsFromServiceExport(newTestMultiClusterObject("ServiceExport", tc.annotations, nil), tc.svc)
+			require.NoError(t, err)
+
+			validateEndpoints(t, endpoints, tc.expected)
+		})
+	}
+}
+
+func TestServiceImportEndpoints(t *testing.T) {
+	for _, tc := range []struct {
+		title       string
+		annotations map[string]string
+		fields      map[string]interface{}
+		expected    []*endpoint.Endpoint
+	}{
+		{
+			title: "annotated import points at its cluster set IPs",
+			annotations: map[string]string{
+				hostnameAnnotationKey: "foo.clusterset.example.org",
+			},
+			fields: map[string]interface{}{
+				"spec": map[string]interface{}{"type": "ClusterSetIP", "ips": []interface{}{"10.1.0.1"}},
+			},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "foo.clusterset.example.org", Targets: endpoint.Targets{"10.1.0.1"}, RecordType: endpoint.RecordTypeA},
+			},
+		},
+		{
+			title: "headless import isn't published",
+			annotations: map[string]string{
+				hostnameAnnotationKey: "foo.clusterset.example.org",
+			},
+			fields: map[string]interface{}{
+				"spec": map[string]interface{}{"type": "Headless"},
+			},
+			expected: []*endpoint.Endpoint{},
+		},
+		{
+			title: "import without hostname isn't published",
+			fields: map[string]interface{}{
+				"spec": map[string]interface{}{"type": "ClusterSetIP", "ips": []interface{}{"10.1.0.1"}},
+			},
+			expected: []*endpoint.Endpoint{},
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			sc := &multiClusterServiceSource{}
+
+			endpoints := sc.endpointsFromServiceImport(newTestMultiClusterObject("ServiceImport", tc.annotations, tc.fields))
+
+			validateEndpoints(t, endpoints, tc.expected)
+		})
+	}
+}
+
+func TestServiceExportValid(t *testing.T) {
+	valid := newTestMultiClusterObject("ServiceExport", nil, map[string]interface{}{
+		"status": map[string]interface{}{
+			"conditions": []interface{}{
+				map[string]interface{}{"type": "Valid", "status": "True"},
+			},
+		},
+	})
+	invalid := newTestMultiClusterObject("ServiceExport", nil, map[string]interface{}{
+		"status": map[string]interface{}{
+			"conditions": []interface{}{
+				map[string]interface{}{"type": "Valid", "status": "False"},
+			},
+		},
+	})
+	pending := newTestMultiClusterObject("ServiceExport", nil, nil)
+
+	assert.True(t, serviceExportValid(valid))
+	assert.False(t, serviceExportValid(invalid))
+	assert.True(t, serviceExportValid(pending))
+}
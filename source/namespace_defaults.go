@@ -0,0 +1,116 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	kubeinformers "k8s.io/client-go/informers"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// The annotations a namespace can define the defaults of, in addition to the ones starting with one of
+// the inheritable prefixes
+var (
+	inheritableAnnotationKeys = map[string]bool{
+		targetAnnotationKey:      true,
+		ttlAnnotationKey:         true,
+		aliasAnnotationKey:       true,
+		descriptionAnnotationKey: true,
+		CloudflareProxiedKey:     true,
+		SetIdentifierKey:         true,
+		providerAnnotationKey:    true,
+	}
+	inheritableAnnotationPrefixes = []string{
+		"external-dns.alpha.kubernetes.io/aws-",
+		"external-dns.alpha.kubernetes.io/health-check-",
+	}
+)
+
+// namespaceAnnotationDefaults lets namespaces define the default annotations of the Services and Ingresses
+// in them, i.e. the target, TTL and provider-specific annotations, so platform teams don't have to repeat
+// them on every object. The annotations of an object take precedence over those of its namespace. The
+// hostnames and the other annotations are never inherited.
+type namespaceAnnotationDefaults struct {
+	namespaceInformer coreinformers.NamespaceInformer
+}
+
+// newNamespaceAnnotationDefaults creates a new namespaceAnnotationDefaults with a namespace informer of the
+// informer factory, which has to be started afterwards.
+func newNamespaceAnnotationDefaults(informerFactory kubeinformers.SharedInformerFactory) *namespaceAnnotationDefaults {
+	namespaceInformer := informerFactory.Core().V1().Namespaces()
+
+	// Add default resource event handlers to properly initialize informer.
+	namespaceInformer.Informer().AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+			},
+		},
+	)
+
+	return &namespaceAnnotationDefaults{namespaceInformer: namespaceInformer}
+}
+
+// hasSynced returns true once the namespaces are listed, or right away without namespace defaults.
+func (d *namespaceAnnotationDefaults) hasSynced() bool {
+	return d == nil || d.namespaceInformer.Informer().HasSynced()
+}
+
+// annotations returns the annotations of an object in the namespace with the inheritable annotations of the
+// namespace it doesn't set itself, or nil if it doesn't inherit any.
+func (d *namespaceAnnotationDefaults) annotations(namespace string, annotations map[string]string) map[string]string {
+	if d == nil {
+		return nil
+	}
+	ns, err := d.namespaceInformer.Lister().Get(namespace)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			log.Warnf("Unable to get the annotation defaults of namespace %s: %v", namespace, err)
+		}
+		return nil
+	}
+
+	var merged map[string]string
+	for key, value := range ns.Annotations {
+		if _, ok := annotations[key]; ok || !isInheritableAnnotation(key) {
+			continue
+		}
+		if merged == nil {
+			merged = make(map[string]string, len(annotations)+1)
+			for k, v := range annotations {
+				merged[k] = v
+			}
+		}
+		merged[key] = value
+	}
+	return merged
+}
+
+func isInheritableAnnotation(key string) bool {
+	if inheritableAnnotationKeys[key] {
+		return true
+	}
+	for _, prefix := range inheritableAnnotationPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
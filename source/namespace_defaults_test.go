@@ -0,0 +1,222 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func newTestNamespace(name string, annotations map[string]string) *v1.Namespace {
+	return &v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Annotations: annotations,
+		},
+	}
+}
+
+func TestNamespaceAnnotationDefaults(t *testing.T) {
+	kubernetes := fake.NewSimpleClientset(
+		newTestNamespace("team-a", map[string]string{
+			ttlAnnotationKey:                              "60",
+			targetAnnotationKey:                           "lb.example.org",
+			hostnameAnnotationKey:                         "ns.example.org",
+			"external-dns.alpha.kubernetes.io/aws-weight": "10",
+			"team": "a",
+		}),
+		newTestNamespace("team-b", nil),
+	)
+	informerFactory := kubeinformers.NewSharedInformerFactory(kubernetes, 0)
+	defaults := newNamespaceAnnotationDefaults(informerFactory)
+	informerFactory.Start(wait.NeverStop)
+	require.NoError(t, wait.Poll(100*time.Millisecond, 3*time.Second, func() (bool, error) {
+		return defaults.hasSynced(), nil
+	}))
+
+	for _, tc := range []struct {
+		title       string
+		namespace   string
+		annotations map[string]string
+		expected    map[string]string
+	}{
+		{
+			"the inheritable annotations of the namespace are added",
+			"team-a",
+			map[string]string{hostnameAnnotationKey: "foo.example.org"},
+			map[string]string{
+				hostnameAnnotationKey:                         "foo.example.org",
+				ttlAnnotationKey:                              "60",
+				targetAnnotationKey:                           "lb.example.org",
+				"external-dns.alpha.kubernetes.io/aws-weight": "10",
+			},
+		},
+		{
+			"the annotations of the object take precedence",
+			"team-a",
+			map[string]string{
+				hostnameAnnotationKey: "foo.example.org",
+				ttlAnnotationKey:      "300",
+			},
+			map[string]string{
+				hostnameAnnotationKey:                         "foo.example.org",
+				ttlAnnotationKey:                              "300",
+				targetAnnotationKey:                           "lb.example.org",
+				"external-dns.alpha.kubernetes.io/aws-weight": "10",
+			},
+		},
+		{
+			"nothing is inherited when the object sets all inheritable annotations",
+			"team-a",
+			map[string]string{
+				ttlAnnotationKey:    "300",
+				targetAnnotationKey: "other.example.org",
+				"external-dns.alpha.kubernetes.io/aws-weight": "20",
+			},
+			nil,
+		},
+		{
+			"nothing is inherited from a namespace without annotations",
+			"team-b",
+			map[string]string{hostnameAnnotationKey: "foo.example.org"},
+			nil,
+		},
+		{
+			"nothing is inherited from an unknown namespace",
+			"team-c",
+			map[string]string{hostnameAnnotationKey: "foo.example.org"},
+			nil,
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			assert.Equal(t, tc.expected, defaults.annotations(tc.namespace, tc.annotations))
+		})
+	}
+}
+
+func TestNamespaceAnnotationDefaultsDisabled(t *testing.T) {
+	var defaults *namespaceAnnotationDefaults
+
+	assert.True(t, defaults.hasSynced())
+	assert.Nil(t, defaults.annotations("team-a", map[string]string{hostnameAnnotationKey: "foo.example.org"}))
+}
+
+func TestIsInheritableAnnotation(t *testing.T) {
+	for key, expected := range map[string]bool{
+		ttlAnnotationKey:     true,
+		targetAnnotationKey:  true,
+		CloudflareProxiedKey: true,
+		SetIdentifierKey:     true,
+		"external-dns.alpha.kubernetes.io/aws-weight":        true,
+		"external-dns.alpha.kubernetes.io/health-check-path": true,
+		hostnameAnnotationKey:                                false,
+		controllerAnnotationKey:                              false,
+		"external-dns.alpha.kubernetes.io/internal-hostname": false,
+		"kubernetes.io/ingress.class":                        false,
+	} {
+		assert.Equal(t, expected, isInheritableAnnotation(key), key)
+	}
+}
+
+func TestSourcesInheritNamespaceDefaults(t *testing.T) {
+	kubernetes := fake.NewSimpleClientset(newTestNamespace("team-a", map[string]string{
+		ttlAnnotationKey:    "60",
+		targetAnnotationKey: "1.2.3.4",
+	}))
+
+	for _, svc := range []*v1.Service{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "team-a",
+				Name:        "foo",
+				Annotations: map[string]string{hostnameAnnotationKey: "foo.example.org"},
+			},
+			Spec: v1.ServiceSpec{Type: v1.ServiceTypeLoadBalancer},
+			Status: v1.ServiceStatus{
+				LoadBalancer: v1.LoadBalancerStatus{Ingress: []v1.LoadBalancerIngress{{IP: "8.8.8.8"}}},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "team-a",
+				Name:      "bar",
+				Annotations: map[string]string{
+					hostnameAnnotationKey: "bar.example.org",
+					ttlAnnotationKey:      "300",
+				},
+			},
+			Spec: v1.ServiceSpec{Type: v1.ServiceTypeLoadBalancer},
+			Status: v1.ServiceStatus{
+				LoadBalancer: v1.LoadBalancerStatus{Ingress: []v1.LoadBalancerIngress{{IP: "8.8.8.8"}}},
+			},
+		},
+	} {
+		_, err := kubernetes.CoreV1().Services(svc.Namespace).Create(svc)
+		require.NoError(t, err)
+	}
+	_, err := kubernetes.Extensions().Ingresses("team-a").Create(&v1beta1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "team-a",
+			Name:      "baz",
+		},
+		Spec: v1beta1.IngressSpec{
+			Rules: []v1beta1.IngressRule{{Host: "baz.example.org"}},
+		},
+		Status: v1beta1.IngressStatus{
+			LoadBalancer: v1.LoadBalancerStatus{Ingress: []v1.LoadBalancerIngress{{IP: "8.8.8.8"}}},
+		},
+	})
+	require.NoError(t, err)
+
+	serviceSource, err := NewServiceSource(NewInformerFactories(), kubernetes, "", "", "", false, "", false, false, []string{}, false, false, true, "", false)
+	require.NoError(t, err)
+	endpoints, err := serviceSource.Endpoints()
+	require.NoError(t, err)
+	// the service source doesn't support the target annotation, only the TTL is inherited
+	validateEndpoints(t, endpoints, []*endpoint.Endpoint{
+		{DNSName: "foo.example.org", Targets: endpoint.Targets{"8.8.8.8"}, RecordType: endpoint.RecordTypeA, RecordTTL: 60},
+		{DNSName: "bar.example.org", Targets: endpoint.Targets{"8.8.8.8"}, RecordType: endpoint.RecordTypeA, RecordTTL: 300},
+	})
+
+	ingressSource, err := NewIngressSource(NewInformerFactories(), kubernetes, "", "", "", false, false, true)
+	require.NoError(t, err)
+	endpoints, err = ingressSource.Endpoints()
+	require.NoError(t, err)
+	validateEndpoints(t, endpoints, []*endpoint.Endpoint{
+		{DNSName: "baz.example.org", Targets: endpoint.Targets{"1.2.3.4"}, RecordType: endpoint.RecordTypeA, RecordTTL: 60},
+	})
+
+	serviceSource, err = NewServiceSource(NewInformerFactories(), kubernetes, "", "", "", false, "", false, false, []string{}, false, false, false, "", false)
+	require.NoError(t, err)
+	endpoints, err = serviceSource.Endpoints()
+	require.NoError(t, err)
+	validateEndpoints(t, endpoints, []*endpoint.Endpoint{
+		{DNSName: "foo.example.org", Targets: endpoint.Targets{"8.8.8.8"}, RecordType: endpoint.RecordTypeA},
+		{DNSName: "bar.example.org", Targets: endpoint.Targets{"8.8.8.8"}, RecordType: endpoint.RecordTypeA, RecordTTL: 300},
+	})
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// The annotation of a namespace naming the subzone created for it, e.g. team-a.example.org
+const subzoneAnnotationKey = "external-dns.alpha.kubernetes.io/subzone"
+
+// NamespaceSubzoneLister lists the subzones requested by annotated namespaces, so teams get their own
+// zone delegated from the parent zone as a self-service DNS boundary.
+type NamespaceSubzoneLister struct {
+	client kubernetes.Interface
+}
+
+// NewNamespaceSubzoneLister creates a new NamespaceSubzoneLister listing the namespaces with the client.
+func NewNamespaceSubzoneLister(kubeClient kubernetes.Interface) *NamespaceSubzoneLister {
+	return &NamespaceSubzoneLister{client: kubeClient}
+}
+
+// Subzones returns the sorted, distinct subzones of the annotated namespaces.
+func (l *NamespaceSubzoneLister) Subzones() ([]string, error) {
+	namespaces, err := l.client.CoreV1().Namespaces().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	var subzones []string
+	for _, ns := range namespaces.Items {
+		value, ok := ns.Annotations[subzoneAnnotationKey]
+		if !ok {
+			continue
+		}
+		subzone := strings.ToLower(strings.Trim(strings.TrimSpace(value), "."))
+		if subzone == "" || !strings.Contains(subzone, ".") {
+			log.Warnf("Skipping namespace %s because its subzone %q isn't a domain", ns.Name, value)
+			continue
+		}
+		if seen[subzone] {
+			continue
+		}
+		seen[subzone] = true
+		subzones = append(subzones, subzone)
+	}
+	sort.Strings(subzones)
+
+	return subzones, nil
+}
@@ -0,0 +1,48 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestNamespaceSubzoneLister(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	for name, annotations := range map[string]map[string]string{
+		"team-a":   {subzoneAnnotationKey: "Team-A.example.org."},
+		"team-a-2": {subzoneAnnotationKey: "team-a.example.org"},
+		"team-b":   {subzoneAnnotationKey: " team-b.example.org "},
+		"invalid":  {subzoneAnnotationKey: "localhost"},
+		"empty":    {subzoneAnnotationKey: ""},
+		"default":  nil,
+	} {
+		_, err := client.CoreV1().Namespaces().Create(&v1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: annotations},
+		})
+		require.NoError(t, err)
+	}
+
+	subzones, err := NewNamespaceSubzoneLister(client).Subzones()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"team-a.example.org", "team-b.example.org"}, subzones)
+}
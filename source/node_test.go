@@ -47,6 +47,7 @@ func testNodeSourceNewNodeSource(t *testing.T) {
 	} {
 		t.Run(ti.title, func(t *testing.T) {
 			_, err := NewNodeSource(
+				NewInformerFactories(),
 				fake.NewSimpleClientset(),
 				ti.annotationFilter,
 				ti.fqdnTemplate,
@@ -311,6 +312,7 @@ func testNodeSourceEndpoints(t *testing.T) {
 
 			// Create our object under test and get the endpoints.
 			client, err := NewNodeSource(
+				NewInformerFactories(),
 				kubernetes,
 				tc.annotationFilter,
 				tc.fqdnTemplate,
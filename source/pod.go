@@ -0,0 +1,264 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// podSource is an implementation of Source for bare pods annotated with a hostname, e.g. the pods of an
+// ingress controller DaemonSet using the host network without a Service in front. A pod is published
+// with its pod IPs; a pod using the host network is published with the external IPs of its node, as its
+// pod IP is the internal IP of the node. The hostname of pods sharing it points at all of them. IPv4
+// addresses are published as A and IPv6 addresses as AAAA records.
+type podSource struct {
+	client           kubernetes.Interface
+	namespace        string
+	annotationFilter string
+	podInformer      coreinformers.PodInformer
+	nodeInformer     coreinformers.NodeInformer
+}
+
+// NewPodSource creates a new podSource with the given config.
+func NewPodSource(informers *InformerFactories, kubeClient kubernetes.Interface, namespace, annotationFilter string) (Source, error) {
+	// Use shared informers to listen for add/update/delete of pods/nodes in the specified namespace.
+	// Set resync period to 0, to prevent processing when nothing has changed.
+	informerFactory := informers.Kube(kubeClient, namespace)
+	podInformer := informerFactory.Core().V1().Pods()
+	nodeInformer := informerFactory.Core().V1().Nodes()
+
+	// Add default resource event handlers to properly initialize informer.
+	for _, informer := range []cache.SharedIndexInformer{podInformer.Informer(), nodeInformer.Informer()} {
+		informer.AddEventHandler(
+			cache.ResourceEventHandlerFuncs{
+				AddFunc: func(obj interface{}) {
+				},
+			},
+		)
+	}
+
+	// TODO informer is not explicitly stopped since controller is not passing in its channel.
+	informerFactory.Start(wait.NeverStop)
+
+	// wait for the local cache to be populated.
+	err := wait.Poll(time.Second, 60*time.Second, func() (bool, error) {
+		return podInformer.Informer().HasSynced() && nodeInformer.Informer().HasSynced(), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sync cache: %v", err)
+	}
+
+	return &podSource{
+		client:           kubeClient,
+		namespace:        namespace,
+		annotationFilter: annotationFilter,
+		podInformer:      podInformer,
+		nodeInformer:     nodeInformer,
+	}, nil
+}
+
+// Endpoints returns endpoint objects for the hostnames of each annotated running pod.
+func (sc *podSource) Endpoints() ([]*endpoint.Endpoint, error) {
+	pods, err := sc.podInformer.Lister().Pods(sc.namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	pods, err = sc.filterByAnnotations(pods)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(pods, func(i, j int) bool {
+		if pods[i].Namespace != pods[j].Namespace {
+			return pods[i].Namespace < pods[j].Namespace
+		}
+		return pods[i].Name < pods[j].Name
+	})
+
+	// the first pod of a hostname defines its TTL, provider-specific properties and resource
+	firstPods := map[string]*v1.Pod{}
+	targets := map[string]endpoint.Targets{}
+	seen := map[string]bool{}
+	var hostnames []string
+
+	for _, pod := range pods {
+		// Check controller annotation to see if we are responsible.
+		controller, ok := pod.Annotations[controllerAnnotationKey]
+		if ok && controller != controllerAnnotationValue {
+			log.Debugf("Skipping pod %s/%s because controller value does not match, found: %s, required: %s",
+				pod.Namespace, pod.Name, controller, controllerAnnotationValue)
+			continue
+		}
+		if pod.DeletionTimestamp != nil || pod.Status.Phase != v1.PodRunning {
+			log.Debugf("Skipping pod %s/%s because it isn't running", pod.Namespace, pod.Name)
+			continue
+		}
+
+		podHostnames := getHostnamesFromAnnotations(pod.Annotations)
+		if len(podHostnames) == 0 {
+			continue
+		}
+		podTargets := sc.podTargets(pod)
+		if len(podTargets) == 0 {
+			log.Debugf("Skipping pod %s/%s because it has no address", pod.Namespace, pod.Name)
+			continue
+		}
+
+		for _, hostname := range podHostnames {
+			if hostname == "" {
+				continue
+			}
+			if _, ok := firstPods[hostname]; !ok {
+				firstPods[hostname] = pod
+				hostnames = append(hostnames, hostname)
+			}
+			for _, target := range podTargets {
+				if !seen[hostname+"/"+target] {
+					seen[hostname+"/"+target] = true
+					targets[hostname] = append(targets[hostname], target)
+				}
+			}
+		}
+	}
+
+	endpoints := []*endpoint.Endpoint{}
+	for _, hostname := range hostnames {
+		pod := firstPods[hostname]
+		podEndpoints := addressEndpoints(hostname, targets[hostname], pod.Annotations, fmt.Sprintf("pod %s/%s", pod.Namespace, pod.Name))
+		log.Debugf("Endpoints generated from pod: %s/%s: %v", pod.Namespace, pod.Name, podEndpoints)
+		for _, ep := range podEndpoints {
+			ep.Labels[endpoint.ResourceLabelKey] = fmt.Sprintf("pod/%s/%s", pod.Namespace, pod.Name)
+		}
+		endpoints = append(endpoints, podEndpoints...)
+	}
+
+	for _, ep := range endpoints {
+		sort.Sort(ep.Targets)
+	}
+
+	return endpoints, nil
+}
+
+// podTargets returns the target annotation of a pod, the external IPs of its node if it uses the host
+// network or otherwise its pod IP.
+func (sc *podSource) podTargets(pod *v1.Pod) endpoint.Targets {
+	if targets := getTargetsFromTargetAnnotation(pod.Annotations); len(targets) > 0 {
+		return targets
+	}
+
+	if pod.Spec.HostNetwork {
+		if pod.Spec.NodeName == "" {
+			return nil
+		}
+		node, err := sc.nodeInformer.Lister().Get(pod.Spec.NodeName)
+		if err != nil {
+			log.Debugf("Unable to find node %s of pod %s/%s", pod.Spec.NodeName, pod.Namespace, pod.Name)
+			return nil
+		}
+		var targets endpoint.Targets
+		for _, address := range node.Status.Addresses {
+			if address.Type == v1.NodeExternalIP {
+				targets = append(targets, address.Address)
+			}
+		}
+		return targets
+	}
+
+	if pod.Status.PodIP == "" {
+		return nil
+	}
+	return endpoint.Targets{pod.Status.PodIP}
+}
+
+// addressEndpoints returns the A record of the IPv4 and the AAAA record of the IPv6 addresses of the
+// targets, taking the TTL and provider-specific annotations into account.
+func addressEndpoints(hostname string, targets endpoint.Targets, annotations map[string]string, resource string) []*endpoint.Endpoint {
+	ttl, err := getTTLFromAnnotations(annotations)
+	if err != nil {
+		log.Warnf("Unable to use the TTL of %s: %v", resource, err)
+	}
+	providerSpecific, setIdentifier := getProviderSpecificAnnotations(annotations)
+
+	byType := map[string]endpoint.Targets{}
+	for _, target := range targets {
+		ip := net.ParseIP(target)
+		switch {
+		case ip == nil:
+			log.Debugf("Skipping target %s of %s because it isn't an IP address", target, resource)
+		case ip.To4() != nil:
+			byType[endpoint.RecordTypeA] = append(byType[endpoint.RecordTypeA], target)
+		default:
+			byType[endpoint.RecordTypeAAAA] = append(byType[endpoint.RecordTypeAAAA], target)
+		}
+	}
+
+	var endpoints []*endpoint.Endpoint
+	for _, recordType := range []string{endpoint.RecordTypeA, endpoint.RecordTypeAAAA} {
+		if len(byType[recordType]) == 0 {
+			continue
+		}
+		ep := endpoint.NewEndpointWithTTL(hostname, recordType, ttl, byType[recordType]...)
+		ep.ProviderSpecific = providerSpecific
+		ep.SetIdentifier = setIdentifier
+		endpoints = append(endpoints, ep)
+	}
+	return endpoints
+}
+
+// filterByAnnotations filters a list of pods by a given annotation selector.
+func (sc *podSource) filterByAnnotations(pods []*v1.Pod) ([]*v1.Pod, error) {
+	labelSelector, err := metav1.ParseToLabelSelector(sc.annotationFilter)
+	if err != nil {
+		return nil, err
+	}
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	// empty filter returns original list
+	if selector.Empty() {
+		return pods, nil
+	}
+
+	filteredList := []*v1.Pod{}
+
+	for _, pod := range pods {
+		// include pod if its annotations match the selector
+		if selector.Matches(labels.Set(pod.Annotations)) {
+			filteredList = append(filteredList, pod)
+		}
+	}
+
+	return filteredList, nil
+}
+
+func (sc *podSource) AddEventHandler(handler func() error, stopChan <-chan struct{}, minInterval time.Duration) {
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestPodEndpoints(t *testing.T) {
+	newPod := func(name string, annotations map[string]string, hostNetwork bool, nodeName string, phase v1.PodPhase, podIP string) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "testing", Name: name, Annotations: annotations},
+			Spec:       v1.PodSpec{HostNetwork: hostNetwork, NodeName: nodeName},
+			Status:     v1.PodStatus{Phase: phase, PodIP: podIP},
+		}
+	}
+	ingress := map[string]string{hostnameAnnotationKey: "ingress.example.org", ttlAnnotationKey: "60"}
+
+	kubernetes := fake.NewSimpleClientset()
+	for _, pod := range []*v1.Pod{
+		// host network pods of an ingress DaemonSet are published with the external IPs of their nodes
+		newPod("ingress-a", ingress, true, "node-a", v1.PodRunning, "10.0.0.1"),
+		newPod("ingress-b", ingress, true, "node-b", v1.PodRunning, "10.0.0.2"),
+		newPod("ingress-c", ingress, true, "node-c", v1.PodPending, ""),
+		// other pods are published with their pod IPs
+		newPod("ipv4", map[string]string{hostnameAnnotationKey: "ipv4.example.org"}, false, "node-a", v1.PodRunning, "10.1.0.1"),
+		newPod("ipv6", map[string]string{hostnameAnnotationKey: "ipv6.example.org"}, false, "node-a", v1.PodRunning, "2001:db8::1"),
+		newPod("target", map[string]string{hostnameAnnotationKey: "target.example.org", targetAnnotationKey: "5.6.7.8"}, false, "node-a", v1.PodRunning, "10.1.0.2"),
+		newPod("unannotated", nil, false, "node-a", v1.PodRunning, "10.1.0.3"),
+		newPod("other-controller", map[string]string{hostnameAnnotationKey: "other.example.org", controllerAnnotationKey: "other"}, false, "node-a", v1.PodRunning, "10.1.0.4"),
+	} {
+		_, err := kubernetes.CoreV1().Pods(pod.Namespace).Create(pod)
+		require.NoError(t, err)
+	}
+
+	for name, ip := range map[string]string{"node-a": "1.1.1.1", "node-b": "2.2.2.2", "node-c": "3.3.3.3"} {
+		node := &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: v1.NodeStatus{
+				Addresses: []v1.NodeAddress{
+					{Type: v1.NodeInternalIP, Address: "10.0.0.1"},
+					{Type: v1.NodeExternalIP, Address: ip},
+				},
+			},
+		}
+		_, err := kubernetes.CoreV1().Nodes().Create(node)
+		require.NoError(t, err)
+	}
+
+	client, err := NewPodSource(NewInformerFactories(), kubernetes, "", "")
+	require.NoError(t, err)
+
+	endpoints, err := client.Endpoints()
+	require.NoError(t, err)
+
+	validateEndpoints(t, endpoints, []*endpoint.Endpoint{
+		{DNSName: "ipv4.example.org", Targets: endpoint.Targets{"10.1.0.1"}, RecordType: endpoint.RecordTypeA},
+		{DNSName: "ipv6.example.org", Targets: endpoint.Targets{"2001:db8::1"}, RecordType: endpoint.RecordTypeAAAA},
+		{DNSName: "ingress.example.org", Targets: endpoint.Targets{"1.1.1.1", "2.2.2.2"}, RecordType: endpoint.RecordTypeA, RecordTTL: 60},
+		{DNSName: "target.example.org", Targets: endpoint.Targets{"5.6.7.8"}, RecordType: endpoint.RecordTypeA},
+	})
+	for _, ep := range endpoints {
+		if ep.DNSName == "ingress.example.org" {
+			require.Equal(t, "pod/testing/ingress-a", ep.Labels[endpoint.ResourceLabelKey])
+		}
+	}
+}
+
+func TestPodSourceAnnotationFilter(t *testing.T) {
+	kubernetes := fake.NewSimpleClientset()
+	for name, team := range map[string]string{"web": "web", "db": "db"} {
+		pod := &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "testing", Name: name, Annotations: map[string]string{
+				hostnameAnnotationKey: name + ".example.org",
+				"team":                team,
+			}},
+			Status: v1.PodStatus{Phase: v1.PodRunning, PodIP: "10.1.0.1"},
+		}
+		_, err := kubernetes.CoreV1().Pods(pod.Namespace).Create(pod)
+		require.NoError(t, err)
+	}
+
+	client, err := NewPodSource(NewInformerFactories(), kubernetes, "testing", "team=web")
+	require.NoError(t, err)
+
+	endpoints, err := client.Endpoints()
+	require.NoError(t, err)
+
+	validateEndpoints(t, endpoints, []*endpoint.Endpoint{
+		{DNSName: "web.example.org", Targets: endpoint.Targets{"10.1.0.1"}, RecordType: endpoint.RecordTypeA},
+	})
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+const (
+	// The annotation used for selecting the pipeline the records of a resource are published with
+	providerAnnotationKey = "external-dns.alpha.kubernetes.io/provider"
+
+	// The provider-specific property carrying the provider annotation to the providerSelectionSource
+	providerSelectionProperty = "provider-selection"
+)
+
+// providerSelectionSource is a Source for the pipelines of --pipeline, e.g. one pipeline per DNS provider
+// with overlapping domain filters. A resource annotated with the provider annotation is only published by
+// the pipeline of that name, so it can choose the backend of its records among the pipelines whose domain
+// filters include its hostnames. The endpoints of resources without the annotation are published by all
+// pipelines as before. Without pipelines, or naming no pipeline, the annotation is ignored with a warning.
+type providerSelectionSource struct {
+	source    Source
+	pipeline  string
+	pipelines map[string]bool
+	// matchDomain tells if the domain filter of the pipeline includes a hostname
+	matchDomain func(string) bool
+
+	sync.Mutex
+	// the unknown values that were warned about
+	warned map[string]bool
+}
+
+// NewProviderSelectionSource creates a new providerSelectionSource wrapping the provided Source for the
+// given pipeline out of the given pipelines of the process, keeping the endpoints without a provider
+// annotation and those selecting the pipeline.
+func NewProviderSelectionSource(source Source, pipeline string, pipelines []string, matchDomain func(string) bool) Source {
+	names := make(map[string]bool, len(pipelines))
+	for _, name := range pipelines {
+		names[strings.ToLower(name)] = true
+	}
+	return &providerSelectionSource{
+		source:      source,
+		pipeline:    strings.ToLower(pipeline),
+		pipelines:   names,
+		matchDomain: matchDomain,
+		warned:      map[string]bool{},
+	}
+}
+
+// Endpoints collects endpoints from its wrapped source and drops the ones selecting another pipeline.
+func (ps *providerSelectionSource) Endpoints() ([]*endpoint.Endpoint, error) {
+	endpoints, err := ps.source.Endpoints()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*endpoint.Endpoint, 0, len(endpoints))
+	for _, ep := range endpoints {
+		if selected, ok := ep.GetProviderSpecificProperty(providerSelectionProperty); ok {
+			ep.ProviderSpecific = withoutProviderSelectionProperty(ep.ProviderSpecific)
+			switch {
+			case !ps.pipelines[selected.Value]:
+				ps.warnUnknown(selected.Value)
+			case selected.Value != ps.pipeline:
+				log.Debugf("Skipping endpoint %v because it selects the %s pipeline", ep, selected.Value)
+				continue
+			case !ps.matchDomain(ep.DNSName):
+				log.Warnf("Endpoint %v selects the %s pipeline, but its domain filter doesn't include the hostname", ep, selected.Value)
+			}
+		}
+		result = append(result, ep)
+	}
+
+	return result, nil
+}
+
+// warnUnknown warns once about a value of the provider annotation which isn't the name of a pipeline.
+func (ps *providerSelectionSource) warnUnknown(value string) {
+	ps.Lock()
+	defer ps.Unlock()
+	if ps.warned[value] {
+		return
+	}
+	ps.warned[value] = true
+	if len(ps.pipelines) == 0 {
+		log.Warnf("Ignoring the %s annotation %q, it selects a pipeline of --pipeline", providerAnnotationKey, value)
+		return
+	}
+	log.Warnf("Ignoring the %s annotation %q, it isn't the name of a pipeline", providerAnnotationKey, value)
+}
+
+func (ps *providerSelectionSource) AddEventHandler(handler func() error, stopChan <-chan struct{}, minInterval time.Duration) {
+	ps.source.AddEventHandler(handler, stopChan, minInterval)
+}
+
+// withoutProviderSelectionProperty returns a copy of the provider-specific properties without the provider
+// selection, which isn't meant for the providers.
+func withoutProviderSelectionProperty(providerSpecific endpoint.ProviderSpecific) endpoint.ProviderSpecific {
+	result := endpoint.ProviderSpecific{}
+	for _, prop := range providerSpecific {
+		if prop.Name != providerSelectionProperty {
+			result = append(result, prop)
+		}
+	}
+	return result
+}
+
+// getProviderSelectionFromAnnotations returns the provider-specific property carrying the provider annotation.
+func getProviderSelectionFromAnnotations(annotations map[string]string) endpoint.ProviderSpecific {
+	provider := strings.ToLower(strings.TrimSpace(annotations[providerAnnotationKey]))
+	if provider == "" {
+		return nil
+	}
+	return endpoint.ProviderSpecific{{Name: providerSelectionProperty, Value: provider}}
+}
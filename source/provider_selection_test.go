@@ -0,0 +1,120 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/internal/testutils"
+)
+
+// Validates that providerSelectionSource is a Source
+var _ Source = &providerSelectionSource{}
+
+// matchAll is the domain filter of the pipelines of the tests without one.
+func matchAll(string) bool { return true }
+
+func TestProviderSelectionSourceEndpoints(t *testing.T) {
+	mockSource := new(testutils.MockSource)
+	mockSource.On("Endpoints").Return([]*endpoint.Endpoint{
+		{DNSName: "api.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}},
+		{DNSName: "www.example.org", RecordType: endpoint.RecordTypeCNAME, Targets: endpoint.Targets{"lb.example.org"}, ProviderSpecific: endpoint.ProviderSpecific{
+			{Name: providerSelectionProperty, Value: "edge"},
+		}},
+		{DNSName: "shop.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}, ProviderSpecific: endpoint.ProviderSpecific{
+			{Name: "aws/weight", Value: "10"},
+			{Name: providerSelectionProperty, Value: "public"},
+		}},
+		{DNSName: "typo.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}, ProviderSpecific: endpoint.ProviderSpecific{
+			{Name: providerSelectionProperty, Value: "aws"},
+		}},
+	}, nil)
+
+	// the value is the name of the pipeline, unknown values are ignored
+	endpoints, err := NewProviderSelectionSource(mockSource, "Public", []string{"Public", "edge"}, matchAll).Endpoints()
+	require.NoError(t, err)
+	assert.Equal(t, []*endpoint.Endpoint{
+		{DNSName: "api.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}},
+		{DNSName: "shop.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}, ProviderSpecific: endpoint.ProviderSpecific{
+			{Name: "aws/weight", Value: "10"},
+		}},
+		{DNSName: "typo.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}, ProviderSpecific: endpoint.ProviderSpecific{}},
+	}, endpoints)
+}
+
+func TestProviderSelectionSourceWithoutPipelines(t *testing.T) {
+	mockSource := new(testutils.MockSource)
+	mockSource.On("Endpoints").Return([]*endpoint.Endpoint{
+		{DNSName: "www.example.org", RecordType: endpoint.RecordTypeCNAME, Targets: endpoint.Targets{"lb.example.org"}, ProviderSpecific: endpoint.ProviderSpecific{
+			{Name: providerSelectionProperty, Value: "edge"},
+		}},
+	}, nil)
+
+	endpoints, err := NewProviderSelectionSource(mockSource, "", nil, matchAll).Endpoints()
+	require.NoError(t, err)
+	assert.Equal(t, []*endpoint.Endpoint{
+		{DNSName: "www.example.org", RecordType: endpoint.RecordTypeCNAME, Targets: endpoint.Targets{"lb.example.org"}, ProviderSpecific: endpoint.ProviderSpecific{}},
+	}, endpoints)
+}
+
+func TestProviderSelectionSourceHealthChecks(t *testing.T) {
+	healthCheck := (&endpoint.HealthCheck{Protocol: endpoint.HealthCheckProtocolTCP, Port: 5432, Interval: 10, FailureThreshold: 3}).ProviderSpecific()
+	newEndpoints := func() []*endpoint.Endpoint {
+		return []*endpoint.Endpoint{
+			{DNSName: "db.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}, ProviderSpecific: append(endpoint.ProviderSpecific{
+				{Name: "aws/weight", Value: "10"},
+			}, healthCheck...)},
+		}
+	}
+
+	mockSource := new(testutils.MockSource)
+	mockSource.On("Endpoints").Return(newEndpoints(), nil).Once()
+	endpoints, err := NewProviderSelectionSource(mockSource, "public", []string{"public", "edge"}, matchAll).Endpoints()
+	require.NoError(t, err)
+	assert.Equal(t, newEndpoints(), endpoints)
+
+	// the health checks are kept for the other pipelines too, whose providers may ignore them
+	mockSource.On("Endpoints").Return(newEndpoints(), nil).Once()
+	endpoints, err = NewProviderSelectionSource(mockSource, "edge", []string{"public", "edge"}, matchAll).Endpoints()
+	require.NoError(t, err)
+	assert.Equal(t, newEndpoints(), endpoints)
+}
+
+func TestProviderSelectionSourceError(t *testing.T) {
+	mockSource := new(testutils.MockSource)
+	mockSource.On("Endpoints").Return([]*endpoint.Endpoint(nil), errors.New("source failed"))
+
+	_, err := NewProviderSelectionSource(mockSource, "public", []string{"public", "edge"}, matchAll).Endpoints()
+	assert.EqualError(t, err, "source failed")
+}
+
+func TestGetProviderSelectionFromAnnotations(t *testing.T) {
+	assert.Nil(t, getProviderSelectionFromAnnotations(map[string]string{}))
+	assert.Nil(t, getProviderSelectionFromAnnotations(map[string]string{providerAnnotationKey: " "}))
+	assert.Equal(t,
+		endpoint.ProviderSpecific{{Name: providerSelectionProperty, Value: "google"}},
+		getProviderSelectionFromAnnotations(map[string]string{providerAnnotationKey: " Google"}),
+	)
+
+	providerSpecific, _ := getProviderSpecificAnnotations(map[string]string{providerAnnotationKey: "google"})
+	assert.Equal(t, endpoint.ProviderSpecific{{Name: providerSelectionProperty, Value: "google"}}, providerSpecific)
+}
@@ -0,0 +1,234 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kubeinformers "k8s.io/client-go/informers"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// The annotation used for defining the number of ready endpoints required before records are published
+	minReadyEndpointsAnnotationKey = "external-dns.alpha.kubernetes.io/min-ready-endpoints"
+	// The annotation used for withdrawing the records once no endpoint is ready anymore
+	withdrawWhenUnreadyAnnotationKey = "external-dns.alpha.kubernetes.io/withdraw-when-unready"
+	// The annotation used for only counting the endpoints whose pods have the given condition, e.g. the
+	// readiness gate of the load balancer health check
+	readyConditionAnnotationKey = "external-dns.alpha.kubernetes.io/ready-condition"
+	// The annotation ExternalDNS sets on the resources which passed the gate
+	readinessGateOpenedAnnotationKey = "external-dns.alpha.kubernetes.io/readiness-gate-opened"
+)
+
+// readinessGate holds back the records of a resource annotated with min-ready-endpoints until its
+// backend services have at least that many ready endpoints, i.e. the pods the load balancer health
+// checks would consider healthy. With the ready-condition annotation only the endpoints whose pods have
+// the condition are counted, e.g. the cloud.google.com/load-balancer-neg-ready readiness gate of GKE.
+// Once the gate has opened the records are kept, unless the resource is annotated with
+// withdraw-when-unready and no endpoint is ready anymore, which allows DNS-based failover. Which
+// resources passed the gate is recorded with the readiness-gate-opened annotation on the resource, so
+// the records survive a restart.
+type readinessGate struct {
+	informerFactory kubeinformers.SharedInformerFactory
+	// patch applies a merge patch to the resource of the given namespace and name
+	patch func(namespace, name string, data []byte) error
+
+	sync.Mutex
+	// the informers are only started for the first gated resource, so the endpoints and pods are
+	// neither cached nor need permissions without gated resources
+	endpointsInformer coreinformers.EndpointsInformer
+	podInformer       coreinformers.PodInformer
+	// the states which were recorded but aren't seen by the informer of the resources yet, or couldn't
+	// be recorded, by the UIDs of their resources
+	pending map[types.UID]bool
+	seen    map[types.UID]bool
+}
+
+func newReadinessGate(informerFactory kubeinformers.SharedInformerFactory, patch func(namespace, name string, data []byte) error) *readinessGate {
+	return &readinessGate{
+		informerFactory: informerFactory,
+		patch:           patch,
+		pending:         map[types.UID]bool{},
+		seen:            map[types.UID]bool{},
+	}
+}
+
+// hasSynced starts the informers of the endpoints and pods if needed and returns true once they're listed.
+func (g *readinessGate) hasSynced() bool {
+	g.Lock()
+	defer g.Unlock()
+	if g.endpointsInformer == nil {
+		g.endpointsInformer = g.informerFactory.Core().V1().Endpoints()
+		g.podInformer = g.informerFactory.Core().V1().Pods()
+
+		// Add default resource event handlers to properly initialize informer.
+		g.endpointsInformer.Informer().AddEventHandler(
+			cache.ResourceEventHandlerFuncs{
+				AddFunc: func(obj interface{}) {
+				},
+			},
+		)
+		g.podInformer.Informer().AddEventHandler(
+			cache.ResourceEventHandlerFuncs{
+				AddFunc: func(obj interface{}) {
+				},
+			},
+		)
+		g.informerFactory.Start(wait.NeverStop)
+	}
+	return g.endpointsInformer.Informer().HasSynced() && g.podInformer.Informer().HasSynced()
+}
+
+// open returns true if the records of a resource routing traffic to the given services may be published.
+func (g *readinessGate) open(obj metav1.Object, namespace string, services []string) bool {
+	value, ok := obj.GetAnnotations()[minReadyEndpointsAnnotationKey]
+	if !ok {
+		return true
+	}
+	minReady, err := strconv.Atoi(value)
+	if err != nil || minReady < 0 {
+		log.Warnf("Ignoring invalid %s annotation %q of %s/%s", minReadyEndpointsAnnotationKey, value, obj.GetNamespace(), obj.GetName())
+		return true
+	}
+
+	opened := g.opened(obj)
+	if !g.hasSynced() {
+		log.Debugf("Keeping the state of the readiness gate of %s/%s until the endpoints are listed", obj.GetNamespace(), obj.GetName())
+		return opened
+	}
+	ready, err := g.readyEndpoints(namespace, services, obj.GetAnnotations()[readyConditionAnnotationKey])
+	if err != nil {
+		// keep the current state rather than flapping the records on errors
+		log.Errorf("Unable to count the ready endpoints of %s/%s: %v", obj.GetNamespace(), obj.GetName(), err)
+		return opened
+	}
+
+	if ready >= minReady {
+		if !opened {
+			g.record(obj, true)
+		}
+		return true
+	}
+	if !opened {
+		log.Debugf("Holding back the records of %s/%s until %d endpoints are ready, found %d", obj.GetNamespace(), obj.GetName(), minReady, ready)
+		return false
+	}
+	if ready == 0 && obj.GetAnnotations()[withdrawWhenUnreadyAnnotationKey] == "true" {
+		log.Infof("Withdrawing the records of %s/%s because no endpoint is ready", obj.GetNamespace(), obj.GetName())
+		g.record(obj, false)
+		return false
+	}
+	return true
+}
+
+// opened returns true if the resource passed the gate, according to its annotation or the state which
+// isn't seen by the informer yet.
+func (g *readinessGate) opened(obj metav1.Object) bool {
+	annotated := obj.GetAnnotations()[readinessGateOpenedAnnotationKey] == "true"
+
+	g.Lock()
+	defer g.Unlock()
+	g.seen[obj.GetUID()] = true
+	pending, ok := g.pending[obj.GetUID()]
+	if !ok {
+		return annotated
+	}
+	if pending == annotated {
+		delete(g.pending, obj.GetUID())
+	}
+	return pending
+}
+
+// record annotates the resource with the state of its gate. The state is kept in memory until the
+// informer sees the annotation, or only in memory if the resource can't be patched.
+func (g *readinessGate) record(obj metav1.Object, opened bool) {
+	value := "null"
+	if opened {
+		value = `"true"`
+	}
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:%s}}}`, readinessGateOpenedAnnotationKey, value)
+	if err := g.patch(obj.GetNamespace(), obj.GetName(), []byte(patch)); err != nil {
+		log.Warnf("Unable to record the readiness gate of %s/%s, it's passed again after a restart: %v", obj.GetNamespace(), obj.GetName(), err)
+	}
+
+	g.Lock()
+	defer g.Unlock()
+	g.pending[obj.GetUID()] = opened
+}
+
+// prune forgets the states of the resources which weren't gated since the last call, e.g. because they
+// were deleted. It's called after every listing of the resources.
+func (g *readinessGate) prune() {
+	g.Lock()
+	defer g.Unlock()
+	for uid := range g.pending {
+		if !g.seen[uid] {
+			delete(g.pending, uid)
+		}
+	}
+	g.seen = map[types.UID]bool{}
+}
+
+// readyEndpoints returns the number of ready addresses of the endpoints of the given services. With a
+// condition, only the addresses of the pods with the condition are counted.
+func (g *readinessGate) readyEndpoints(namespace string, services []string, condition string) (int, error) {
+	ready := 0
+	for _, name := range services {
+		endpoints, err := g.endpointsInformer.Lister().Endpoints(namespace).Get(name)
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		for _, subset := range endpoints.Subsets {
+			for _, address := range subset.Addresses {
+				if condition == "" || g.podHasCondition(address.TargetRef, condition) {
+					ready++
+				}
+			}
+		}
+	}
+	return ready, nil
+}
+
+// podHasCondition returns true if the pod of an endpoint address has the given condition.
+func (g *readinessGate) podHasCondition(ref *v1.ObjectReference, condition string) bool {
+	if ref == nil || ref.Kind != "Pod" {
+		return false
+	}
+	pod, err := g.podInformer.Lister().Pods(ref.Namespace).Get(ref.Name)
+	if err != nil {
+		return false
+	}
+	for _, c := range pod.Status.Conditions {
+		if string(c.Type) == condition {
+			return c.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
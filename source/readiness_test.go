@@ -0,0 +1,263 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestReadinessGate(t *testing.T) {
+	for _, tc := range []struct {
+		title       string
+		annotations map[string]string
+		ready       []int
+		expected    []bool
+	}{
+		{
+			title:    "resources without the annotation are not gated",
+			ready:    []int{0, 1},
+			expected: []bool{true, true},
+		},
+		{
+			title: "records are held back until enough endpoints are ready",
+			annotations: map[string]string{
+				minReadyEndpointsAnnotationKey: "2",
+			},
+			ready:    []int{0, 1, 2, 1, 0},
+			expected: []bool{false, false, true, true, true},
+		},
+		{
+			title: "records are withdrawn when no endpoint is ready",
+			annotations: map[string]string{
+				minReadyEndpointsAnnotationKey:   "2",
+				withdrawWhenUnreadyAnnotationKey: "true",
+			},
+			ready:    []int{2, 1, 0, 1, 2},
+			expected: []bool{true, true, false, false, true},
+		},
+		{
+			title: "invalid annotations are ignored",
+			annotations: map[string]string{
+				minReadyEndpointsAnnotationKey: "some",
+			},
+			ready:    []int{0},
+			expected: []bool{true},
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			svc := &v1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:   "testing",
+					Name:        "foo",
+					UID:         "foo-uid",
+					Annotations: tc.annotations,
+				},
+			}
+			gate, _ := newTestReadinessGate(svc, nil)
+
+			for i, ready := range tc.ready {
+				var addresses []v1.EndpointAddress
+				for j := 0; j < ready; j++ {
+					addresses = append(addresses, v1.EndpointAddress{IP: "10.0.0.1"})
+				}
+				require.NoError(t, gate.endpointsInformer.Informer().GetIndexer().Update(&v1.Endpoints{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "testing", Name: "foo"},
+					Subsets: []v1.EndpointSubset{
+						{
+							Addresses:         addresses,
+							NotReadyAddresses: []v1.EndpointAddress{{IP: "10.0.0.2"}},
+						},
+					},
+				}))
+
+				assert.Equal(t, tc.expected[i], gate.open(svc, svc.Namespace, []string{svc.Name}), "step %d", i)
+				gate.prune()
+			}
+		})
+	}
+}
+
+// newTestReadinessGate returns a started gate whose patches are applied to the annotations of the given service
+// right away, like the informer would see them, or fail with the given error.
+func newTestReadinessGate(svc *v1.Service, patchErr error) (*readinessGate, *[]string) {
+	var patches []string
+	informerFactory := kubeinformers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0)
+	gate := newReadinessGate(informerFactory, func(namespace, name string, data []byte) error {
+		patches = append(patches, string(data))
+		if patchErr != nil {
+			return patchErr
+		}
+		var patch struct {
+			Metadata struct {
+				Annotations map[string]*string `json:"annotations"`
+			} `json:"metadata"`
+		}
+		if err := json.Unmarshal(data, &patch); err != nil {
+			return err
+		}
+		if svc.Annotations == nil {
+			svc.Annotations = map[string]string{}
+		}
+		for k, v := range patch.Metadata.Annotations {
+			if v == nil {
+				delete(svc.Annotations, k)
+			} else {
+				svc.Annotations[k] = *v
+			}
+		}
+		return nil
+	})
+	// the informers are started and listed before the indexers are filled, so the listing doesn't
+	// replace the objects of the tests
+	gate.hasSynced()
+	informerFactory.WaitForCacheSync(wait.NeverStop)
+	return gate, &patches
+}
+
+func TestReadinessGateRestart(t *testing.T) {
+	svc := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "testing",
+			Name:      "foo",
+			UID:       "foo-uid",
+			Annotations: map[string]string{
+				minReadyEndpointsAnnotationKey: "2",
+			},
+		},
+	}
+	endpoints := func(ready int) *v1.Endpoints {
+		var addresses []v1.EndpointAddress
+		for j := 0; j < ready; j++ {
+			addresses = append(addresses, v1.EndpointAddress{IP: "10.0.0.1"})
+		}
+		return &v1.Endpoints{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "testing", Name: "foo"},
+			Subsets:    []v1.EndpointSubset{{Addresses: addresses}},
+		}
+	}
+
+	gate, patches := newTestReadinessGate(svc, nil)
+	require.NoError(t, gate.endpointsInformer.Informer().GetIndexer().Add(endpoints(2)))
+	assert.True(t, gate.open(svc, svc.Namespace, []string{svc.Name}))
+	assert.Equal(t, []string{`{"metadata":{"annotations":{"external-dns.alpha.kubernetes.io/readiness-gate-opened":"true"}}}`}, *patches)
+	assert.Equal(t, "true", svc.Annotations[readinessGateOpenedAnnotationKey])
+
+	// the gate of the new instance is open as the annotation is kept
+	restarted, patches := newTestReadinessGate(svc, nil)
+	require.NoError(t, restarted.endpointsInformer.Informer().GetIndexer().Add(endpoints(1)))
+	assert.True(t, restarted.open(svc, svc.Namespace, []string{svc.Name}))
+	assert.Empty(t, *patches)
+}
+
+func TestReadinessGatePrune(t *testing.T) {
+	svc := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "testing",
+			Name:      "foo",
+			UID:       "foo-uid",
+			Annotations: map[string]string{
+				minReadyEndpointsAnnotationKey: "1",
+			},
+		},
+	}
+	gate, _ := newTestReadinessGate(svc, errors.New("forbidden"))
+	require.NoError(t, gate.endpointsInformer.Informer().GetIndexer().Add(&v1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "testing", Name: "foo"},
+		Subsets:    []v1.EndpointSubset{{Addresses: []v1.EndpointAddress{{IP: "10.0.0.1"}}}},
+	}))
+
+	// the state which couldn't be recorded is kept in memory while the resource exists
+	assert.True(t, gate.open(svc, svc.Namespace, []string{svc.Name}))
+	gate.prune()
+	assert.Equal(t, map[types.UID]bool{"foo-uid": true}, gate.pending)
+	gate.prune()
+	assert.Empty(t, gate.pending)
+}
+
+func TestReadinessGateCondition(t *testing.T) {
+	svc := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "testing",
+			Name:      "foo",
+			UID:       "foo-uid",
+			Annotations: map[string]string{
+				minReadyEndpointsAnnotationKey: "2",
+				readyConditionAnnotationKey:    "cloud.google.com/load-balancer-neg-ready",
+			},
+		},
+	}
+	gate, _ := newTestReadinessGate(svc, nil)
+	require.NoError(t, gate.endpointsInformer.Informer().GetIndexer().Add(&v1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "testing", Name: "foo"},
+		Subsets: []v1.EndpointSubset{{Addresses: []v1.EndpointAddress{
+			{IP: "10.0.0.1", TargetRef: &v1.ObjectReference{Kind: "Pod", Namespace: "testing", Name: "healthy"}},
+			{IP: "10.0.0.2", TargetRef: &v1.ObjectReference{Kind: "Pod", Namespace: "testing", Name: "unhealthy"}},
+		}}},
+	}))
+	pod := func(name string, status v1.ConditionStatus) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "testing", Name: name},
+			Status: v1.PodStatus{Conditions: []v1.PodCondition{
+				{Type: v1.PodReady, Status: v1.ConditionTrue},
+				{Type: "cloud.google.com/load-balancer-neg-ready", Status: status},
+			}},
+		}
+	}
+	require.NoError(t, gate.podInformer.Informer().GetIndexer().Add(pod("healthy", v1.ConditionTrue)))
+	require.NoError(t, gate.podInformer.Informer().GetIndexer().Add(pod("unhealthy", v1.ConditionFalse)))
+
+	// only the endpoints passing the health check of the load balancer are counted
+	assert.False(t, gate.open(svc, svc.Namespace, []string{svc.Name}))
+
+	require.NoError(t, gate.podInformer.Informer().GetIndexer().Update(pod("unhealthy", v1.ConditionTrue)))
+	assert.True(t, gate.open(svc, svc.Namespace, []string{svc.Name}))
+}
+
+func TestIngressBackendServices(t *testing.T) {
+	ing := &v1beta1.Ingress{
+		Spec: v1beta1.IngressSpec{
+			Backend: &v1beta1.IngressBackend{ServiceName: "default"},
+			Rules: []v1beta1.IngressRule{
+				{
+					IngressRuleValue: v1beta1.IngressRuleValue{
+						HTTP: &v1beta1.HTTPIngressRuleValue{
+							Paths: []v1beta1.HTTPIngressPath{
+								{Path: "/api", Backend: v1beta1.IngressBackend{ServiceName: "api"}},
+								{Path: "/", Backend: v1beta1.IngressBackend{ServiceName: "default"}},
+							},
+						},
+					},
+				},
+				{Host: "example.org"},
+			},
+		},
+	}
+
+	assert.Equal(t, []string{"default", "api"}, ingressBackendServices(ing))
+}
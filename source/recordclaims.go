@@ -0,0 +1,158 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/dynamic"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+const (
+	// The label of the DNSRecordClaims selecting the owner ID of their records
+	recordClaimOwnerLabelKey = "externaldns.k8s.io/owner"
+	// The label of the DNSRecordClaims selecting the namespace of the resource of their records
+	recordClaimNamespaceLabelKey = "externaldns.k8s.io/namespace"
+)
+
+var recordClaimGVR = schema.GroupVersionResource{Group: "externaldns.k8s.io", Version: "v1alpha1", Resource: "dnsrecordclaims"}
+
+// DNSRecordClaimPublisher publishes the records owned by an owner ID as cluster-scoped DNSRecordClaim
+// resources, one per record, so teams can find the records of their namespace with kubectl, e.g.
+// kubectl get dnsrecordclaims -l externaldns.k8s.io/namespace=team-a, without provider credentials.
+// The claims are read-only copies of the registry: they are created, updated and deleted to match it,
+// and the claims of records which are no longer owned are deleted.
+type DNSRecordClaimPublisher struct {
+	client  dynamic.Interface
+	ownerID string
+}
+
+// NewDNSRecordClaimPublisher creates a new DNSRecordClaimPublisher for the records owned by the owner ID.
+func NewDNSRecordClaimPublisher(client dynamic.Interface, ownerID string) (*DNSRecordClaimPublisher, error) {
+	if errs := validation.IsValidLabelValue(ownerID); len(errs) > 0 {
+		return nil, fmt.Errorf("the owner ID %q can't be used as a label of the DNSRecordClaims: %s", ownerID, strings.Join(errs, "; "))
+	}
+	return &DNSRecordClaimPublisher{
+		client:  client,
+		ownerID: ownerID,
+	}, nil
+}
+
+// PublishOwnership creates, updates and deletes the DNSRecordClaims to match the records of the owner ID.
+// Failures are logged and retried in the next synchronization.
+func (p *DNSRecordClaimPublisher) PublishOwnership(records []*endpoint.Endpoint) {
+	desired := map[string]*unstructured.Unstructured{}
+	for _, ep := range records {
+		if ep.Labels[endpoint.OwnerLabelKey] != p.ownerID {
+			continue
+		}
+		claim := p.claimOf(ep)
+		desired[claim.GetName()] = claim
+	}
+
+	existing, err := p.client.Resource(recordClaimGVR).List(metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{recordClaimOwnerLabelKey: p.ownerID}).String(),
+	})
+	if err != nil {
+		log.Warnf("Unable to list the DNSRecordClaims: %v", err)
+		return
+	}
+
+	for i := range existing.Items {
+		claim := &existing.Items[i]
+		want, ok := desired[claim.GetName()]
+		if !ok {
+			if err := p.client.Resource(recordClaimGVR).Delete(claim.GetName(), &metav1.DeleteOptions{}); err != nil {
+				log.Warnf("Unable to delete the DNSRecordClaim %s: %v", claim.GetName(), err)
+			}
+			continue
+		}
+		delete(desired, claim.GetName())
+		if reflect.DeepEqual(claim.Object["spec"], want.Object["spec"]) && reflect.DeepEqual(claim.GetLabels(), want.GetLabels()) {
+			continue
+		}
+		want.SetResourceVersion(claim.GetResourceVersion())
+		if _, err := p.client.Resource(recordClaimGVR).Update(want, metav1.UpdateOptions{}); err != nil {
+			log.Warnf("Unable to update the DNSRecordClaim %s: %v", claim.GetName(), err)
+		}
+	}
+
+	names := make([]string, 0, len(desired))
+	for name := range desired {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := p.client.Resource(recordClaimGVR).Create(desired[name], metav1.CreateOptions{}); err != nil {
+			log.Warnf("Unable to create the DNSRecordClaim %s: %v", name, err)
+		}
+	}
+}
+
+// claimOf returns the DNSRecordClaim of a record.
+func (p *DNSRecordClaimPublisher) claimOf(ep *endpoint.Endpoint) *unstructured.Unstructured {
+	targets := make([]interface{}, 0, len(ep.Targets))
+	for _, target := range ep.Targets {
+		targets = append(targets, target)
+	}
+	spec := map[string]interface{}{
+		"dnsName":    ep.DNSName,
+		"recordType": ep.RecordType,
+		"targets":    targets,
+	}
+	if ep.SetIdentifier != "" {
+		spec["setIdentifier"] = ep.SetIdentifier
+	}
+	if ep.RecordTTL.IsConfigured() {
+		spec["recordTTL"] = int64(ep.RecordTTL)
+	}
+
+	claimLabels := map[string]string{recordClaimOwnerLabelKey: p.ownerID}
+	if resource := ep.Labels[endpoint.ResourceLabelKey]; resource != "" {
+		spec["resource"] = resource
+		parts := strings.Split(resource, "/")
+		if len(parts) == 3 && parts[1] != "" && len(validation.IsValidLabelValue(parts[1])) == 0 {
+			claimLabels[recordClaimNamespaceLabelKey] = parts[1]
+		}
+	}
+
+	claim := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	claim.SetAPIVersion(recordClaimGVR.GroupVersion().String())
+	claim.SetKind("DNSRecordClaim")
+	claim.SetName(recordClaimName(ep))
+	claim.SetLabels(claimLabels)
+	return claim
+}
+
+// recordClaimName returns a name identifying the record, as record names, e.g. wildcards, aren't valid
+// resource names.
+func recordClaimName(ep *endpoint.Endpoint) string {
+	hash := sha256.Sum256([]byte(strings.ToLower(strings.TrimSuffix(ep.DNSName, ".")) + "\x00" + ep.RecordType + "\x00" + ep.SetIdentifier))
+	return fmt.Sprintf("%s-%x", strings.ToLower(ep.RecordType), hash[:10])
+}
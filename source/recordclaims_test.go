@@ -0,0 +1,135 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// newFakeRecordClaimClient returns a fake dynamic client storing the DNSRecordClaims in the map.
+func newFakeRecordClaimClient(claims map[string]*unstructured.Unstructured) *fakedynamic.FakeDynamicClient {
+	client := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme())
+	client.PrependReactor("list", "dnsrecordclaims", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		list := &unstructured.UnstructuredList{Object: map[string]interface{}{}}
+		for _, claim := range claims {
+			list.Items = append(list.Items, *claim.DeepCopy())
+		}
+		return true, list, nil
+	})
+	for _, verb := range []string{"create", "update"} {
+		client.PrependReactor(verb, "dnsrecordclaims", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			claim := action.(k8stesting.CreateAction).GetObject().(*unstructured.Unstructured)
+			claims[claim.GetName()] = claim.DeepCopy()
+			return true, claim, nil
+		})
+	}
+	client.PrependReactor("delete", "dnsrecordclaims", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		delete(claims, action.(k8stesting.DeleteAction).GetName())
+		return true, nil, nil
+	})
+	return client
+}
+
+func newTestRecordClaim(name, owner string, spec map[string]interface{}) *unstructured.Unstructured {
+	claim := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	claim.SetAPIVersion("externaldns.k8s.io/v1alpha1")
+	claim.SetKind("DNSRecordClaim")
+	claim.SetName(name)
+	claim.SetLabels(map[string]string{recordClaimOwnerLabelKey: owner})
+	return claim
+}
+
+func TestDNSRecordClaimPublisher(t *testing.T) {
+	owned := func(ep *endpoint.Endpoint, owner, resource string) *endpoint.Endpoint {
+		ep.Labels[endpoint.OwnerLabelKey] = owner
+		if resource != "" {
+			ep.Labels[endpoint.ResourceLabelKey] = resource
+		}
+		return ep
+	}
+	foo := owned(endpoint.NewEndpointWithTTL("foo.example.org", endpoint.RecordTypeA, 300, "1.2.3.4"), "default", "ingress/team-a/foo")
+	bar := owned(endpoint.NewEndpoint("*.bar.example.org", endpoint.RecordTypeCNAME, "lb.example.com"), "default", "service/team-b/bar")
+	records := []*endpoint.Endpoint{
+		foo,
+		bar,
+		owned(endpoint.NewEndpoint("baz.example.org", endpoint.RecordTypeA, "4.3.2.1"), "other", "ingress/team-a/baz"),
+		endpoint.NewEndpoint("unowned.example.org", endpoint.RecordTypeA, "4.3.2.1"),
+	}
+
+	claims := map[string]*unstructured.Unstructured{
+		"a-stale":            newTestRecordClaim("a-stale", "default", map[string]interface{}{"dnsName": "stale.example.org"}),
+		"a-other":            newTestRecordClaim("a-other", "other", map[string]interface{}{"dnsName": "baz.example.org"}),
+		recordClaimName(foo): newTestRecordClaim(recordClaimName(foo), "default", map[string]interface{}{"dnsName": "foo.example.org"}),
+	}
+	client := newFakeRecordClaimClient(claims)
+	publisher, err := NewDNSRecordClaimPublisher(client, "default")
+	require.NoError(t, err)
+
+	publisher.PublishOwnership(records)
+
+	// the stale claim is deleted, the outdated one updated and the missing one created
+	require.Len(t, claims, 3)
+	assert.Contains(t, claims, "a-other")
+	assert.Equal(t, map[string]interface{}{
+		"dnsName":    "foo.example.org",
+		"recordType": "A",
+		"targets":    []interface{}{"1.2.3.4"},
+		"recordTTL":  int64(300),
+		"resource":   "ingress/team-a/foo",
+	}, claims[recordClaimName(foo)].Object["spec"])
+	assert.Equal(t, map[string]string{
+		recordClaimOwnerLabelKey:     "default",
+		recordClaimNamespaceLabelKey: "team-a",
+	}, claims[recordClaimName(foo)].GetLabels())
+	assert.Equal(t, map[string]interface{}{
+		"dnsName":    "*.bar.example.org",
+		"recordType": "CNAME",
+		"targets":    []interface{}{"lb.example.com"},
+		"resource":   "service/team-b/bar",
+	}, claims[recordClaimName(bar)].Object["spec"])
+	assert.Equal(t, "team-b", claims[recordClaimName(bar)].GetLabels()[recordClaimNamespaceLabelKey])
+
+	// the claims aren't written again if nothing changed
+	client.ClearActions()
+	publisher.PublishOwnership(records)
+	require.Len(t, client.Actions(), 1)
+	assert.Equal(t, "list", client.Actions()[0].GetVerb())
+}
+
+func TestNewDNSRecordClaimPublisherInvalidOwner(t *testing.T) {
+	_, err := NewDNSRecordClaimPublisher(newFakeRecordClaimClient(nil), "my owner")
+	assert.Error(t, err)
+}
+
+func TestRecordClaimName(t *testing.T) {
+	wildcard := endpoint.NewEndpoint("*.example.org", endpoint.RecordTypeA, "1.2.3.4")
+	name := recordClaimName(wildcard)
+	assert.Empty(t, validation.IsDNS1123Subdomain(name))
+	assert.Equal(t, name, recordClaimName(endpoint.NewEndpoint("*.Example.org.", endpoint.RecordTypeA, "4.3.2.1")))
+	assert.NotEqual(t, name, recordClaimName(endpoint.NewEndpoint("*.example.org", endpoint.RecordTypeAAAA, "::1")))
+	assert.NotEqual(t, name, recordClaimName(endpoint.NewEndpoint("*.example.org", endpoint.RecordTypeA, "1.2.3.4").WithSetIdentifier("eu")))
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// The resources with labels by the kind of their resource labels, besides the DNSEndpoints
+var labeledResources = map[string]schema.GroupVersionResource{
+	"service":     {Version: "v1", Resource: "services"},
+	"ingress":     {Group: "extensions", Version: "v1beta1", Resource: "ingresses"},
+	"pod":         {Version: "v1", Resource: "pods"},
+	"statefulset": {Group: "apps", Version: "v1", Resource: "statefulsets"},
+}
+
+// ResourceLabelLister lists the labels of the Services, Ingresses, Pods, StatefulSets and DNSEndpoints of
+// the endpoints, e.g. for the label endpoint filter. The resources are read from the informers shared with
+// the sources, the informer of a kind is started when its first resource is looked up.
+type ResourceLabelLister struct {
+	informers     *InformerFactories
+	kubeClient    kubernetes.Interface
+	dynamicClient dynamic.Interface
+	namespace     string
+	crdGVR        schema.GroupVersionResource
+	listers       map[string]cache.GenericLister
+	lock          sync.Mutex
+}
+
+// NewResourceLabelLister creates a new ResourceLabelLister for the resources in the namespace, empty for
+// all namespaces. The DNSEndpoints are the resources of the given API version and kind of the crd source.
+func NewResourceLabelLister(informers *InformerFactories, kubeClient kubernetes.Interface, dynamicClient dynamic.Interface, namespace, crdAPIVersion, crdKind string) (*ResourceLabelLister, error) {
+	resources, err := writableResources(crdAPIVersion, crdKind)
+	if err != nil {
+		return nil, err
+	}
+	return &ResourceLabelLister{
+		informers:     informers,
+		kubeClient:    kubeClient,
+		dynamicClient: dynamicClient,
+		namespace:     namespace,
+		crdGVR:        resources["crd"],
+		listers:       map[string]cache.GenericLister{},
+	}, nil
+}
+
+// ResourceLabels returns the labels of the resource of the resource label, e.g. service/default/api, and
+// whether its kind is known. The labels of a resource which is gone are empty.
+func (l *ResourceLabelLister) ResourceLabels(resource string) (map[string]string, bool, error) {
+	parts := strings.Split(resource, "/")
+	if len(parts) != 3 {
+		return nil, false, nil
+	}
+	lister, known, err := l.lister(parts[0])
+	if err != nil || !known {
+		return nil, known, err
+	}
+
+	obj, err := lister.ByNamespace(parts[1]).Get(parts[2])
+	if apierrors.IsNotFound(err) {
+		return nil, true, nil
+	}
+	if err != nil {
+		return nil, true, err
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, true, err
+	}
+	return accessor.GetLabels(), true, nil
+}
+
+// lister returns the lister of the resources of the kind, starting and syncing their informer the first time.
+func (l *ResourceLabelLister) lister(kind string) (cache.GenericLister, bool, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if lister, ok := l.listers[kind]; ok {
+		return lister, true, nil
+	}
+
+	var informer kubeinformers.GenericInformer
+	if gvr, ok := labeledResources[kind]; ok {
+		informerFactory := l.informers.Kube(l.kubeClient, l.namespace)
+		var err error
+		if informer, err = informerFactory.ForResource(gvr); err != nil {
+			return nil, true, err
+		}
+		informer.Informer()
+		// TODO informer is not explicitly stopped since controller is not passing in its channel.
+		informerFactory.Start(wait.NeverStop)
+	} else if kind == "crd" {
+		informerFactory := l.informers.Dynamic(l.dynamicClient, l.namespace)
+		informer = informerFactory.ForResource(l.crdGVR)
+		informer.Informer()
+		informerFactory.Start(wait.NeverStop)
+	} else {
+		return nil, false, nil
+	}
+
+	// wait for the local cache to be populated, right away if the informer is shared with a source.
+	err := wait.PollImmediate(time.Second, 60*time.Second, func() (bool, error) {
+		return informer.Informer().HasSynced(), nil
+	})
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to sync cache of the %s resources: %v", kind, err)
+	}
+	l.listers[kind] = informer.Lister()
+	return l.listers[kind], true, nil
+}
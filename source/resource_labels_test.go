@@ -0,0 +1,67 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestResourceLabelLister(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset(&v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "api", Labels: map[string]string{"team": "payments"}},
+	})
+	dynamicClient := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme())
+	dynamicClient.PrependReactor("list", "dnsendpoints", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		dnsEndpoint := unstructured.Unstructured{Object: map[string]interface{}{}}
+		dnsEndpoint.SetAPIVersion("externaldns.k8s.io/v1alpha1")
+		dnsEndpoint.SetKind("DNSEndpoint")
+		dnsEndpoint.SetNamespace("default")
+		dnsEndpoint.SetName("pay")
+		dnsEndpoint.SetLabels(map[string]string{"team": "search"})
+		return true, &unstructured.UnstructuredList{Object: map[string]interface{}{}, Items: []unstructured.Unstructured{dnsEndpoint}}, nil
+	})
+
+	lister, err := NewResourceLabelLister(NewInformerFactories(), kubeClient, dynamicClient, "", "externaldns.k8s.io/v1alpha1", "DNSEndpoint")
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		resource string
+		labels   map[string]string
+		known    bool
+	}{
+		{resource: "service/default/api", labels: map[string]string{"team": "payments"}, known: true},
+		{resource: "service/default/gone", known: true},
+		{resource: "crd/default/pay", labels: map[string]string{"team": "search"}, known: true},
+		{resource: "gateway/default/api"},
+		{resource: ""},
+	} {
+		labels, known, err := lister.ResourceLabels(tc.resource)
+		require.NoError(t, err, tc.resource)
+		assert.Equal(t, tc.labels, labels, tc.resource)
+		assert.Equal(t, tc.known, known, tc.resource)
+	}
+}
@@ -0,0 +1,247 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	kubeinformers "k8s.io/client-go/informers"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+const (
+	// The annotation used for defining the hostnames of the active (stable) version of a rollout
+	activeHostnameAnnotationKey = "external-dns.alpha.kubernetes.io/active-hostname"
+	// The annotation used for defining the hostnames of the preview (canary) version of a rollout
+	previewHostnameAnnotationKey = "external-dns.alpha.kubernetes.io/preview-hostname"
+)
+
+var rolloutGVR = schema.GroupVersionResource{Group: "argoproj.io", Version: "v1alpha1", Resource: "rollouts"}
+
+// rolloutSource is an implementation of Source for Argo Rollouts. The active hostnames of a rollout
+// point at its active (blue-green) or stable (canary) service and the preview hostnames point at its
+// preview or canary service while a new version is waiting for promotion. Once the rollout is promoted,
+// the preview hostnames follow the active service until the next version is rolled out.
+type rolloutSource struct {
+	kubeClient        kubernetes.Interface
+	dynamicKubeClient dynamic.Interface
+	namespace         string
+	annotationFilter  string
+	serviceInformer   coreinformers.ServiceInformer
+	rolloutInformer   kubeinformers.GenericInformer
+}
+
+// NewRolloutSource creates a new rolloutSource with the given config.
+func NewRolloutSource(informers *InformerFactories, kubeClient kubernetes.Interface, dynamicKubeClient dynamic.Interface, namespace, annotationFilter string) (Source, error) {
+	// Use shared informers to listen for add/update/delete of services and rollouts in the specified namespace.
+	// Set resync period to 0, to prevent processing when nothing has changed.
+	informerFactory := informers.Kube(kubeClient, namespace)
+	serviceInformer := informerFactory.Core().V1().Services()
+	dynamicInformerFactory := informers.Dynamic(dynamicKubeClient, namespace)
+	rolloutInformer := dynamicInformerFactory.ForResource(rolloutGVR)
+
+	// Add default resource event handlers to properly initialize informer.
+	for _, informer := range []cache.SharedIndexInformer{serviceInformer.Informer(), rolloutInformer.Informer()} {
+		informer.AddEventHandler(
+			cache.ResourceEventHandlerFuncs{
+				AddFunc: func(obj interface{}) {
+				},
+			},
+		)
+	}
+
+	// TODO informer is not explicitly stopped since controller is not passing in its channel.
+	informerFactory.Start(wait.NeverStop)
+	dynamicInformerFactory.Start(wait.NeverStop)
+
+	// wait for the local cache to be populated.
+	err := wait.Poll(time.Second, 60*time.Second, func() (bool, error) {
+		return serviceInformer.Informer().HasSynced() && rolloutInformer.Informer().HasSynced(), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sync cache: %v", err)
+	}
+
+	return &rolloutSource{
+		kubeClient:        kubeClient,
+		dynamicKubeClient: dynamicKubeClient,
+		namespace:         namespace,
+		annotationFilter:  annotationFilter,
+		serviceInformer:   serviceInformer,
+		rolloutInformer:   rolloutInformer,
+	}, nil
+}
+
+// Endpoints returns endpoint objects for the active and preview hostnames of each rollout.
+func (sc *rolloutSource) Endpoints() ([]*endpoint.Endpoint, error) {
+	objects, err := sc.rolloutInformer.Lister().ByNamespace(sc.namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	labelSelector, err := metav1.ParseToLabelSelector(sc.annotationFilter)
+	if err != nil {
+		return nil, err
+	}
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := []*endpoint.Endpoint{}
+
+	for _, obj := range objects {
+		rollout, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		if !selector.Empty() && !selector.Matches(labels.Set(rollout.GetAnnotations())) {
+			continue
+		}
+
+		// Check controller annotation to see if we are responsible.
+		controller, ok := rollout.GetAnnotations()[controllerAnnotationKey]
+		if ok && controller != controllerAnnotationValue {
+			log.Debugf("Skipping rollout %s/%s because controller value does not match, found: %s, required: %s",
+				rollout.GetNamespace(), rollout.GetName(), controller, controllerAnnotationValue)
+			continue
+		}
+
+		rolloutEndpoints, err := sc.endpointsFromRollout(rollout)
+		if err != nil {
+			return nil, err
+		}
+		if len(rolloutEndpoints) == 0 {
+			log.Debugf("No endpoints could be generated from rollout %s/%s", rollout.GetNamespace(), rollout.GetName())
+			continue
+		}
+
+		log.Debugf("Endpoints generated from rollout: %s/%s: %v", rollout.GetNamespace(), rollout.GetName(), rolloutEndpoints)
+		setUnstructuredResourceLabel("rollout", rollout, rolloutEndpoints)
+		endpoints = append(endpoints, rolloutEndpoints...)
+	}
+
+	for _, ep := range endpoints {
+		sort.Sort(ep.Targets)
+	}
+
+	return endpoints, nil
+}
+
+// endpointsFromRollout returns the endpoints of the active and preview hostnames of a rollout.
+func (sc *rolloutSource) endpointsFromRollout(rollout *unstructured.Unstructured) ([]*endpoint.Endpoint, error) {
+	activeService, previewService := rolloutServices(rollout)
+	if activeService == "" {
+		log.Debugf("Skipping rollout %s/%s because it doesn't reference an active or stable service", rollout.GetNamespace(), rollout.GetName())
+		return nil, nil
+	}
+	if previewService == "" || !rolloutPreviewPending(rollout) {
+		previewService = activeService
+	}
+
+	annotations := rollout.GetAnnotations()
+	resource := fmt.Sprintf("rollout %s/%s", rollout.GetNamespace(), rollout.GetName())
+
+	var endpoints []*endpoint.Endpoint
+	for _, hostnames := range []struct {
+		annotation string
+		service    string
+	}{
+		{activeHostnameAnnotationKey, activeService},
+		{previewHostnameAnnotationKey, previewService},
+	} {
+		value, ok := annotations[hostnames.annotation]
+		if !ok {
+			continue
+		}
+
+		targets, err := sc.serviceTargets(rollout.GetNamespace(), hostnames.service)
+		if err != nil {
+			return nil, err
+		}
+		if len(targets) == 0 {
+			log.Debugf("Skipping %s of rollout %s/%s because service %s has no address", hostnames.annotation, rollout.GetNamespace(), rollout.GetName(), hostnames.service)
+			continue
+		}
+
+		endpoints = append(endpoints, endpointsForHostnames(strings.Split(strings.Replace(value, " ", "", -1), ","), targets, annotations, resource)...)
+	}
+
+	return endpoints, nil
+}
+
+// serviceTargets returns the load balancer addresses of a service or its cluster IP if it doesn't have any.
+func (sc *rolloutSource) serviceTargets(namespace, name string) (endpoint.Targets, error) {
+	svc, err := sc.serviceInformer.Lister().Services(namespace).Get(name)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if targets := extractLoadBalancerTargets(svc); len(targets) > 0 {
+		return targets, nil
+	}
+	if svc.Spec.ClusterIP == "" {
+		return nil, nil
+	}
+	return extractServiceIps(svc), nil
+}
+
+func (sc *rolloutSource) AddEventHandler(handler func() error, stopChan <-chan struct{}, minInterval time.Duration) {
+}
+
+// rolloutServices returns the services a rollout routes its active and preview traffic through.
+func rolloutServices(rollout *unstructured.Unstructured) (active, preview string) {
+	if _, ok, _ := unstructured.NestedMap(rollout.Object, "spec", "strategy", "blueGreen"); ok {
+		active, _, _ = unstructured.NestedString(rollout.Object, "spec", "strategy", "blueGreen", "activeService")
+		preview, _, _ = unstructured.NestedString(rollout.Object, "spec", "strategy", "blueGreen", "previewService")
+		return active, preview
+	}
+	active, _, _ = unstructured.NestedString(rollout.Object, "spec", "strategy", "canary", "stableService")
+	preview, _, _ = unstructured.NestedString(rollout.Object, "spec", "strategy", "canary", "canaryService")
+	return active, preview
+}
+
+// rolloutPreviewPending returns true while a new version of a rollout runs next to the active one,
+// i.e. until the rollout is promoted or aborted.
+func rolloutPreviewPending(rollout *unstructured.Unstructured) bool {
+	if _, ok, _ := unstructured.NestedMap(rollout.Object, "spec", "strategy", "blueGreen"); ok {
+		activeSelector, _, _ := unstructured.NestedString(rollout.Object, "status", "blueGreen", "activeSelector")
+		previewSelector, _, _ := unstructured.NestedString(rollout.Object, "status", "blueGreen", "previewSelector")
+		return previewSelector != "" && previewSelector != activeSelector
+	}
+	stable, _, _ := unstructured.NestedString(rollout.Object, "status", "stableRS")
+	current, _, _ := unstructured.NestedString(rollout.Object, "status", "currentPodHash")
+	return current != "" && current != stable
+}
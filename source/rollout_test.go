@@ -0,0 +1,136 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func newTestRollout(strategy string, spec, status map[string]interface{}) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec":   map[string]interface{}{"strategy": map[string]interface{}{strategy: spec}},
+		"status": status,
+	}}
+	u.SetAPIVersion("argoproj.io/v1alpha1")
+	u.SetKind("Rollout")
+	u.SetNamespace("testing")
+	u.SetName("foo")
+	u.SetAnnotations(map[string]string{
+		activeHostnameAnnotationKey:  "foo.example.org",
+		previewHostnameAnnotationKey: "preview.foo.example.org",
+	})
+	return u
+}
+
+func TestRolloutEndpoints(t *testing.T) {
+	blueGreen := map[string]interface{}{"activeService": "foo-active", "previewService": "foo-preview"}
+	canary := map[string]interface{}{"stableService": "foo-active", "canaryService": "foo-preview"}
+
+	for _, tc := range []struct {
+		title    string
+		rollout  *unstructured.Unstructured
+		expected []*endpoint.Endpoint
+	}{
+		{
+			title: "blue-green preview points at the preview service while waiting for promotion",
+			rollout: newTestRollout("blueGreen", blueGreen, map[string]interface{}{
+				"blueGreen": map[string]interface{}{"activeSelector": "abc", "previewSelector": "def"},
+			}),
+			expected: []*endpoint.Endpoint{
+				{DNSName: "foo.example.org", Targets: endpoint.Targets{"1.1.1.1"}, RecordType: endpoint.RecordTypeA},
+				{DNSName: "preview.foo.example.org", Targets: endpoint.Targets{"2.2.2.2"}, RecordType: endpoint.RecordTypeA},
+			},
+		},
+		{
+			title: "blue-green preview follows the active service once promoted",
+			rollout: newTestRollout("blueGreen", blueGreen, map[string]interface{}{
+				"blueGreen": map[string]interface{}{"activeSelector": "def", "previewSelector": "def"},
+			}),
+			expected: []*endpoint.Endpoint{
+				{DNSName: "foo.example.org", Targets: endpoint.Targets{"1.1.1.1"}, RecordType: endpoint.RecordTypeA},
+				{DNSName: "preview.foo.example.org", Targets: endpoint.Targets{"1.1.1.1"}, RecordType: endpoint.RecordTypeA},
+			},
+		},
+		{
+			title: "canary preview points at the canary service while a new version rolls out",
+			rollout: newTestRollout("canary", canary, map[string]interface{}{
+				"stableRS": "abc", "currentPodHash": "def",
+			}),
+			expected: []*endpoint.Endpoint{
+				{DNSName: "foo.example.org", Targets: endpoint.Targets{"1.1.1.1"}, RecordType: endpoint.RecordTypeA},
+				{DNSName: "preview.foo.example.org", Targets: endpoint.Targets{"2.2.2.2"}, RecordType: endpoint.RecordTypeA},
+			},
+		},
+		{
+			title: "canary preview follows the stable service once the rollout completed",
+			rollout: newTestRollout("canary", canary, map[string]interface{}{
+				"stableRS": "def", "currentPodHash": "def",
+			}),
+			expected: []*endpoint.Endpoint{
+				{DNSName: "foo.example.org", Targets: endpoint.Targets{"1.1.1.1"}, RecordType: endpoint.RecordTypeA},
+				{DNSName: "preview.foo.example.org", Targets: endpoint.Targets{"1.1.1.1"}, RecordType: endpoint.RecordTypeA},
+			},
+		},
+		{
+			title:    "rollout without services isn't published",
+			rollout:  newTestRollout("canary", map[string]interface{}{}, map[string]interface{}{}),
+			expected: []*endpoint.Endpoint{},
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			kubernetes := fake.NewSimpleClientset()
+			for name, ip := range map[string]string{"foo-active": "1.1.1.1", "foo-preview": "2.2.2.2"} {
+				svc := &v1.Service{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "testing", Name: name},
+					Spec:       v1.ServiceSpec{Type: v1.ServiceTypeLoadBalancer},
+					Status: v1.ServiceStatus{
+						LoadBalancer: v1.LoadBalancerStatus{Ingress: []v1.LoadBalancerIngress{{IP: ip}}},
+					},
+				}
+				_, err := kubernetes.CoreV1().Services(svc.Namespace).Create(svc)
+				require.NoError(t, err)
+			}
+
+			informerFactory := kubeinformers.NewSharedInformerFactory(kubernetes, 0)
+			serviceInformer := informerFactory.Core().V1().Services()
+			serviceInformer.Informer()
+			informerFactory.Start(wait.NeverStop)
+			err := wait.Poll(100*time.Millisecond, 3*time.Second, func() (bool, error) {
+				return serviceInformer.Informer().HasSynced(), nil
+			})
+			require.NoError(t, err)
+
+			sc := &rolloutSource{serviceInformer: serviceInformer}
+
+			endpoints, err := sc.endpointsFromRollout(tc.rollout)
+			require.NoError(t, err)
+
+			validateEndpoints(t, endpoints, tc.expected)
+		})
+	}
+}
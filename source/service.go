@@ -28,8 +28,8 @@ import (
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
-	kubeinformers "k8s.io/client-go/informers"
 	coreinformers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
@@ -40,6 +40,11 @@ import (
 
 const (
 	defaultTargetsCapacity = 10
+	// The annotation used for publishing SRV records of the ports of a service, "true" for all ports or a
+	// comma separated list of port names
+	srvPortsAnnotationKey = "external-dns.alpha.kubernetes.io/srv-ports"
+	// The annotation used for publishing the cluster IP of a ClusterIP service into the internal zone
+	internalHostnameAnnotationKey = "external-dns.alpha.kubernetes.io/internal-hostname"
 )
 
 // serviceSource is an implementation of Source for Kubernetes service objects.
@@ -60,13 +65,19 @@ type serviceSource struct {
 	publishHostIP            bool
 	serviceInformer          coreinformers.ServiceInformer
 	podInformer              coreinformers.PodInformer
+	speakerInformer          coreinformers.PodInformer
 	nodeInformer             coreinformers.NodeInformer
 	serviceTypeFilter        map[string]struct{}
+	metalLBAnnouncedOnly     bool
+	readinessGate            *readinessGate
+	namespaceDefaults        *namespaceAnnotationDefaults
+	internalZone             string
+	externalNames            *externalNameSync
 	runner                   *async.BoundedFrequencyRunner
 }
 
 // NewServiceSource creates a new serviceSource with the given config.
-func NewServiceSource(kubeClient kubernetes.Interface, namespace, annotationFilter string, fqdnTemplate string, combineFqdnAnnotation bool, compatibility string, publishInternal bool, publishHostIP bool, serviceTypeFilter []string, ignoreHostnameAnnotation bool) (Source, error) {
+func NewServiceSource(informers *InformerFactories, kubeClient kubernetes.Interface, namespace, annotationFilter string, fqdnTemplate string, combineFqdnAnnotation bool, compatibility string, publishInternal bool, publishHostIP bool, serviceTypeFilter []string, ignoreHostnameAnnotation bool, metalLBAnnouncedOnly bool, inheritNamespaceDefaults bool, internalZone string, syncExternalNames bool) (Source, error) {
 	var (
 		tmpl *template.Template
 		err  error
@@ -82,7 +93,7 @@ func NewServiceSource(kubeClient kubernetes.Interface, namespace, annotationFilt
 
 	// Use shared informers to listen for add/update/delete of services/pods/nodes in the specified namespace.
 	// Set resync period to 0, to prevent processing when nothing has changed
-	informerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(kubeClient, 0, kubeinformers.WithNamespace(namespace))
+	informerFactory := informers.Kube(kubeClient, namespace)
 	serviceInformer := informerFactory.Core().V1().Services()
 	podInformer := informerFactory.Core().V1().Pods()
 	nodeInformer := informerFactory.Core().V1().Nodes()
@@ -106,18 +117,48 @@ func NewServiceSource(kubeClient kubernetes.Interface, namespace, annotationFilt
 			},
 		},
 	)
+	// The MetalLB speakers run in their own namespace, not only the one of the services.
+	speakerInformerFactory := informerFactory
+	speakerInformer := podInformer
+	if metalLBAnnouncedOnly && namespace != "" {
+		speakerInformerFactory = informers.Kube(kubeClient, "")
+		speakerInformer = speakerInformerFactory.Core().V1().Pods()
+		speakerInformer.Informer().AddEventHandler(
+			cache.ResourceEventHandlerFuncs{
+				AddFunc: func(obj interface{}) {
+				},
+			},
+		)
+	}
+	var namespaceDefaults *namespaceAnnotationDefaults
+	if inheritNamespaceDefaults {
+		namespaceDefaults = newNamespaceAnnotationDefaults(informerFactory)
+	}
+	readinessGate := newReadinessGate(informerFactory, func(namespace, name string, data []byte) error {
+		_, err := kubeClient.CoreV1().Services(namespace).Patch(name, types.MergePatchType, data)
+		return err
+	})
 
 	// TODO informer is not explicitly stopped since controller is not passing in its channel.
 	informerFactory.Start(wait.NeverStop)
+	speakerInformerFactory.Start(wait.NeverStop)
 
 	// wait for the local cache to be populated.
 	err = wait.Poll(time.Second, 60*time.Second, func() (bool, error) {
-		return serviceInformer.Informer().HasSynced(), nil
+		if metalLBAnnouncedOnly && !(speakerInformer.Informer().HasSynced() && podInformer.Informer().HasSynced() && nodeInformer.Informer().HasSynced()) {
+			return false, nil
+		}
+		return serviceInformer.Informer().HasSynced() && namespaceDefaults.hasSynced(), nil
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to sync cache: %v", err)
 	}
 
+	var externalNames *externalNameSync
+	if syncExternalNames {
+		externalNames = newExternalNameSync(kubeClient)
+	}
+
 	// Transform the slice into a map so it will
 	// be way much easier and fast to filter later
 	serviceTypes := make(map[string]struct{})
@@ -137,8 +178,14 @@ func NewServiceSource(kubeClient kubernetes.Interface, namespace, annotationFilt
 		publishHostIP:            publishHostIP,
 		serviceInformer:          serviceInformer,
 		podInformer:              podInformer,
+		speakerInformer:          speakerInformer,
 		nodeInformer:             nodeInformer,
 		serviceTypeFilter:        serviceTypes,
+		metalLBAnnouncedOnly:     metalLBAnnouncedOnly,
+		readinessGate:            readinessGate,
+		namespaceDefaults:        namespaceDefaults,
+		internalZone:             strings.Trim(internalZone, "."),
+		externalNames:            externalNames,
 	}, nil
 }
 
@@ -168,6 +215,15 @@ func (sc *serviceSource) Endpoints() ([]*endpoint.Endpoint, error) {
 				svc.Namespace, svc.Name, controller, controllerAnnotationValue)
 			continue
 		}
+		if annotations := sc.namespaceDefaults.annotations(svc.Namespace, svc.Annotations); annotations != nil {
+			svc = svc.DeepCopy()
+			svc.Annotations = annotations
+		}
+
+		if !sc.readinessGate.open(svc, svc.Namespace, []string{svc.Name}) {
+			continue
+		}
+		svc = sc.externalNames.sync(svc)
 
 		svcEndpoints := sc.endpoints(svc)
 
@@ -190,6 +246,8 @@ func (sc *serviceSource) Endpoints() ([]*endpoint.Endpoint, error) {
 			}
 		}
 
+		svcEndpoints = append(svcEndpoints, sc.internalEndpoints(svc)...)
+
 		if len(svcEndpoints) == 0 {
 			log.Debugf("No endpoints could be generated from service %s/%s", svc.Namespace, svc.Name)
 			continue
@@ -199,6 +257,7 @@ func (sc *serviceSource) Endpoints() ([]*endpoint.Endpoint, error) {
 		sc.setResourceLabel(svc, svcEndpoints)
 		endpoints = append(endpoints, svcEndpoints...)
 	}
+	sc.readinessGate.prune()
 
 	for _, ep := range endpoints {
 		sort.Sort(ep.Targets)
@@ -260,11 +319,21 @@ func (sc *serviceSource) extractHeadlessEndpoints(svc *v1.Service, hostname stri
 	}
 	sort.Strings(headlessDomains)
 	for _, headlessDomain := range headlessDomains {
-		targets := targetsByHeadlessDomain[headlessDomain]
-		if ttl.IsConfigured() {
-			endpoints = append(endpoints, endpoint.NewEndpointWithTTL(headlessDomain, endpoint.RecordTypeA, ttl, targets...))
-		} else {
-			endpoints = append(endpoints, endpoint.NewEndpoint(headlessDomain, endpoint.RecordTypeA, targets...))
+		// the pods of dual-stack clusters are published with their IPv4 and IPv6 addresses
+		targetsByType := map[string]endpoint.Targets{}
+		for _, target := range targetsByHeadlessDomain[headlessDomain] {
+			targetsByType[suitableType(target)] = append(targetsByType[suitableType(target)], target)
+		}
+		for _, recordType := range []string{endpoint.RecordTypeA, endpoint.RecordTypeAAAA} {
+			targets := targetsByType[recordType]
+			if len(targets) == 0 {
+				continue
+			}
+			if ttl.IsConfigured() {
+				endpoints = append(endpoints, endpoint.NewEndpointWithTTL(headlessDomain, recordType, ttl, targets...))
+			} else {
+				endpoints = append(endpoints, endpoint.NewEndpoint(headlessDomain, recordType, targets...))
+			}
 		}
 	}
 
@@ -304,6 +373,52 @@ func (sc *serviceSource) endpoints(svc *v1.Service) []*endpoint.Endpoint {
 	return endpoints
 }
 
+// internalEndpoints returns the A or AAAA records of the internal hostnames of a ClusterIP service, which point at
+// its cluster IP regardless of --publish-internal-services. Hostnames outside of the internal zone are
+// skipped, so cluster IPs never leak into public zones.
+func (sc *serviceSource) internalEndpoints(svc *v1.Service) []*endpoint.Endpoint {
+	if sc.internalZone == "" || svc.Spec.Type != v1.ServiceTypeClusterIP {
+		return nil
+	}
+	annotation, ok := svc.Annotations[internalHostnameAnnotationKey]
+	if !ok {
+		return nil
+	}
+	if svc.Spec.ClusterIP == "" || svc.Spec.ClusterIP == v1.ClusterIPNone {
+		log.Debugf("Skipping internal hostnames of service %s/%s because it has no cluster IP", svc.Namespace, svc.Name)
+		return nil
+	}
+
+	ttl, err := getTTLFromAnnotations(svc.Annotations)
+	if err != nil {
+		log.Warn(err)
+	}
+	providerSpecific, setIdentifier := getProviderSpecificAnnotations(svc.Annotations)
+
+	var endpoints []*endpoint.Endpoint
+	for _, hostname := range strings.Split(strings.Replace(annotation, " ", "", -1), ",") {
+		hostname = strings.TrimSuffix(hostname, ".")
+		if hostname == "" {
+			continue
+		}
+		if !inInternalZone(hostname, sc.internalZone) {
+			log.Warnf("Skipping internal hostname %s of service %s/%s because it isn't part of the internal zone %s", hostname, svc.Namespace, svc.Name, sc.internalZone)
+			continue
+		}
+		ep := endpoint.NewEndpointWithTTL(hostname, suitableType(svc.Spec.ClusterIP), ttl, svc.Spec.ClusterIP)
+		ep.ProviderSpecific = providerSpecific
+		ep.SetIdentifier = setIdentifier
+		endpoints = append(endpoints, ep)
+	}
+	return endpoints
+}
+
+// inInternalZone returns true if the hostname is the internal zone itself or one of its subdomains.
+func inInternalZone(hostname, zone string) bool {
+	hostname, zone = strings.ToLower(hostname), strings.ToLower(zone)
+	return hostname == zone || strings.HasSuffix(hostname, "."+zone)
+}
+
 // filterByAnnotations filters a list of services by a given annotation selector.
 func (sc *serviceSource) filterByAnnotations(services []*v1.Service) ([]*v1.Service, error) {
 	labelSelector, err := metav1.ParseToLabelSelector(sc.annotationFilter)
@@ -369,6 +484,14 @@ func (sc *serviceSource) generateEndpoints(svc *v1.Service, hostname string, pro
 		DNSName:    hostname,
 	}
 
+	epAAAA := &endpoint.Endpoint{
+		RecordTTL:  ttl,
+		RecordType: endpoint.RecordTypeAAAA,
+		Labels:     endpoint.NewLabels(),
+		Targets:    make(endpoint.Targets, 0, defaultTargetsCapacity),
+		DNSName:    hostname,
+	}
+
 	epCNAME := &endpoint.Endpoint{
 		RecordTTL:  ttl,
 		RecordType: endpoint.RecordTypeCNAME,
@@ -383,6 +506,16 @@ func (sc *serviceSource) generateEndpoints(svc *v1.Service, hostname string, pro
 	switch svc.Spec.Type {
 	case v1.ServiceTypeLoadBalancer:
 		targets = append(targets, extractLoadBalancerTargets(svc)...)
+		// fall back to the addresses announced by the load balancer implementation while the status is lagging behind
+		if len(targets) == 0 {
+			targets = append(targets, extractCiliumLBIPAMTargets(svc)...)
+		}
+		if len(targets) == 0 {
+			targets = append(targets, sc.extractKubeVIPTargets(svc)...)
+		}
+		if sc.metalLBAnnouncedOnly {
+			targets = sc.filterMetalLBAnnouncedTargets(svc, targets)
+		}
 	case v1.ServiceTypeClusterIP:
 		if sc.publishInternal {
 			targets = append(targets, extractServiceIps(svc)...)
@@ -403,10 +536,12 @@ func (sc *serviceSource) generateEndpoints(svc *v1.Service, hostname string, pro
 	}
 
 	for _, t := range targets {
-		if suitableType(t) == endpoint.RecordTypeA {
+		switch suitableType(t) {
+		case endpoint.RecordTypeA:
 			epA.Targets = append(epA.Targets, t)
-		}
-		if suitableType(t) == endpoint.RecordTypeCNAME {
+		case endpoint.RecordTypeAAAA:
+			epAAAA.Targets = append(epAAAA.Targets, t)
+		case endpoint.RecordTypeCNAME:
 			epCNAME.Targets = append(epCNAME.Targets, t)
 		}
 	}
@@ -414,9 +549,16 @@ func (sc *serviceSource) generateEndpoints(svc *v1.Service, hostname string, pro
 	if len(epA.Targets) > 0 {
 		endpoints = append(endpoints, epA)
 	}
+	if len(epAAAA.Targets) > 0 {
+		endpoints = append(endpoints, epAAAA)
+	}
 	if len(epCNAME.Targets) > 0 {
 		endpoints = append(endpoints, epCNAME)
 	}
+	// the SRV records of node ports are always published
+	if svc.Spec.Type != v1.ServiceTypeNodePort && (len(epA.Targets) > 0 || len(epAAAA.Targets) > 0 || len(epCNAME.Targets) > 0) {
+		endpoints = append(endpoints, extractPortSRVEndpoints(svc, hostname, ttl)...)
+	}
 	for _, endpoint := range endpoints {
 		endpoint.ProviderSpecific = providerSpecific
 		endpoint.SetIdentifier = setIdentifier
@@ -538,6 +680,8 @@ func (sc *serviceSource) extractNodePortEndpoints(svc *v1.Service, nodeTargets e
 			} else {
 				ep = endpoint.NewEndpoint(recordName, endpoint.RecordTypeSRV, target)
 			}
+			// the providers with structured SRV records take the priority, weight and port from the metadata
+			ep.WithTargetMetadata(target, endpoint.TargetMetadata{Weight: 50, Port: int64(port.NodePort)})
 
 			endpoints = append(endpoints, ep)
 		}
@@ -546,6 +690,44 @@ func (sc *serviceSource) extractNodePortEndpoints(svc *v1.Service, nodeTargets e
 	return endpoints
 }
 
+// extractPortSRVEndpoints returns the SRV records of the ports selected by the srv-ports annotation,
+// pointing at the published hostname of the service, e.g. _sip._udp.<hostname> for a port named sip.
+func extractPortSRVEndpoints(svc *v1.Service, hostname string, ttl endpoint.TTL) []*endpoint.Endpoint {
+	annotation := strings.TrimSpace(svc.Annotations[srvPortsAnnotationKey])
+	if annotation == "" || annotation == "false" {
+		return nil
+	}
+	selected := map[string]bool{}
+	for _, name := range strings.Split(strings.Replace(annotation, " ", "", -1), ",") {
+		selected[name] = true
+	}
+
+	var endpoints []*endpoint.Endpoint
+	for _, port := range svc.Spec.Ports {
+		// figure out the portname
+		portName := port.Name
+		if portName == "" {
+			portName = fmt.Sprintf("%d", port.Port)
+		}
+		if annotation != "true" && !selected[portName] {
+			continue
+		}
+
+		// figure out the protocol
+		protocol := strings.ToLower(string(port.Protocol))
+		if protocol == "" {
+			protocol = "tcp"
+		}
+
+		recordName := fmt.Sprintf("_%s._%s.%s", portName, protocol, hostname)
+		target := fmt.Sprintf("0 50 %d %s", port.Port, hostname)
+		ep := endpoint.NewEndpointWithTTL(recordName, endpoint.RecordTypeSRV, ttl, target).
+			WithTargetMetadata(target, endpoint.TargetMetadata{Weight: 50, Port: int64(port.Port)})
+		endpoints = append(endpoints, ep)
+	}
+	return endpoints
+}
+
 func (sc *serviceSource) AddEventHandler(handler func() error, stopChan <-chan struct{}, minInterval time.Duration) {
 	// Add custom resource event handler
 	log.Debug("Adding (bounded) event handler for service")
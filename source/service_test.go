@@ -17,6 +17,7 @@ limitations under the License.
 package source
 
 import (
+	"fmt"
 	"net"
 	"testing"
 	"time"
@@ -43,6 +44,7 @@ func (suite *ServiceSuite) SetupTest() {
 	var err error
 
 	suite.sc, err = NewServiceSource(
+		NewInformerFactories(),
 		fakeClient,
 		"",
 		"",
@@ -53,6 +55,10 @@ func (suite *ServiceSuite) SetupTest() {
 		false,
 		[]string{},
 		false,
+		false,
+		false,
+		"",
+		false,
 	)
 	suite.fooWithTargets = &v1.Service{
 		Spec: v1.ServiceSpec{
@@ -135,6 +141,7 @@ func testServiceSourceNewServiceSource(t *testing.T) {
 	} {
 		t.Run(ti.title, func(t *testing.T) {
 			_, err := NewServiceSource(
+				NewInformerFactories(),
 				fake.NewSimpleClientset(),
 				"",
 				ti.annotationFilter,
@@ -145,6 +152,10 @@ func testServiceSourceNewServiceSource(t *testing.T) {
 				false,
 				ti.serviceTypesFilter,
 				false,
+				false,
+				false,
+				"",
+				false,
 			)
 
 			if ti.expectError {
@@ -238,6 +249,30 @@ func testServiceSourceEndpoints(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"annotated dual-stack services return an A and an AAAA endpoint",
+			"",
+			"",
+			"testing",
+			"foo",
+			v1.ServiceTypeLoadBalancer,
+			"",
+			"",
+			false,
+			false,
+			map[string]string{},
+			map[string]string{
+				hostnameAnnotationKey: "foo.example.org.",
+			},
+			"",
+			[]string{"1.2.3.4", "2001:db8::1"},
+			[]string{},
+			[]*endpoint.Endpoint{
+				{DNSName: "foo.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}},
+				{DNSName: "foo.example.org", RecordType: endpoint.RecordTypeAAAA, Targets: endpoint.Targets{"2001:db8::1"}},
+			},
+			false,
+		},
 		{
 			"hostname annotation on services is ignored",
 			"",
@@ -1099,6 +1134,7 @@ func testServiceSourceEndpoints(t *testing.T) {
 
 			// Create our object under test and get the endpoints.
 			client, _ := NewServiceSource(
+				NewInformerFactories(),
 				kubernetes,
 				tc.targetNamespace,
 				tc.annotationFilter,
@@ -1109,6 +1145,10 @@ func testServiceSourceEndpoints(t *testing.T) {
 				false,
 				tc.serviceTypesFilter,
 				tc.ignoreHostnameAnnotation,
+				false,
+				false,
+				"",
+				false,
 			)
 			require.NoError(t, err)
 
@@ -1269,6 +1309,7 @@ func TestClusterIpServices(t *testing.T) {
 
 			// Create our object under test and get the endpoints.
 			client, _ := NewServiceSource(
+				NewInformerFactories(),
 				kubernetes,
 				tc.targetNamespace,
 				tc.annotationFilter,
@@ -1279,6 +1320,10 @@ func TestClusterIpServices(t *testing.T) {
 				false,
 				[]string{},
 				tc.ignoreHostnameAnnotation,
+				false,
+				false,
+				"",
+				false,
 			)
 			require.NoError(t, err)
 
@@ -1600,6 +1645,7 @@ func TestNodePortServices(t *testing.T) {
 
 			// Create our object under test and get the endpoints.
 			client, _ := NewServiceSource(
+				NewInformerFactories(),
 				kubernetes,
 				tc.targetNamespace,
 				tc.annotationFilter,
@@ -1610,6 +1656,10 @@ func TestNodePortServices(t *testing.T) {
 				false,
 				[]string{},
 				tc.ignoreHostnameAnnotation,
+				false,
+				false,
+				"",
+				false,
 			)
 			require.NoError(t, err)
 
@@ -1834,6 +1884,7 @@ func TestHeadlessServices(t *testing.T) {
 
 			// Create our object under test and get the endpoints.
 			client, _ := NewServiceSource(
+				NewInformerFactories(),
 				kubernetes,
 				tc.targetNamespace,
 				"",
@@ -1844,6 +1895,10 @@ func TestHeadlessServices(t *testing.T) {
 				false,
 				[]string{},
 				tc.ignoreHostnameAnnotation,
+				false,
+				false,
+				"",
+				false,
 			)
 			require.NoError(t, err)
 
@@ -2068,6 +2123,7 @@ func TestHeadlessServicesHostIP(t *testing.T) {
 
 			// Create our object under test and get the endpoints.
 			client, _ := NewServiceSource(
+				NewInformerFactories(),
 				kubernetes,
 				tc.targetNamespace,
 				"",
@@ -2078,6 +2134,10 @@ func TestHeadlessServicesHostIP(t *testing.T) {
 				true,
 				[]string{},
 				tc.ignoreHostnameAnnotation,
+				false,
+				false,
+				"",
+				false,
 			)
 			require.NoError(t, err)
 
@@ -2172,6 +2232,7 @@ func TestExternalServices(t *testing.T) {
 
 			// Create our object under test and get the endpoints.
 			client, _ := NewServiceSource(
+				NewInformerFactories(),
 				kubernetes,
 				tc.targetNamespace,
 				"",
@@ -2182,6 +2243,10 @@ func TestExternalServices(t *testing.T) {
 				false,
 				[]string{},
 				tc.ignoreHostnameAnnotation,
+				false,
+				false,
+				"",
+				false,
 			)
 			require.NoError(t, err)
 
@@ -2198,6 +2263,195 @@ func TestExternalServices(t *testing.T) {
 	}
 }
 
+func TestServicePortSRVEndpoints(t *testing.T) {
+	for _, tc := range []struct {
+		title    string
+		svcType  v1.ServiceType
+		srvPorts string
+		expected []*endpoint.Endpoint
+	}{
+		{
+			title:    "all ports of a load balancer are published",
+			svcType:  v1.ServiceTypeLoadBalancer,
+			srvPorts: "true",
+			expected: []*endpoint.Endpoint{
+				{DNSName: "sip.example.org", Targets: endpoint.Targets{"1.2.3.4"}, RecordType: endpoint.RecordTypeA, RecordTTL: 300},
+				{DNSName: "_sip._udp.sip.example.org", Targets: endpoint.Targets{"0 50 5060 sip.example.org"}, RecordType: endpoint.RecordTypeSRV, RecordTTL: 300},
+				{DNSName: "_sips._tcp.sip.example.org", Targets: endpoint.Targets{"0 50 5061 sip.example.org"}, RecordType: endpoint.RecordTypeSRV, RecordTTL: 300},
+				{DNSName: "_8080._tcp.sip.example.org", Targets: endpoint.Targets{"0 50 8080 sip.example.org"}, RecordType: endpoint.RecordTypeSRV, RecordTTL: 300},
+			},
+		},
+		{
+			title:    "only the listed ports are published",
+			svcType:  v1.ServiceTypeLoadBalancer,
+			srvPorts: "sips, 8080",
+			expected: []*endpoint.Endpoint{
+				{DNSName: "sip.example.org", Targets: endpoint.Targets{"1.2.3.4"}, RecordType: endpoint.RecordTypeA, RecordTTL: 300},
+				{DNSName: "_sips._tcp.sip.example.org", Targets: endpoint.Targets{"0 50 5061 sip.example.org"}, RecordType: endpoint.RecordTypeSRV, RecordTTL: 300},
+				{DNSName: "_8080._tcp.sip.example.org", Targets: endpoint.Targets{"0 50 8080 sip.example.org"}, RecordType: endpoint.RecordTypeSRV, RecordTTL: 300},
+			},
+		},
+		{
+			title:   "without the annotation no SRV records are published",
+			svcType: v1.ServiceTypeLoadBalancer,
+			expected: []*endpoint.Endpoint{
+				{DNSName: "sip.example.org", Targets: endpoint.Targets{"1.2.3.4"}, RecordType: endpoint.RecordTypeA, RecordTTL: 300},
+			},
+		},
+		{
+			title:    "services without a published address get no SRV records",
+			svcType:  v1.ServiceTypeClusterIP,
+			srvPorts: "true",
+			expected: []*endpoint.Endpoint{},
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			svc := &v1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "testing",
+					Name:      "sip",
+					Annotations: map[string]string{
+						ttlAnnotationKey:      "300",
+						srvPortsAnnotationKey: tc.srvPorts,
+					},
+				},
+				Spec: v1.ServiceSpec{
+					Type:      tc.svcType,
+					ClusterIP: "10.0.0.1",
+					Ports: []v1.ServicePort{
+						{Name: "sip", Protocol: v1.ProtocolUDP, Port: 5060},
+						{Name: "sips", Protocol: v1.ProtocolTCP, Port: 5061},
+						{Port: 8080},
+					},
+				},
+				Status: v1.ServiceStatus{
+					LoadBalancer: v1.LoadBalancerStatus{
+						Ingress: []v1.LoadBalancerIngress{{IP: "1.2.3.4"}},
+					},
+				},
+			}
+			sc := &serviceSource{}
+
+			endpoints := sc.generateEndpoints(svc, "sip.example.org.", endpoint.ProviderSpecific{}, "")
+			validateEndpoints(t, endpoints, tc.expected)
+			for _, ep := range endpoints {
+				if ep.RecordType != endpoint.RecordTypeSRV {
+					continue
+				}
+				metadata, ok := ep.GetTargetMetadata(ep.Targets[0])
+				assert.True(t, ok, ep.DNSName)
+				assert.Equal(t, int64(50), metadata.Weight, ep.DNSName)
+				assert.Contains(t, ep.Targets[0], fmt.Sprintf(" %d ", metadata.Port), ep.DNSName)
+			}
+		})
+	}
+}
+
+func TestServiceInternalEndpoints(t *testing.T) {
+	for _, tc := range []struct {
+		title            string
+		internalZone     string
+		svcType          v1.ServiceType
+		clusterIP        string
+		internalHostname string
+		expected         []*endpoint.Endpoint
+	}{
+		{
+			title:            "hostnames in the internal zone point at the cluster IP",
+			internalZone:     "internal.example.org",
+			svcType:          v1.ServiceTypeClusterIP,
+			clusterIP:        "10.0.0.1",
+			internalHostname: "api.internal.example.org., Web.Internal.example.org",
+			expected: []*endpoint.Endpoint{
+				{DNSName: "api.internal.example.org", Targets: endpoint.Targets{"10.0.0.1"}, RecordType: endpoint.RecordTypeA, RecordTTL: 300},
+				{DNSName: "Web.Internal.example.org", Targets: endpoint.Targets{"10.0.0.1"}, RecordType: endpoint.RecordTypeA, RecordTTL: 300},
+			},
+		},
+		{
+			title:            "hostnames outside of the internal zone are skipped",
+			internalZone:     "internal.example.org",
+			svcType:          v1.ServiceTypeClusterIP,
+			clusterIP:        "10.0.0.1",
+			internalHostname: "api.example.org,api.notinternal.example.org,api.internal.example.org",
+			expected: []*endpoint.Endpoint{
+				{DNSName: "api.internal.example.org", Targets: endpoint.Targets{"10.0.0.1"}, RecordType: endpoint.RecordTypeA, RecordTTL: 300},
+			},
+		},
+		{
+			title:            "nothing is published without an internal zone",
+			svcType:          v1.ServiceTypeClusterIP,
+			clusterIP:        "10.0.0.1",
+			internalHostname: "api.internal.example.org",
+			expected:         []*endpoint.Endpoint{},
+		},
+		{
+			title:            "headless services are skipped",
+			internalZone:     "internal.example.org",
+			svcType:          v1.ServiceTypeClusterIP,
+			clusterIP:        v1.ClusterIPNone,
+			internalHostname: "api.internal.example.org",
+			expected:         []*endpoint.Endpoint{},
+		},
+		{
+			title:            "other service types are skipped",
+			internalZone:     "internal.example.org",
+			svcType:          v1.ServiceTypeLoadBalancer,
+			clusterIP:        "10.0.0.1",
+			internalHostname: "api.internal.example.org",
+			expected:         []*endpoint.Endpoint{},
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			svc := &v1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "testing",
+					Name:      "api",
+					Annotations: map[string]string{
+						ttlAnnotationKey:              "300",
+						internalHostnameAnnotationKey: tc.internalHostname,
+					},
+				},
+				Spec: v1.ServiceSpec{
+					Type:      tc.svcType,
+					ClusterIP: tc.clusterIP,
+				},
+			}
+			sc := &serviceSource{internalZone: tc.internalZone}
+
+			validateEndpoints(t, sc.internalEndpoints(svc), tc.expected)
+		})
+	}
+}
+
+func TestServiceSourceInternalZone(t *testing.T) {
+	kubernetes := fake.NewSimpleClientset()
+
+	_, err := kubernetes.CoreV1().Services("testing").Create(&v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "testing",
+			Name:      "api",
+			Annotations: map[string]string{
+				hostnameAnnotationKey:         "api.example.org",
+				internalHostnameAnnotationKey: "api.internal.example.org",
+			},
+		},
+		Spec: v1.ServiceSpec{
+			Type:      v1.ServiceTypeClusterIP,
+			ClusterIP: "10.0.0.1",
+		},
+	})
+	require.NoError(t, err)
+
+	client, err := NewServiceSource(NewInformerFactories(), kubernetes, "", "", "", false, "", false, false, []string{}, false, false, false, "internal.example.org.", false)
+	require.NoError(t, err)
+
+	endpoints, err := client.Endpoints()
+	require.NoError(t, err)
+	validateEndpoints(t, endpoints, []*endpoint.Endpoint{
+		{DNSName: "api.internal.example.org", Targets: endpoint.Targets{"10.0.0.1"}, RecordType: endpoint.RecordTypeA},
+	})
+}
+
 func BenchmarkServiceEndpoints(b *testing.B) {
 	kubernetes := fake.NewSimpleClientset()
 
@@ -2222,7 +2476,7 @@ func BenchmarkServiceEndpoints(b *testing.B) {
 	_, err := kubernetes.CoreV1().Services(service.Namespace).Create(service)
 	require.NoError(b, err)
 
-	client, err := NewServiceSource(kubernetes, v1.NamespaceAll, "", "", false, "", false, false, []string{}, false)
+	client, err := NewServiceSource(NewInformerFactories(), kubernetes, v1.NamespaceAll, "", "", false, "", false, false, []string{}, false, false, false, "", false)
 	require.NoError(b, err)
 
 	for i := 0; i < b.N; i++ {
@@ -24,6 +24,8 @@ import (
 	"strings"
 	"time"
 
+	log "github.com/sirupsen/logrus"
+
 	"sigs.k8s.io/external-dns/endpoint"
 )
 
@@ -38,6 +40,8 @@ const (
 	ttlAnnotationKey = "external-dns.alpha.kubernetes.io/ttl"
 	// The annotation used for switching to the alias record types e. g. AWS Alias records instead of a normal CNAME
 	aliasAnnotationKey = "external-dns.alpha.kubernetes.io/alias"
+	// The annotation used for defining the description providers write as record comment
+	descriptionAnnotationKey = "external-dns.alpha.kubernetes.io/description"
 	// The value of the controller annotation so that we feel responsible
 	controllerAnnotationValue = "dns-controller"
 )
@@ -50,6 +54,20 @@ const (
 	SetIdentifierKey = "external-dns.alpha.kubernetes.io/set-identifier"
 )
 
+// Health check annotations, materialized by the providers supporting health checks
+const (
+	// The annotation used for defining the protocol of the health check of the targets, i.e. HTTP, HTTPS or TCP
+	healthCheckProtocolAnnotationKey = "external-dns.alpha.kubernetes.io/health-check-protocol"
+	// The annotation used for defining the port of the health check
+	healthCheckPortAnnotationKey = "external-dns.alpha.kubernetes.io/health-check-port"
+	// The annotation used for defining the path requested by HTTP and HTTPS health checks
+	healthCheckPathAnnotationKey = "external-dns.alpha.kubernetes.io/health-check-path"
+	// The annotation used for defining the interval between two checks in seconds
+	healthCheckIntervalAnnotationKey = "external-dns.alpha.kubernetes.io/health-check-interval"
+	// The annotation used for defining the number of failed checks before a target is unhealthy
+	healthCheckFailureThresholdAnnotationKey = "external-dns.alpha.kubernetes.io/health-check-failure-threshold"
+)
+
 const (
 	ttlMinimum = 1
 	ttlMaximum = math.MaxInt32
@@ -123,6 +141,20 @@ func getProviderSpecificAnnotations(annotations map[string]string) (endpoint.Pro
 			Value: "true",
 		})
 	}
+	healthCheck, err := getHealthCheckFromAnnotations(annotations)
+	if err != nil {
+		log.Warn(err)
+	} else if healthCheck != nil {
+		providerSpecificAnnotations = append(providerSpecificAnnotations, healthCheck.ProviderSpecific()...)
+	}
+	if description := strings.TrimSpace(annotations[descriptionAnnotationKey]); description != "" {
+		providerSpecificAnnotations = append(providerSpecificAnnotations, endpoint.ProviderSpecificProperty{
+			Name:  endpoint.DescriptionProperty,
+			Value: description,
+		})
+	}
+	providerSpecificAnnotations = append(providerSpecificAnnotations, getCutoverFromAnnotations(annotations)...)
+	providerSpecificAnnotations = append(providerSpecificAnnotations, getProviderSelectionFromAnnotations(annotations)...)
 	setIdentifier := ""
 	for k, v := range annotations {
 		if k == SetIdentifierKey {
@@ -138,6 +170,40 @@ func getProviderSpecificAnnotations(annotations map[string]string) (endpoint.Pro
 	return providerSpecificAnnotations, setIdentifier
 }
 
+// getHealthCheckFromAnnotations returns the health check defined by the annotations or nil if there is none.
+func getHealthCheckFromAnnotations(annotations map[string]string) (*endpoint.HealthCheck, error) {
+	protocol, exists := annotations[healthCheckProtocolAnnotationKey]
+	if !exists {
+		return nil, nil
+	}
+
+	hc := endpoint.HealthCheck{
+		Protocol: strings.ToUpper(protocol),
+		Path:     annotations[healthCheckPathAnnotationKey],
+	}
+	for key, value := range map[string]*int{
+		healthCheckPortAnnotationKey:             &hc.Port,
+		healthCheckIntervalAnnotationKey:         &hc.Interval,
+		healthCheckFailureThresholdAnnotationKey: &hc.FailureThreshold,
+	} {
+		annotation, exists := annotations[key]
+		if !exists {
+			continue
+		}
+		i, err := strconv.Atoi(annotation)
+		if err != nil {
+			return nil, fmt.Errorf("\"%v\" is not a valid value of %s", annotation, key)
+		}
+		*value = i
+	}
+
+	healthCheck, err := hc.WithDefaults()
+	if err != nil {
+		return nil, fmt.Errorf("ignoring the health check annotations: %v", err)
+	}
+	return healthCheck, nil
+}
+
 // getTargetsFromTargetAnnotation gets endpoints from optional "target" annotation.
 // Returns empty endpoints array if none are found.
 func getTargetsFromTargetAnnotation(annotations map[string]string) endpoint.Targets {
@@ -159,53 +225,65 @@ func getTargetsFromTargetAnnotation(annotations map[string]string) endpoint.Targ
 // suitableType returns the DNS resource record type suitable for the target.
 // In this case type A for IPs and type CNAME for everything else.
 func suitableType(target string) string {
-	if net.ParseIP(target) != nil {
+	ip := net.ParseIP(target)
+	switch {
+	case ip == nil:
+		return endpoint.RecordTypeCNAME
+	case ip.To4() == nil:
+		return endpoint.RecordTypeAAAA
+	default:
 		return endpoint.RecordTypeA
 	}
-	return endpoint.RecordTypeCNAME
 }
 
-// endpointsForHostname returns the endpoint objects for each host-target combination.
+// endpointsForHostname returns the endpoint objects for each host-target combination. IPv4 and IPv6
+// targets of dual-stack resources result in an A and an AAAA endpoint.
 func endpointsForHostname(hostname string, targets endpoint.Targets, ttl endpoint.TTL, providerSpecific endpoint.ProviderSpecific, setIdentifier string) []*endpoint.Endpoint {
 	var endpoints []*endpoint.Endpoint
 
-	var aTargets endpoint.Targets
-	var cnameTargets endpoint.Targets
-
+	targetsByType := map[string]endpoint.Targets{}
 	for _, t := range targets {
-		switch suitableType(t) {
-		case endpoint.RecordTypeA:
-			aTargets = append(aTargets, t)
-		default:
-			cnameTargets = append(cnameTargets, t)
-		}
+		recordType := suitableType(t)
+		targetsByType[recordType] = append(targetsByType[recordType], t)
 	}
 
-	if len(aTargets) > 0 {
-		epA := &endpoint.Endpoint{
+	for _, recordType := range []string{endpoint.RecordTypeA, endpoint.RecordTypeAAAA, endpoint.RecordTypeCNAME} {
+		if len(targetsByType[recordType]) == 0 {
+			continue
+		}
+		endpoints = append(endpoints, &endpoint.Endpoint{
 			DNSName:          strings.TrimSuffix(hostname, "."),
-			Targets:          aTargets,
+			Targets:          targetsByType[recordType],
 			RecordTTL:        ttl,
-			RecordType:       endpoint.RecordTypeA,
+			RecordType:       recordType,
 			Labels:           endpoint.NewLabels(),
 			ProviderSpecific: providerSpecific,
 			SetIdentifier:    setIdentifier,
-		}
-		endpoints = append(endpoints, epA)
+		})
 	}
 
-	if len(cnameTargets) > 0 {
-		epCNAME := &endpoint.Endpoint{
-			DNSName:          strings.TrimSuffix(hostname, "."),
-			Targets:          cnameTargets,
-			RecordTTL:        ttl,
-			RecordType:       endpoint.RecordTypeCNAME,
-			Labels:           endpoint.NewLabels(),
-			ProviderSpecific: providerSpecific,
-			SetIdentifier:    setIdentifier,
-		}
-		endpoints = append(endpoints, epCNAME)
+	return endpoints
+}
+
+// endpointsForHostnames returns the endpoints for each of the hostnames pointing at the targets,
+// taking the TTL and provider-specific annotations into account.
+func endpointsForHostnames(hostnames []string, targets endpoint.Targets, annotations map[string]string, resource string) []*endpoint.Endpoint {
+	if len(targets) == 0 {
+		return nil
+	}
+
+	ttl, err := getTTLFromAnnotations(annotations)
+	if err != nil {
+		log.Warnf("Unable to use the TTL of %s: %v", resource, err)
 	}
+	providerSpecific, setIdentifier := getProviderSpecificAnnotations(annotations)
 
+	var endpoints []*endpoint.Endpoint
+	for _, hostname := range hostnames {
+		if hostname == "" {
+			continue
+		}
+		endpoints = append(endpoints, endpointsForHostname(hostname, targets, ttl, providerSpecific, setIdentifier)...)
+	}
 	return endpoints
 }
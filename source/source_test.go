@@ -21,6 +21,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"sigs.k8s.io/external-dns/endpoint"
 )
@@ -94,6 +95,8 @@ func TestSuitableType(t *testing.T) {
 		target, recordType, expected string
 	}{
 		{"8.8.8.8", "", "A"},
+		{"2001:db8::1", "", "AAAA"},
+		{"::ffff:8.8.8.8", "", "A"},
 		{"foo.example.org", "", "CNAME"},
 		{"bar.eu-central-1.elb.amazonaws.com", "", "CNAME"},
 	} {
@@ -105,3 +108,87 @@ func TestSuitableType(t *testing.T) {
 		}
 	}
 }
+
+func TestEndpointsForHostnameDualStack(t *testing.T) {
+	endpoints := endpointsForHostname("dual.example.org.", endpoint.Targets{"2001:db8::1", "1.2.3.4", "lb.example.com", "2001:db8::2"}, endpoint.TTL(60), nil, "eu")
+	require.Len(t, endpoints, 3)
+	for i, expected := range []struct {
+		recordType string
+		targets    endpoint.Targets
+	}{
+		{endpoint.RecordTypeA, endpoint.Targets{"1.2.3.4"}},
+		{endpoint.RecordTypeAAAA, endpoint.Targets{"2001:db8::1", "2001:db8::2"}},
+		{endpoint.RecordTypeCNAME, endpoint.Targets{"lb.example.com"}},
+	} {
+		assert.Equal(t, "dual.example.org", endpoints[i].DNSName)
+		assert.Equal(t, expected.recordType, endpoints[i].RecordType)
+		assert.Equal(t, expected.targets, endpoints[i].Targets)
+		assert.Equal(t, endpoint.TTL(60), endpoints[i].RecordTTL)
+		assert.Equal(t, "eu", endpoints[i].SetIdentifier)
+	}
+}
+
+func TestGetHealthCheckFromAnnotations(t *testing.T) {
+	for _, tc := range []struct {
+		title       string
+		annotations map[string]string
+		expected    *endpoint.HealthCheck
+		expectErr   bool
+	}{
+		{
+			title:       "health check annotations not present",
+			annotations: map[string]string{"foo": "bar"},
+		},
+		{
+			title: "health check with defaults",
+			annotations: map[string]string{
+				healthCheckProtocolAnnotationKey: "http",
+			},
+			expected: &endpoint.HealthCheck{Protocol: endpoint.HealthCheckProtocolHTTP, Port: 80, Path: "/", Interval: 30, FailureThreshold: 3},
+		},
+		{
+			title: "health check with all annotations",
+			annotations: map[string]string{
+				healthCheckProtocolAnnotationKey:         "HTTPS",
+				healthCheckPortAnnotationKey:             "8443",
+				healthCheckPathAnnotationKey:             "/healthz",
+				healthCheckIntervalAnnotationKey:         "10",
+				healthCheckFailureThresholdAnnotationKey: "2",
+			},
+			expected: &endpoint.HealthCheck{Protocol: endpoint.HealthCheckProtocolHTTPS, Port: 8443, Path: "/healthz", Interval: 10, FailureThreshold: 2},
+		},
+		{
+			title: "health check port is not a number",
+			annotations: map[string]string{
+				healthCheckProtocolAnnotationKey: "TCP",
+				healthCheckPortAnnotationKey:     "ssh",
+			},
+			expectErr: true,
+		},
+		{
+			title: "invalid health check",
+			annotations: map[string]string{
+				healthCheckProtocolAnnotationKey: "TCP",
+			},
+			expectErr: true,
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			healthCheck, err := getHealthCheckFromAnnotations(tc.annotations)
+			if tc.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, healthCheck)
+		})
+	}
+}
+
+func TestGetProviderSpecificAnnotationsDescription(t *testing.T) {
+	providerSpecific, _ := getProviderSpecificAnnotations(map[string]string{descriptionAnnotationKey: " Checkout frontend, owned by team payments "})
+	assert.Equal(t, endpoint.ProviderSpecific{{Name: endpoint.DescriptionProperty, Value: "Checkout frontend, owned by team payments"}}, providerSpecific)
+
+	providerSpecific, _ = getProviderSpecificAnnotations(map[string]string{descriptionAnnotationKey: ""})
+	assert.Empty(t, providerSpecific)
+}
@@ -0,0 +1,264 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	appsinformers "k8s.io/client-go/informers/apps/v1"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// The label set by the StatefulSet controller to the name of each replica pod
+const statefulSetPodNameLabelKey = "statefulset.kubernetes.io/pod-name"
+
+// statefulSetSource is an implementation of Source for the replicas of annotated StatefulSets.
+// Every replica is published as <pod name>.<hostname> for each hostname of the StatefulSet, which
+// clustered databases like Kafka or Cassandra need to be reachable from outside the cluster.
+// A replica points at the load balancer of a per-replica service selecting it by its pod name label
+// or otherwise at the external IP of the node it's running on.
+type statefulSetSource struct {
+	client              kubernetes.Interface
+	namespace           string
+	annotationFilter    string
+	statefulSetInformer appsinformers.StatefulSetInformer
+	podInformer         coreinformers.PodInformer
+	nodeInformer        coreinformers.NodeInformer
+	serviceInformer     coreinformers.ServiceInformer
+}
+
+// NewStatefulSetSource creates a new statefulSetSource with the given config.
+func NewStatefulSetSource(informers *InformerFactories, kubeClient kubernetes.Interface, namespace, annotationFilter string) (Source, error) {
+	// Use shared informers to listen for add/update/delete of statefulsets/pods/nodes/services in the specified namespace.
+	// Set resync period to 0, to prevent processing when nothing has changed.
+	informerFactory := informers.Kube(kubeClient, namespace)
+	statefulSetInformer := informerFactory.Apps().V1().StatefulSets()
+	podInformer := informerFactory.Core().V1().Pods()
+	nodeInformer := informerFactory.Core().V1().Nodes()
+	serviceInformer := informerFactory.Core().V1().Services()
+
+	// Add default resource event handlers to properly initialize informer.
+	for _, informer := range []cache.SharedIndexInformer{statefulSetInformer.Informer(), podInformer.Informer(), nodeInformer.Informer(), serviceInformer.Informer()} {
+		informer.AddEventHandler(
+			cache.ResourceEventHandlerFuncs{
+				AddFunc: func(obj interface{}) {
+				},
+			},
+		)
+	}
+
+	// TODO informer is not explicitly stopped since controller is not passing in its channel.
+	informerFactory.Start(wait.NeverStop)
+
+	// wait for the local cache to be populated.
+	err := wait.Poll(time.Second, 60*time.Second, func() (bool, error) {
+		return statefulSetInformer.Informer().HasSynced() &&
+			podInformer.Informer().HasSynced() &&
+			nodeInformer.Informer().HasSynced() &&
+			serviceInformer.Informer().HasSynced(), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sync cache: %v", err)
+	}
+
+	return &statefulSetSource{
+		client:              kubeClient,
+		namespace:           namespace,
+		annotationFilter:    annotationFilter,
+		statefulSetInformer: statefulSetInformer,
+		podInformer:         podInformer,
+		nodeInformer:        nodeInformer,
+		serviceInformer:     serviceInformer,
+	}, nil
+}
+
+// Endpoints returns endpoint objects for each replica of the annotated statefulsets.
+func (sc *statefulSetSource) Endpoints() ([]*endpoint.Endpoint, error) {
+	statefulSets, err := sc.statefulSetInformer.Lister().StatefulSets(sc.namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	statefulSets, err = sc.filterByAnnotations(statefulSets)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := []*endpoint.Endpoint{}
+
+	for _, sts := range statefulSets {
+		// Check controller annotation to see if we are responsible.
+		controller, ok := sts.Annotations[controllerAnnotationKey]
+		if ok && controller != controllerAnnotationValue {
+			log.Debugf("Skipping statefulset %s/%s because controller value does not match, found: %s, required: %s",
+				sts.Namespace, sts.Name, controller, controllerAnnotationValue)
+			continue
+		}
+
+		hostnames := getHostnamesFromAnnotations(sts.Annotations)
+		if len(hostnames) == 0 {
+			continue
+		}
+
+		stsEndpoints, err := sc.endpointsFromStatefulSet(sts, hostnames)
+		if err != nil {
+			return nil, err
+		}
+		if len(stsEndpoints) == 0 {
+			log.Debugf("No endpoints could be generated from statefulset %s/%s", sts.Namespace, sts.Name)
+			continue
+		}
+
+		log.Debugf("Endpoints generated from statefulset: %s/%s: %v", sts.Namespace, sts.Name, stsEndpoints)
+		for _, ep := range stsEndpoints {
+			ep.Labels[endpoint.ResourceLabelKey] = fmt.Sprintf("statefulset/%s/%s", sts.Namespace, sts.Name)
+		}
+		endpoints = append(endpoints, stsEndpoints...)
+	}
+
+	for _, ep := range endpoints {
+		sort.Sort(ep.Targets)
+	}
+
+	return endpoints, nil
+}
+
+func (sc *statefulSetSource) endpointsFromStatefulSet(sts *appsv1.StatefulSet, hostnames []string) ([]*endpoint.Endpoint, error) {
+	selector, err := metav1.LabelSelectorAsSelector(sts.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+	pods, err := sc.podInformer.Lister().Pods(sts.Namespace).List(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	services, err := sc.serviceInformer.Lister().Services(sts.Namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(pods, func(i, j int) bool {
+		return pods[i].Name < pods[j].Name
+	})
+
+	var endpoints []*endpoint.Endpoint
+	for _, pod := range pods {
+		if !metav1.IsControlledBy(pod, sts) {
+			continue
+		}
+		if pod.DeletionTimestamp != nil {
+			log.Debugf("Skipping pod %s/%s because it is terminating", pod.Namespace, pod.Name)
+			continue
+		}
+
+		targets := replicaServiceTargets(pod, services)
+		if len(targets) == 0 {
+			targets = sc.nodeExternalTargets(pod)
+		}
+		if len(targets) == 0 {
+			log.Debugf("Skipping pod %s/%s because it has no external address", pod.Namespace, pod.Name)
+			continue
+		}
+
+		replicaHostnames := make([]string, 0, len(hostnames))
+		for _, hostname := range hostnames {
+			replicaHostnames = append(replicaHostnames, pod.Name+"."+hostname)
+		}
+		endpoints = append(endpoints, endpointsForHostnames(replicaHostnames, targets, sts.Annotations, fmt.Sprintf("statefulset %s/%s", sts.Namespace, sts.Name))...)
+	}
+
+	return endpoints, nil
+}
+
+// replicaServiceTargets returns the load balancer addresses of the service dedicated to a single replica.
+func replicaServiceTargets(pod *v1.Pod, services []*v1.Service) endpoint.Targets {
+	for _, svc := range services {
+		if svc.Spec.Type != v1.ServiceTypeLoadBalancer || svc.Spec.Selector[statefulSetPodNameLabelKey] != pod.Name {
+			continue
+		}
+		if !labels.SelectorFromSet(svc.Spec.Selector).Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+		if targets := extractLoadBalancerTargets(svc); len(targets) > 0 {
+			return targets
+		}
+	}
+	return nil
+}
+
+// nodeExternalTargets returns the external IPs of the node a pod is running on.
+func (sc *statefulSetSource) nodeExternalTargets(pod *v1.Pod) endpoint.Targets {
+	if pod.Spec.NodeName == "" {
+		return nil
+	}
+	node, err := sc.nodeInformer.Lister().Get(pod.Spec.NodeName)
+	if err != nil {
+		log.Debugf("Unable to find node %s of pod %s/%s", pod.Spec.NodeName, pod.Namespace, pod.Name)
+		return nil
+	}
+
+	var targets endpoint.Targets
+	for _, address := range node.Status.Addresses {
+		if address.Type == v1.NodeExternalIP {
+			targets = append(targets, address.Address)
+		}
+	}
+	return targets
+}
+
+// filterByAnnotations filters a list of statefulsets by a given annotation selector.
+func (sc *statefulSetSource) filterByAnnotations(statefulSets []*appsv1.StatefulSet) ([]*appsv1.StatefulSet, error) {
+	labelSelector, err := metav1.ParseToLabelSelector(sc.annotationFilter)
+	if err != nil {
+		return nil, err
+	}
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	// empty filter returns original list
+	if selector.Empty() {
+		return statefulSets, nil
+	}
+
+	filteredList := []*appsv1.StatefulSet{}
+
+	for _, sts := range statefulSets {
+		// include statefulset if its annotations match the selector
+		if selector.Matches(labels.Set(sts.Annotations)) {
+			filteredList = append(filteredList, sts)
+		}
+	}
+
+	return filteredList, nil
+}
+
+func (sc *statefulSetSource) AddEventHandler(handler func() error, stopChan <-chan struct{}, minInterval time.Duration) {
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestStatefulSetEndpoints(t *testing.T) {
+	kafkaLabels := map[string]string{"app": "kafka"}
+	isController := true
+
+	sts := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "testing",
+			Name:      "kafka",
+			UID:       types.UID("kafka-uid"),
+			Annotations: map[string]string{
+				hostnameAnnotationKey: "kafka.example.org",
+			},
+		},
+		Spec: appsv1.StatefulSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: kafkaLabels},
+		},
+	}
+
+	newPod := func(name, nodeName string) *v1.Pod {
+		podLabels := map[string]string{statefulSetPodNameLabelKey: name}
+		for k, v := range kafkaLabels {
+			podLabels[k] = v
+		}
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "testing",
+				Name:      name,
+				Labels:    podLabels,
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: "apps/v1",
+					Kind:       "StatefulSet",
+					Name:       sts.Name,
+					UID:        sts.UID,
+					Controller: &isController,
+				}},
+			},
+			Spec: v1.PodSpec{NodeName: nodeName},
+		}
+	}
+
+	kubernetes := fake.NewSimpleClientset()
+
+	_, err := kubernetes.AppsV1().StatefulSets(sts.Namespace).Create(sts)
+	require.NoError(t, err)
+
+	for _, pod := range []*v1.Pod{newPod("kafka-0", "node-a"), newPod("kafka-1", "node-b"), newPod("kafka-2", "")} {
+		_, err := kubernetes.CoreV1().Pods(pod.Namespace).Create(pod)
+		require.NoError(t, err)
+	}
+
+	for name, ip := range map[string]string{"node-a": "1.1.1.1", "node-b": "2.2.2.2"} {
+		node := &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: v1.NodeStatus{
+				Addresses: []v1.NodeAddress{
+					{Type: v1.NodeInternalIP, Address: "10.0.0.1"},
+					{Type: v1.NodeExternalIP, Address: ip},
+				},
+			},
+		}
+		_, err := kubernetes.CoreV1().Nodes().Create(node)
+		require.NoError(t, err)
+	}
+
+	// kafka-1 has a dedicated load balancer
+	svc := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "testing", Name: "kafka-1-external"},
+		Spec: v1.ServiceSpec{
+			Type:     v1.ServiceTypeLoadBalancer,
+			Selector: map[string]string{statefulSetPodNameLabelKey: "kafka-1"},
+		},
+		Status: v1.ServiceStatus{
+			LoadBalancer: v1.LoadBalancerStatus{Ingress: []v1.LoadBalancerIngress{{Hostname: "kafka-1.elb.example.com"}}},
+		},
+	}
+	_, err = kubernetes.CoreV1().Services(svc.Namespace).Create(svc)
+	require.NoError(t, err)
+
+	client, err := NewStatefulSetSource(NewInformerFactories(), kubernetes, "", "")
+	require.NoError(t, err)
+
+	endpoints, err := client.Endpoints()
+	require.NoError(t, err)
+
+	validateEndpoints(t, endpoints, []*endpoint.Endpoint{
+		{DNSName: "kafka-0.kafka.example.org", Targets: endpoint.Targets{"1.1.1.1"}, RecordType: endpoint.RecordTypeA},
+		{DNSName: "kafka-1.kafka.example.org", Targets: endpoint.Targets{"kafka-1.elb.example.com"}, RecordType: endpoint.RecordTypeCNAME},
+	})
+}
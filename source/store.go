@@ -30,6 +30,7 @@ import (
 	log "github.com/sirupsen/logrus"
 	istiocontroller "istio.io/istio/pilot/pkg/config/kube/crd/controller"
 	istiomodel "istio.io/istio/pilot/pkg/model"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -48,9 +49,17 @@ type Config struct {
 	Compatibility               string
 	PublishInternal             bool
 	PublishHostIP               bool
+	MetalLBAnnouncedOnly        bool
+	InheritNamespaceDefaults    bool
+	InternalZone                string
+	SyncExternalNames           bool
 	ConnectorServer             string
 	CRDSourceAPIVersion         string
 	CRDSourceKind               string
+	JSONPathSourceAPIVersion    string
+	JSONPathSourceKind          string
+	JSONPathSourceHostname      string
+	JSONPathSourceTarget        string
 	KubeConfig                  string
 	KubeMaster                  string
 	ServiceTypeFilter           []string
@@ -59,6 +68,9 @@ type Config struct {
 	CFUsername                  string
 	CFPassword                  string
 	ContourLoadBalancerService  string
+	EmissaryService             string
+	GoogleProject               string
+	APIServerAdditionalServices []string
 }
 
 // ClientGenerator provides clients
@@ -67,6 +79,8 @@ type ClientGenerator interface {
 	IstioClient() (istiomodel.ConfigStore, error)
 	CloudFoundryClient(cfAPPEndpoint string, cfUsername string, cfPassword string) (*cfclient.Client, error)
 	ContourClient() (contour.Interface, error)
+	DynamicKubernetesClient() (dynamic.Interface, error)
+	InformerFactories() *InformerFactories
 }
 
 // SingletonClientGenerator stores provider clients and guarantees that only one instance of client
@@ -79,10 +93,14 @@ type SingletonClientGenerator struct {
 	istioClient    istiomodel.ConfigStore
 	cfClient       *cfclient.Client
 	contourClient  contour.Interface
+	dynamicClient  dynamic.Interface
+	informers      *InformerFactories
 	kubeOnce       sync.Once
 	istioOnce      sync.Once
 	cfOnce         sync.Once
 	contourOnce    sync.Once
+	dynamicOnce    sync.Once
+	informersOnce  sync.Once
 }
 
 // KubeClient generates a kube client if it was not created before
@@ -136,6 +154,23 @@ func (p *SingletonClientGenerator) ContourClient() (contour.Interface, error) {
 	return p.contourClient, err
 }
 
+// DynamicKubernetesClient generates a dynamic client if it was not created before
+func (p *SingletonClientGenerator) DynamicKubernetesClient() (dynamic.Interface, error) {
+	var err error
+	p.dynamicOnce.Do(func() {
+		p.dynamicClient, err = NewDynamicKubernetesClient(p.KubeConfig, p.KubeMaster, p.RequestTimeout)
+	})
+	return p.dynamicClient, err
+}
+
+// InformerFactories returns the informer factories shared by the sources built with the generator
+func (p *SingletonClientGenerator) InformerFactories() *InformerFactories {
+	p.informersOnce.Do(func() {
+		p.informers = NewInformerFactories()
+	})
+	return p.informers
+}
+
 // ByNames returns multiple Sources given multiple names.
 func ByNames(p ClientGenerator, names []string, cfg *Config) ([]Source, error) {
 	sources := []Source{}
@@ -152,25 +187,54 @@ func ByNames(p ClientGenerator, names []string, cfg *Config) ([]Source, error) {
 
 // BuildWithConfig allows to generate a Source implementation from the shared config
 func BuildWithConfig(source string, p ClientGenerator, cfg *Config) (Source, error) {
+	informers := p.InformerFactories()
 	switch source {
 	case "node":
 		client, err := p.KubeClient()
 		if err != nil {
 			return nil, err
 		}
-		return NewNodeSource(client, cfg.AnnotationFilter, cfg.FQDNTemplate)
+		return NewNodeSource(informers, client, cfg.AnnotationFilter, cfg.FQDNTemplate)
 	case "service":
 		client, err := p.KubeClient()
 		if err != nil {
 			return nil, err
 		}
-		return NewServiceSource(client, cfg.Namespace, cfg.AnnotationFilter, cfg.FQDNTemplate, cfg.CombineFQDNAndAnnotation, cfg.Compatibility, cfg.PublishInternal, cfg.PublishHostIP, cfg.ServiceTypeFilter, cfg.IgnoreHostnameAnnotation)
+		return NewServiceSource(informers, client, cfg.Namespace, cfg.AnnotationFilter, cfg.FQDNTemplate, cfg.CombineFQDNAndAnnotation, cfg.Compatibility, cfg.PublishInternal, cfg.PublishHostIP, cfg.ServiceTypeFilter, cfg.IgnoreHostnameAnnotation, cfg.MetalLBAnnouncedOnly, cfg.InheritNamespaceDefaults, cfg.InternalZone, cfg.SyncExternalNames)
 	case "ingress":
 		client, err := p.KubeClient()
 		if err != nil {
 			return nil, err
 		}
-		return NewIngressSource(client, cfg.Namespace, cfg.AnnotationFilter, cfg.FQDNTemplate, cfg.CombineFQDNAndAnnotation, cfg.IgnoreHostnameAnnotation)
+		return NewIngressSource(informers, client, cfg.Namespace, cfg.AnnotationFilter, cfg.FQDNTemplate, cfg.CombineFQDNAndAnnotation, cfg.IgnoreHostnameAnnotation, cfg.InheritNamespaceDefaults)
+	case "statefulset":
+		client, err := p.KubeClient()
+		if err != nil {
+			return nil, err
+		}
+		return NewStatefulSetSource(informers, client, cfg.Namespace, cfg.AnnotationFilter)
+	case "pod":
+		client, err := p.KubeClient()
+		if err != nil {
+			return nil, err
+		}
+		return NewPodSource(informers, client, cfg.Namespace, cfg.AnnotationFilter)
+	case "api-server":
+		client, err := p.KubeClient()
+		if err != nil {
+			return nil, err
+		}
+		return NewAPIServerSource(client, cfg.FQDNTemplate, cfg.APIServerAdditionalServices)
+	case "jsonpath":
+		client, err := p.KubeClient()
+		if err != nil {
+			return nil, err
+		}
+		dynamicClient, err := p.DynamicKubernetesClient()
+		if err != nil {
+			return nil, err
+		}
+		return NewJSONPathSource(informers, client, dynamicClient, cfg.Namespace, cfg.AnnotationFilter, cfg.JSONPathSourceAPIVersion, cfg.JSONPathSourceKind, cfg.JSONPathSourceHostname, cfg.JSONPathSourceTarget)
 	case "istio-gateway":
 		kubernetesClient, err := p.KubeClient()
 		if err != nil {
@@ -180,7 +244,27 @@ func BuildWithConfig(source string, p ClientGenerator, cfg *Config) (Source, err
 		if err != nil {
 			return nil, err
 		}
-		return NewIstioGatewaySource(kubernetesClient, istioClient, cfg.Namespace, cfg.AnnotationFilter, cfg.FQDNTemplate, cfg.CombineFQDNAndAnnotation, cfg.IgnoreHostnameAnnotation)
+		return NewIstioGatewaySource(informers, kubernetesClient, istioClient, cfg.Namespace, cfg.AnnotationFilter, cfg.FQDNTemplate, cfg.CombineFQDNAndAnnotation, cfg.IgnoreHostnameAnnotation)
+	case "istio-virtualservice":
+		kubernetesClient, err := p.KubeClient()
+		if err != nil {
+			return nil, err
+		}
+		istioClient, err := p.IstioClient()
+		if err != nil {
+			return nil, err
+		}
+		return NewIstioVirtualServiceSource(informers, kubernetesClient, istioClient, cfg.Namespace, cfg.AnnotationFilter, cfg.FQDNTemplate, cfg.CombineFQDNAndAnnotation, cfg.IgnoreHostnameAnnotation)
+	case "emissary-host":
+		kubernetesClient, err := p.KubeClient()
+		if err != nil {
+			return nil, err
+		}
+		dynamicClient, err := p.DynamicKubernetesClient()
+		if err != nil {
+			return nil, err
+		}
+		return NewEmissaryHostSource(informers, kubernetesClient, dynamicClient, cfg.EmissaryService, cfg.Namespace, cfg.AnnotationFilter)
 	case "cloudfoundry":
 		cfClient, err := p.CloudFoundryClient(cfg.CFAPIEndpoint, cfg.CFUsername, cfg.CFPassword)
 		if err != nil {
@@ -197,6 +281,69 @@ func BuildWithConfig(source string, p ClientGenerator, cfg *Config) (Source, err
 			return nil, err
 		}
 		return NewContourIngressRouteSource(kubernetesClient, contourClient, cfg.ContourLoadBalancerService, cfg.Namespace, cfg.AnnotationFilter, cfg.FQDNTemplate, cfg.CombineFQDNAndAnnotation, cfg.IgnoreHostnameAnnotation)
+	case "multicluster-service":
+		kubernetesClient, err := p.KubeClient()
+		if err != nil {
+			return nil, err
+		}
+		dynamicClient, err := p.DynamicKubernetesClient()
+		if err != nil {
+			return nil, err
+		}
+		return NewMultiClusterServiceSource(informers, kubernetesClient, dynamicClient, cfg.Namespace, cfg.AnnotationFilter, cfg.FQDNTemplate, cfg.CombineFQDNAndAnnotation, cfg.IgnoreHostnameAnnotation)
+	case "aws-target-group-binding":
+		kubernetesClient, err := p.KubeClient()
+		if err != nil {
+			return nil, err
+		}
+		dynamicClient, err := p.DynamicKubernetesClient()
+		if err != nil {
+			return nil, err
+		}
+		resolver, err := NewDefaultELBV2DNSResolver()
+		if err != nil {
+			return nil, err
+		}
+		return NewTargetGroupBindingSource(informers, kubernetesClient, dynamicClient, resolver, cfg.Namespace, cfg.AnnotationFilter, cfg.IgnoreHostnameAnnotation)
+	case "gke-ingress":
+		kubernetesClient, err := p.KubeClient()
+		if err != nil {
+			return nil, err
+		}
+		resolver, err := NewDefaultComputeAddressResolver(cfg.GoogleProject)
+		if err != nil {
+			return nil, err
+		}
+		return NewGKEIngressSource(informers, kubernetesClient, resolver, cfg.Namespace, cfg.AnnotationFilter, cfg.IgnoreHostnameAnnotation)
+	case "argo-rollout":
+		kubernetesClient, err := p.KubeClient()
+		if err != nil {
+			return nil, err
+		}
+		dynamicClient, err := p.DynamicKubernetesClient()
+		if err != nil {
+			return nil, err
+		}
+		return NewRolloutSource(informers, kubernetesClient, dynamicClient, cfg.Namespace, cfg.AnnotationFilter)
+	case "gateway-httproute", "gateway-tlsroute", "gateway-grpcroute":
+		dynamicClient, err := p.DynamicKubernetesClient()
+		if err != nil {
+			return nil, err
+		}
+		kinds := map[string]string{"gateway-httproute": "HTTPRoute", "gateway-tlsroute": "TLSRoute", "gateway-grpcroute": "GRPCRoute"}
+		return NewGatewayRouteSource(informers, dynamicClient, cfg.Namespace, cfg.AnnotationFilter, kinds[source])
+	case "cert-manager-challenge-delegation":
+		dynamicClient, err := p.DynamicKubernetesClient()
+		if err != nil {
+			return nil, err
+		}
+		return NewCertManagerChallengeSource(informers, dynamicClient, cfg.Namespace, cfg.AnnotationFilter)
+	case "domain-verification":
+		dynamicClient, err := p.DynamicKubernetesClient()
+		if err != nil {
+			return nil, err
+		}
+		return NewDomainVerificationSource(informers, dynamicClient, cfg.Namespace)
 	case "fake":
 		return NewFakeSource(cfg.FQDNTemplate)
 	case "connector":
@@ -284,7 +431,7 @@ func NewIstioClient(kubeConfig string) (*istiocontroller.Client, error) {
 	client, err := istiocontroller.NewClient(
 		kubeConfig,
 		"",
-		istiomodel.ConfigDescriptor{istiomodel.Gateway},
+		istiomodel.ConfigDescriptor{istiomodel.Gateway, istiomodel.VirtualService},
 		"",
 	)
 	if err != nil {
@@ -331,3 +478,40 @@ func NewContourClient(kubeConfig, kubeMaster string, requestTimeout time.Duratio
 
 	return client, nil
 }
+
+// NewDynamicKubernetesClient returns a new dynamic Kubernetes client object used by the sources
+// of custom resources without a typed client. It takes a Config and uses KubeMaster and KubeConfig
+// attributes to connect to the cluster. If KubeConfig isn't provided it defaults to using the
+// recommended default.
+func NewDynamicKubernetesClient(kubeConfig, kubeMaster string, requestTimeout time.Duration) (dynamic.Interface, error) {
+	if kubeConfig == "" {
+		if _, err := os.Stat(clientcmd.RecommendedHomeFile); err == nil {
+			kubeConfig = clientcmd.RecommendedHomeFile
+		}
+	}
+
+	config, err := clientcmd.BuildConfigFromFlags(kubeMaster, kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	config.WrapTransport = func(rt http.RoundTripper) http.RoundTripper {
+		return instrumented_http.NewTransport(rt, &instrumented_http.Callbacks{
+			PathProcessor: func(path string) string {
+				parts := strings.Split(path, "/")
+				return parts[len(parts)-1]
+			},
+		})
+	}
+
+	config.Timeout = requestTimeout
+
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("Created dynamic Kubernetes client %s", config.Host)
+
+	return client, nil
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 	istiomodel "istio.io/istio/pilot/pkg/model"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
 )
@@ -36,6 +37,8 @@ type MockClientGenerator struct {
 	istioClient        istiomodel.ConfigStore
 	cloudFoundryClient *cfclient.Client
 	contourClient      contour.Interface
+	dynamicKubeClient  dynamic.Interface
+	informers          *InformerFactories
 }
 
 func (m *MockClientGenerator) KubeClient() (kubernetes.Interface, error) {
@@ -74,6 +77,22 @@ func (m *MockClientGenerator) ContourClient() (contour.Interface, error) {
 	return nil, args.Error(1)
 }
 
+func (m *MockClientGenerator) DynamicKubernetesClient() (dynamic.Interface, error) {
+	args := m.Called()
+	if args.Error(1) == nil {
+		m.dynamicKubeClient = args.Get(0).(dynamic.Interface)
+		return m.dynamicKubeClient, nil
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockClientGenerator) InformerFactories() *InformerFactories {
+	if m.informers == nil {
+		m.informers = NewInformerFactories()
+	}
+	return m.informers
+}
+
 type ByNamesTestSuite struct {
 	suite.Suite
 }
@@ -118,11 +137,23 @@ func (suite *ByNamesTestSuite) TestKubeClientFails() {
 	_, err = ByNames(mockClientGenerator, []string{"ingress"}, minimalConfig)
 	suite.Error(err, "should return an error if kubernetes client cannot be created")
 
+	_, err = ByNames(mockClientGenerator, []string{"statefulset"}, minimalConfig)
+	suite.Error(err, "should return an error if kubernetes client cannot be created")
+
+	_, err = ByNames(mockClientGenerator, []string{"pod"}, minimalConfig)
+	suite.Error(err, "should return an error if kubernetes client cannot be created")
+
 	_, err = ByNames(mockClientGenerator, []string{"istio-gateway"}, minimalConfig)
 	suite.Error(err, "should return an error if kubernetes client cannot be created")
 
 	_, err = ByNames(mockClientGenerator, []string{"contour-ingressroute"}, minimalConfig)
 	suite.Error(err, "should return an error if kubernetes client cannot be created")
+
+	_, err = ByNames(mockClientGenerator, []string{"multicluster-service"}, minimalConfig)
+	suite.Error(err, "should return an error if kubernetes client cannot be created")
+
+	_, err = ByNames(mockClientGenerator, []string{"argo-rollout"}, minimalConfig)
+	suite.Error(err, "should return an error if kubernetes client cannot be created")
 }
 
 func (suite *ByNamesTestSuite) TestIstioClientFails() {
@@ -138,6 +169,30 @@ func (suite *ByNamesTestSuite) TestIstioClientFails() {
 	suite.Error(err, "should return an error if contour client cannot be created")
 }
 
+func (suite *ByNamesTestSuite) TestDynamicKubernetesClientFails() {
+	mockClientGenerator := new(MockClientGenerator)
+	mockClientGenerator.On("KubeClient").Return(fake.NewSimpleClientset(), nil)
+	mockClientGenerator.On("DynamicKubernetesClient").Return(nil, errors.New("foo"))
+
+	_, err := ByNames(mockClientGenerator, []string{"multicluster-service"}, minimalConfig)
+	suite.Error(err, "should return an error if dynamic kubernetes client cannot be created")
+
+	_, err = ByNames(mockClientGenerator, []string{"argo-rollout"}, minimalConfig)
+	suite.Error(err, "should return an error if dynamic kubernetes client cannot be created")
+
+	_, err = ByNames(mockClientGenerator, []string{"cert-manager-challenge-delegation"}, minimalConfig)
+	suite.Error(err, "should return an error if dynamic kubernetes client cannot be created")
+
+	_, err = ByNames(mockClientGenerator, []string{"gateway-httproute"}, minimalConfig)
+	suite.Error(err, "should return an error if dynamic kubernetes client cannot be created")
+
+	_, err = ByNames(mockClientGenerator, []string{"domain-verification"}, minimalConfig)
+	suite.Error(err, "should return an error if dynamic kubernetes client cannot be created")
+
+	_, err = ByNames(mockClientGenerator, []string{"jsonpath"}, minimalConfig)
+	suite.Error(err, "should return an error if dynamic kubernetes client cannot be created")
+}
+
 func TestByNames(t *testing.T) {
 	suite.Run(t, new(ByNamesTestSuite))
 }
@@ -145,4 +200,5 @@ func TestByNames(t *testing.T) {
 var minimalConfig = &Config{
 	IstioIngressGatewayServices: []string{"istio-system/istio-ingressgateway"},
 	ContourLoadBalancerService:  "heptio-contour/contour",
+	EmissaryService:             "emissary/emissary-ingress",
 }
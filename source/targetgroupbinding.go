@@ -0,0 +1,348 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	kubeinformers "k8s.io/client-go/informers"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+var targetGroupBindingGVR = schema.GroupVersionResource{Group: "elbv2.k8s.aws", Version: "v1beta1", Resource: "targetgroupbindings"}
+
+// elbv2DNSCacheTTL is how long the load balancer of a target group is cached, after which the target group
+// is looked up again in case it was attached to another load balancer.
+const elbv2DNSCacheTTL = 10 * time.Minute
+
+// targetGroupBindingSource is an implementation of Source for services bound to load balancers by
+// the TargetGroupBinding objects of the AWS Load Balancer Controller. The DNS name of the load balancer
+// is looked up through the ELBv2 API as soon as the binding exists instead of waiting for the service
+// status to be updated.
+type targetGroupBindingSource struct {
+	kubeClient                 kubernetes.Interface
+	dynamicKubeClient          dynamic.Interface
+	namespace                  string
+	annotationFilter           string
+	ignoreHostnameAnnotation   bool
+	resolver                   LoadBalancerDNSResolver
+	serviceInformer            coreinformers.ServiceInformer
+	targetGroupBindingInformer kubeinformers.GenericInformer
+}
+
+// LoadBalancerDNSResolver looks up the DNS name of the load balancer a target group is attached to.
+type LoadBalancerDNSResolver interface {
+	LoadBalancerDNSName(targetGroupARN string) (string, error)
+}
+
+// NewTargetGroupBindingSource creates a new targetGroupBindingSource with the given config.
+func NewTargetGroupBindingSource(
+	informers *InformerFactories,
+	kubeClient kubernetes.Interface,
+	dynamicKubeClient dynamic.Interface,
+	resolver LoadBalancerDNSResolver,
+	namespace string,
+	annotationFilter string,
+	ignoreHostnameAnnotation bool,
+) (Source, error) {
+	// Use shared informers to listen for add/update/delete of services and target group bindings in the specified namespace.
+	// Set resync period to 0, to prevent processing when nothing has changed.
+	informerFactory := informers.Kube(kubeClient, namespace)
+	serviceInformer := informerFactory.Core().V1().Services()
+	dynamicInformerFactory := informers.Dynamic(dynamicKubeClient, namespace)
+	targetGroupBindingInformer := dynamicInformerFactory.ForResource(targetGroupBindingGVR)
+
+	// Add default resource event handlers to properly initialize informer.
+	for _, informer := range []cache.SharedIndexInformer{serviceInformer.Informer(), targetGroupBindingInformer.Informer()} {
+		informer.AddEventHandler(
+			cache.ResourceEventHandlerFuncs{
+				AddFunc: func(obj interface{}) {
+				},
+			},
+		)
+	}
+
+	// TODO informer is not explicitly stopped since controller is not passing in its channel.
+	informerFactory.Start(wait.NeverStop)
+	dynamicInformerFactory.Start(wait.NeverStop)
+
+	// wait for the local cache to be populated.
+	err := wait.Poll(time.Second, 60*time.Second, func() (bool, error) {
+		return serviceInformer.Informer().HasSynced() && targetGroupBindingInformer.Informer().HasSynced(), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sync cache: %v", err)
+	}
+
+	return &targetGroupBindingSource{
+		kubeClient:                 kubeClient,
+		dynamicKubeClient:          dynamicKubeClient,
+		namespace:                  namespace,
+		annotationFilter:           annotationFilter,
+		ignoreHostnameAnnotation:   ignoreHostnameAnnotation,
+		resolver:                   resolver,
+		serviceInformer:            serviceInformer,
+		targetGroupBindingInformer: targetGroupBindingInformer,
+	}, nil
+}
+
+// Endpoints returns endpoint objects for each annotated service bound by a TargetGroupBinding.
+func (sc *targetGroupBindingSource) Endpoints() ([]*endpoint.Endpoint, error) {
+	objects, err := sc.targetGroupBindingInformer.Lister().ByNamespace(sc.namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	bindings := make([]*unstructured.Unstructured, 0, len(objects))
+	for _, obj := range objects {
+		if binding, ok := obj.(*unstructured.Unstructured); ok {
+			bindings = append(bindings, binding)
+		}
+	}
+
+	endpoints, err := sc.endpointsFromTargetGroupBindings(bindings)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ep := range endpoints {
+		sort.Sort(ep.Targets)
+	}
+
+	return endpoints, nil
+}
+
+// endpointsFromTargetGroupBindings returns the endpoints of the services bound by the given target group bindings.
+// The load balancer is only looked up while the service status doesn't carry any address yet. A failed lookup
+// fails the sync, so the records of the service aren't deleted while the ELBv2 API is unavailable.
+func (sc *targetGroupBindingSource) endpointsFromTargetGroupBindings(bindings []*unstructured.Unstructured) ([]*endpoint.Endpoint, error) {
+	// a service bound by multiple target groups (e.g. one per port) is published once
+	targetsByService := map[string]endpoint.Targets{}
+	services := map[string]*v1.Service{}
+
+	for _, binding := range bindings {
+		arn, _, _ := unstructured.NestedString(binding.Object, "spec", "targetGroupARN")
+		serviceName, _, _ := unstructured.NestedString(binding.Object, "spec", "serviceRef", "name")
+		if arn == "" || serviceName == "" {
+			log.Debugf("Skipping targetgroupbinding %s/%s because it doesn't reference a target group and service", binding.GetNamespace(), binding.GetName())
+			continue
+		}
+
+		svc, err := sc.serviceInformer.Lister().Services(binding.GetNamespace()).Get(serviceName)
+		if errors.IsNotFound(err) {
+			log.Debugf("Skipping targetgroupbinding %s/%s because service %s doesn't exist", binding.GetNamespace(), binding.GetName(), serviceName)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		key := svc.Namespace + "/" + svc.Name
+		services[key] = svc
+
+		targets := getTargetsFromTargetAnnotation(svc.Annotations)
+		if len(targets) == 0 {
+			targets = extractLoadBalancerTargets(svc)
+		}
+		if len(targets) == 0 {
+			dnsName, err := sc.resolver.LoadBalancerDNSName(arn)
+			if err != nil {
+				return nil, fmt.Errorf("failed to look up the load balancer of targetgroupbinding %s/%s: %v", binding.GetNamespace(), binding.GetName(), err)
+			}
+			if dnsName != "" {
+				targets = endpoint.Targets{dnsName}
+			}
+		}
+		targetsByService[key] = mergeTargets(targetsByService[key], targets)
+	}
+
+	filtered, err := filterServicesByAnnotations(sc.annotationFilter, services)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := []*endpoint.Endpoint{}
+
+	for _, svc := range filtered {
+		// Check controller annotation to see if we are responsible.
+		controller, ok := svc.Annotations[controllerAnnotationKey]
+		if ok && controller != controllerAnnotationValue {
+			log.Debugf("Skipping service %s/%s because controller value does not match, found: %s, required: %s",
+				svc.Namespace, svc.Name, controller, controllerAnnotationValue)
+			continue
+		}
+		if sc.ignoreHostnameAnnotation {
+			continue
+		}
+
+		svcEndpoints := endpointsForHostnames(getHostnamesFromAnnotations(svc.Annotations), targetsByService[svc.Namespace+"/"+svc.Name], svc.Annotations, fmt.Sprintf("service %s/%s", svc.Namespace, svc.Name))
+		if len(svcEndpoints) == 0 {
+			log.Debugf("No endpoints could be generated from service %s/%s", svc.Namespace, svc.Name)
+			continue
+		}
+
+		log.Debugf("Endpoints generated from service: %s/%s: %v", svc.Namespace, svc.Name, svcEndpoints)
+		for _, ep := range svcEndpoints {
+			ep.Labels[endpoint.ResourceLabelKey] = fmt.Sprintf("service/%s/%s", svc.Namespace, svc.Name)
+		}
+		endpoints = append(endpoints, svcEndpoints...)
+	}
+
+	return endpoints, nil
+}
+
+func (sc *targetGroupBindingSource) AddEventHandler(handler func() error, stopChan <-chan struct{}, minInterval time.Duration) {
+}
+
+// filterServicesByAnnotations returns the services matching the annotation filter sorted by namespace and name.
+func filterServicesByAnnotations(annotationFilter string, services map[string]*v1.Service) ([]*v1.Service, error) {
+	labelSelector, err := metav1.ParseToLabelSelector(annotationFilter)
+	if err != nil {
+		return nil, err
+	}
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(services))
+	for key := range services {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	filteredList := []*v1.Service{}
+	for _, key := range keys {
+		// include service if its annotations match the selector
+		if selector.Empty() || selector.Matches(labels.Set(services[key].Annotations)) {
+			filteredList = append(filteredList, services[key])
+		}
+	}
+
+	return filteredList, nil
+}
+
+// mergeTargets appends the targets which aren't part of the existing ones yet.
+func mergeTargets(existing, targets endpoint.Targets) endpoint.Targets {
+	seen := map[string]bool{}
+	for _, t := range existing {
+		seen[t] = true
+	}
+	for _, t := range targets {
+		if !seen[t] {
+			existing = append(existing, t)
+			seen[t] = true
+		}
+	}
+	return existing
+}
+
+// elbv2DNSResolver resolves the load balancers of target groups through the ELBv2 API. Successful lookups
+// are cached for the TTL, failed or empty ones drop the cached load balancer. It's safe for concurrent use
+// by the sources of several pipelines.
+type elbv2DNSResolver struct {
+	client elbv2iface.ELBV2API
+	ttl    time.Duration
+	mutex  sync.Mutex
+	cache  map[string]elbv2DNSCacheEntry
+}
+
+type elbv2DNSCacheEntry struct {
+	dnsName string
+	expires time.Time
+}
+
+// NewELBV2DNSResolver returns a LoadBalancerDNSResolver using the given ELBv2 client.
+func NewELBV2DNSResolver(client elbv2iface.ELBV2API) LoadBalancerDNSResolver {
+	return &elbv2DNSResolver{client: client, ttl: elbv2DNSCacheTTL, cache: map[string]elbv2DNSCacheEntry{}}
+}
+
+// NewDefaultELBV2DNSResolver returns a LoadBalancerDNSResolver using the default AWS credential chain and region.
+func NewDefaultELBV2DNSResolver() (LoadBalancerDNSResolver, error) {
+	sess, err := session.NewSessionWithOptions(session.Options{
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return NewELBV2DNSResolver(elbv2.New(sess)), nil
+}
+
+// LoadBalancerDNSName returns the DNS name of the first load balancer the target group is attached to or
+// an empty string if the target group isn't attached yet.
+func (r *elbv2DNSResolver) LoadBalancerDNSName(targetGroupARN string) (string, error) {
+	r.mutex.Lock()
+	entry, ok := r.cache[targetGroupARN]
+	r.mutex.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.dnsName, nil
+	}
+
+	dnsName, err := r.lookupLoadBalancerDNSName(targetGroupARN)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if err != nil || dnsName == "" {
+		delete(r.cache, targetGroupARN)
+		return "", err
+	}
+	r.cache[targetGroupARN] = elbv2DNSCacheEntry{dnsName: dnsName, expires: time.Now().Add(r.ttl)}
+	return dnsName, nil
+}
+
+func (r *elbv2DNSResolver) lookupLoadBalancerDNSName(targetGroupARN string) (string, error) {
+	tgs, err := r.client.DescribeTargetGroups(&elbv2.DescribeTargetGroupsInput{
+		TargetGroupArns: []*string{aws.String(targetGroupARN)},
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(tgs.TargetGroups) == 0 || len(tgs.TargetGroups[0].LoadBalancerArns) == 0 {
+		return "", nil
+	}
+
+	lbs, err := r.client.DescribeLoadBalancers(&elbv2.DescribeLoadBalancersInput{
+		LoadBalancerArns: tgs.TargetGroups[0].LoadBalancerArns[:1],
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(lbs.LoadBalancers) == 0 {
+		return "", nil
+	}
+	return aws.StringValue(lbs.LoadBalancers[0].DNSName), nil
+}
@@ -0,0 +1,258 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+type fakeLoadBalancerDNSResolver map[string]string
+
+func (r fakeLoadBalancerDNSResolver) LoadBalancerDNSName(targetGroupARN string) (string, error) {
+	if dnsName, ok := r[targetGroupARN]; ok {
+		return dnsName, nil
+	}
+	return "", fmt.Errorf("target group %s not found", targetGroupARN)
+}
+
+type fakeELBV2Client struct {
+	elbv2iface.ELBV2API
+	mutex            sync.Mutex
+	targetGroupCalls int
+	err              error
+}
+
+func (c *fakeELBV2Client) DescribeTargetGroups(input *elbv2.DescribeTargetGroupsInput) (*elbv2.DescribeTargetGroupsOutput, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.targetGroupCalls++
+	if c.err != nil {
+		return nil, c.err
+	}
+	if aws.StringValue(input.TargetGroupArns[0]) != "arn:tg/foo" {
+		return &elbv2.DescribeTargetGroupsOutput{TargetGroups: []*elbv2.TargetGroup{{}}}, nil
+	}
+	return &elbv2.DescribeTargetGroupsOutput{
+		TargetGroups: []*elbv2.TargetGroup{{LoadBalancerArns: []*string{aws.String("arn:lb/foo")}}},
+	}, nil
+}
+
+func (c *fakeELBV2Client) DescribeLoadBalancers(input *elbv2.DescribeLoadBalancersInput) (*elbv2.DescribeLoadBalancersOutput, error) {
+	return &elbv2.DescribeLoadBalancersOutput{
+		LoadBalancers: []*elbv2.LoadBalancer{{DNSName: aws.String("foo-123.elb.us-east-1.amazonaws.com")}},
+	}, nil
+}
+
+func TestELBV2DNSResolver(t *testing.T) {
+	client := &fakeELBV2Client{}
+	resolver := NewELBV2DNSResolver(client)
+
+	for i := 0; i < 2; i++ {
+		dnsName, err := resolver.LoadBalancerDNSName("arn:tg/foo")
+		require.NoError(t, err)
+		assert.Equal(t, "foo-123.elb.us-east-1.amazonaws.com", dnsName)
+	}
+	assert.Equal(t, 1, client.targetGroupCalls, "resolved load balancers should be cached")
+
+	dnsName, err := resolver.LoadBalancerDNSName("arn:tg/unattached")
+	require.NoError(t, err)
+	assert.Equal(t, "", dnsName)
+}
+
+func TestELBV2DNSResolverCacheExpires(t *testing.T) {
+	client := &fakeELBV2Client{}
+	resolver := NewELBV2DNSResolver(client).(*elbv2DNSResolver)
+	resolver.ttl = 0
+
+	dnsName, err := resolver.LoadBalancerDNSName("arn:tg/foo")
+	require.NoError(t, err)
+	assert.Equal(t, "foo-123.elb.us-east-1.amazonaws.com", dnsName)
+
+	// the expired load balancer is looked up again and dropped when the lookup fails
+	client.err = errors.New("throttled")
+	_, err = resolver.LoadBalancerDNSName("arn:tg/foo")
+	assert.Error(t, err)
+	assert.Equal(t, 2, client.targetGroupCalls)
+	assert.Empty(t, resolver.cache)
+}
+
+func TestELBV2DNSResolverConcurrency(t *testing.T) {
+	resolver := NewELBV2DNSResolver(&fakeELBV2Client{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			dnsName, err := resolver.LoadBalancerDNSName("arn:tg/foo")
+			assert.NoError(t, err)
+			assert.Equal(t, "foo-123.elb.us-east-1.amazonaws.com", dnsName)
+		}()
+	}
+	wg.Wait()
+}
+
+func newTestTargetGroupBinding(name, arn, serviceName string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"targetGroupARN": arn,
+			"serviceRef":     map[string]interface{}{"name": serviceName, "port": int64(80)},
+		},
+	}}
+	u.SetAPIVersion("elbv2.k8s.aws/v1beta1")
+	u.SetKind("TargetGroupBinding")
+	u.SetNamespace("testing")
+	u.SetName(name)
+	return u
+}
+
+func TestTargetGroupBindingEndpoints(t *testing.T) {
+	for _, tc := range []struct {
+		title       string
+		svc         *v1.Service
+		bindings    []*unstructured.Unstructured
+		expected    []*endpoint.Endpoint
+		expectError bool
+	}{
+		{
+			title: "load balancer is looked up while the service status is empty",
+			svc: &v1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:   "testing",
+					Name:        "foo",
+					Annotations: map[string]string{hostnameAnnotationKey: "foo.example.org"},
+				},
+			},
+			bindings: []*unstructured.Unstructured{
+				newTestTargetGroupBinding("foo-80", "arn:tg/foo", "foo"),
+				newTestTargetGroupBinding("foo-443", "arn:tg/foo-https", "foo"),
+			},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "foo.example.org", Targets: endpoint.Targets{"foo-123.elb.us-east-1.amazonaws.com"}, RecordType: endpoint.RecordTypeCNAME},
+			},
+		},
+		{
+			title: "service status is preferred",
+			svc: &v1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:   "testing",
+					Name:        "foo",
+					Annotations: map[string]string{hostnameAnnotationKey: "foo.example.org"},
+				},
+				Status: v1.ServiceStatus{
+					LoadBalancer: v1.LoadBalancerStatus{Ingress: []v1.LoadBalancerIngress{{Hostname: "status.elb.amazonaws.com"}}},
+				},
+			},
+			bindings: []*unstructured.Unstructured{
+				newTestTargetGroupBinding("foo-80", "arn:tg/foo", "foo"),
+			},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "foo.example.org", Targets: endpoint.Targets{"status.elb.amazonaws.com"}, RecordType: endpoint.RecordTypeCNAME},
+			},
+		},
+		{
+			title: "service without hostname isn't published",
+			svc: &v1.Service{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "testing", Name: "foo"},
+			},
+			bindings: []*unstructured.Unstructured{
+				newTestTargetGroupBinding("foo-80", "arn:tg/foo", "foo"),
+			},
+			expected: []*endpoint.Endpoint{},
+		},
+		{
+			title: "binding of a missing service is skipped",
+			svc: &v1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:   "testing",
+					Name:        "foo",
+					Annotations: map[string]string{hostnameAnnotationKey: "foo.example.org"},
+				},
+			},
+			bindings: []*unstructured.Unstructured{
+				newTestTargetGroupBinding("bar-80", "arn:tg/bar", "bar"),
+			},
+			expected: []*endpoint.Endpoint{},
+		},
+		{
+			title: "failed lookup of the load balancer fails the sync",
+			svc: &v1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:   "testing",
+					Name:        "foo",
+					Annotations: map[string]string{hostnameAnnotationKey: "foo.example.org"},
+				},
+			},
+			bindings: []*unstructured.Unstructured{
+				newTestTargetGroupBinding("foo-80", "arn:tg/foo", "foo"),
+				newTestTargetGroupBinding("foo-8080", "arn:tg/unknown", "foo"),
+			},
+			expectError: true,
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			kubernetes := fake.NewSimpleClientset()
+			_, err := kubernetes.CoreV1().Services(tc.svc.Namespace).Create(tc.svc)
+			require.NoError(t, err)
+
+			informerFactory := kubeinformers.NewSharedInformerFactory(kubernetes, 0)
+			serviceInformer := informerFactory.Core().V1().Services()
+			serviceInformer.Informer()
+			informerFactory.Start(wait.NeverStop)
+			err = wait.Poll(100*time.Millisecond, 3*time.Second, func() (bool, error) {
+				return serviceInformer.Informer().HasSynced(), nil
+			})
+			require.NoError(t, err)
+
+			sc := &targetGroupBindingSource{
+				resolver: fakeLoadBalancerDNSResolver{
+					"arn:tg/foo":       "foo-123.elb.us-east-1.amazonaws.com",
+					"arn:tg/foo-https": "foo-123.elb.us-east-1.amazonaws.com",
+					"arn:tg/bar":       "bar-456.elb.us-east-1.amazonaws.com",
+				},
+				serviceInformer: serviceInformer,
+			}
+
+			endpoints, err := sc.endpointsFromTargetGroupBindings(tc.bindings)
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			validateEndpoints(t, endpoints, tc.expected)
+		})
+	}
+}
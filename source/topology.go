@@ -0,0 +1,154 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+const (
+	// The well-known label of the region a node is running in
+	topologyRegionLabelKey = "topology.kubernetes.io/region"
+	// The label of the region of nodes before Kubernetes 1.17
+	deprecatedTopologyRegionLabelKey = "failure-domain.beta.kubernetes.io/region"
+
+	// The provider-specific property of the region of AWS latency records
+	awsRegionProperty = "aws/region"
+)
+
+// The provider-specific properties selecting a routing policy, an endpoint with any of them already
+// has its own routing
+var routingProperties = []string{
+	"aws/weight",
+	awsRegionProperty,
+	"aws/failover",
+	"aws/geolocation-continent-code",
+	"aws/geolocation-country-code",
+	"aws/geolocation-subdivision-code",
+	"aws/multi-value-answer",
+}
+
+// topologySource is a Source attaching latency routing to the records of its wrapped source by the
+// region of the cluster, read from the topology labels of its nodes. Multi-region clusters publishing
+// the same hostname then each get a record of their own and clients are answered with the records of
+// the closest region, without annotating every resource. The records are told apart by the set
+// identifier, which defaults to the region. Endpoints with a set identifier or a routing policy of
+// their own are left as they are.
+type topologySource struct {
+	source        Source
+	client        kubernetes.Interface
+	setIdentifier string
+}
+
+// NewTopologySource creates a new topologySource wrapping the provided Source.
+func NewTopologySource(source Source, kubeClient kubernetes.Interface, setIdentifier string) (Source, error) {
+	if kubeClient == nil {
+		return nil, fmt.Errorf("topology routing requires a Kubernetes client")
+	}
+	return &topologySource{source: source, client: kubeClient, setIdentifier: setIdentifier}, nil
+}
+
+// Endpoints collects endpoints from its wrapped source and attaches the region of the cluster to the
+// A and CNAME records without routing.
+func (ts *topologySource) Endpoints() ([]*endpoint.Endpoint, error) {
+	endpoints, err := ts.source.Endpoints()
+	if err != nil {
+		return nil, err
+	}
+
+	region, err := ts.clusterRegion()
+	if err != nil {
+		return nil, err
+	}
+	if region == "" {
+		log.Warn("Skipping topology routing because no node has a region label")
+		return endpoints, nil
+	}
+	setIdentifier := ts.setIdentifier
+	if setIdentifier == "" {
+		setIdentifier = region
+	}
+
+	for _, ep := range endpoints {
+		if ep.RecordType != endpoint.RecordTypeA && ep.RecordType != endpoint.RecordTypeAAAA && ep.RecordType != endpoint.RecordTypeCNAME {
+			continue
+		}
+		if ep.SetIdentifier != "" || hasRoutingProperty(ep) {
+			continue
+		}
+		ep.SetIdentifier = setIdentifier
+		ep.WithProviderSpecific(awsRegionProperty, region)
+	}
+
+	return endpoints, nil
+}
+
+func (ts *topologySource) AddEventHandler(handler func() error, stopChan <-chan struct{}, minInterval time.Duration) {
+	ts.source.AddEventHandler(handler, stopChan, minInterval)
+}
+
+// clusterRegion returns the region most nodes of the cluster are running in, ties are broken by name.
+func (ts *topologySource) clusterRegion() (string, error) {
+	nodes, err := ts.client.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	count := map[string]int{}
+	for _, node := range nodes.Items {
+		region := node.Labels[topologyRegionLabelKey]
+		if region == "" {
+			region = node.Labels[deprecatedTopologyRegionLabelKey]
+		}
+		if region = strings.TrimSpace(region); region != "" {
+			count[region]++
+		}
+	}
+
+	regions := make([]string, 0, len(count))
+	for region := range count {
+		regions = append(regions, region)
+	}
+	sort.Slice(regions, func(i, j int) bool {
+		if count[regions[i]] != count[regions[j]] {
+			return count[regions[i]] > count[regions[j]]
+		}
+		return regions[i] < regions[j]
+	})
+	if len(regions) == 0 {
+		return "", nil
+	}
+	return regions[0], nil
+}
+
+func hasRoutingProperty(ep *endpoint.Endpoint) bool {
+	for _, name := range routingProperties {
+		if _, ok := ep.GetProviderSpecificProperty(name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/internal/testutils"
+)
+
+// Validates that topologySource is a Source
+var _ Source = &topologySource{}
+
+func TestTopologySourceEndpoints(t *testing.T) {
+	for _, tc := range []struct {
+		title         string
+		nodes         map[string]map[string]string
+		setIdentifier string
+		expected      []*endpoint.Endpoint
+	}{
+		{
+			title: "most nodes are in a region",
+			nodes: map[string]map[string]string{
+				"node-1": {topologyRegionLabelKey: "eu-west-1"},
+				"node-2": {topologyRegionLabelKey: "eu-central-1"},
+				"node-3": {deprecatedTopologyRegionLabelKey: "eu-central-1"},
+			},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "api.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}, SetIdentifier: "eu-central-1", ProviderSpecific: endpoint.ProviderSpecific{{Name: awsRegionProperty, Value: "eu-central-1"}}},
+				{DNSName: "www.example.org", RecordType: endpoint.RecordTypeCNAME, Targets: endpoint.Targets{"lb.example.org"}, SetIdentifier: "eu-central-1", ProviderSpecific: endpoint.ProviderSpecific{{Name: awsRegionProperty, Value: "eu-central-1"}}},
+				{DNSName: "weighted.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}, SetIdentifier: "blue", ProviderSpecific: endpoint.ProviderSpecific{{Name: "aws/weight", Value: "10"}}},
+				{DNSName: "failover.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}, ProviderSpecific: endpoint.ProviderSpecific{{Name: "aws/failover", Value: "PRIMARY"}}},
+				{DNSName: "example.org", RecordType: "MX", Targets: endpoint.Targets{"10 mail.example.org"}},
+			},
+		},
+		{
+			title:         "custom set identifier",
+			nodes:         map[string]map[string]string{"node-1": {topologyRegionLabelKey: "us-east-1"}},
+			setIdentifier: "cluster-a",
+			expected: []*endpoint.Endpoint{
+				{DNSName: "api.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}, SetIdentifier: "cluster-a", ProviderSpecific: endpoint.ProviderSpecific{{Name: awsRegionProperty, Value: "us-east-1"}}},
+				{DNSName: "www.example.org", RecordType: endpoint.RecordTypeCNAME, Targets: endpoint.Targets{"lb.example.org"}, SetIdentifier: "cluster-a", ProviderSpecific: endpoint.ProviderSpecific{{Name: awsRegionProperty, Value: "us-east-1"}}},
+				{DNSName: "weighted.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}, SetIdentifier: "blue", ProviderSpecific: endpoint.ProviderSpecific{{Name: "aws/weight", Value: "10"}}},
+				{DNSName: "failover.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}, ProviderSpecific: endpoint.ProviderSpecific{{Name: "aws/failover", Value: "PRIMARY"}}},
+				{DNSName: "example.org", RecordType: "MX", Targets: endpoint.Targets{"10 mail.example.org"}},
+			},
+		},
+		{
+			title: "no node has a region",
+			nodes: map[string]map[string]string{"node-1": nil},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "api.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}},
+				{DNSName: "www.example.org", RecordType: endpoint.RecordTypeCNAME, Targets: endpoint.Targets{"lb.example.org"}},
+				{DNSName: "weighted.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}, SetIdentifier: "blue", ProviderSpecific: endpoint.ProviderSpecific{{Name: "aws/weight", Value: "10"}}},
+				{DNSName: "failover.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}, ProviderSpecific: endpoint.ProviderSpecific{{Name: "aws/failover", Value: "PRIMARY"}}},
+				{DNSName: "example.org", RecordType: "MX", Targets: endpoint.Targets{"10 mail.example.org"}},
+			},
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			kubeClient := fake.NewSimpleClientset()
+			for name, labels := range tc.nodes {
+				_, err := kubeClient.CoreV1().Nodes().Create(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}})
+				require.NoError(t, err)
+			}
+
+			mockSource := new(testutils.MockSource)
+			mockSource.On("Endpoints").Return([]*endpoint.Endpoint{
+				{DNSName: "api.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}},
+				{DNSName: "www.example.org", RecordType: endpoint.RecordTypeCNAME, Targets: endpoint.Targets{"lb.example.org"}},
+				{DNSName: "weighted.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}, SetIdentifier: "blue", ProviderSpecific: endpoint.ProviderSpecific{{Name: "aws/weight", Value: "10"}}},
+				{DNSName: "failover.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}, ProviderSpecific: endpoint.ProviderSpecific{{Name: "aws/failover", Value: "PRIMARY"}}},
+				{DNSName: "example.org", RecordType: "MX", Targets: endpoint.Targets{"10 mail.example.org"}},
+			}, nil)
+
+			ts, err := NewTopologySource(mockSource, kubeClient, tc.setIdentifier)
+			require.NoError(t, err)
+
+			endpoints, err := ts.Endpoints()
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, endpoints)
+		})
+	}
+}
+
+func TestNewTopologySourceRequiresClient(t *testing.T) {
+	_, err := NewTopologySource(new(testutils.MockSource), nil, "")
+	assert.EqualError(t, err, "topology routing requires a Kubernetes client")
+}
@@ -0,0 +1,385 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	istionetworking "istio.io/api/networking/v1alpha3"
+	istiomodel "istio.io/istio/pilot/pkg/model"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// The gateway name of a virtual service routing the traffic of the sidecars instead of an ingress gateway
+const istioMeshGateway = "mesh"
+
+// virtualServiceSource is an implementation of Source for Istio VirtualService objects.
+// The hosts of a virtual service are published with the addresses of the ingress gateway services
+// selected by the Gateways it's bound to, as long as a server of the gateway accepts the host.
+// Hosts which no bound gateway accepts aren't reachable from outside the mesh and are skipped.
+// Use targetAnnotationKey on the virtual service or the gateway to explicitly set Endpoint.
+type virtualServiceSource struct {
+	kubeClient               kubernetes.Interface
+	istioClient              istiomodel.ConfigStore
+	namespace                string
+	annotationFilter         string
+	fqdnTemplate             *template.Template
+	combineFQDNAnnotation    bool
+	ignoreHostnameAnnotation bool
+	serviceInformer          coreinformers.ServiceInformer
+}
+
+// NewIstioVirtualServiceSource creates a new virtualServiceSource with the given config.
+func NewIstioVirtualServiceSource(
+	informers *InformerFactories,
+	kubeClient kubernetes.Interface,
+	istioClient istiomodel.ConfigStore,
+	namespace string,
+	annotationFilter string,
+	fqdnTemplate string,
+	combineFqdnAnnotation bool,
+	ignoreHostnameAnnotation bool,
+) (Source, error) {
+	var (
+		tmpl *template.Template
+		err  error
+	)
+
+	if fqdnTemplate != "" {
+		tmpl, err = template.New("endpoint").Funcs(template.FuncMap{
+			"trimPrefix": strings.TrimPrefix,
+		}).Parse(fqdnTemplate)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Use shared informers to listen for add/update/delete of services in the specified namespace.
+	// Set resync period to 0, to prevent processing when nothing has changed
+	informerFactory := informers.Kube(kubeClient, namespace)
+	serviceInformer := informerFactory.Core().V1().Services()
+
+	// Add default resource event handlers to properly initialize informer.
+	serviceInformer.Informer().AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+			},
+		},
+	)
+
+	// TODO informer is not explicitly stopped since controller is not passing in its channel.
+	informerFactory.Start(wait.NeverStop)
+
+	// wait for the local cache to be populated.
+	err = wait.Poll(time.Second, 60*time.Second, func() (bool, error) {
+		return serviceInformer.Informer().HasSynced(), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sync cache: %v", err)
+	}
+
+	return &virtualServiceSource{
+		kubeClient:               kubeClient,
+		istioClient:              istioClient,
+		namespace:                namespace,
+		annotationFilter:         annotationFilter,
+		fqdnTemplate:             tmpl,
+		combineFQDNAnnotation:    combineFqdnAnnotation,
+		ignoreHostnameAnnotation: ignoreHostnameAnnotation,
+		serviceInformer:          serviceInformer,
+	}, nil
+}
+
+// Endpoints returns endpoint objects for each host of the virtual services bound to a gateway.
+// Retrieves all virtual service resources in the source's namespace(s).
+func (sc *virtualServiceSource) Endpoints() ([]*endpoint.Endpoint, error) {
+	configs, err := sc.istioClient.List(istiomodel.VirtualService.Type, sc.namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	configs, err = sc.filterByAnnotations(configs)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := []*endpoint.Endpoint{}
+
+	for _, config := range configs {
+		// Check controller annotation to see if we are responsible.
+		controller, ok := config.Annotations[controllerAnnotationKey]
+		if ok && controller != controllerAnnotationValue {
+			log.Debugf("Skipping virtualservice %s/%s because controller value does not match, found: %s, required: %s",
+				config.Namespace, config.Name, controller, controllerAnnotationValue)
+			continue
+		}
+
+		gateways := sc.gatewaysOf(config)
+
+		vsEndpoints, err := sc.endpointsFromVirtualServiceConfig(config, gateways)
+		if err != nil {
+			return nil, err
+		}
+
+		// apply template if the virtual service has no hosts bound to a gateway
+		if (sc.combineFQDNAnnotation || len(vsEndpoints) == 0) && sc.fqdnTemplate != nil {
+			tEndpoints, err := sc.endpointsFromTemplate(config, gateways)
+			if err != nil {
+				return nil, err
+			}
+
+			if sc.combineFQDNAnnotation {
+				vsEndpoints = append(vsEndpoints, tEndpoints...)
+			} else {
+				vsEndpoints = tEndpoints
+			}
+		}
+
+		if len(vsEndpoints) == 0 {
+			log.Debugf("No endpoints could be generated from virtualservice %s/%s", config.Namespace, config.Name)
+			continue
+		}
+
+		log.Debugf("Endpoints generated from virtualservice: %s/%s: %v", config.Namespace, config.Name, vsEndpoints)
+		for _, ep := range vsEndpoints {
+			ep.Labels[endpoint.ResourceLabelKey] = fmt.Sprintf("virtualservice/%s/%s", config.Namespace, config.Name)
+		}
+		endpoints = append(endpoints, vsEndpoints...)
+	}
+
+	for _, ep := range endpoints {
+		sort.Sort(ep.Targets)
+	}
+
+	return endpoints, nil
+}
+
+func (sc *virtualServiceSource) AddEventHandler(handler func() error, stopChan <-chan struct{}, minInterval time.Duration) {
+}
+
+// gatewaysOf returns the gateways a virtual service is bound to. References are either the name of a
+// gateway in the namespace of the virtual service or of the form my-namespace/my-gateway.
+func (sc *virtualServiceSource) gatewaysOf(config istiomodel.Config) []*istiomodel.Config {
+	virtualService := config.Spec.(*istionetworking.VirtualService)
+
+	var gateways []*istiomodel.Config
+	for _, ref := range virtualService.Gateways {
+		if ref == "" || ref == istioMeshGateway {
+			continue
+		}
+		namespace, name := config.Namespace, ref
+		if parts := strings.Split(ref, "/"); len(parts) == 2 {
+			namespace, name = parts[0], parts[1]
+		}
+
+		gateway := sc.istioClient.Get(istiomodel.Gateway.Type, name, namespace)
+		if gateway == nil {
+			log.Debugf("Unable to find gateway %s/%s of virtualservice %s/%s", namespace, name, config.Namespace, config.Name)
+			continue
+		}
+		gateways = append(gateways, gateway)
+	}
+	return gateways
+}
+
+// targetsFromGateway returns the targets of a gateway, either of its target annotation or the load
+// balancer addresses of the ingress gateway services matching its selector.
+func (sc *virtualServiceSource) targetsFromGateway(gateway *istiomodel.Config) (endpoint.Targets, error) {
+	if targets := getTargetsFromTargetAnnotation(gateway.Annotations); len(targets) > 0 {
+		return targets, nil
+	}
+
+	selector := labels.SelectorFromSet(gateway.Spec.(*istionetworking.Gateway).Selector)
+	services, err := sc.serviceInformer.Lister().Services(sc.namespace).List(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	var targets endpoint.Targets
+	for _, service := range services {
+		targets = append(targets, extractLoadBalancerTargets(service)...)
+	}
+	return targets, nil
+}
+
+// targetsForHost returns the targets of the gateways accepting the host. The target annotation of the
+// virtual service overrides them, but the host must still be accepted by one of its gateways.
+func (sc *virtualServiceSource) targetsForHost(config istiomodel.Config, gateways []*istiomodel.Config, host string) (endpoint.Targets, error) {
+	overrides := getTargetsFromTargetAnnotation(config.Annotations)
+
+	var targets endpoint.Targets
+	seen := map[string]bool{}
+	accepted := false
+	for _, gateway := range gateways {
+		if host != "" && !gatewayAcceptsHost(gateway, config.Namespace, host) {
+			continue
+		}
+		accepted = true
+		if len(overrides) > 0 {
+			continue
+		}
+		gwTargets, err := sc.targetsFromGateway(gateway)
+		if err != nil {
+			return nil, err
+		}
+		// gateways sharing an ingress gateway service have the same targets
+		for _, target := range gwTargets {
+			if !seen[target] {
+				seen[target] = true
+				targets = append(targets, target)
+			}
+		}
+	}
+
+	if !accepted {
+		return nil, nil
+	}
+	if len(overrides) > 0 {
+		return overrides, nil
+	}
+	return targets, nil
+}
+
+// endpointsFromVirtualServiceConfig extracts the endpoints from an Istio VirtualService Config object
+func (sc *virtualServiceSource) endpointsFromVirtualServiceConfig(config istiomodel.Config, gateways []*istiomodel.Config) ([]*endpoint.Endpoint, error) {
+	var endpoints []*endpoint.Endpoint
+	resource := fmt.Sprintf("virtualservice %s/%s", config.Namespace, config.Name)
+	virtualService := config.Spec.(*istionetworking.VirtualService)
+
+	for _, host := range virtualService.Hosts {
+		if host == "" || host == "*" {
+			continue
+		}
+		targets, err := sc.targetsForHost(config, gateways, host)
+		if err != nil {
+			return nil, err
+		}
+		if len(targets) == 0 {
+			log.Debugf("Skipping host %s of %s because no gateway with an address accepts it", host, resource)
+			continue
+		}
+		endpoints = append(endpoints, endpointsForHostnames([]string{host}, targets, config.Annotations, resource)...)
+	}
+
+	// Skip endpoints if we do not want entries from annotations
+	if !sc.ignoreHostnameAnnotation {
+		hostnames := getHostnamesFromAnnotations(config.Annotations)
+		if len(hostnames) > 0 {
+			targets, err := sc.targetsForHost(config, gateways, "")
+			if err != nil {
+				return nil, err
+			}
+			endpoints = append(endpoints, endpointsForHostnames(hostnames, targets, config.Annotations, resource)...)
+		}
+	}
+
+	return endpoints, nil
+}
+
+func (sc *virtualServiceSource) endpointsFromTemplate(config istiomodel.Config, gateways []*istiomodel.Config) ([]*endpoint.Endpoint, error) {
+	// Process the whole template string
+	var buf bytes.Buffer
+	err := sc.fqdnTemplate.Execute(&buf, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply template on istio config %s: %v", config.Name, err)
+	}
+
+	targets, err := sc.targetsForHost(config, gateways, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var hostnames []string
+	// splits the FQDN template and removes the trailing periods
+	for _, hostname := range strings.Split(strings.Replace(buf.String(), " ", "", -1), ",") {
+		hostnames = append(hostnames, strings.TrimSuffix(hostname, "."))
+	}
+	return endpointsForHostnames(hostnames, targets, config.Annotations, fmt.Sprintf("virtualservice %s/%s", config.Namespace, config.Name)), nil
+}
+
+// filterByAnnotations filters a list of configs by a given annotation selector.
+func (sc *virtualServiceSource) filterByAnnotations(configs []istiomodel.Config) ([]istiomodel.Config, error) {
+	labelSelector, err := metav1.ParseToLabelSelector(sc.annotationFilter)
+	if err != nil {
+		return nil, err
+	}
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	// empty filter returns original list
+	if selector.Empty() {
+		return configs, nil
+	}
+
+	filteredList := []istiomodel.Config{}
+
+	for _, config := range configs {
+		// include if the annotations match the selector
+		if selector.Matches(labels.Set(config.Annotations)) {
+			filteredList = append(filteredList, config)
+		}
+	}
+
+	return filteredList, nil
+}
+
+// gatewayAcceptsHost returns true if a server of the gateway accepts the host of a virtual service in the
+// given namespace, either exactly or by a wildcard like *.example.org or *. Hosts of the servers may be
+// restricted to the virtual services of a namespace, e.g. my-namespace/foo.bar.com, where * stands for
+// any namespace and . for the namespace of the gateway.
+func gatewayAcceptsHost(gateway *istiomodel.Config, namespace, host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, server := range gateway.Spec.(*istionetworking.Gateway).Servers {
+		for _, gwHost := range server.Hosts {
+			if parts := strings.Split(gwHost, "/"); len(parts) == 2 {
+				switch parts[0] {
+				case "*", namespace:
+				case ".":
+					if gateway.Namespace != namespace {
+						continue
+					}
+				default:
+					continue
+				}
+				gwHost = parts[1]
+			}
+			gwHost = strings.ToLower(strings.TrimSuffix(gwHost, "."))
+			switch {
+			case gwHost == "*", gwHost == host:
+				return true
+			case strings.HasPrefix(gwHost, "*.") && strings.HasSuffix(host, gwHost[1:]):
+				return true
+			}
+		}
+	}
+	return false
+}
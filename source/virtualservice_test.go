@@ -0,0 +1,305 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	istionetworking "istio.io/api/networking/v1alpha3"
+	istiomodel "istio.io/istio/pilot/pkg/model"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// This is a compile-time validation that virtualServiceSource is a Source.
+var _ Source = &virtualServiceSource{}
+
+type fakeVirtualServiceConfig struct {
+	namespace   string
+	name        string
+	annotations map[string]string
+	hosts       []string
+	gateways    []string
+}
+
+func (c fakeVirtualServiceConfig) Config() istiomodel.Config {
+	return istiomodel.Config{
+		ConfigMeta: istiomodel.ConfigMeta{
+			Namespace:   c.namespace,
+			Name:        c.name,
+			Type:        istiomodel.VirtualService.Type,
+			Annotations: c.annotations,
+		},
+		Spec: &istionetworking.VirtualService{
+			Hosts:    c.hosts,
+			Gateways: c.gateways,
+		},
+	}
+}
+
+func TestVirtualServiceSourceEndpoints(t *testing.T) {
+	services := []fakeIngressGatewayService{
+		{
+			ips:       []string{"1.2.3.4"},
+			namespace: "istio-system",
+			name:      "istio-ingressgateway",
+			labels:    map[string]string{"istio": "ingressgateway"},
+		},
+		{
+			hostnames: []string{"internal.elb.amazonaws.com"},
+			namespace: "istio-system",
+			name:      "istio-internal-gateway",
+			labels:    map[string]string{"istio": "internal-gateway"},
+		},
+	}
+	gateways := []fakeGatewayConfig{
+		{
+			namespace: "istio-system",
+			name:      "public",
+			dnsnames:  [][]string{{"*.example.org"}},
+			selector:  map[string]string{"istio": "ingressgateway"},
+		},
+		{
+			namespace: "istio-system",
+			name:      "public-api",
+			dnsnames:  [][]string{{"default/api.example.org"}},
+			selector:  map[string]string{"istio": "ingressgateway"},
+		},
+		{
+			namespace: "default",
+			name:      "internal",
+			dnsnames:  [][]string{{"*"}},
+			selector:  map[string]string{"istio": "internal-gateway"},
+		},
+		{
+			namespace:   "default",
+			name:        "annotated",
+			annotations: map[string]string{targetAnnotationKey: "gateway.example.net"},
+			dnsnames:    [][]string{{"*"}},
+			selector:    map[string]string{"istio": "ingressgateway"},
+		},
+	}
+
+	for _, tc := range []struct {
+		title                    string
+		annotationFilter         string
+		fqdnTemplate             string
+		combineFQDNAnnotation    bool
+		ignoreHostnameAnnotation bool
+		virtualServices          []fakeVirtualServiceConfig
+		expected                 []*endpoint.Endpoint
+	}{
+		{
+			title: "hosts accepted by a gateway in another namespace",
+			virtualServices: []fakeVirtualServiceConfig{{
+				namespace: "default",
+				name:      "app",
+				hosts:     []string{"app.example.org", "app.example.com", "reviews"},
+				gateways:  []string{"istio-system/public"},
+			}},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "app.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}},
+			},
+		},
+		{
+			title: "gateway in the namespace of the virtual service",
+			virtualServices: []fakeVirtualServiceConfig{{
+				namespace: "default",
+				name:      "app",
+				hosts:     []string{"app.internal.example.org"},
+				gateways:  []string{"internal"},
+			}},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "app.internal.example.org", RecordType: endpoint.RecordTypeCNAME, Targets: endpoint.Targets{"internal.elb.amazonaws.com"}},
+			},
+		},
+		{
+			title: "gateways sharing an ingress gateway service",
+			virtualServices: []fakeVirtualServiceConfig{{
+				namespace: "default",
+				name:      "api",
+				hosts:     []string{"api.example.org"},
+				gateways:  []string{"istio-system/public", "istio-system/public-api", "mesh"},
+			}},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "api.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}},
+			},
+		},
+		{
+			title: "mesh and unknown gateways",
+			virtualServices: []fakeVirtualServiceConfig{{
+				namespace: "default",
+				name:      "reviews",
+				hosts:     []string{"reviews.example.org"},
+				gateways:  []string{"mesh", "istio-system/unknown"},
+			}},
+			expected: []*endpoint.Endpoint{},
+		},
+		{
+			title: "target annotation of the gateway",
+			virtualServices: []fakeVirtualServiceConfig{{
+				namespace: "default",
+				name:      "app",
+				hosts:     []string{"app.example.org"},
+				gateways:  []string{"annotated"},
+			}},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "app.example.org", RecordType: endpoint.RecordTypeCNAME, Targets: endpoint.Targets{"gateway.example.net"}},
+			},
+		},
+		{
+			title: "target annotation of the virtual service",
+			virtualServices: []fakeVirtualServiceConfig{{
+				namespace:   "default",
+				name:        "app",
+				annotations: map[string]string{targetAnnotationKey: "5.6.7.8", ttlAnnotationKey: "60"},
+				hosts:       []string{"app.example.org", "app.example.com"},
+				gateways:    []string{"istio-system/public"},
+			}},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "app.example.org", RecordType: endpoint.RecordTypeA, RecordTTL: 60, Targets: endpoint.Targets{"5.6.7.8"}},
+			},
+		},
+		{
+			title: "hostname annotation",
+			virtualServices: []fakeVirtualServiceConfig{{
+				namespace:   "default",
+				name:        "app",
+				annotations: map[string]string{hostnameAnnotationKey: "www.example.org"},
+				hosts:       []string{"app.example.org"},
+				gateways:    []string{"istio-system/public"},
+			}},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "app.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}},
+				{DNSName: "www.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}},
+			},
+		},
+		{
+			title:                    "ignored hostname annotation",
+			ignoreHostnameAnnotation: true,
+			virtualServices: []fakeVirtualServiceConfig{{
+				namespace:   "default",
+				name:        "app",
+				annotations: map[string]string{hostnameAnnotationKey: "www.example.org"},
+				hosts:       []string{"app.example.org"},
+				gateways:    []string{"istio-system/public"},
+			}},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "app.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}},
+			},
+		},
+		{
+			title:        "FQDN template for virtual services without accepted hosts",
+			fqdnTemplate: "{{.Name}}.example.org",
+			virtualServices: []fakeVirtualServiceConfig{{
+				namespace: "default",
+				name:      "reviews",
+				hosts:     []string{"reviews"},
+				gateways:  []string{"istio-system/public"},
+			}},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "reviews.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}},
+			},
+		},
+		{
+			title:            "annotation filter",
+			annotationFilter: "external-dns.alpha.kubernetes.io/enabled in (true)",
+			virtualServices: []fakeVirtualServiceConfig{
+				{
+					namespace:   "default",
+					name:        "enabled",
+					annotations: map[string]string{"external-dns.alpha.kubernetes.io/enabled": "true"},
+					hosts:       []string{"enabled.example.org"},
+					gateways:    []string{"istio-system/public"},
+				},
+				{
+					namespace: "default",
+					name:      "other",
+					hosts:     []string{"other.example.org"},
+					gateways:  []string{"istio-system/public"},
+				},
+				{
+					namespace:   "default",
+					name:        "other-controller",
+					annotations: map[string]string{"external-dns.alpha.kubernetes.io/enabled": "true", controllerAnnotationKey: "other-controller"},
+					hosts:       []string{"other-controller.example.org"},
+					gateways:    []string{"istio-system/public"},
+				},
+			},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "enabled.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}},
+			},
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			fakeKubernetesClient := fake.NewSimpleClientset()
+			for _, svc := range services {
+				_, err := fakeKubernetesClient.CoreV1().Services(svc.namespace).Create(svc.Service())
+				require.NoError(t, err)
+			}
+			fakeIstioClient := NewFakeConfigStore()
+			for _, gw := range gateways {
+				_, err := fakeIstioClient.Create(gw.Config())
+				require.NoError(t, err)
+			}
+			for _, vs := range tc.virtualServices {
+				_, err := fakeIstioClient.Create(vs.Config())
+				require.NoError(t, err)
+			}
+
+			src, err := NewIstioVirtualServiceSource(NewInformerFactories(), fakeKubernetesClient, fakeIstioClient, "", tc.annotationFilter, tc.fqdnTemplate, tc.combineFQDNAnnotation, tc.ignoreHostnameAnnotation)
+			require.NoError(t, err)
+
+			endpoints, err := src.Endpoints()
+			require.NoError(t, err)
+			validateEndpoints(t, endpoints, tc.expected)
+			for _, ep := range endpoints {
+				assert.Equal(t, "virtualservice/default/"+tc.virtualServices[0].name, ep.Labels[endpoint.ResourceLabelKey])
+			}
+		})
+	}
+}
+
+func TestGatewayAcceptsHost(t *testing.T) {
+	gateway := (fakeGatewayConfig{
+		namespace: "istio-system",
+		name:      "public",
+		dnsnames:  [][]string{{"*.example.org", "prod/api.example.com.", "./admin.example.com", "*/www.example.com"}},
+	}).Config()
+
+	for _, tc := range []struct {
+		namespace string
+		host      string
+		accepted  bool
+	}{
+		{"default", "app.example.org", true},
+		{"default", "a.b.example.org.", true},
+		{"default", "example.org", false},
+		{"default", "badexample.org", false},
+		{"prod", "API.example.com", true},
+		{"default", "api.example.com", false},
+		{"istio-system", "admin.example.com", true},
+		{"default", "admin.example.com", false},
+		{"default", "www.example.com", true},
+		{"default", "app.example.com", false},
+	} {
+		assert.Equal(t, tc.accepted, gatewayAcceptsHost(&gateway, tc.namespace, tc.host), "%s/%s", tc.namespace, tc.host)
+	}
+}
@@ -0,0 +1,169 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// The annotation ExternalDNS writes the records it applied for a resource to
+const appliedRecordsAnnotationKey = "external-dns.alpha.kubernetes.io/applied-records"
+
+// appliedRecords is the value of the applied records annotation.
+type appliedRecords struct {
+	AppliedAt time.Time       `json:"appliedAt"`
+	Records   []appliedRecord `json:"records"`
+}
+
+type appliedRecord struct {
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	Zone    string   `json:"zone,omitempty"`
+	TTL     int64    `json:"ttl,omitempty"`
+	Targets []string `json:"targets"`
+}
+
+// AppliedRecordsAnnotator writes the records applied for Services, Ingresses and DNSEndpoints back onto
+// them as the applied-records annotation, so the resulting DNS state shows up with kubectl. The zone of
+// a record is the most specific domain of the domain filter it belongs to.
+type AppliedRecordsAnnotator struct {
+	client    dynamic.Interface
+	resources map[string]schema.GroupVersionResource
+	zones     []string
+}
+
+// NewAppliedRecordsAnnotator creates a new AppliedRecordsAnnotator. The DNSEndpoints are the resources of
+// the given API version and kind of the crd source.
+func NewAppliedRecordsAnnotator(client dynamic.Interface, crdAPIVersion, crdKind string, zones []string) (*AppliedRecordsAnnotator, error) {
+	resources, err := writableResources(crdAPIVersion, crdKind)
+	if err != nil {
+		return nil, err
+	}
+
+	normalized := []string{}
+	for _, zone := range zones {
+		if zone = strings.ToLower(strings.Trim(zone, ".")); zone != "" {
+			normalized = append(normalized, zone)
+		}
+	}
+
+	return &AppliedRecordsAnnotator{
+		client:    client,
+		resources: resources,
+		zones:     normalized,
+	}, nil
+}
+
+// writableResources returns the resources of the Services, Ingresses and DNSEndpoints by the kind of
+// their resource labels. The DNSEndpoints are the resources of the given API version and kind.
+func writableResources(crdAPIVersion, crdKind string) (map[string]schema.GroupVersionResource, error) {
+	groupVersion, err := schema.ParseGroupVersion(crdAPIVersion)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]schema.GroupVersionResource{
+		"service": {Version: "v1", Resource: "services"},
+		"ingress": {Group: "extensions", Version: "v1beta1", Resource: "ingresses"},
+		"crd":     groupVersion.WithResource(strings.ToLower(crdKind) + "s"),
+	}, nil
+}
+
+// WriteAppliedRecords annotates each of the resources with its endpoints. Resources which are gone
+// or of other kinds are skipped, failures are logged as the records are already applied.
+func (a *AppliedRecordsAnnotator) WriteAppliedRecords(resources []string, endpoints []*endpoint.Endpoint, appliedAt time.Time) {
+	byResource := map[string][]*endpoint.Endpoint{}
+	for _, ep := range endpoints {
+		resource := ep.Labels[endpoint.ResourceLabelKey]
+		byResource[resource] = append(byResource[resource], ep)
+	}
+
+	for _, resource := range resources {
+		parts := strings.Split(resource, "/")
+		if len(parts) != 3 {
+			continue
+		}
+		gvr, ok := a.resources[parts[0]]
+		if !ok {
+			continue
+		}
+
+		value := appliedRecords{AppliedAt: appliedAt.UTC(), Records: []appliedRecord{}}
+		for _, ep := range byResource[resource] {
+			value.Records = append(value.Records, appliedRecord{
+				Name:    ep.DNSName,
+				Type:    ep.RecordType,
+				Zone:    a.zoneOf(ep.DNSName),
+				TTL:     int64(ep.RecordTTL),
+				Targets: ep.Targets,
+			})
+		}
+		sort.Slice(value.Records, func(i, j int) bool {
+			if value.Records[i].Name != value.Records[j].Name {
+				return value.Records[i].Name < value.Records[j].Name
+			}
+			return value.Records[i].Type < value.Records[j].Type
+		})
+
+		if err := a.annotate(gvr, parts[1], parts[2], value); err != nil {
+			log.Warnf("Unable to write the applied records to %s: %v", resource, err)
+		}
+	}
+}
+
+func (a *AppliedRecordsAnnotator) annotate(gvr schema.GroupVersionResource, namespace, name string, value appliedRecords) error {
+	annotation, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{appliedRecordsAnnotationKey: string(annotation)},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = a.client.Resource(gvr).Namespace(namespace).Patch(name, types.MergePatchType, patch, metav1.UpdateOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func (a *AppliedRecordsAnnotator) zoneOf(dnsName string) string {
+	name := strings.ToLower(strings.TrimSuffix(dnsName, "."))
+	match := ""
+	for _, zone := range a.zones {
+		if (name == zone || strings.HasSuffix(name, "."+zone)) && len(zone) > len(match) {
+			match = zone
+		}
+	}
+	return match
+}
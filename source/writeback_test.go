@@ -0,0 +1,86 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestAppliedRecordsAnnotator(t *testing.T) {
+	client := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme())
+	patches := map[string]string{}
+	client.PrependReactor("patch", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch := action.(k8stesting.PatchAction)
+		if patch.GetName() == "gone" {
+			return true, nil, apierrors.NewNotFound(schema.GroupResource{Resource: patch.GetResource().Resource}, patch.GetName())
+		}
+		patches[patch.GetResource().String()+" "+patch.GetNamespace()+"/"+patch.GetName()] = string(patch.GetPatch())
+		return true, nil, nil
+	})
+
+	annotator, err := NewAppliedRecordsAnnotator(client, "externaldns.k8s.io/v1alpha1", "DNSEndpoint", []string{"example.org", "sub.example.org."})
+	require.NoError(t, err)
+
+	withResource := func(ep *endpoint.Endpoint, resource string) *endpoint.Endpoint {
+		ep.Labels[endpoint.ResourceLabelKey] = resource
+		return ep
+	}
+	appliedAt := time.Date(2020, 6, 1, 12, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+	annotator.WriteAppliedRecords(
+		[]string{"service/default/web", "ingress/default/gone", "crd/team-a/records", "node//worker", "invalid"},
+		[]*endpoint.Endpoint{
+			withResource(endpoint.NewEndpointWithTTL("web.sub.example.org", endpoint.RecordTypeA, 300, "1.2.3.4"), "service/default/web"),
+			withResource(endpoint.NewEndpoint("web.example.org", endpoint.RecordTypeCNAME, "lb.example.com"), "service/default/web"),
+			withResource(endpoint.NewEndpoint("other.example.org", endpoint.RecordTypeA, "4.3.2.1"), "service/default/other"),
+		},
+		appliedAt,
+	)
+
+	require.Len(t, patches, 2)
+	var patch struct {
+		Metadata struct {
+			Annotations map[string]string `json:"annotations"`
+		} `json:"metadata"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(patches["/v1, Resource=services default/web"]), &patch))
+	assert.JSONEq(t, `{
+		"appliedAt": "2020-06-01T10:00:00Z",
+		"records": [
+			{"name": "web.example.org", "type": "CNAME", "zone": "example.org", "targets": ["lb.example.com"]},
+			{"name": "web.sub.example.org", "type": "A", "zone": "sub.example.org", "ttl": 300, "targets": ["1.2.3.4"]}
+		]
+	}`, patch.Metadata.Annotations[appliedRecordsAnnotationKey])
+
+	// the records of resources without endpoints are cleared
+	require.NoError(t, json.Unmarshal([]byte(patches["externaldns.k8s.io/v1alpha1, Resource=dnsendpoints team-a/records"]), &patch))
+	assert.JSONEq(t, `{"appliedAt": "2020-06-01T10:00:00Z", "records": []}`, patch.Metadata.Annotations[appliedRecordsAnnotationKey])
+
+	_, err = NewAppliedRecordsAnnotator(client, "a/b/c", "DNSEndpoint", nil)
+	assert.Error(t, err)
+}